@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"n42-test/internal/account"
+	"n42-test/internal/capability"
+	"n42-test/internal/envflag"
+	"n42-test/internal/keys"
+	"n42-test/internal/rpcpool"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: account repair --key 0x... [flags]")
+	fmt.Fprintln(os.Stderr, "  检查 EOA 的 latest/pending nonce，列出空洞与卡住的交易，可用空交易填洞或提价替换")
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "repair" {
+		usage()
+		os.Exit(2)
+	}
+	if err := repair(os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func repair(args []string) error {
+	fs := flag.NewFlagSet("account repair", flag.ExitOnError)
+	keyHex := fs.String("key", "", "EOA 私钥（0x…）")
+	rpcURL := fs.String("rpc", "http://127.0.0.1:8545", "执行层 RPC")
+	fillGaps := fs.Bool("fill-gaps", true, "用自转账 0 wei 的空交易填补 nonce 空洞")
+	bumpStuck := fs.Bool("bump", true, "提价替换卡住的交易")
+	cancel := fs.Bool("cancel", false, "替换卡住的交易时改用同 nonce 的空交易（取消原交易）")
+	yes := fs.Bool("yes", false, "不询问，直接发送修复交易")
+	inspectOnly := fs.Bool("inspect", false, "只检查不修复")
+	envflag.ParseSet(fs, "", args)
+
+	if *keyHex == "" {
+		return fmt.Errorf("必须提供 --key")
+	}
+	priv, err := keys.ParseECDSA(*keyHex)
+	if err != nil {
+		return fmt.Errorf("私钥解析失败: %w", err)
+	}
+	addr := crypto.PubkeyToAddress(priv.PublicKey)
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancelCtx()
+
+	cli, err := rpcpool.DialEth(ctx, *rpcURL)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	caps := capability.For(ctx, *rpcURL)
+	st, err := account.Inspect(ctx, cli, caps, addr)
+	if err != nil {
+		return err
+	}
+	printState(st)
+
+	if *inspectOnly {
+		return nil
+	}
+	actions, err := account.Plan(ctx, cli, priv, st, *fillGaps, *bumpStuck, *cancel)
+	if err != nil {
+		return err
+	}
+	if len(actions) == 0 {
+		fmt.Println("\n无需修复。")
+		return nil
+	}
+
+	fmt.Printf("\n计划发送 %d 笔修复交易：\n", len(actions))
+	for _, a := range actions {
+		fmt.Println("  " + a.String())
+	}
+	if !*yes && !confirm("确认发送？[y/N] ") {
+		fmt.Println("已取消。")
+		return nil
+	}
+
+	failed := 0
+	for _, a := range actions {
+		if err := cli.SendTransaction(ctx, a.Tx); err != nil {
+			failed++
+			log.Printf("❌ nonce=%d 发送失败: %v", a.Nonce, err)
+			continue
+		}
+		log.Printf("✅ nonce=%d %s 已发送 %s", a.Nonce, a.Kind, a.Tx.Hash().Hex())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 笔修复交易发送失败", failed)
+	}
+
+	time.Sleep(2 * time.Second)
+	if after, err := account.Inspect(ctx, cli, caps, addr); err == nil {
+		fmt.Println()
+		printState(after)
+	}
+	return nil
+}
+
+func printState(st *account.State) {
+	fmt.Printf("账户 %s\n", st.Address.Hex())
+	fmt.Printf("  余额:          %s wei\n", st.Balance)
+	fmt.Printf("  latest nonce:  %d\n", st.LatestNonce)
+	fmt.Printf("  pending nonce: %d（池中可执行 %d 笔）\n", st.PendingNonce, st.PendingNonce-st.LatestNonce)
+	if st.BaseFee != nil {
+		fmt.Printf("  baseFee:       %s wei，建议小费 %s wei\n", st.BaseFee, orZero(st.SuggestedTip))
+	}
+	if !st.PoolVisible {
+		fmt.Println("  ⚠️ 节点未开放 txpool API，无法列出池中交易与空洞")
+		return
+	}
+	if len(st.Txs) == 0 {
+		fmt.Println("  交易池中没有本账户的交易")
+	}
+	for _, t := range st.Txs {
+		state := "pending"
+		if t.Queued {
+			state = "queued"
+		}
+		if t.Stuck {
+			state += ",stuck"
+		}
+		to := "(create)"
+		if t.To != nil {
+			to = t.To.Hex()
+		}
+		fmt.Printf("  nonce=%-6d %-14s %s → %s\n", t.Nonce, state, t.Hash.Hex(), to)
+	}
+	if len(st.Gaps) > 0 {
+		fmt.Printf("  nonce 空洞: %v\n", st.Gaps)
+	}
+}
+
+func orZero(x *big.Int) *big.Int {
+	if x == nil {
+		return new(big.Int)
+	}
+	return x
+}
+
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	line = strings.ToLower(strings.TrimSpace(line))
+	return line == "y" || line == "yes"
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"n42-test/internal/daemon"
+	"n42-test/internal/keystore"
+	"n42-test/internal/validator"
+)
+
+// fleetFile -config 指定的验证者列表；ws/rpc 为成员默认端点（省略时取命令行 -ws/-rpc）
+//
+//	{
+//	  "ws": "ws://127.0.0.1:8546",
+//	  "rpc": "http://127.0.0.1:8545",
+//	  "validators": [
+//	    {"name": "v0", "key": "0x..."},
+//	    {"name": "v1", "keystore": "keys/keystore-1.json", "password_file": "keys/pw.txt", "ws": "ws://10.0.0.2:8546"}
+//	  ]
+//	}
+type fleetFile struct {
+	WS         string        `json:"ws"`
+	RPC        string        `json:"rpc"`
+	Validators []fleetMember `json:"validators"`
+}
+
+// fleetMember key 接受交互输入支持的所有格式；设置 keystore 时改为解密 keystore
+type fleetMember struct {
+	Name         string `json:"name"`
+	Key          string `json:"key"`
+	Keystore     string `json:"keystore"`
+	PasswordFile string `json:"password_file"`
+	WS           string `json:"ws"`
+	RPC          string `json:"rpc"`
+}
+
+// loadFleet 读取并解析配置，得到成员列表；相对路径相对配置文件所在目录
+func loadFleet(path, defWS, defRPC string) ([]validator.FleetMember, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ff fleetFile
+	if err := json.Unmarshal(raw, &ff); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if ff.WS == "" {
+		ff.WS = defWS
+	}
+	if ff.RPC == "" {
+		ff.RPC = defRPC
+	}
+	dir := filepath.Dir(path)
+	rel := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	members := make([]validator.FleetMember, 0, len(ff.Validators))
+	for i, v := range ff.Validators {
+		var priv string
+		switch {
+		case v.Keystore != "":
+			priv, err = loadKeystore(rel(v.Keystore), rel(v.PasswordFile))
+		case v.Key != "":
+			priv, err = blsKeyHex(v.Key, "")
+		default:
+			err = fmt.Errorf("需要 key 或 keystore")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("validators[%d] %s: %w", i, v.Name, err)
+		}
+		m := validator.FleetMember{Name: v.Name, PrivHex: priv, WSURL: v.WS, HTTPURL: v.RPC}
+		if m.WSURL == "" {
+			m.WSURL = ff.WS
+		}
+		if m.HTTPURL == "" {
+			m.HTTPURL = ff.RPC
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// runFleet 在同一进程内运行 load 给出的多个验证者（每个私钥各自的二进制与订阅），直到 ctx 结束；
+// 收到 SIGHUP 时重新调用 load（重读 -config 或 keystore 目录），只启停/重启有变化的验证者，
+// 未变化的验证者订阅不中断。重读有误时保留当前舰队。
+// 首批验证者启动后与每次重载后向 systemd 报告就绪；结束时打印各验证者的统计
+func runFleet(ctx context.Context, d *daemon.Daemon, source string, load func() ([]validator.FleetMember, error), cfg validator.StreamConfig) error {
+	members, err := load()
+	if err != nil {
+		return err
+	}
+	fleet := validator.NewFleet(cfg)
+	ch, err := fleet.Apply(ctx, members)
+	if err != nil {
+		return err
+	}
+	log.Printf("fleet: %d validators started from %s (kill -HUP %d to reload)", len(ch.Added), source, os.Getpid())
+	d.Ready(fmt.Sprintf("%d validators", len(members)))
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("fleet: stopping")
+			err := fleet.Stop()
+			printFleetStats(fleet.Stats())
+			return err
+		case <-hup:
+		}
+		d.Reloading("reloading " + source)
+		members, err := load()
+		if err == nil {
+			ch, err = fleet.Apply(ctx, members)
+		}
+		if err != nil {
+			log.Printf("⚠️ fleet: reload failed, keeping current validators: %v", err)
+			d.Ready("reload failed: " + err.Error())
+			continue
+		}
+		log.Printf("fleet: reloaded %s: %s", source, ch)
+		d.Ready(fmt.Sprintf("%d validators", len(members)))
+	}
+}
+
+// loadKeys -keys 与 -keystore-dir 给出的私钥，全部使用 -ws/-rpc；keystore 目录每次重新扫描（SIGHUP 时生效）
+func loadKeys(keyList, keystoreDir, passwordFile, ws, rpc string) ([]validator.FleetMember, error) {
+	var members []validator.FleetMember
+	for i, spec := range strings.Split(keyList, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		priv, err := blsKeyHex(spec, "")
+		if err != nil {
+			return nil, fmt.Errorf("-keys[%d]: %w", i, err)
+		}
+		members = append(members, validator.FleetMember{PrivHex: priv, WSURL: ws, HTTPURL: rpc})
+	}
+	if keystoreDir != "" {
+		if passwordFile == "" {
+			return nil, fmt.Errorf("--keystore-dir 需要配合 --password-file")
+		}
+		pw, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := keystore.OpenDir(keystoreDir, strings.TrimRight(string(pw), "\r\n"))
+		if err != nil {
+			return nil, err
+		}
+		for _, pk := range dir.Pubkeys() {
+			secret, err := dir.Secret(pk)
+			if err != nil {
+				return nil, fmt.Errorf("keystore %s: %w", pk, err)
+			}
+			members = append(members, validator.FleetMember{PrivHex: hex.EncodeToString(secret), WSURL: ws, HTTPURL: rpc})
+		}
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("-keys / -keystore-dir 中没有私钥")
+	}
+	return members, nil
+}
+
+// printFleetStats 每个验证者一行的统计
+func printFleetStats(stats []validator.MemberStats) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VALIDATOR\tRECEIVED\tPROCESSED\tATTESTED\tMISSED\tDROPPED\tMALFORMED\tDUPLICATE\tDOUBLE\tGAP\tRESTARTS\tERROR")
+	var total validator.MemberStats
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n", s.Name, s.Received, s.Processed, s.Attested,
+			s.MissedDeadline, s.Dropped, s.Malformed, s.Duplicate, s.DoubleAttest, s.Gap, s.Restarts, s.Err)
+		total.Received += s.Received
+		total.Processed += s.Processed
+		total.Attested += s.Attested
+		total.MissedDeadline += s.MissedDeadline
+		total.Dropped += s.Dropped
+		total.Malformed += s.Malformed
+		total.Duplicate += s.Duplicate
+		total.DoubleAttest += s.DoubleAttest
+		total.Gap += s.Gap
+		total.Restarts += s.Restarts
+	}
+	if len(stats) > 1 {
+		fmt.Fprintf(tw, "total(%d)\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n", len(stats), total.Received, total.Processed, total.Attested,
+			total.MissedDeadline, total.Dropped, total.Malformed, total.Duplicate, total.DoubleAttest, total.Gap, total.Restarts)
+	}
+	tw.Flush()
+}
@@ -3,30 +3,234 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/hex"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/daemon"
+	"n42-test/internal/envflag"
+	"n42-test/internal/keys"
+	"n42-test/internal/netprofile"
+	"n42-test/internal/receipts"
 	"n42-test/internal/validator"
 )
 
 func main() {
-	// 运行时输入 BLS 私钥
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("请输入 BLS 私钥 (hex): ")
-	priv, _ := reader.ReadString('\n')
-	priv = strings.TrimSpace(priv) // 去掉换行符
+	// 子命令：attestion-test selfcheck --from N --to M
+	if len(os.Args) > 1 && os.Args[1] == "selfcheck" {
+		if err := selfcheck(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	// 子命令：attestion-test sim --rate 2 --malformed 0.1（本地模拟验证请求服务器）
+	if len(os.Args) > 1 && os.Args[1] == "sim" {
+		if err := sim(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	slotSeconds := flag.Int("slot-seconds", validator.DefaultSecondsPerSlot, "每个 slot 的秒数（超过 slot 截止时间的区块查询会被放弃）")
+	queueSize := flag.Int("queue-size", validator.DefaultQueueSize, "推送积压队列容量（满了丢弃最旧的推送）")
+	queuePolicy := flag.String("queue-policy", string(validator.PolicyNewestFirst), "积压策略：newest-first|drop-oldest")
+	watchdogSlots := flag.Int("watchdog-slots", validator.DefaultWatchdogSlots, "超过多少个 slot 无推送且链仍在出块时强制重连（<0 关闭）")
+	wsURL := flag.String("ws", "ws://127.0.0.1:8546", "执行层 WS（订阅用）")
+	httpURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 HTTP RPC（查询区块哈希用）；逗号分隔多个地址时轮询并自动故障切换")
+	verifyInclusion := flag.Bool("verify-inclusion", false, "提交后在后续区块的信标状态中确认参与标记，记录包含距离")
+	inclusionWindow := flag.Int("inclusion-window", validator.DefaultInclusionWindow, "确认参与标记最多查看的后续区块数")
+	slotsPerEpoch := flag.Uint64("slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "每纪元 slot 数（参与标记按纪元重置）")
+	keystorePath := flag.String("keystore", "", "EIP-2335 keystore 文件；设置后不再交互输入私钥")
+	passwordFile := flag.String("password-file", "", "keystore 口令文件（配合 --keystore / --keystore-dir）")
+	keySpec := flag.String("key", "", "BLS 私钥（hex、\"le:\" 小端或 keystore 路径，同交互输入）；容器内可用 N42_ATTESTION_TEST_KEY 设置，免去交互输入")
+	profileName := flag.String("profile", netprofile.DefaultName, "网络配置档："+strings.Join(netprofile.Names(), "|"))
+	checkReceipts := flag.Bool("check-receipts-root", false, "按配置档的收据规则本地重算每个推送区块的 receipts_root 并比对")
+	emptyReceiptsRoot := flag.String("empty-receipts-root", "", "覆盖配置档的空区块 receipts_root")
+	receiptEncoding := flag.String("receipt-encoding", "", "覆盖配置档的收据编码：eip2718|legacy|wrapped")
+	receiptsSelfCheck := flag.Bool("receipts-selfcheck", false, "重算的 receipts_root 同时与 RPC 区块头比对，不一致时打印逐条收据诊断（隐含 -check-receipts-root）")
+	latencyOut := flag.String("latency-out", "", "把每个推送的分阶段耗时（push/queue/visibility/hash/receipts_fetch/root_compute/sign/submit）以 JSON Lines 写入该文件")
+	quarantineOut := flag.String("quarantine-out", "", "把解析失败的推送原文与错误以 JSON Lines 追加写入该文件（收集节点升级后的新负载形状）")
+	pidFile := flag.String("pid-file", "", "把进程 PID 写入该文件，退出时删除（交给 systemd/监控管理；文件指向的进程仍在运行时拒绝启动）")
+	progressFile := flag.String("progress-file", "", "每个私钥最近一次成功签名提交的区块号追加记入该文件（JSON Lines）；重启后不高于它的推送不再转发给二进制（需设置 WS 地址）")
+	gapCheckBlocks := flag.Int("gap-check-blocks", validator.DefaultGapCheckBlocks, "每次（重新）订阅成功后经 HTTP 最多检查多少个漏推的区块，只检查与记数、不签名（<=0 不检查）")
+	keyList := flag.String("keys", "", "多个 BLS 私钥，逗号分隔（格式同 -key）：同一进程内每个私钥各自订阅、签名提交，结束时按私钥打印统计")
+	keystoreDir := flag.String("keystore-dir", "", "EIP-2335 keystore 目录（配合 -password-file）：目录下每个 keystore 一个验证者，可与 -keys 同用；SIGHUP 时重新扫描")
+	configPath := flag.String("config", "", "多验证者配置（JSON：validators 列表及各自的 key/keystore、ws/rpc）；每个验证者一个进程，收到 SIGHUP 时重新读取并只启停有变化的验证者")
+	headerSchema := flag.String("schema", validator.SchemaAuto, "推送区块头形状："+strings.Join(validator.SchemaNames(), "|")+"（auto 按推送自动协商，分叉激活时自动切换）")
+	envflag.Parse("attestion-test")
+
+	policy, err := validator.ParseQueuePolicy(*queuePolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *headerSchema != validator.SchemaAuto {
+		if _, err := validator.LookupHeaderSchema(*headerSchema); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	multiKey := *keyList != "" || *keystoreDir != ""
+	if *configPath != "" && (*keystorePath != "" || *keySpec != "" || multiKey) {
+		log.Fatal("-config 与 -keystore/-key/-keys/-keystore-dir 不能同时使用（在配置文件中为每个验证者指定 key/keystore）")
+	}
+	if multiKey && (*keystorePath != "" || *keySpec != "") {
+		log.Fatal("-keys/-keystore-dir 与 -keystore/-key 不能同时使用")
+	}
+	if *keystorePath != "" && *keySpec != "" {
+		log.Fatal("-keystore 与 -key 只能指定一个")
+	}
+
+	var priv string
+	switch {
+	case *configPath != "", multiKey:
+		// 私钥来自配置文件 / -keys / -keystore-dir
+	case *keystorePath != "":
+		priv, err = loadKeystore(*keystorePath, *passwordFile)
+		if err != nil {
+			log.Fatalf("读取 keystore 失败: %v", err)
+		}
+	case *keySpec != "":
+		if priv, err = blsKeyHex(strings.TrimSpace(*keySpec), ""); err != nil {
+			log.Fatalf("解析 -key 失败: %v", err)
+		}
+	default:
+		// 运行时输入 BLS 私钥
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Print("请输入 BLS 私钥 (hex): ")
+		priv, _ = reader.ReadString('\n')
+		priv = strings.TrimSpace(priv) // 去掉换行符
+		if priv == "" {
+			log.Fatal("必须输入私钥！")
+		}
+		// 交互输入也可以是 keystore 路径/JSON 或 "le:" 前缀的小端私钥；统一转成大端 hex 交给 attest
+		if priv, err = blsKeyHex(priv, ""); err != nil {
+			log.Fatalf("解析 BLS 私钥失败: %v", err)
+		}
+	}
 
-	if priv == "" {
-		log.Fatal("必须输入私钥！")
+	profile, err := netprofile.Lookup(*profileName)
+	if err != nil {
+		log.Fatal(err)
 	}
+	var rules *receipts.Rules
+	if *checkReceipts || *receiptsSelfCheck {
+		rr, err := receipts.RulesFromProfile(profile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := rr.Override(*emptyReceiptsRoot, *receiptEncoding); err != nil {
+			log.Fatal(err)
+		}
+		rules = &rr
+	}
+
+	cfg := validator.StreamConfig{
+		SecondsPerSlot: *slotSeconds,
+		QueueSize:      *queueSize,
+		QueuePolicy:    policy,
+		WatchdogSlots:  *watchdogSlots,
+
+		VerifyInclusion: *verifyInclusion,
+		InclusionWindow: *inclusionWindow,
+		SlotsPerEpoch:   *slotsPerEpoch,
+
+		ReceiptRules:     rules,
+		ReceiptSelfCheck: *receiptsSelfCheck,
 
-	// RPC URL 你可以写死，或者也提示输入
-	rpcURL := "ws://127.0.0.1:8546"
-	httpURL := "http://127.0.0.1:8545"
+		HeaderSchema: *headerSchema,
 
-	if err := validator.ValidateStreamFiltered(context.Background(), priv, rpcURL, httpURL); err != nil {
+		GapCheckBlocks: *gapCheckBlocks,
+	}
+	if *gapCheckBlocks == 0 {
+		cfg.GapCheckBlocks = -1
+	}
+	if *progressFile != "" {
+		p, err := validator.OpenProgress(*progressFile)
+		if err != nil {
+			log.Fatalf("打开进度文件 %s 失败: %v", *progressFile, err)
+		}
+		defer p.Close()
+		cfg.Progress = p
+	}
+	if *latencyOut != "" {
+		f, err := os.Create(*latencyOut)
+		if err != nil {
+			log.Fatalf("创建 %s 失败: %v", *latencyOut, err)
+		}
+		defer f.Close()
+		cfg.LatencyOut = f
+	}
+	if *quarantineOut != "" {
+		f, err := os.OpenFile(*quarantineOut, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatalf("打开 %s 失败: %v", *quarantineOut, err)
+		}
+		defer f.Close()
+		cfg.QuarantineOut = f
+	}
+
+	// SIGINT/SIGTERM：停止二进制、打印统计并落盘后正常退出（systemd stop 不算失败）
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	d, err := daemon.Start(ctx, *pidFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch {
+	case *configPath != "":
+		err = runFleet(ctx, d, *configPath, func() ([]validator.FleetMember, error) {
+			return loadFleet(*configPath, *wsURL, *httpURL)
+		}, cfg)
+	case multiKey:
+		err = runFleet(ctx, d, "-keys/-keystore-dir", func() ([]validator.FleetMember, error) {
+			return loadKeys(*keyList, *keystoreDir, *passwordFile, *wsURL, *httpURL)
+		}, cfg)
+	default:
+		var once sync.Once
+		cfg.OnSubscribed = func() {
+			once.Do(func() { d.Ready("subscribed") })
+			d.Status("subscribed at " + time.Now().Format(time.RFC3339))
+		}
+		err = validator.ValidateStreamFilteredWithConfig(ctx, priv, *wsURL, *httpURL, cfg)
+		if ctx.Err() != nil {
+			err = nil
+		}
+	}
+	d.Close()
+	if err != nil {
 		log.Fatalf("validate run error: %v", err)
 	}
 }
+
+// loadKeystore 解密 keystore，返回大端私钥 hex（与 JSON 中 validator-private-key 的格式一致）
+func loadKeystore(path, passwordFile string) (string, error) {
+	if passwordFile == "" {
+		return "", fmt.Errorf("--keystore 需要配合 --password-file")
+	}
+	pw, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return "", err
+	}
+	return blsKeyHex(path, strings.TrimRight(string(pw), "\r\n"))
+}
+
+// blsKeyHex 按 internal/keys 识别格式解析 BLS 私钥，返回大端 hex；password 为空时取环境变量
+func blsKeyHex(spec, password string) (string, error) {
+	raw, littleEndian, err := keys.ParseBLSSecretWith(spec, false, keys.Options{Password: password})
+	if err != nil {
+		return "", err
+	}
+	if littleEndian {
+		slices.Reverse(raw)
+	}
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"n42-test/internal/envflag"
+	"n42-test/internal/netprofile"
+	"n42-test/internal/receipts"
+)
+
+// selfcheckDump 不一致区块的诊断转储
+type selfcheckDump struct {
+	Number   uint64          `json:"number"`
+	Hash     string          `json:"hash"`
+	Header   string          `json:"receipts_root_header"`
+	Computed string          `json:"receipts_root_computed"`
+	Rules    string          `json:"rules"`
+	TxCount  int             `json:"tx_count"`
+	FastPath bool            `json:"fast_path"`
+	Receipts []receipts.Diag `json:"receipts"`
+}
+
+// selfcheck 对历史区块区间重算 receipts_root 并与区块头比对，
+// 用于在信任实时见证之前验证收据根的实现与配置档规则。
+func selfcheck(args []string) error {
+	fs := flag.NewFlagSet("attestion-test selfcheck", flag.ExitOnError)
+	rpcURL := fs.String("rpc", "http://127.0.0.1:8545", "执行层 HTTP RPC")
+	from := fs.Uint64("from", 0, "起始块号（含）")
+	to := fs.Uint64("to", 0, "结束块号（含）；0 表示最新块")
+	workers := fs.Int("workers", 4, "并发查询数")
+	profileName := fs.String("profile", netprofile.DefaultName, "网络配置档："+strings.Join(netprofile.Names(), "|"))
+	emptyReceiptsRoot := fs.String("empty-receipts-root", "", "覆盖配置档的空区块 receipts_root")
+	receiptEncoding := fs.String("receipt-encoding", "", "覆盖配置档的收据编码：eip2718|legacy|wrapped")
+	dumpDir := fs.String("dump-dir", "", "不一致区块的诊断转储目录（每块一个 JSON）；为空只打印到终端")
+	envflag.ParseSet(fs, "", args)
+
+	profile, err := netprofile.Lookup(*profileName)
+	if err != nil {
+		return err
+	}
+	rules, err := receipts.RulesFromProfile(profile)
+	if err != nil {
+		return err
+	}
+	if err := rules.Override(*emptyReceiptsRoot, *receiptEncoding); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	f, err := receipts.Dial(ctx, *rpcURL)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	end := *to
+	if end == 0 {
+		if end, err = f.Latest(ctx); err != nil {
+			return err
+		}
+	}
+	if *from > end {
+		return fmt.Errorf("-from %d 大于 -to %d", *from, end)
+	}
+	if *dumpDir != "" {
+		if err := os.MkdirAll(*dumpDir, 0o755); err != nil {
+			return err
+		}
+	}
+	if *workers <= 0 {
+		*workers = 1
+	}
+	fmt.Printf("selfcheck blocks %d..%d via %s (%s)\n", *from, end, *rpcURL, rules)
+
+	var (
+		mu                          sync.Mutex
+		checked, fastPath, mismatch int
+		failed                      []string
+		mismatched                  []uint64
+	)
+	blocks := make(chan uint64)
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range blocks {
+				res, err := f.Compute(ctx, rules, n)
+				mu.Lock()
+				switch {
+				case err != nil:
+					failed = append(failed, fmt.Sprintf("#%d: %v", n, err))
+				case res.Match():
+					checked++
+					if res.FastPath {
+						fastPath++
+					}
+				default:
+					checked++
+					mismatch++
+					mismatched = append(mismatched, n)
+					fmt.Println(res.Validate(rules))
+					if *dumpDir != "" {
+						if err := writeSelfcheckDump(*dumpDir, rules, res); err != nil {
+							fmt.Fprintf(os.Stderr, "写诊断转储失败: %v\n", err)
+						}
+					}
+				}
+				if done := checked + len(failed); done%100 == 0 {
+					fmt.Printf("progress: %d/%d\n", done, end-*from+1)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for n := *from; n <= end; n++ {
+		select {
+		case <-ctx.Done():
+			break feed
+		case blocks <- n:
+		}
+	}
+	close(blocks)
+	wg.Wait()
+
+	for _, e := range failed {
+		fmt.Fprintln(os.Stderr, "failed", e)
+	}
+	fmt.Printf("checked=%d fast_path=%d mismatch=%d failed=%d\n", checked, fastPath, mismatch, len(failed))
+	if mismatch > 0 {
+		slices.Sort(mismatched)
+		return fmt.Errorf("receipts_root 不一致的区块: %v", mismatched)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d 个区块查询失败", len(failed))
+	}
+	return ctx.Err()
+}
+
+func writeSelfcheckDump(dir string, rules receipts.Rules, res *receipts.Result) error {
+	d := selfcheckDump{
+		Number:   res.Number,
+		Header:   res.Header.Hex(),
+		Computed: res.Computed.Hex(),
+		Rules:    rules.String(),
+		TxCount:  res.TxCount,
+		FastPath: res.FastPath,
+	}
+	if res.Block != nil {
+		d.Hash = res.Block.Hash.Hex()
+		d.Receipts = receipts.Diagnose(rules, res.Block)
+	}
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, fmt.Sprintf("block-%d.json", res.Number)), b, 0o644)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/daemon"
+	"n42-test/internal/envflag"
+	"n42-test/internal/verifysim"
+)
+
+// sim 启动本地模拟验证请求服务器：合成区块经 WS 订阅推送，同一端口应答 HTTP eth_ 查询并接受提交，
+// 用于在没有共识节点时调试、压测 attest 运行器
+func sim(args []string) error {
+	fs := flag.NewFlagSet("attestion-test sim", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8546", "监听地址（WS 与 HTTP JSON-RPC 共用）")
+	rate := fs.Float64("rate", verifysim.DefaultRate, "每秒推送的区块数")
+	count := fs.Int("count", 0, "推送的区块数上限；0 表示直到中断")
+	shapes := fs.String("shapes", strings.Join(verifysim.ShapeNames(), ","), "随机选用的区块形状："+strings.Join(verifysim.ShapeNames(), "|")+"，逗号分隔")
+	maxTxs := fs.Int("max-txs", verifysim.DefaultMaxTxs, "transfers/logs 形状每块最多交易数")
+	malformed := fs.Float64("malformed", 0, "推送替换为畸形负载的比例（0~1）")
+	malformedKinds := fs.String("malformed-kinds", strings.Join(verifysim.MalformedKinds(), ","), "畸形负载种类："+strings.Join(verifysim.MalformedKinds(), "|")+"，逗号分隔")
+	chainID := fs.Uint64("chain-id", verifysim.DefaultChainID, "合成链的 chainId")
+	emptyReceiptsRoot := fs.String("empty-receipts-root", "", "空区块的 receipts_root（与被测配置档一致）；为空用以太坊空根")
+	seed := fs.Int64("seed", 0, "随机种子（复现同一序列的形状与畸形推送）；0 表示按时间")
+	statsEvery := fs.Duration("stats-interval", 10*time.Second, "定期打印统计的间隔；0 关闭")
+	linger := fs.Duration("linger", 10*time.Second, "达到 -count 后继续接受提交的时间")
+	pidFile := fs.String("pid-file", "", "把进程 PID 写入该文件，退出时删除（交给 systemd/监控管理）")
+	envflag.ParseSet(fs, "", args)
+
+	cfg := verifysim.Config{
+		Rate: *rate, Count: *count, Shapes: splitList(*shapes), MaxTxs: *maxTxs,
+		MalformedRate: *malformed, MalformedKinds: splitList(*malformedKinds),
+		ChainID: *chainID, Seed: *seed,
+	}
+	if *emptyReceiptsRoot != "" {
+		if !strings.HasPrefix(*emptyReceiptsRoot, "0x") || len(*emptyReceiptsRoot) != 66 {
+			return fmt.Errorf("-empty-receipts-root 必须是 0x 开头的 32 字节 hex: %q", *emptyReceiptsRoot)
+		}
+		cfg.EmptyReceiptsRoot = common.HexToHash(*emptyReceiptsRoot)
+	}
+	srv, err := verifysim.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	hs := &http.Server{Handler: srv}
+	go hs.Serve(ln)
+	defer hs.Close()
+	addr := ln.Addr().String()
+	log.Printf("sim: listening on ws://%s (HTTP JSON-RPC on http://%s)", addr, addr)
+	log.Printf("sim: run the attester against it with: attestion-test -ws ws://%s -rpc http://%s", addr, addr)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	d, err := daemon.Start(ctx, *pidFile)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	d.Ready("listening on " + addr)
+	if *statsEvery > 0 {
+		go func() {
+			t := time.NewTicker(*statsEvery)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					log.Printf("sim: %s", srv.Stats())
+					d.Status(srv.Stats().String())
+				}
+			}
+		}()
+	}
+
+	runErr := srv.Run(ctx)
+	if runErr == nil && ctx.Err() == nil && *linger > 0 {
+		log.Printf("sim: %d blocks pushed, accepting submissions for another %s", *count, *linger)
+		select {
+		case <-ctx.Done():
+		case <-time.After(*linger):
+		}
+	}
+	srv.Close()
+	log.Printf("sim: done: %s", srv.Stats())
+	if runErr != nil && !errors.Is(runErr, context.Canceled) {
+		return runErr
+	}
+	return nil
+}
+
+// splitList 逗号分隔的列表，去掉空白与空项
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/envflag"
+)
+
+// diff 子命令：对比两个 eth1 区块对应的信标状态
+func diff(args []string) error {
+	fs := flag.NewFlagSet("beacon-state diff", flag.ExitOnError)
+	rpcURL := fs.String("rpc", "http://127.0.0.1:8545", "执行层 RPC（consensusBeaconExt 查询）")
+	from := fs.String("from", "", "之前的 eth1 区块哈希")
+	to := fs.String("to", "", "之后的 eth1 区块哈希；为空取 latest")
+	fromFile := fs.String("from-file", "", "之前的状态改为读取本地 JSON（如 beacon_state.json），优先于 -from")
+	toFile := fs.String("to-file", "", "之后的状态改为读取本地 JSON，优先于 -to")
+	slotsPerEpoch := fs.Uint64("slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "每纪元 slot 数（按 slot 推算纪元与验证者状态）")
+	top := fs.Int("top", 20, "余额变化只列绝对值最大的前 N 个（0=全部；-json 始终输出全部）")
+	asJSON := fs.Bool("json", false, "以 JSON 输出")
+	timeout := fs.Duration("timeout", 5*time.Minute, "读取两份状态的总超时")
+	envflag.ParseSet(fs, "", args)
+
+	if *from == "" && *fromFile == "" {
+		return errors.New("需要 -from（eth1 区块哈希）或 -from-file")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	c := beaconext.NewClient(*rpcURL)
+
+	a, err := diffSide(ctx, c, *from, *fromFile)
+	if err != nil {
+		return fmt.Errorf("读取之前的状态: %w", err)
+	}
+	b, err := diffSide(ctx, c, *to, *toFile)
+	if err != nil {
+		return fmt.Errorf("读取之后的状态: %w", err)
+	}
+	if b.Slot < a.Slot {
+		fmt.Fprintf(os.Stderr, "⚠️ 之后的状态 slot %d 早于之前的 slot %d，-from / -to 是否写反了？\n", b.Slot, a.Slot)
+	}
+
+	d := beaconstate.DiffStates(a, b, *slotsPerEpoch)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	}
+	d.Print(os.Stdout, *top)
+	if d.Empty() {
+		fmt.Println("两份状态之间没有变化")
+	}
+	return nil
+}
+
+// diffSide 读取一侧的状态：本地文件优先，其次 eth1 区块哈希，都为空时取 latest
+func diffSide(ctx context.Context, c *beaconext.Client, eth1Hash, file string) (*beaconstate.State, error) {
+	switch {
+	case file != "":
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return beaconstate.ParseStream(f)
+	case eth1Hash != "":
+		if !looksLikeHash(eth1Hash) {
+			return nil, fmt.Errorf("%q 不是合法的 0x… 区块哈希", eth1Hash)
+		}
+		return beaconstate.FetchAt(ctx, c, eth1Hash)
+	default:
+		return beaconstate.FetchLatest(ctx, c)
+	}
+}
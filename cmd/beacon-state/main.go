@@ -4,44 +4,143 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"n42-test/internal/beaconext" // ← 按你的实际 module 路径修改
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/capability"
+	"n42-test/internal/envflag"
+)
+
+// tableOptions 模式 1 的验证者状态表
+type tableOptions struct {
+	sortKey       string
+	desc          bool
+	top           int
+	slotsPerEpoch uint64
+	raw           bool
+	pubkeys       []string        // 只显示这些公钥的验证者（为空不过滤）
+	statuses      map[string]bool // 只显示这些状态的验证者（为空不过滤）
+}
+
+// -query-target 的取值
+const (
+	queryState = "state"
+	queryBlock = "block"
 )
 
 func main() {
-	// 读模式参数
-	mode := readMode()
+	// 子命令：diff 对比两个 eth1 区块对应的信标状态
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if err := diff(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	// RPC 地址
-	rpc := os.Getenv("RPC_URL")
-	if rpc == "" {
-		rpc = "http://127.0.0.1:8545"
+	defRPC := os.Getenv("RPC_URL")
+	if defRPC == "" {
+		defRPC = "http://127.0.0.1:8545"
+	}
+	rpcFlag := flag.String("rpc", defRPC, "执行层 RPC（默认取环境变量 RPC_URL）")
+	var opts tableOptions
+	flag.StringVar(&opts.sortKey, "sort", beaconstate.SortIndex, "模式 1 状态表的排序键："+strings.Join(beaconstate.SortKeys(), "|"))
+	flag.BoolVar(&opts.desc, "desc", false, "模式 1 降序排列")
+	flag.IntVar(&opts.top, "top", 0, "模式 1 只显示排序后的前 N 个验证者（0=全部）")
+	flag.Uint64Var(&opts.slotsPerEpoch, "slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "每纪元 slot 数（按 slot 推算当前纪元与验证者状态）")
+	flag.BoolVar(&opts.raw, "raw", false, "模式 1 原样输出 validators + balances 数组，不计算状态表")
+	pubkeyFilter := flag.String("pubkey", "", "模式 1 只显示这些公钥的验证者（逗号分隔）")
+	statusFilter := flag.String("status", "", "模式 1 只显示这些状态的验证者（逗号分隔）："+strings.Join(beaconstate.Statuses(), "|"))
+	queryExpr := flag.String("query", "", "jq 表达式（gojq，如 '.validators[0]'、'.balances | length'）：不再选择模式，对输入哈希的信标状态执行，结果 JSON 写到标准输出、提示改写到标准错误；以 .字段 开头时流式只读该字段")
+	queryTarget := flag.String("query-target", queryState, "-query 的输入："+queryState+"|"+queryBlock)
+	envflag.Parse("beacon-state")
+	if err := beaconstate.SortValidatorRows(nil, opts.sortKey, false); err != nil {
+		log.Fatal(err)
+	}
+	opts.pubkeys = splitList(*pubkeyFilter)
+	if list := splitList(*statusFilter); len(list) > 0 {
+		opts.statuses = map[string]bool{}
+		known := beaconstate.Statuses()
+		for _, st := range list {
+			if !slices.Contains(known, st) {
+				log.Fatalf("未知的 -status %q（%s）", st, strings.Join(known, "|"))
+			}
+			opts.statuses[st] = true
+		}
 	}
+	var query *beaconext.Query
+	if *queryExpr != "" {
+		q, err := beaconext.CompileQuery(*queryExpr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *queryTarget != queryState && *queryTarget != queryBlock {
+			log.Fatalf("未知的 -query-target %q（%s|%s）", *queryTarget, queryState, queryBlock)
+		}
+		query = q
+	}
+
+	// 提示信息；-query 时标准输出只留查询结果，便于重定向
+	var msg io.Writer = os.Stdout
+	mode := 0
+	if query != nil {
+		msg = os.Stderr
+	} else {
+		// 读模式参数
+		mode = readMode()
+	}
+
+	// RPC 地址
+	rpc := *rpcFlag
 	c := beaconext.NewClient(rpc)
 
 	in := bufio.NewReader(os.Stdin)
-	fmt.Printf("已连接执行层 RPC: %s\n", rpc)
-	fmt.Println("输入 eth1 区块哈希（0x + 64位hex），回车查询；输入 q 回车退出。")
+	fmt.Fprintf(msg, "已连接执行层 RPC: %s\n", rpc)
+	warnUnsupported(msg, rpc)
+	fmt.Fprintln(msg, "输入 eth1 区块哈希（0x + 64位hex），回车查询；输入 q 回车退出。")
 
 	for {
-		fmt.Print("\n请输入 eth1 区块哈希(输入q退出)：")
-		line, _ := in.ReadString('\n')
+		fmt.Fprint(msg, "\n请输入 eth1 区块哈希(输入q退出)：")
+		line, readErr := in.ReadString('\n')
 		eth1Hash := strings.TrimSpace(line)
 
 		if eth1Hash == "" {
-			fmt.Println("⚠️ 不能为空，请重新输入。")
+			if readErr != nil {
+				// 标准输入已结束（如 echo 0x… | beacon-state -query …）
+				fmt.Fprintln(msg)
+				return
+			}
+			fmt.Fprintln(msg, "⚠️ 不能为空，请重新输入。")
 			continue
 		}
 		if eth1Hash == "q" || eth1Hash == "Q" {
-			fmt.Println("已退出。")
+			fmt.Fprintln(msg, "已退出。")
 			return
 		}
 		if !looksLikeHash(eth1Hash) {
-			fmt.Println("⚠️ 似乎不是合法的 0x… 区块哈希（期望长度 66）。仍然尝试查询……")
+			fmt.Fprintln(msg, "⚠️ 似乎不是合法的 0x… 区块哈希（期望长度 66）。仍然尝试查询……")
+		}
+
+		if query != nil {
+			if err := runQuery(c, eth1Hash, query, *queryTarget); err != nil {
+				fmt.Fprintf(msg, "❌ 查询失败：%v\n", err)
+			}
+			continue
+		}
+
+		if mode == 1 {
+			// 仅输出 Beacon State 的 validators + balances：流式解析，不缓存整个状态
+			if err := printValidatorsAndBalances(c, eth1Hash, opts); err != nil {
+				fmt.Printf("❌ 查询失败：%v\n", err)
+			}
+			continue
 		}
 
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
@@ -55,29 +154,13 @@ func main() {
 		// 通用头部
 		fmt.Println("eth1 hash        :", snap.Eth1Hash)
 		fmt.Println("beacon block hash:", snap.BeaconBlockHash)
+		printBlockHeader(snap.BeaconBlockRaw)
 
 		switch mode {
 		case 0:
 			// 全部输出
 			beaconext.PrettyPrintJSON("Beacon Block", snap.BeaconBlockRaw)
 			beaconext.PrettyPrintJSON("Beacon State", snap.BeaconStateRaw)
-		case 1:
-			// 仅输出 Beacon State 的 validators + balances
-			var state struct {
-				Validators []map[string]any `json:"validators"`
-				Balances   []uint64         `json:"balances"`
-			}
-			if err := json.Unmarshal(snap.BeaconStateRaw, &state); err != nil {
-				fmt.Printf("❌ 解析 Beacon State 失败：%v\n", err)
-				continue
-			}
-			partial := map[string]any{
-				"validators": state.Validators,
-				"balances":   state.Balances,
-			}
-			bs, _ := json.MarshalIndent(partial, "", "  ")
-			fmt.Println("Beacon State（仅 validators + balances）：")
-			fmt.Println(string(bs))
 		default:
 			fmt.Println("⚠️ 未知模式，使用 0（全部）作为回退。")
 			beaconext.PrettyPrintJSON("Beacon Block", snap.BeaconBlockRaw)
@@ -86,11 +169,180 @@ func main() {
 	}
 }
 
+// runQuery 对 eth1 区块哈希对应的信标状态（或区块）执行 -query，结果写到标准输出
+func runQuery(c *beaconext.Client, eth1Hash string, q *beaconext.Query, target string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	beaconHash, err := c.GetBeaconBlockHashByEth1Hash(ctx, eth1Hash)
+	if err != nil {
+		return fmt.Errorf("map eth1 hash -> beacon block hash: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "beacon block hash:", beaconHash)
+
+	var results []any
+	if target == queryBlock {
+		blk, err := c.GetBeaconBlockByHash(ctx, beaconHash)
+		if err != nil {
+			return fmt.Errorf("get beacon block by hash: %w", err)
+		}
+		results, err = q.RunJSON(ctx, blk)
+		if err != nil {
+			return err
+		}
+	} else {
+		if results, err = q.RunState(ctx, c, beaconHash); err != nil {
+			return err
+		}
+	}
+	return beaconext.WriteQueryResults(os.Stdout, results)
+}
+
+// printValidatorsAndBalances 流式读取状态，只取 validators + balances，输出验证者状态表（-raw 时原样输出数组）
+func printValidatorsAndBalances(c *beaconext.Client, eth1Hash string, opts tableOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	beaconHash, err := c.GetBeaconBlockHashByEth1Hash(ctx, eth1Hash)
+	if err != nil {
+		return fmt.Errorf("map eth1 hash -> beacon block hash: %w", err)
+	}
+	fmt.Println("eth1 hash        :", eth1Hash)
+	fmt.Println("beacon block hash:", beaconHash)
+
+	if !opts.raw {
+		return printValidatorTable(ctx, c, beaconHash, opts)
+	}
+
+	// 元素保留原始 JSON，避免 FAR_FUTURE_EPOCH 等大整数经 float64 失真
+	var partial struct {
+		Validators []json.RawMessage `json:"validators"`
+		Balances   []json.RawMessage `json:"balances"`
+	}
+	err = c.StreamBeaconStateByBeaconBlockHash(ctx, beaconHash, func(key string, dec *json.Decoder) (bool, error) {
+		switch key {
+		case "validators":
+			return true, dec.Decode(&partial.Validators)
+		case "balances":
+			return true, dec.Decode(&partial.Balances)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	bs, _ := json.Marshal(partial)
+	beaconext.PrettyPrintJSON("Beacon State（仅 validators + balances）", bs)
+	return nil
+}
+
+// printValidatorTable 按状态所在纪元计算每个验证者的状态，排序、截取后以表格输出
+func printValidatorTable(ctx context.Context, c *beaconext.Client, beaconHash string, opts tableOptions) error {
+	var st beaconstate.State
+	err := c.StreamBeaconStateByBeaconBlockHash(ctx, beaconHash, func(key string, dec *json.Decoder) (bool, error) {
+		switch key {
+		case "slot", "validators", "balances":
+			return st.DecodeField(key, dec)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	epoch := st.Epoch(opts.slotsPerEpoch)
+	total := len(st.Validators)
+	rows := filterRows(&st, st.ValidatorRows(epoch), opts)
+	if err := beaconstate.SortValidatorRows(rows, opts.sortKey, opts.desc); err != nil {
+		return err
+	}
+	shown := rows
+	if opts.top > 0 && opts.top < len(rows) {
+		shown = rows[:opts.top]
+	}
+	fmt.Printf("slot %d（epoch %d），验证者 %d 个", st.Slot, epoch, total)
+	if len(rows) < total {
+		fmt.Printf("，过滤后 %d 个", len(rows))
+	}
+	if len(shown) < len(rows) {
+		order := "升序"
+		if opts.desc {
+			order = "降序"
+		}
+		fmt.Printf("，按 %s %s显示前 %d 个", opts.sortKey, order, len(shown))
+	}
+	fmt.Println()
+	beaconstate.PrintValidatorTable(os.Stdout, shown)
+	return nil
+}
+
+// filterRows 按 -pubkey / -status 过滤状态表；找不到的公钥逐个提示
+func filterRows(st *beaconstate.State, rows []beaconstate.ValidatorRow, opts tableOptions) []beaconstate.ValidatorRow {
+	if len(opts.pubkeys) > 0 {
+		picked := make([]beaconstate.ValidatorRow, 0, len(opts.pubkeys))
+		for _, pk := range opts.pubkeys {
+			if _, i, ok := st.ValidatorByPubkey(pk); ok {
+				picked = append(picked, rows[i])
+			} else {
+				fmt.Printf("⚠️ 状态中没有公钥 %s\n", pk)
+			}
+		}
+		rows = picked
+	}
+	if len(opts.statuses) > 0 {
+		kept := rows[:0:0]
+		for _, r := range rows {
+			if opts.statuses[r.Status] {
+				kept = append(kept, r)
+			}
+		}
+		rows = kept
+	}
+	return rows
+}
+
+// printBlockHeader 打印信标区块的类型化摘要（slot、提议者、父根 / 状态根、执行层区块）；解析失败时只提示
+func printBlockHeader(raw json.RawMessage) {
+	b, err := beaconstate.ParseBlock(raw)
+	if err != nil {
+		fmt.Printf("⚠️ 信标区块摘要解析失败：%v\n", err)
+		return
+	}
+	fmt.Printf("slot             : %d\n", b.Slot)
+	fmt.Printf("proposer index   : %d\n", b.ProposerIndex)
+	fmt.Println("parent root      :", b.ParentRoot)
+	fmt.Println("state root       :", b.StateRoot)
+	if p := b.ExecutionPayload; p != nil {
+		fmt.Printf("execution block  : %d %s\n", p.BlockNumber, p.BlockHash)
+	}
+	if d := b.Eth1Data; d != nil {
+		fmt.Printf("eth1_data        : deposit_count=%d deposit_root=%s\n", d.DepositCount, d.DepositRoot)
+	}
+}
+
+// splitList 逗号分隔的列表，去掉空白与空项
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
 // 读取模式：0=全部；1=仅 state.validators+balances
 func readMode() int {
+	// N42_BEACON_STATE_MODE 预设时不再交互选择
+	switch m := envflag.String("beacon-state", "mode", ""); m {
+	case "0", "1":
+		return int(m[0] - '0')
+	case "":
+	default:
+		fmt.Printf("⚠️ 忽略 %s=%q（只能为 0 或 1）\n", envflag.Name("beacon-state", "mode"), m)
+	}
 	in := bufio.NewReader(os.Stdin)
 	for {
-		fmt.Print("请选择输出模式（0=全部，1=仅state.validators+balances）：")
+		fmt.Print("请选择输出模式（0=全部，1=验证者状态表（validators+balances））：")
 		line, _ := in.ReadString('\n')
 		s := strings.TrimSpace(line)
 		switch s {
@@ -116,3 +368,16 @@ func looksLikeHash(s string) bool {
 	}
 	return true
 }
+
+// warnUnsupported 若 version --probe 缓存过该节点的能力矩阵，提前提示缺失的 beaconext 方法
+func warnUnsupported(w io.Writer, rpc string) {
+	m, ok := capability.Load(capability.DefaultCachePath(), rpc, capability.DefaultMaxAge)
+	if !ok {
+		return
+	}
+	for _, method := range capability.BeaconExtMethods {
+		if !m.Supports(method) {
+			fmt.Fprintf(w, "⚠️ 节点不支持 %s（探测于 %s），查询可能失败\n", method, m.ProbedAt.Format(time.RFC3339))
+		}
+	}
+}
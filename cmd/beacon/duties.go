@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/daemon"
+	"n42-test/internal/envflag"
+)
+
+func duties(args []string) error {
+	fs := flag.NewFlagSet("beacon duties", flag.ExitOnError)
+	rpcURL := fs.String("rpc", "http://127.0.0.1:8545", "执行层 RPC（consensusBeaconExt 查询）")
+	eth1Hash := fs.String("eth1-hash", "", "按该执行层区块哈希取状态；为空取 latest（-watch 时忽略）")
+	file := fs.String("file", "", "直接读取本地状态 JSON（如 beacon_state.json），不访问节点")
+	pubkeysFlag := fs.String("pubkeys", "", "跟踪的验证者公钥，逗号分隔")
+	jsonPath := fs.String("deposit-json", "", "从 deposit 数据 JSON 读取 validator-public-key 作为跟踪公钥")
+	watch := fs.Bool("watch", false, "持续轮询，每个纪元结束时报告预期职责与实际参与标记")
+	poll := fs.Duration("poll", 6*time.Second, "-watch 轮询间隔")
+	asJSON := fs.Bool("json", false, "以 JSON 输出职责（非 -watch）")
+	pidFile := fs.String("pid-file", "", "-watch 时把进程 PID 写入该文件，退出时删除（交给 systemd/监控管理）")
+	envflag.ParseSet(fs, "", args)
+
+	pubkeys, err := trackedPubkeys(*pubkeysFlag, *jsonPath)
+	if err != nil {
+		return err
+	}
+	if len(pubkeys) == 0 {
+		return errors.New("需要 -pubkeys 或 -deposit-json")
+	}
+
+	if *watch {
+		if *file != "" {
+			return errors.New("-watch 不能与 -file 同时使用")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		d, err := daemon.Start(ctx, *pidFile)
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+		return watchDuties(ctx, d, beaconext.NewClient(*rpcURL), pubkeys, *poll)
+	}
+
+	st, err := loadState(*rpcURL, *eth1Hash, *file)
+	if err != nil {
+		return err
+	}
+	ds, missing := st.Duties(pubkeys)
+	for _, pk := range missing {
+		fmt.Fprintf(os.Stderr, "不在验证者集合中: %s\n", pk)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ds)
+	}
+	fmt.Printf("状态 slot %d，%d 个公钥，%d 条职责\n", st.Slot, len(pubkeys), len(ds))
+	printDuties(ds)
+	return nil
+}
+
+// trackedPubkeys 合并 -pubkeys 与 -deposit-json 里的公钥
+func trackedPubkeys(list, jsonPath string) ([]string, error) {
+	var out []string
+	for _, pk := range strings.Split(list, ",") {
+		if pk = strings.TrimSpace(pk); pk != "" {
+			out = append(out, pk)
+		}
+	}
+	if jsonPath != "" {
+		raw, err := os.ReadFile(jsonPath)
+		if err != nil {
+			return nil, err
+		}
+		var items []struct {
+			ValidatorPublicKey string `json:"validator-public-key"`
+		}
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", jsonPath, err)
+		}
+		for _, it := range items {
+			if it.ValidatorPublicKey != "" {
+				out = append(out, it.ValidatorPublicKey)
+			}
+		}
+	}
+	return out, nil
+}
+
+func printDuties(ds []beaconstate.Duty) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EPOCH\tSLOT\tCOMMITTEE\tPOSITION\tINDEX\tPUBKEY")
+	for _, d := range ds {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d/%d\t%d\t%s\n", d.Epoch, d.Slot, d.CommitteeIndex, d.CommitteePosition, d.CommitteeSize, d.ValidatorIndex, d.Pubkey)
+	}
+	tw.Flush()
+}
+
+// watchDuties 每次轮询取 latest 状态：新纪元的职责提前打印（便于预热连接），
+// 纪元切换时用上一纪元最后一次看到的参与标记对比该纪元的预期职责。
+// 第一次读到状态后向 systemd 报告就绪，之后每次轮询更新状态行；SIGINT/SIGTERM 时正常返回。
+func watchDuties(ctx context.Context, d *daemon.Daemon, r beaconext.BeaconReader, pubkeys []string, poll time.Duration) error {
+	var (
+		known     = map[uint64][]beaconstate.Duty{} // epoch -> 职责
+		attesters []uint64
+		curEpoch  uint64
+		started   bool
+		st        beaconstate.State
+	)
+	t := time.NewTicker(poll)
+	defer t.Stop()
+	for {
+		qctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		err := beaconstate.StreamLatest(qctx, r, func(key string, dec *json.Decoder) (bool, error) {
+			switch key {
+			case "slot", "validators", "committee_caches", "epoch_attester_indexes":
+				return st.DecodeField(key, dec)
+			}
+			return false, nil
+		})
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "%s 读取状态失败: %v\n", time.Now().Format(time.RFC3339), err)
+		} else {
+			spe := st.SlotsPerEpochHint()
+			if spe == 0 {
+				spe = beaconstate.DefaultSlotsPerEpoch
+			}
+			epoch := st.Epoch(spe)
+			if started && epoch != curEpoch {
+				a := beaconstate.CompareActivity(curEpoch, known[curEpoch], attesters)
+				fmt.Printf("%s %s\n", time.Now().Format(time.RFC3339), a)
+				delete(known, curEpoch)
+			}
+			status := fmt.Sprintf("slot %d, epoch %d, %d pubkeys", st.Slot, epoch, len(pubkeys))
+			if !started {
+				d.Ready(status)
+			} else {
+				d.Status(status)
+			}
+			curEpoch, started = epoch, true
+			attesters = st.EpochAttesterIndexes
+
+			ds, _ := st.Duties(pubkeys)
+			var fresh []beaconstate.Duty
+			for _, d := range ds {
+				if d.Epoch < epoch {
+					continue
+				}
+				if _, ok := known[d.Epoch]; !ok {
+					fresh = append(fresh, d)
+				}
+			}
+			for _, d := range fresh {
+				known[d.Epoch] = append(known[d.Epoch], d)
+			}
+			if len(fresh) > 0 {
+				fmt.Printf("%s slot %d（epoch %d）新的职责:\n", time.Now().Format(time.RFC3339), st.Slot, epoch)
+				printDuties(fresh)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
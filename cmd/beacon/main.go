@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/envflag"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: beacon <stats|duties> [flags]")
+	fmt.Fprintln(os.Stderr, "  stats   信标状态概览：各状态验证者数、余额/有效余额分布、提款凭证类型、罚没数")
+	fmt.Fprintln(os.Stderr, "  duties  查询公钥在上一/当前/下一纪元的委员会职责；-watch 时逐纪元报告预期与实际见证")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "stats":
+		err = stats(os.Args[2:])
+	case "duties":
+		err = duties(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func stats(args []string) error {
+	fs := flag.NewFlagSet("beacon stats", flag.ExitOnError)
+	rpcURL := fs.String("rpc", "http://127.0.0.1:8545", "执行层 RPC（consensusBeaconExt 查询）")
+	eth1Hash := fs.String("eth1-hash", "", "按该执行层区块哈希取状态；为空取 latest")
+	file := fs.String("file", "", "直接读取本地状态 JSON（如 beacon_state.json），不访问节点")
+	slotsPerEpoch := fs.Uint64("slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "每纪元 slot 数")
+	asJSON := fs.Bool("json", false, "以 JSON 输出")
+	envflag.ParseSet(fs, "", args)
+
+	st, err := loadState(*rpcURL, *eth1Hash, *file)
+	if err != nil {
+		return err
+	}
+	s := beaconstate.ComputeStats(st, *slotsPerEpoch)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+	s.Print(os.Stdout)
+	return nil
+}
+
+func loadState(rpc, eth1Hash, file string) (*beaconstate.State, error) {
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return beaconstate.ParseStream(f)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	c := beaconext.NewClient(rpc)
+	if eth1Hash == "" {
+		return beaconstate.FetchLatest(ctx, c)
+	}
+	snap, err := c.ResolveBeaconByEth1Hash(ctx, eth1Hash)
+	if err != nil {
+		return nil, err
+	}
+	return beaconstate.Parse(snap.BeaconStateRaw)
+}
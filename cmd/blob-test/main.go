@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/blobtx"
+	"n42-test/internal/envflag"
+	"n42-test/internal/netprofile"
+	"n42-test/internal/receipts"
+	"n42-test/internal/units"
+)
+
+func main() {
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC")
+	keySpec := flag.String("key", "", "发送账户私钥（hex / keystore / 助记词）；为空取环境变量 PRIVATE_KEY")
+	toAddr := flag.String("to", "", "接收地址；为空发给自己")
+	count := flag.Int("count", 1, "发送的 blob 交易笔数")
+	blobs := flag.Int("blobs", 1, fmt.Sprintf("每笔交易携带的 blob 数（1..%d）", blobtx.MaxBlobsPerTx))
+	seed := flag.Int64("seed", 0, "blob 内容的随机种子（0 表示按时间）")
+	blobFeeCapGwei := flag.String("blob-fee-cap-gwei", "0", "maxFeePerBlobGas（默认单位 Gwei，可带后缀，不做舍入）；0 表示当前 blob 基础费的 2 倍")
+	noWait := flag.Bool("no-wait", false, "只发送不等待上链（不做校验）")
+	checkReceipts := flag.Bool("check-receipts-root", true, "按配置档的收据规则重算 blob 交易所在区块的 receipts_root 并与区块头比对")
+	profileName := flag.String("profile", netprofile.DefaultName, "网络配置档："+strings.Join(netprofile.Names(), "|"))
+	timeout := flag.Duration("timeout", 10*time.Minute, "整体超时")
+	envflag.Parse("blob-test")
+
+	if *keySpec == "" {
+		*keySpec = os.Getenv("PRIVATE_KEY")
+	}
+	if *keySpec == "" {
+		log.Fatal("需要 -key 或环境变量 PRIVATE_KEY")
+	}
+	if *blobs < 1 || *blobs > blobtx.MaxBlobsPerTx {
+		log.Fatalf("-blobs 需在 1..%d", blobtx.MaxBlobsPerTx)
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(*seed))
+
+	var rules receipts.Rules
+	if *checkReceipts {
+		profile, err := netprofile.Lookup(*profileName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if rules, err = receipts.RulesFromProfile(profile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	s, err := blobtx.NewSender(ctx, *rpcURL, *keySpec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+
+	to := s.From()
+	if *toAddr != "" {
+		if !common.IsHexAddress(*toAddr) {
+			log.Fatalf("非法接收地址: %s", *toAddr)
+		}
+		to = common.HexToAddress(*toAddr)
+	}
+	blobFeeCap := feeWei("blob-fee-cap-gwei", *blobFeeCapGwei)
+	if base, err := s.BlobBaseFee(ctx); err == nil {
+		fmt.Printf("from=%s to=%s blob_base_fee=%s wei seed=%d\n", s.From().Hex(), to.Hex(), base, *seed)
+	} else {
+		log.Fatalf("读取 blob 基础费失败: %v", err)
+	}
+
+	var rf *receipts.Fetcher
+	if *checkReceipts && !*noWait {
+		if rf, err = receipts.Dial(ctx, *rpcURL); err != nil {
+			log.Fatal(err)
+		}
+		defer rf.Close()
+	}
+
+	var sent, ok, bad int
+	for i := 0; i < *count; i++ {
+		res, err := s.Send(ctx, &blobtx.Params{
+			To:               to,
+			Blobs:            blobtx.DummyBlobs(*blobs, rng),
+			Nonce:            -1,
+			MaxFeePerBlobGas: blobFeeCap,
+		}, !*noWait)
+		if err != nil {
+			bad++
+			fmt.Printf("❌ #%d %v\n", i, err)
+			continue
+		}
+		sent++
+		if *noWait {
+			fmt.Printf("📤 #%d tx=%s nonce=%d blobs=%d\n", i, res.TxHash.Hex(), res.Nonce, len(res.BlobHashes))
+			continue
+		}
+
+		chk, err := blobtx.Verify(ctx, s.Client(), res)
+		if err != nil {
+			bad++
+			fmt.Printf("❌ #%d tx=%s 校验失败: %v\n", i, res.TxHash.Hex(), err)
+			continue
+		}
+		problems := chk.Problems
+		if rf != nil {
+			r, err := rf.Compute(ctx, rules, chk.BlockNumber)
+			switch {
+			case err != nil:
+				problems = append(problems, fmt.Sprintf("receipts_root: %v", err))
+			case !r.Match():
+				problems = append(problems, r.Validate(rules).Error())
+			}
+		}
+		if len(problems) > 0 {
+			bad++
+			fmt.Printf("❌ #%d tx=%s block=%d\n", i, res.TxHash.Hex(), chk.BlockNumber)
+			for _, p := range problems {
+				fmt.Println("   " + p)
+			}
+			continue
+		}
+		ok++
+		fmt.Printf("✅ #%d tx=%s block=%d blobs=%d blob_gas_used=%d blob_gas_price=%s\n",
+			i, res.TxHash.Hex(), chk.BlockNumber, len(res.BlobHashes), chk.BlobGasUsed, chk.BlobGasPrice)
+	}
+
+	fmt.Printf("sent=%d verified=%d failed=%d\n", sent, ok, bad)
+	if bad > 0 {
+		os.Exit(1)
+	}
+}
+
+// feeWei 解析 -<name> 的费用（默认单位 Gwei）为 wei；0 表示未指定，返回 nil
+func feeWei(name, s string) *big.Int {
+	a, err := units.ParseAmount(s, units.Gwei)
+	if err != nil {
+		log.Fatalf("-%s: %v", name, err)
+	}
+	if a.IsZero() {
+		return nil
+	}
+	return a.Wei()
+}
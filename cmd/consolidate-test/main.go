@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/capability"
+	"n42-test/internal/consolidate"
+	"n42-test/internal/envflag"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+	"n42-test/internal/rpcpool"
+)
+
+// consolidate-test：发送 EIP-7251 合并请求（流程同 exit-test）。
+// 单条用 -source / -target（-target 省略时与 source 相同，即切换为复利凭证）；
+// 多条用 -pairs 文件，每行 "source[,target]"，# 开头为注释。发送前默认按信标状态预检，未通过的不发送。
+func main() {
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC（同时用于 consensusBeaconExt 查询）；逗号分隔多个 HTTP 地址时轮询并自动故障切换")
+	contractAddr := flag.String("contract", consolidate.DefaultContract, "合并请求系统合约地址（0x…）")
+	keySpec := flag.String("key", "", "发送账户私钥（须为 source 验证者的提款地址；hex / keystore / 助记词）；为空取环境变量 PRIVATE_KEY")
+	source := flag.String("source", "", "source 验证者 BLS 公钥（48B 0x…）：余额转出并退出")
+	target := flag.String("target", "", "target 验证者 BLS 公钥（48B 0x…，须为 0x02 凭证）；为空与 source 相同，即把 source 切换为 0x02")
+	pairsPath := flag.String("pairs", "", "批量请求文件：每行 source[,target]，逐条顺序发送；与 -source 二选一")
+	checkState := flag.Bool("check-state", true, "发送前按信标状态预检（激活与退出状态、凭证类型、发送者是否为提款地址、排队中的部分提款），未通过的不发送")
+	force := flag.Bool("force", false, "预检未通过也发送（信标链会忽略该请求，合约费用不退；用于测试合约侧行为）")
+	slotsPerEpoch := flag.Uint64("slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "计算当前纪元用的每纪元 slot 数")
+	wait := flag.Bool("wait", true, "是否等待交易上链（true 等待回执，false 只发不等）")
+	dryRun := flag.Bool("dry-run", false, "只打印 calldata、费用与预检结果，不发送")
+	timeout := flag.Duration("timeout", 3*time.Minute, "每条请求发送并等待回执的超时")
+	envflag.Parse("consolidate-test")
+
+	if *keySpec == "" {
+		*keySpec = os.Getenv("PRIVATE_KEY")
+	}
+	if *keySpec == "" && !*dryRun {
+		log.Fatal("需要 --key 或环境变量 PRIVATE_KEY")
+	}
+	if !common.IsHexAddress(*contractAddr) {
+		log.Fatalf("非法合约地址: %s", *contractAddr)
+	}
+	contract := common.HexToAddress(*contractAddr)
+
+	var reqs []consolidate.Request
+	var err error
+	switch {
+	case *pairsPath != "" && *source != "":
+		log.Fatal("--source 与 --pairs 只能二选一")
+	case *pairsPath != "":
+		reqs, err = readPairs(*pairsPath)
+	case *source != "":
+		var req consolidate.Request
+		req, err = parsePair(*source, *target)
+		reqs = []consolidate.Request{req}
+	default:
+		log.Fatal("需要 --source（单条）或 --pairs（批量）")
+	}
+	if err != nil {
+		log.Fatalf("请求参数错误: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	cli, err := rpcpool.DialEth(ctx, *rpcURL)
+	if err != nil {
+		log.Fatalf("连接 RPC 失败: %v", err)
+	}
+	defer cli.Close()
+	r := &runner{
+		client:  consolidate.NewClient(cli, contract).WithCapabilities(capability.For(ctx, *rpcURL)),
+		spe:     *slotsPerEpoch,
+		force:   *force,
+		dryRun:  *dryRun,
+		wait:    *wait,
+		timeout: *timeout,
+	}
+	if *keySpec != "" {
+		if r.priv, err = keys.ParseECDSA(*keySpec); err != nil {
+			log.Fatalf("私钥无效: %v", err)
+		}
+		r.sender = crypto.PubkeyToAddress(r.priv.PublicKey)
+		log.Printf("发送者 %s，合约 %s，%d 条请求", r.sender.Hex(), contract.Hex(), len(reqs))
+	} else {
+		log.Printf("未给私钥（dry-run，不检查发送者），合约 %s，%d 条请求", contract.Hex(), len(reqs))
+	}
+
+	if *checkState {
+		if r.state, err = beaconstate.FetchLatest(ctx, beaconext.NewClient(rpcpool.Parse(*rpcURL)[0])); err != nil {
+			log.Fatalf("获取信标状态失败（--check-state=false 跳过预检）: %v", err)
+		}
+		log.Printf("🔎 信标状态 slot=%d（纪元 %d）：发送前预检", r.state.Slot, r.state.Epoch(*slotsPerEpoch))
+	}
+	fee, err := r.client.GetFee(ctx)
+	cancel()
+	if err != nil {
+		log.Fatalf("读取请求费用失败: %v", err)
+	}
+	log.Printf("当前请求费用 %s wei", fee)
+	if fee.Sign() <= 0 && !*dryRun {
+		// 系统合约的费用至少 1 wei；为 0 通常是该地址上没有合约
+		log.Fatalf("请求费用为 %s：%s 上可能没有部署合并合约", fee, contract.Hex())
+	}
+
+	var failed int
+	for i, req := range reqs {
+		if err := r.handleOne(req); err != nil {
+			failed++
+			log.Printf("❌ [#%d] %s %s: %v", i, req.Kind(), hexutil.Encode(req.Source), err)
+		}
+	}
+	log.Printf("完成：成功 %d，失败 %d", len(reqs)-failed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runner 逐条预检并发送；state 为 nil 时不预检，priv 为 nil 时只能 dry-run
+type runner struct {
+	client  *consolidate.Client
+	state   *beaconstate.State
+	priv    *ecdsa.PrivateKey
+	sender  common.Address
+	spe     uint64
+	force   bool
+	dryRun  bool
+	wait    bool
+	timeout time.Duration
+}
+
+// handleOne 预检并发送一条请求；dryRun 时只打印
+func (r *runner) handleOne(req consolidate.Request) error {
+	calldata, err := req.Calldata()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s source=%s target=%s\n  calldata=%s\n", req.Kind(), hexutil.Encode(req.Source), hexutil.Encode(req.Target), hexutil.Encode(calldata))
+	if r.state != nil {
+		c, err := consolidate.CheckRequest(r.state, r.sender, req, r.spe)
+		if err != nil {
+			return err
+		}
+		if err := c.Err(); err != nil {
+			if !r.force {
+				return err
+			}
+			log.Printf("⚠️ %v（--force，照常发送）", err)
+		} else {
+			fmt.Println("  ✅ 预检通过")
+		}
+	}
+	if r.dryRun {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	tx, rcpt, err := r.client.SendRequest(ctx, r.priv, req, r.wait)
+	if err != nil {
+		return fmt.Errorf("发送失败: %w", err)
+	}
+	fmt.Println("  TxHash:", tx.Hash().Hex())
+	if rcpt != nil {
+		fmt.Printf("  Status=%d BlockNumber=%s GasUsed=%d\n", rcpt.Status, rcpt.BlockNumber, rcpt.GasUsed)
+		if rcpt.Status == 0 {
+			return errors.New("交易 revert（status=0）")
+		}
+	}
+	return nil
+}
+
+// parsePair 解析一对公钥；target 为空时与 source 相同
+func parsePair(source, target string) (consolidate.Request, error) {
+	src, err := hexutil.DecodeFixed(source, hexutil.PubkeyLen)
+	if err != nil {
+		return consolidate.Request{}, fmt.Errorf("source 公钥: %w", err)
+	}
+	if strings.TrimSpace(target) == "" {
+		return consolidate.Request{Source: src, Target: src}, nil
+	}
+	tgt, err := hexutil.DecodeFixed(target, hexutil.PubkeyLen)
+	if err != nil {
+		return consolidate.Request{}, fmt.Errorf("target 公钥: %w", err)
+	}
+	return consolidate.Request{Source: src, Target: tgt}, nil
+}
+
+// readPairs 读取 -pairs 文件：每行 source[,target]，空行与 # 注释跳过
+func readPairs(path string) ([]consolidate.Request, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []consolidate.Request
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		src, tgt, _ := strings.Cut(line, ",")
+		req, err := parsePair(strings.TrimSpace(src), tgt)
+		if err != nil {
+			return nil, fmt.Errorf("%s 第 %d 行: %w", path, n, err)
+		}
+		out = append(out, req)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%s 中没有请求", path)
+	}
+	return out, nil
+}
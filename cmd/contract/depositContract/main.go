@@ -15,8 +15,10 @@ import (
 	gethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/joho/godotenv"
+
+	"n42-test/internal/keys"
+	"n42-test/internal/rpcpool"
 )
 
 const artifactPath = "./build/DepositContract.json" // 固定路径：把 artifact 放到这里即可
@@ -42,15 +44,14 @@ func main() {
 	}
 
 	// 4) 连接 RPC
-	client, err := ethclient.Dial(rpcURL)
+	client, err := rpcpool.DialEth(context.Background(), rpcURL)
 	if err != nil {
 		log.Fatalf("连接 RPC 失败: %v", err)
 	}
 	defer client.Close()
 
 	// 5) 私钥 & from 地址
-	privHex = strings.TrimPrefix(strings.TrimPrefix(privHex, "0x"), "0X")
-	privateKey, err := crypto.HexToECDSA(privHex)
+	privateKey, err := keys.ParseECDSA(privHex)
 	if err != nil {
 		log.Fatalf("解析 PRIVATE_KEY 失败: %v", err)
 	}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
 	"log"
 	"math/big"
@@ -16,6 +15,11 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/joho/godotenv"
+
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+	"n42-test/internal/rpcpool"
+	"n42-test/internal/units"
 )
 
 func mustEnv(k string) string {
@@ -44,7 +48,7 @@ func main() {
 	// === 相当于 ethers.getDefaultProvider(process.env.RPC_URL) ===
 	_ = godotenv.Load()
 	rpcURL := mustEnv("RPC_URL")
-	client, err := ethclient.Dial(rpcURL)
+	client, err := rpcpool.DialEth(context.Background(), rpcURL)
 	if err != nil {
 		log.Fatalf("dial rpc: %v", err)
 	}
@@ -64,8 +68,7 @@ func main() {
 
 	// === 用 .env 里的 PRIVATE_KEY 账户转 1 ETH 给接收地址 ===
 	privHex := mustEnv("PRIVATE_KEY")
-	privHex = strings.TrimPrefix(strings.TrimPrefix(privHex, "0x"), "0X")
-	privKey, err := crypto.HexToECDSA(privHex)
+	privKey, err := keys.ParseECDSA(privHex)
 	if err != nil {
 		log.Fatalf("parse PRIVATE_KEY: %v", err)
 	}
@@ -77,10 +80,10 @@ func main() {
 	if err != nil {
 		log.Fatalf("get sender balance: %v", err)
 	}
-	fmt.Printf("Sender balance before: %s ETH\n", weiToEth(fromBal))
+	fmt.Printf("Sender balance before: %s ETH\n", units.FromWei(fromBal).ETH())
 
 	// 组装一笔 native ETH 转账（1 ETH）
-	amountToSendWei := new(big.Int).Mul(big.NewInt(1), big.NewInt(1e18))
+	amountToSendWei := units.FromGwei(1_000_000_000).Wei()
 	nonce, err := client.PendingNonceAt(ctx, from)
 	if err != nil {
 		log.Fatalf("get nonce: %v", err)
@@ -120,7 +123,7 @@ func main() {
 	}
 	if receipt.Status == types.ReceiptStatusSuccessful {
 		fmt.Printf("Transaction confirmed in block: %d\n", receipt.BlockNumber.Uint64())
-		fmt.Printf("Gas used: %s\n", receipt.GasUsed)
+		fmt.Printf("Gas used: %d\n", receipt.GasUsed)
 	} else {
 		log.Fatalf("tx reverted, status=%d", receipt.Status)
 	}
@@ -146,7 +149,7 @@ func main() {
 	txNonce := mustHexBig(transactionDataNonceHex).Uint64()
 	txGasPrice := mustHexBig(transactionDataGasPriceHex)
 	txGasLimit := mustHexBig(transactionDataGasLimitHex).Uint64()
-	txDataBytes, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(transactionDataDataHex, "0x"), "0X"))
+	txDataBytes, err := hexutil.Decode(transactionDataDataHex)
 	if err != nil {
 		log.Fatalf("decode data: %v", err)
 	}
@@ -183,7 +186,7 @@ func main() {
 		log.Fatalf("wait raw tx: %v", err)
 	}
 	if rawRcpt.Status == types.ReceiptStatusSuccessful {
-		fmt.Printf("🎉 Raw tx confirmed in block %d, gasUsed %s\n", rawRcpt.BlockNumber.Uint64(), rawRcpt.GasUsed)
+		fmt.Printf("🎉 Raw tx confirmed in block %d, gasUsed %d\n", rawRcpt.BlockNumber.Uint64(), rawRcpt.GasUsed)
 	} else {
 		log.Fatalf("raw tx reverted, status=%d", rawRcpt.Status)
 	}
@@ -199,13 +202,3 @@ func waitMined(ctx context.Context, c *ethclient.Client, hash common.Hash) (*typ
 		time.Sleep(800 * time.Millisecond)
 	}
 }
-
-// 小工具：wei → ETH 字符串
-func weiToEth(wei *big.Int) string {
-	if wei == nil {
-		return "0"
-	}
-	// 仅作为人类可读输出
-	f := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18))
-	return f.Text('f', 6)
-}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/abiutil"
+	"n42-test/internal/deposit"
+	"n42-test/internal/envflag"
+	"n42-test/internal/rpcpool"
+	"n42-test/internal/units"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: contract <call|send> -abi <file> -to <addr> -method <name> [args...]")
+	fmt.Fprintln(os.Stderr, "  call  只读调用（eth_call），按 ABI 解码返回值")
+	fmt.Fprintln(os.Stderr, "  send  发送交易（nonce/费用策略同 deposit），等待回执并按 ABI 解码事件")
+	fmt.Fprintln(os.Stderr, "  参数按方法签名解析：整数支持十进制/0x，bytes 为十六进制，数组为 JSON（如 '[\"0x..\",\"0x..\"]'）")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "call":
+		err = call(os.Args[2:])
+	case "send":
+		err = send(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// target 两个子命令共用的参数
+type target struct {
+	rpc     *string
+	abiPath *string
+	to      *string
+	method  *string
+}
+
+func targetFlags(fs *flag.FlagSet) *target {
+	return &target{
+		rpc:     fs.String("rpc", "http://127.0.0.1:8545", "执行层 RPC"),
+		abiPath: fs.String("abi", "", "ABI 文件（ABI 数组或带 abi 字段的 hardhat/foundry 产物）"),
+		to:      fs.String("to", "", "合约地址"),
+		method:  fs.String("method", "", "方法名或完整签名（重载时用，如 transfer(address,uint256)）"),
+	}
+}
+
+// resolve 读取 ABI、找到方法并把位置参数打包成 calldata
+func (t *target) resolve(args []string) (abi.ABI, abi.Method, common.Address, []byte, error) {
+	if *t.abiPath == "" || *t.method == "" {
+		return abi.ABI{}, abi.Method{}, common.Address{}, nil, errors.New("需要 -abi 与 -method")
+	}
+	if !common.IsHexAddress(*t.to) {
+		return abi.ABI{}, abi.Method{}, common.Address{}, nil, fmt.Errorf("非法合约地址 -to %q", *t.to)
+	}
+	a, err := abiutil.Load(*t.abiPath)
+	if err != nil {
+		return abi.ABI{}, abi.Method{}, common.Address{}, nil, err
+	}
+	m, err := abiutil.Method(a, *t.method)
+	if err != nil {
+		return abi.ABI{}, abi.Method{}, common.Address{}, nil, err
+	}
+	data, err := abiutil.Pack(m, args)
+	if err != nil {
+		return abi.ABI{}, abi.Method{}, common.Address{}, nil, err
+	}
+	return a, m, common.HexToAddress(*t.to), data, nil
+}
+
+func call(args []string) error {
+	fs := flag.NewFlagSet("contract call", flag.ExitOnError)
+	t := targetFlags(fs)
+	block := fs.Int64("block", -1, "在该高度调用；-1 表示 latest")
+	envflag.ParseSet(fs, "", args)
+
+	_, m, to, data, err := t.resolve(fs.Args())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cli, err := rpcpool.DialEth(ctx, *t.rpc)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	var at *big.Int
+	if *block >= 0 {
+		at = big.NewInt(*block)
+	}
+	out, err := cli.CallContract(ctx, ethCall(to, data), at)
+	if err != nil {
+		return fmt.Errorf("eth_call %s: %w", m.Sig, err)
+	}
+	lines, err := abiutil.Outputs(m, out)
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+	return nil
+}
+
+func send(args []string) error {
+	fs := flag.NewFlagSet("contract send", flag.ExitOnError)
+	t := targetFlags(fs)
+	keySpec := fs.String("key", "", "发送账户私钥（hex / keystore / 助记词）；为空取环境变量 PRIVATE_KEY")
+	valueEth := fs.String("value-eth", "0", "随交易转入的金额（默认单位 ETH，可带后缀 eth|gwei|wei，不做舍入）")
+	gasLimit := fs.Uint64("gas-limit", 0, "gas 上限；0 表示估算")
+	nonce := fs.Int64("nonce", -1, "nonce；-1 表示自动读取")
+	tipGwei := fs.String("tip-gwei", "0", "maxPriorityFeePerGas（默认单位 gwei，可带后缀，不做舍入），与 -max-fee-gwei 同时给出才生效")
+	maxFeeGwei := fs.String("max-fee-gwei", "0", "maxFeePerGas（默认单位 gwei，可带后缀，不做舍入）")
+	noWait := fs.Bool("no-wait", false, "只发送不等待回执")
+	envflag.ParseSet(fs, "", args)
+
+	a, m, to, data, err := t.resolve(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *keySpec == "" {
+		*keySpec = os.Getenv("PRIVATE_KEY")
+	}
+	if *keySpec == "" {
+		return errors.New("需要 -key 或环境变量 PRIVATE_KEY")
+	}
+	value, err := units.Parse(*valueEth, units.Ether)
+	if err != nil {
+		return fmt.Errorf("-value-eth: %w", err)
+	}
+	if value.Sign() > 0 && !m.Payable {
+		return fmt.Errorf("%s 不是 payable，不能带 -value-eth", m.Sig)
+	}
+	tip, err := units.ParseAmount(*tipGwei, units.Gwei)
+	if err != nil {
+		return fmt.Errorf("-tip-gwei: %w", err)
+	}
+	maxFee, err := units.ParseAmount(*maxFeeGwei, units.Gwei)
+	if err != nil {
+		return fmt.Errorf("-max-fee-gwei: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	c, err := deposit.NewClient(ctx, *t.rpc, *keySpec)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	opt := deposit.TxOptions{Nonce: *nonce, GasLimit: *gasLimit}
+	if tip.Sign() > 0 && maxFee.Sign() > 0 {
+		opt.MaxPriorityFeePerGas, opt.MaxFeePerGas = tip.Wei(), maxFee.Wei()
+	}
+	res, err := c.SendTx(ctx, &to, value, data, opt, !*noWait)
+	if err != nil {
+		if res != nil {
+			fmt.Printf("tx=%s nonce=%d\n", res.TxHash, res.Nonce)
+		}
+		return err
+	}
+	fmt.Printf("from=%s to=%s method=%s\n", c.From().Hex(), to.Hex(), m.Sig)
+	fmt.Printf("tx=%s nonce=%d gas_limit=%d\n", res.TxHash, res.Nonce, res.EstimatedGas)
+	if *noWait {
+		return nil
+	}
+	fmt.Printf("block=%d status=%d gas_used=%d\n", res.BlockNumber, res.Status, res.UsedGas)
+	for _, l := range res.Logs {
+		if text, ok := abiutil.Event(a, l); ok {
+			fmt.Println("  event " + text)
+		} else {
+			fmt.Printf("  log %s topics=%d\n", l.Address.Hex(), len(l.Topics))
+		}
+	}
+	if res.Status != 1 {
+		return fmt.Errorf("交易 revert（status=%d）", res.Status)
+	}
+	return nil
+}
+
+func ethCall(to common.Address, data []byte) ethereum.CallMsg {
+	return ethereum.CallMsg{To: &to, Data: data}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+
+	"n42-test/internal/deposit"
+	"n42-test/internal/keys"
+	"n42-test/internal/keystore"
+	"n42-test/internal/runsummary"
+	"n42-test/internal/units"
+)
+
+// ---------------- 流式读取输入 ----------------
+
+// itemDecoder 逐条解码 JSON 数组，不把整个文件读进内存
+type itemDecoder struct {
+	f     *os.File
+	dec   *json.Decoder
+	index int // 下一条在文件中的下标
+}
+
+func openItems(path string) (*itemDecoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(f)
+	tok, err := dec.Token()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("解析 JSON 数组失败: %w", err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		f.Close()
+		return nil, fmt.Errorf("解析 JSON 数组失败: 期望 '['，得到 %v", tok)
+	}
+	return &itemDecoder{f: f, dec: dec}, nil
+}
+
+// next 解码下一条，返回其在文件中的下标；数组结束时返回 io.EOF
+func (d *itemDecoder) next() (int, JsonItem, error) {
+	var it JsonItem
+	if !d.dec.More() {
+		return 0, it, io.EOF
+	}
+	if err := d.dec.Decode(&it); err != nil {
+		return 0, it, fmt.Errorf("解析第 %d 条失败: %w", d.index, err)
+	}
+	// deposit_data.json 条目：公钥统一放到 validator-public-key（--keystore-dir 匹配、交接都按它）
+	if it.ValidatorPublicKey == "" {
+		it.ValidatorPublicKey = it.Pubkey
+	}
+	d.index++
+	return d.index - 1, it, nil
+}
+
+func (d *itemDecoder) Close() error { return d.f.Close() }
+
+// taskStream 按 --start/--limit 把文件中的条目逐条送入 channel。
+// Task.Index 为条目在文件中的下标（与 --start 无关），断点续跑、对账时保持稳定。
+type taskStream struct {
+	C       <-chan Task
+	first   int // 第一条的下标，即 --start
+	err     error
+	skipped int
+	done    chan struct{}
+
+	mu      sync.Mutex
+	skipSet map[int]bool // 已跳过、尚未被有序输出越过的下标
+}
+
+// streamTasks 启动读取；assign 为每条分配金额与凭证类型（每次调用都从同一 seed 起步，
+// 分叉模拟与真实发送得到相同的任务）。skip 非 nil 且返回 true 的条目不送出，但仍计入 --limit，
+// 且照常消耗随机序列，其余条目的金额与凭证不受影响。
+func streamTasks(path string, start, limit, queue int, assign func(*Task), skip func(Task) bool) (*taskStream, error) {
+	d, err := openItems(path)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 {
+		start = 0
+	}
+	ch := make(chan Task, max(queue, 0))
+	s := &taskStream{C: ch, first: start, done: make(chan struct{}), skipSet: map[int]bool{}}
+	go func() {
+		defer close(s.done)
+		defer close(ch)
+		defer d.Close()
+		sent := 0
+		for limit < 0 || sent < limit {
+			idx, it, err := d.next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				s.err = err
+				return
+			}
+			if idx < start {
+				continue
+			}
+			t := Task{Index: idx, Item: it, Nonce: -1}
+			assign(&t)
+			sent++
+			if skip != nil && skip(t) {
+				s.mu.Lock()
+				s.skipped++
+				s.skipSet[idx] = true
+				s.mu.Unlock()
+				continue
+			}
+			ch <- t
+		}
+	}()
+	return s, nil
+}
+
+// Err 读取结束后的解析错误（C 关闭后调用）
+func (s *taskStream) Err() error {
+	<-s.done
+	return s.err
+}
+
+// takeSkipped 下标 i 是否被跳过（有序输出越过它时调用，之后不再保留）
+func (s *taskStream) takeSkipped(i int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.skipSet[i] {
+		return false
+	}
+	delete(s.skipSet, i)
+	return true
+}
+
+// Skipped 被 skip 跳过的条数（C 关闭后调用）
+func (s *taskStream) Skipped() int {
+	<-s.done
+	return s.skipped
+}
+
+// checkInput 在建运行目录、发送之前确认文件是 JSON 数组且 --start 处有条目（只解码到该条为止），返回该条目
+func checkInput(path string, start int) (JsonItem, error) {
+	d, err := openItems(path)
+	if err != nil {
+		return JsonItem{}, err
+	}
+	defer d.Close()
+	for {
+		idx, it, err := d.next()
+		if err == io.EOF {
+			if d.index == 0 {
+				return JsonItem{}, errors.New("JSON 数组为空")
+			}
+			return JsonItem{}, fmt.Errorf("--start=%d 超出条目数 %d", start, d.index)
+		}
+		if err != nil {
+			return JsonItem{}, err
+		}
+		if idx >= start {
+			return it, nil
+		}
+	}
+}
+
+// lookupItems 再扫一遍文件，取出 want 中下标对应的条目（交接时需要成功条目的私钥）
+func lookupItems(path string, want map[int]bool) (map[int]JsonItem, error) {
+	d, err := openItems(path)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	out := make(map[int]JsonItem, len(want))
+	for len(out) < len(want) {
+		idx, it, err := d.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if want[idx] {
+			out[idx] = it
+		}
+	}
+	return out, nil
+}
+
+// ---------------- 逐条分配金额 / 凭证类型 ----------------
+
+// assignConfig 金额模糊与提款凭证类型的分配规则
+type assignConfig struct {
+	fuzz             bool
+	minGwei, maxGwei uint64
+	mixed            bool // --wc-type mixed：逐条随机
+	defWC            byte
+	seed             int64
+	nonces           map[int]uint64 // --replay-nonces：按下标固定 nonce
+	depositKey       string         // 条目缺少 deposit-private-key 时的发送账户
+}
+
+func newAssignConfig(fuzzRange, wcType string, seed int64, depositKey string) (assignConfig, error) {
+	c := assignConfig{seed: seed, mixed: strings.EqualFold(wcType, "mixed"), depositKey: depositKey}
+	if fuzzRange != "" {
+		lo, hi, err := parseAmountRange(fuzzRange)
+		if err != nil {
+			return c, fmt.Errorf("--fuzz-amounts 参数错误: %w", err)
+		}
+		c.fuzz, c.minGwei, c.maxGwei = true, lo, hi
+	}
+	if !c.mixed {
+		t, err := deposit.ParseWCType(wcType)
+		if err != nil {
+			return c, fmt.Errorf("提款凭证类型错误: %w", err)
+		}
+		c.defWC = t
+	}
+	return c, nil
+}
+
+// assigner 返回按条目顺序消耗随机序列的分配函数：
+// 金额在 [minGwei, maxGwei] 内随机（gwei 对齐）；凭证类型 JSON 逐条指定 > --wc-type（mixed 时按种子随机）。
+// 同一 seed、同一顺序得到的任务与一次性读入时完全相同。
+func (c assignConfig) assigner() func(*Task) {
+	amounts := rand.New(rand.NewSource(c.seed))
+	// 与金额模糊使用不同的随机序列，互不影响
+	wcs := rand.New(rand.NewSource(c.seed + 1))
+	types := []byte{deposit.WCTypeBLS, deposit.WCTypeEth1, deposit.WCTypeCompounding}
+	span := c.maxGwei - c.minGwei + 1
+	return func(t *Task) {
+		if c.fuzz {
+			g := c.minGwei + uint64(amounts.Int63n(int64(span)))
+			t.Amount = units.FromGwei(g)
+		}
+		wc := c.defWC
+		if c.mixed {
+			wc = types[wcs.Intn(len(types))]
+		}
+		if s := t.Item.WithdrawalCredentialType; s != "" {
+			if v, err := deposit.ParseWCType(s); err != nil {
+				t.Err = runsummary.BadInput(fmt.Errorf("index %d: %w", t.Index, err))
+			} else {
+				wc = v
+			}
+		}
+		t.WCType = wc
+		if t.Item.DepositPrivateKey == "" {
+			t.Item.DepositPrivateKey = c.depositKey
+		}
+		if n, ok := c.nonces[t.Index]; ok {
+			t.Nonce = int64(n)
+		}
+	}
+}
+
+// ---------------- 验证者私钥 ----------------
+
+// validatorKeys --keystore-dir 中的 keystore；为 nil 时只使用 JSON 中的私钥
+var validatorKeys *keystore.Dir
+
+// loadKeystores 读取 --keystore-password-file（同时作为 JSON 中 keystore 格式私钥的口令）并打开 --keystore-dir
+func loadKeystores(dir, passwordFile string) error {
+	password := os.Getenv(keys.PasswordEnv)
+	if passwordFile != "" {
+		b, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return fmt.Errorf("读取口令文件失败: %w", err)
+		}
+		password = strings.TrimRight(string(b), "\r\n")
+		keys.SetDefaultOptions(keys.Options{Password: password})
+	}
+	if dir == "" {
+		return nil
+	}
+	if password == "" {
+		return fmt.Errorf("--keystore-dir 需要 --keystore-password-file 或环境变量 %s", keys.PasswordEnv)
+	}
+	d, err := keystore.OpenDir(dir, password)
+	if err != nil {
+		return err
+	}
+	validatorKeys = d
+	return nil
+}
+
+// validatorKey 本条的验证者私钥：JSON 中给出的（hex / keystore JSON 或路径），
+// 为空时按 validator-public-key 从 --keystore-dir 解密（首次使用时解密并缓存）
+func validatorKey(it JsonItem) (string, error) {
+	if it.ValidatorPrivateKey != "" || validatorKeys == nil {
+		return it.ValidatorPrivateKey, nil
+	}
+	secret, err := validatorKeys.Secret(it.ValidatorPublicKey)
+	if err != nil {
+		return "", err
+	}
+	// keystore 中固定为大端，显式加前缀，不受 --bls-key-endian 影响
+	return "be:" + hex.EncodeToString(secret), nil
+}
@@ -2,13 +2,14 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"os"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,24 +17,77 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 
 	// 改成你项目的真实模块路径
+	"n42-test/internal/autoscale"
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/blsutil"
+	"n42-test/internal/checkpoint"
 	"n42-test/internal/deposit"
+	"n42-test/internal/envflag"
+	"n42-test/internal/forksim"
+	"n42-test/internal/handoff"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+	"n42-test/internal/manifest"
+	"n42-test/internal/netprofile"
+	"n42-test/internal/pipeline"
+	"n42-test/internal/pushgw"
+	"n42-test/internal/ratelimit"
+	"n42-test/internal/registry"
+	"n42-test/internal/resultout"
+	"n42-test/internal/rpcpool"
+	"n42-test/internal/rundir"
+	"n42-test/internal/runsummary"
+	"n42-test/internal/units"
 )
 
 type JsonItem struct {
-	WithdrawalPrivateKey string `json:"withdrawal-private-key"` // 目前未用
+	WithdrawalPrivateKey string `json:"withdrawal-private-key"` // withdrawal-address 为空时用于推导地址
 	ValidatorPublicKey   string `json:"validator-public-key"`   // BLS 公钥(48B hex，无0x也可)
 	WithdrawalAddress    string `json:"withdrawal-address"`     // 20B exec addr（0x…）
-	ValidatorPrivateKey  string `json:"validator-private-key"`  // BLS 私钥(用于签名)
+	ValidatorPrivateKey  string `json:"validator-private-key"`  // BLS 私钥(用于签名)；hex 或 EIP-2335 keystore，为空时从 --keystore-dir 取
 	DepositPrivateKey    string `json:"deposit-private-key"`    // 发交易的 EOA 私钥（secp256k1）
+
+	// 可选：本条的提款凭证类型 0x00|0x01|0x02（覆盖 --wc-type）
+	WithdrawalCredentialType string `json:"withdrawal-credential-type,omitempty"`
+	// 可选：0x00 凭证使用的 BLS 提款公钥；为空时使用验证者公钥
+	BLSWithdrawalPublicKey string `json:"bls-withdrawal-public-key,omitempty"`
+	// 可选：bls-withdrawal-public-key 为空时，由该 BLS 提款私钥推导 0x00 凭证
+	BLSWithdrawalPrivateKey string `json:"bls-withdrawal-private-key,omitempty"`
+
+	// staking-deposit-cli 的 deposit_data-*.json 字段：已带签名，不在本地计算，
+	// 金额与凭证以文件为准（忽略 --amount-* / --fuzz-amounts / --wc-type），发送账户取 --deposit-key
+	Pubkey                string `json:"pubkey,omitempty"`
+	WithdrawalCredentials string `json:"withdrawal_credentials,omitempty"`
+	Amount                uint64 `json:"amount,omitempty"` // gwei
+	Signature             string `json:"signature,omitempty"`
+	DepositDataRoot       string `json:"deposit_data_root,omitempty"`
+	ForkVersion           string `json:"fork_version,omitempty"`
+}
+
+// presigned 是否为 deposit_data.json 条目（自带签名）
+func (it JsonItem) presigned() bool { return it.Signature != "" }
+
+// pubkey 验证者公钥（deposit_data.json 条目取 pubkey）
+func (it JsonItem) pubkey() string {
+	if it.presigned() {
+		return it.Pubkey
+	}
+	return it.ValidatorPublicKey
 }
 
 type Task struct {
-	Index int
-	Item  JsonItem
+	Index  int
+	Item   JsonItem
+	Amount units.Amount // 本条金额；为 0 时使用全局金额（--fuzz-amounts 时逐条随机）
+	WCType byte         // 本条提款凭证类型
+	Err    error        // 读取阶段发现的错误（如非法的凭证类型），处理时直接记为失败
+	Nonce  int64        // >=0 时使用该 nonce（--replay-nonces），否则按账户自动分配
 }
 
 type Result struct {
 	Index        int
+	Pubkey       string // 验证者公钥（写入去重登记）
 	Hash         string
 	Err          error
 	Nonce        uint64
@@ -41,132 +95,822 @@ type Result struct {
 	EstimatedGas uint64
 	BlockNumber  uint64
 	BlockHash    string
+	GasCostWei   *big.Int
+	Amount       units.Amount // 本条实际使用的质押金额
+	WCType       string       // 本条提款凭证类型（0x00|0x01|0x02）
+	WC           string       // 本条使用的 withdrawal_credentials
+	Derived      string       // 由私钥推导出的提款地址 / BLS 提款公钥（JSON 中缺失时）
+	DerivedFrom  string       // Derived 的来源字段
+	Beacon       *beaconCheck // --verify-beacon 的核验结果；未核验时为 nil
+
+	Deposit         *deposit.DepositEvent // 回执中解码出的 DepositEvent（含链上存款下标）；未等待回执时为 nil
+	DepositMismatch []string              // DepositEvent 与提交参数不一致的字段（或找不到事件）
+
+	SigInvalid string // --preflight-verify --preflight-action mark：签名预检失败的原因（照常发送）
+
+	Unconfirmed bool   // 已发送，但 --confirm-timeout 与运行后的 --resolve-timeout 补查内都没拿到回执（不计为失败，可 --resume 续查）
+	Reused      string // 沿用了上次发出的交易（checkpoint.Mined / InPool），未重新广播
+	IdemKey     string // 存款数据的幂等键（deposit.IdempotencyKey），跨运行查重
+	From        string // 发送账户（登记幂等键时一并记录；未启用重发保护时为空）
 }
 
+// 交易已打包但执行失败
+var errReverted = errors.New("交易 revert（status=0）")
+
+// stuckTx 交易卡住时的提价替换策略（--replace-after 为 0 时为 nil）
+var stuckTx *deposit.StuckTxPolicy
+
+// stepTimeouts 单条存款各步骤的时限（--estimate-timeout / --submit-timeout / --confirm-timeout），互不占用
+var stepTimeouts struct {
+	Estimate, Submit, Confirm time.Duration
+}
+
+// 签名预检的处理方式（--preflight-action）
+const (
+	preflightReject = "reject" // 记为失败，不发送
+	preflightMark   = "mark"   // 照常发送，结果中标记 signature_invalid
+)
+
+// preflightAction 发送前验证 BLS 签名、验证失败时的处理方式（未开启 --preflight-verify 时为空，不预检）
+var preflightAction string
+
+// depositDomain 本链的存款签名域（配置档，--fork-version / --genesis-validators-root 覆盖）
+var depositDomain deposit.DepositDomainConfig
+
 func main() {
-	deposit.EnsureBLS()
+	blsutil.EnsureInit()
 
 	// ---------- CLI flags ----------
-	jsonPath := flag.String("json", "accounts.json", "JSON 文件路径（数组）")
-	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC")
+	jsonPath := flag.String("json", "accounts.json", "JSON 文件路径（数组）：accounts.json，或 staking-deposit-cli 的 deposit_data-*.json")
+	depositKey := flag.String("deposit-key", os.Getenv("PRIVATE_KEY"), "条目中没有 deposit-private-key 时使用的发送账户私钥（deposit_data.json 必需；默认取环境变量 PRIVATE_KEY）")
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC；逗号分隔多个 HTTP 地址时轮询并自动故障切换")
 	contractAddr := flag.String("contract", "", "Deposit 合约地址（0x…）")
-	mode := flag.String("mode", "concurrent", "发送模式：sequential|concurrent")
+	mode := flag.String("mode", "concurrent", "发送模式：sequential|concurrent|pipeline")
 	workers := flag.Int("workers", 8, "并发度，仅在 --mode=concurrent 生效")
+	signWorkers := flag.Int("sign-workers", runtime.NumCPU(), "pipeline 模式：签名阶段（BLS 签名、deposit_data_root）的并发度")
+	submitWorkers := flag.Int("submit-workers", 4, "pipeline 模式：提交阶段的并发度（同一发送账户内始终串行）")
+	confirmWorkers := flag.Int("confirm-workers", 64, "pipeline 模式：等回执阶段的并发度")
+	queueSize := flag.Int("queue", 256, "pipeline 模式：阶段之间的队列长度")
+	adaptive := flag.Bool("adaptive", false, "自适应并发（AIMD）：按单条耗时与错误率动态增减并发，--workers 为起始值")
+	minWorkers := flag.Int("min-workers", 1, "自适应并发的下限")
+	maxWorkers := flag.Int("max-workers", 64, "自适应并发的上限")
+	rate := flag.Float64("rate", 0, "目标速率（笔/秒）：令牌桶按该速率放行提交，产生持续负载；结束时报告实际达成速率（0 不限速）")
+	rateBurst := flag.Int("rate-burst", 1, "--rate 的突发量：空闲后允许连续放行的笔数")
 	orderedOut := flag.Bool("ordered-output", true, "并发模式下是否按输入顺序输出结果")
 	start := flag.Int("start", 0, "从第几条（基于0）开始处理")
 	limit := flag.Int("limit", -1, "最多处理多少条；<0 表示全部")
 	dryRun := flag.Bool("dry-run", false, "仅打印将要发送的摘要，不真正上链")
 	noWait := flag.Bool("no-wait", false, "不等待回执，发送后立即返回")
+	retries := flag.Int("retries", 3, "瞬时 RPC 错误（连接中断、限流、already known、underpriced 等）的最多重试次数；0 不重试。重试沿用同一 nonce，不会重复存款")
+	retryBackoff := flag.Duration("retry-backoff", time.Second, "首次重试前的等待，之后每次翻倍（上限 30s）")
+	replaceAfter := flag.Duration("replace-after", 0, "等回执时交易超过该时长未打包，就用同一 nonce 提价重发（0 不替换）")
+	replaceBump := flag.Int("replace-bump", 12, "每次替换时 tip 与 fee cap 提高的百分比（至少 10）")
+	replaceMaxFeeGwei := flag.String("replace-max-fee-gwei", "0", "替换时 fee cap 的上限（默认单位 Gwei，可带后缀，不做舍入；0=初始 fee cap 的 4 倍），到达上限后只等待")
+	replaceTimeout := flag.Duration("replace-timeout", 10*time.Minute, "启用替换时单笔交易的总等待上限")
+	flag.DurationVar(&stepTimeouts.Estimate, "estimate-timeout", time.Minute, "单条 gas 估算的时限（--gas-limit 为 0 时）")
+	flag.DurationVar(&stepTimeouts.Submit, "submit-timeout", time.Minute, "单条提交的时限：取 nonce / 费用、签名并广播（不含 gas 估算）")
+	flag.DurationVar(&stepTimeouts.Confirm, "confirm-timeout", 180*time.Second, "单条等回执的时限；超时的交易记为已发送未确认，不计失败（启用替换时取与 --replace-timeout 的较大者）")
+	resolveTimeout := flag.Duration("resolve-timeout", 2*time.Minute, "运行结束后统一补查已发送未确认交易回执的最长时间（0=不补查）；查到的按上链结果改记，仍查不到的保持 unconfirmed")
+	preflightVerify := flag.Bool("preflight-verify", false, "发送前用存款域验证每条的 BLS 签名（含本地签名与 deposit_data.json 自带的签名），在花费 gas 前发现错配的私钥/公钥等坏数据；篡改签名类测试不要开启")
+	preflightActionFlag := flag.String("preflight-action", preflightReject, "--preflight-verify 验证失败时：reject=记为失败不发送，mark=照常发送并在结果中标记 signature_invalid")
 
-	amountETH := flag.Float64("amount-eth", 32, "每笔质押金额（ETH，默认32）。与 --amount-wei 互斥")
-	amountWeiStr := flag.String("amount-wei", "", "每笔质押金额（Wei，字符串）。若设置则覆盖 --amount-eth")
+	amountETH := flag.String("amount-eth", "32", "每笔质押金额（默认单位 ETH，可带后缀：32eth / 32000000000gwei / …wei；须为 1 gwei 的整数倍，不做舍入）。与 --amount-wei 互斥")
+	amountWeiStr := flag.String("amount-wei", "", "每笔质押金额（默认单位 Wei，同样可带后缀）。若设置则覆盖 --amount-eth")
+	fuzzAmounts := flag.String("fuzz-amounts", "", "金额模糊测试：每条在 min..max（默认单位 ETH，可带后缀，如 1..64 或 1eth..64eth）内随机取 gwei 对齐的金额（max 不超过 2048 ETH），覆盖 --amount-eth/--amount-wei")
+	wcType := flag.String("wc-type", "0x01", "提款凭证类型：0x00|0x01|0x02|mixed（mixed=逐条随机）；JSON 中的 withdrawal-credential-type 优先")
+	seed := flag.Int64("seed", 0, "--fuzz-amounts / --wc-type mixed 的随机种子（0=按时间生成并打印，便于复现）")
 
 	// 手动费用（留空则自动）
 	gasLimit := flag.Uint64("gas-limit", 0, "GasLimit（0=自动估算）")
-	maxTipGwei := flag.Float64("max-tip-gwei", 0, "MaxPriorityFeePerGas（单位 Gwei，0=自动建议）")
-	maxFeeGwei := flag.Float64("max-fee-gwei", 0, "MaxFeePerGas（单位 Gwei，0=自动建议）")
+	maxTipGwei := flag.String("max-tip-gwei", "0", "MaxPriorityFeePerGas（默认单位 Gwei，可带后缀，不做舍入；0=自动建议）")
+	maxFeeGwei := flag.String("max-fee-gwei", "0", "MaxFeePerGas（默认单位 Gwei，可带后缀，不做舍入；0=自动建议）")
+
+	// BLS 私钥格式（默认取自 --profile）
+	profileName := flag.String("profile", netprofile.DefaultName, "网络配置档："+strings.Join(netprofile.Names(), "|"))
+	blsKeyEndian := flag.String("bls-key-endian", "", "BLS 私钥字节序 be|le（覆盖配置档）")
+	blsETHMode := flag.String("bls-eth-mode", "", "BLS ETH mode latest|draft07|draft06|draft05|old（覆盖配置档）")
+	forkVersion := flag.String("fork-version", "", "存款签名域的 fork_version（4 字节十六进制，覆盖配置档；空为 0x00000000）")
+	genesisValidatorsRoot := flag.String("genesis-validators-root", "", "存款签名域的 genesis_validators_root（32 字节十六进制，覆盖配置档；空为零，即规范取值）")
+
+	// EIP-2335 keystore（测试数据里不放明文验证者私钥）
+	keystoreDir := flag.String("keystore-dir", "", "EIP-2335 keystore 目录（如 staking-deposit-cli 的 validator_keys/）；validator-private-key 为空的条目按公钥从这里解密私钥")
+	keystorePasswordFile := flag.String("keystore-password-file", "", "keystore 口令文件；也用于 JSON 中以 keystore 给出的私钥（未设置时取环境变量 "+keys.PasswordEnv+"）")
+
+	// 分叉模拟（先在分叉上跑整批，全部通过才真实发送）
+	simulateFork := flag.Bool("simulate-fork", false, "真实发送前先在 anvil 分叉上跑整批，有条目失败则中止")
+	forkRPC := flag.String("fork-rpc", "", "使用现成的分叉 RPC 做模拟（设置后隐含 --simulate-fork，不再启动 anvil）")
+	anvilBin := flag.String("anvil-bin", forksim.DefaultAnvilBin, "anvil 可执行文件路径")
+	simulateOnly := flag.Bool("simulate-only", false, "只做分叉模拟，不进行真实发送")
+
+	// 质押→验证交接（等待激活后生成 keystore/启动脚本）
+	handoffDir := flag.String("handoff-dir", "", "激活后在该目录生成 keystore/secrets、启动脚本（为空不生成）")
+	handoffClient := flag.String("handoff-client", handoff.ClientAttest, "交接目标客户端：attest|lighthouse")
+	handoffSystemd := flag.Bool("handoff-systemd", false, "同时生成 systemd unit 文件")
+	handoffWS := flag.String("handoff-ws", "ws://127.0.0.1:8546", "attest 启动命令使用的执行层 WS")
+	beaconNode := flag.String("handoff-beacon-node", "", "lighthouse vc 的 --beacon-nodes")
+	activationTimeout := flag.Duration("activation-timeout", 2*time.Hour, "等待激活的最长时间")
+	verifyBeaconFlag := flag.Bool("verify-beacon", false, "质押成功后轮询信标状态（consensusBeaconExt），直到验证者公钥出现或超时，逐条报告激活状态")
+	verifyBeaconTimeout := flag.Duration("verify-beacon-timeout", 10*time.Minute, "--verify-beacon 等待公钥出现的最长时间")
+	verifyBeaconPoll := flag.Duration("verify-beacon-poll", 12*time.Second, "--verify-beacon 轮询信标状态的间隔")
 
-	flag.Parse()
+	outputPath := flag.String("output", "", "逐条结果输出文件（下标、公钥、交易哈希、nonce、gas、区块、错误等）；为空不写")
+	outputFormat := flag.String("output-format", "", "--output 的格式 json|csv|ndjson（为空按扩展名，默认 json）")
+	manifestPath := flag.String("manifest", "", "运行清单输出路径（JSON；记录版本、完整配置、输入哈希、链 ID/创世哈希）；为空不写")
+	runsDir := flag.String("runs-dir", rundir.DefaultRootDir(), "运行目录根：每次运行在 <根>/<run_id>/ 下保存清单、结果与日志；为空不创建")
+	replay := flag.String("replay", "", "重放 --runs-dir 下某次运行（run ID 或唯一前缀）：沿用其清单中的参数与种子，命令行显式给出的参数优先")
+	replayNonces := flag.Bool("replay-nonces", false, "重放时沿用原运行每笔交易的 nonce（针对重置后的 devnet）")
+	registryDir := flag.String("registry-dir", registry.DefaultDirPath(), "跨运行去重登记目录（每个网络按创世哈希一个 SQLite 库，记录已确认存款的验证者公钥）；为空不登记")
+	skipExisting := flag.Bool("skip-existing", false, "跳过登记中已存过款的验证者公钥（需要 --registry-dir）")
+	allowDuplicates := flag.Bool("allow-duplicate-deposits", false, "不按幂等键（pubkey+凭证+金额+签名的哈希）查重，登记中已发过的同一笔存款也重新发送（重复存款类测试）；仍照常登记")
+	stateFile := flag.String("state-file", "", "断点状态文件（JSONL：每条提交前后与完成时追加下标、状态 pending|sent|confirmed|failed、交易哈希）；为空时写到运行目录的 checkpoints/state.jsonl")
+	resume := flag.Bool("resume", false, "按 --state-file 续跑：跳过已确认的条目（已提交未确认的先查回执）；与原运行使用相同的 --start/--limit")
+	retryFailed := flag.String("retry-failed", "", "只重跑上一次结果文件（--output 或运行目录 results/results.json，json|ndjson）中失败的条目（按下标与公钥识别）；结束后把新结果合并写回该文件（设置了 --output 时写到 --output）")
+	pushGateway := flag.String("pushgateway", "", "Prometheus Pushgateway 地址（如 http://127.0.0.1:9091）；设置后运行结束推送汇总指标（成功/失败、gas、耗时），按 run_id、network 分组")
+
+	envflag.Parse("deposit-batch")
+
+	var plan *replayPlan
+	if *replay != "" {
+		p, err := loadReplay(*runsDir, *replay, *replayNonces)
+		if err != nil {
+			log.Fatalf("重放失败: %v", err)
+		}
+		plan = p
+		log.Printf("🔁 重放运行 %s（沿用参数: %s）", plan.Run.ID, strings.Join(plan.Applied, " "))
+		if err := checkReplayInput(*jsonPath, plan.Run.Manifest); err != nil {
+			log.Fatalf("重放失败: %v", err)
+		}
+		if plan.Nonces != nil {
+			log.Printf("🔁 沿用原运行的 %d 个 nonce", len(plan.Nonces))
+		}
+	} else if *replayNonces {
+		log.Fatalf("--replay-nonces 需要配合 --replay")
+	}
+
+	profile, err := netprofile.Lookup(*profileName)
+	if err != nil {
+		log.Fatalf("配置档错误: %v", err)
+	}
+	blsOpts := blsutil.KeyOptionsFromProfile(profile)
+	if *blsKeyEndian != "" {
+		blsOpts.Endian = *blsKeyEndian
+	}
+	if *blsETHMode != "" {
+		blsOpts.ETHMode = *blsETHMode
+	}
+	if err := blsutil.SetDefaultKeyOptions(blsOpts); err != nil {
+		log.Fatalf("BLS 选项错误: %v", err)
+	}
+	if depositDomain, err = deposit.DomainConfigFromProfile(profile, *forkVersion, *genesisValidatorsRoot); err != nil {
+		log.Fatalf("存款签名域配置错误: %v", err)
+	}
+	log.Printf("🔏 存款签名域: %s", depositDomain)
 
 	if *contractAddr == "" || !common.IsHexAddress(*contractAddr) {
 		log.Fatalf("必须提供合法的 --contract 合约地址 (0x...)")
 	}
+	if err := loadKeystores(*keystoreDir, *keystorePasswordFile); err != nil {
+		log.Fatalf("keystore 错误: %v", err)
+	}
+	if validatorKeys != nil {
+		log.Printf("🔐 从 %s 载入 %d 个验证者 keystore（使用时解密）", validatorKeys.Path, validatorKeys.Len())
+	}
 	if *noWait {
 		log.Println("⚡ no-wait 模式：发送后不等待回执")
 	}
 
+	outFormat, err := resultout.ResolveFormat(*outputPath, *outputFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var retry *resultout.RetrySet[resultRecord]
+	if *retryFailed != "" {
+		retry, err = resultout.LoadRetry(*retryFailed, func(r resultRecord) (int, string, bool) { return r.Index, r.Pubkey, r.Error != "" })
+		if err != nil {
+			log.Fatalf("读取 --retry-failed 结果失败: %v", err)
+		}
+		if retry.Len() == 0 {
+			log.Printf("%s 中没有失败的条目，无需重跑", *retryFailed)
+			return
+		}
+		log.Printf("🔁 只重跑 %s 中失败的 %d 条（共 %d 条记录）", *retryFailed, retry.Len(), len(retry.Prior))
+	}
+
 	// ---------- 读取 JSON ----------
-	items, err := readJson(*jsonPath)
+	// 条目在发送时逐条解码，这里只确认文件格式与起始条目
+	first, err := checkInput(*jsonPath, *start)
 	if err != nil {
 		log.Fatalf("读取 JSON 失败: %v", err)
 	}
-	// 截取 start/limit
-	items = sliceRange(items, *start, *limit)
-	if len(items) == 0 {
-		log.Println("无可处理条目，退出。")
-		return
+	// deposit_data.json 不带验证者私钥，交接生成 keystore 只能从 --keystore-dir 取
+	if *handoffDir != "" && !*dryRun && first.presigned() && validatorKeys == nil {
+		log.Fatalf("--handoff-dir 用于 deposit_data.json（预签名）输入时需要 --keystore-dir 提供验证者私钥")
+	}
+	log.Printf("流式读取 %s（start=%d, limit=%d）", *jsonPath, *start, *limit)
+
+	// ---------- 运行清单 ----------
+	var mf *manifest.Manifest
+	if *manifestPath != "" || *runsDir != "" || *pushGateway != "" {
+		mf = manifest.ForRun("deposit-batch", *jsonPath, *rpcURL)
+	}
+	runDir := rundir.Start(*runsDir, mf)
+
+	// ---------- 去重登记 ----------
+	if *skipExisting && *registryDir == "" {
+		log.Fatalf("--skip-existing 需要 --registry-dir")
+	}
+	var reg *registry.Registry
+	if *registryDir != "" && (*skipExisting || !*dryRun) {
+		reg, err = registry.OpenForRun(*registryDir, *rpcURL, mf)
+		if err != nil {
+			if *skipExisting {
+				log.Fatalf("打开去重登记失败: %v", err)
+			}
+			log.Printf("⚠️ 打开去重登记失败，本次不登记: %v", err)
+		} else {
+			defer reg.Close()
+		}
+	}
+	if *skipExisting {
+		n, _ := reg.Count(registry.KindDeposit)
+		log.Printf("⏭️ 跳过登记中已存款的验证者（%s，%d 个）", reg.Path, n)
+	}
+
+	// ---------- 断点状态 ----------
+	if *resume && *stateFile == "" {
+		log.Fatalf("--resume 需要 --state-file")
+	}
+	statePath := *stateFile
+	if statePath == "" && runDir != nil {
+		statePath = runDir.Path(rundir.Checkpoints, "state.jsonl")
+	}
+	var stateWriter *checkpoint.Writer
+	if statePath != "" && !*dryRun {
+		if stateWriter, err = checkpoint.Create(statePath); err != nil {
+			log.Fatalf("打开状态文件失败: %v", err)
+		}
+		defer stateWriter.Close()
+	}
+	var resumed checkpoint.State
+	if *resume {
+		if resumed, err = checkpoint.Resume(statePath, *rpcURL, stateWriter, "产生重复存款"); err != nil {
+			log.Fatalf("读取状态文件失败: %v", err)
+		}
+	}
+
+	// 重发保护：续跑 / 重跑的上次交易、登记库中同一存款数据（幂等键）的交易，先核对再决定是否发送
+	var sg *sendGuard
+	if priors := collectPriors(resumed, retry); (len(priors) > 0 || reg != nil) && !*dryRun {
+		sg = &sendGuard{priors: priors, keys: reg, checkKeys: !*allowDuplicates}
+		if mf != nil {
+			sg.runID = mf.RunID
+		}
+		if sg.cli, err = rpcpool.DialEth(context.Background(), *rpcURL); err != nil {
+			log.Fatalf("RPC 连接失败: %v", err)
+		}
+		defer sg.cli.Close()
+		if len(priors) > 0 {
+			log.Printf("🛡️ %d 条上次已发出交易，重发前先按交易哈希与 (发送账户, nonce) 核对链上与交易池", len(priors))
+		}
+		if reg != nil && sg.checkKeys {
+			log.Printf("🛡️ 发送前按存款数据的幂等键查 %s，已发过的同一笔存款沿用原交易", reg.Path)
+		}
+	}
+
+	var skip func(Task) bool
+	if *skipExisting || resumed != nil || retry != nil {
+		skip = func(t Task) bool {
+			if retry != nil && !retry.Wants(t.Index, t.Item.pubkey()) {
+				return true
+			}
+			if resumed.Done(t.Index) {
+				return true
+			}
+			if !*skipExisting {
+				return false
+			}
+			done, err := reg.Has(registry.KindDeposit, t.Item.ValidatorPublicKey)
+			if err != nil {
+				log.Printf("⚠️ [#%d] 查询去重登记失败，照常处理: %v", t.Index, err)
+			}
+			return done
+		}
 	}
-	log.Printf("共载入 %d 条（start=%d, limit=%d）", len(items), *start, *limit)
 
 	// ---------- 计算金额 ----------
-	amountWei, err := decideAmount(*amountWeiStr, *amountETH)
+	amount, err := decideAmount(*amountWeiStr, *amountETH)
 	if err != nil {
 		log.Fatalf("金额参数错误: %v", err)
 	}
+	if *fuzzAmounts == "" {
+		log.Printf("💰 每笔质押金额 %s（%s wei）", amount, amount.Wei())
+	}
 
 	// EIP-1559 手动费
-	var maxTipWei, maxFeeWei *big.Int
-	if *maxTipGwei > 0 {
-		maxTipWei = gweiF(*maxTipGwei)
+	maxTipWei := feeWei("max-tip-gwei", *maxTipGwei)
+	maxFeeWei := feeWei("max-fee-gwei", *maxFeeGwei)
+
+	if *preflightVerify {
+		switch *preflightActionFlag {
+		case preflightReject, preflightMark:
+			preflightAction = *preflightActionFlag
+		default:
+			log.Fatalf("--preflight-action 只能为 %s|%s", preflightReject, preflightMark)
+		}
+		log.Printf("🔏 发送前验证 BLS 签名，失败时 %s", preflightAction)
+	}
+
+	if stepTimeouts.Estimate <= 0 || stepTimeouts.Submit <= 0 || stepTimeouts.Confirm <= 0 {
+		log.Fatal("--estimate-timeout / --submit-timeout / --confirm-timeout 必须 > 0")
+	}
+	if *replaceAfter > 0 {
+		stuckTx = &deposit.StuckTxPolicy{After: *replaceAfter, BumpPercent: *replaceBump, Timeout: *replaceTimeout}
+		stuckTx.MaxFeePerGas = feeWei("replace-max-fee-gwei", *replaceMaxFeeGwei)
+	}
+
+	// ---------- 任务分配规则 ----------
+	randomized := *fuzzAmounts != "" || strings.EqualFold(*wcType, "mixed")
+	if randomized && *seed == 0 {
+		*seed = time.Now().UnixNano()
+		log.Printf("🎲 随机种子 seed=%d（复现时加 --seed %d）", *seed, *seed)
+	}
+	assign, err := newAssignConfig(*fuzzAmounts, *wcType, *seed, *depositKey)
+	if err != nil {
+		log.Fatal(err)
 	}
-	if *maxFeeGwei > 0 {
-		maxFeeWei = gweiF(*maxFeeGwei)
+	if plan != nil {
+		assign.nonces = plan.Nonces
+	}
+	if *fuzzAmounts != "" {
+		log.Printf("🎲 金额模糊测试：%s ETH", *fuzzAmounts)
 	}
 
-	// ---------- 构造任务 ----------
-	tasks := make([]Task, len(items))
-	for i, it := range items {
-		tasks[i] = Task{Index: i, Item: it}
+	// 自适应并发：节点健康时逐步加并发，耗时翻倍或错误率超标时减半
+	var ctl *autoscale.Controller
+	if *adaptive {
+		ctl = autoscale.New(autoscale.Config{
+			Min: *minWorkers, Max: *maxWorkers, Start: *workers,
+			OnChange: func(old, new int, reason string) {
+				log.Printf("⚙️ 并发 %d → %d：%s", old, new, reason)
+			},
+		})
 	}
 
 	// ---------- 跑任务 ----------
 	ctx := context.Background()
 
-	switch strings.ToLower(*mode) {
-	case "sequential":
-		runSequential(ctx, *rpcURL, *contractAddr, tasks, amountWei, *gasLimit, maxTipWei, maxFeeWei, *dryRun, *noWait)
-	case "concurrent":
-		runConcurrent(ctx, *rpcURL, *contractAddr, tasks, *workers, amountWei, *gasLimit, maxTipWei, maxFeeWei, *dryRun, *orderedOut, *noWait)
-	default:
-		log.Fatalf("未知的 --mode：%s（可选 sequential|concurrent）", *mode)
+	var inputErr error
+	var skipped int
+	run := func(rpc string, noWait bool, lim *ratelimit.Limiter) ([]Result, *runsummary.RunSummary) {
+		// 每次运行重新流式读取；分配规则从同一 seed 起步，分叉模拟与真实发送的任务一致
+		tasks, err := streamTasks(*jsonPath, *start, *limit, *queueSize, assign.assigner(), skip)
+		if err != nil {
+			log.Fatalf("读取 JSON 失败: %v", err)
+		}
+		// 同一 deposit-private-key 的条目共用连接与本地 nonce 分配，提交串行、等回执并行
+		bs := deposit.NewBatchSender(rpc)
+		bs.SetRetryPolicy(deposit.RetryPolicy{MaxAttempts: *retries + 1, Backoff: *retryBackoff})
+		defer bs.Close()
+		var results []Result
+		var sum *runsummary.RunSummary
+		switch strings.ToLower(*mode) {
+		case "sequential":
+			results, sum = runSequential(ctx, bs, *contractAddr, tasks, lim, amount, *gasLimit, maxTipWei, maxFeeWei, *dryRun, noWait)
+		case "concurrent":
+			results, sum = runConcurrent(ctx, bs, *contractAddr, tasks, *workers, ctl, lim, amount, *gasLimit, maxTipWei, maxFeeWei, *dryRun, *orderedOut, noWait)
+		case "pipeline":
+			sw := stageWorkers{Sign: *signWorkers, Submit: *submitWorkers, Confirm: *confirmWorkers, Queue: *queueSize}
+			results, sum = runPipeline(ctx, bs, *contractAddr, tasks, sw, lim, amount, *gasLimit, maxTipWei, maxFeeWei, *dryRun, *orderedOut, noWait)
+		default:
+			log.Fatalf("未知的 --mode：%s（可选 sequential|concurrent|pipeline）", *mode)
+		}
+		if n := bs.Senders(); n > 0 {
+			log.Printf("发送账户 %d 个", n)
+		}
+		// 文件中途损坏时已读出的条目照常处理，剩余部分不再发送
+		if inputErr = tasks.Err(); inputErr != nil {
+			log.Printf("⚠️ 输入读取中断，之后的条目未处理: %v", inputErr)
+		}
+		if skipped = tasks.Skipped(); skipped > 0 {
+			log.Printf("⏭️ 跳过 %d 条（断点中已确认 / 登记中已存款 / 上一次未失败）", skipped)
+		}
+		return results, sum
+	}
+
+	// ---------- 分叉模拟 ----------
+	if (*simulateFork || *forkRPC != "" || *simulateOnly) && !*dryRun {
+		passed := simulateOnFork(ctx, *rpcURL, *forkRPC, *anvilBin, func(forkURL string) []Result {
+			// 模拟必须等待回执，才能知道是否 revert 及实际 gas；分叉上不限速
+			results, _ := run(forkURL, false, nil)
+			return results
+		})
+		if *simulateOnly {
+			return
+		}
+		if !passed {
+			log.Fatalf("分叉模拟存在失败条目，已中止真实发送")
+		}
+		log.Println("分叉模拟全部通过，开始真实发送")
+	}
+
+	state, guard = stateWriter, sg
+	lim := ratelimit.New(*rate, *rateBurst)
+	if lim != nil {
+		log.Printf("🚦 限速 %.2f 笔/秒（突发 %d）", lim.Rate(), *rateBurst)
+	}
+	results, sum := run(*rpcURL, *noWait, lim)
+	if rep := sum.ErrorReport(); rep != "" {
+		log.Print(rep)
+	}
+	mismatched := depositMismatches(results)
+	if mismatched > 0 {
+		log.Printf("⚠️ %d 笔质押回执中的 DepositEvent 与提交参数不一致（见各条目 deposit_mismatch）", mismatched)
+	}
+	unconfirmed := unconfirmedCount(results)
+	if unconfirmed > 0 && *resolveTimeout > 0 {
+		resolveUnconfirmed(ctx, *rpcURL, results, sum, *resolveTimeout)
+		unconfirmed = unconfirmedCount(results)
+	}
+	if unconfirmed > 0 {
+		log.Printf("⏳ %d 笔已发送但仍未确认，未计为失败（见各条目 unconfirmed）；交易已广播，不要重新发送，之后加 --resume 续查回执", unconfirmed)
+	}
+	sigInvalid := sigInvalidCount(results)
+	if sigInvalid > 0 {
+		log.Printf("⚠️ %d 笔签名预检未通过但已照常发送（见各条目 signature_invalid），信标链会忽略这些存款", sigInvalid)
+	}
+	if err := state.Err(); err != nil {
+		log.Printf("⚠️ 写状态文件失败，断点可能不完整: %v", err)
+	} else if state != nil {
+		log.Printf("⏯️ 断点状态已写入 %s（中断后加 --state-file %s --resume 续跑）", state.Path, state.Path)
+	}
+	if reg != nil && !*dryRun {
+		recordDeposits(reg, results, mf)
+	}
+
+	// ---------- 信标核验 ----------
+	if *verifyBeaconFlag && !*dryRun {
+		verifyBeacon(ctx, beaconext.NewClient(*rpcURL), results, beaconstate.DefaultSlotsPerEpoch, *verifyBeaconPoll, *verifyBeaconTimeout)
+	}
+
+	// ---------- 交接 ----------
+	if *handoffDir != "" && !*dryRun {
+		err := handOff(ctx, *rpcURL, *jsonPath, results, handoff.Options{
+			Dir:        *handoffDir,
+			Client:     *handoffClient,
+			Systemd:    *handoffSystemd,
+			WSURL:      *handoffWS,
+			HTTPURL:    *rpcURL,
+			BeaconNode: *beaconNode,
+		}, *activationTimeout)
+		if err != nil {
+			log.Printf("⚠️ 交接失败: %v", err)
+		}
+	}
+
+	if mf != nil {
+		mf.Summary = sum.Fields()
+		mf.Summary["dry_run"] = *dryRun
+		if inputErr != nil {
+			mf.Summary["input_error"] = inputErr.Error()
+		}
+		if plan != nil {
+			mf.Summary["replay_of"] = plan.Run.ID
+		}
+		if *skipExisting || *resume || retry != nil {
+			mf.Summary["skipped"] = skipped
+		}
+		if retry != nil {
+			mf.Summary["retry_of"] = retry.Path
+		}
+		if lim != nil {
+			mf.Summary["target_tps"], mf.Summary["achieved_tps"] = lim.Rate(), lim.Achieved()
+		}
+		if mismatched > 0 {
+			mf.Summary["deposit_mismatch"] = mismatched
+		}
+		if unconfirmed > 0 {
+			mf.Summary["unconfirmed"] = unconfirmed
+		}
+		if preflightAction != "" {
+			mf.Summary["preflight_action"] = preflightAction
+			if sigInvalid > 0 {
+				mf.Summary["signature_invalid"] = sigInvalid
+			}
+		}
+		if randomized {
+			// 记录实际使用的种子（--seed 为 0 时为自动生成的值）
+			mf.Summary["seed"] = *seed
+		}
+		if derived := derivedValues(results); len(derived) > 0 {
+			mf.Summary["derived"] = derived
+		}
+		if *verifyBeaconFlag && !*dryRun {
+			mf.Summary["beacon_status"] = beaconSummary(results)
+		}
+		if *manifestPath != "" {
+			if err := mf.Write(*manifestPath); err != nil {
+				log.Printf("⚠️ 写运行清单失败: %v", err)
+			} else {
+				log.Printf("📝 运行清单已写入 %s（run_id=%s）", *manifestPath, mf.RunID)
+			}
+		}
+	}
+	switch {
+	case retry != nil:
+		// 重跑的条目替换上一次的记录，其余原样保留
+		path, format := retry.Path, retry.Format
+		if *outputPath != "" {
+			path, format = *outputPath, outFormat
+		}
+		merged := retry.Merge(resultRecords(results))
+		if err := resultout.Write(path, format, merged); err != nil {
+			log.Printf("⚠️ 写结果文件失败: %v", err)
+		} else {
+			log.Printf("📄 重跑的 %d 条已合并，共 %d 条结果写入 %s（%s）", len(results), len(merged), path, format)
+		}
+	case *outputPath != "":
+		if err := resultout.Write(*outputPath, outFormat, resultRecords(results)); err != nil {
+			log.Printf("⚠️ 写结果文件失败: %v", err)
+		} else {
+			log.Printf("📄 %d 条结果已写入 %s（%s）", len(results), *outputPath, outFormat)
+		}
+	}
+	if runDir != nil {
+		if err := runDir.WriteJSON(rundir.Results, "results.json", resultRecords(results)); err != nil {
+			log.Printf("⚠️ 写结果失败: %v", err)
+		}
+		if err := runDir.Finish(); err != nil {
+			log.Printf("⚠️ 写运行清单失败: %v", err)
+		} else {
+			log.Printf("📁 运行产物已写入 %s", runDir.Dir)
+		}
+	}
+	if *pushGateway != "" {
+		pctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := pushgw.PushSummary(pctx, *pushGateway, mf, *profileName); err != nil {
+			log.Printf("⚠️ 推送指标失败: %v", err)
+		} else {
+			log.Printf("📈 指标已推送到 %s（job=%s run_id=%s）", *pushGateway, mf.Tool, mf.RunID)
+		}
+		cancel()
+	}
+}
+
+// recordDeposits 把已确认的存款登记进去重库（未等回执的不登记）
+func recordDeposits(reg *registry.Registry, results []Result, mf *manifest.Manifest) {
+	var runID string
+	if mf != nil {
+		runID = mf.RunID
+	}
+	n := 0
+	for _, r := range results {
+		if r.Err != nil || r.BlockNumber == 0 || r.Pubkey == "" {
+			continue
+		}
+		e := registry.Entry{Pubkey: r.Pubkey, TxHash: r.Hash, Block: r.BlockNumber, RunID: runID}
+		if err := reg.Record(registry.KindDeposit, e); err != nil {
+			log.Printf("⚠️ [#%d] 写入去重登记失败: %v", r.Index, err)
+			continue
+		}
+		n++
+	}
+	if n > 0 {
+		log.Printf("🗂️ %d 个验证者已登记到 %s", n, reg.Path)
+	}
+}
+
+// simulateOnFork 在分叉上执行整批并打印报告；全部通过返回 true
+func simulateOnFork(ctx context.Context, upstream, forkRPC, anvilBin string, run func(forkURL string) []Result) bool {
+	var fork *forksim.Fork
+	if forkRPC != "" {
+		fork = forksim.Use(forkRPC)
+	} else {
+		log.Printf("启动 anvil 分叉: %s", upstream)
+		f, err := forksim.StartAnvil(ctx, anvilBin, upstream)
+		if err != nil {
+			log.Fatalf("启动分叉失败: %v", err)
+		}
+		fork = f
+	}
+	defer fork.Close()
+	log.Printf("在分叉 %s 上模拟整批交易……", fork.URL)
+
+	prefix := log.Prefix()
+	log.SetPrefix(prefix + "[fork] ")
+	results := run(fork.URL)
+	log.SetPrefix(prefix)
+
+	var rep forksim.Report
+	for _, r := range results {
+		o := forksim.ItemOutcome{Index: r.Index, GasUsed: r.UsedGas, GasCost: r.GasCostWei}
+		switch {
+		case errors.Is(r.Err, errReverted):
+			o.Reverted = true
+		case r.Err != nil:
+			o.Err = r.Err
+		default:
+			o.ValueWei = r.Amount.Wei()
+		}
+		rep.Add(o)
+	}
+	log.Println(rep.String())
+	return len(rep.Failed()) == 0
+}
+
+// handOff 等待成功质押的验证者激活，再为已激活的生成 keystore 与启动脚本
+func handOff(ctx context.Context, rpc, jsonPath string, results []Result, opts handoff.Options, timeout time.Duration) error {
+	succeeded := map[int]bool{}
+	for _, r := range results {
+		if r.Err == nil {
+			succeeded[r.Index] = true
+		}
+	}
+	if len(succeeded) == 0 {
+		return errors.New("没有成功的质押")
+	}
+	// 输入是流式读取的，这里只把成功条目重新读出来
+	byIndex, err := lookupItems(jsonPath, succeeded)
+	if err != nil {
+		return err
+	}
+	var pubkeys []string
+	for _, r := range results {
+		if succeeded[r.Index] {
+			pubkeys = append(pubkeys, byIndex[r.Index].ValidatorPublicKey)
+		}
+	}
+
+	log.Printf("等待 %d 个验证者激活后交接……", len(pubkeys))
+	active, err := handoff.WaitActive(ctx, beaconext.NewClient(rpc), pubkeys, beaconstate.DefaultSlotsPerEpoch, 12*time.Second, timeout)
+	if err != nil {
+		return err
+	}
+
+	var entries []handoff.Entry
+	for _, r := range results {
+		it := byIndex[r.Index]
+		if r.Err != nil || !active[beaconstate.NormPubkey(it.ValidatorPublicKey)] {
+			continue
+		}
+		sk, err := validatorKey(it)
+		if err == nil && sk == "" {
+			err = errors.New("没有验证者私钥（条目未带 validator-private-key，--keystore-dir 中也没有）")
+		}
+		if err != nil {
+			log.Printf("⚠️ [#%d] 跳过交接: %v", r.Index, err)
+			continue
+		}
+		secret, err := blsutil.SecretKeyBytes(sk, blsutil.DefaultKeyOptions())
+		if err != nil {
+			log.Printf("⚠️ [#%d] 跳过交接: %v", r.Index, err)
+			continue
+		}
+		entries = append(entries, handoff.Entry{PubkeyHex: it.ValidatorPublicKey, Secret: secret})
+	}
+	if len(entries) == 0 {
+		return errors.New("没有可交接的已激活验证者")
+	}
+
+	res, err := handoff.Write(entries, opts)
+	if err != nil {
+		return err
+	}
+	log.Printf("✅ 已为 %d 个验证者生成交接文件：%s", len(res.Keystores), opts.Dir)
+	log.Printf("   启动：bash %s", res.LaunchScript)
+	for _, u := range res.Units {
+		log.Printf("   systemd unit：%s", u)
 	}
+	return nil
 }
 
+// resultRecord 单条结果：写入运行目录 results/results.json 与 --output
+type resultRecord struct {
+	Index        int    `json:"index"`
+	Pubkey       string `json:"pubkey,omitempty"`
+	TxHash       string `json:"tx_hash,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Nonce        uint64 `json:"nonce,omitempty"`
+	EstimatedGas uint64 `json:"estimated_gas,omitempty"`
+	GasUsed      uint64 `json:"gas_used,omitempty"`
+	BlockNumber  uint64 `json:"block_number,omitempty"`
+	BlockHash    string `json:"block_hash,omitempty"`
+	GasCostWei   string `json:"gas_cost_wei,omitempty"`
+	AmountWei    string `json:"amount_wei,omitempty"`
+	WCType       string `json:"wc_type,omitempty"`
+	WC           string `json:"withdrawal_credentials,omitempty"`
+	Derived      string `json:"derived,omitempty"`
+	DerivedFrom  string `json:"derived_from,omitempty"`
+	// --verify-beacon：信标状态中的下标、状态（not_found 表示超时未出现）与纪元（- 表示未分配）
+	BeaconIndex      string `json:"beacon_index,omitempty"`
+	BeaconStatus     string `json:"beacon_status,omitempty"`
+	EligibilityEpoch string `json:"activation_eligibility_epoch,omitempty"`
+	ActivationEpoch  string `json:"activation_epoch,omitempty"`
+	// 回执中 DepositEvent 的链上存款下标，及其与提交参数的不一致项（"; " 分隔）
+	DepositIndex    string `json:"deposit_index,omitempty"`
+	DepositMismatch string `json:"deposit_mismatch,omitempty"`
+	// --preflight-action mark：签名预检失败的原因
+	SigInvalid string `json:"signature_invalid,omitempty"`
+	// 已发送但 --confirm-timeout 内未确认
+	Unconfirmed bool `json:"unconfirmed,omitempty"`
+	// 续跑 / 重跑或按幂等键沿用的上次交易：mined | in_pool
+	Reused string `json:"reused,omitempty"`
+	// 存款数据的幂等键：sha256(pubkey || 凭证 || 金额 || 签名)
+	IdemKey string `json:"idempotency_key,omitempty"`
+}
+
+func resultRecords(results []Result) []resultRecord {
+	out := make([]resultRecord, len(results))
+	for i, r := range results {
+		rec := resultRecord{
+			Index: r.Index, Pubkey: r.Pubkey, TxHash: r.Hash, Nonce: r.Nonce, EstimatedGas: r.EstimatedGas, GasUsed: r.UsedGas,
+			BlockNumber: r.BlockNumber, BlockHash: r.BlockHash, WCType: r.WCType, WC: r.WC,
+			Derived: r.Derived, DerivedFrom: r.DerivedFrom, SigInvalid: r.SigInvalid, Unconfirmed: r.Unconfirmed,
+			Reused: r.Reused, IdemKey: r.IdemKey,
+		}
+		if r.Err != nil {
+			rec.Error = r.Err.Error()
+		}
+		if r.GasCostWei != nil {
+			rec.GasCostWei = r.GasCostWei.String()
+		}
+		if !r.Amount.IsZero() {
+			rec.AmountWei = r.Amount.Wei().String()
+		}
+		if r.Deposit != nil {
+			rec.DepositIndex = strconv.FormatUint(r.Deposit.Index, 10)
+		}
+		rec.DepositMismatch = strings.Join(r.DepositMismatch, "; ")
+		if c := r.Beacon; c != nil {
+			rec.BeaconStatus = c.Status
+			if c.found() {
+				rec.BeaconIndex = strconv.Itoa(c.Index)
+				rec.EligibilityEpoch, rec.ActivationEpoch = epochString(c.EligibilityEpoch), epochString(c.ActivationEpoch)
+			}
+		}
+		out[i] = rec
+	}
+	return out
+}
+
+// Outcome 实现 runsummary.Outcome
+func (r Result) Outcome() (uint64, *big.Int, error) { return r.UsedGas, r.GasCostWei, r.Err }
+
 // ---------------- 任务执行 ----------------
 
 func runSequential(
 	ctx context.Context,
-	rpc, contract string,
-	tasks []Task,
-	amountWei *big.Int,
+	bs *deposit.BatchSender,
+	contract string,
+	tasks *taskStream,
+	lim *ratelimit.Limiter,
+	amount units.Amount,
 	gasLimit uint64,
 	maxTipWei, maxFeeWei *big.Int,
 	dryRun bool,
 	noWait bool,
-) {
-	ok, fail := 0, 0
-	startAt := time.Now()
+) ([]Result, *runsummary.RunSummary) {
+	sum := runsummary.New("sequential", 0)
+	var results []Result
 
-	for _, t := range tasks {
-		res := handleOne(ctx, rpc, contract, t, amountWei, gasLimit, maxTipWei, maxFeeWei, dryRun, noWait)
+	for t := range tasks.C {
+		lim.Wait(ctx)
+		res := handleOne(ctx, bs, contract, t, amount, gasLimit, maxTipWei, maxFeeWei, dryRun, noWait)
 		printResult(res)
-		if res.Err != nil {
-			fail++
-		} else {
-			ok++
-		}
+		results = append(results, res)
 	}
 
-	log.Printf("顺序完成：成功 %d，失败 %d，耗时 %s", ok, fail, time.Since(startAt).Round(time.Millisecond))
+	runsummary.Summarize(sum, results)
+	log.Printf("顺序完成：%s", sum)
+	if lim != nil {
+		log.Print(lim.Summary())
+	}
+	return results, sum
 }
 
 func runConcurrent(
 	ctx context.Context,
-	rpc, contract string,
-	tasks []Task,
+	bs *deposit.BatchSender,
+	contract string,
+	tasks *taskStream,
 	workers int,
-	amountWei *big.Int,
+	ctl *autoscale.Controller,
+	lim *ratelimit.Limiter,
+	amount units.Amount,
 	gasLimit uint64,
 	maxTipWei, maxFeeWei *big.Int,
 	dryRun bool,
 	orderedOutput bool,
 	noWait bool,
-) {
+) ([]Result, *runsummary.RunSummary) {
 	if workers <= 0 {
 		workers = 4
 	}
+	if ctl != nil {
+		// 预先起满上限个 worker，实际在途数由 ctl 控制
+		workers = ctl.Max()
+	}
 
-	startAt := time.Now()
+	sum := runsummary.New("concurrent", workers)
 	in := make(chan Task)
 	out := make(chan Result)
 
@@ -177,7 +921,11 @@ func runConcurrent(
 		go func() {
 			defer wg.Done()
 			for t := range in {
-				res := handleOne(ctx, rpc, contract, t, amountWei, gasLimit, maxTipWei, maxFeeWei, dryRun, noWait)
+				tok := ctl.Acquire()
+				lim.Wait(ctx)
+				began := time.Now()
+				res := handleOne(ctx, bs, contract, t, amount, gasLimit, maxTipWei, maxFeeWei, dryRun, noWait)
+				ctl.Release(tok, time.Since(began), res.Err)
 				out <- res
 			}
 		}()
@@ -190,219 +938,536 @@ func runConcurrent(
 	}()
 
 	go func() {
-		for _, t := range tasks {
+		for t := range tasks.C {
 			in <- t
 		}
 		close(in)
 	}()
 
-	ok, fail := 0, 0
+	results := collectResults(out, tasks, orderedOutput)
+
+	runsummary.Summarize(sum, results)
+	log.Printf("并发完成：%s", sum)
+	if ctl != nil {
+		log.Print(ctl.Summary())
+	}
+	if lim != nil {
+		log.Print(lim.Summary())
+	}
+	return results, sum
+}
+
+// stageWorkers 流水线各阶段的 worker 数与阶段间队列长度
+type stageWorkers struct {
+	Sign, Submit, Confirm int
+	Queue                 int
+}
+
+// staged 在流水线阶段间传递的一条：失败的条目带着 Err 直接穿过后续阶段
+type staged struct {
+	res    Result
+	params *deposit.DepositParams
+	tx     *deposit.TxResult
+}
+
+// runPipeline 分阶段执行：签名（CPU）→ 提交（按账户串行分配 nonce）→ 等回执，
+// 各阶段独立并发、之间用有界队列衔接，慢的阶段只对上游形成背压
+func runPipeline(
+	ctx context.Context,
+	bs *deposit.BatchSender,
+	contract string,
+	tasks *taskStream,
+	sw stageWorkers,
+	lim *ratelimit.Limiter,
+	amount units.Amount,
+	gasLimit uint64,
+	maxTipWei, maxFeeWei *big.Int,
+	dryRun bool,
+	orderedOutput bool,
+	noWait bool,
+) ([]Result, *runsummary.RunSummary) {
+	sum := runsummary.New("pipeline", sw.Sign+sw.Submit+sw.Confirm)
+	wait := !noWait && !dryRun
+
+	signed, signSt := pipeline.Stage("sign", tasks.C, sw.Sign, sw.Queue, func(t Task) staged {
+		res, params := prepareOne(contract, bs.RPC(), t, amount, gasLimit, maxTipWei, maxFeeWei)
+		return staged{res: res, params: params}
+	})
+	submitted, submitSt := pipeline.Stage("submit", signed, sw.Submit, sw.Queue, func(s staged) staged {
+		if s.params == nil {
+			return s
+		}
+		if dryRun {
+			s.res.Hash = "(dry-run)"
+			return s
+		}
+		res, tx, stop := reusePrior(ctx, s.params, s.res)
+		if stop {
+			s.res = res
+			return s
+		}
+		var err error
+		if tx == nil {
+			lim.Wait(ctx)
+			state.Mark(checkpoint.Record{Index: s.res.Index, Status: checkpoint.Pending})
+			tx, err = bs.Submit(ctx, s.params)
+		}
+		s.res, s.tx = applyTx(res, tx, err, false), tx
+		if err == nil && wait {
+			markSent(s.res, tx)
+		}
+		return s
+	})
+	stats := []*pipeline.Stats{signSt, submitSt}
+	last := submitted
+	if wait {
+		confirmed, confirmSt := pipeline.Stage("confirm", submitted, sw.Confirm, sw.Queue, func(s staged) staged {
+			if s.res.Err != nil || s.tx == nil {
+				return s
+			}
+			s.res = confirmOne(ctx, bs, s.params, s.res, s.tx)
+			return s
+		})
+		stats, last = append(stats, confirmSt), confirmed
+	}
+
+	out := make(chan Result)
+	go func() {
+		defer close(out)
+		for s := range last {
+			markResult(s.res)
+			out <- s.res
+		}
+	}()
+	results := collectResults(out, tasks, orderedOutput)
+
+	runsummary.Summarize(sum, results)
+	log.Printf("流水线完成：%s", sum)
+	for _, st := range stats {
+		log.Printf("   %s", st)
+	}
+	if lim != nil {
+		log.Print(lim.Summary())
+	}
+	return results, sum
+}
 
-	if !orderedOutput {
+// collectResults 收集结果并打印；ordered 时按输入顺序（从 --start 起，越过被跳过的下标）输出
+func collectResults(out <-chan Result, tasks *taskStream, ordered bool) []Result {
+	var results []Result
+	if !ordered {
 		// 到达即打
 		for res := range out {
 			printResult(res)
-			if res.Err != nil {
-				fail++
-			} else {
-				ok++
-			}
+			results = append(results, res)
 		}
-	} else {
-		// 按输入顺序输出：用缓冲 map，维护 nextIndex
-		buf := make(map[int]Result, len(tasks))
-		next := 0
-		for res := range out {
-			buf[res.Index] = res
-			for {
-				if r, ok2 := buf[next]; ok2 {
-					printResult(r)
-					if r.Err != nil {
-						fail++
-					} else {
-						ok++
-					}
-					delete(buf, next)
-					next++
-				} else {
-					break
-				}
+		return results
+	}
+	// 按输入顺序输出：用缓冲 map，维护 nextIndex
+	// 收到下标大于 next 的结果时，读取端必然已处理过 next：不是已送出就是已跳过
+	buf := map[int]Result{}
+	next := tasks.first
+	for res := range out {
+		buf[res.Index] = res
+		for {
+			if r, ok := buf[next]; ok {
+				printResult(r)
+				results = append(results, r)
+				delete(buf, next)
+				next++
+			} else if tasks.takeSkipped(next) {
+				next++
+			} else {
+				break
 			}
 		}
 	}
-
-	log.Printf("并发完成：成功 %d，失败 %d，并发度 %d，耗时 %s", ok, fail, workers, time.Since(startAt).Round(time.Millisecond))
+	return results
 }
 
-// 实际处理一条：构造 DepositParams 并发交易
+// 处理一条：签名 → 提交 →（可选）等待回执
 func handleOne(
 	ctx context.Context,
-	rpc, contract string,
+	bs *deposit.BatchSender,
+	contract string,
 	task Task,
-	amountWei *big.Int,
+	amount units.Amount,
 	gasLimit uint64,
 	maxTipWei, maxFeeWei *big.Int,
 	dryRun bool,
 	noWait bool,
-) Result {
+) (res Result) {
+	defer func() { markResult(res) }()
+	res, params := prepareOne(contract, bs.RPC(), task, amount, gasLimit, maxTipWei, maxFeeWei)
+	if params == nil {
+		return res
+	}
+	if dryRun {
+		res.Hash = "(dry-run)"
+		return res
+	}
+
+	// 同一发送账户复用连接，nonce 在本地连续分配；估算、提交、等回执各有时限
+	res, txRes, stop := reusePrior(ctx, params, res)
+	if stop {
+		return res
+	}
+	var err error
+	if txRes == nil {
+		state.Mark(checkpoint.Record{Index: res.Index, Status: checkpoint.Pending})
+		txRes, err = bs.Submit(ctx, params)
+	}
+	if err != nil || noWait {
+		return applyTx(res, txRes, err, false)
+	}
+	markSent(res, txRes)
+	return confirmOne(ctx, bs, params, res, txRes)
+}
+
+// confirmOne 在 --confirm-timeout 内等待已提交交易的回执；超时（而非整个运行被中断）时记为已发送未确认，不计失败
+func confirmOne(ctx context.Context, bs *deposit.BatchSender, params *deposit.DepositParams, res Result, txRes *deposit.TxResult) Result {
+	budget := stepTimeouts.Confirm
+	if stuckTx != nil && stuckTx.Timeout > budget {
+		budget = stuckTx.Timeout
+	}
+	cctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+	err := bs.Confirm(cctx, params, txRes)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		res = applyTx(res, txRes, nil, false)
+		res.Unconfirmed = true
+		return res
+	}
+	return applyTx(res, txRes, err, true)
+}
+
+// prepareOne 签名阶段：确定提款凭证、计算 BLS 签名与 deposit_data_root 并组装交易参数，
+// 结果中记录本条金额与凭证；失败时 params 为 nil，错误记在结果里
+func prepareOne(
+	contract, rpc string,
+	task Task,
+	amount units.Amount,
+	gasLimit uint64,
+	maxTipWei, maxFeeWei *big.Int,
+) (Result, *deposit.DepositParams) {
 	idx := task.Index
 	it := task.Item
+	if it.presigned() {
+		return preparePresigned(contract, rpc, task, gasLimit, maxTipWei, maxFeeWei)
+	}
+	if !task.Amount.IsZero() {
+		amount = task.Amount
+	}
+	res := Result{Index: idx, Pubkey: it.ValidatorPublicKey, Amount: amount, WCType: deposit.WCTypeName(task.WCType)}
+	if task.Err != nil {
+		res.Err = task.Err
+		return res, nil
+	}
 
-	// 1) 生成 WC
-	wc, err := deposit.ComputeWithdrawalCredentialsFromEth1(it.WithdrawalAddress)
+	// 1) 0x00 默认用验证者公钥作为 BLS 提款公钥；缺少地址/公钥时从对应私钥推导
+	wc, err := deposit.ResolveWithdrawalCredentials(task.WCType, deposit.WithdrawalSource{
+		Address:       it.WithdrawalAddress,
+		PrivateKey:    it.WithdrawalPrivateKey,
+		BLSPubkey:     it.BLSWithdrawalPublicKey,
+		BLSPrivateKey: it.BLSWithdrawalPrivateKey,
+		FallbackBLS:   it.ValidatorPublicKey,
+	})
 	if err != nil {
-		return Result{Index: idx, Err: fmt.Errorf("index %d: 生成WC失败: %w", idx, err)}
+		res.Err = runsummary.BadInput(fmt.Errorf("index %d: 生成WC失败: %w", idx, err))
+		return res, nil
 	}
+	res.WC, res.Derived, res.DerivedFrom = wc.Credentials, wc.Derived, wc.DerivedFrom
 
 	// 2) 生成签名 + deposit_data_root
-	//    将交易金额 Wei -> Gwei，用于 BLS 的 amount 字段
-	amountGwei := new(big.Int).Div(new(big.Int).Set(amountWei), big.NewInt(1_000_000_000)).Uint64()
+	//    BLS 签名的 amount 字段以 gwei 计
+	amountGwei, err := amount.Gwei()
+	if err != nil {
+		res.Err = runsummary.BadInput(fmt.Errorf("index %d: 质押金额: %w", idx, err))
+		return res, nil
+	}
 
+	sk, err := validatorKey(it)
+	if err != nil {
+		res.Err = runsummary.BadInput(fmt.Errorf("index %d: 读取验证者私钥失败: %w", idx, err))
+		return res, nil
+	}
 	sigHex, rootHex, err := deposit.ComputeDepositSignatureAndRoot(
 		it.ValidatorPublicKey,
-		wc,
+		wc.Credentials,
 		amountGwei, // 与交易金额对齐
-		it.ValidatorPrivateKey,
+		sk,
+		depositDomain,
 	)
 	if err != nil {
-		return Result{Index: idx, Err: fmt.Errorf("index %d: 计算签名/根失败: %w", idx, err)}
+		res.Err = runsummary.BadInput(fmt.Errorf("index %d: 计算签名/根失败: %w", idx, err))
+		return res, nil
+	}
+	if preflightAction != "" {
+		// 私钥与 validator-public-key 不配对时签名照样算得出来，只有验证才能发现
+		if reason := signatureProblem(it.ValidatorPublicKey, wc.Credentials, amountGwei, sigHex, ""); reason != "" {
+			if err := preflight(&res, reason); err != nil {
+				res.Err = err
+				return res, nil
+			}
+		}
 	}
 
 	// 3) 准备参数
-	params := &deposit.DepositParams{
+	return res, &deposit.DepositParams{
 		Contract:             contract,
 		PrivateKeyHex:        it.DepositPrivateKey,
 		RPC:                  rpc,
 		PubkeyHex:            it.ValidatorPublicKey,
-		WCHex:                wc,
+		WCHex:                wc.Credentials,
 		SignatureHex:         sigHex,
 		RootHex:              rootHex,
-		AmountWei:            new(big.Int).Set(amountWei),
-		Nonce:                -1, // 自动取 nonce
+		Amount:               amount,
+		Nonce:                task.Nonce, // -1 时由 BatchSender 按账户分配
 		GasLimit:             gasLimit,
 		MaxPriorityFeePerGas: maxTipWei,
 		MaxFeePerGas:         maxFeeWei,
+		StuckTx:              stuckTx,
+		EstimateTimeout:      stepTimeouts.Estimate,
+		SubmitTimeout:        stepTimeouts.Submit,
 	}
+}
 
-	if dryRun {
-		return Result{
-			Index: idx,
-			Hash:  "(dry-run)",
-			Err:   nil,
+// preparePresigned deposit_data.json 条目：签名与金额取自文件，只重算 deposit_data_root 核对，
+// 并用存款域验证签名——签名无效的存款在执行层照常成功，但会被信标链忽略，这里只告警
+func preparePresigned(
+	contract, rpc string,
+	task Task,
+	gasLimit uint64,
+	maxTipWei, maxFeeWei *big.Int,
+) (Result, *deposit.DepositParams) {
+	idx := task.Index
+	it := task.Item
+	amount := units.FromGwei(it.Amount)
+	res := Result{Index: idx, Pubkey: it.Pubkey, Amount: amount, WC: it.WithdrawalCredentials}
+	if wc, err := hexutil.DecodeFixed(it.WithdrawalCredentials, hexutil.HashLen); err == nil {
+		res.WCType = deposit.WCTypeName(wc[0])
+	}
+	if it.DepositPrivateKey == "" {
+		res.Err = runsummary.BadInput(fmt.Errorf("index %d: 缺少发送账户（deposit_data.json 需配合 --deposit-key）", idx))
+		return res, nil
+	}
+
+	rootHex, err := deposit.ComputeDepositDataRoot(it.Pubkey, it.WithdrawalCredentials, it.Amount, it.Signature)
+	if err != nil {
+		res.Err = runsummary.BadInput(fmt.Errorf("index %d: 计算 deposit_data_root 失败: %w", idx, err))
+		return res, nil
+	}
+	if it.DepositDataRoot != "" && !strings.EqualFold(hexutil.Normalize(it.DepositDataRoot), rootHex) {
+		res.Err = runsummary.BadInput(fmt.Errorf("index %d: deposit_data_root 与文件不一致（文件 %s，重算 %s）", idx, hexutil.Normalize(it.DepositDataRoot), rootHex))
+		return res, nil
+	}
+	if reason := signatureProblem(it.Pubkey, it.WithdrawalCredentials, it.Amount, it.Signature, it.ForkVersion); reason != "" {
+		if preflightAction == "" {
+			// 未开启预检时保持原有行为：只告警，照常发送
+			log.Printf("⚠️ [#%d] %s，信标链会忽略这笔存款", idx, reason)
+		} else if err := preflight(&res, reason); err != nil {
+			res.Err = err
+			return res, nil
 		}
 	}
 
-	// 4) 发送交易：使用每条目的私钥新建 client
-	ctx2, cancel := context.WithTimeout(ctx, 180*time.Second)
-	defer cancel()
+	return res, &deposit.DepositParams{
+		Contract:             contract,
+		PrivateKeyHex:        it.DepositPrivateKey,
+		RPC:                  rpc,
+		PubkeyHex:            it.Pubkey,
+		WCHex:                it.WithdrawalCredentials,
+		SignatureHex:         it.Signature,
+		RootHex:              rootHex,
+		Amount:               amount,
+		Nonce:                task.Nonce,
+		GasLimit:             gasLimit,
+		MaxPriorityFeePerGas: maxTipWei,
+		MaxFeePerGas:         maxFeeWei,
+		StuckTx:              stuckTx,
+		EstimateTimeout:      stepTimeouts.Estimate,
+		SubmitTimeout:        stepTimeouts.Submit,
+	}
+}
 
-	cli, err := deposit.NewClient(ctx2, params.RPC, params.PrivateKeyHex)
+// signatureProblem 用本链存款域验证签名，通过时返回空串，否则返回原因；
+// forkVersion 非空且签名在该 fork_version 的存款域下有效时，指明是签名域不对
+func signatureProblem(pubkey, wc string, amountGwei uint64, sig, forkVersion string) string {
+	ok, err := deposit.VerifyDepositSignature(pubkey, wc, amountGwei, sig, depositDomain)
 	if err != nil {
-		return Result{Index: idx, Err: fmt.Errorf("index %d: NewClient 失败: %w", idx, err)}
+		return fmt.Sprintf("签名无法验证: %v", err)
 	}
-	defer cli.Close()
-
-	txRes, err := func() (*deposit.TxResult, error) {
-		if noWait {
-			return cli.SendDepositNoWait(ctx2, params)
+	if ok {
+		return ""
+	}
+	if fv, e := deposit.ParseForkVersion(forkVersion); e == nil {
+		if ok, _ := deposit.VerifyDepositSignatureInDomain(pubkey, wc, amountGwei, sig, deposit.DepositDomain(fv)); ok {
+			return fmt.Sprintf("签名来自 fork_version %s 的存款域，与本链（%s）不同；目标链确为该 fork_version 时用 --fork-version 指定",
+				hexutil.Normalize(forkVersion), depositDomain)
 		}
-		return cli.SendDeposit(ctx2, params)
-	}()
-	if err != nil {
-		return Result{Index: idx, Err: fmt.Errorf("index %d: SendDeposit 失败: %w", idx, err)}
 	}
+	return "签名无效"
+}
 
-	return Result{
-		Index:        idx,
-		Hash:         txRes.TxHash,
-		Err:          nil,
-		Nonce:        txRes.Nonce,
-		UsedGas:      txRes.UsedGas,
-		EstimatedGas: txRes.EstimatedGas,
-		BlockNumber:  txRes.BlockNumber,
-		BlockHash:    txRes.BlockHash,
+// preflight 按 --preflight-action 处理预检失败：reject 返回该条的错误；mark 记入 res 并告警，返回 nil 照常发送
+func preflight(res *Result, reason string) error {
+	if preflightAction == preflightReject {
+		return runsummary.BadInput(fmt.Errorf("index %d: 签名预检未通过（%s），未发送", res.Index, reason))
 	}
+	res.SigInvalid = reason
+	log.Printf("⚠️ [#%d] 签名预检未通过（%s），按 --preflight-action mark 照常发送", res.Index, reason)
+	return nil
 }
 
-// ---------------- 工具函数 ----------------
-
-func readJson(path string) ([]JsonItem, error) {
-	f, err := os.Open(path)
+// applyTx 把提交 / 回执结果合并进 res；waited 为 true 时检查回执状态
+func applyTx(res Result, txRes *deposit.TxResult, err error, waited bool) Result {
+	idx := res.Index
+	if txRes != nil {
+		res.Hash, res.Nonce, res.EstimatedGas = txRes.TxHash, txRes.Nonce, txRes.EstimatedGas
+	}
 	if err != nil {
-		return nil, err
+		res.Err = fmt.Errorf("index %d: SendDeposit 失败: %w", idx, err)
+		return res
 	}
-	defer f.Close()
-
-	var arr []JsonItem
-	dec := json.NewDecoder(f)
-	if err := dec.Decode(&arr); err != nil {
-		return nil, fmt.Errorf("解析 JSON 数组失败: %w", err)
+	if !waited {
+		return res
 	}
-	if len(arr) == 0 {
-		return nil, errors.New("JSON 数组为空")
+	res.UsedGas, res.BlockNumber, res.BlockHash, res.GasCostWei = txRes.UsedGas, txRes.BlockNumber, txRes.BlockHash, txRes.GasCostWei
+	res.Deposit, res.DepositMismatch = txRes.Deposit, txRes.DepositMismatch
+	if txRes.Status == 0 {
+		res.Err = fmt.Errorf("index %d: tx=%s: %w", idx, txRes.TxHash, errReverted)
 	}
-	return arr, nil
+	return res
 }
 
-func sliceRange[T any](in []T, start, limit int) []T {
-	if start < 0 {
-		start = 0
+// ---------------- 工具函数 ----------------
+
+// decideAmount 每笔质押金额：--amount-wei 优先；存款按 gwei 记账，必须是 1 gwei 的整数倍
+func decideAmount(amountWeiStr, amountETH string) (units.Amount, error) {
+	flagName, s, def := "--amount-eth", amountETH, units.Ether
+	if strings.TrimSpace(amountWeiStr) != "" {
+		flagName, s, def = "--amount-wei", amountWeiStr, units.Wei
 	}
-	if start >= len(in) {
-		return []T{}
+	amount, err := units.ParsePositive(s, def)
+	if err != nil {
+		return units.Amount{}, fmt.Errorf("%s: %w", flagName, err)
 	}
-	end := len(in)
-	if limit >= 0 {
-		if start+limit < end {
-			end = start + limit
-		}
+	if _, err := amount.Gwei(); err != nil {
+		return units.Amount{}, fmt.Errorf("%s: 存款金额须为 1 gwei 的整数倍: %w", flagName, err)
 	}
-	return in[start:end]
+	return amount, nil
 }
 
-func decideAmount(amountWeiStr string, amountETH float64) (*big.Int, error) {
-	if strings.TrimSpace(amountWeiStr) != "" {
-		z := new(big.Int)
-		_, ok := z.SetString(strings.TrimSpace(amountWeiStr), 10)
-		if !ok {
-			return nil, fmt.Errorf("无法解析 --amount-wei=%s", amountWeiStr)
-		}
-		if z.Sign() <= 0 {
-			return nil, fmt.Errorf("amount-wei 必须 > 0")
-		}
-		return z, nil
+// maxFuzzGwei --fuzz-amounts 上限：compounding 验证者的最大有效余额 2048 ETH
+const maxFuzzGwei = 2048 * 1_000_000_000
+
+// parseAmountRange 解析 "min..max"（默认单位 ETH，可带小数与单位后缀）为 gwei 区间
+func parseAmountRange(s string) (minGwei, maxGwei uint64, err error) {
+	lo, hi, ok := strings.Cut(strings.TrimSpace(s), "..")
+	if !ok {
+		return 0, 0, fmt.Errorf("格式应为 min..max，例如 1..64")
+	}
+	if minGwei, err = units.ParseGwei(lo, units.Ether); err != nil {
+		return 0, 0, err
+	}
+	if maxGwei, err = units.ParseGwei(hi, units.Ether); err != nil {
+		return 0, 0, err
 	}
-	if amountETH <= 0 {
-		return nil, fmt.Errorf("amount-eth 必须 > 0")
+	if minGwei == 0 || minGwei > maxGwei {
+		return 0, 0, fmt.Errorf("需要 0 < min <= max")
 	}
-	// ETH -> Wei：ETH * 1e18
-	eth := big.NewFloat(amountETH)
-	weiPerEth := new(big.Float).SetInt(big.NewInt(1_000_000_000_000_000_000)) // 1e18
-	weiF := new(big.Float).Mul(eth, weiPerEth)
-	z := new(big.Int)
-	weiF.Int(z) // 向下取整即可
-	if z.Sign() <= 0 {
-		return nil, fmt.Errorf("换算后的 Wei 非法")
+	if maxGwei > maxFuzzGwei {
+		return 0, 0, fmt.Errorf("max 不能超过 2048 ETH（最大有效余额）")
 	}
-	return z, nil
+	return minGwei, maxGwei, nil
 }
 
-func gweiF(v float64) *big.Int {
-	// Gwei -> Wei：1e9
-	f := big.NewFloat(v)
-	unit := new(big.Float).SetInt(big.NewInt(1_000_000_000)) // 1e9
-	w := new(big.Float).Mul(f, unit)
-	z := new(big.Int)
-	w.Int(z)
-	return z
+// derivedValues JSON 中缺失、由私钥推导出的提款地址 / BLS 提款公钥（按条目下标），便于追溯
+func derivedValues(results []Result) map[string]map[string]string {
+	out := map[string]map[string]string{}
+	for _, r := range results {
+		if r.Derived != "" {
+			out[strconv.Itoa(r.Index)] = map[string]string{
+				"value":                  r.Derived,
+				"from":                   r.DerivedFrom,
+				"withdrawal_credentials": r.WC,
+			}
+		}
+	}
+	return out
+}
+
+// feeWei 解析 --<name> 的费用（默认单位 Gwei）为 wei；0 表示未指定，返回 nil
+func feeWei(name, s string) *big.Int {
+	a, err := units.ParseAmount(s, units.Gwei)
+	if err != nil {
+		log.Fatalf("--%s: %v", name, err)
+	}
+	if a.IsZero() {
+		return nil
+	}
+	return a.Wei()
 }
 
 func printResult(r Result) {
 	prefix := fmt.Sprintf("[#%d]", r.Index)
+	if r.WCType != "" {
+		prefix += " wc=" + r.WCType
+	}
+	if !r.Amount.IsZero() {
+		prefix += fmt.Sprintf(" amount=%s ETH", r.Amount.ETH())
+	}
+	if r.Derived != "" {
+		prefix += fmt.Sprintf(" derived=%s(from %s)", r.Derived, r.DerivedFrom)
+	}
 	if r.Err != nil {
 		log.Printf("%s ❌ 失败: %v", prefix, r.Err)
 		return
 	}
-	log.Printf("%s ✅ 成功: tx=%s nonce=%d gasUsed=%d estGas=%d block=%d(%s)",
-		prefix, r.Hash, r.Nonce, r.UsedGas, r.EstimatedGas, r.BlockNumber, r.BlockHash)
+	if r.Unconfirmed {
+		log.Printf("%s ⏳ 已发送，未在时限内确认: tx=%s nonce=%d estGas=%d（可 --resume 续查回执）", prefix, r.Hash, r.Nonce, r.EstimatedGas)
+		return
+	}
+	depositIndex := ""
+	if r.Deposit != nil {
+		depositIndex = fmt.Sprintf(" deposit_index=%d", r.Deposit.Index)
+	}
+	log.Printf("%s ✅ 成功: tx=%s nonce=%d gasUsed=%d estGas=%d block=%d(%s)%s",
+		prefix, r.Hash, r.Nonce, r.UsedGas, r.EstimatedGas, r.BlockNumber, r.BlockHash, depositIndex)
+	if len(r.DepositMismatch) > 0 {
+		log.Printf("%s ⚠️ DepositEvent 与提交参数不一致: %s", prefix, strings.Join(r.DepositMismatch, "; "))
+	}
+}
+
+// unconfirmedCount 已发送但未在 --confirm-timeout 内确认的条目数
+func unconfirmedCount(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Unconfirmed {
+			n++
+		}
+	}
+	return n
+}
+
+// sigInvalidCount 签名预检未通过但照常发送（--preflight-action mark）的条目数
+func sigInvalidCount(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.SigInvalid != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// depositMismatches 回执中 DepositEvent 与提交参数不一致（或缺失）的条目数
+func depositMismatches(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if len(r.DepositMismatch) > 0 {
+			n++
+		}
+	}
+	return n
 }
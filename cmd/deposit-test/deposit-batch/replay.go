@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"n42-test/internal/manifest"
+	"n42-test/internal/rundir"
+)
+
+// 重放时不沿用的参数：重放控制本身、会覆盖原运行产物的输出位置，以及会跳过条目的续跑/去重
+var replaySkip = map[string]bool{
+	"replay":        true,
+	"replay-nonces": true,
+	"manifest":      true,
+	"output":        true,
+	"runs-dir":      true,
+	"state-file":    true,
+	"resume":        true,
+	"skip-existing": true,
+	"retry-failed":  true,
+}
+
+// replayPlan 重放一次运行所需的信息
+type replayPlan struct {
+	Run     rundir.Info
+	Applied []string       // 从原运行沿用的参数
+	Nonces  map[int]uint64 // --replay-nonces：按条目下标固定的 nonce
+}
+
+// loadReplay 找到 root 下的运行 id，把其清单中的参数套用到本次运行：
+// 本次命令行显式给出的参数优先（例如换成重置后的 devnet 的 -rpc）；
+// 原运行自动生成的 seed 从汇总中取回；withNonces 时读取原结果中每条交易的 nonce。
+func loadReplay(root, id string, withNonces bool) (*replayPlan, error) {
+	info, err := rundir.Find(root, id)
+	if err != nil {
+		return nil, err
+	}
+	mf := info.Manifest
+	if mf == nil {
+		return nil, fmt.Errorf("run %s has no readable %s", info.ID, rundir.ManifestFile)
+	}
+	if mf.Tool != "deposit-batch" {
+		return nil, fmt.Errorf("run %s was made by %s, not deposit-batch", info.ID, mf.Tool)
+	}
+
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	plan := &replayPlan{Run: info}
+	names := make([]string, 0, len(mf.Config))
+	for name := range mf.Config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := mf.Config[name]
+		if replaySkip[name] || explicit[name] || v == "<redacted>" || flag.Lookup(name) == nil {
+			continue
+		}
+		if flag.Lookup(name).Value.String() == v {
+			continue
+		}
+		if err := flag.Set(name, v); err != nil {
+			return nil, fmt.Errorf("replay flag -%s=%s: %w", name, v, err)
+		}
+		plan.Applied = append(plan.Applied, name+"="+v)
+	}
+	// --seed 为 0 时原运行自动生成了种子，实际值记在汇总里
+	if s, ok := mf.Summary["seed"].(json.Number); ok && !explicit["seed"] && mf.Config["seed"] == "0" {
+		if err := flag.Set("seed", s.String()); err != nil {
+			return nil, fmt.Errorf("replay seed %s: %w", s, err)
+		}
+		plan.Applied = append(plan.Applied, "seed="+s.String())
+	}
+
+	if withNonces {
+		if plan.Nonces, err = replayNonces(info); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// replayNonces 原运行 results/results.json 中已发出交易的 nonce（按条目下标）
+func replayNonces(info rundir.Info) (map[int]uint64, error) {
+	path := filepath.Join(info.Dir, rundir.Results, "results.json")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("--replay-nonces needs the original results: %w", err)
+	}
+	var recs []resultRecord
+	if err := json.Unmarshal(b, &recs); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	out := map[int]uint64{}
+	for _, r := range recs {
+		if r.TxHash == "" || r.TxHash == "(dry-run)" {
+			continue
+		}
+		out[r.Index] = r.Nonce
+	}
+	return out, nil
+}
+
+// checkReplayInput 确认输入文件与原运行是同一份（sha256 一致）
+func checkReplayInput(path string, mf *manifest.Manifest) error {
+	if mf.InputSHA256 == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != mf.InputSHA256 {
+		return fmt.Errorf("%s 与原运行的输入不一致（sha256 %s，原 %s）", path, sum, mf.InputSHA256)
+	}
+	return nil
+}
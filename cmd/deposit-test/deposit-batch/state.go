@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/checkpoint"
+	"n42-test/internal/deposit"
+	"n42-test/internal/keys"
+	"n42-test/internal/registry"
+	"n42-test/internal/resultout"
+	"n42-test/internal/runsummary"
+)
+
+// state 真实发送时的断点状态文件（分叉模拟、dry-run 时为 nil，不写）
+var state *checkpoint.Writer
+
+// markResult 记录一条的最终状态；dry-run 结果不记录
+func markResult(r Result) {
+	if state == nil || r.Hash == "(dry-run)" {
+		return
+	}
+	rec := checkpoint.Record{Index: r.Index, TxHash: r.Hash, Nonce: r.Nonce, Block: r.BlockNumber}
+	switch {
+	case r.Err != nil:
+		rec.Status, rec.Error = checkpoint.Failed, r.Err.Error()
+	case r.BlockNumber > 0:
+		rec.Status = checkpoint.Confirmed
+	default:
+		rec.Status = checkpoint.Sent // --no-wait
+	}
+	state.Mark(rec)
+	if r.Err == nil {
+		guard.recordKey(r, r.Hash, r.Nonce, r.BlockNumber)
+	}
+}
+
+// resolveUnconfirmed 运行结束后统一补查已发送未确认条目的回执（最长 timeout）：上链的改记为成功或 revert，
+// 同步更新断点状态与 sum；仍查不到的保持 unconfirmed。补查不比对 DepositEvent
+func resolveUnconfirmed(ctx context.Context, rpc string, results []Result, sum *runsummary.RunSummary, timeout time.Duration) {
+	var idx []int
+	var hashes []string
+	for i, r := range results {
+		if r.Unconfirmed {
+			idx, hashes = append(idx, i), append(hashes, r.Hash)
+		}
+	}
+	checkpoint.Resolve(ctx, rpc, hashes, timeout, func(i int, rcpt *gethtypes.Receipt, reverted bool) {
+		r := &results[idx[i]]
+		r.Unconfirmed = false
+		r.UsedGas, r.BlockNumber, r.BlockHash = rcpt.GasUsed, rcpt.BlockNumber.Uint64(), rcpt.BlockHash.Hex()
+		if rcpt.EffectiveGasPrice != nil {
+			r.GasCostWei = new(big.Int).Mul(new(big.Int).SetUint64(rcpt.GasUsed), rcpt.EffectiveGasPrice)
+		}
+		if reverted {
+			r.Err = fmt.Errorf("index %d: tx=%s: %w", r.Index, r.Hash, errReverted)
+		}
+		sum.Amend(r.Err, r.UsedGas, r.GasCostWei)
+		markResult(*r)
+		printResult(*r)
+	})
+}
+
+// sendGuard 重发保护：续跑 / 重跑时各条目上次发出的交易，以及跨运行的存款幂等键登记
+type sendGuard struct {
+	cli       *ethclient.Client        // 核对上次交易用的连接
+	priors    map[int]checkpoint.Prior // 续跑 / 重跑时各下标上次发出、尚未确认的交易
+	keys      *registry.Registry       // 存款幂等键登记（未打开登记库时为 nil）
+	checkKeys bool                     // 发送前按幂等键查找上次的交易（--allow-duplicate-deposits 时只登记不查）
+	runID     string
+}
+
+// guard 只在真实发送时启用（分叉模拟、dry-run 时为 nil）
+var guard *sendGuard
+
+// collectPriors 汇总上次已发出、尚未确认的交易：断点状态中未确认的记录，及 --retry-failed 结果中失败但有交易哈希的记录
+func collectPriors(resumed checkpoint.State, retry *resultout.RetrySet[resultRecord]) map[int]checkpoint.Prior {
+	return checkpoint.CollectPriors(resumed, retry, func(r resultRecord) (int, checkpoint.Prior, bool) {
+		return r.Index, checkpoint.Prior{TxHash: r.TxHash, Nonce: r.Nonce}, r.Error != "" && r.TxHash != ""
+	})
+}
+
+// prior 本条上次发出的交易：续跑 / 重跑的记录优先，其次按幂等键查登记库
+func (g *sendGuard) prior(res Result) (p checkpoint.Prior, source string, ok bool) {
+	if p, ok := g.priors[res.Index]; ok {
+		return p, "上次运行", true
+	}
+	if !g.checkKeys || g.keys == nil || res.IdemKey == "" {
+		return checkpoint.Prior{}, "", false
+	}
+	e, found, err := g.keys.LookupKey(res.IdemKey)
+	if err != nil {
+		log.Printf("⚠️ [#%d] 查询幂等键登记失败，照常发送: %v", res.Index, err)
+		return checkpoint.Prior{}, "", false
+	}
+	if !found || e.TxHash == "" {
+		return checkpoint.Prior{}, "", false
+	}
+	source = "幂等键登记"
+	if e.RunID != "" {
+		source += "（run " + e.RunID + "）"
+	}
+	return checkpoint.Prior{TxHash: e.TxHash, Nonce: e.Nonce, From: e.From}, source, true
+}
+
+// recordKey 登记本条幂等键对应的交易；block 为 0 时记为已广播未确认
+func (g *sendGuard) recordKey(res Result, txHash string, nonce, block uint64) {
+	if g == nil || g.keys == nil || res.IdemKey == "" || txHash == "" {
+		return
+	}
+	status := registry.KeySent
+	if block > 0 {
+		status = registry.KeyConfirmed
+	}
+	e := registry.KeyEntry{Key: res.IdemKey, Pubkey: res.Pubkey, Status: status, TxHash: txHash, From: res.From, Nonce: nonce, Block: block, RunID: g.runID}
+	if err := g.keys.RecordKey(e); err != nil {
+		log.Printf("⚠️ [#%d] 写入幂等键登记失败: %v", res.Index, err)
+	}
+}
+
+// markSent 交易已广播：写断点状态并登记幂等键，崩溃或下次运行时据此核对而不是重发
+func markSent(res Result, tx *deposit.TxResult) {
+	state.Mark(checkpoint.Record{Index: res.Index, Status: checkpoint.Sent, TxHash: tx.TxHash, Nonce: tx.Nonce})
+	guard.recordKey(res, tx.TxHash, tx.Nonce, 0)
+}
+
+// reusePrior 发送前核对本条上次发出的交易（续跑 / 重跑的记录，或登记库中同一幂等键的交易）：
+// 已成功上链或仍在交易池中的沿用（返回的 tx 非 nil，之后照常等回执）；nonce 已被占用或核对失败时不重发
+// （stop 为 true，原因记在 res）；其余（没有记录、已丢弃、已 revert）照常提交
+func reusePrior(ctx context.Context, params *deposit.DepositParams, res Result) (_ Result, tx *deposit.TxResult, stop bool) {
+	if key, err := params.IdempotencyKey(); err == nil {
+		res.IdemKey = key
+	}
+	if guard == nil {
+		return res, nil, false
+	}
+	priv, err := keys.ParseECDSA(params.PrivateKeyHex)
+	if err != nil {
+		res.Err = fmt.Errorf("index %d: privKey 解析失败: %w", res.Index, err)
+		return res, nil, true
+	}
+	from := crypto.PubkeyToAddress(priv.PublicKey)
+	res.From = from.Hex()
+	p, source, ok := guard.prior(res)
+	if !ok {
+		return res, nil, false
+	}
+	cctx, cancel := context.WithTimeout(ctx, stepTimeouts.Submit)
+	defer cancel()
+	v, reuse, err := checkpoint.Reuse(cctx, guard.cli, from, p)
+	switch {
+	case err != nil:
+		res.Hash, res.Nonce = p.TxHash, p.Nonce
+		res.Err = fmt.Errorf("index %d: %s的交易未重发，避免重复存款: %w", res.Index, source, err)
+		return res, nil, true
+	case reuse:
+		log.Printf("[#%d] ♻️ 沿用%s的交易 tx=%s（%s），不重新发送", res.Index, source, p.TxHash, v)
+		res.Reused = v.String()
+		txRes := &deposit.TxResult{TxHash: p.TxHash, Nonce: p.Nonce}
+		markSent(res, txRes)
+		return res, txRes, false
+	case v == checkpoint.Mined:
+		log.Printf("[#%d] %s的交易 tx=%s 已 revert，重新发送", res.Index, source, p.TxHash)
+	}
+	return res, nil, false
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+)
+
+// beaconNotFound 超时仍未出现在信标状态中的质押
+const beaconNotFound = "not_found"
+
+// beaconCheck 一笔质押在信标状态中的核验结果
+type beaconCheck struct {
+	Status           string // beaconstate.Status* 或 beaconNotFound
+	Index            int    // 验证者下标
+	SeenSlot         uint64 // 首次出现时的信标 slot
+	SeenAfter        time.Duration
+	EligibilityEpoch uint64
+	ActivationEpoch  uint64
+}
+
+func (c *beaconCheck) found() bool { return c != nil && c.Status != beaconNotFound }
+
+// epochString FarFutureEpoch 显示为 "-"
+func epochString(e uint64) string {
+	if e == beaconstate.FarFutureEpoch {
+		return "-"
+	}
+	return strconv.FormatUint(e, 10)
+}
+
+// verifyBeacon 质押成功后轮询信标状态（latest 执行层区块经 ResolveBeaconByEth1Hash 解析），
+// 直到所有成功条目的验证者公钥都出现或超时；每轮用最新状态刷新已出现条目的激活状态。
+// 结果写回 results[i].Beacon
+func verifyBeacon(ctx context.Context, r beaconext.BeaconReader, results []Result, slotsPerEpoch uint64, poll, timeout time.Duration) {
+	want := map[string][]int{} // 公钥 -> results 下标（同一公钥可能有多笔追加质押）
+	for i, res := range results {
+		if res.Err == nil && res.Pubkey != "" {
+			pk := beaconstate.NormPubkey(res.Pubkey)
+			want[pk] = append(want[pk], i)
+		}
+	}
+	if len(want) == 0 {
+		log.Printf("⚠️ 信标核验：没有成功的质押")
+		return
+	}
+	log.Printf("🔎 信标核验：等待 %d 个验证者公钥出现在信标状态中（最长 %s）……", len(want), timeout)
+
+	began := time.Now()
+	deadline := began.Add(timeout)
+	seen := map[string]bool{}
+	for {
+		st, err := latestBeaconState(ctx, r)
+		if err != nil {
+			log.Printf("⚠️ 读取信标状态失败: %v", err)
+		} else {
+			epoch := st.Epoch(slotsPerEpoch)
+			index := st.Index()
+			for pk, idxs := range want {
+				vi, ok := index[pk]
+				if !ok {
+					continue
+				}
+				v := st.Validators[vi]
+				balance := st.Balance(vi)
+				for _, i := range idxs {
+					c := results[i].Beacon
+					if c == nil {
+						c = &beaconCheck{SeenSlot: st.Slot, SeenAfter: time.Since(began)}
+						results[i].Beacon = c
+					}
+					c.Index, c.Status = vi, v.Status(epoch, balance)
+					c.EligibilityEpoch, c.ActivationEpoch = v.ActivationEligibilityEpoch, v.ActivationEpoch
+				}
+				if !seen[pk] {
+					seen[pk] = true
+					log.Printf("   %s… 出现在 slot %d（validator_index=%d，%s）", pk[:18], st.Slot, vi, time.Since(began).Round(time.Second))
+				}
+			}
+			log.Printf("🔎 slot %d（epoch %d）：已出现 %d/%d", st.Slot, epoch, len(seen), len(want))
+			if len(seen) == len(want) {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			log.Printf("⚠️ 信标核验超时（%s），%d 个公钥未出现", timeout, len(want)-len(seen))
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(poll):
+		}
+	}
+
+	for pk, idxs := range want {
+		if seen[pk] {
+			continue
+		}
+		for _, i := range idxs {
+			results[i].Beacon = &beaconCheck{Status: beaconNotFound, Index: -1}
+		}
+	}
+	printBeaconReport(results)
+}
+
+// latestBeaconState 执行层 latest 块对应的信标状态
+func latestBeaconState(ctx context.Context, r beaconext.BeaconReader) (*beaconstate.State, error) {
+	blk, err := r.EthGetBlockByNumber(ctx, "latest", false)
+	if err != nil {
+		return nil, fmt.Errorf("get latest block: %w", err)
+	}
+	snap, err := r.ResolveBeaconByEth1Hash(ctx, blk.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return beaconstate.Parse(snap.BeaconStateRaw)
+}
+
+// printBeaconReport 逐条打印核验结果与按状态的计数
+func printBeaconReport(results []Result) {
+	counts := map[string]int{}
+	for _, r := range results {
+		c := r.Beacon
+		if c == nil {
+			continue
+		}
+		counts[c.Status]++
+		if !c.found() {
+			log.Printf("❌ [#%d] %s 未出现在信标状态中", r.Index, r.Pubkey)
+			continue
+		}
+		log.Printf("✅ [#%d] %s validator_index=%d status=%s eligibility_epoch=%s activation_epoch=%s (slot %d, +%s)",
+			r.Index, r.Pubkey, c.Index, c.Status, epochString(c.EligibilityEpoch), epochString(c.ActivationEpoch),
+			c.SeenSlot, c.SeenAfter.Round(time.Second))
+	}
+	log.Printf("🔎 信标核验汇总：%v", counts)
+}
+
+// beaconSummary 写入运行清单的核验计数（按状态）
+func beaconSummary(results []Result) map[string]int {
+	counts := map[string]int{}
+	for _, r := range results {
+		if r.Beacon != nil {
+			counts[r.Beacon.Status]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/blsutil"
+	"n42-test/internal/deposit"
+	"n42-test/internal/envflag"
+	"n42-test/internal/units"
+)
+
+// 激活流量（churn）上限测试：一次性提交超过每纪元激活上限的质押，
+// 再从信标状态统计 activation_eligibility_epoch / activation_epoch 的分布，验证链是否正确限流。
+
+type JsonItem struct {
+	WithdrawalPrivateKey string `json:"withdrawal-private-key"`
+	ValidatorPublicKey   string `json:"validator-public-key"`
+	WithdrawalAddress    string `json:"withdrawal-address"`
+	ValidatorPrivateKey  string `json:"validator-private-key"`
+	DepositPrivateKey    string `json:"deposit-private-key"`
+}
+
+// depositDomain 签名使用的存款域（--fork-version / --genesis-validators-root）
+var depositDomain deposit.DepositDomainConfig
+
+func main() {
+	blsutil.EnsureInit()
+
+	jsonPath := flag.String("json", "deposit-data.json", "JSON 文件路径（数组）")
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC（同时用于 consensusBeaconExt 查询）")
+	contractAddr := flag.String("contract", "", "Deposit 合约地址（0x…）")
+	count := flag.Int("count", -1, "提交多少条质押（<0 表示 JSON 中全部）")
+	workers := flag.Int("workers", 8, "发送并发度")
+	amountETH := flag.String("amount-eth", "32", "每笔质押金额（默认单位 ETH，可带后缀 eth|gwei|wei；须为 1 gwei 的整数倍）")
+	churnName := flag.String("churn", string(beaconstate.ChurnElectra), "激活限流规则：deneb（按个数，看 activation_epoch）|electra（按余额，看 activation_eligibility_epoch）")
+	slotsPerEpoch := flag.Uint64("slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "每纪元 slot 数")
+	poll := flag.Duration("poll", 12*time.Second, "轮询信标状态的间隔")
+	timeout := flag.Duration("timeout", time.Hour, "等待全部激活的最长时间")
+	skipDeposit := flag.Bool("skip-deposit", false, "不发送质押，只统计 JSON 中公钥的激活分布（用于复查上一次运行）")
+	forkVersion := flag.String("fork-version", "", "存款签名域的 fork_version（4 字节十六进制，空为 0x00000000）")
+	genesisValidatorsRoot := flag.String("genesis-validators-root", "", "存款签名域的 genesis_validators_root（32 字节十六进制，空为零，即规范取值）")
+	envflag.Parse("deposit-churn")
+
+	domain, err := deposit.ParseDepositDomainConfig(*forkVersion, *genesisValidatorsRoot)
+	if err != nil {
+		log.Fatalf("存款签名域配置错误: %v", err)
+	}
+	depositDomain = domain
+	amountGwei, err := units.ParseGwei(*amountETH, units.Ether)
+	if err == nil && amountGwei == 0 {
+		err = errors.New("质押金额必须 > 0")
+	}
+	if err != nil {
+		log.Fatalf("--amount-eth 错误: %v", err)
+	}
+
+	rule, err := beaconstate.ParseChurnRule(*churnName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	items, err := readJson(*jsonPath)
+	if err != nil {
+		log.Fatalf("读取 JSON 失败: %v", err)
+	}
+	if *count >= 0 && *count < len(items) {
+		items = items[:*count]
+	}
+
+	ctx := context.Background()
+	reader := beaconext.NewClient(*rpcURL)
+
+	// ---------- 提交前的状态：算出本纪元上限，提示是否足以超过 ----------
+	before, err := beaconstate.FetchLatest(ctx, reader)
+	if err != nil {
+		log.Fatalf("读取信标状态失败: %v", err)
+	}
+	startEpoch := before.Epoch(*slotsPerEpoch)
+	limit := rule.Limit(before, startEpoch)
+	need := limit + 1
+	if rule == beaconstate.ChurnElectra {
+		need = limit/amountGwei + 1
+	}
+	active, _ := before.ActiveCount(startEpoch)
+	log.Printf("当前纪元 %d，激活验证者 %d，每纪元上限 %d %s；提交 %d 条（至少需要 %d 条才能超过上限）",
+		startEpoch, active, limit, rule.Unit(), len(items), need)
+	if uint64(len(items)) < need {
+		log.Printf("⚠️ 条目数不足以超过单纪元上限，结果只能验证“未超限时不被延迟”")
+	}
+
+	// ---------- 提交质押 ----------
+	if !*skipDeposit {
+		if *contractAddr == "" || !common.IsHexAddress(*contractAddr) {
+			log.Fatalf("必须提供合法的 --contract 合约地址 (0x...)")
+		}
+		sent := sendAll(ctx, *rpcURL, *contractAddr, items, *workers, amountGwei)
+		log.Printf("已发送 %d/%d 条质押", sent, len(items))
+	}
+
+	// ---------- 等待激活 ----------
+	want := map[string]bool{}
+	for _, it := range items {
+		want[beaconstate.NormPubkey(it.ValidatorPublicKey)] = true
+	}
+	final := waitActivations(ctx, reader, want, *poll, *timeout)
+	if final == nil {
+		log.Fatalf("未能读取到信标状态")
+	}
+
+	// ---------- 报告 ----------
+	ours := collect(final, want)
+	printDistribution("activation_eligibility_epoch", ours, func(v beaconstate.Validator) uint64 { return v.ActivationEligibilityEpoch })
+	printDistribution("activation_epoch", ours, func(v beaconstate.Validator) uint64 { return v.ActivationEpoch })
+
+	violations := 0
+	fmt.Printf("\n按 %s 规则的每纪元占用（全部验证者，纪元 >= %d）：\n", rule, startEpoch)
+	fmt.Printf("  %-8s %-6s %-18s %-18s\n", "epoch", "count", "used", "limit")
+	for _, u := range rule.Usage(final, startEpoch) {
+		mark := ""
+		if u.Used(rule) > u.Limit {
+			mark = "  ❌ 超限"
+			violations++
+		}
+		fmt.Printf("  %-8d %-6d %-18d %-18d%s\n", u.Epoch, u.Count, u.Used(rule), u.Limit, mark)
+	}
+	if rule == beaconstate.ChurnElectra {
+		fmt.Println("  注：electra 上限按当前激活集合估算；单笔超过上限的存款会跨纪元消耗，可能出现一次合理的超出")
+	}
+
+	pending := len(want) - len(ours)
+	for _, v := range ours {
+		if v.ActivationEpoch == beaconstate.FarFutureEpoch {
+			pending++
+		}
+	}
+	fmt.Printf("\n结果：%d 个验证者，未激活 %d，超限纪元 %d\n", len(want), pending, violations)
+	if violations > 0 {
+		os.Exit(1)
+	}
+}
+
+// sendAll 并发发送质押（不等待回执，尽量在同一纪元内全部进入）
+func sendAll(ctx context.Context, rpc, contract string, items []JsonItem, workers int, amountGwei uint64) int {
+	if workers <= 0 {
+		workers = 4
+	}
+	var (
+		mu   sync.Mutex
+		sent int
+		wg   sync.WaitGroup
+	)
+	in := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range in {
+				if err := sendOne(ctx, rpc, contract, items[i], amountGwei); err != nil {
+					log.Printf("[#%d] ❌ %v", i, err)
+					continue
+				}
+				mu.Lock()
+				sent++
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range items {
+		in <- i
+	}
+	close(in)
+	wg.Wait()
+	return sent
+}
+
+func sendOne(ctx context.Context, rpc, contract string, it JsonItem, amountGwei uint64) error {
+	wc, err := deposit.ResolveWithdrawalCredentials(deposit.WCTypeEth1, deposit.WithdrawalSource{
+		Address:    it.WithdrawalAddress,
+		PrivateKey: it.WithdrawalPrivateKey,
+	})
+	if err != nil {
+		return fmt.Errorf("生成WC失败: %w", err)
+	}
+	if wc.Derived != "" {
+		log.Printf("%s: 提款地址由 %s 推导: %s", it.ValidatorPublicKey, wc.DerivedFrom, wc.Derived)
+	}
+	sig, root, err := deposit.ComputeDepositSignatureAndRoot(it.ValidatorPublicKey, wc.Credentials, amountGwei, it.ValidatorPrivateKey, depositDomain)
+	if err != nil {
+		return fmt.Errorf("计算签名/根失败: %w", err)
+	}
+	ctx2, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	cli, err := deposit.NewClient(ctx2, rpc, it.DepositPrivateKey)
+	if err != nil {
+		return fmt.Errorf("NewClient 失败: %w", err)
+	}
+	defer cli.Close()
+	_, err = cli.SendDepositNoWait(ctx2, &deposit.DepositParams{
+		Contract:      contract,
+		PrivateKeyHex: it.DepositPrivateKey,
+		RPC:           rpc,
+		PubkeyHex:     it.ValidatorPublicKey,
+		WCHex:         wc.Credentials,
+		SignatureHex:  sig,
+		RootHex:       root,
+		Amount:        units.FromGwei(amountGwei),
+		Nonce:         -1,
+	})
+	return err
+}
+
+// waitActivations 轮询信标状态，直到 want 中的公钥全部分配了 activation_epoch 或超时；返回最后一次读到的状态
+func waitActivations(ctx context.Context, r beaconext.BeaconReader, want map[string]bool, poll, timeout time.Duration) *beaconstate.State {
+	deadline := time.Now().Add(timeout)
+	var last *beaconstate.State
+	for {
+		st, err := beaconstate.FetchLatest(ctx, r)
+		if err != nil {
+			log.Printf("⚠️ 读取信标状态失败: %v", err)
+		} else {
+			last = st
+			ours := collect(st, want)
+			scheduled := 0
+			for _, v := range ours {
+				if v.ActivationEpoch != beaconstate.FarFutureEpoch {
+					scheduled++
+				}
+			}
+			log.Printf("slot %d：已入队 %d/%d，已分配 activation_epoch %d/%d", st.Slot, len(ours), len(want), scheduled, len(want))
+			if scheduled == len(want) {
+				return st
+			}
+		}
+		if time.Now().After(deadline) {
+			log.Printf("⚠️ 等待激活超时（%s），按当前状态出报告", timeout)
+			return last
+		}
+		time.Sleep(poll)
+	}
+}
+
+func collect(st *beaconstate.State, want map[string]bool) []beaconstate.Validator {
+	var out []beaconstate.Validator
+	for _, v := range st.Validators {
+		if want[beaconstate.NormPubkey(v.Pubkey)] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// printDistribution 打印本次验证者在某个纪元字段上的分布
+func printDistribution(field string, vs []beaconstate.Validator, get func(beaconstate.Validator) uint64) {
+	hist := map[uint64]int{}
+	for _, v := range vs {
+		hist[get(v)]++
+	}
+	epochs := make([]uint64, 0, len(hist))
+	for e := range hist {
+		epochs = append(epochs, e)
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+
+	fmt.Printf("\n%s 分布（本次 %d 个验证者）：\n", field, len(vs))
+	for _, e := range epochs {
+		label := fmt.Sprintf("%d", e)
+		if e == beaconstate.FarFutureEpoch {
+			label = "未分配"
+		}
+		fmt.Printf("  %-8s %4d %s\n", label, hist[e], strings.Repeat("█", hist[e]))
+	}
+}
+
+func readJson(path string) ([]JsonItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var arr []JsonItem
+	if err := json.NewDecoder(f).Decode(&arr); err != nil {
+		return nil, fmt.Errorf("解析 JSON 数组失败: %w", err)
+	}
+	if len(arr) == 0 {
+		return nil, errors.New("JSON 数组为空")
+	}
+	return arr, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"n42-test/internal/blsutil"
+	"n42-test/internal/deposit"
+	"n42-test/internal/envflag"
+	"n42-test/internal/units"
+)
+
+// 存款故障注入：在一份正确的存款数据上施加指定故障并发送，检查合约是否按预期 revert，
+// 或（签名类故障）合约接受、交给信标链忽略。取代原先各自硬编码密钥的 deposit-sig-tamper /
+// deposit-sig-tamper-but-is-validator / deposit-amount-err。
+
+// 回执状态之外的判定结果
+const (
+	outcomeRejected = "rejected" // 合约 revert
+	outcomeAccepted = "accepted" // 合约接受
+)
+
+func main() {
+	blsutil.EnsureInit()
+
+	faultList := flag.String("fault", "", "故障类型，逗号分隔或 all："+strings.Join(deposit.FaultNames(), "|"))
+	keysPath := flag.String("keys", "", "密钥材料 JSON（单个对象或 accounts.json 数组）：validator-public-key、validator-private-key、withdrawal-address|withdrawal-credentials、deposit-private-key")
+	index := flag.Int("index", 0, "-keys 为数组时使用第几条（基于0）")
+	depositKey := flag.String("deposit-key", "", "发送账户私钥（覆盖密钥材料中的 deposit-private-key）")
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC")
+	contractAddr := flag.String("contract", "", "Deposit 合约地址（0x…）")
+	amountETH := flag.String("amount-eth", "32", "签名使用的质押金额（默认单位 ETH，可带后缀 eth|gwei|wei；须为 1 gwei 的整数倍）")
+	mismatchETH := flag.String("mismatch-amount-eth", "0", "amount-mismatch 时交易实际发送的金额（默认单位 ETH，可带后缀；0=签名金额的两倍）")
+	gasLimit := flag.Uint64("gas-limit", 500_000, "预期 revert 的故障使用的固定 GasLimit（交易上链后以回执状态判定；0=估算，估算失败即视为被拒）")
+	dryRun := flag.Bool("dry-run", false, "只打印注入故障后的存款数据，不发送")
+	timeout := flag.Duration("timeout", 3*time.Minute, "每笔交易的超时")
+	forkVersion := flag.String("fork-version", "", "存款签名域的 fork_version（4 字节十六进制，空为 0x00000000）")
+	genesisValidatorsRoot := flag.String("genesis-validators-root", "", "存款签名域的 genesis_validators_root（32 字节十六进制，空为零，即规范取值）")
+	envflag.Parse("deposit-fault")
+
+	faults, err := parseFaults(*faultList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *keysPath == "" {
+		log.Fatal("需要 -keys（密钥材料 JSON）")
+	}
+	km, err := deposit.ReadKeyMaterial(*keysPath, *index)
+	if err != nil {
+		log.Fatalf("读取密钥材料失败: %v", err)
+	}
+	if *depositKey != "" {
+		km.DepositPrivateKey = *depositKey
+	}
+	wc, err := km.Credentials()
+	if err != nil {
+		log.Fatalf("生成 withdrawal_credentials 失败: %v", err)
+	}
+	domain, err := deposit.ParseDepositDomainConfig(*forkVersion, *genesisValidatorsRoot)
+	if err != nil {
+		log.Fatalf("存款签名域配置错误: %v", err)
+	}
+	amountGwei, err := units.ParseGwei(*amountETH, units.Ether)
+	if err != nil {
+		log.Fatalf("--amount-eth 错误: %v", err)
+	}
+	mismatchGwei, err := units.ParseGwei(*mismatchETH, units.Ether)
+	if err != nil {
+		log.Fatalf("--mismatch-amount-eth 错误: %v", err)
+	}
+	in := deposit.FaultInput{
+		PubkeyHex:          km.ValidatorPublicKey,
+		WCHex:              wc,
+		BLSKeyHex:          km.ValidatorPrivateKey,
+		AmountGwei:         amountGwei,
+		MismatchAmountGwei: mismatchGwei,
+		Domain:             domain,
+	}
+
+	var cli *deposit.Client
+	if !*dryRun {
+		if *contractAddr == "" {
+			log.Fatal("需要 -contract（0x 开头的合约地址）")
+		}
+		if km.DepositPrivateKey == "" {
+			log.Fatal("缺少发送账户：密钥材料中没有 deposit-private-key，也未给 -deposit-key")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		cli, err = deposit.NewClient(ctx, *rpcURL, km.DepositPrivateKey)
+		cancel()
+		if err != nil {
+			log.Fatalf("NewClient失败: %v", err)
+		}
+		defer cli.Close()
+		cli.DebugPrintAccountState(context.Background())
+	}
+
+	unexpected := 0
+	for _, f := range faults {
+		d, err := deposit.ApplyFault(f, in)
+		if err != nil {
+			log.Fatalf("[%s] 构造故障存款失败: %v", f, err)
+		}
+		want := outcomeAccepted
+		if f.ContractRejects() {
+			want = outcomeRejected
+		}
+		fmt.Printf("\n=== [%s] %s（预期：%s）===\n", f, d.Note, describe(want))
+		fmt.Println("pubkey   :", d.PubkeyHex)
+		fmt.Println("wc       :", d.WCHex)
+		fmt.Println("signature:", d.SignatureHex)
+		fmt.Println("root     :", d.RootHex)
+		fmt.Printf("amount   : %d gwei\n", d.AmountGwei)
+		if *dryRun {
+			continue
+		}
+
+		params := d.Params(*contractAddr, km.DepositPrivateKey, *rpcURL)
+		if f.ContractRejects() {
+			params.GasLimit = *gasLimit
+		}
+		got, detail := send(cli, params, *timeout)
+		if got == want {
+			fmt.Printf("✅ [%s] %s：%s\n", f, describe(got), detail)
+		} else {
+			unexpected++
+			fmt.Printf("❌ [%s] %s（预期%s）：%s\n", f, describe(got), describe(want), detail)
+		}
+	}
+	if unexpected > 0 {
+		log.Printf("%d 个故障的结果不符合预期", unexpected)
+		os.Exit(1)
+	}
+}
+
+// send 发送一笔故障存款，返回合约判定（accepted|rejected，发送失败为空）与说明
+func send(cli *deposit.Client, p *deposit.DepositParams, timeout time.Duration) (string, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	res, err := cli.SendDeposit(ctx, p)
+	switch {
+	case err != nil && p.GasLimit == 0 && strings.Contains(err.Error(), "estimate gas failed"):
+		// 未指定 GasLimit 时合约在估算阶段就 revert
+		return outcomeRejected, err.Error()
+	case err != nil:
+		return "", "发送失败: " + err.Error()
+	case res.Status == 0:
+		return outcomeRejected, fmt.Sprintf("tx=%s block=%d status=0", res.TxHash, res.BlockNumber)
+	}
+	detail := fmt.Sprintf("tx=%s block=%d", res.TxHash, res.BlockNumber)
+	if res.Deposit != nil {
+		detail += fmt.Sprintf(" deposit_index=%d", res.Deposit.Index)
+	}
+	return outcomeAccepted, detail
+}
+
+func describe(outcome string) string {
+	switch outcome {
+	case outcomeRejected:
+		return "合约拒绝（revert）"
+	case outcomeAccepted:
+		return "合约接受（信标链应忽略）"
+	}
+	return "未能判定"
+}
+
+func parseFaults(list string) ([]deposit.Fault, error) {
+	if list == "" {
+		return nil, fmt.Errorf("需要 -fault：%s|all", strings.Join(deposit.FaultNames(), "|"))
+	}
+	if list == "all" {
+		list = strings.Join(deposit.FaultNames(), ",")
+	}
+	var out []deposit.Fault
+	for _, name := range strings.Split(list, ",") {
+		f, err := deposit.LookupFault(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/deposit"
+	"n42-test/internal/depositreport"
+	"n42-test/internal/envflag"
+	"n42-test/internal/rpcpool"
+)
+
+// 存款流水线关联报告：DepositEvent（执行层区块、存款下标）-> 信标链处理区块/纪元 -> 验证者记录，
+// 统计一次活动里存款上链到被处理、再到激活的延迟分布。
+
+type JsonItem struct {
+	ValidatorPublicKey string `json:"validator-public-key"`
+}
+
+func main() {
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC（同时用于 consensusBeaconExt 查询）")
+	contractAddr := flag.String("contract", "", "Deposit 合约地址（0x…）")
+	fromBlock := flag.Uint64("from-block", 0, "从该区块开始查询 DepositEvent")
+	toBlock := flag.Uint64("to-block", 0, "查询到该区块（0 表示最新区块）")
+	jsonPath := flag.String("json", "", "只关联该 JSON 文件（数组）中 validator-public-key 的存款；为空关联范围内全部存款")
+	slotsPerEpoch := flag.Uint64("slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "每纪元 slot 数")
+	logChunk := flag.Uint64("log-chunk", deposit.DefaultLogChunk, "每次 eth_getLogs 查询的区块跨度")
+	report := flag.String("report", "", "把报告以 JSON 写到该文件")
+	timeout := flag.Duration("timeout", 10*time.Minute, "整体超时")
+	envflag.Parse("deposit-report")
+
+	if *contractAddr == "" {
+		log.Fatal("需要 -contract")
+	}
+	var pubkeys []string
+	if *jsonPath != "" {
+		raw, err := os.ReadFile(*jsonPath)
+		if err != nil {
+			log.Fatalf("读取 JSON 失败: %v", err)
+		}
+		var items []JsonItem
+		if err := json.Unmarshal(raw, &items); err != nil {
+			log.Fatalf("解析 JSON 失败: %v", err)
+		}
+		for _, it := range items {
+			pubkeys = append(pubkeys, it.ValidatorPublicKey)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	cli, err := rpcpool.DialEth(ctx, *rpcURL)
+	if err != nil {
+		log.Fatalf("连接 RPC 失败: %v", err)
+	}
+	defer cli.Close()
+
+	rep, err := depositreport.Build(ctx, cli, beaconext.NewClient(*rpcURL), depositreport.Options{
+		Contract:      *contractAddr,
+		FromBlock:     *fromBlock,
+		ToBlock:       *toBlock,
+		SlotsPerEpoch: *slotsPerEpoch,
+		LogChunk:      *logChunk,
+		Pubkeys:       pubkeys,
+	})
+	if err != nil {
+		log.Fatalf("生成报告失败: %v", err)
+	}
+	rep.Print(os.Stdout)
+	if *report != "" {
+		if err := rep.Save(*report); err != nil {
+			log.Fatalf("写报告失败: %v", err)
+		}
+		log.Printf("报告已写入 %s", *report)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/deposit"
+	"n42-test/internal/envflag"
+	"n42-test/internal/rpcpool"
+)
+
+// 存款合约状态：打印 get_deposit_count / get_deposit_root。
+// 批量质押前 -save 存一份快照，结束后 -since 对比，确认存款树前进了预期的条数。
+
+// snapshot 某个区块上的合约存款状态
+type snapshot struct {
+	Contract     string    `json:"contract"`
+	BlockNumber  uint64    `json:"block_number"`
+	BlockHash    string    `json:"block_hash"`
+	DepositCount uint64    `json:"deposit_count"`
+	DepositRoot  string    `json:"deposit_root"`
+	At           time.Time `json:"at"`
+}
+
+func main() {
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC")
+	contractAddr := flag.String("contract", "", "Deposit 合约地址（0x…）")
+	block := flag.Uint64("block", 0, "读取该区块上的状态（0 表示最新区块）")
+	save := flag.String("save", "", "把本次状态快照写到该文件（批量质押前）")
+	since := flag.String("since", "", "与该快照对比，打印存款数增量与树根变化（批量质押后）")
+	expect := flag.Int64("expect", -1, "配合 -since：期望的存款数增量；不符时退出码为 1（<0 不检查）")
+	asJSON := flag.Bool("json", false, "以 JSON 输出本次状态")
+	timeout := flag.Duration("timeout", 30*time.Second, "整体超时")
+	envflag.Parse("deposit-status")
+
+	if *contractAddr == "" || !common.IsHexAddress(*contractAddr) {
+		log.Fatal("需要 -contract（0x 开头的合约地址）")
+	}
+	if *expect >= 0 && *since == "" {
+		log.Fatal("-expect 需要配合 -since")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	cli, err := rpcpool.DialEth(ctx, *rpcURL)
+	if err != nil {
+		log.Fatalf("连接 RPC 失败: %v", err)
+	}
+	defer cli.Close()
+
+	// 树根与计数固定在同一个区块上读取，避免两次调用之间有新存款
+	var num *big.Int
+	if *block > 0 {
+		num = new(big.Int).SetUint64(*block)
+	}
+	head, err := cli.HeaderByNumber(ctx, num)
+	if err != nil {
+		log.Fatalf("读取区块失败: %v", err)
+	}
+	contract := common.HexToAddress(*contractAddr)
+	count, err := deposit.DepositCountAt(ctx, cli, contract, head.Number)
+	if err != nil {
+		log.Fatal(err)
+	}
+	root, err := deposit.DepositRootAt(ctx, cli, contract, head.Number)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cur := snapshot{
+		Contract: contract.Hex(), BlockNumber: head.Number.Uint64(), BlockHash: head.Hash().Hex(),
+		DepositCount: count, DepositRoot: root.Hex(), At: time.Now().UTC(),
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(cur)
+	} else {
+		fmt.Printf("合约 %s @ 区块 #%d (%s)\n", cur.Contract, cur.BlockNumber, cur.BlockHash)
+		fmt.Printf("  deposit_count = %d\n", cur.DepositCount)
+		fmt.Printf("  deposit_root  = %s\n", cur.DepositRoot)
+	}
+
+	if *save != "" {
+		raw, _ := json.MarshalIndent(cur, "", "  ")
+		if err := os.WriteFile(*save, append(raw, '\n'), 0o644); err != nil {
+			log.Fatalf("写快照失败: %v", err)
+		}
+		log.Printf("📝 快照已写入 %s", *save)
+	}
+	if *since != "" {
+		if !compare(*since, cur, *expect) {
+			os.Exit(1)
+		}
+	}
+}
+
+// compare 打印与快照的差异；expect >= 0 时检查增量，不符（或快照不可用）返回 false
+func compare(path string, cur snapshot, expect int64) bool {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("❌ 读取快照失败: %v", err)
+		return false
+	}
+	var prev snapshot
+	if err := json.Unmarshal(raw, &prev); err != nil {
+		log.Printf("❌ 解析快照 %s 失败: %v", path, err)
+		return false
+	}
+	if prev.Contract != cur.Contract {
+		log.Printf("❌ 快照来自合约 %s，与 %s 不同", prev.Contract, cur.Contract)
+		return false
+	}
+	if cur.DepositCount < prev.DepositCount {
+		log.Printf("❌ deposit_count 倒退：%d -> %d（链被重置或快照来自另一条链？）", prev.DepositCount, cur.DepositCount)
+		return false
+	}
+	delta := cur.DepositCount - prev.DepositCount
+	fmt.Printf("自区块 #%d 以来：deposit_count %d -> %d（+%d），区块 +%d\n",
+		prev.BlockNumber, prev.DepositCount, cur.DepositCount, delta, int64(cur.BlockNumber)-int64(prev.BlockNumber))
+	fmt.Printf("  deposit_root %s -> %s\n", prev.DepositRoot, cur.DepositRoot)
+
+	ok := true
+	switch rootChanged := prev.DepositRoot != cur.DepositRoot; {
+	case delta > 0 && !rootChanged:
+		log.Printf("❌ 新增 %d 笔存款但 deposit_root 未变化", delta)
+		ok = false
+	case delta == 0 && rootChanged:
+		log.Printf("❌ 没有新存款但 deposit_root 变化了")
+		ok = false
+	}
+	if expect >= 0 {
+		if int64(delta) != expect {
+			log.Printf("❌ 存款数增量 %d，期望 %d", delta, expect)
+			return false
+		}
+		if ok {
+			log.Printf("✅ 存款树前进了预期的 %d 条", expect)
+		}
+	}
+	return ok
+}
@@ -3,38 +3,42 @@ package main
 import (
 	"bufio"
 	"context"
-	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"math/big"
 	"os"
 	"strings"
 	"time"
 
 	// 改成你的真实模块路径
+	"n42-test/internal/blsutil"
 	"n42-test/internal/deposit"
+	"n42-test/internal/envflag"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/units"
 )
 
-// ======= 固定配置（按你的本地链替换）=======
-const (
-	RPC      = "http://127.0.0.1:8545"
-	CONTRACT = "0x5FbDB2315678afecb367f032d93F642f64180aa3" // 本地/测试链 Deposit 合约地址
+// ======= 固定配置（按你的本地链替换，或用 N42_DEPOSIT_TEST_RPC / N42_DEPOSIT_TEST_CONTRACT 等覆盖）=======
+var (
+	RPC      = envflag.String("deposit-test", "rpc", "http://127.0.0.1:8545")
+	CONTRACT = envflag.String("deposit-test", "contract", "0x5FbDB2315678afecb367f032d93F642f64180aa3") // 本地/测试链 Deposit 合约地址
+
+	// 存款签名域（空为 0x00000000 / 零）
+	FORK_VERSION            = envflag.String("deposit-test", "fork-version", "")
+	GENESIS_VALIDATORS_ROOT = envflag.String("deposit-test", "genesis-validators-root", "")
 )
 
-// 0x01：ETH1 地址型提现凭证
-// wc = 0x01 || 11*0x00 || address(20B)
-func computeWithdrawalCredentialsFromEth1(executionAddressHex string) (string, error) {
-	addrBytes, err := hex.DecodeString(strings.TrimPrefix(executionAddressHex, "0x"))
-	if err != nil {
-		return "", fmt.Errorf("decode address hex failed: %w", err)
-	}
-	if len(addrBytes) != 20 {
-		return "", fmt.Errorf("execution address must be 20 bytes, got %d", len(addrBytes))
-	}
-	var wc [32]byte
-	wc[0] = 0x01
-	copy(wc[12:], addrBytes) // 直接粘贴 20 字节地址
-	return "0x" + hex.EncodeToString(wc[:]), nil
+// 给出其中任一参数（命令行或环境变量）即为非交互模式，不再进入向导
+var inputFlags = []string{"keys", "deposit-key", "bls-key", "pubkey", "withdrawal-address", "withdrawal-credentials"}
+
+// depositInput 一笔存款的全部输入（向导逐项输入，或由参数/密钥文件给出）
+type depositInput struct {
+	senderSK  string
+	blsSK     string
+	pubkeyHex string
+	wcHex     string
+	amtGwei   uint64
 }
 
 // —— 输入辅助 —— //
@@ -53,117 +57,214 @@ func readHexWithLen(prompt string, wantBytes int) string {
 			fmt.Println("⚠️ 不能为空")
 			continue
 		}
-		if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
-			s = "0x" + s
-		}
-		hexPart := s[2:]
-		if len(hexPart)%2 != 0 {
-			fmt.Println("⚠️ 十六进制长度必须为偶数")
-			continue
-		}
-		b, err := hex.DecodeString(hexPart)
-		if err != nil {
+		if _, err := hexutil.DecodeFixed(s, wantBytes); err != nil {
 			fmt.Printf("⚠️ 非法十六进制：%v\n", err)
 			continue
 		}
-		if len(b) != wantBytes {
-			fmt.Printf("⚠️ 长度不对：期望 %d 字节，实际 %d 字节\n", wantBytes, len(b))
-			continue
-		}
-		return s
+		return hexutil.Normalize(s)
 	}
 }
 
-// 读取 ETH 金额（可小数），转换为 (gwei uint64, wei *big.Int)
-func readAmountETH(prompt string, def string) (uint64, *big.Int) {
+// 读取金额（默认单位 ETH，可小数、可带单位后缀），转换为 gwei
+func readAmountETH(prompt string, def string) uint64 {
 	for {
 		s := readLine(prompt)
 		if s == "" {
 			s = def
 		}
-		// 解析小数 ETH
-		f, ok := new(big.Float).SetString(s)
-		if !ok {
-			fmt.Println("⚠️ 请输入合法的数字（可小数）")
-			continue
-		}
-		if f.Sign() <= 0 {
-			fmt.Println("⚠️ 金额必须 > 0")
+		gwei, err := parseAmountETH(s)
+		if err != nil {
+			fmt.Printf("⚠️ %v，请重试\n", err)
 			continue
 		}
+		return gwei
+	}
+}
 
-		// ETH -> gwei（× 1e9），要求能表示为整数 gwei
-		gweiF := new(big.Float).Mul(f, big.NewFloat(1e9))
-		gweiInt := new(big.Int)
-		_, _ = gweiF.Int(gweiInt) // 向下取整，返回值丢弃
+// parseAmountETH 金额（默认单位 ETH，可带 eth|gwei|wei 后缀）转换为 gwei，须精确到 1 gwei
+func parseAmountETH(s string) (uint64, error) {
+	gwei, err := units.ParseGwei(s, units.Ether)
+	if err != nil {
+		return 0, err
+	}
+	if gwei == 0 {
+		return 0, errors.New("金额必须 > 0")
+	}
+	return gwei, nil
+}
 
-		// 校验没有小数残留（保证 gwei 精度准确）
-		diff := new(big.Float).Sub(gweiF, new(big.Float).SetInt(gweiInt))
-		if diff.Cmp(big.NewFloat(0)) != 0 {
-			fmt.Println("⚠️ 金额需精确到 1 gwei（小数位需满足 9 位以内且不产生残留），请重试")
-			continue
+// wizardInput 交互式逐项输入
+func wizardInput(defAmount string) depositInput {
+	var d depositInput
+	d.senderSK = readHexWithLen("1) 发送账户私钥(EOA 32B 0x…): ", 32)
+	d.blsSK = readHexWithLen("2) 验证者 BLS 私钥(32B 0x…): ", 32)
+	d.pubkeyHex = readHexWithLen("3) 验证者 BLS 公钥(48B 0x…): ", 48)
+	withdrawAddr := readHexWithLen("4) 提现地址(执行层地址 20B 0x…): ", 20)
+	d.amtGwei = readAmountETH(fmt.Sprintf("5) 质押金额(默认单位 ETH，可小数，可带后缀 eth|gwei|wei；默认 %s): ", defAmount), defAmount)
+
+	// 计算 withdrawal_credentials (0x01)
+	wc, err := deposit.ComputeWithdrawalCredentialsFromEth1(withdrawAddr)
+	if err != nil {
+		log.Fatalf("计算提现凭证失败: %v", err)
+	}
+	d.wcHex = wc
+	return d
+}
+
+// flagInput 由密钥材料（-keys 与单项参数合并后）组装输入；缺项直接报错，不再提示输入
+func flagInput(km deposit.KeyMaterial, amountETH string, needSender bool) (depositInput, error) {
+	var d depositInput
+	if needSender {
+		if km.DepositPrivateKey == "" {
+			return d, errors.New("缺少发送账户私钥：-deposit-key 或密钥文件中的 deposit-private-key")
 		}
-		// 检查范围
-		if !gweiInt.IsUint64() {
-			fmt.Println("⚠️ 金额过大（gwei 溢出 uint64）")
-			continue
+		if _, err := hexutil.DecodeFixed(km.DepositPrivateKey, 32); err != nil {
+			return d, fmt.Errorf("deposit-key: %w", err)
 		}
-		gwei := gweiInt.Uint64()
+		d.senderSK = hexutil.Normalize(km.DepositPrivateKey)
+	}
+	if km.ValidatorPrivateKey == "" {
+		return d, errors.New("缺少验证者 BLS 私钥：-bls-key 或密钥文件中的 validator-private-key")
+	}
+	d.blsSK = km.ValidatorPrivateKey
+
+	// 公钥可省略（由私钥推导）；给出时须与私钥配对，否则签名在信标链上无效
+	derived, err := blsutil.DerivePublicKeyHex(d.blsSK, blsutil.DefaultKeyOptions())
+	if err != nil {
+		return d, fmt.Errorf("bls-key: %w", err)
+	}
+	d.pubkeyHex = hexutil.Normalize(derived)
+	if km.ValidatorPublicKey != "" && hexutil.Normalize(km.ValidatorPublicKey) != d.pubkeyHex {
+		return d, fmt.Errorf("pubkey %s 与 BLS 私钥推导出的公钥 %s 不一致", hexutil.Normalize(km.ValidatorPublicKey), d.pubkeyHex)
+	}
+
+	if km.WithdrawalCredentials == "" && km.WithdrawalAddress == "" {
+		return d, errors.New("缺少提款目标：-withdrawal-address 或 -withdrawal-credentials")
+	}
+	wc, err := km.Credentials()
+	if err != nil {
+		return d, fmt.Errorf("withdrawal_credentials: %w", err)
+	}
+	if _, err := hexutil.DecodeFixed(wc, hexutil.HashLen); err != nil {
+		return d, fmt.Errorf("withdrawal_credentials: %w", err)
+	}
+	d.wcHex = hexutil.Normalize(wc)
+
+	if d.amtGwei, err = parseAmountETH(amountETH); err != nil {
+		return d, fmt.Errorf("amount-eth: %w", err)
+	}
+	return d, nil
+}
 
-		// wei = gwei * 1e9
-		wei := new(big.Int).Mul(gweiInt, big.NewInt(1_000_000_000))
-		return gwei, wei
+// override 参数非空时覆盖密钥文件中的字段
+func override(dst *string, v string) {
+	if v != "" {
+		*dst = v
 	}
 }
 
 func main() {
-	fmt.Println("=== 交互式质押（Deposit）===")
-	fmt.Printf("固定 RPC: %s\n固定合约: %s\n\n", RPC, CONTRACT)
+	// 子命令：deposit-test prove --index N
+	if len(os.Args) > 1 && os.Args[1] == "prove" {
+		if err := prove(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	// 1) 输入参数
-	senderSK := readHexWithLen("1) 发送账户私钥(EOA 32B 0x…): ", 32)
-	blsSK := readHexWithLen("2) 验证者 BLS 私钥(32B 0x…): ", 32)
-	pubkeyHex := readHexWithLen("3) 验证者 BLS 公钥(48B 0x…): ", 48)
-	withdrawAddr := readHexWithLen("4) 提现地址(执行层地址 20B 0x…): ", 20)
-	amtGwei, amtWei := readAmountETH("5) 质押金额(单位 ETH，可小数；默认 32): ", "32")
+	fs := flag.NewFlagSet("deposit-test", flag.ExitOnError)
+	rpcURL := fs.String("rpc", RPC, "执行层 RPC")
+	contractAddr := fs.String("contract", CONTRACT, "Deposit 合约地址（0x…）")
+	forkVersion := fs.String("fork-version", FORK_VERSION, "存款签名域的 fork_version（4 字节十六进制，空为 0x00000000）")
+	gvr := fs.String("genesis-validators-root", GENESIS_VALIDATORS_ROOT, "存款签名域的 genesis_validators_root（32 字节十六进制，空为零）")
+	keysPath := fs.String("keys", "", "密钥材料 JSON（单个对象或 accounts.json 数组，同 deposit-fault -keys）；下列单项参数覆盖其中的字段")
+	index := fs.Int("index", 0, "-keys 为数组时使用第几条（基于0）")
+	depositKey := fs.String("deposit-key", "", "发送账户私钥（EOA 32B 0x…）")
+	blsKey := fs.String("bls-key", "", "验证者 BLS 私钥（32B 0x…）")
+	pubkey := fs.String("pubkey", "", "验证者 BLS 公钥（48B 0x…；为空时由 BLS 私钥推导，给出时须与私钥配对）")
+	withdrawalAddr := fs.String("withdrawal-address", "", "提现地址（执行层地址 20B 0x…），生成 0x01 凭证")
+	withdrawalCreds := fs.String("withdrawal-credentials", "", "直接给出 32 字节提款凭证，优先于 -withdrawal-address")
+	amountETH := fs.String("amount-eth", "32", "质押金额（默认单位 ETH，可带后缀：32eth / 32000000000gwei / …wei，须精确到 1 gwei）；向导模式下为默认值")
+	dryRun := fs.Bool("dry-run", false, "只计算并打印提款凭证、签名与 deposit_data_root，不发送")
+	timeout := fs.Duration("timeout", 3*time.Minute, "发送并等待回执的超时")
+	envflag.ParseSet(fs, "", os.Args[1:])
 
-	// 2) 计算 withdrawal_credentials (0x01)
-	wcHex, err := computeWithdrawalCredentialsFromEth1(withdrawAddr)
+	scripted := false
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range inputFlags {
+			scripted = scripted || f.Name == name
+		}
+	})
+
+	domain, err := deposit.ParseDepositDomainConfig(*forkVersion, *gvr)
 	if err != nil {
-		log.Fatalf("计算提现凭证失败: %v", err)
+		log.Fatalf("存款签名域配置错误: %v", err)
 	}
 
-	// 3) 计算签名 & root（正确）
-	correctSigHex, correctRootHex, err := deposit.ComputeDepositSignatureAndRoot(pubkeyHex, wcHex, amtGwei, blsSK)
+	// 1) 输入参数：未给出任何密钥参数时进入交互向导
+	var d depositInput
+	if scripted {
+		var km deposit.KeyMaterial
+		if *keysPath != "" {
+			if km, err = deposit.ReadKeyMaterial(*keysPath, *index); err != nil {
+				log.Fatalf("读取密钥材料失败: %v", err)
+			}
+		}
+		override(&km.DepositPrivateKey, *depositKey)
+		override(&km.ValidatorPrivateKey, *blsKey)
+		override(&km.ValidatorPublicKey, *pubkey)
+		override(&km.WithdrawalAddress, *withdrawalAddr)
+		override(&km.WithdrawalCredentials, *withdrawalCreds)
+		if *withdrawalAddr != "" && *withdrawalCreds == "" {
+			// 命令行的地址覆盖密钥文件中的凭证
+			km.WithdrawalCredentials = ""
+		}
+		if d, err = flagInput(km, *amountETH, !*dryRun); err != nil {
+			log.Fatalf("参数错误: %v", err)
+		}
+		fmt.Printf("RPC: %s\n合约: %s\n存款域: %s\n", *rpcURL, *contractAddr, domain)
+		fmt.Printf("pubkey: %s\namount: %d gwei\n", d.pubkeyHex, d.amtGwei)
+	} else {
+		fmt.Println("=== 交互式质押（Deposit）===")
+		fmt.Printf("固定 RPC: %s\n固定合约: %s\n存款域: %s\n\n", *rpcURL, *contractAddr, domain)
+		d = wizardInput(*amountETH)
+	}
+
+	// 2) 计算签名 & root（正确）
+	correctSigHex, correctRootHex, err := deposit.ComputeDepositSignatureAndRoot(d.pubkeyHex, d.wcHex, d.amtGwei, d.blsSK, domain)
 	if err != nil {
 		log.Fatalf("计算签名失败: %v", err)
 	}
 	fmt.Println("\n=== 计算完成 ===")
-	fmt.Println("withdrawal_credentials:", wcHex)
+	fmt.Println("withdrawal_credentials:", d.wcHex)
 	fmt.Println("signature:", correctSigHex)
 	fmt.Println("root     :", correctRootHex)
+	if *dryRun {
+		fmt.Println("\n[dry-run] 未发送交易")
+		return
+	}
 
-	// 4) 组装交易参数（Nonce/Gas 自动）
+	// 3) 组装交易参数（Nonce/Gas 自动）
 	params := &deposit.DepositParams{
-		Contract:             CONTRACT,
-		PrivateKeyHex:        senderSK,
-		RPC:                  RPC,
-		PubkeyHex:            pubkeyHex,
-		WCHex:                wcHex,
+		Contract:             *contractAddr,
+		PrivateKeyHex:        d.senderSK,
+		RPC:                  *rpcURL,
+		PubkeyHex:            d.pubkeyHex,
+		WCHex:                d.wcHex,
 		SignatureHex:         correctSigHex,
 		RootHex:              correctRootHex,
-		AmountWei:            amtWei,
+		Amount:               units.FromGwei(d.amtGwei),
 		Nonce:                -1,
 		GasLimit:             0,
 		MaxPriorityFeePerGas: nil,
 		MaxFeePerGas:         nil,
 	}
 
-	// 5) 发送交易
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	// 4) 发送交易
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 
-	cli, err := deposit.NewClient(ctx, RPC, senderSK)
+	cli, err := deposit.NewClient(ctx, *rpcURL, d.senderSK)
 	if err != nil {
 		log.Fatalf("NewClient 失败: %v", err)
 	}
@@ -176,10 +277,14 @@ func main() {
 		log.Fatalf("发送失败: %v", err)
 	}
 
-	// 6) 输出结果
+	// 5) 输出结果
 	fmt.Println("\n=== 交易结果 ===")
 	fmt.Printf("TxHash=%s\nNonce=%d\nEstGas=%d\nUsedGas=%d\nBlockNumber=%d\nEth1BlockHash=%s\n",
 		txRes.TxHash, txRes.Nonce, txRes.EstimatedGas, txRes.UsedGas, txRes.BlockNumber, txRes.BlockHash)
+	if txRes.Status == 0 {
+		// 脚本按退出码判断
+		log.Fatalf("交易 revert（status=0）")
+	}
 
 	fmt.Println("\n[说明] 正常质押路径：稍后在 BeaconState 中该 pubkey 会进入激活流程。")
 }
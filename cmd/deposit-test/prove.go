@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/deposit"
+	"n42-test/internal/envflag"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/rpcpool"
+)
+
+// proofOutput deposit prove 的机器可读输出
+type proofOutput struct {
+	Index           uint64   `json:"index"`
+	DepositDataRoot string   `json:"deposit_data_root"`
+	Pubkey          string   `json:"pubkey"`
+	AmountGwei      uint64   `json:"amount_gwei"`
+	DepositBlock    uint64   `json:"deposit_block"`
+	DepositTx       string   `json:"deposit_tx"`
+	BeaconBlockHash string   `json:"beacon_block_hash"`
+	Eth1BlockHash   string   `json:"eth1_block_hash"`
+	DepositCount    uint64   `json:"deposit_count"`
+	DepositRoot     string   `json:"deposit_root"`          // Eth1Data 承诺的根
+	ComputedRoot    string   `json:"computed_deposit_root"` // 由 DepositEvent 重建的根
+	Proof           []string `json:"proof"`                 // 32 个兄弟节点 + 长度块
+	Valid           bool     `json:"valid"`                 // is_valid_merkle_branch 对 Eth1Data 根的结果
+}
+
+// prove 从 DepositEvent 重建存款树，生成下标 index 的 Merkle 证明，
+// 并验证它包含在某个信标区块 Eth1Data 承诺的 deposit_root 之下
+func prove(args []string) error {
+	fs := flag.NewFlagSet("deposit-test prove", flag.ExitOnError)
+	rpcURL := fs.String("rpc", RPC, "执行层 RPC（同时用于 consensusBeaconExt 查询）")
+	contractAddr := fs.String("contract", CONTRACT, "Deposit 合约地址（0x…）")
+	index := fs.Int64("index", -1, "要证明的存款下标")
+	dataRoot := fs.String("data-root", "", "期望的 deposit_data_root；给出时须与链上事件重算的叶子一致")
+	eth1Block := fs.String("eth1-block", "latest", "取该执行层区块（块号 / 0x 哈希 / latest）对应信标区块的 Eth1Data")
+	fromBlock := fs.Uint64("from-block", 0, "从该区块开始查询 DepositEvent（须覆盖合约的第一笔存款）")
+	logChunk := fs.Uint64("log-chunk", deposit.DefaultLogChunk, "每次 eth_getLogs 查询的区块跨度")
+	jsonOut := fs.Bool("json", false, "只输出 JSON")
+	outPath := fs.String("out", "", "把证明以 JSON 写到该文件")
+	timeout := fs.Duration("timeout", 5*time.Minute, "整体超时")
+	envflag.ParseSet(fs, "", args)
+
+	if *index < 0 {
+		return fmt.Errorf("需要 --index")
+	}
+	if !common.IsHexAddress(*contractAddr) {
+		return fmt.Errorf("非法合约地址: %s", *contractAddr)
+	}
+	idx := uint64(*index)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	cli, err := rpcpool.DialEth(ctx, *rpcURL)
+	if err != nil {
+		return fmt.Errorf("连接 RPC 失败: %w", err)
+	}
+	defer cli.Close()
+	bc := beaconext.NewClient(*rpcURL)
+
+	// 1) 信标区块承诺的 Eth1Data
+	eth1Hash, err := resolveEth1Hash(ctx, bc, *eth1Block)
+	if err != nil {
+		return err
+	}
+	e1, beaconHash, err := beaconstate.Eth1DataAt(ctx, bc, eth1Hash)
+	if err != nil {
+		return err
+	}
+	if idx >= e1.DepositCount {
+		return fmt.Errorf("存款 %d 尚未被 Eth1Data 覆盖（deposit_count=%d，信标区块 %s）", idx, e1.DepositCount, beaconHash)
+	}
+	committed, err := hexutil.DecodeFixed(e1.DepositRoot, hexutil.HashLen)
+	if err != nil {
+		return fmt.Errorf("eth1_data.deposit_root: %w", err)
+	}
+
+	// 2) 查询到 Eth1Data 所指区块为止的 DepositEvent；该区块查不到时查到最新块
+	to, err := cli.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("get block number: %w", err)
+	}
+	if h, err := cli.HeaderByHash(ctx, common.HexToHash(e1.BlockHash)); err == nil {
+		to = h.Number.Uint64()
+	}
+	events, err := deposit.FilterDepositEvents(ctx, cli, common.HexToAddress(*contractAddr), *fromBlock, to, *logChunk)
+	if err != nil {
+		return err
+	}
+	if uint64(len(events)) < e1.DepositCount {
+		return fmt.Errorf("区块 [%d, %d] 内只有 %d 条 DepositEvent，少于 deposit_count=%d（检查 --from-block）",
+			*fromBlock, to, len(events), e1.DepositCount)
+	}
+	tree, err := deposit.BuildDepositTree(events[:e1.DepositCount])
+	if err != nil {
+		return err
+	}
+
+	// 3) 证明与验证
+	computed, err := tree.Root(e1.DepositCount)
+	if err != nil {
+		return err
+	}
+	proof, err := tree.Proof(idx, e1.DepositCount)
+	if err != nil {
+		return err
+	}
+	leaf, err := events[idx].DataRoot()
+	if err != nil {
+		return err
+	}
+	if *dataRoot != "" {
+		want, err := hexutil.DecodeFixed(*dataRoot, hexutil.HashLen)
+		if err != nil {
+			return fmt.Errorf("--data-root: %w", err)
+		}
+		if common.BytesToHash(want) != common.Hash(leaf) {
+			return fmt.Errorf("存款 %d 的 deposit_data_root 为 %s，与 --data-root %s 不一致", idx, hexutil.Encode(leaf[:]), hexutil.Normalize(*dataRoot))
+		}
+	}
+
+	out := proofOutput{
+		Index:           idx,
+		DepositDataRoot: hexutil.Encode(leaf[:]),
+		Pubkey:          events[idx].Pubkey,
+		AmountGwei:      events[idx].AmountGwei,
+		DepositBlock:    events[idx].BlockNumber,
+		DepositTx:       events[idx].TxHash,
+		BeaconBlockHash: beaconHash,
+		Eth1BlockHash:   e1.BlockHash,
+		DepositCount:    e1.DepositCount,
+		DepositRoot:     hexutil.Normalize(e1.DepositRoot),
+		ComputedRoot:    hexutil.Encode(computed[:]),
+		Valid:           deposit.VerifyMerkleBranch(leaf, proof, deposit.DepositContractTreeDepth+1, idx, [32]byte(committed)),
+	}
+	for _, p := range proof {
+		out.Proof = append(out.Proof, hexutil.Encode(p[:]))
+	}
+
+	b, _ := json.MarshalIndent(out, "", "  ")
+	if *outPath != "" {
+		if err := os.WriteFile(*outPath, append(b, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	if *jsonOut {
+		fmt.Println(string(b))
+	} else {
+		printProof(out, *outPath)
+	}
+	if !out.Valid {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func printProof(p proofOutput, outPath string) {
+	fmt.Printf("存款 #%d  pubkey=%s amount=%d gwei  block=%d tx=%s\n", p.Index, p.Pubkey, p.AmountGwei, p.DepositBlock, p.DepositTx)
+	fmt.Printf("deposit_data_root: %s\n", p.DepositDataRoot)
+	fmt.Printf("信标区块 %s  Eth1Data: deposit_count=%d block_hash=%s\n", p.BeaconBlockHash, p.DepositCount, p.Eth1BlockHash)
+	fmt.Printf("deposit_root 承诺=%s 重算=%s\n", p.DepositRoot, p.ComputedRoot)
+	if !strings.EqualFold(p.DepositRoot, p.ComputedRoot) {
+		fmt.Println("⚠️ 由 DepositEvent 重建的根与 Eth1Data 不一致（事件缺失或合约地址不对？）")
+	}
+	if outPath != "" {
+		fmt.Printf("证明（%d 项）已写入 %s\n", len(p.Proof), outPath)
+	}
+	if p.Valid {
+		fmt.Println("✅ 证明有效：存款包含在 Eth1Data 承诺的 deposit_root 之下")
+	} else {
+		fmt.Println("❌ 证明无效")
+	}
+}
+
+// resolveEth1Hash 把 latest / 块号 / 哈希统一成执行层区块哈希
+func resolveEth1Hash(ctx context.Context, bc *beaconext.Client, spec string) (string, error) {
+	if strings.HasPrefix(spec, "0x") && len(spec) == 66 {
+		return spec, nil
+	}
+	tag := spec
+	if n, err := strconv.ParseUint(spec, 10, 64); err == nil {
+		tag = fmt.Sprintf("0x%x", n)
+	}
+	blk, err := bc.EthGetBlockByNumber(ctx, tag, false)
+	if err != nil {
+		return "", fmt.Errorf("get block %s: %w", spec, err)
+	}
+	return blk.Hash, nil
+}
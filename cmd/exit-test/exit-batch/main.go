@@ -1,8 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"encoding/hex"
+	"crypto/ecdsa"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -10,15 +11,34 @@ import (
 	"log"
 	"math/big"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 
+	"n42-test/internal/autoscale"
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/capability"
+	"n42-test/internal/checkpoint"
+	"n42-test/internal/envflag"
 	"n42-test/internal/exit"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+	"n42-test/internal/manifest"
+	"n42-test/internal/pushgw"
+	"n42-test/internal/ratelimit"
+	"n42-test/internal/registry"
+	"n42-test/internal/resultout"
+	"n42-test/internal/rpcpool"
+	"n42-test/internal/rundir"
+	"n42-test/internal/runsummary"
+	"n42-test/internal/units"
 )
 
 type JsonItem struct {
@@ -31,8 +51,10 @@ type JsonItem struct {
 	ValidatorPrivateKey  string `json:"validator-private-key,omitempty"`
 
 	// 可选：如果以后想单独给退出用的私钥，也兼容
-	ExitPrivateKey   string `json:"exit-private-key,omitempty"`
-	ExitAmountWeiStr string `json:"exit-amount-wei,omitempty"` // 可选：退出请求里的 amount(wei)，默认 0
+	ExitPrivateKey    string `json:"exit-private-key,omitempty"`
+	ExitAmountGweiStr string `json:"exit-amount-gwei,omitempty"` // 可选：请求里的 amount(gwei)，0 为全额退出，>0 为部分提款
+	// 旧字段名：合约按 EIP-7002 一直把它当作 gwei 解释，exit-amount-gwei 为空时使用
+	ExitAmountWeiStr string `json:"exit-amount-wei,omitempty"`
 }
 
 type Task struct {
@@ -41,28 +63,107 @@ type Task struct {
 }
 
 type Result struct {
-	Index int
-	Hash  string
-	Err   error
-	Block uint64
+	Index      int
+	Pubkey     string // 验证者公钥（写入去重登记）
+	Kind       string // full-exit | partial
+	AmountGwei uint64
+	Hash       string
+	Err        error
+	Block      uint64
+	FundHash   string     // 代付账户的充值交易（未充值为空）
+	Queue      string     // 入队确认：queued | dequeued | missing（未确认为空）
+	QueuePos   uint64     // Queue=queued 时前面还有多少条
+	QueueWait  uint64     // Queue=queued 时预计还需多少个区块出队
+	UsedGas    uint64     // 回执中的 gasUsed（不等待回执时为 0）
+	GasCostWei *big.Int   // gasUsed × effectiveGasPrice
+	Confirmed  time.Time  // 拿到回执的时间
+	Track      *exitTrack // --track 时的退出时间线
+	// 已广播，但等回执超时且运行后的 --resolve-timeout 补查也没查到（不计为失败，可 --resume 续查）
+	Unconfirmed bool
+	Nonce       uint64 // 发起交易的 nonce（Hash 非空时有效）
+	Reused      string // 续跑 / 重跑时沿用了上次发出的交易（checkpoint.Mined / InPool），未重新广播
 }
 
+// Result.Queue 的取值
+const (
+	queueQueued   = "queued"   // 仍在合约队列中
+	queueDequeued = "dequeued" // 回执日志确认入队，已在区块末尾出队交给共识层
+	queueMissing  = "missing"  // 回执日志与队列中都没有找到
+)
+
 func main() {
 	// ---------- CLI flags ----------
 	jsonPath := flag.String("json", "deposit-data.json", "JSON 文件路径（数组）")
-	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC")
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC；逗号分隔多个 HTTP 地址时轮询并自动故障切换")
 	contractAddr := flag.String("contract", "", "Exit 合约地址 (0x..)")
 	mode := flag.String("mode", "concurrent", "sequential|concurrent")
 	workers := flag.Int("workers", 4, "并发度，仅在 concurrent 模式下生效")
+	adaptive := flag.Bool("adaptive", false, "自适应并发（AIMD）：按单条耗时与错误率动态增减并发，--workers 为起始值")
+	minWorkers := flag.Int("min-workers", 1, "自适应并发的下限")
+	maxWorkers := flag.Int("max-workers", 64, "自适应并发的上限")
+	rate := flag.Float64("rate", 0, "目标速率（笔/秒）：令牌桶按该速率放行退出交易，产生持续负载；结束时报告实际达成速率（0 不限速）")
+	rateBurst := flag.Int("rate-burst", 1, "--rate 的突发量：空闲后允许连续放行的笔数")
 	start := flag.Int("start", 0, "起始 index（从0开始）")
 	limit := flag.Int("limit", -1, "最大处理条数（<0 表示到末尾）")
 	wait := flag.Bool("wait", true, "是否等待交易上链（true 等待回执，false 只发不等）")
-	flag.Parse()
+	resolveTimeout := flag.Duration("resolve-timeout", 2*time.Minute, "运行结束后统一补查已发送但等回执超时交易的最长时间（0=不补查）；仍查不到的记为 unconfirmed")
+	feePayerKey := flag.String("fee-payer-key", "", "代付账户私钥：发送前把退出费用+gas 即时转给发送者（提款地址没有 ETH 时使用）")
+	feeMargin := flag.Int64("fee-margin-percent", exit.DefaultFeeMarginPercent, "代付时退出费用上浮的百分比")
+	feeMultiplier := flag.Int64("fee-multiplier-percent", exit.DefaultFeeMultiplierPercent, "退出费用报价（百分比）：每次发送前重新读取费用并乘以该值作为 value，多付的部分不退；100 为按当前费用支付")
+	feeRetries := flag.Int("fee-retries", 3, "读取退出费用失败时的重试次数（间隔从 500ms 起翻倍）")
+	feeSimulate := flag.Bool("fee-simulate", false, "按本批次已发送未上链的请求数预估 excess 增长，报价取预估费用与当前费用的较大者；开始前打印整批的费用预估")
+	outputPath := flag.String("output", "", "逐条结果输出文件（下标、公钥、交易哈希、区块、代付交易、错误）；为空不写")
+	outputFormat := flag.String("output-format", "", "--output 的格式 json|csv|ndjson（为空按扩展名，默认 json）")
+	manifestPath := flag.String("manifest", "", "运行清单输出路径（JSON；记录版本、完整配置、输入哈希、链 ID/创世哈希）；为空不写")
+	runsDir := flag.String("runs-dir", rundir.DefaultRootDir(), "运行目录根：每次运行在 <根>/<run_id>/ 下保存清单与日志；为空不创建")
+	registryDir := flag.String("registry-dir", registry.DefaultDirPath(), "跨运行去重登记目录（每个网络按创世哈希一个 SQLite 库，记录已确认退出的验证者公钥）；为空不登记")
+	skipExisting := flag.Bool("skip-existing", false, "跳过登记中已发起过退出的验证者公钥（需要 --registry-dir）")
+	stateFile := flag.String("state-file", "", "断点状态文件（JSONL：每条提交前与完成时追加下标、状态 pending|sent|confirmed|failed、交易哈希）；为空时写到运行目录的 checkpoints/state.jsonl")
+	resume := flag.Bool("resume", false, "按 --state-file 续跑：跳过已确认的条目（已提交未确认的先查回执）；与原运行使用相同的 --start/--limit")
+	retryFailed := flag.String("retry-failed", "", "只重跑上一次结果文件（--output 或运行目录 results/results.json，json|ndjson）中失败的条目（按下标与公钥识别）；结束后把新结果合并写回该文件（设置了 --output 时写到 --output）")
+	partialGwei := flag.Uint64("partial-amount-gwei", 0, "部分提款金额（gwei）：>0 时每条都发部分提款（覆盖 JSON 中的 exit-amount-gwei），0 按 JSON（默认全额退出）")
+	checkState := flag.String("check-state", checkPartial, "发送前按信标状态预检（激活与退出状态、凭证类型、发送者是否为提款地址、部分提款金额不超过 32 ETH 以上的超额余额），未通过的条目不发送：none|partial|all")
+	slotsPerEpoch := flag.Uint64("slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "计算当前纪元用的每纪元 slot 数")
+	track := flag.Bool("track", false, "全部发送完后轮询信标状态跟踪全额退出：记录每个验证者的状态变化（exit_epoch 设置、到达退出纪元、可提款、余额提走）并打印时间线（需要 --wait）")
+	trackUntil := flag.String("track-until", trackInitiated, "跟踪到哪一步为止："+trackInitiated+"（共识层已处理请求）|"+trackExited+"|"+trackWithdrawable+"|"+trackWithdrawn)
+	trackPoll := flag.Duration("track-poll", 12*time.Second, "退出跟踪读取信标状态的间隔")
+	trackTimeout := flag.Duration("track-timeout", 30*time.Minute, "退出跟踪的最长时间")
+	verify := flag.Bool("verify-queue", true, "上链后确认请求已入队：解码回执中合约的入队日志，并读取合约存储中的队列报告排队位置（需要 --wait）")
+	pushGateway := flag.String("pushgateway", "", "Prometheus Pushgateway 地址（如 http://127.0.0.1:9091）；设置后运行结束推送汇总指标（成功/失败、gas、耗时），按 run_id、network 分组")
+	envflag.Parse("exit-batch")
 
 	if *contractAddr == "" || !common.IsHexAddress(*contractAddr) {
 		log.Fatalf("必须提供合法的 --contract 地址")
 	}
 	contract := common.HexToAddress(*contractAddr)
+	outFormat, err := resultout.ResolveFormat(*outputPath, *outputFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var retry *resultout.RetrySet[resultRecord]
+	if *retryFailed != "" {
+		retry, err = resultout.LoadRetry(*retryFailed, func(r resultRecord) (int, string, bool) { return r.Index, r.Pubkey, r.Error != "" })
+		if err != nil {
+			log.Fatalf("读取 --retry-failed 结果失败: %v", err)
+		}
+		if retry.Len() == 0 {
+			log.Printf("%s 中没有失败的条目，无需重跑", *retryFailed)
+			return
+		}
+		log.Printf("🔁 只重跑 %s 中失败的 %d 条（共 %d 条记录）", *retryFailed, retry.Len(), len(retry.Prior))
+	}
+	switch *checkState {
+	case checkNone, checkPartial, checkAll:
+	default:
+		log.Fatalf("未知 --check-state=%s（可选 %s|%s|%s）", *checkState, checkNone, checkPartial, checkAll)
+	}
+	partialAmount = *partialGwei
+	if *track {
+		if err := validateTrack(*trackUntil, *wait); err != nil {
+			log.Fatal(err)
+		}
+	}
+	verifyQueue = *verify && *wait
 
 	// ---------- load JSON ----------
 	items, err := readJson(*jsonPath)
@@ -75,45 +176,476 @@ func main() {
 		return
 	}
 	log.Printf("载入 %d 条退出请求（start=%d, limit=%d）", len(items), *start, *limit)
+	if partialAmount > 0 {
+		log.Printf("💧 部分提款模式：每条提取 %d gwei", partialAmount)
+	}
+
+	var mf *manifest.Manifest
+	if *manifestPath != "" || *runsDir != "" || *pushGateway != "" {
+		mf = manifest.ForRun("exit-batch", *jsonPath, *rpcURL)
+	}
+	runDir := rundir.Start(*runsDir, mf)
+
+	// ---------- 去重登记 ----------
+	if *skipExisting && *registryDir == "" {
+		log.Fatalf("--skip-existing 需要 --registry-dir")
+	}
+	if mf != nil {
+		runID = mf.RunID
+	}
+	if *registryDir != "" {
+		r, err := registry.OpenForRun(*registryDir, *rpcURL, mf)
+		if err != nil {
+			if *skipExisting {
+				log.Fatalf("打开去重登记失败: %v", err)
+			}
+			log.Printf("⚠️ 打开去重登记失败，本次不登记: %v", err)
+		} else {
+			reg = r
+			defer reg.Close()
+		}
+	}
+
+	// ---------- 断点状态 ----------
+	if *resume && *stateFile == "" {
+		log.Fatalf("--resume 需要 --state-file")
+	}
+	statePath := *stateFile
+	if statePath == "" && runDir != nil {
+		statePath = runDir.Path(rundir.Checkpoints, "state.jsonl")
+	}
+	if statePath != "" {
+		if state, err = checkpoint.Create(statePath); err != nil {
+			log.Fatalf("打开状态文件失败: %v", err)
+		}
+		defer state.Close()
+	}
+	var resumed checkpoint.State
+	if *resume {
+		if resumed, err = checkpoint.Resume(statePath, *rpcURL, state, "重复发起退出"); err != nil {
+			log.Fatalf("读取状态文件失败: %v", err)
+		}
+	}
+	// 续跑 / 重跑：上次已发出的交易先核对再决定是否重发
+	if priors = collectPriors(resumed, retry); len(priors) > 0 {
+		log.Printf("🛡️ %d 条上次已发出交易，重发前先按交易哈希与 (发送账户, nonce) 核对链上与交易池", len(priors))
+	}
 
 	// ---------- 构造任务 ----------
-	tasks := make([]Task, len(items))
+	tasks := make([]Task, 0, len(items))
+	skipped := 0
 	for i, it := range items {
-		tasks[i] = Task{Index: i + *start, Item: it} // 输出里的 Index 体现原始行号
+		if retry != nil && !retry.Wants(i+*start, it.ValidatorPubkey) {
+			skipped++
+			continue
+		}
+		if resumed.Done(i + *start) {
+			skipped++
+			continue
+		}
+		// 去重登记只记录全额退出，部分提款可以对同一验证者重复发起
+		if amt, err := requestAmount(it); *skipExisting && err == nil && amt == 0 {
+			done, err := reg.Has(registry.KindExit, it.ValidatorPubkey)
+			if err != nil {
+				log.Printf("⚠️ [#%d] 查询去重登记失败，照常处理: %v", i+*start, err)
+			}
+			if done {
+				skipped++
+				continue
+			}
+		}
+		tasks = append(tasks, Task{Index: i + *start, Item: it}) // 输出里的 Index 体现原始行号
+	}
+	if skipped > 0 {
+		log.Printf("⏭️ 跳过 %d 条（断点中已确认 / 登记中已退出 / 上一次未失败）", skipped)
 	}
 
 	ctx := context.Background()
 
+	// ---------- 信标状态预检 ----------
+	if needsCheck(*checkState, tasks) {
+		checkMode, checkSPE = *checkState, *slotsPerEpoch
+		sctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		st, err := beaconstate.FetchLatest(sctx, beaconext.NewClient(rpcpool.Parse(*rpcURL)[0]))
+		cancel()
+		if err != nil {
+			log.Printf("⚠️ 获取信标状态失败，本次不预检: %v", err)
+		} else {
+			chainState = st
+			log.Printf("🔎 信标状态 slot=%d（纪元 %d）：发送前预检 %s 请求", st.Slot, st.Epoch(checkSPE), checkMode)
+		}
+	}
+
+	// ---------- 费用报价 ----------
+	if *feeMultiplier < 100 {
+		log.Fatalf("--fee-multiplier-percent 不能小于 100（低于当前费用的交易会 revert）")
+	}
+	oracleCli, err := rpcpool.DialEth(context.Background(), *rpcURL)
+	if err != nil {
+		log.Fatalf("RPC 连接失败: %v", err)
+	}
+	defer oracleCli.Close()
+	feeOracle = exit.NewFeeOracle(oracleCli, contract)
+	feeOracle.MultiplierPercent, feeOracle.Retries, feeOracle.Simulate = *feeMultiplier, *feeRetries, *feeSimulate
+	if *feeSimulate {
+		perBlock := *workers
+		if strings.ToLower(*mode) == "sequential" {
+			perBlock = 1
+		}
+		forecastFees(ctx, oracleCli, contract, len(tasks), perBlock, *feeMultiplier)
+	}
+
+	// ---------- 代付账户 ----------
+	var payer *exit.FeePayer
+	if *feePayerKey != "" {
+		priv, err := keys.ParseECDSA(*feePayerKey)
+		if err != nil {
+			log.Fatalf("代付私钥解析失败: %v", err)
+		}
+		cli, err := rpcpool.DialEth(context.Background(), *rpcURL)
+		if err != nil {
+			log.Fatalf("RPC 连接失败: %v", err)
+		}
+		defer cli.Close()
+		payer = exit.NewFeePayer(cli, priv, contract)
+		payer.MarginPercent = *feeMargin
+		payer.Oracle = feeOracle
+		log.Printf("💸 代付账户 %s：发送前为余额不足的发送者即时充值", payer.Address().Hex())
+	}
+
+	// 自适应并发：节点健康时逐步加并发，耗时翻倍或错误率超标时减半
+	var ctl *autoscale.Controller
+	if *adaptive {
+		ctl = autoscale.New(autoscale.Config{
+			Min: *minWorkers, Max: *maxWorkers, Start: *workers,
+			OnChange: func(old, new int, reason string) {
+				log.Printf("⚙️ 并发 %d → %d：%s", old, new, reason)
+			},
+		})
+	}
+
+	lim := ratelimit.New(*rate, *rateBurst)
+	if lim != nil {
+		log.Printf("🚦 限速 %.2f 笔/秒（突发 %d）", lim.Rate(), *rateBurst)
+	}
+
+	var results []Result
+	var sum *runsummary.RunSummary
 	switch strings.ToLower(*mode) {
 	case "sequential":
-		runSequential(ctx, *rpcURL, contract, tasks, *wait)
+		results, sum = runSequential(ctx, *rpcURL, contract, tasks, lim, *wait, payer)
 	case "concurrent":
-		runConcurrent(ctx, *rpcURL, contract, tasks, *workers, *wait)
+		results, sum = runConcurrent(ctx, *rpcURL, contract, tasks, *workers, ctl, lim, *wait, payer)
 	default:
 		log.Fatalf("未知 mode=%s（可选 sequential|concurrent）", *mode)
 	}
+	unconfirmed := unconfirmedCount(results)
+	if unconfirmed > 0 && *resolveTimeout > 0 {
+		resolveUnconfirmed(ctx, *rpcURL, results, sum, *resolveTimeout)
+		unconfirmed = unconfirmedCount(results)
+	}
+	if unconfirmed > 0 {
+		log.Printf("⏳ %d 笔已发送但仍未确认，未计为失败（见各条目 unconfirmed）；交易已广播，不要重新发送，之后加 --resume 续查回执", unconfirmed)
+	}
+	if rep := sum.ErrorReport(); rep != "" {
+		log.Print(rep)
+	}
+	if *track {
+		trackExits(ctx, beaconext.NewClient(rpcpool.Parse(*rpcURL)[0]), results, *slotsPerEpoch, *trackUntil, *trackPoll, *trackTimeout)
+	}
+
+	if err := state.Err(); err != nil {
+		log.Printf("⚠️ 写状态文件失败，断点可能不完整: %v", err)
+	} else if state != nil {
+		log.Printf("⏯️ 断点状态已写入 %s（中断后加 --state-file %s --resume 续跑）", state.Path, state.Path)
+	}
+
+	if mf != nil {
+		mf.Summary = sum.Fields()
+		if *track {
+			mf.Summary["exit_track"] = trackSummary(results)
+		}
+		if *skipExisting || *resume || retry != nil {
+			mf.Summary["skipped"] = skipped
+		}
+		if retry != nil {
+			mf.Summary["retry_of"] = retry.Path
+		}
+		if lim != nil {
+			mf.Summary["target_tps"], mf.Summary["achieved_tps"] = lim.Rate(), lim.Achieved()
+		}
+		if unconfirmed > 0 {
+			mf.Summary["unconfirmed"] = unconfirmed
+		}
+		if *manifestPath != "" {
+			if err := mf.Write(*manifestPath); err != nil {
+				log.Printf("⚠️ 写运行清单失败: %v", err)
+			} else {
+				log.Printf("📝 运行清单已写入 %s（run_id=%s）", *manifestPath, mf.RunID)
+			}
+		}
+	}
+	switch {
+	case retry != nil:
+		// 重跑的条目替换上一次的记录，其余原样保留
+		path, format := retry.Path, retry.Format
+		if *outputPath != "" {
+			path, format = *outputPath, outFormat
+		}
+		merged := retry.Merge(resultRecords(results))
+		if err := resultout.Write(path, format, merged); err != nil {
+			log.Printf("⚠️ 写结果文件失败: %v", err)
+		} else {
+			log.Printf("📄 重跑的 %d 条已合并，共 %d 条结果写入 %s（%s）", len(results), len(merged), path, format)
+		}
+	case *outputPath != "":
+		if err := resultout.Write(*outputPath, outFormat, resultRecords(results)); err != nil {
+			log.Printf("⚠️ 写结果文件失败: %v", err)
+		} else {
+			log.Printf("📄 %d 条结果已写入 %s（%s）", len(results), *outputPath, outFormat)
+		}
+	}
+	if runDir != nil {
+		if err := runDir.WriteJSON(rundir.Results, "results.json", resultRecords(results)); err != nil {
+			log.Printf("⚠️ 写结果失败: %v", err)
+		}
+		if err := runDir.Finish(); err != nil {
+			log.Printf("⚠️ 写运行清单失败: %v", err)
+		} else {
+			log.Printf("📁 运行产物已写入 %s", runDir.Dir)
+		}
+	}
+	if *pushGateway != "" {
+		pctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := pushgw.PushSummary(pctx, *pushGateway, mf, ""); err != nil {
+			log.Printf("⚠️ 推送指标失败: %v", err)
+		} else {
+			log.Printf("📈 指标已推送到 %s（job=%s run_id=%s）", *pushGateway, mf.Tool, mf.RunID)
+		}
+		cancel()
+	}
+}
+
+// reg 去重登记库（为 nil 时不登记）；runID 为登记中记录的运行 ID；state 断点状态文件（为 nil 时不写）
+var (
+	reg   *registry.Registry
+	runID string
+	state *checkpoint.Writer
+)
+
+// --check-state 的取值
+const (
+	checkNone    = "none"
+	checkPartial = "partial"
+	checkAll     = "all"
+)
+
+// partialAmount --partial-amount-gwei；chainState 预检用的信标状态（为 nil 时不预检），checkMode / checkSPE 为预检范围与每纪元 slot 数；
+// feeOracle 每次发送前的费用报价；verifyQueue 上链后确认入队
+var (
+	partialAmount uint64
+	chainState    *beaconstate.State
+	checkMode     string
+	checkSPE      uint64
+	feeOracle     *exit.FeeOracle
+	verifyQueue   bool
+)
+
+// forecastFees 按合约当前 excess 预估整批（每块 perBlock 条、没有其他人的请求）的费用并打印
+func forecastFees(ctx context.Context, cli *ethclient.Client, contract common.Address, n, perBlock int, multiplier int64) {
+	if n == 0 {
+		return
+	}
+	excess, _, err := exit.QueueState(ctx, cli, contract)
+	if err != nil {
+		log.Printf("⚠️ 读取退出合约 excess 失败，跳过费用预估: %v", err)
+		return
+	}
+	fees := exit.SimulateFees(excess, n, perBlock)
+	total := new(big.Int)
+	for _, f := range fees {
+		total.Add(total, f)
+	}
+	log.Printf("💹 费用预估：excess=%d，%d 条（每块 %d 条）首条 %s wei、末条 %s wei、合计 %s wei；报价按 %d%% 支付",
+		excess, n, perBlock, fees[0], fees[len(fees)-1], total, multiplier)
+}
+
+// requestAmount 条目的请求金额（gwei）：--partial-amount-gwei 优先，其次 exit-amount-gwei、exit-amount-wei，默认 0（全额退出）
+func requestAmount(it JsonItem) (uint64, error) {
+	if partialAmount > 0 {
+		return partialAmount, nil
+	}
+	field, raw := "exit-amount-gwei", strings.TrimSpace(it.ExitAmountGweiStr)
+	if raw == "" {
+		field, raw = "exit-amount-wei", strings.TrimSpace(it.ExitAmountWeiStr)
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	// 不带单位按 gwei；也可写 "1eth"、"0.5ether" 等，须为整 gwei
+	amt, err := units.ParseGwei(raw, units.Gwei)
+	if err != nil {
+		return 0, fmt.Errorf("%s 解析失败: %w", field, err)
+	}
+	return amt, nil
+}
+
+// needsCheck 按 --check-state 是否有条目需要预检
+func needsCheck(mode string, tasks []Task) bool {
+	switch mode {
+	case checkAll:
+		return len(tasks) > 0
+	case checkPartial:
+		for _, t := range tasks {
+			if amt, err := requestAmount(t.Item); err == nil && amt > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// precheck 按信标状态预检一条请求；未取到状态或不在预检范围内时返回 nil
+func precheck(priv *ecdsa.PrivateKey, req exit.WithdrawalRequest) error {
+	if chainState == nil || (checkMode == checkPartial && req.IsFullExit()) {
+		return nil
+	}
+	c, err := exit.CheckWithdrawalRequest(chainState, crypto.PubkeyToAddress(priv.PublicKey), req, checkSPE)
+	if err != nil {
+		return fmt.Errorf("信标状态预检失败: %w", err)
+	}
+	return c.Err()
+}
+
+// priors 续跑 / 重跑时各下标上次发出、尚未确认的交易；发送前用 reusePrior 核对（没有时为 nil）
+var priors map[int]checkpoint.Prior
+
+// collectPriors 汇总上次已发出、尚未确认的交易：断点状态中未确认的记录，及 --retry-failed 结果中失败但有交易哈希的记录
+func collectPriors(resumed checkpoint.State, retry *resultout.RetrySet[resultRecord]) map[int]checkpoint.Prior {
+	return checkpoint.CollectPriors(resumed, retry, func(r resultRecord) (int, checkpoint.Prior, bool) {
+		return r.Index, checkpoint.Prior{TxHash: r.TxHash, Nonce: r.Nonce}, r.Error != "" && r.TxHash != ""
+	})
+}
+
+// reusePrior 重发前核对本条上次发出的交易：已成功上链或仍在交易池中的沿用（返回 true，哈希与 nonce 写入 r，之后照常等回执）；
+// nonce 已被占用或核对失败时不重发（stop 为 true，原因记在 r.Err）；其余（没有记录、已丢弃、已 revert）照常发送
+func reusePrior(ctx context.Context, cli *ethclient.Client, priv *ecdsa.PrivateKey, r *Result) (reused, stop bool) {
+	p, ok := priors[r.Index]
+	if !ok {
+		return false, false
+	}
+	v, reuse, err := checkpoint.Reuse(ctx, cli, crypto.PubkeyToAddress(priv.PublicKey), p)
+	switch {
+	case err != nil:
+		r.Hash, r.Nonce = p.TxHash, p.Nonce
+		r.Err = fmt.Errorf("上次的交易未重发，避免重复发起退出: %w", err)
+		return false, true
+	case reuse:
+		log.Printf("[#%d] ♻️ 沿用上次的交易 tx=%s（%s），不重新发送", r.Index, p.TxHash, v)
+		r.Hash, r.Nonce, r.Reused = p.TxHash, p.Nonce, v.String()
+		return true, false
+	case v == checkpoint.Mined:
+		log.Printf("[#%d] 上次的交易 tx=%s 已 revert，重新发送", r.Index, p.TxHash)
+	}
+	return false, false
+}
+
+// markResult 记录一条的最终状态
+func markResult(r Result) {
+	rec := checkpoint.Record{Index: r.Index, TxHash: r.Hash, Nonce: r.Nonce, Block: r.Block}
+	switch {
+	case r.Err != nil:
+		rec.Status, rec.Error = checkpoint.Failed, r.Err.Error()
+	case r.Block > 0:
+		rec.Status = checkpoint.Confirmed
+	default:
+		rec.Status = checkpoint.Sent // --wait=false
+	}
+	state.Mark(rec)
+}
+
+// errReverted 补查到的回执 status=0
+var errReverted = errors.New("交易 revert（status=0）")
+
+// unconfirmedCount 已发送但未确认的条目数
+func unconfirmedCount(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Unconfirmed {
+			n++
+		}
+	}
+	return n
+}
+
+// resolveUnconfirmed 运行结束后统一补查已发送未确认条目的回执（最长 timeout）：上链的改记为成功或 revert，
+// 同步更新断点状态、去重登记与 sum；仍查不到的保持 unconfirmed。补查不做入队确认
+func resolveUnconfirmed(ctx context.Context, rpc string, results []Result, sum *runsummary.RunSummary, timeout time.Duration) {
+	var idx []int
+	var hashes []string
+	for i, r := range results {
+		if r.Unconfirmed {
+			idx, hashes = append(idx, i), append(hashes, r.Hash)
+		}
+	}
+	checkpoint.Resolve(ctx, rpc, hashes, timeout, func(i int, rcpt *types.Receipt, reverted bool) {
+		r := &results[idx[i]]
+		r.Unconfirmed = false
+		r.Block, r.Confirmed, r.UsedGas = rcpt.BlockNumber.Uint64(), time.Now(), rcpt.GasUsed
+		if rcpt.EffectiveGasPrice != nil {
+			r.GasCostWei = new(big.Int).Mul(new(big.Int).SetUint64(rcpt.GasUsed), rcpt.EffectiveGasPrice)
+		}
+		if reverted {
+			r.Err = fmt.Errorf("tx=%s: %w", r.Hash, errReverted)
+		}
+		sum.Amend(r.Err, r.UsedGas, r.GasCostWei)
+		markResult(*r)
+		recordExit(*r)
+		printResult(*r)
+	})
+}
+
+// recordExit 把已确认的全额退出登记进去重库（未等回执的、部分提款不登记）
+func recordExit(r Result) {
+	if reg == nil || r.Err != nil || r.Block == 0 || r.Kind != exit.KindFullExit {
+		return
+	}
+	e := registry.Entry{Pubkey: r.Pubkey, TxHash: r.Hash, Block: r.Block, RunID: runID}
+	if err := reg.Record(registry.KindExit, e); err != nil {
+		log.Printf("⚠️ [#%d] 写入去重登记失败: %v", r.Index, err)
+	}
 }
 
 // ---------------- runners ----------------
 
-func runSequential(ctx context.Context, rpc string, contract common.Address, tasks []Task, wait bool) {
-	ok, fail := 0, 0
+func runSequential(ctx context.Context, rpc string, contract common.Address, tasks []Task, lim *ratelimit.Limiter, wait bool, payer *exit.FeePayer) ([]Result, *runsummary.RunSummary) {
+	sum := runsummary.New("sequential", 0)
+	var results []Result
 	for _, t := range tasks {
-		res := handleOne(ctx, rpc, contract, t, wait)
+		lim.Wait(ctx)
+		res := handleOne(ctx, rpc, contract, t, wait, payer)
 		printResult(res)
-		if res.Err != nil {
-			fail++
-		} else {
-			ok++
-		}
+		markResult(res)
+		recordExit(res)
+		results = append(results, res)
+	}
+	runsummary.Summarize(sum, results)
+	log.Printf("顺序退出完成：%s", sum)
+	if lim != nil {
+		log.Print(lim.Summary())
 	}
-	log.Printf("顺序退出完成：成功 %d，失败 %d", ok, fail)
+	return results, sum
 }
 
-func runConcurrent(ctx context.Context, rpc string, contract common.Address, tasks []Task, workers int, wait bool) {
+func runConcurrent(ctx context.Context, rpc string, contract common.Address, tasks []Task, workers int, ctl *autoscale.Controller, lim *ratelimit.Limiter, wait bool, payer *exit.FeePayer) ([]Result, *runsummary.RunSummary) {
 	if workers <= 0 {
 		workers = 1
 	}
+	if ctl != nil {
+		// 预先起满上限个 worker，实际在途数由 ctl 控制
+		workers = ctl.Max()
+	}
+	sum := runsummary.New("concurrent", workers)
 	in := make(chan Task)
 	out := make(chan Result)
 
@@ -123,7 +655,11 @@ func runConcurrent(ctx context.Context, rpc string, contract common.Address, tas
 		go func() {
 			defer wg.Done()
 			for t := range in {
-				res := handleOne(ctx, rpc, contract, t, wait)
+				tok := ctl.Acquire()
+				lim.Wait(ctx)
+				began := time.Now()
+				res := handleOne(ctx, rpc, contract, t, wait, payer)
+				ctl.Release(tok, time.Since(began), res.Err)
 				out <- res
 			}
 		}()
@@ -139,61 +675,60 @@ func runConcurrent(ctx context.Context, rpc string, contract common.Address, tas
 		close(out)
 	}()
 
-	ok, fail := 0, 0
+	var results []Result
 	for res := range out {
 		printResult(res)
-		if res.Err != nil {
-			fail++
-		} else {
-			ok++
-		}
+		markResult(res)
+		recordExit(res)
+		results = append(results, res)
+	}
+	runsummary.Summarize(sum, results)
+	log.Printf("并发退出完成：%s", sum)
+	if ctl != nil {
+		log.Print(ctl.Summary())
 	}
-	log.Printf("并发退出完成：成功 %d，失败 %d (workers=%d)", ok, fail, workers)
+	if lim != nil {
+		log.Print(lim.Summary())
+	}
+	// 结果按到达顺序打印，写文件时按下标排序
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	return results, sum
 }
 
 // ---------------- core ----------------
 
-func handleOne(ctx context.Context, rpc string, contract common.Address, task Task, wait bool) Result {
+func handleOne(ctx context.Context, rpc string, contract common.Address, task Task, wait bool, payer *exit.FeePayer) Result {
 	idx := task.Index
 	it := task.Item
 
 	// 1) 选择发起交易的 EOA 私钥：优先 exit-private-key，其次 deposit-private-key
 	rawKey := firstNonEmpty(it.ExitPrivateKey, it.DepositPrivateKey)
 	if strings.TrimSpace(rawKey) == "" {
-		return Result{Index: idx, Err: fmt.Errorf("缺少私钥（exit-private-key 或 deposit-private-key）")}
-	}
-	k := strings.TrimPrefix(strings.TrimSpace(rawKey), "0x")
-	if len(k) != 64 {
-		return Result{Index: idx, Err: fmt.Errorf("privKey hex 长度=%d，期望64（32字节）", len(k))}
+		return Result{Index: idx, Err: runsummary.BadInput(errors.New("缺少私钥（exit-private-key 或 deposit-private-key）"))}
 	}
-	priv, err := crypto.HexToECDSA(k)
+	priv, err := keys.ParseECDSA(rawKey)
 	if err != nil {
-		return Result{Index: idx, Err: fmt.Errorf("privKey 解析失败: %w", err)}
+		return Result{Index: idx, Err: runsummary.BadInput(fmt.Errorf("privKey 解析失败: %w", err))}
 	}
 
 	// 2) 解析 48B BLS 公钥
-	pubkey, err := hexToBytes(it.ValidatorPubkey, 48)
+	pubkey, err := hexutil.DecodeFixed(it.ValidatorPubkey, hexutil.PubkeyLen)
 	if err != nil {
-		return Result{Index: idx, Err: fmt.Errorf("validator-public-key 错误: %w", err)}
+		return Result{Index: idx, Err: runsummary.BadInput(fmt.Errorf("validator-public-key 错误: %w", err))}
 	}
 
-	// 3) 退出请求里的 amount（Wei），默认 0
-	amt := big.NewInt(0)
-	if strings.TrimSpace(it.ExitAmountWeiStr) != "" {
-		z := new(big.Int)
-		if _, ok := z.SetString(strings.TrimSpace(it.ExitAmountWeiStr), 10); ok {
-			if z.Sign() >= 0 {
-				amt = z
-			} else {
-				return Result{Index: idx, Err: errors.New("exit-amount-wei 不可为负")}
-			}
-		} else {
-			return Result{Index: idx, Err: errors.New("exit-amount-wei 解析失败")}
-		}
+	// 3) 请求金额（gwei）：0 为全额退出，>0 为部分提款
+	amt, err := requestAmount(it)
+	if err != nil {
+		return Result{Index: idx, Err: runsummary.BadInput(err)}
+	}
+	req := exit.WithdrawalRequest{Pubkey: pubkey, AmountGwei: amt}
+	if err := precheck(priv, req); err != nil {
+		return Result{Index: idx, Kind: req.Kind(), AmountGwei: amt, Err: runsummary.BadInput(err)}
 	}
 
 	// 4) 执行发送
-	client, err := ethclient.Dial(rpc)
+	client, err := rpcpool.DialEth(context.Background(), rpc)
 	if err != nil {
 		return Result{Index: idx, Err: fmt.Errorf("RPC 连接失败: %w", err)}
 	}
@@ -202,20 +737,133 @@ func handleOne(ctx context.Context, rpc string, contract common.Address, task Ta
 	ctx2, cancel := context.WithTimeout(ctx, 120*time.Second)
 	defer cancel()
 
-	tx, rcpt, err := exit.SendExitRequest(ctx2, client, priv, contract, pubkey, amt, wait)
-	if err != nil {
-		return Result{Index: idx, Err: err}
+	r := Result{Index: idx, Pubkey: it.ValidatorPubkey, Kind: req.Kind(), AmountGwei: amt}
+	// 续跑 / 重跑：上次已发出的交易能沿用就不重发
+	reused, stop := reusePrior(ctx2, client, priv, &r)
+	if stop {
+		return r
 	}
+	if !reused {
+		// 代付：发送者余额不足时即时充值
+		if payer != nil {
+			fundTx, err := payer.TopUp(ctx2, crypto.PubkeyToAddress(priv.PublicKey), pubkey, req.Amount())
+			if fundTx != nil {
+				r.FundHash = fundTx.Hash().Hex()
+			}
+			if err != nil {
+				r.Err = fmt.Errorf("代付充值失败: %w", err)
+				return r
+			}
+		}
 
-	r := Result{Index: idx, Hash: tx.Hash().Hex()}
-	if rcpt != nil && rcpt.BlockNumber != nil {
-		r.Block = rcpt.BlockNumber.Uint64()
+		caps := capability.For(ctx, rpc)
+		state.Mark(checkpoint.Record{Index: idx, Status: checkpoint.Pending})
+		calldata, err := req.Calldata()
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		// 发送前重新报价：同批次先发的请求可能已推高费用
+		tx, _, err := feeOracle.Send(ctx2, client, caps, priv, calldata, false)
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		r.Hash, r.Nonce = tx.Hash().Hex(), tx.Nonce()
+	}
+	if !wait {
+		return r
+	}
+
+	state.Mark(checkpoint.Record{Index: idx, Status: checkpoint.Sent, TxHash: r.Hash, Nonce: r.Nonce})
+	rcpt, err := exit.WaitMined(ctx2, client, common.HexToHash(r.Hash))
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		// 已广播，只是等回执超时：哈希已知、结果未知，不记失败，运行结束后统一补查
+		r.Unconfirmed = true
+		return r
+	}
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Block, r.Confirmed = rcpt.BlockNumber.Uint64(), time.Now()
+	r.UsedGas = rcpt.GasUsed
+	if rcpt.EffectiveGasPrice != nil {
+		r.GasCostWei = new(big.Int).Mul(new(big.Int).SetUint64(rcpt.GasUsed), rcpt.EffectiveGasPrice)
+	}
+	if verifyQueue && rcpt.Status == types.ReceiptStatusSuccessful {
+		verifyEnqueued(ctx2, client, contract, req, rcpt, &r)
 	}
 	return r
 }
 
+// verifyEnqueued 按回执日志与合约队列确认请求已入队，结果写入 r；读取队列失败只告警
+func verifyEnqueued(ctx context.Context, cli *ethclient.Client, contract common.Address, req exit.WithdrawalRequest, rcpt *types.Receipt, r *Result) {
+	logged := false
+	for _, q := range exit.RequestLogs(rcpt, contract) {
+		if bytes.Equal(q.Pubkey, req.Pubkey) && q.AmountGwei == req.AmountGwei {
+			logged = true
+			break
+		}
+	}
+	e, err := exit.VerifyEnqueued(ctx, cli, contract, req.Pubkey)
+	if err != nil {
+		log.Printf("[#%d] ⚠️ 读取退出队列失败: %v", r.Index, err)
+		if logged {
+			r.Queue = queueDequeued
+		}
+		return
+	}
+	switch {
+	case e.Found:
+		r.Queue, r.QueuePos, r.QueueWait = queueQueued, e.Position(), e.Blocks()
+	case logged:
+		r.Queue = queueDequeued
+	default:
+		r.Queue = queueMissing
+	}
+}
+
 // ---------------- utils ----------------
 
+// resultRecord 单条结果：写入运行目录 results/results.json 与 --output
+type resultRecord struct {
+	Index      int        `json:"index"`
+	Pubkey     string     `json:"pubkey,omitempty"`
+	Kind       string     `json:"kind,omitempty"`
+	AmountGwei uint64     `json:"amount_gwei,omitempty"`
+	TxHash     string     `json:"tx_hash,omitempty"`
+	Block      uint64     `json:"block_number,omitempty"`
+	FundTxHash string     `json:"fund_tx_hash,omitempty"`
+	Queue      string     `json:"queue,omitempty"`
+	Track      *exitTrack `json:"track,omitempty"`
+	QueuePos   *uint64    `json:"queue_position,omitempty"`
+	Nonce      uint64     `json:"nonce,omitempty"`
+	// 已发送但未确认（哈希已知、结果未知）
+	Unconfirmed bool `json:"unconfirmed,omitempty"`
+	// 续跑 / 重跑时沿用的上次交易：mined | in_pool
+	Reused string `json:"reused,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func resultRecords(results []Result) []resultRecord {
+	out := make([]resultRecord, len(results))
+	for i, r := range results {
+		out[i] = resultRecord{Index: r.Index, Pubkey: r.Pubkey, Kind: r.Kind, AmountGwei: r.AmountGwei, TxHash: r.Hash, Block: r.Block, FundTxHash: r.FundHash, Queue: r.Queue, Track: r.Track, Nonce: r.Nonce, Unconfirmed: r.Unconfirmed, Reused: r.Reused}
+		if r.Queue == queueQueued {
+			pos := r.QueuePos
+			out[i].QueuePos = &pos
+		}
+		if r.Err != nil {
+			out[i].Error = r.Err.Error()
+		}
+	}
+	return out
+}
+
+// Outcome 实现 runsummary.Outcome
+func (r Result) Outcome() (uint64, *big.Int, error) { return r.UsedGas, r.GasCostWei, r.Err }
+
 func readJson(path string) ([]JsonItem, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -246,18 +894,6 @@ func sliceRange[T any](in []T, start, limit int) []T {
 	return in[start:end]
 }
 
-func hexToBytes(s string, want int) ([]byte, error) {
-	s = strings.TrimPrefix(strings.TrimSpace(s), "0x")
-	b, err := hex.DecodeString(s)
-	if err != nil {
-		return nil, err
-	}
-	if len(b) != want {
-		return nil, fmt.Errorf("invalid length %d, want %d", len(b), want)
-	}
-	return b, nil
-}
-
 func firstNonEmpty(ss ...string) string {
 	for _, s := range ss {
 		if strings.TrimSpace(s) != "" {
@@ -268,13 +904,31 @@ func firstNonEmpty(ss ...string) string {
 }
 
 func printResult(r Result) {
+	if r.FundHash != "" {
+		log.Printf("[#%d] 💸 代付充值: tx=%s", r.Index, r.FundHash)
+	}
 	if r.Err != nil {
 		log.Printf("[#%d] ❌ 失败: %v", r.Index, r.Err)
 		return
 	}
-	if r.Block > 0 {
-		log.Printf("[#%d] ✅ 成功: tx=%s block=%d", r.Index, r.Hash, r.Block)
-	} else {
-		log.Printf("[#%d] ✅ 已发送: tx=%s", r.Index, r.Hash)
+	var kind string
+	if r.Kind == exit.KindPartial {
+		kind = fmt.Sprintf("（部分提款 %d gwei）", r.AmountGwei)
+	}
+	switch {
+	case r.Unconfirmed:
+		log.Printf("[#%d] ⏳ 已发送%s，未在时限内确认: tx=%s", r.Index, kind, r.Hash)
+	case r.Block > 0:
+		log.Printf("[#%d] ✅ 成功%s: tx=%s block=%d", r.Index, kind, r.Hash, r.Block)
+	default:
+		log.Printf("[#%d] ✅ 已发送%s: tx=%s", r.Index, kind, r.Hash)
+	}
+	switch r.Queue {
+	case queueQueued:
+		log.Printf("[#%d] 📥 已入队：前面还有 %d 条（约 %d 个区块后出队）", r.Index, r.QueuePos, r.QueueWait)
+	case queueDequeued:
+		log.Printf("[#%d] 📤 回执日志确认已入队，已出队交给共识层", r.Index)
+	case queueMissing:
+		log.Printf("[#%d] ⚠️ 回执日志与合约队列中都没有找到该请求（合约地址是否正确？）", r.Index)
 	}
 }
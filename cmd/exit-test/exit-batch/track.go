@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/exit"
+)
+
+// --track-until 的取值：跟踪到退出的哪一步为止（依次推进）
+const (
+	trackInitiated    = "initiated"    // exit_epoch 已设置：共识层已处理该请求
+	trackExited       = "exited"       // 到达 exit_epoch，不再履行职责
+	trackWithdrawable = "withdrawable" // 到达 withdrawable_epoch，余额可被提走
+	trackWithdrawn    = "withdrawn"    // 余额已被提走（为 0）
+)
+
+// trackStages 各步按推进顺序排列，下标即 stageOf 的返回值（0 为尚未发起退出）
+var trackStages = []string{"", trackInitiated, trackExited, trackWithdrawable, trackWithdrawn}
+
+// stageOf 验证者状态对应的退出进度
+func stageOf(status string) int {
+	switch status {
+	case beaconstate.StatusActiveExiting, beaconstate.StatusActiveSlashed:
+		return 1
+	case beaconstate.StatusExitedUnslashed, beaconstate.StatusExitedSlashed:
+		return 2
+	case beaconstate.StatusWithdrawalPossible:
+		return 3
+	case beaconstate.StatusWithdrawalDone:
+		return 4
+	}
+	return 0
+}
+
+// stageIndex --track-until 对应的进度；未知取值返回 -1
+func stageIndex(until string) int {
+	for i, s := range trackStages {
+		if i > 0 && s == until {
+			return i
+		}
+	}
+	return -1
+}
+
+// trackEvent 时间线上的一次状态变化
+type trackEvent struct {
+	Status string  `json:"status"`
+	Slot   uint64  `json:"slot"`
+	Epoch  uint64  `json:"epoch"`
+	After  float64 `json:"after_seconds"` // 距请求交易上链的秒数
+}
+
+// exitTrack 一个验证者的退出时间线
+type exitTrack struct {
+	ValidatorIndex    int          `json:"validator_index"`
+	ExitEpoch         uint64       `json:"exit_epoch"`
+	WithdrawableEpoch uint64       `json:"withdrawable_epoch"`
+	Stage             string       `json:"stage,omitempty"` // 已到达的最后一步（为空表示尚未发起退出）
+	Timeline          []trackEvent `json:"timeline"`
+	Found             bool         `json:"-"`
+}
+
+// trackExits 全额退出上链后轮询信标状态，记录每个验证者的状态变化，直到全部到达 until 这一步或超时。
+// 时间线写回 results[i].Track
+func trackExits(ctx context.Context, r beaconext.BeaconReader, results []Result, slotsPerEpoch uint64, until string, poll, timeout time.Duration) {
+	target := stageIndex(until)
+	want := map[string]int{} // 公钥 -> results 下标
+	for i, res := range results {
+		if res.Err == nil && res.Kind == exit.KindFullExit && res.Block > 0 {
+			want[beaconstate.NormPubkey(res.Pubkey)] = i
+			results[i].Track = &exitTrack{ValidatorIndex: -1}
+		}
+	}
+	if len(want) == 0 {
+		log.Printf("⚠️ 退出跟踪：没有已上链的全额退出请求")
+		return
+	}
+	log.Printf("🛰️ 退出跟踪：等待 %d 个验证者到达 %s（每 %s 读取一次信标状态，最长 %s）……", len(want), until, poll, timeout)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		st, err := beaconstate.FetchLatest(ctx, r)
+		if err != nil {
+			log.Printf("⚠️ 读取信标状态失败: %v", err)
+		} else {
+			epoch := st.Epoch(slotsPerEpoch)
+			index := st.Index()
+			reached := 0
+			for pk, i := range want {
+				t := results[i].Track
+				vi, ok := index[pk]
+				if !ok {
+					continue
+				}
+				v := st.Validators[vi]
+				balance := st.Balance(vi)
+				status := v.Status(epoch, balance)
+				t.Found, t.ValidatorIndex = true, vi
+				t.ExitEpoch, t.WithdrawableEpoch = v.ExitEpoch, v.WithdrawableEpoch
+				if n := len(t.Timeline); n == 0 || t.Timeline[n-1].Status != status {
+					after := time.Since(results[i].Confirmed).Seconds()
+					t.Timeline = append(t.Timeline, trackEvent{Status: status, Slot: st.Slot, Epoch: epoch, After: after})
+					t.Stage = trackStages[stageOf(status)]
+					log.Printf("   [#%d] validator_index=%d → %s（slot %d，epoch %d，exit_epoch=%s）",
+						results[i].Index, vi, status, st.Slot, epoch, epochString(v.ExitEpoch))
+				}
+				if stageOf(status) >= target {
+					reached++
+				}
+			}
+			log.Printf("🛰️ slot %d（epoch %d）：%d/%d 已到达 %s", st.Slot, epoch, reached, len(want), until)
+			if reached == len(want) {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			log.Printf("⚠️ 退出跟踪超时（%s）", timeout)
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(poll):
+		}
+	}
+	printTimelines(results)
+}
+
+// printTimelines 逐个验证者打印退出时间线
+func printTimelines(results []Result) {
+	for _, r := range results {
+		t := r.Track
+		if t == nil {
+			continue
+		}
+		if !t.Found {
+			log.Printf("❌ [#%d] %s 未出现在信标状态中", r.Index, r.Pubkey)
+			continue
+		}
+		stage := t.Stage
+		if stage == "" {
+			stage = "未发起退出"
+		}
+		log.Printf("🛰️ [#%d] %s validator_index=%d exit_epoch=%s withdrawable_epoch=%s，进度 %s",
+			r.Index, r.Pubkey, t.ValidatorIndex, epochString(t.ExitEpoch), epochString(t.WithdrawableEpoch), stage)
+		for _, e := range t.Timeline {
+			log.Printf("     +%-10s slot %-8d epoch %-6d %s",
+				(time.Duration(e.After * float64(time.Second))).Round(time.Second), e.Slot, e.Epoch, e.Status)
+		}
+	}
+}
+
+// trackSummary 写入运行清单的跟踪计数（按已到达的步骤；未出现在信标状态中的记为 not_found）
+func trackSummary(results []Result) map[string]int {
+	counts := map[string]int{}
+	for _, r := range results {
+		switch t := r.Track; {
+		case t == nil:
+		case !t.Found:
+			counts["not_found"]++
+		case t.Stage == "":
+			counts["pending"]++
+		default:
+			counts[t.Stage]++
+		}
+	}
+	return counts
+}
+
+// epochString FarFutureEpoch 显示为 "-"
+func epochString(e uint64) string {
+	if e == beaconstate.FarFutureEpoch {
+		return "-"
+	}
+	return strconv.FormatUint(e, 10)
+}
+
+// validateTrack 检查 --track 相关参数
+func validateTrack(until string, wait bool) error {
+	if stageIndex(until) < 0 {
+		return fmt.Errorf("未知 --track-until=%s（可选 %s|%s|%s|%s）", until, trackInitiated, trackExited, trackWithdrawable, trackWithdrawn)
+	}
+	if !wait {
+		return fmt.Errorf("--track 需要 --wait（只跟踪已上链的请求）")
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/capability"
+	"n42-test/internal/envflag"
+	"n42-test/internal/exit"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+	"n42-test/internal/manifest"
+	"n42-test/internal/rpcpool"
+	"n42-test/internal/rundir"
+)
+
+// EIP-7002 合约存储槽
+const (
+	slotExcess    = 0 // 超额请求数（决定费用）
+	slotCount     = 1 // 当前块内的请求数
+	slotQueueHead = 2
+	slotQueueTail = 3
+)
+
+type JsonItem struct {
+	DepositPrivateKey string `json:"deposit-private-key"`
+	ExitPrivateKey    string `json:"exit-private-key,omitempty"`
+	ValidatorPubkey   string `json:"validator-public-key"`
+}
+
+// 一个发交易的账户：同一账户串行发送，避免 nonce 冲突
+type sender struct {
+	priv *ecdsa.PrivateKey
+}
+
+type request struct {
+	seq    int
+	rate   float64
+	pubkey []byte
+}
+
+func main() {
+	jsonPath := flag.String("json", "deposit-data.json", "JSON 文件（取其中的私钥作为发送账户）")
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC")
+	contractAddr := flag.String("contract", "0x00000961Ef480Eb55e80D19ad83579A64c007002", "Exit 合约地址 (0x..)")
+	startRate := flag.Float64("start-rate", 1, "起始发送速率（请求/秒）")
+	stepRate := flag.Float64("step-rate", 1, "每个阶段速率增加量（请求/秒）")
+	maxRate := flag.Float64("max-rate", 10, "最大发送速率（请求/秒）")
+	stepDur := flag.Duration("step-duration", time.Minute, "每个速率阶段持续时间")
+	drainDur := flag.Duration("drain", time.Minute, "停止发送后继续采样的时长（观察队列消化）")
+	amountWei := flag.String("amount-wei", "1", "每个退出请求的 amount（>0 为部分提款，避免真正退出验证者）")
+	randomPubkeys := flag.Bool("random-pubkeys", true, "使用随机公钥（不影响真实验证者）；false 时轮流使用 JSON 中的公钥")
+	outPath := flag.String("out", "exit-stress.csv", "每个请求一行的 CSV（发送时间、目标速率、费用、tx、错误）")
+	samplesPath := flag.String("samples", "exit-stress-samples.csv", "每个区块一行的 CSV（费用、excess、队列头尾与长度）")
+	runsDir := flag.String("runs-dir", rundir.DefaultRootDir(), "运行目录根：每次运行在 <根>/<run_id>/ 下保存清单与日志，未显式指定 --out/--samples 时两个 CSV 也写到 results/；为空不创建")
+	envflag.Parse("exit-stress")
+
+	if !common.IsHexAddress(*contractAddr) {
+		log.Fatalf("非法的 --contract 地址")
+	}
+	contract := common.HexToAddress(*contractAddr)
+	if *startRate <= 0 || *maxRate < *startRate || *stepRate <= 0 {
+		log.Fatalf("速率参数非法：需要 0 < start-rate <= max-rate 且 step-rate > 0")
+	}
+	amt, ok := new(big.Int).SetString(*amountWei, 10)
+	if !ok || amt.Sign() < 0 {
+		log.Fatalf("无法解析 --amount-wei=%s", *amountWei)
+	}
+
+	items, err := readJson(*jsonPath)
+	if err != nil {
+		log.Fatalf("读取 JSON 失败: %v", err)
+	}
+	senders, pubkeys, err := loadAccounts(items)
+	if err != nil {
+		log.Fatalf("解析账户失败: %v", err)
+	}
+	if !*randomPubkeys && len(pubkeys) == 0 {
+		log.Fatalf("JSON 中没有可用的 validator-public-key")
+	}
+	// 并发压测各自落到自己的运行目录，默认文件名不再互相覆盖
+	var runDir *rundir.Run
+	if *runsDir != "" {
+		runDir = rundir.Start(*runsDir, manifest.ForRun("exit-stress", *jsonPath, *rpcURL))
+	}
+	if runDir != nil {
+		set := map[string]bool{}
+		flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["out"] {
+			*outPath = runDir.Path(rundir.Results, *outPath)
+		}
+		if !set["samples"] {
+			*samplesPath = runDir.Path(rundir.Results, *samplesPath)
+		}
+	}
+
+	log.Printf("发送账户 %d 个；速率 %.2f→%.2f req/s（步长 %.2f，每阶段 %s）", len(senders), *startRate, *maxRate, *stepRate, *stepDur)
+
+	cli, err := rpcpool.DialEth(context.Background(), *rpcURL)
+	if err != nil {
+		log.Fatalf("RPC 连接失败: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	reqOut, err := newCSV(*outPath, "time", "elapsed_s", "target_rate", "seq", "fee_wei", "tx_hash", "error")
+	if err != nil {
+		log.Fatalf("创建 %s 失败: %v", *outPath, err)
+	}
+	defer reqOut.close()
+	sampleOut, err := newCSV(*samplesPath, "time", "elapsed_s", "block", "fee_wei", "excess", "count", "queue_head", "queue_tail", "queue_len")
+	if err != nil {
+		log.Fatalf("创建 %s 失败: %v", *samplesPath, err)
+	}
+	defer sampleOut.close()
+
+	startAt := time.Now()
+	caps := capability.For(ctx, *rpcURL)
+
+	// ---------- 区块采样 ----------
+	sampleCtx, stopSampling := context.WithCancel(ctx)
+	var sampleWG sync.WaitGroup
+	sampleWG.Add(1)
+	go func() {
+		defer sampleWG.Done()
+		sampleBlocks(sampleCtx, cli, contract, startAt, sampleOut)
+	}()
+
+	// ---------- 发送者 ----------
+	reqs := make(chan request, len(senders))
+	var stats phaseStats
+	var sendWG sync.WaitGroup
+	for _, s := range senders {
+		sendWG.Add(1)
+		go func(s sender) {
+			defer sendWG.Done()
+			for r := range reqs {
+				sctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+				tx, _, err := exit.SendExitRequestWithCaps(sctx, cli, caps, s.priv, contract, r.pubkey, amt, false)
+				cancel()
+				now := time.Now()
+				row := []string{now.UTC().Format(time.RFC3339Nano), secs(now.Sub(startAt)), fmt.Sprintf("%.2f", r.rate), strconv.Itoa(r.seq)}
+				if err != nil {
+					stats.failed.Add(1)
+					row = append(row, "", "", err.Error())
+				} else {
+					stats.sent.Add(1)
+					stats.observeFee(tx.Value())
+					row = append(row, tx.Value().String(), tx.Hash().Hex(), "")
+				}
+				reqOut.write(row)
+			}
+		}(s)
+	}
+
+	// ---------- 逐级加压 ----------
+	seq := 0
+	for rate := *startRate; rate <= *maxRate+1e-9 && ctx.Err() == nil; rate += *stepRate {
+		stats.reset()
+		seq = runPhase(ctx, rate, *stepDur, seq, reqs, &stats, func(i int) []byte {
+			if *randomPubkeys {
+				return randomPubkey()
+			}
+			return pubkeys[i%len(pubkeys)]
+		})
+		log.Printf("阶段 %.2f req/s：%s", rate, stats.String())
+	}
+	close(reqs)
+	sendWG.Wait()
+
+	// ---------- 消化观察 ----------
+	if ctx.Err() == nil && *drainDur > 0 {
+		log.Printf("停止发送，继续采样 %s 观察队列消化……", *drainDur)
+		select {
+		case <-ctx.Done():
+		case <-time.After(*drainDur):
+		}
+	}
+	stopSampling()
+	sampleWG.Wait()
+
+	log.Printf("完成：请求明细 %s，区块采样 %s，总耗时 %s", *outPath, *samplesPath, time.Since(startAt).Round(time.Second))
+	if runDir != nil {
+		if err := runDir.Finish(); err != nil {
+			log.Printf("⚠️ 写运行清单失败: %v", err)
+		}
+	}
+}
+
+// runPhase 以固定速率投递请求 d 时长；所有发送者都忙时记为 skipped（速率无法达到）
+func runPhase(ctx context.Context, rate float64, d time.Duration, seq int, reqs chan<- request, stats *phaseStats, pubkey func(int) []byte) int {
+	interval := time.Duration(float64(time.Second) / rate)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	end := time.After(d)
+	for {
+		select {
+		case <-ctx.Done():
+			return seq
+		case <-end:
+			return seq
+		case <-t.C:
+			select {
+			case reqs <- request{seq: seq, rate: rate, pubkey: pubkey(seq)}:
+				seq++
+			default:
+				stats.skipped.Add(1)
+			}
+		}
+	}
+}
+
+// sampleBlocks 每出一个新块记录一次费用与队列状态
+func sampleBlocks(ctx context.Context, cli *ethclient.Client, contract common.Address, startAt time.Time, out *csvOut) {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	var last uint64
+	var lastHead, lastHeadBlock uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		num, err := cli.BlockNumber(ctx)
+		if err != nil || num == last {
+			continue
+		}
+		last = num
+		at := new(big.Int).SetUint64(num)
+
+		fee, err := exit.GetExitFee(ctx, cli, contract)
+		if err != nil {
+			continue
+		}
+		slots := make([]uint64, 4)
+		for i := range slots {
+			raw, err := cli.StorageAt(ctx, contract, common.BigToHash(big.NewInt(int64(i))), at)
+			if err == nil {
+				slots[i] = new(big.Int).SetBytes(raw).Uint64()
+			}
+		}
+		head, tail := slots[slotQueueHead], slots[slotQueueTail]
+		qlen := uint64(0)
+		if tail > head {
+			qlen = tail - head
+		}
+		// 队列头前进的速度即 CL 消化请求的速度
+		if lastHeadBlock > 0 && head > lastHead {
+			printEvery(fmt.Sprintf("block #%d fee=%s queue=%d dequeued=%d in %d blocks", num, fee, qlen, head-lastHead, num-lastHeadBlock))
+		}
+		lastHead, lastHeadBlock = head, num
+
+		now := time.Now()
+		out.write([]string{
+			now.UTC().Format(time.RFC3339Nano), secs(now.Sub(startAt)), strconv.FormatUint(num, 10), fee.String(),
+			strconv.FormatUint(slots[slotExcess], 10), strconv.FormatUint(slots[slotCount], 10),
+			strconv.FormatUint(head, 10), strconv.FormatUint(tail, 10), strconv.FormatUint(qlen, 10),
+		})
+	}
+}
+
+// ---------------- 统计 ----------------
+
+type phaseStats struct {
+	sent    atomic.Int64
+	failed  atomic.Int64
+	skipped atomic.Int64
+
+	mu     sync.Mutex
+	feeSum *big.Int
+	feeMax *big.Int
+}
+
+func (s *phaseStats) reset() {
+	s.sent.Store(0)
+	s.failed.Store(0)
+	s.skipped.Store(0)
+	s.mu.Lock()
+	s.feeSum, s.feeMax = new(big.Int), new(big.Int)
+	s.mu.Unlock()
+}
+
+func (s *phaseStats) observeFee(fee *big.Int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feeSum.Add(s.feeSum, fee)
+	if fee.Cmp(s.feeMax) > 0 {
+		s.feeMax.Set(fee)
+	}
+}
+
+func (s *phaseStats) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	avg := new(big.Int)
+	if n := s.sent.Load(); n > 0 {
+		avg.Div(s.feeSum, big.NewInt(n))
+	}
+	return fmt.Sprintf("sent=%d failed=%d skipped=%d fee_avg=%s fee_max=%s (wei)",
+		s.sent.Load(), s.failed.Load(), s.skipped.Load(), avg, s.feeMax)
+}
+
+// ---------------- CSV ----------------
+
+type csvOut struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *csv.Writer
+}
+
+func newCSV(path string, header ...string) (*csvOut, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &csvOut{f: f, w: csv.NewWriter(f)}
+	c.write(header)
+	return c, nil
+}
+
+// write 写一行并立即 flush，中途中断也不丢数据
+func (c *csvOut) write(row []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.w.Write(row)
+	c.w.Flush()
+}
+
+func (c *csvOut) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.Flush()
+	_ = c.f.Close()
+}
+
+// ---------------- utils ----------------
+
+func readJson(path string) ([]JsonItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var arr []JsonItem
+	if err := json.NewDecoder(f).Decode(&arr); err != nil {
+		return nil, err
+	}
+	if len(arr) == 0 {
+		return nil, errors.New("JSON 空数组")
+	}
+	return arr, nil
+}
+
+// loadAccounts 去重后的发送账户与可用的公钥
+func loadAccounts(items []JsonItem) ([]sender, [][]byte, error) {
+	seen := map[common.Address]bool{}
+	var senders []sender
+	var pubkeys [][]byte
+	for i, it := range items {
+		raw := strings.TrimSpace(firstNonEmpty(it.ExitPrivateKey, it.DepositPrivateKey))
+		if raw != "" {
+			priv, err := keys.ParseECDSA(raw)
+			if err != nil {
+				return nil, nil, fmt.Errorf("index %d: 私钥解析失败: %w", i, err)
+			}
+			addr := crypto.PubkeyToAddress(priv.PublicKey)
+			if !seen[addr] {
+				seen[addr] = true
+				senders = append(senders, sender{priv: priv})
+			}
+		}
+		if pk, err := hexutil.DecodeFixed(it.ValidatorPubkey, hexutil.PubkeyLen); err == nil {
+			pubkeys = append(pubkeys, pk)
+		}
+	}
+	if len(senders) == 0 {
+		return nil, nil, errors.New("没有可用的私钥（exit-private-key 或 deposit-private-key）")
+	}
+	return senders, pubkeys, nil
+}
+
+func randomPubkey() []byte {
+	b := make([]byte, 48)
+	_, _ = rand.Read(b)
+	return b
+}
+
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if strings.TrimSpace(s) != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func secs(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+var lastPrint atomic.Int64
+
+// 每秒最多打印一次，避免刷屏
+func printEvery(s string) {
+	now := time.Now().Unix()
+	if lastPrint.Swap(now) != now {
+		log.Println(s)
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/capability"
+	"n42-test/internal/consolidate"
+	"n42-test/internal/envflag"
+	"n42-test/internal/exit"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+	"n42-test/internal/rpcpool"
+	"n42-test/internal/units"
+)
+
+// 交互式向导（同 deposit-test 的存款向导）：从信标状态查出验证者，展示余额与提款凭证，
+// 计算费用并预检，确认后才发送。供手工的一次性操作使用。
+//
+//	exit-test exit         全额退出 / 部分提款（EIP-7002）
+//	exit-test consolidate  合并 / 切换为复利凭证（EIP-7251）
+
+// wizard 向导共用的连接与信标状态
+type wizard struct {
+	rpc      string
+	contract common.Address
+	cli      *ethclient.Client
+	caps     *capability.Matrix
+	state    *beaconstate.State // 取不到时为 nil，跳过查找与预检
+	spe      uint64
+	timeout  time.Duration
+}
+
+// newWizard 解析子命令参数并连接节点；defContract 为系统合约的默认地址
+func newWizard(name, defContract string, args []string) (*wizard, error) {
+	fs := flag.NewFlagSet("exit-test "+name, flag.ExitOnError)
+	rpcURL := fs.String("rpc", "http://127.0.0.1:8545", "执行层 RPC（同时用于 consensusBeaconExt 查询）")
+	contractAddr := fs.String("contract", defContract, "系统合约地址（0x…）")
+	slotsPerEpoch := fs.Uint64("slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "计算当前纪元用的每纪元 slot 数")
+	timeout := fs.Duration("timeout", 3*time.Minute, "发送并等待回执的超时")
+	envflag.ParseSet(fs, "", args)
+
+	if !common.IsHexAddress(*contractAddr) {
+		return nil, fmt.Errorf("非法合约地址: %s", *contractAddr)
+	}
+	w := &wizard{rpc: *rpcURL, contract: common.HexToAddress(*contractAddr), spe: *slotsPerEpoch, timeout: *timeout}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	cli, err := rpcpool.DialEth(ctx, w.rpc)
+	if err != nil {
+		return nil, fmt.Errorf("连接 RPC 失败: %w", err)
+	}
+	w.cli = cli
+	w.caps = capability.For(ctx, w.rpc)
+	if w.state, err = beaconstate.FetchLatest(ctx, beaconext.NewClient(rpcpool.Parse(w.rpc)[0])); err != nil {
+		fmt.Printf("⚠️ 获取信标状态失败，无法查找验证者与预检: %v\n", err)
+	}
+	return w, nil
+}
+
+func (w *wizard) header(title string) {
+	fmt.Printf("=== %s ===\n", title)
+	fmt.Printf("RPC: %s\n合约: %s\n", w.rpc, w.contract.Hex())
+	if w.state != nil {
+		fmt.Printf("信标状态: slot=%d 纪元 %d，%d 个验证者\n", w.state.Slot, w.state.Epoch(w.spe), len(w.state.Validators))
+	}
+	fmt.Println()
+}
+
+// readSender 读取发送账户私钥并显示地址与余额
+func (w *wizard) readSender(prompt string) *ecdsa.PrivateKey {
+	for {
+		priv, err := keys.ParseECDSA(readHexWithLen(prompt, 32))
+		if err != nil {
+			fmt.Printf("⚠️ 私钥无效：%v\n", err)
+			continue
+		}
+		from := crypto.PubkeyToAddress(priv.PublicKey)
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		bal, err := w.cli.BalanceAt(ctx, from, nil)
+		cancel()
+		if err != nil {
+			fmt.Printf("   发送者 %s（余额查询失败：%v）\n", from.Hex(), err)
+		} else {
+			fmt.Printf("   发送者 %s，余额 %s ETH\n", from.Hex(), units.FromWei(bal).ETH())
+		}
+		return priv
+	}
+}
+
+// readValidator 读取验证者公钥并显示其在信标状态中的信息
+func (w *wizard) readValidator(prompt string) []byte {
+	pk := readHexWithLen(prompt, hexutil.PubkeyLen)
+	b, _ := hexutil.Decode(pk)
+	if w.state == nil {
+		return b
+	}
+	info, err := exit.LookupValidator(w.state, b, w.spe)
+	if err != nil {
+		fmt.Printf("   ⚠️ 查找失败：%v\n", err)
+		return b
+	}
+	printValidator(info)
+	return b
+}
+
+func printValidator(v *exit.ValidatorInfo) {
+	if !v.Found() {
+		fmt.Println("   ⚠️ 信标状态中没有该验证者")
+		return
+	}
+	fmt.Printf("   下标 %d，状态 %s\n", v.Index, v.Status)
+	fmt.Printf("   余额 %s ETH，有效余额 %s ETH", units.FromGwei(v.Balance).ETH(), units.FromGwei(v.EffectiveBalance).ETH())
+	if v.PendingGwei > 0 {
+		fmt.Printf("，排队中的部分提款 %s ETH", units.FromGwei(v.PendingGwei).ETH())
+	}
+	fmt.Println()
+	if v.HasExecutionAddress() {
+		fmt.Printf("   提款凭证 %s，提款地址 %s\n", v.CredentialType, v.WithdrawalAddress.Hex())
+	} else {
+		fmt.Printf("   提款凭证 %s（没有执行层地址，无法通过合约发起请求）\n", v.CredentialType)
+	}
+}
+
+// showFee 显示请求费用与预估 gas，发送者余额不足时提示；返回 false 表示无法取得费用
+func (w *wizard) showFee(from common.Address, calldata []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	fee, err := exit.GetExitFee(ctx, w.cli, w.contract)
+	if err != nil {
+		fmt.Printf("❌ 读取请求费用失败：%v\n", err)
+		return false
+	}
+	if fee.Sign() <= 0 {
+		// 系统合约的费用至少 1 wei；为 0 通常是该地址上没有合约
+		fmt.Printf("❌ 请求费用为 %s：%s 上可能没有部署系统合约\n", fee, w.contract.Hex())
+		return false
+	}
+	fmt.Println("\n=== 费用 ===")
+	fmt.Printf("请求费用: %s wei（%s ETH）\n", fee, units.FromWei(fee).ETH())
+	total := new(big.Int).Set(fee)
+	gas, gasErr := w.cli.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &w.contract, Value: fee, Data: calldata})
+	price, priceErr := w.cli.SuggestGasPrice(ctx)
+	if gasErr != nil || priceErr != nil {
+		fmt.Printf("预估 gas 失败：%v\n", errors.Join(gasErr, priceErr))
+	} else {
+		cost := new(big.Int).Mul(new(big.Int).SetUint64(gas), price)
+		total.Add(total, cost)
+		fmt.Printf("预估 gas: %d × %s wei = %s ETH\n", gas, price, units.FromWei(cost).ETH())
+		fmt.Printf("预估合计: %s ETH（发送时 gas 上限与价格留有余量，实际以回执为准）\n", units.FromWei(total).ETH())
+	}
+	if bal, err := w.cli.BalanceAt(ctx, from, nil); err == nil && bal.Cmp(total) < 0 {
+		fmt.Printf("⚠️ 发送者余额 %s ETH 不足以支付费用\n", units.FromWei(bal).ETH())
+	}
+	return true
+}
+
+// confirmSend 汇总预检结果并确认；预检未通过时明确提示费用不退
+func confirmSend(problems []string, checked bool) bool {
+	fmt.Println("\n=== 预检 ===")
+	switch {
+	case !checked:
+		fmt.Println("⚠️ 没有信标状态，未预检")
+	case len(problems) == 0:
+		fmt.Println("✅ 信标状态满足处理条件")
+	default:
+		for _, p := range problems {
+			fmt.Println("❌ " + p)
+		}
+		return confirm("信标链会忽略该请求，合约费用不退。仍要发送？[y/N] ")
+	}
+	return confirm("确认发送？[y/N] ")
+}
+
+// send 发送并输出回执
+func (w *wizard) send(do func(ctx context.Context) (*types.Transaction, *types.Receipt, error)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	tx, rcpt, err := do(ctx)
+	if err != nil {
+		return fmt.Errorf("发送失败: %w", err)
+	}
+	fmt.Println("\n=== 交易结果 ===")
+	fmt.Println("TxHash:", tx.Hash().Hex())
+	if rcpt != nil {
+		fmt.Printf("Status=%d BlockNumber=%s GasUsed=%d\n", rcpt.Status, rcpt.BlockNumber, rcpt.GasUsed)
+		if rcpt.Status == 0 {
+			return errors.New("交易 revert（status=0）")
+		}
+	}
+	return nil
+}
+
+// exitWizard exit-test exit：全额退出或部分提款
+func exitWizard(args []string) error {
+	w, err := newWizard("exit", "0x00000961Ef480Eb55e80D19ad83579A64c007002", args)
+	if err != nil {
+		return err
+	}
+	defer w.cli.Close()
+	w.header("交互式退出 / 部分提款（EIP-7002）")
+
+	priv := w.readSender("1) 发送账户私钥（须为验证者的提款地址，EOA 32B 0x…）: ")
+	from := crypto.PubkeyToAddress(priv.PublicKey)
+	pubkey := w.readValidator("2) 验证者 BLS 公钥（48B 0x…）: ")
+	amount := readGwei("3) 提取金额（默认单位 ETH，可小数，可带后缀 eth|gwei|wei；回车或 0 为全额退出）: ")
+	req := exit.WithdrawalRequest{Pubkey: pubkey, AmountGwei: amount}
+
+	var problems []string
+	if w.state != nil {
+		c, err := exit.CheckWithdrawalRequest(w.state, from, req, w.spe)
+		if err != nil {
+			return err
+		}
+		problems = c.Problems
+		if !req.IsFullExit() && c.Found() {
+			fmt.Printf("   可提取上限 %s ETH（余额 - 32 ETH - 排队中的部分提款）\n", units.FromGwei(c.ExcessGwei()).ETH())
+		}
+	}
+	calldata, err := req.Calldata()
+	if err != nil {
+		return err
+	}
+	if !w.showFee(from, calldata) {
+		return errors.New("无法取得请求费用")
+	}
+	fmt.Println("\n=== 请求 ===")
+	if req.IsFullExit() {
+		fmt.Println("类型: 全额退出（验证者进入退出队列，余额在可提取后全部转到提款地址）")
+	} else {
+		fmt.Printf("类型: 部分提款 %s ETH\n", units.FromGwei(req.AmountGwei).ETH())
+	}
+	if !confirmSend(problems, w.state != nil) {
+		fmt.Println("已取消。")
+		return nil
+	}
+	return w.send(func(ctx context.Context) (*types.Transaction, *types.Receipt, error) {
+		return exit.SendWithdrawalRequest(ctx, w.cli, w.caps, priv, w.contract, req, true)
+	})
+}
+
+// consolidateWizard exit-test consolidate：合并，或 source 与 target 相同时切换为复利凭证
+func consolidateWizard(args []string) error {
+	w, err := newWizard("consolidate", consolidate.DefaultContract, args)
+	if err != nil {
+		return err
+	}
+	defer w.cli.Close()
+	w.header("交互式合并 / 切换复利凭证（EIP-7251）")
+
+	priv := w.readSender("1) 发送账户私钥（须为 source 验证者的提款地址，EOA 32B 0x…）: ")
+	from := crypto.PubkeyToAddress(priv.PublicKey)
+	source := w.readValidator("2) source 验证者 BLS 公钥（余额转出并退出，48B 0x…）: ")
+	target := source
+	if s := readLine("3) target 验证者 BLS 公钥（接收余额，须为 0x02 凭证；回车与 source 相同，即把 source 切换为 0x02）: "); s != "" {
+		b, err := hexutil.DecodeFixed(s, hexutil.PubkeyLen)
+		if err != nil {
+			return fmt.Errorf("target 公钥: %w", err)
+		}
+		target = b
+		if w.state != nil {
+			info, err := exit.LookupValidator(w.state, target, w.spe)
+			if err != nil {
+				return err
+			}
+			printValidator(info)
+		}
+	}
+	req := consolidate.Request{Source: source, Target: target}
+
+	var problems []string
+	if w.state != nil {
+		c, err := consolidate.CheckRequest(w.state, from, req, w.spe)
+		if err != nil {
+			return err
+		}
+		problems = c.Problems
+	}
+	calldata, err := req.Calldata()
+	if err != nil {
+		return err
+	}
+	if !w.showFee(from, calldata) {
+		return errors.New("无法取得请求费用")
+	}
+	fmt.Println("\n=== 请求 ===")
+	if req.IsSwitchToCompounding() {
+		fmt.Println("类型: 切换为复利凭证（0x01 → 0x02，余额不变）")
+	} else {
+		fmt.Println("类型: 合并（source 的余额并入 target，source 退出）")
+	}
+	if !confirmSend(problems, w.state != nil) {
+		fmt.Println("已取消。")
+		return nil
+	}
+	return w.send(func(ctx context.Context) (*types.Transaction, *types.Receipt, error) {
+		return consolidate.SendRequest(ctx, w.cli, w.caps, priv, w.contract, req, true)
+	})
+}
+
+// —— 输入辅助 —— //
+var in = bufio.NewReader(os.Stdin)
+
+func readLine(prompt string) string {
+	fmt.Print(prompt)
+	s, err := in.ReadString('\n')
+	if err != nil && s == "" {
+		// 输入已结束（非交互管道），避免死循环
+		fmt.Println()
+		os.Exit(1)
+	}
+	return strings.TrimSpace(s)
+}
+
+func readHexWithLen(prompt string, wantBytes int) string {
+	for {
+		s := readLine(prompt)
+		if s == "" {
+			fmt.Println("⚠️ 不能为空")
+			continue
+		}
+		if _, err := hexutil.DecodeFixed(s, wantBytes); err != nil {
+			fmt.Printf("⚠️ 非法十六进制：%v\n", err)
+			continue
+		}
+		return hexutil.Normalize(s)
+	}
+}
+
+// readGwei 读取金额（默认单位 ETH，可小数、可带 eth|gwei|wei 后缀，精确到 1 gwei），空为 0
+func readGwei(prompt string) uint64 {
+	for {
+		s := readLine(prompt)
+		if s == "" {
+			return 0
+		}
+		g, err := units.ParseGwei(s, units.Ether)
+		if err != nil {
+			fmt.Printf("⚠️ %v，请重试\n", err)
+			continue
+		}
+		return g
+	}
+}
+
+func confirm(prompt string) bool {
+	line := strings.ToLower(readLine(prompt))
+	return line == "y" || line == "yes"
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/blsutil"
+	"n42-test/internal/envflag"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+	"n42-test/internal/netprofile"
+	"n42-test/internal/units"
+)
+
+// accountItem 与 deposit-batch 读取的 accounts.json 条目同形
+type accountItem struct {
+	WithdrawalPrivateKey string `json:"withdrawal-private-key"`
+	ValidatorPublicKey   string `json:"validator-public-key"`
+	WithdrawalAddress    string `json:"withdrawal-address"`
+	ValidatorPrivateKey  string `json:"validator-private-key"`
+	DepositPrivateKey    string `json:"deposit-private-key"`
+}
+
+func main() {
+	n := flag.Int("n", 10, "生成的验证者数量")
+	out := flag.String("out", "accounts.json", "输出的 accounts.json 路径")
+	force := flag.Bool("force", false, "输出文件已存在时覆盖")
+
+	depositKey := flag.String("deposit-key", "", "所有条目共用的出资账户私钥（不生成 deposit EOA）")
+	sharedDeposit := flag.Bool("shared-deposit", false, "只生成一个出资账户供所有条目共用（默认每条一个）")
+	depositIsWithdrawal := flag.Bool("deposit-is-withdrawal", false, "出资账户与提款账户使用同一私钥（旧版 deposit-data.json 的布局）")
+
+	fundCSV := flag.String("fund-csv", "", "另写一份出资账户充值清单（to,amount_eth），可直接交给 transfer -csv")
+	fundETH := flag.String("fund-eth", "33", "充值清单中每笔存款对应的金额（质押金额 + gas 余量；默认单位 ETH，可带后缀 eth|gwei|wei）")
+
+	profileName := flag.String("profile", netprofile.DefaultName, "网络配置档："+strings.Join(netprofile.Names(), "|"))
+	blsKeyEndian := flag.String("bls-key-endian", "", "BLS 私钥字节序 be|le（覆盖配置档）")
+	blsETHMode := flag.String("bls-eth-mode", "", "BLS ETH mode latest|draft07|draft06|draft05|old（覆盖配置档）")
+	envflag.Parse("keygen")
+
+	if *n <= 0 {
+		log.Fatalf("-n 必须 > 0")
+	}
+	if *depositKey != "" && (*sharedDeposit || *depositIsWithdrawal) {
+		log.Fatalf("-deposit-key 与 -shared-deposit / -deposit-is-withdrawal 互斥")
+	}
+	if *sharedDeposit && *depositIsWithdrawal {
+		log.Fatalf("-shared-deposit 与 -deposit-is-withdrawal 互斥")
+	}
+	if _, err := os.Stat(*out); err == nil && !*force {
+		log.Fatalf("%s 已存在（加 -force 覆盖）", *out)
+	}
+
+	blsutil.EnsureInit()
+	profile, err := netprofile.Lookup(*profileName)
+	if err != nil {
+		log.Fatalf("配置档错误: %v", err)
+	}
+	blsOpts := blsutil.KeyOptionsFromProfile(profile)
+	if *blsKeyEndian != "" {
+		blsOpts.Endian = *blsKeyEndian
+	}
+	if *blsETHMode != "" {
+		blsOpts.ETHMode = *blsETHMode
+	}
+	if err := blsutil.SetDefaultKeyOptions(blsOpts); err != nil {
+		log.Fatalf("BLS 选项错误: %v", err)
+	}
+	littleEndian := strings.EqualFold(blsOpts.Endian, "le") || strings.EqualFold(blsOpts.Endian, "little")
+
+	// 共用的出资账户
+	var shared string
+	switch {
+	case *depositKey != "":
+		if _, err := keys.Address(*depositKey); err != nil {
+			log.Fatalf("-deposit-key 解析失败: %v", err)
+		}
+		shared = *depositKey
+	case *sharedDeposit:
+		if shared, _, err = keys.GenerateECDSA(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	items := make([]accountItem, *n)
+	for i := range items {
+		it, err := generate(shared, *depositIsWithdrawal, littleEndian, blsOpts)
+		if err != nil {
+			log.Fatalf("生成第 %d 条失败: %v", i, err)
+		}
+		items[i] = it
+	}
+
+	b, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 文件里是明文私钥，仅本人可读
+	if err := os.WriteFile(*out, append(b, '\n'), 0o600); err != nil {
+		log.Fatalf("写 %s 失败: %v", *out, err)
+	}
+	log.Printf("✅ 已生成 %d 个验证者 → %s（BLS 私钥字节序 %s）", len(items), *out, blsOpts.Endian)
+
+	perDeposit, err := units.Parse(*fundETH, units.Ether)
+	if err != nil {
+		log.Fatalf("-fund-eth: %v", err)
+	}
+	funding, err := fundingPlan(items, perDeposit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range funding {
+		log.Printf("   出资账户 %s：%d 笔存款，需 %s ETH", f.addr.Hex(), f.deposits, f.amount)
+	}
+	if *fundCSV != "" {
+		if err := writeFundCSV(*fundCSV, funding); err != nil {
+			log.Fatalf("写 %s 失败: %v", *fundCSV, err)
+		}
+		log.Printf("💰 充值清单已写入 %s（go run ./cmd/transfer -key <funder> -csv %s）", *fundCSV, *fundCSV)
+	}
+}
+
+// generate 生成一条：验证者 BLS 密钥对、提款 EOA，以及（未共用时）出资 EOA
+func generate(sharedDeposit string, depositIsWithdrawal, littleEndian bool, opts blsutil.KeyOptions) (accountItem, error) {
+	sk, err := keys.GenerateBLSSecret(littleEndian)
+	if err != nil {
+		return accountItem{}, err
+	}
+	pk, err := blsutil.DerivePublicKeyHex(sk, opts)
+	if err != nil {
+		return accountItem{}, err
+	}
+	wKey, wAddr, err := keys.GenerateECDSA()
+	if err != nil {
+		return accountItem{}, err
+	}
+	dKey := sharedDeposit
+	switch {
+	case depositIsWithdrawal:
+		dKey = wKey
+	case dKey == "":
+		if dKey, _, err = keys.GenerateECDSA(); err != nil {
+			return accountItem{}, err
+		}
+	}
+	return accountItem{
+		WithdrawalPrivateKey: wKey,
+		ValidatorPublicKey:   hexutil.Trim(pk),
+		WithdrawalAddress:    wAddr.Hex(),
+		ValidatorPrivateKey:  sk,
+		DepositPrivateKey:    dKey,
+	}, nil
+}
+
+type funder struct {
+	addr     common.Address
+	deposits int
+	amount   string // ETH
+}
+
+// fundingPlan 每个出资账户需要的 ETH（按首次出现的顺序）
+func fundingPlan(items []accountItem, perDepositWei *big.Int) ([]funder, error) {
+	var out []funder
+	idx := map[common.Address]int{}
+	for _, it := range items {
+		addr, err := keys.Address(it.DepositPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := idx[addr]
+		if !ok {
+			i = len(out)
+			idx[addr] = i
+			out = append(out, funder{addr: addr})
+		}
+		out[i].deposits++
+	}
+	for i := range out {
+		total := new(big.Int).Mul(perDepositWei, big.NewInt(int64(out[i].deposits)))
+		out[i].amount = units.Decimal(total, units.Ether)
+	}
+	return out, nil
+}
+
+func writeFundCSV(path string, funding []funder) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"to", "amount_eth"})
+	for _, fd := range funding {
+		w.Write([]string{fd.addr.Hex(), fd.amount})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
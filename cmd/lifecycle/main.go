@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/blsutil"
+	"n42-test/internal/deposit"
+	"n42-test/internal/envflag"
+	"n42-test/internal/lifecycle"
+	"n42-test/internal/netprofile"
+	"n42-test/internal/units"
+	"n42-test/internal/validator"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法:")
+	fmt.Fprintln(os.Stderr, "  lifecycle run --validator i [flags]")
+	fmt.Fprintln(os.Stderr, "    质押 → 等待激活 → 见证 N 个纪元 → 发起退出 → 等待提款，输出时间线报告")
+	fmt.Fprintln(os.Stderr, "  lifecycle campaign --validators 0-19 --group-size 10 --stagger 30m [flags]")
+	fmt.Fprintln(os.Stderr, "    多个验证者并发运行生命周期，按组错开开始时间，输出活动汇总报告")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "run":
+		err = runOne(os.Args[2:])
+	case "campaign":
+		err = runCampaign(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// common run/campaign 共用的参数
+type common struct {
+	jsonPath          *string
+	rpcURL            *string
+	wsURL             *string
+	depositContract   *string
+	exitContract      *string
+	amountETH         *string
+	attestEpochs      *uint64
+	slotSeconds       *int
+	slotsPerEpoch     *uint64
+	poll              *time.Duration
+	activationTimeout *time.Duration
+	withdrawalTimeout *time.Duration
+	profileName       *string
+	forkVersion       *string
+	gvr               *string
+	reportPath        *string
+}
+
+func commonFlags(fs *flag.FlagSet) *common {
+	return &common{
+		jsonPath:          fs.String("json", "accounts.json", "JSON 文件路径（数组，与 deposit-batch 同格式）"),
+		rpcURL:            fs.String("rpc", "http://127.0.0.1:8545", "执行层 RPC（同时用于 consensusBeaconExt 查询）"),
+		wsURL:             fs.String("ws", "ws://127.0.0.1:8546", "执行层 WS（见证订阅用）"),
+		depositContract:   fs.String("deposit-contract", "", "Deposit 合约地址（0x…）"),
+		exitContract:      fs.String("exit-contract", "0x00000961Ef480Eb55e80D19ad83579A64c007002", "Exit 合约地址（0x…）"),
+		amountETH:         fs.String("amount-eth", "32", "质押金额（默认单位 ETH，可带后缀 eth|gwei|wei；须为 1 gwei 的整数倍）"),
+		attestEpochs:      fs.Uint64("attest-epochs", 2, "激活后见证多少个纪元（0 跳过）"),
+		slotSeconds:       fs.Int("slot-seconds", validator.DefaultSecondsPerSlot, "每个 slot 的秒数"),
+		slotsPerEpoch:     fs.Uint64("slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "每纪元 slot 数"),
+		poll:              fs.Duration("poll", 12*time.Second, "轮询信标状态的间隔"),
+		activationTimeout: fs.Duration("activation-timeout", 2*time.Hour, "等待激活的最长时间"),
+		withdrawalTimeout: fs.Duration("withdrawal-timeout", 2*time.Hour, "发起退出后等待提款的最长时间"),
+		profileName:       fs.String("profile", netprofile.DefaultName, "网络配置档"),
+		forkVersion:       fs.String("fork-version", "", "存款签名域的 fork_version（4 字节十六进制，覆盖配置档）"),
+		gvr:               fs.String("genesis-validators-root", "", "存款签名域的 genesis_validators_root（32 字节十六进制，覆盖配置档）"),
+		reportPath:        fs.String("report", "", "报告输出路径（JSON）；为空只打印"),
+	}
+}
+
+// setup 校验参数、设置 BLS 选项与存款域并读取 JSON
+func (c *common) setup() (lifecycle.Config, []lifecycle.Item, error) {
+	blsutil.EnsureInit()
+	if !ethcommon.IsHexAddress(*c.depositContract) {
+		return lifecycle.Config{}, nil, errors.New("必须提供合法的 --deposit-contract 合约地址 (0x...)")
+	}
+	if !ethcommon.IsHexAddress(*c.exitContract) {
+		return lifecycle.Config{}, nil, errors.New("--exit-contract 不是合法地址")
+	}
+	profile, err := netprofile.Lookup(*c.profileName)
+	if err != nil {
+		return lifecycle.Config{}, nil, err
+	}
+	if err := blsutil.SetDefaultKeyOptions(blsutil.KeyOptionsFromProfile(profile)); err != nil {
+		return lifecycle.Config{}, nil, err
+	}
+	domain, err := deposit.DomainConfigFromProfile(profile, *c.forkVersion, *c.gvr)
+	if err != nil {
+		return lifecycle.Config{}, nil, err
+	}
+	items, err := readJson(*c.jsonPath)
+	if err != nil {
+		return lifecycle.Config{}, nil, fmt.Errorf("读取 JSON 失败: %w", err)
+	}
+
+	amountGwei, err := units.ParseGwei(*c.amountETH, units.Ether)
+	if err == nil && amountGwei == 0 {
+		err = errors.New("质押金额必须 > 0")
+	}
+	if err != nil {
+		return lifecycle.Config{}, nil, fmt.Errorf("--amount-eth: %w", err)
+	}
+	return lifecycle.Config{
+		RPC:               *c.rpcURL,
+		WSURL:             *c.wsURL,
+		DepositContract:   *c.depositContract,
+		ExitContract:      *c.exitContract,
+		Amount:            units.FromGwei(amountGwei),
+		Domain:            domain,
+		AttestEpochs:      *c.attestEpochs,
+		SlotsPerEpoch:     *c.slotsPerEpoch,
+		SecondsPerSlot:    *c.slotSeconds,
+		Poll:              *c.poll,
+		ActivationTimeout: *c.activationTimeout,
+		WithdrawalTimeout: *c.withdrawalTimeout,
+	}, items, nil
+}
+
+func runOne(args []string) error {
+	fs := flag.NewFlagSet("lifecycle run", flag.ExitOnError)
+	c := commonFlags(fs)
+	index := fs.Int("validator", -1, "使用 JSON 中第几条（基于0）")
+	envflag.ParseSet(fs, "", args)
+
+	if *index < 0 {
+		return errors.New("必须指定 --validator")
+	}
+	cfg, items, err := c.setup()
+	if err != nil {
+		return err
+	}
+	if *index >= len(items) {
+		return fmt.Errorf("--validator %d 超出范围（共 %d 条）", *index, len(items))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	tl, runErr := lifecycle.NewRunner(cfg, *index, items[*index]).Run(ctx)
+
+	fmt.Println()
+	tl.Print(os.Stdout)
+	if *c.reportPath != "" {
+		if err := tl.Save(*c.reportPath); err != nil {
+			log.Printf("⚠️ 写报告失败: %v", err)
+		} else {
+			log.Printf("报告已写入 %s", *c.reportPath)
+		}
+	}
+	return runErr
+}
+
+func runCampaign(args []string) error {
+	fs := flag.NewFlagSet("lifecycle campaign", flag.ExitOnError)
+	c := commonFlags(fs)
+	validators := fs.String("validators", "", "参与的 JSON 条目，如 0-19 或 0,2,5-9（为空表示全部）")
+	groupSize := fs.Int("group-size", 10, "每组验证者数；同组同时开始")
+	stagger := fs.Duration("stagger", 30*time.Minute, "相邻两组开始时间的间隔")
+	envflag.ParseSet(fs, "", args)
+
+	cfg, items, err := c.setup()
+	if err != nil {
+		return err
+	}
+	idx, err := parseIndexList(*validators, len(items))
+	if err != nil {
+		return err
+	}
+	members := make([]lifecycle.CampaignMember, len(idx))
+	for i, n := range idx {
+		members[i] = lifecycle.CampaignMember{Index: n, Item: items[n]}
+	}
+	log.Printf("活动：%d 个验证者，每组 %d 个，组间错开 %s", len(members), *groupSize, *stagger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rep := lifecycle.RunCampaign(ctx, lifecycle.CampaignConfig{Config: cfg, GroupSize: *groupSize, Stagger: *stagger}, members)
+
+	fmt.Println()
+	rep.Print(os.Stdout)
+	if *c.reportPath != "" {
+		if err := rep.Save(*c.reportPath); err != nil {
+			log.Printf("⚠️ 写报告失败: %v", err)
+		} else {
+			log.Printf("报告已写入 %s", *c.reportPath)
+		}
+	}
+	if n := rep.Failed(); n > 0 {
+		return fmt.Errorf("%d 个验证者未完成生命周期", n)
+	}
+	return nil
+}
+
+// parseIndexList 解析 "0-19" / "0,2,5-9"；空串表示 0..n-1
+func parseIndexList(s string, n int) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		out := make([]int, n)
+		for i := range out {
+			out[i] = i
+		}
+		return out, nil
+	}
+	var out []int
+	seen := map[int]bool{}
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		a, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("--validators 格式错误: %q", part)
+		}
+		b := a
+		if isRange {
+			if b, err = strconv.Atoi(hi); err != nil || b < a {
+				return nil, fmt.Errorf("--validators 格式错误: %q", part)
+			}
+		}
+		for i := a; i <= b; i++ {
+			if i < 0 || i >= n {
+				return nil, fmt.Errorf("--validators 中的 %d 超出范围（共 %d 条）", i, n)
+			}
+			if !seen[i] {
+				seen[i] = true
+				out = append(out, i)
+			}
+		}
+	}
+	return out, nil
+}
+
+func readJson(path string) ([]lifecycle.Item, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var arr []lifecycle.Item
+	if err := json.NewDecoder(f).Decode(&arr); err != nil {
+		return nil, fmt.Errorf("解析 JSON 数组失败: %w", err)
+	}
+	if len(arr) == 0 {
+		return nil, errors.New("JSON 数组为空")
+	}
+	return arr, nil
+}
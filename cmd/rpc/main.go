@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"n42-test/internal/conformance"
+	"n42-test/internal/envflag"
+	"n42-test/internal/rpcdiff"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: rpc <conformance|diff> [flags]")
+	fmt.Fprintln(os.Stderr, "  conformance  用合法/非法输入逐个调用 consensusBeaconExt_* 方法，检查响应外形与内容（节点 RPC 层回归用例）")
+	fmt.Fprintln(os.Stderr, "  diff         对两个节点在同一区块区间发出相同的 eth_* 查询（区块、回执、日志），逐字段对比归一化后的响应")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "conformance":
+		err = conformanceCmd(os.Args[2:])
+	case "diff":
+		err = diffCmd(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func conformanceCmd(args []string) error {
+	fs := flag.NewFlagSet("rpc conformance", flag.ExitOnError)
+	rpcURL := fs.String("rpc", "http://127.0.0.1:8545", "执行层 RPC")
+	eth1Hash := fs.String("eth1-hash", "", "合法输入使用的执行层区块哈希；为空取 latest")
+	method := fs.String("method", "", "只测方法名包含该子串的方法（如 get_beacon_state）")
+	timeout := fs.Duration("timeout", 60*time.Second, "单次请求超时（信标状态可能很大）")
+	asJSON := fs.Bool("json", false, "以 JSON 输出报告")
+	envflag.ParseSet(fs, "", args)
+
+	rep, err := conformance.Run(context.Background(), *rpcURL, conformance.Options{
+		Eth1Hash: *eth1Hash,
+		Filter:   *method,
+		Timeout:  *timeout,
+	})
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			return err
+		}
+	} else {
+		rep.Print(os.Stdout)
+	}
+	if n := rep.Failed(); n > 0 {
+		return fmt.Errorf("%d 个用例未通过", n)
+	}
+	return nil
+}
+
+func diffCmd(args []string) error {
+	fs := flag.NewFlagSet("rpc diff", flag.ExitOnError)
+	a := fs.String("a", "http://127.0.0.1:8545", "节点 A 的 RPC（如 N42）")
+	b := fs.String("b", "", "节点 B 的 RPC（如 geth）")
+	from := fs.Int64("from", -1, "起始区块（<0 时为 -to 往前 -blocks 个）")
+	to := fs.Int64("to", -1, "结束区块（含；<0 时取两个节点中较低的最新块高）")
+	blocks := fs.Uint64("blocks", 20, "未给 -from 时对比的区块数")
+	receipts := fs.Bool("receipts", true, "对比回执（eth_getBlockReceipts，不支持时逐笔 eth_getTransactionReceipt）")
+	logs := fs.Bool("logs", true, "对比 eth_getLogs（按块）")
+	ignore := fs.String("ignore", "", "忽略的字段，逗号分隔：字段名（如 totalDifficulty）或去掉下标的路径（如 block.transactions.yParity）")
+	workers := fs.Int("workers", 4, "并发对比的区块数")
+	examples := fs.Int("examples", 20, "打印的差异示例数")
+	asJSON := fs.Bool("json", false, "以 JSON 输出全部差异")
+	envflag.ParseSet(fs, "", args)
+
+	if *b == "" {
+		return fmt.Errorf("需要 -b 指定第二个节点")
+	}
+	ctx := context.Background()
+	end := uint64(*to)
+	if *to < 0 {
+		head, err := rpcdiff.Head(ctx, *a, *b)
+		if err != nil {
+			return err
+		}
+		end = head
+	}
+	start := uint64(0)
+	if *from >= 0 {
+		start = uint64(*from)
+	} else if *blocks > 0 && end+1 > *blocks {
+		start = end + 1 - *blocks
+	}
+	if start > end {
+		return fmt.Errorf("区间为空：%d..%d", start, end)
+	}
+
+	var ign []string
+	if *ignore != "" {
+		ign = strings.Split(*ignore, ",")
+	}
+	rep, err := rpcdiff.Run(ctx, rpcdiff.Options{
+		A: *a, B: *b, From: start, To: end,
+		Receipts: *receipts, Logs: *logs,
+		Ignore: ign, Workers: *workers,
+	})
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			return err
+		}
+	} else {
+		rep.Print(os.Stdout, *examples)
+	}
+	if n := len(rep.Diffs); n > 0 {
+		return fmt.Errorf("%d 处差异", n)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"n42-test/internal/envflag"
+	"n42-test/internal/rundir"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: runs <list|show|clean> [flags]")
+	fmt.Fprintln(os.Stderr, "  list   列出运行目录（run ID、工具、开始时间、耗时、汇总、大小）")
+	fmt.Fprintln(os.Stderr, "  show   显示某次运行的清单与产物文件（run ID 可用唯一前缀）")
+	fmt.Fprintln(os.Stderr, "  clean  删除旧的运行目录")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "list":
+		err = list(os.Args[2:])
+	case "show":
+		err = show(os.Args[2:])
+	case "clean":
+		err = clean(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func rootFlag(fs *flag.FlagSet) *string {
+	return fs.String("root", rundir.DefaultRootDir(), "运行目录根（默认取环境变量 "+rundir.EnvRoot+"，否则 ./runs）")
+}
+
+func list(args []string) error {
+	fs := flag.NewFlagSet("runs list", flag.ExitOnError)
+	root := rootFlag(fs)
+	tool := fs.String("tool", "", "只列出该工具的运行")
+	envflag.ParseSet(fs, "", args)
+
+	runs, err := rundir.List(*root)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN_ID\tTOOL\tSTARTED\tDURATION\tSUMMARY\tSIZE")
+	n := 0
+	for _, r := range runs {
+		if *tool != "" && (r.Manifest == nil || r.Manifest.Tool != *tool) {
+			continue
+		}
+		n++
+		if r.Manifest == nil {
+			fmt.Fprintf(w, "%s\t?\t-\t-\t(no manifest)\t%s\n", r.ID, humanSize(r.Size))
+			continue
+		}
+		mf := r.Manifest
+		dur := "running"
+		if !r.Running() {
+			dur = mf.FinishedAt.Sub(mf.StartedAt).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, mf.Tool,
+			mf.StartedAt.Local().Format("2006-01-02 15:04:05"), dur, summary(mf.Summary), humanSize(r.Size))
+	}
+	w.Flush()
+	fmt.Printf("%d runs under %s\n", n, *root)
+	return nil
+}
+
+func show(args []string) error {
+	fs := flag.NewFlagSet("runs show", flag.ExitOnError)
+	root := rootFlag(fs)
+	envflag.ParseSet(fs, "", args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("用法: runs show [-root dir] <run-id>")
+	}
+
+	r, err := rundir.Find(*root, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("run:      %s\n", r.ID)
+	fmt.Printf("dir:      %s\n", r.Dir)
+	if mf := r.Manifest; mf != nil {
+		fmt.Printf("tool:     %s (%s)\n", mf.Tool, mf.Build.Version)
+		fmt.Printf("started:  %s\n", mf.StartedAt.Local().Format(time.RFC3339))
+		if r.Running() {
+			fmt.Println("finished: - (running or interrupted)")
+		} else {
+			fmt.Printf("finished: %s (%s)\n", mf.FinishedAt.Local().Format(time.RFC3339), mf.FinishedAt.Sub(mf.StartedAt).Round(time.Second))
+		}
+		if mf.ChainID != "" {
+			fmt.Printf("chain:    id=%s genesis=%s rpc=%s\n", mf.ChainID, mf.GenesisHash, mf.RPC)
+		}
+		if mf.InputFile != "" {
+			fmt.Printf("input:    %s (sha256 %s)\n", mf.InputFile, mf.InputSHA256)
+		}
+		fmt.Printf("args:     %s\n", strings.Join(mf.Args, " "))
+		if len(mf.Summary) > 0 {
+			fmt.Printf("summary:  %s\n", summary(mf.Summary))
+		}
+	}
+	fmt.Println("files:")
+	return filepath.Walk(r.Dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(r.Dir, path)
+		fmt.Printf("  %-40s %s\n", rel, humanSize(fi.Size()))
+		return nil
+	})
+}
+
+func clean(args []string) error {
+	fs := flag.NewFlagSet("runs clean", flag.ExitOnError)
+	root := rootFlag(fs)
+	olderThan := fs.Duration("older-than", 0, "删除开始时间早于该时长之前的运行（如 168h）；0 表示不按时间筛选")
+	keep := fs.Int("keep", -1, "每个工具保留最近的 N 次运行，其余删除；<0 表示不按数量筛选")
+	tool := fs.String("tool", "", "只清理该工具的运行")
+	includeRunning := fs.Bool("include-running", false, "也删除没有结束时间的运行（被中断的运行；小心正在跑的活动）")
+	yes := fs.Bool("yes", false, "不询问，直接删除")
+	dryRun := fs.Bool("dry-run", false, "只列出将删除的运行")
+	envflag.ParseSet(fs, "", args)
+
+	if *olderThan <= 0 && *keep < 0 {
+		return fmt.Errorf("需要 --older-than 或 --keep")
+	}
+	runs, err := rundir.List(*root)
+	if err != nil {
+		return err
+	}
+
+	// 按工具分组，组内从新到旧，超出 keep 的才是候选
+	byTool := map[string][]rundir.Info{}
+	for _, r := range runs {
+		t := "?"
+		if r.Manifest != nil {
+			t = r.Manifest.Tool
+		}
+		if *tool != "" && t != *tool {
+			continue
+		}
+		byTool[t] = append(byTool[t], r)
+	}
+	cutoff := time.Now().Add(-*olderThan)
+	var victims []rundir.Info
+	for _, group := range byTool {
+		sort.Slice(group, func(a, b int) bool { return group[b].Before(group[a]) })
+		for i, r := range group {
+			if *keep >= 0 && i < *keep {
+				continue
+			}
+			if r.Running() && !*includeRunning {
+				continue
+			}
+			if *olderThan > 0 && r.Manifest != nil && r.Manifest.StartedAt.After(cutoff) {
+				continue
+			}
+			victims = append(victims, r)
+		}
+	}
+	sort.Slice(victims, func(a, b int) bool { return victims[a].Before(victims[b]) })
+
+	if len(victims) == 0 {
+		fmt.Println("没有需要清理的运行")
+		return nil
+	}
+	var total int64
+	for _, r := range victims {
+		total += r.Size
+		fmt.Printf("  %s  %s\n", r.ID, humanSize(r.Size))
+	}
+	fmt.Printf("共 %d 个运行，%s\n", len(victims), humanSize(total))
+	if *dryRun {
+		return nil
+	}
+	if !*yes && !confirm("删除以上运行目录？") {
+		fmt.Println("已取消")
+		return nil
+	}
+	for _, r := range victims {
+		if err := os.RemoveAll(r.Dir); err != nil {
+			return fmt.Errorf("remove %s: %w", r.Dir, err)
+		}
+	}
+	fmt.Printf("已删除 %d 个运行目录\n", len(victims))
+	return nil
+}
+
+func confirm(prompt string) bool {
+	fmt.Printf("%s [y/N] ", prompt)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	line = strings.ToLower(strings.TrimSpace(line))
+	return line == "y" || line == "yes"
+}
+
+// summary 把清单汇总压成一行 k=v（按键排序）
+func summary(m map[string]any) string {
+	if len(m) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		switch m[k].(type) {
+		case map[string]any, []any:
+			continue // 嵌套结构在 show 里看清单文件
+		}
+		parts = append(parts, fmt.Sprintf("%s=%v", k, m[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/deposit"
+	"n42-test/internal/envflag"
+	"n42-test/internal/manifest"
+	"n42-test/internal/pushgw"
+	"n42-test/internal/rundir"
+	"n42-test/internal/runsummary"
+	"n42-test/internal/units"
+)
+
+// Task 一笔转账
+type Task struct {
+	Index  int
+	To     common.Address
+	Amount units.Amount
+}
+
+type Result struct {
+	Index        int
+	To           common.Address
+	Amount       units.Amount
+	Hash         string
+	Err          error
+	Nonce        uint64
+	UsedGas      uint64
+	EstimatedGas uint64
+	BlockNumber  uint64
+	BlockHash    string
+	GasCostWei   *big.Int
+}
+
+// 交易已打包但执行失败
+var errReverted = errors.New("交易 revert（status=0）")
+
+func main() {
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC")
+	keySpec := flag.String("key", "", "转出账户私钥（hex / keystore / 助记词）；为空取环境变量 PRIVATE_KEY")
+
+	// 单笔
+	toAddr := flag.String("to", "", "单笔转账的接收地址（0x…）；与 --csv 互斥")
+	amountETH := flag.String("amount-eth", "", "单笔转账金额（默认单位 ETH，可带后缀：1.5eth / 1500000000gwei / …wei，不做舍入）。与 --amount-wei 互斥")
+	amountWeiStr := flag.String("amount-wei", "", "单笔转账金额（默认单位 Wei，同样可带后缀）。若设置则覆盖 --amount-eth")
+
+	// 批量
+	csvPath := flag.String("csv", "", "批量转账 CSV：每行 to,amount_eth（表头可选；表头写 amount_wei 时金额按 Wei 解析）")
+	mode := flag.String("mode", "sequential", "发送模式：sequential|concurrent")
+	workers := flag.Int("workers", 8, "并发度，仅在 --mode=concurrent 生效")
+	orderedOut := flag.Bool("ordered-output", true, "并发模式下是否按输入顺序输出结果")
+	start := flag.Int("start", 0, "从第几条（基于0）开始处理")
+	limit := flag.Int("limit", -1, "最多处理多少条；<0 表示全部")
+	dryRun := flag.Bool("dry-run", false, "仅打印将要发送的摘要，不真正上链")
+	noWait := flag.Bool("no-wait", false, "不等待回执，发送后立即返回")
+
+	// 手动费用（留空则自动）
+	gasLimit := flag.Uint64("gas-limit", 0, "GasLimit（0=自动估算）")
+	maxTipGwei := flag.String("max-tip-gwei", "0", "MaxPriorityFeePerGas（默认单位 Gwei，可带后缀，不做舍入；0=自动建议）")
+	maxFeeGwei := flag.String("max-fee-gwei", "0", "MaxFeePerGas（默认单位 Gwei，可带后缀，不做舍入；0=自动建议）")
+
+	manifestPath := flag.String("manifest", "", "运行清单输出路径（JSON；记录版本、完整配置、输入哈希、链 ID/创世哈希）；为空不写")
+	runsDir := flag.String("runs-dir", rundir.DefaultRootDir(), "运行目录根：每次运行在 <根>/<run_id>/ 下保存清单、结果与日志；为空不创建")
+	pushGateway := flag.String("pushgateway", "", "Prometheus Pushgateway 地址（如 http://127.0.0.1:9091）；设置后运行结束推送汇总指标（成功/失败、gas、耗时），按 run_id、network 分组")
+
+	envflag.Parse("transfer")
+
+	if *keySpec == "" {
+		*keySpec = os.Getenv("PRIVATE_KEY")
+	}
+	if *keySpec == "" {
+		log.Fatal("需要 --key 或环境变量 PRIVATE_KEY")
+	}
+
+	// ---------- 构造任务 ----------
+	var tasks []Task
+	switch {
+	case *csvPath != "" && *toAddr != "":
+		log.Fatal("--to 与 --csv 只能二选一")
+	case *csvPath != "":
+		all, err := readCSV(*csvPath)
+		if err != nil {
+			log.Fatalf("读取 CSV 失败: %v", err)
+		}
+		tasks = sliceRange(all, *start, *limit)
+		for i := range tasks {
+			tasks[i].Index = i
+		}
+		log.Printf("共载入 %d 条（start=%d, limit=%d）", len(tasks), *start, *limit)
+	case *toAddr != "":
+		if !common.IsHexAddress(*toAddr) {
+			log.Fatalf("非法接收地址: %s", *toAddr)
+		}
+		amount, err := decideAmount(*amountWeiStr, *amountETH)
+		if err != nil {
+			log.Fatalf("金额参数错误: %v", err)
+		}
+		tasks = []Task{{To: common.HexToAddress(*toAddr), Amount: amount}}
+	default:
+		log.Fatal("需要 --to（单笔）或 --csv（批量）")
+	}
+	if len(tasks) == 0 {
+		log.Println("无可处理条目，退出。")
+		return
+	}
+
+	var mf *manifest.Manifest
+	if *manifestPath != "" || *runsDir != "" || *pushGateway != "" {
+		mf = manifest.ForRun("transfer", *csvPath, *rpcURL)
+	}
+	runDir := rundir.Start(*runsDir, mf)
+
+	// EIP-1559 手动费
+	opt := deposit.TxOptions{Nonce: -1, GasLimit: *gasLimit}
+	opt.MaxPriorityFeePerGas = feeWei("max-tip-gwei", *maxTipGwei)
+	opt.MaxFeePerGas = feeWei("max-fee-gwei", *maxFeeGwei)
+
+	ctx := context.Background()
+	cli, err := deposit.NewClient(ctx, *rpcURL, *keySpec)
+	if err != nil {
+		log.Fatalf("NewClient 失败: %v", err)
+	}
+	defer cli.Close()
+
+	var total units.Amount
+	for _, t := range tasks {
+		total = total.Add(t.Amount)
+	}
+	log.Printf("转出账户 %s，共 %d 笔，合计 %s ETH", cli.From().Hex(), len(tasks), total.ETH())
+	if *noWait {
+		log.Println("⚡ no-wait 模式：发送后不等待回执")
+	}
+
+	s := &sender{cli: cli, nonces: deposit.NewNonceManager(cli), opt: opt, dryRun: *dryRun, noWait: *noWait}
+	var results []Result
+	var sum *runsummary.RunSummary
+	switch strings.ToLower(*mode) {
+	case "sequential":
+		results, sum = runSequential(ctx, s, tasks)
+	case "concurrent":
+		results, sum = runConcurrent(ctx, s, tasks, *workers, *orderedOut)
+	default:
+		log.Fatalf("未知的 --mode：%s（可选 sequential|concurrent）", *mode)
+	}
+	if rep := sum.ErrorReport(); rep != "" {
+		log.Print(rep)
+	}
+
+	if mf != nil {
+		mf.Summary = sum.Fields()
+		mf.Summary["dry_run"], mf.Summary["from"], mf.Summary["total_wei"] = *dryRun, cli.From().Hex(), total.String()
+		if *manifestPath != "" {
+			if err := mf.Write(*manifestPath); err != nil {
+				log.Printf("⚠️ 写运行清单失败: %v", err)
+			} else {
+				log.Printf("📝 运行清单已写入 %s（run_id=%s）", *manifestPath, mf.RunID)
+			}
+		}
+	}
+	if runDir != nil {
+		if err := runDir.WriteJSON(rundir.Results, "results.json", resultRecords(results)); err != nil {
+			log.Printf("⚠️ 写结果失败: %v", err)
+		}
+		if err := runDir.Finish(); err != nil {
+			log.Printf("⚠️ 写运行清单失败: %v", err)
+		} else {
+			log.Printf("📁 运行产物已写入 %s", runDir.Dir)
+		}
+	}
+	if *pushGateway != "" {
+		pctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := pushgw.PushSummary(pctx, *pushGateway, mf, ""); err != nil {
+			log.Printf("⚠️ 推送指标失败: %v", err)
+		} else {
+			log.Printf("📈 指标已推送到 %s（job=%s run_id=%s）", *pushGateway, mf.Tool, mf.RunID)
+		}
+		cancel()
+	}
+	if sum.Fail > 0 {
+		os.Exit(1)
+	}
+}
+
+// ---------------- 任务执行 ----------------
+
+// sender 所有转账共用一个账户，nonce 由 NonceManager 在本地连续分配，
+// 并发发送时不会互相抢同一个 pending nonce
+type sender struct {
+	cli    *deposit.Client
+	nonces *deposit.NonceManager
+	opt    deposit.TxOptions
+	dryRun bool
+	noWait bool
+}
+
+func (s *sender) send(ctx context.Context, t Task) Result {
+	res := Result{Index: t.Index, To: t.To, Amount: t.Amount}
+	if s.dryRun {
+		res.Hash = "(dry-run)"
+		return res
+	}
+
+	ctx2, cancel := context.WithTimeout(ctx, 180*time.Second)
+	defer cancel()
+
+	nonce, err := s.nonces.Next(ctx2)
+	if err != nil {
+		res.Err = fmt.Errorf("index %d: %w", t.Index, err)
+		return res
+	}
+	opt := s.opt
+	opt.Nonce = nonce
+	to := t.To
+	txRes, err := s.cli.SendTx(ctx2, &to, t.Amount.Wei(), nil, opt, !s.noWait)
+	if err != nil {
+		if txRes == nil {
+			// 未发出：该 nonce 空缺，下次重新同步
+			s.nonces.Reset()
+		}
+		res.Err = fmt.Errorf("index %d: SendTx 失败: %w", t.Index, err)
+		if txRes != nil {
+			res.Hash, res.Nonce = txRes.TxHash, txRes.Nonce
+		}
+		return res
+	}
+	res.Hash = txRes.TxHash
+	res.Nonce = txRes.Nonce
+	res.UsedGas = txRes.UsedGas
+	res.EstimatedGas = txRes.EstimatedGas
+	res.BlockNumber = txRes.BlockNumber
+	res.BlockHash = txRes.BlockHash
+	res.GasCostWei = txRes.GasCostWei
+	if !s.noWait && txRes.Status == 0 {
+		res.Err = fmt.Errorf("index %d: tx=%s: %w", t.Index, txRes.TxHash, errReverted)
+	}
+	return res
+}
+
+// resultRecord 写入运行目录 results/results.json 的单条结果
+type resultRecord struct {
+	Index       int    `json:"index"`
+	To          string `json:"to"`
+	AmountWei   string `json:"amount_wei"`
+	TxHash      string `json:"tx_hash,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Nonce       uint64 `json:"nonce,omitempty"`
+	GasUsed     uint64 `json:"gas_used,omitempty"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+	BlockHash   string `json:"block_hash,omitempty"`
+	GasCostWei  string `json:"gas_cost_wei,omitempty"`
+}
+
+func resultRecords(results []Result) []resultRecord {
+	out := make([]resultRecord, len(results))
+	for i, r := range results {
+		rec := resultRecord{
+			Index: r.Index, To: r.To.Hex(), AmountWei: r.Amount.Wei().String(), TxHash: r.Hash,
+			Nonce: r.Nonce, GasUsed: r.UsedGas, BlockNumber: r.BlockNumber, BlockHash: r.BlockHash,
+		}
+		if r.Err != nil {
+			rec.Error = r.Err.Error()
+		}
+		if r.GasCostWei != nil {
+			rec.GasCostWei = r.GasCostWei.String()
+		}
+		out[i] = rec
+	}
+	return out
+}
+
+// Outcome 实现 runsummary.Outcome
+func (r Result) Outcome() (uint64, *big.Int, error) { return r.UsedGas, r.GasCostWei, r.Err }
+
+func runSequential(ctx context.Context, s *sender, tasks []Task) ([]Result, *runsummary.RunSummary) {
+	sum := runsummary.New("sequential", 0)
+	results := make([]Result, 0, len(tasks))
+
+	for _, t := range tasks {
+		res := s.send(ctx, t)
+		printResult(res)
+		results = append(results, res)
+	}
+
+	runsummary.Summarize(sum, results)
+	log.Printf("顺序完成：%s", sum)
+	return results, sum
+}
+
+func runConcurrent(ctx context.Context, s *sender, tasks []Task, workers int, orderedOutput bool) ([]Result, *runsummary.RunSummary) {
+	if workers <= 0 {
+		workers = 4
+	}
+
+	sum := runsummary.New("concurrent", workers)
+	in := make(chan Task)
+	out := make(chan Result)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range in {
+				out <- s.send(ctx, t)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	go func() {
+		for _, t := range tasks {
+			in <- t
+		}
+		close(in)
+	}()
+
+	results := make([]Result, 0, len(tasks))
+	if !orderedOutput {
+		for res := range out {
+			printResult(res)
+			results = append(results, res)
+		}
+	} else {
+		// 按输入顺序输出：用缓冲 map，维护 nextIndex
+		buf := make(map[int]Result, len(tasks))
+		next := 0
+		for res := range out {
+			buf[res.Index] = res
+			for {
+				r, ok := buf[next]
+				if !ok {
+					break
+				}
+				printResult(r)
+				results = append(results, r)
+				delete(buf, next)
+				next++
+			}
+		}
+	}
+
+	runsummary.Summarize(sum, results)
+	log.Printf("并发完成：%s", sum)
+	return results, sum
+}
+
+// ---------------- 工具函数 ----------------
+
+// readCSV 读取 to,amount 两列；第一行不是地址时视为表头，
+// 表头第二列为 amount_wei 时金额默认按 Wei 解析，否则按 ETH（单元格可带单位后缀）
+func readCSV(path string) ([]Task, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	inWei := false
+	var tasks []Task
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("第 %d 行: 需要 to,amount 两列", line)
+		}
+		to, amount := strings.TrimSpace(rec[0]), strings.TrimSpace(rec[1])
+		if line == 1 && !common.IsHexAddress(to) {
+			inWei = strings.EqualFold(amount, "amount_wei")
+			continue
+		}
+		if !common.IsHexAddress(to) {
+			return nil, fmt.Errorf("第 %d 行: 非法地址 %q", line, to)
+		}
+		// 金额列默认单位按表头（amount_wei 为 Wei，否则 ETH），单元格也可带 eth|gwei|wei 后缀
+		unit := units.Ether
+		if inWei {
+			unit = units.Wei
+		}
+		a, err := units.ParsePositive(amount, unit)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", line, err)
+		}
+		tasks = append(tasks, Task{To: common.HexToAddress(to), Amount: a})
+	}
+	if len(tasks) == 0 {
+		return nil, errors.New("CSV 中没有转账条目")
+	}
+	return tasks, nil
+}
+
+func sliceRange[T any](in []T, start, limit int) []T {
+	if start < 0 {
+		start = 0
+	}
+	if start >= len(in) {
+		return []T{}
+	}
+	end := len(in)
+	if limit >= 0 && start+limit < end {
+		end = start + limit
+	}
+	return in[start:end]
+}
+
+func decideAmount(amountWeiStr, amountETH string) (units.Amount, error) {
+	if strings.TrimSpace(amountWeiStr) != "" {
+		return units.ParsePositive(amountWeiStr, units.Wei)
+	}
+	if strings.TrimSpace(amountETH) == "" {
+		return units.Amount{}, fmt.Errorf("需要 --amount-eth 或 --amount-wei（> 0）")
+	}
+	return units.ParsePositive(amountETH, units.Ether)
+}
+
+// feeWei 解析 --<name> 的费用（默认单位 Gwei）为 wei；0 表示未指定，返回 nil
+func feeWei(name, s string) *big.Int {
+	a, err := units.ParseAmount(s, units.Gwei)
+	if err != nil {
+		log.Fatalf("--%s: %v", name, err)
+	}
+	if a.IsZero() {
+		return nil
+	}
+	return a.Wei()
+}
+
+func printResult(r Result) {
+	prefix := fmt.Sprintf("[#%d] to=%s amount=%s ETH", r.Index, r.To.Hex(), r.Amount.ETH())
+	if r.Err != nil {
+		log.Printf("%s ❌ 失败: %v", prefix, r.Err)
+		return
+	}
+	log.Printf("%s ✅ 成功: tx=%s nonce=%d gasUsed=%d estGas=%d block=%d(%s)",
+		prefix, r.Hash, r.Nonce, r.UsedGas, r.EstimatedGas, r.BlockNumber, r.BlockHash)
+}
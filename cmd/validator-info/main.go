@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/envflag"
+	"n42-test/internal/rpcpool"
+	"n42-test/internal/units"
+)
+
+// 按 BLS 公钥查验证者：读取最新信标状态，打印下标、状态、余额、有效余额与提款凭证；
+// -watch 时定期（或给出 -ws 时每个新信标区块）重读，打印状态迁移（pending → active → exiting → withdrawable）。
+
+// statusNotFound 信标状态中还没有该公钥（存款尚未被处理）
+const statusNotFound = "not_found"
+
+// info 一个公钥在某份信标状态中的信息
+type info struct {
+	Pubkey                     string `json:"pubkey"`
+	Found                      bool   `json:"found"`
+	Index                      int    `json:"index"` // 未找到时为 -1
+	Status                     string `json:"status"`
+	BalanceGwei                uint64 `json:"balance_gwei"`
+	EffectiveBalanceGwei       uint64 `json:"effective_balance_gwei"`
+	WithdrawalCredentials      string `json:"withdrawal_credentials,omitempty"`
+	Slashed                    bool   `json:"slashed"`
+	ActivationEligibilityEpoch uint64 `json:"activation_eligibility_epoch,omitempty"`
+	ActivationEpoch            uint64 `json:"activation_epoch,omitempty"`
+	ExitEpoch                  uint64 `json:"exit_epoch,omitempty"`
+	WithdrawableEpoch          uint64 `json:"withdrawable_epoch,omitempty"`
+}
+
+// snapshot 一次查询的结果
+type snapshot struct {
+	Slot       uint64 `json:"slot"`
+	Epoch      uint64 `json:"epoch"`
+	Validators []info `json:"validators"`
+}
+
+func main() {
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC（consensusBeaconExt 查询）")
+	pubkeyFlag := flag.String("pubkey", "", "验证者 BLS 公钥，逗号分隔多个；也可作为位置参数给出")
+	jsonPath := flag.String("json", "", "从 JSON 数组读取公钥（accounts.json 的 validator-public-key 或 deposit_data.json 的 pubkey）")
+	slotsPerEpoch := flag.Uint64("slots-per-epoch", beaconstate.DefaultSlotsPerEpoch, "每纪元 slot 数（按 slot 推算当前纪元与验证者状态）")
+	format := flag.String("format", "text", "输出格式 text|json")
+	watch := flag.Bool("watch", false, "持续监视：每 -interval 重读信标状态，打印状态迁移")
+	interval := flag.Duration("interval", 12*time.Second, "-watch 读取信标状态的间隔")
+	wsURL := flag.String("ws", "", "执行层 WS：-watch 时订阅新信标区块，每个区块读取一次状态（代替 -interval 轮询；订阅结束后回退到轮询）")
+	timeout := flag.Duration("timeout", 0, "-watch 的最长时间（0 不限）")
+	until := flag.String("until", "", "-watch 时全部公钥都到达该状态（或其后的状态）后结束："+strings.Join(beaconstate.Statuses(), "|"))
+	envflag.Parse("validator-info")
+
+	if *format != "text" && *format != "json" {
+		log.Fatalf("未知 -format %q（text|json）", *format)
+	}
+	if *until != "" && !slices.Contains(beaconstate.Statuses(), *until) {
+		log.Fatalf("未知 -until %q（%s）", *until, strings.Join(beaconstate.Statuses(), "|"))
+	}
+	if *watch && *interval <= 0 {
+		log.Fatal("-interval 必须 > 0")
+	}
+	pubkeys, err := collectPubkeys(*pubkeyFlag, *jsonPath, flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *watch && *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	c := beaconext.NewClient(rpcpool.Parse(*rpcURL)[0]).WithWS(*wsURL)
+
+	snap, err := lookup(ctx, c, "", pubkeys, *slotsPerEpoch)
+	if err != nil {
+		log.Fatalf("读取信标状态失败: %v", err)
+	}
+	if err := printSnapshot(snap, *format); err != nil {
+		log.Fatal(err)
+	}
+	if !*watch {
+		return
+	}
+
+	var blocks <-chan beaconext.BeaconBlockEvent
+	if *wsURL != "" {
+		if blocks, err = c.SubscribeBeaconBlocks(ctx); err != nil {
+			log.Printf("⚠️ 订阅新信标区块失败: %v；改为每 %s 轮询", err, *interval)
+		}
+	}
+	if blocks != nil {
+		log.Printf("👀 监视 %d 个验证者，每个新信标区块读取一次状态（Ctrl-C 结束）", len(pubkeys))
+	} else {
+		log.Printf("👀 监视 %d 个验证者，每 %s 读取一次信标状态（Ctrl-C 结束）", len(pubkeys), *interval)
+	}
+	for !reached(snap, *until) {
+		eth1Hash := "" // 轮询时读最新状态
+		select {
+		case <-ctx.Done():
+		case <-time.After(*interval):
+			if blocks != nil {
+				continue
+			}
+		case ev, ok := <-blocks:
+			switch {
+			case !ok:
+				blocks = nil
+				log.Printf("⚠️ 新信标区块订阅已结束，改为每 %s 轮询", *interval)
+				continue
+			case ev.Err != nil:
+				log.Printf("⚠️ %v", ev.Err)
+				continue
+			case ev.Slot != 0 && ev.Slot <= snap.Slot:
+				continue
+			}
+			eth1Hash = ev.Eth1Hash
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		next, err := lookup(ctx, c, eth1Hash, pubkeys, *slotsPerEpoch)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("⚠️ 读取信标状态失败: %v", err)
+			}
+			continue
+		}
+		if next.Slot == snap.Slot {
+			continue
+		}
+		for _, t := range transitions(snap, next) {
+			printTransition(t, *format)
+		}
+		snap = next
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		log.Printf("⏱️ 已到 -timeout（%s），停止监视", *timeout)
+		if *until != "" {
+			os.Exit(1)
+		}
+	case ctx.Err() == nil:
+		log.Printf("✅ %d 个验证者都已到达 %s", len(pubkeys), *until)
+	}
+}
+
+// collectPubkeys 合并 -pubkey、-json 与位置参数中的公钥，规范化并去重（保持先后顺序）
+func collectPubkeys(flagList, jsonPath string, args []string) ([]string, error) {
+	var raw []string
+	raw = append(raw, strings.Split(flagList, ",")...)
+	for _, a := range args {
+		raw = append(raw, strings.Split(a, ",")...)
+	}
+	if jsonPath != "" {
+		fromFile, err := readPubkeys(jsonPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %w", jsonPath, err)
+		}
+		raw = append(raw, fromFile...)
+	}
+	var out []string
+	seen := map[string]bool{}
+	for _, s := range raw {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		pk := beaconstate.NormPubkey(s)
+		if len(pk) != 2+96 {
+			return nil, fmt.Errorf("%q 不是 48 字节的 BLS 公钥", s)
+		}
+		if !seen[pk] {
+			seen[pk] = true
+			out = append(out, pk)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("需要至少一个公钥（-pubkey、-json 或位置参数）")
+	}
+	return out, nil
+}
+
+// readPubkeys 读取 JSON 数组中每条的 validator-public-key（accounts.json）或 pubkey（deposit_data.json）
+func readPubkeys(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var items []struct {
+		ValidatorPublicKey string `json:"validator-public-key"`
+		Pubkey             string `json:"pubkey"`
+	}
+	if err := json.Unmarshal(b, &items); err != nil {
+		return nil, fmt.Errorf("parse json: %w", err)
+	}
+	out := make([]string, 0, len(items))
+	for i, it := range items {
+		pk := it.ValidatorPublicKey
+		if pk == "" {
+			pk = it.Pubkey
+		}
+		if pk == "" {
+			return nil, fmt.Errorf("item %d: missing validator-public-key / pubkey", i)
+		}
+		out = append(out, pk)
+	}
+	return out, nil
+}
+
+// lookup 读取执行层区块 eth1Hash（为空时取最新区块）对应的信标状态并按公钥查出各验证者
+func lookup(ctx context.Context, r beaconext.BeaconReader, eth1Hash string, pubkeys []string, slotsPerEpoch uint64) (*snapshot, error) {
+	var st *beaconstate.State
+	var err error
+	if eth1Hash == "" {
+		st, err = beaconstate.FetchLatest(ctx, r)
+	} else {
+		st, err = beaconstate.FetchAt(ctx, r, eth1Hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	epoch := st.Epoch(slotsPerEpoch)
+	index := st.Index()
+	snap := &snapshot{Slot: st.Slot, Epoch: epoch, Validators: make([]info, len(pubkeys))}
+	for i, pk := range pubkeys {
+		vi, ok := index[pk]
+		if !ok {
+			snap.Validators[i] = info{Pubkey: pk, Index: -1, Status: statusNotFound}
+			continue
+		}
+		v := st.Validators[vi]
+		bal := st.Balance(vi)
+		snap.Validators[i] = info{
+			Pubkey: pk, Found: true, Index: vi,
+			Status:                     v.Status(epoch, bal),
+			BalanceGwei:                bal,
+			EffectiveBalanceGwei:       v.EffectiveBalance,
+			WithdrawalCredentials:      v.WithdrawalCredentials,
+			Slashed:                    v.Slashed,
+			ActivationEligibilityEpoch: v.ActivationEligibilityEpoch,
+			ActivationEpoch:            v.ActivationEpoch,
+			ExitEpoch:                  v.ExitEpoch,
+			WithdrawableEpoch:          v.WithdrawableEpoch,
+		}
+	}
+	return snap, nil
+}
+
+func printSnapshot(s *snapshot, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+	fmt.Printf("slot %d（epoch %d）\n", s.Slot, s.Epoch)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PUBKEY\tINDEX\tSTATUS\tBALANCE(ETH)\tEFFECTIVE(ETH)\tACTIVATION\tEXIT\tWITHDRAWABLE\tCREDENTIALS")
+	for _, v := range s.Validators {
+		if !v.Found {
+			fmt.Fprintf(tw, "%s\t-\t%s\t-\t-\t-\t-\t-\t-\n", v.Pubkey, v.Status)
+			continue
+		}
+		status := v.Status
+		if v.Slashed && !strings.Contains(status, "slashed") {
+			status += "(slashed)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", v.Pubkey, v.Index, status,
+			units.FromGwei(v.BalanceGwei).ETH(), units.FromGwei(v.EffectiveBalanceGwei).ETH(),
+			epochString(v.ActivationEpoch), epochString(v.ExitEpoch), epochString(v.WithdrawableEpoch), v.WithdrawalCredentials)
+	}
+	return tw.Flush()
+}
+
+// transition 一个验证者在两次查询之间的状态变化
+type transition struct {
+	Slot   uint64 `json:"slot"`
+	Epoch  uint64 `json:"epoch"`
+	Pubkey string `json:"pubkey"`
+	Index  int    `json:"index"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	// 迁移后的余额（gwei），余额变化也会在状态迁移行里一并显示
+	BalanceGwei uint64 `json:"balance_gwei"`
+}
+
+// transitions prev → next 之间状态发生变化的验证者（按公钥顺序）
+func transitions(prev, next *snapshot) []transition {
+	var out []transition
+	for i, v := range next.Validators {
+		from := prev.Validators[i].Status
+		if from == v.Status {
+			continue
+		}
+		out = append(out, transition{
+			Slot: next.Slot, Epoch: next.Epoch, Pubkey: v.Pubkey, Index: v.Index,
+			From: from, To: v.Status, BalanceGwei: v.BalanceGwei,
+		})
+	}
+	return out
+}
+
+func printTransition(t transition, format string) {
+	if format == "json" {
+		b, _ := json.Marshal(t)
+		fmt.Println(string(b))
+		return
+	}
+	log.Printf("🔄 slot %d（epoch %d）#%d %s：%s → %s，余额 %s ETH",
+		t.Slot, t.Epoch, t.Index, t.Pubkey, t.From, t.To, units.FromGwei(t.BalanceGwei).ETH())
+}
+
+// reached 全部验证者是否都已到达 status（按 beaconstate.Statuses 的生命周期先后比较）；status 为空时始终为 false
+func reached(s *snapshot, status string) bool {
+	if status == "" {
+		return false
+	}
+	order := beaconstate.Statuses()
+	want := slices.Index(order, status)
+	for _, v := range s.Validators {
+		if slices.Index(order, v.Status) < want {
+			return false
+		}
+	}
+	return true
+}
+
+// epochString FarFutureEpoch 显示为 "-"
+func epochString(e uint64) string {
+	if e == beaconstate.FarFutureEpoch {
+		return "-"
+	}
+	return strconv.FormatUint(e, 10)
+}
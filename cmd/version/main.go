@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"n42-test/internal/buildinfo"
+	"n42-test/internal/capability"
+	"n42-test/internal/envflag"
+)
+
+func main() {
+	probe := flag.Bool("probe", false, "对节点做兼容性探测并缓存能力矩阵")
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC（仅 --probe 时使用）")
+	cachePath := flag.String("cache", capability.DefaultCachePath(), "能力矩阵缓存文件")
+	envflag.Parse("version")
+
+	bi := buildinfo.Get()
+	dirty := ""
+	if bi.Dirty {
+		dirty = " (dirty)"
+	}
+	fmt.Printf("n42-test %s\n", bi.Version)
+	fmt.Printf("  commit: %s%s\n", bi.ShortCommit(), dirty)
+	if bi.Date != "" {
+		fmt.Printf("  built:  %s\n", bi.Date)
+	}
+	fmt.Printf("  go:     %s\n", bi.GoVersion)
+
+	if !*probe {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	m, err := capability.Probe(ctx, *rpcURL)
+	if err != nil {
+		log.Fatalf("探测失败: %v", err)
+	}
+
+	fmt.Printf("\n节点 %s\n", m.Endpoint)
+	fmt.Printf("  client:   %s\n", m.ClientVersion)
+	fmt.Printf("  chain id: %s\n", m.ChainID)
+	fmt.Printf("  %-58s %s\n", "EIP-1559", mark(m.EIP1559))
+	fmt.Printf("  %-58s %s\n", "eth_getBlockReceipts", mark(m.BlockReceipts))
+	fmt.Printf("  %-58s %s\n", "JSON-RPC batch", mark(m.Batch))
+	fmt.Printf("  %-58s %s\n", "txpool API", mark(m.TxPool))
+	fmt.Printf("  %-58s %s\n", "WS subscriptions (eth_subscribe, 仅 ws://)", mark(m.WSSubscriptions))
+	for _, method := range capability.BeaconExtMethods {
+		fmt.Printf("  %-58s %s\n", method, mark(m.Supports(method)))
+	}
+
+	if err := capability.Save(*cachePath, m); err != nil {
+		log.Printf("⚠️ 写能力缓存失败: %v", err)
+		return
+	}
+	fmt.Printf("\n能力矩阵已缓存到 %s\n", *cachePath)
+}
+
+func mark(ok bool) string {
+	if ok {
+		return "✅"
+	}
+	return "❌"
+}
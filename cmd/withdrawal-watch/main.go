@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethhex "github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/envflag"
+	"n42-test/internal/keys"
+	"n42-test/internal/resultout"
+	"n42-test/internal/rpcpool"
+	"n42-test/internal/units"
+)
+
+// 提款清扫监视：逐块读取执行层区块的 withdrawals，挑出记入 accounts.json 提款地址的条目，
+// 按验证者下标对回公钥，报告每次清扫的金额与区块。退出测试的最后一步：余额确实到了提款地址。
+
+// accountItem 与 keygen 生成的 accounts.json 条目同形（只用到提款地址与验证者公钥）
+type accountItem struct {
+	WithdrawalPrivateKey string `json:"withdrawal-private-key"` // withdrawal-address 为空时用于推导地址
+	ValidatorPublicKey   string `json:"validator-public-key"`
+	WithdrawalAddress    string `json:"withdrawal-address"`
+}
+
+// sweep 一次记入关注地址的提款
+type sweep struct {
+	Block           uint64    `json:"block"`
+	BlockTime       time.Time `json:"block_time"`
+	WithdrawalIndex uint64    `json:"withdrawal_index"`
+	ValidatorIndex  uint64    `json:"validator_index"`
+	Address         string    `json:"address"`
+	AmountGwei      uint64    `json:"amount_gwei"`
+	AmountETH       string    `json:"amount_eth"`
+	Pubkey          string    `json:"pubkey,omitempty"`     // 对回的验证者公钥（对不上时为空）
+	Listed          bool      `json:"listed"`               // 公钥在 accounts.json 中
+	Candidates      []string  `json:"candidates,omitempty"` // 没有信标状态时，该地址下 accounts.json 中的全部公钥
+}
+
+// rpcBlock eth_getBlockByNumber（不含交易体）中用到的字段；直接解码 withdrawals，不依赖交易类型
+type rpcBlock struct {
+	Number      gethhex.Uint64      `json:"number"`
+	Timestamp   gethhex.Uint64      `json:"timestamp"`
+	Withdrawals []*types.Withdrawal `json:"withdrawals"`
+}
+
+// watcher 关注的地址与公钥，以及按下标查公钥的信标状态缓存
+type watcher struct {
+	byAddress map[common.Address][]string // 提款地址 -> accounts.json 中的公钥
+	listed    map[string]bool             // accounts.json 中的全部公钥
+	beacon    beaconext.BeaconReader      // 为 nil 时不查信标状态
+	pubkeys   []string                    // 信标状态中按下标排列的公钥
+	refreshed uint64                      // 上次刷新信标状态时的区块，同一区块内不重复刷新
+}
+
+func main() {
+	jsonPath := flag.String("json", "accounts.json", "accounts.json 路径（提款地址 withdrawal-address，为空时由 withdrawal-private-key 推导）")
+	rpcURL := flag.String("rpc", "http://127.0.0.1:8545", "执行层 RPC")
+	fromBlock := flag.Uint64("from-block", 0, "起始区块（0 表示从当前最新区块开始）")
+	toBlock := flag.Uint64("to-block", 0, "结束区块（含）；0 表示持续跟随新区块，直到 -timeout、-until-all 或 Ctrl-C")
+	poll := flag.Duration("poll", 2*time.Second, "跟随模式下检查新区块的间隔")
+	timeout := flag.Duration("timeout", 0, "跟随模式的最长时间（0 不限）")
+	untilAll := flag.Bool("until-all", false, "跟随模式下 accounts.json 中每个验证者都至少被清扫一次后结束（需要信标状态对回公钥）")
+	useBeacon := flag.Bool("beacon", true, "读取信标状态，按 withdrawals 中的验证者下标对回公钥；关闭时只按地址列出候选公钥")
+	outputPath := flag.String("output", "", "逐条清扫记录输出文件；为空不写")
+	outputFormat := flag.String("output-format", "", "-output 的格式 json|csv|ndjson（为空按扩展名，默认 json）")
+	envflag.Parse("withdrawal-watch")
+
+	if *toBlock > 0 && *fromBlock > *toBlock {
+		log.Fatalf("-from-block %d 大于 -to-block %d", *fromBlock, *toBlock)
+	}
+	if *untilAll && !*useBeacon {
+		log.Fatal("-until-all 需要 -beacon（按公钥判断是否已清扫）")
+	}
+	outFormat, err := resultout.ResolveFormat(*outputPath, *outputFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w, err := loadAccounts(*jsonPath)
+	if err != nil {
+		log.Fatalf("读取 %s 失败: %v", *jsonPath, err)
+	}
+	log.Printf("👀 关注 %d 个提款地址、%d 个验证者公钥", len(w.byAddress), len(w.listed))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *toBlock == 0 && *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	cli, err := rpcpool.DialEth(ctx, *rpcURL)
+	if err != nil {
+		log.Fatalf("连接 RPC 失败: %v", err)
+	}
+	defer cli.Close()
+
+	if *useBeacon {
+		w.beacon = beaconext.NewClient(rpcpool.Parse(*rpcURL)[0])
+		if err := w.refresh(ctx, 0); err != nil {
+			log.Fatalf("读取信标状态失败: %v（可加 -beacon=false 只按地址匹配）", err)
+		}
+	}
+
+	next := *fromBlock
+	if next == 0 {
+		if next, err = cli.BlockNumber(ctx); err != nil {
+			log.Fatalf("读取最新区块失败: %v", err)
+		}
+	}
+	if *toBlock > 0 {
+		log.Printf("🔎 扫描区块 %d..%d", next, *toBlock)
+	} else {
+		log.Printf("🔎 从区块 %d 开始跟随新区块（每 %s 检查一次）", next, *poll)
+	}
+
+	var sweeps []sweep
+	swept := map[string]bool{}
+	done := false
+	for !done {
+		head, err := cli.BlockNumber(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("⚠️ 读取最新区块失败: %v", err)
+			head = next - 1
+		}
+		if *toBlock > 0 && head > *toBlock {
+			head = *toBlock
+		}
+		for ; next <= head && ctx.Err() == nil; next++ {
+			got, err := w.scan(ctx, cli, next)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("⚠️ 读取区块 %d 失败: %v", next, err)
+				}
+				break
+			}
+			for _, s := range got {
+				printSweep(s)
+				if s.Listed {
+					swept[s.Pubkey] = true
+				}
+			}
+			sweeps = append(sweeps, got...)
+			if *untilAll && len(swept) == len(w.listed) {
+				log.Printf("✅ accounts.json 中的 %d 个验证者都已被清扫", len(w.listed))
+				done = true
+				break
+			}
+		}
+		if done || ctx.Err() != nil || (*toBlock > 0 && next > *toBlock) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(*poll):
+		}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("⏱️ 已到 -timeout（%s），停止跟随", *timeout)
+	}
+
+	printSummary(w, sweeps, next-1)
+	if *outputPath != "" {
+		if err := resultout.Write(*outputPath, outFormat, sweeps); err != nil {
+			log.Fatalf("写结果文件失败: %v", err)
+		}
+		log.Printf("📝 清扫记录已写入 %s", *outputPath)
+	}
+	if *untilAll && len(swept) < len(w.listed) {
+		os.Exit(1)
+	}
+}
+
+// loadAccounts 读取 accounts.json，按提款地址分组公钥
+func loadAccounts(path string) (*watcher, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var items []accountItem
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("parse json: %w", err)
+	}
+	w := &watcher{byAddress: map[common.Address][]string{}, listed: map[string]bool{}}
+	for i, it := range items {
+		var addr common.Address
+		switch {
+		case it.WithdrawalAddress != "":
+			if !common.IsHexAddress(it.WithdrawalAddress) {
+				return nil, fmt.Errorf("item %d: invalid withdrawal-address %q", i, it.WithdrawalAddress)
+			}
+			addr = common.HexToAddress(it.WithdrawalAddress)
+		case it.WithdrawalPrivateKey != "":
+			if addr, err = keys.Address(it.WithdrawalPrivateKey); err != nil {
+				return nil, fmt.Errorf("item %d: withdrawal-private-key: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("item %d: missing withdrawal-address", i)
+		}
+		if it.ValidatorPublicKey == "" {
+			return nil, fmt.Errorf("item %d: missing validator-public-key", i)
+		}
+		pk := beaconstate.NormPubkey(it.ValidatorPublicKey)
+		w.byAddress[addr] = append(w.byAddress[addr], pk)
+		w.listed[pk] = true
+	}
+	if len(w.byAddress) == 0 {
+		return nil, errors.New("no accounts")
+	}
+	return w, nil
+}
+
+// refresh 重新读取信标状态中的公钥列表；block 为触发刷新的区块
+func (w *watcher) refresh(ctx context.Context, block uint64) error {
+	st, err := beaconstate.FetchLatest(ctx, w.beacon)
+	if err != nil {
+		return err
+	}
+	w.pubkeys = make([]string, len(st.Validators))
+	for i, v := range st.Validators {
+		w.pubkeys[i] = beaconstate.NormPubkey(v.Pubkey)
+	}
+	w.refreshed = block
+	return nil
+}
+
+// pubkeyOf 验证者下标对应的公钥；下标超出缓存时（新激活的验证者）刷新一次信标状态
+func (w *watcher) pubkeyOf(ctx context.Context, index, block uint64) string {
+	if w.beacon == nil {
+		return ""
+	}
+	if index >= uint64(len(w.pubkeys)) && w.refreshed != block {
+		if err := w.refresh(ctx, block); err != nil {
+			log.Printf("⚠️ 刷新信标状态失败: %v", err)
+			w.refreshed = block
+		}
+	}
+	if index < uint64(len(w.pubkeys)) {
+		return w.pubkeys[index]
+	}
+	return ""
+}
+
+// scan 读取一个区块，返回其中记入关注地址的提款
+func (w *watcher) scan(ctx context.Context, cli *ethclient.Client, number uint64) ([]sweep, error) {
+	var b *rpcBlock
+	if err := cli.Client().CallContext(ctx, &b, "eth_getBlockByNumber", gethhex.EncodeUint64(number), false); err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	var out []sweep
+	for _, wd := range b.Withdrawals {
+		cands, ok := w.byAddress[wd.Address]
+		if !ok {
+			continue
+		}
+		s := sweep{
+			Block:           number,
+			BlockTime:       time.Unix(int64(b.Timestamp), 0),
+			WithdrawalIndex: wd.Index,
+			ValidatorIndex:  wd.Validator,
+			Address:         wd.Address.Hex(),
+			AmountGwei:      wd.Amount,
+			AmountETH:       units.FromGwei(wd.Amount).ETH(),
+		}
+		if pk := w.pubkeyOf(ctx, wd.Validator, number); pk != "" {
+			s.Pubkey, s.Listed = pk, w.listed[pk]
+		} else if len(cands) == 1 && w.beacon == nil {
+			s.Pubkey, s.Listed = cands[0], w.listed[cands[0]]
+		} else {
+			s.Candidates = cands
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// printSweep 一次清扫一行
+func printSweep(s sweep) {
+	who := s.Pubkey
+	switch {
+	case who == "":
+		who = fmt.Sprintf("未对上公钥（候选 %d 个）", len(s.Candidates))
+	case !s.Listed:
+		who += "（不在 accounts.json 中）"
+	}
+	log.Printf("💸 区块 %d withdrawal #%d validator_index=%d → %s %s ETH，%s",
+		s.Block, s.WithdrawalIndex, s.ValidatorIndex, s.Address, s.AmountETH, who)
+}
+
+// printSummary 按验证者汇总清扫次数与金额，并列出尚未被清扫的验证者
+func printSummary(w *watcher, sweeps []sweep, last uint64) {
+	type total struct {
+		count  int
+		amount units.Amount
+	}
+	byPubkey := map[string]*total{}
+	unmatched := 0
+	for _, s := range sweeps {
+		if s.Pubkey == "" {
+			unmatched++
+			continue
+		}
+		t, ok := byPubkey[s.Pubkey]
+		if !ok {
+			t = &total{}
+			byPubkey[s.Pubkey] = t
+		}
+		t.count++
+		t.amount = t.amount.Add(units.FromGwei(s.AmountGwei))
+	}
+	log.Printf("📊 扫描至区块 %d：清扫 %d 次，涉及 %d 个验证者，未对上公钥 %d 次", last, len(sweeps), len(byPubkey), unmatched)
+	pks := make([]string, 0, len(byPubkey))
+	for pk := range byPubkey {
+		pks = append(pks, pk)
+	}
+	sort.Strings(pks)
+	for _, pk := range pks {
+		t := byPubkey[pk]
+		log.Printf("   %s  %d 次，合计 %s ETH", pk, t.count, t.amount.ETH())
+	}
+	var pending []string
+	for pk := range w.listed {
+		if _, ok := byPubkey[pk]; !ok {
+			pending = append(pending, pk)
+		}
+	}
+	if len(pending) > 0 && w.beacon != nil {
+		sort.Strings(pending)
+		log.Printf("⏳ %d 个验证者尚未被清扫：%s", len(pending), strings.Join(pending, ", "))
+	}
+}
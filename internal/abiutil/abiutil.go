@@ -0,0 +1,235 @@
+// 命令行与合约 ABI 之间的转换：读取 ABI 文件、把字符串参数按方法签名转成 Go 值、
+// 把返回值与事件格式化成可读文本。供通用的 contract call/send 使用。
+package abiutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"n42-test/internal/hexutil"
+)
+
+// Load 读取 ABI：纯 ABI 数组，或带 "abi" 字段的 hardhat / foundry 编译产物
+func Load(path string) (abi.ABI, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return abi.ABI{}, err
+	}
+	raw := json.RawMessage(b)
+	var artifact struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(b)), "{") {
+		if err := json.Unmarshal(b, &artifact); err != nil {
+			return abi.ABI{}, fmt.Errorf("%s: %w", path, err)
+		}
+		if len(artifact.ABI) == 0 {
+			return abi.ABI{}, fmt.Errorf("%s: no \"abi\" field", path)
+		}
+		raw = artifact.ABI
+	}
+	a, err := abi.JSON(strings.NewReader(string(raw)))
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("%s: parse abi: %w", path, err)
+	}
+	return a, nil
+}
+
+// Method 按名字或完整签名（如 "transfer(address,uint256)"，用于区分重载）查找方法
+func Method(a abi.ABI, name string) (abi.Method, error) {
+	if m, ok := a.Methods[name]; ok {
+		return m, nil
+	}
+	for _, m := range a.Methods {
+		if m.Sig == name {
+			return m, nil
+		}
+	}
+	names := make([]string, 0, len(a.Methods))
+	for _, m := range a.Methods {
+		names = append(names, m.Sig)
+	}
+	return abi.Method{}, fmt.Errorf("method %q not in abi (have: %s)", name, strings.Join(names, ", "))
+}
+
+// Pack 把字符串参数按方法签名转换后打包成 calldata
+func Pack(m abi.Method, args []string) ([]byte, error) {
+	if len(args) != len(m.Inputs) {
+		return nil, fmt.Errorf("%s needs %d args, got %d", m.Sig, len(m.Inputs), len(args))
+	}
+	vals := make([]any, len(args))
+	for i, in := range m.Inputs {
+		v, err := ParseArg(in.Type, args[i])
+		if err != nil {
+			return nil, fmt.Errorf("arg %d (%s %s): %w", i, in.Type, in.Name, err)
+		}
+		vals[i] = v
+	}
+	packed, err := m.Inputs.Pack(vals...)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, m.ID...), packed...), nil
+}
+
+// ParseArg 把一个字符串参数转换成 t 对应的 Go 值：
+// 整数支持十进制与 0x 十六进制，bytes 为十六进制，数组/切片为 JSON 数组（元素同样按字符串规则解析）
+func ParseArg(t abi.Type, s string) (any, error) {
+	s = strings.TrimSpace(s)
+	switch t.T {
+	case abi.AddressTy:
+		if !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+		return common.HexToAddress(s), nil
+	case abi.BoolTy:
+		return strconv.ParseBool(s)
+	case abi.StringTy:
+		return s, nil
+	case abi.BytesTy:
+		if hexutil.Trim(s) == "" {
+			return []byte{}, nil
+		}
+		return hexutil.Decode(s)
+	case abi.FixedBytesTy:
+		b, err := hexutil.DecodeFixed(s, t.Size)
+		if err != nil {
+			return nil, err
+		}
+		v := reflect.New(t.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v.Interface(), nil
+	case abi.IntTy, abi.UintTy:
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		if t.T == abi.UintTy && n.Sign() < 0 {
+			return nil, fmt.Errorf("negative value for %s", t)
+		}
+		if t.Size > 64 {
+			return n, nil
+		}
+		if t.T == abi.UintTy {
+			if !n.IsUint64() || n.BitLen() > t.Size {
+				return nil, fmt.Errorf("%s out of range for %s", s, t)
+			}
+			return reflect.ValueOf(n.Uint64()).Convert(t.GetType()).Interface(), nil
+		}
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("%s out of range for %s", s, t)
+		}
+		return reflect.ValueOf(n.Int64()).Convert(t.GetType()).Interface(), nil
+	case abi.SliceTy, abi.ArrayTy:
+		var items []json.RawMessage
+		if err := json.Unmarshal([]byte(s), &items); err != nil {
+			return nil, fmt.Errorf("want JSON array for %s: %w", t, err)
+		}
+		if t.T == abi.ArrayTy && len(items) != t.Size {
+			return nil, fmt.Errorf("%s needs %d elements, got %d", t, t.Size, len(items))
+		}
+		var out reflect.Value
+		if t.T == abi.ArrayTy {
+			out = reflect.New(t.GetType()).Elem()
+		} else {
+			out = reflect.MakeSlice(t.GetType(), len(items), len(items))
+		}
+		for i, item := range items {
+			// 元素可以是 JSON 字符串或裸值（数字、布尔、嵌套数组）
+			var str string
+			if err := json.Unmarshal(item, &str); err != nil {
+				str = string(item)
+			}
+			v, err := ParseArg(*t.Elem, str)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			out.Index(i).Set(reflect.ValueOf(v))
+		}
+		return out.Interface(), nil
+	default:
+		return nil, fmt.Errorf("unsupported argument type %s", t)
+	}
+}
+
+// Format 把解码出的值格式化为可读文本：地址/哈希/bytes 为 0x 十六进制，其余按 JSON
+func Format(v any) string {
+	switch x := v.(type) {
+	case common.Address:
+		return x.Hex()
+	case common.Hash:
+		return x.Hex()
+	case []byte:
+		return hexutil.Encode(x)
+	case *big.Int:
+		return x.String()
+	case string:
+		return strconv.Quote(x)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return hexutil.Encode(b)
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// Outputs 解码方法返回值，返回 "名字=值" 列表（未命名的输出用下标）
+func Outputs(m abi.Method, data []byte) ([]string, error) {
+	vals, err := m.Outputs.Unpack(data)
+	if err != nil {
+		return nil, fmt.Errorf("unpack %s outputs: %w", m.Sig, err)
+	}
+	out := make([]string, len(vals))
+	for i, v := range vals {
+		name := m.Outputs[i].Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		out[i] = name + "=" + Format(v)
+	}
+	return out, nil
+}
+
+// Event 按 ABI 解码一条日志；不认识的日志返回 ok=false
+func Event(a abi.ABI, l *gethtypes.Log) (text string, ok bool) {
+	if len(l.Topics) == 0 {
+		return "", false
+	}
+	ev, err := a.EventByID(l.Topics[0])
+	if err != nil {
+		return "", false
+	}
+	fields := map[string]any{}
+	if err := ev.Inputs.UnpackIntoMap(fields, l.Data); err != nil {
+		return "", false
+	}
+	var indexed abi.Arguments
+	for _, in := range ev.Inputs {
+		if in.Indexed {
+			indexed = append(indexed, in)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(fields, indexed, l.Topics[1:]); err != nil {
+		return "", false
+	}
+	parts := make([]string, 0, len(ev.Inputs))
+	for _, in := range ev.Inputs {
+		parts = append(parts, in.Name+"="+Format(fields[in.Name]))
+	}
+	return ev.Name + "(" + strings.Join(parts, ", ") + ")", true
+}
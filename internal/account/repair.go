@@ -0,0 +1,238 @@
+// EOA 状态检查与修复：中断的批量任务常留下 nonce 空洞（后面的交易永远排不上）
+// 或费用过低卡在交易池里的交易；这里找出它们，并用空交易填洞或提价替换。
+package account
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/capability"
+)
+
+// 替换交易至少提价的比例（geth 默认要求 10%，这里留一点余量）
+const bumpPercent = 125
+
+// 空交易（自转账 0 wei）的 gas
+const noopGas = 21_000
+
+// PoolTx 交易池中本账户的一笔交易（txpool_content 的元素）
+type PoolTx struct {
+	Hash                 common.Hash     `json:"hash"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	To                   *common.Address `json:"to"`
+	Value                *hexutil.Big    `json:"value"`
+	Input                hexutil.Bytes   `json:"input"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+
+	Queued bool `json:"-"` // 在 queued（nonce 不连续）而非 pending 中
+	Stuck  bool `json:"-"` // 费用上限低于当前 baseFee 或小费低于建议值
+}
+
+// feeCap 交易的费用上限（legacy 交易为 gasPrice）
+func (t *PoolTx) feeCap() *big.Int {
+	if t.MaxFeePerGas != nil {
+		return t.MaxFeePerGas.ToInt()
+	}
+	if t.GasPrice != nil {
+		return t.GasPrice.ToInt()
+	}
+	return new(big.Int)
+}
+
+func (t *PoolTx) tipCap() *big.Int {
+	if t.MaxPriorityFeePerGas != nil {
+		return t.MaxPriorityFeePerGas.ToInt()
+	}
+	return t.feeCap()
+}
+
+// State 账户当前状态
+type State struct {
+	Address      common.Address
+	LatestNonce  uint64 // 已上链交易数
+	PendingNonce uint64 // 含交易池中连续可执行的交易
+	Balance      *big.Int
+	BaseFee      *big.Int
+	SuggestedTip *big.Int
+
+	// 交易池可见时才有以下字段
+	PoolVisible bool
+	Txs         []*PoolTx // 按 nonce 排序
+	Gaps        []uint64  // 空洞 nonce：比已有交易小但池中不存在
+}
+
+// Stuck 卡住的交易
+func (s *State) Stuck() []*PoolTx {
+	var out []*PoolTx
+	for _, t := range s.Txs {
+		if t.Stuck {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Inspect 读取账户的 latest/pending nonce，并在节点支持 txpool API 时列出池中交易、空洞与卡住的交易
+func Inspect(ctx context.Context, cli *ethclient.Client, caps *capability.Matrix, addr common.Address) (*State, error) {
+	s := &State{Address: addr}
+	var err error
+	if s.LatestNonce, err = cli.NonceAt(ctx, addr, nil); err != nil {
+		return nil, fmt.Errorf("get latest nonce: %w", err)
+	}
+	if s.PendingNonce, err = cli.PendingNonceAt(ctx, addr); err != nil {
+		return nil, fmt.Errorf("get pending nonce: %w", err)
+	}
+	if s.Balance, err = cli.BalanceAt(ctx, addr, nil); err != nil {
+		return nil, fmt.Errorf("get balance: %w", err)
+	}
+	if h, err := cli.HeaderByNumber(ctx, nil); err == nil && h.BaseFee != nil {
+		s.BaseFee = h.BaseFee
+	}
+	if tip, err := cli.SuggestGasTipCap(ctx); err == nil {
+		s.SuggestedTip = tip
+	}
+	if !caps.HasTxPool() {
+		return s, nil
+	}
+
+	var content struct {
+		Pending map[common.Address]map[string]*PoolTx `json:"pending"`
+		Queued  map[common.Address]map[string]*PoolTx `json:"queued"`
+	}
+	if err := cli.Client().CallContext(ctx, &content, "txpool_content"); err != nil {
+		return s, nil
+	}
+	s.PoolVisible = true
+	for _, t := range content.Pending[addr] {
+		s.Txs = append(s.Txs, t)
+	}
+	for _, t := range content.Queued[addr] {
+		t.Queued = true
+		s.Txs = append(s.Txs, t)
+	}
+	sort.Slice(s.Txs, func(i, j int) bool { return s.Txs[i].Nonce < s.Txs[j].Nonce })
+
+	have := map[uint64]bool{}
+	var maxNonce uint64
+	for _, t := range s.Txs {
+		n := uint64(t.Nonce)
+		have[n] = true
+		maxNonce = max(maxNonce, n)
+		if !t.Queued && s.BaseFee != nil && t.feeCap().Cmp(s.BaseFee) < 0 {
+			t.Stuck = true
+		}
+		if !t.Queued && s.SuggestedTip != nil && t.tipCap().Cmp(s.SuggestedTip) < 0 {
+			t.Stuck = true
+		}
+	}
+	for n := s.LatestNonce; n < maxNonce; n++ {
+		if !have[n] {
+			s.Gaps = append(s.Gaps, n)
+		}
+	}
+	return s, nil
+}
+
+// Action 一笔修复交易
+type Action struct {
+	Kind  string // "fill" 填空洞 | "bump" 提价替换
+	Nonce uint64
+	Tx    *types.Transaction
+	Old   *PoolTx // bump 时被替换的交易
+}
+
+func (a Action) String() string {
+	switch a.Kind {
+	case "bump":
+		return fmt.Sprintf("bump  nonce=%d %s → %s（feeCap %s → %s wei）", a.Nonce, a.Old.Hash.Hex(), a.Tx.Hash().Hex(), a.Old.feeCap(), a.Tx.GasFeeCap())
+	default:
+		return fmt.Sprintf("fill  nonce=%d 空交易 %s（feeCap %s wei）", a.Nonce, a.Tx.Hash().Hex(), a.Tx.GasFeeCap())
+	}
+}
+
+// Plan 生成修复交易（已签名，尚未发送）：空洞用自转账 0 wei 的空交易填上；
+// 卡住的交易按原内容提价重发，noopBump 为 true 时改为同 nonce 的空交易（直接取消）。
+func Plan(ctx context.Context, cli *ethclient.Client, priv *ecdsa.PrivateKey, s *State, fillGaps, bumpStuck, noopBump bool) ([]Action, error) {
+	chainID, err := cli.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get chain id: %w", err)
+	}
+	signer := types.LatestSignerForChainID(chainID)
+	tip, feeCap := freshFees(s)
+
+	var out []Action
+	if fillGaps {
+		for _, n := range s.Gaps {
+			tx, err := types.SignNewTx(priv, signer, &types.DynamicFeeTx{
+				ChainID: chainID, Nonce: n, GasTipCap: tip, GasFeeCap: feeCap, Gas: noopGas, To: &s.Address, Value: new(big.Int),
+			})
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, Action{Kind: "fill", Nonce: n, Tx: tx})
+		}
+	}
+	if bumpStuck {
+		for _, old := range s.Stuck() {
+			newTip := maxBig(tip, bump(old.tipCap()))
+			newCap := maxBig(feeCap, bump(old.feeCap()))
+			if newCap.Cmp(newTip) < 0 {
+				newCap = new(big.Int).Set(newTip)
+			}
+			value := new(big.Int)
+			if old.Value != nil {
+				value = old.Value.ToInt()
+			}
+			inner := &types.DynamicFeeTx{
+				ChainID: chainID, Nonce: uint64(old.Nonce), GasTipCap: newTip, GasFeeCap: newCap,
+				Gas: uint64(old.Gas), To: old.To, Value: value, Data: old.Input,
+			}
+			if noopBump || old.To == nil {
+				inner.To, inner.Value, inner.Data, inner.Gas = &s.Address, new(big.Int), nil, noopGas
+			}
+			tx, err := types.SignNewTx(priv, signer, inner)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, Action{Kind: "bump", Nonce: uint64(old.Nonce), Tx: tx, Old: old})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Nonce < out[j].Nonce })
+	return out, nil
+}
+
+// freshFees 新交易使用的费用：小费取建议值，上限为 2*baseFee + 小费
+func freshFees(s *State) (tip, feeCap *big.Int) {
+	tip = big.NewInt(1_000_000_000)
+	if s.SuggestedTip != nil && s.SuggestedTip.Sign() > 0 {
+		tip = new(big.Int).Set(s.SuggestedTip)
+	}
+	feeCap = new(big.Int).Set(tip)
+	if s.BaseFee != nil {
+		feeCap.Add(feeCap, new(big.Int).Mul(s.BaseFee, big.NewInt(2)))
+	}
+	return tip, feeCap
+}
+
+func bump(x *big.Int) *big.Int {
+	v := new(big.Int).Mul(x, big.NewInt(bumpPercent))
+	return v.Div(v, big.NewInt(100))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
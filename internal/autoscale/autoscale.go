@@ -0,0 +1,163 @@
+// 自适应并发（AIMD）：批量发送时按观察到的单条耗时与错误率动态调整同时在途的任务数。
+// 节点健康时每个窗口并发 +1（加性增），耗时明显高于基线或错误率超标时并发减半（乘性减），
+// 在健康节点上尽量跑满吞吐，节点吃力时自动退让。
+package autoscale
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config 并发范围与调整阈值；零值字段取默认
+type Config struct {
+	Min, Max, Start int
+	// LatencyFactor 窗口内耗时中位数超过基线（历史最低中位数）的倍数即视为节点吃力，默认 2
+	LatencyFactor float64
+	// MaxErrorRate 窗口内错误率上限，默认 0.1
+	MaxErrorRate float64
+	// OnChange 并发上限变化时回调（用于打印）
+	OnChange func(old, new int, reason string)
+}
+
+// Controller 动态信号量：Acquire/Release 包住每个任务，Release 时记录样本并按窗口调整上限。
+// nil *Controller 的方法都是空操作，调用方可以不区分固定并发与自适应。
+type Controller struct {
+	cfg  Config
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	limit    int
+	inflight int
+	baseline time.Duration
+	gen      uint64 // 每次减并发后递增；之前开始的任务的样本反映的是旧并发，丢弃
+
+	latencies []time.Duration
+	errs      int
+
+	// 统计
+	peak, low    int
+	ups, downs   int
+	limitSum     int64
+	limitSamples int64
+}
+
+func New(cfg Config) *Controller {
+	if cfg.Min <= 0 {
+		cfg.Min = 1
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if cfg.Start < cfg.Min || cfg.Start > cfg.Max {
+		cfg.Start = cfg.Min
+	}
+	if cfg.LatencyFactor <= 1 {
+		cfg.LatencyFactor = 2
+	}
+	if cfg.MaxErrorRate <= 0 {
+		cfg.MaxErrorRate = 0.1
+	}
+	c := &Controller{cfg: cfg, limit: cfg.Start, peak: cfg.Start, low: cfg.Start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Max 需要预先启动的 worker 数
+func (c *Controller) Max() int {
+	if c == nil {
+		return 0
+	}
+	return c.cfg.Max
+}
+
+// Acquire 阻塞直到在途任务数低于当前上限；返回值原样交给 Release
+func (c *Controller) Acquire() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.inflight >= c.limit {
+		c.cond.Wait()
+	}
+	c.inflight++
+	return c.gen
+}
+
+// Release 结束一个任务并记录它的耗时与结果
+func (c *Controller) Release(token uint64, latency time.Duration, err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inflight--
+	c.cond.Broadcast()
+	if token != c.gen {
+		return
+	}
+	c.latencies = append(c.latencies, latency)
+	if err != nil {
+		c.errs++
+	}
+	c.limitSum += int64(c.limit)
+	c.limitSamples++
+	// 窗口大小随上限变化：至少攒够一整轮在途任务的样本再判断
+	if len(c.latencies) >= max(c.limit, 4) {
+		c.adjust()
+	}
+}
+
+func (c *Controller) adjust() {
+	sort.Slice(c.latencies, func(i, j int) bool { return c.latencies[i] < c.latencies[j] })
+	p50 := c.latencies[len(c.latencies)/2]
+	errRate := float64(c.errs) / float64(len(c.latencies))
+	c.latencies, c.errs = c.latencies[:0], 0
+
+	if c.baseline == 0 || p50 < c.baseline {
+		c.baseline = p50
+	}
+	old := c.limit
+	var reason string
+	switch {
+	case errRate > c.cfg.MaxErrorRate:
+		c.limit = max(c.cfg.Min, c.limit/2)
+		reason = fmt.Sprintf("error rate %.0f%% > %.0f%%", errRate*100, c.cfg.MaxErrorRate*100)
+	case float64(p50) > float64(c.baseline)*c.cfg.LatencyFactor:
+		c.limit = max(c.cfg.Min, c.limit/2)
+		reason = fmt.Sprintf("p50 %s > %.1fx baseline %s", p50.Round(time.Millisecond), c.cfg.LatencyFactor, c.baseline.Round(time.Millisecond))
+	case c.limit < c.cfg.Max:
+		c.limit++
+		reason = fmt.Sprintf("healthy (p50 %s, errors %.0f%%)", p50.Round(time.Millisecond), errRate*100)
+	}
+	if c.limit == old {
+		return
+	}
+	if c.limit > old {
+		c.ups++
+	} else {
+		c.downs++
+		c.gen++
+	}
+	c.peak, c.low = max(c.peak, c.limit), min(c.low, c.limit)
+	if c.cfg.OnChange != nil {
+		c.cfg.OnChange(old, c.limit, reason)
+	}
+}
+
+// Summary 运行结束时的并发统计
+func (c *Controller) Summary() string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	avg := float64(c.limit)
+	if c.limitSamples > 0 {
+		avg = float64(c.limitSum) / float64(c.limitSamples)
+	}
+	return fmt.Sprintf("adaptive workers: final=%d avg=%.1f range=[%d, %d] increases=%d decreases=%d baseline_p50=%s",
+		c.limit, avg, c.low, c.peak, c.ups, c.downs, c.baseline.Round(time.Millisecond))
+}
@@ -0,0 +1,280 @@
+package beaconext
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"n42-test/internal/units"
+)
+
+// -------------------- 带注解的 JSON 输出 --------------------
+
+// 注解用的默认链参数（与主网一致）
+const (
+	defaultSecondsPerSlot = 12
+	defaultSlotsPerEpoch  = 32
+	farFutureEpoch        = ^uint64(0)
+)
+
+// AnnotateOptions 注解时换算时间用的链参数
+type AnnotateOptions struct {
+	SecondsPerSlot uint64 // 0 时为 12
+	SlotsPerEpoch  uint64 // 0 时取 JSON 中委员会缓存的 slots_per_epoch，仍没有时为 32
+	// GenesisTime 创世时间（unix 秒）；0 时取 JSON 中的 genesis_time，
+	// 仍没有时把 JSON 中的 slot（或 message.slot）对齐到 Now，时间只是近似值
+	GenesisTime int64
+	Now         time.Time // 零值时为 time.Now()
+}
+
+// WriteAnnotatedJSON 缩进输出 raw，并在常见字段后以 "// …" 注释解读取值：
+// gwei 余额换算为 ETH、纪元/slot 换算为（近似）时间、提款凭证前缀换算为类型名。
+// 输出不再是合法 JSON，只用于人工查看；字段顺序与数字原样保留
+func WriteAnnotatedJSON(w io.Writer, raw json.RawMessage, opts AnnotateOptions) error {
+	if opts.SecondsPerSlot == 0 {
+		opts.SecondsPerSlot = defaultSecondsPerSlot
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	bw := bufio.NewWriter(w)
+	a := &annotator{w: bw, dec: dec, now: opts.Now, clock: newSlotClock(raw, opts)}
+	if err := a.value("", 0); err != nil {
+		return err
+	}
+	a.flushNote()
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
+
+type annotator struct {
+	w     *bufio.Writer
+	dec   *json.Decoder
+	now   time.Time
+	clock slotClock
+	note  string // 上一个值的注释，等逗号写出后再输出
+}
+
+// value 输出下一个值；key 为所在字段名（数组元素沿用数组的字段名，如 balances）
+func (a *annotator) value(key string, depth int) error {
+	tok, err := a.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		return a.container(t, key, depth)
+	case json.Number:
+		a.w.WriteString(t.String())
+		a.note = a.annotate(key, t.String())
+	case string:
+		a.writeString(t)
+		a.note = a.annotate(key, t)
+	case bool:
+		a.w.WriteString(strconv.FormatBool(t))
+	case nil:
+		a.w.WriteString("null")
+	}
+	return nil
+}
+
+func (a *annotator) container(open json.Delim, key string, depth int) error {
+	closing := byte('}')
+	if open == '[' {
+		closing = ']'
+	}
+	a.w.WriteByte(byte(open))
+	first := true
+	for a.dec.More() {
+		if !first {
+			a.w.WriteByte(',')
+		}
+		a.flushNote()
+		a.newline(depth + 1)
+		first = false
+		elemKey := key
+		if open == '{' {
+			tok, err := a.dec.Token()
+			if err != nil {
+				return err
+			}
+			elemKey, _ = tok.(string)
+			a.writeString(elemKey)
+			a.w.WriteString(": ")
+		}
+		if err := a.value(elemKey, depth+1); err != nil {
+			return err
+		}
+	}
+	if _, err := a.dec.Token(); err != nil { // 闭合的 ] 或 }
+		return err
+	}
+	if !first {
+		a.flushNote()
+		a.newline(depth)
+	}
+	a.w.WriteByte(closing)
+	return nil
+}
+
+// writeString 输出带引号的 JSON 字符串（不转义 <>&，与 json.Indent 的输出一致）
+func (a *annotator) writeString(s string) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	a.w.Write(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+func (a *annotator) newline(depth int) {
+	a.w.WriteByte('\n')
+	a.w.WriteString(strings.Repeat("  ", depth))
+}
+
+func (a *annotator) flushNote() {
+	if a.note != "" {
+		a.w.WriteString("  // " + a.note)
+		a.note = ""
+	}
+}
+
+// annotate 按字段名解读取值；不认识的字段返回空串
+func (a *annotator) annotate(key, v string) string {
+	switch {
+	case key == "withdrawal_credentials":
+		return credentialNote(v)
+	case key == "genesis_time":
+		if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(sec, 0).UTC().Format(time.DateTime) + " UTC"
+		}
+	case isGweiField(key):
+		if g, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return units.FromGwei(g).ETH() + " ETH"
+		}
+	case strings.Contains(key, "per_"):
+		// committees_per_slot、slots_per_epoch 等是计数，不是纪元/slot
+	case key == "epoch" || strings.HasSuffix(key, "_epoch"):
+		if e, err := strconv.ParseUint(v, 10, 64); err == nil {
+			if e == farFutureEpoch {
+				return "FAR_FUTURE_EPOCH"
+			}
+			if e > farFutureEpoch/a.clock.slotsPerEpoch {
+				return ""
+			}
+			return a.clock.note(e*a.clock.slotsPerEpoch, a.now)
+		}
+	case key == "slot" || strings.HasSuffix(key, "_slot"):
+		if s, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return a.clock.note(s, a.now)
+		}
+	}
+	return ""
+}
+
+// isGweiField 以 gwei 计的字段：余额、存款/提款金额及各类 *_balance / *_balance_to_consume
+func isGweiField(key string) bool {
+	switch key {
+	case "balances", "balance", "amount", "effective_balance":
+		return true
+	}
+	return strings.HasSuffix(key, "_balance") || strings.HasSuffix(key, "_balance_to_consume")
+}
+
+// credentialNote 提款凭证前缀的类型名；0x01/0x02 附上末 20 字节的执行层地址
+func credentialNote(wc string) string {
+	s := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(wc, "0x"), "0X"))
+	if len(s) != 64 {
+		return ""
+	}
+	switch s[:2] {
+	case "00":
+		return "0x00 BLS 提款公钥"
+	case "01":
+		return "0x01 执行层地址 0x" + s[24:]
+	case "02":
+		return "0x02 复利（compounding）执行层地址 0x" + s[24:]
+	}
+	return "未知凭证类型 0x" + s[:2]
+}
+
+// slotClock slot/纪元 -> 时间：已知创世时间时精确，否则以某个 slot 对齐到当前时间近似
+type slotClock struct {
+	genesis       int64 // unix 秒；0 表示未知
+	anchorSlot    uint64
+	anchorAt      time.Time // 零值表示没有可用的对齐点
+	perSlot       time.Duration
+	slotsPerEpoch uint64
+}
+
+func newSlotClock(raw json.RawMessage, opts AnnotateOptions) slotClock {
+	c := slotClock{
+		genesis:       opts.GenesisTime,
+		perSlot:       time.Duration(opts.SecondsPerSlot) * time.Second,
+		slotsPerEpoch: opts.SlotsPerEpoch,
+	}
+	var top struct {
+		GenesisTime json.Number `json:"genesis_time"`
+		Slot        json.Number `json:"slot"`
+		Message     *struct {
+			Slot json.Number `json:"slot"`
+		} `json:"message"`
+		CommitteeCaches []struct {
+			SlotsPerEpoch uint64 `json:"slots_per_epoch"`
+		} `json:"committee_caches"`
+	}
+	json.Unmarshal(raw, &top) // 取不到的字段保持零值
+	for _, cc := range top.CommitteeCaches {
+		if c.slotsPerEpoch == 0 && cc.SlotsPerEpoch > 0 {
+			c.slotsPerEpoch = cc.SlotsPerEpoch
+		}
+	}
+	if c.slotsPerEpoch == 0 {
+		c.slotsPerEpoch = defaultSlotsPerEpoch
+	}
+	if c.genesis != 0 {
+		return c
+	}
+	if g, err := top.GenesisTime.Int64(); err == nil && g > 0 {
+		c.genesis = g
+		return c
+	}
+	slot := top.Slot
+	if slot == "" && top.Message != nil {
+		slot = top.Message.Slot
+	}
+	if s, err := strconv.ParseUint(slot.String(), 10, 64); err == nil {
+		c.anchorSlot, c.anchorAt = s, opts.Now
+	}
+	return c
+}
+
+// note slot 开始时间及其与 now 的相对关系；无法换算时返回空串
+func (c slotClock) note(slot uint64, now time.Time) string {
+	var at time.Time
+	prefix := ""
+	switch {
+	case c.genesis != 0:
+		at = time.Unix(c.genesis, 0).Add(time.Duration(slot) * c.perSlot)
+	case !c.anchorAt.IsZero():
+		at = c.anchorAt.Add(time.Duration(int64(slot)-int64(c.anchorSlot)) * c.perSlot)
+		prefix = "≈ "
+	default:
+		return ""
+	}
+	d := at.Sub(now).Round(time.Second)
+	rel := "现在"
+	switch {
+	case d > 0:
+		rel = d.String() + " 后"
+	case d < 0:
+		rel = (-d).String() + " 前"
+	}
+	return fmt.Sprintf("%s%s UTC（%s）", prefix, at.UTC().Format(time.DateTime), rel)
+}
@@ -6,94 +6,55 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"sync/atomic"
 	"time"
+
+	"n42-test/internal/rpcclient"
 )
 
 // -------------------- 基础 JSON-RPC 客户端 --------------------
 
-type Client struct {
-	endpoint   string
-	httpClient *http.Client
-	idCounter  int64
+// BeaconReader 本包对外提供的只读查询能力，验证运行与生命周期只依赖它读取区块和信标状态
+type BeaconReader interface {
+	EthGetBlockByNumber(ctx context.Context, tag string, fullTx bool) (*EthBlock, error)
+	GetBeaconBlockHashByEth1Hash(ctx context.Context, eth1Hash string) (string, error)
+	GetBeaconBlockByHash(ctx context.Context, beaconBlockHash string) (json.RawMessage, error)
+	GetBeaconStateByBeaconBlockHash(ctx context.Context, beaconBlockHash string) (json.RawMessage, error)
+	ResolveBeaconByEth1Hash(ctx context.Context, eth1Hash string) (*BeaconSnapshot, error)
 }
 
-func NewClient(endpoint string) *Client {
-	return &Client{
-		endpoint: endpoint,
-		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
-		},
-	}
-}
+var _ BeaconReader = (*Client)(nil)
 
-type rpcRequest struct {
-	JSONRPC string      `json:"jsonrpc"`
-	Method  string      `json:"method"`
-	Params  interface{} `json:"params"`
-	ID      int64       `json:"id"`
-}
+type Client struct {
+	rpc *rpcclient.Client
+	ws  string // SubscribeBeaconBlocks 用的 WS 地址，见 WithWS
 
-type rpcResponse struct {
-	JSONRPC string          `json:"jsonrpc"`
-	ID      int64           `json:"id"`
-	Result  json.RawMessage `json:"result,omitempty"`
-	Error   *rpcError       `json:"error,omitempty"`
+	index *pubkeyIndex // ValidatorIndexByPubkey 的缓存
 }
 
-type rpcError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+func NewClient(endpoint string) *Client {
+	return NewClientFrom(rpcclient.New(endpoint, rpcclient.Options{Timeout: 15 * time.Second}))
 }
 
-func (c *Client) call(ctx context.Context, method string, params interface{}, result any) error {
-	id := atomic.AddInt64(&c.idCounter, 1)
-	reqObj := rpcRequest{
-		JSONRPC: "2.0",
-		Method:  method,
-		Params:  params,
-		ID:      id,
-	}
-	body, err := json.Marshal(reqObj)
-	if err != nil {
-		return fmt.Errorf("marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("build http request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("do http request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode/100 != 2 {
-		raw, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("http status %d: %s", resp.StatusCode, string(raw))
-	}
+// NewClientFrom 使用已配置好（重试、Hook 等）的 JSON-RPC 客户端
+func NewClientFrom(rc *rpcclient.Client) *Client {
+	return &Client{rpc: rc, index: &pubkeyIndex{}}
+}
 
-	var rpcResp rpcResponse
-	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
-		return fmt.Errorf("decode rpc response: %w", err)
-	}
-	if rpcResp.Error != nil {
-		return fmt.Errorf("rpc error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
+// call 与 rpcclient.Client.Call 相同，但 result 非 nil 时把 null 结果视为错误
+func (c *Client) call(ctx context.Context, method string, params []any, result any) error {
+	var raw json.RawMessage
+	if err := c.rpc.Call(ctx, &raw, method, params...); err != nil {
+		return err
 	}
 	if result == nil {
 		return nil
 	}
-	if len(rpcResp.Result) == 0 || string(rpcResp.Result) == "null" {
+	if len(raw) == 0 || string(raw) == "null" {
 		return errors.New("empty result")
 	}
-	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+	if err := json.Unmarshal(raw, result); err != nil {
 		// 提示原始返回，便于排查类型不匹配
-		return fmt.Errorf("unmarshal result: %w; raw=%s", err, string(rpcResp.Result))
+		return fmt.Errorf("unmarshal result: %w; raw=%s", err, string(raw))
 	}
 	return nil
 }
@@ -225,12 +186,12 @@ func (c *Client) ResolveBeaconByEth1Hash(ctx context.Context, eth1Hash string) (
 	}, nil
 }
 
-// PrettyPrintJSON 将 json.RawMessage 格式化输出到控制台
+// PrettyPrintJSON 将 json.RawMessage 格式化输出到控制台，常见字段后附注解（见 WriteAnnotatedJSON）
 func PrettyPrintJSON(label string, raw json.RawMessage) {
 	var pretty bytes.Buffer
-	if err := json.Indent(&pretty, raw, "", "  "); err != nil {
+	if err := WriteAnnotatedJSON(&pretty, raw, AnnotateOptions{}); err != nil {
 		fmt.Printf("%s (raw): %s\n", label, string(raw)) // 回退直接打印
 		return
 	}
-	fmt.Printf("%s:\n%s\n", label, pretty.String())
+	fmt.Printf("%s:\n%s", label, pretty.String())
 }
@@ -0,0 +1,152 @@
+package beaconext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/itchyny/gojq"
+)
+
+// -------------------- jq 表达式查询 --------------------
+//
+// 数百 MB 的状态 JSON 不必再整个导出后交给外部 jq：表达式在进程内执行（gojq，语法同 jq），
+// 只从顶层某个字段开始取值（如 .validators[0]、.balances | length）时，流式读取也只解码这个字段。
+
+// Query 编译好的 jq 表达式
+type Query struct {
+	src   string
+	code  *gojq.Code
+	field string // 表达式只经由这个顶层字段读取输入时非空
+}
+
+// CompileQuery 解析并编译 jq 表达式
+func CompileQuery(expr string) (*Query, error) {
+	parsed, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parse query %q: %w", expr, err)
+	}
+	code, err := gojq.Compile(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("compile query %q: %w", expr, err)
+	}
+	return &Query{src: expr, code: code, field: rootField(parsed)}, nil
+}
+
+func (q *Query) String() string { return q.src }
+
+// Field 表达式只读取输入的这个顶层字段时返回字段名，否则返回空串
+func (q *Query) Field() string { return q.field }
+
+// Run 对已解码的值执行表达式，返回全部结果；数字应为 json.Number（Decoder.UseNumber），以免大整数失真
+func (q *Query) Run(ctx context.Context, v any) ([]any, error) {
+	var out []any
+	iter := q.code.RunWithContext(ctx, v)
+	for {
+		r, ok := iter.Next()
+		if !ok {
+			return out, nil
+		}
+		if err, ok := r.(error); ok {
+			return out, fmt.Errorf("query %q: %w", q.src, err)
+		}
+		out = append(out, r)
+	}
+}
+
+// RunJSON 对原始 JSON（如信标区块）执行表达式
+func (q *Query) RunJSON(ctx context.Context, raw json.RawMessage) ([]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+	return q.Run(ctx, v)
+}
+
+// RunState 流式读取信标状态并执行表达式：Field 非空时只解码该字段，其余字段跳过不缓存
+func (q *Query) RunState(ctx context.Context, s BeaconStateStreamer, beaconBlockHash string) ([]any, error) {
+	doc := map[string]any{}
+	err := s.StreamBeaconStateByBeaconBlockHash(ctx, beaconBlockHash, func(key string, dec *json.Decoder) (bool, error) {
+		if q.field != "" && key != q.field {
+			return false, nil
+		}
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return true, err
+		}
+		doc[key] = v
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q.Run(ctx, doc)
+}
+
+// WriteQueryResults 同 jq 的默认输出：每个结果一段缩进的 JSON
+func WriteQueryResults(w io.Writer, results []any) error {
+	for _, r := range results {
+		b, err := gojq.Marshal(r)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, b, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rootField 表达式形如 .name<后缀> | …（后缀只含常量下标、[]、?）时返回 name：
+// 管道右侧的输入是左侧的输出，整个表达式只读取输入的这一个字段
+func rootField(q *gojq.Query) string {
+	if len(q.FuncDefs) > 0 || len(q.Imports) > 0 {
+		return ""
+	}
+	for q.Op == gojq.OpPipe {
+		q = q.Left
+		if len(q.FuncDefs) > 0 {
+			return ""
+		}
+	}
+	t := q.Term
+	if q.Op != 0 || t == nil || t.Type != gojq.TermTypeIndex || !constIndex(t.Index) || t.Index.Name == "" {
+		return ""
+	}
+	for _, s := range t.SuffixList {
+		// .a as $x | … 的绑定体以原输入执行；.a[.b] 的下标也从原输入取值
+		if s.Bind != nil || (s.Index != nil && !constIndex(s.Index)) {
+			return ""
+		}
+	}
+	return t.Index.Name
+}
+
+func constIndex(ix *gojq.Index) bool {
+	return ix != nil && isLiteral(ix.Start) && isLiteral(ix.End)
+}
+
+func isLiteral(q *gojq.Query) bool {
+	if q == nil {
+		return true
+	}
+	if q.Op != 0 || q.Term == nil || len(q.Term.SuffixList) > 0 {
+		return false
+	}
+	switch q.Term.Type {
+	case gojq.TermTypeNumber:
+		return true
+	case gojq.TermTypeString:
+		return q.Term.Str != nil && q.Term.Str.Queries == nil
+	}
+	return false
+}
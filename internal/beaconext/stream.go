@@ -0,0 +1,155 @@
+package beaconext
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"n42-test/internal/rpcclient"
+)
+
+// -------------------- 流式读取信标状态 --------------------
+//
+// 几十万验证者的状态 JSON 有数百 MB，整体读入 RawMessage 再 Unmarshal 内存占用翻倍。
+// 这里直接在 HTTP 响应体上逐 token 解析，只把调用方关心的字段交给 visit，其余字段跳过。
+
+// FieldVisitor 处理状态对象的一个顶层字段：dec 正好位于该字段的值之前。
+// 处理了就必须完整读掉这个值并返回 true；返回 false 时由调用方跳过。
+type FieldVisitor func(key string, dec *json.Decoder) (bool, error)
+
+// BeaconStateStreamer 可流式读取信标状态；*Client 实现
+type BeaconStateStreamer interface {
+	StreamBeaconStateByBeaconBlockHash(ctx context.Context, beaconBlockHash string, visit FieldVisitor) error
+}
+
+var _ BeaconStateStreamer = (*Client)(nil)
+
+// StreamBeaconStateByBeaconBlockHash 同 GetBeaconStateByBeaconBlockHash，但不缓存整个状态：
+// 对 result 的每个顶层字段调用 visit。
+func (c *Client) StreamBeaconStateByBeaconBlockHash(ctx context.Context, beaconBlockHash string, visit FieldVisitor) error {
+	// 大状态传输时间可能超过默认客户端超时，这里只受 ctx 控制
+	body, err := c.rpc.Stream(ctx, "consensusBeaconExt_get_beacon_state_by_beacon_block_hash", beaconBlockHash)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	sawResult := false
+	err = WalkObject(dec, func(key string, dec *json.Decoder) (bool, error) {
+		switch key {
+		case "error":
+			var e *rpcclient.Error
+			if err := dec.Decode(&e); err != nil {
+				return true, fmt.Errorf("decode rpc error: %w", err)
+			}
+			if e != nil {
+				return true, e
+			}
+			return true, nil
+		case "result":
+			sawResult = true
+			tok, err := dec.Token()
+			if err != nil {
+				return true, fmt.Errorf("decode result: %w", err)
+			}
+			if tok == nil {
+				return true, errors.New("empty result")
+			}
+			if d, ok := tok.(json.Delim); !ok || d != '{' {
+				return true, fmt.Errorf("decode result: expect object, got %v", tok)
+			}
+			return true, walkFields(dec, visit)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !sawResult {
+		return errors.New("empty result")
+	}
+	return nil
+}
+
+// WalkObject 读取一个 JSON 对象，对每个字段调用 visit；未处理的字段跳过
+func WalkObject(dec *json.Decoder, visit FieldVisitor) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return fmt.Errorf("expect object, got %v", tok)
+	}
+	return walkFields(dec, visit)
+}
+
+// walkFields 在已读掉 '{' 之后遍历字段直到 '}'
+func walkFields(dec *json.Decoder, visit FieldVisitor) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("expect object key, got %v", tok)
+		}
+		handled, err := visit(key, dec)
+		if err != nil {
+			return fmt.Errorf("field %q: %w", key, err)
+		}
+		if !handled {
+			if err := SkipValue(dec); err != nil {
+				return fmt.Errorf("skip field %q: %w", key, err)
+			}
+		}
+	}
+	_, err := dec.Token() // '}'
+	return err
+}
+
+// EachElement 读取一个 JSON 数组，对每个元素调用 fn（fn 必须读掉一个元素）
+func EachElement(dec *json.Decoder, fn func(dec *json.Decoder) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return fmt.Errorf("expect array, got %v", tok)
+	}
+	for dec.More() {
+		if err := fn(dec); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token() // ']'
+	return err
+}
+
+// SkipValue 跳过下一个 JSON 值（不缓存其内容）
+func SkipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok {
+			switch d {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package beaconext
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"n42-test/internal/rpcclient"
+)
+
+// -------------------- WS 订阅新信标区块 --------------------
+//
+// 节点没有信标区块的原生订阅：在 WS 上订阅 eth newHeads，每个新的执行层区块头经
+// consensusBeaconExt 映射到信标区块哈希并取回区块（查询仍走 HTTP），调用方按推送反应，
+// 不必再轮询 eth_getBlockByNumber。WS 断线时自动重连，断线期间漏掉的区块按高度补齐。
+
+// maxGapFill 重连后最多补齐的区块数；更大的缺口只从新链头继续
+const maxGapFill = 64
+
+// BeaconBlockEvent 一个新的信标区块。Err 非 nil 时事件只表示一次失败：多数为该高度的区块解析失败（订阅继续）；
+// 订阅因错误结束时，原因作为关闭通道前的最后一个事件送出
+type BeaconBlockEvent struct {
+	Eth1Number      uint64          `json:"eth1_number"`
+	Eth1Hash        string          `json:"eth1_hash"`
+	BeaconBlockHash string          `json:"beacon_block_hash"`
+	Slot            uint64          `json:"slot"` // 区块 JSON 的 slot（兼容 message / data.message 包装），取不到为 0
+	Block           json.RawMessage `json:"block"`
+	Err             error           `json:"-"`
+}
+
+// WithWS 设置订阅用的 WS 地址（ws:// 或 wss://），返回 c 本身
+func (c *Client) WithWS(wsURL string) *Client {
+	c.ws = wsURL
+	return c
+}
+
+// SubscribeBeaconBlocks 订阅新的信标区块，直到 ctx 结束或 WS 重连失败；需先 WithWS。
+// 同一执行层区块只推送一次，分叉切换后同高度的新区块会再推送
+func (c *Client) SubscribeBeaconBlocks(ctx context.Context) (<-chan BeaconBlockEvent, error) {
+	if c.ws == "" {
+		return nil, errors.New("no ws endpoint (use WithWS)")
+	}
+	cli, err := rpcclient.DialWS(ctx, c.ws, rpcclient.WSOptions{
+		Reconnect: rpcclient.RetryPolicy{MaxAttempts: 5, Backoff: time.Second},
+	})
+	if err != nil {
+		return nil, err
+	}
+	sub, err := cli.Subscribe(ctx, "eth", "newHeads")
+	if err != nil {
+		cli.Close()
+		return nil, err
+	}
+	out := make(chan BeaconBlockEvent, 16)
+	go func() {
+		defer close(out)
+		defer cli.Close()
+		defer sub.Unsubscribe()
+		f := &blockFeed{c: c, out: out}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-sub.Err():
+				if err == nil {
+					err = errors.New("subscription closed")
+				}
+				f.send(ctx, BeaconBlockEvent{Err: fmt.Errorf("newHeads subscription ended: %w", err)})
+				return
+			case raw := <-sub.Notifications():
+				var h struct {
+					Number string `json:"number"`
+					Hash   string `json:"hash"`
+				}
+				if json.Unmarshal(raw, &h) != nil || h.Hash == "" {
+					continue
+				}
+				n, err := parseQuantity(h.Number)
+				if err != nil {
+					continue
+				}
+				f.head(ctx, n, h.Hash)
+			}
+		}
+	}()
+	return out, nil
+}
+
+// blockFeed 订阅 goroutine 内的状态：上次推送的执行层区块
+type blockFeed struct {
+	c        *Client
+	out      chan<- BeaconBlockEvent
+	last     uint64
+	lastHash string
+}
+
+// head 处理一个新链头：先补齐与上次之间缺失的区块，再推送它本身
+func (f *blockFeed) head(ctx context.Context, number uint64, hash string) {
+	if hash == f.lastHash {
+		return
+	}
+	if f.lastHash != "" && number > f.last+1 && number-f.last-1 <= maxGapFill {
+		for n := f.last + 1; n < number; n++ {
+			blk, err := f.c.EthGetBlockByNumber(ctx, "0x"+strconv.FormatUint(n, 16), false)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				f.send(ctx, BeaconBlockEvent{Eth1Number: n, Err: fmt.Errorf("get block %d: %w", n, err)})
+				continue
+			}
+			f.emit(ctx, n, blk.Hash)
+		}
+	}
+	f.emit(ctx, number, hash)
+}
+
+func (f *blockFeed) emit(ctx context.Context, number uint64, eth1Hash string) {
+	f.last, f.lastHash = number, eth1Hash
+	ev := BeaconBlockEvent{Eth1Number: number, Eth1Hash: eth1Hash}
+	ev.BeaconBlockHash, ev.Err = f.c.GetBeaconBlockHashByEth1Hash(ctx, eth1Hash)
+	if ev.Err == nil {
+		ev.Block, ev.Err = f.c.GetBeaconBlockByHash(ctx, ev.BeaconBlockHash)
+	}
+	if ev.Err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		ev.Err = fmt.Errorf("resolve beacon block of %s: %w", eth1Hash, ev.Err)
+	} else {
+		ev.Slot = blockSlot(ev.Block)
+	}
+	f.send(ctx, ev)
+}
+
+func (f *blockFeed) send(ctx context.Context, ev BeaconBlockEvent) {
+	select {
+	case f.out <- ev:
+	case <-ctx.Done():
+	}
+}
+
+// blockSlot 区块 JSON 中的 slot：依次查看顶层、message、data.message；数字或十进制 / 0x 字符串均可
+func blockSlot(raw json.RawMessage) uint64 {
+	type level struct {
+		Slot    json.RawMessage  `json:"slot"`
+		Message *json.RawMessage `json:"message"`
+		Data    *json.RawMessage `json:"data"`
+	}
+	cur := raw
+	for depth := 0; depth < 3; depth++ {
+		var l level
+		if json.Unmarshal(cur, &l) != nil {
+			return 0
+		}
+		if len(l.Slot) > 0 {
+			s := strings.Trim(string(l.Slot), `"`)
+			if strings.HasPrefix(s, "0x") {
+				n, _ := parseQuantity(s)
+				return n
+			}
+			n, _ := strconv.ParseUint(s, 10, 64)
+			return n
+		}
+		switch {
+		case l.Data != nil:
+			cur = *l.Data
+		case l.Message != nil:
+			cur = *l.Message
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// parseQuantity 0x 十六进制数量
+func parseQuantity(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return strconv.ParseUint(s[2:], 16, 64)
+}
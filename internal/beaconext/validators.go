@@ -0,0 +1,116 @@
+package beaconext
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"n42-test/internal/hexutil"
+	"n42-test/internal/rpcclient"
+)
+
+// -------------------- 按公钥查验证者下标 --------------------
+//
+// 节点提供 consensusBeaconExt_get_validator_index_by_pubkey 时直接调用；没有该方法时退回客户端索引：
+// 流式读取一次最新状态的 validators，建立 公钥 → 下标 表并缓存。验证者下标只增不减，
+// 已查到的公钥一直有效；查不到时只有链头变化后才重读状态，轮询尚未入链的公钥不会每次都重读。
+
+// MethodValidatorIndexByPubkey 直接按公钥查下标的扩展方法（参数 [pubkey]，结果为下标或 null）
+const MethodValidatorIndexByPubkey = "consensusBeaconExt_get_validator_index_by_pubkey"
+
+// ValidatorIndexer 可按公钥查验证者下标；*Client 实现
+type ValidatorIndexer interface {
+	ValidatorIndexByPubkey(ctx context.Context, pubkey string) (index int, ok bool, err error)
+}
+
+var _ ValidatorIndexer = (*Client)(nil)
+
+// pubkeyIndex 客户端侧的 公钥 → 下标 缓存
+type pubkeyIndex struct {
+	mu       sync.Mutex
+	noDirect bool // 节点不支持 MethodValidatorIndexByPubkey
+	byPubkey map[string]int
+	head     string // 建表时的执行层链头哈希
+}
+
+// ValidatorIndexByPubkey 按公钥（大小写、0x 前缀不限）查验证者下标；不在验证者集合中时 ok 为 false
+func (c *Client) ValidatorIndexByPubkey(ctx context.Context, pubkey string) (int, bool, error) {
+	pk := hexutil.Normalize(pubkey)
+	x := c.index
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if !x.noDirect {
+		var raw json.RawMessage
+		err := c.rpc.Call(ctx, &raw, MethodValidatorIndexByPubkey, pk)
+		var re *rpcclient.Error
+		switch {
+		case err == nil:
+			if len(raw) == 0 || string(raw) == "null" {
+				return -1, false, nil
+			}
+			var i int
+			if err := json.Unmarshal(raw, &i); err != nil {
+				return -1, false, fmt.Errorf("unmarshal result: %w; raw=%s", err, string(raw))
+			}
+			return i, true, nil
+		case errors.As(err, &re) && (re.Code == -32601 || re.Code == -32602):
+			x.noDirect = true // 方法不存在（或参数形状不同）：以后只用客户端索引
+		default:
+			return -1, false, err
+		}
+	}
+
+	if i, ok := x.byPubkey[pk]; ok {
+		return i, true, nil
+	}
+	blk, err := c.EthGetBlockByNumber(ctx, "latest", false)
+	if err != nil {
+		return -1, false, fmt.Errorf("get latest block: %w", err)
+	}
+	if x.byPubkey != nil && blk.Hash == x.head {
+		return -1, false, nil
+	}
+	m, err := c.scanPubkeys(ctx, blk.Hash)
+	if err != nil {
+		return -1, false, err
+	}
+	x.byPubkey, x.head = m, blk.Hash
+	i, ok := m[pk]
+	if !ok {
+		return -1, false, nil
+	}
+	return i, true, nil
+}
+
+// scanPubkeys 流式读取执行层区块 eth1Hash 对应状态的 validators，只解码公钥
+func (c *Client) scanPubkeys(ctx context.Context, eth1Hash string) (map[string]int, error) {
+	beaconHash, err := c.GetBeaconBlockHashByEth1Hash(ctx, eth1Hash)
+	if err != nil {
+		return nil, fmt.Errorf("map eth1 hash -> beacon block hash: %w", err)
+	}
+	m := map[string]int{}
+	n := 0
+	err = c.StreamBeaconStateByBeaconBlockHash(ctx, beaconHash, func(key string, dec *json.Decoder) (bool, error) {
+		if key != "validators" {
+			return false, nil
+		}
+		return true, EachElement(dec, func(dec *json.Decoder) error {
+			var v struct {
+				Pubkey string `json:"pubkey"`
+			}
+			if err := dec.Decode(&v); err != nil {
+				return err
+			}
+			m[hexutil.Normalize(v.Pubkey)] = n
+			n++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,100 @@
+package beaconstate
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ChurnRule 每纪元激活流量上限的计算规则
+type ChurnRule string
+
+const (
+	// ChurnDeneb 按验证者数量：min(8, max(4, active/65536))，作用于 activation_epoch
+	ChurnDeneb ChurnRule = "deneb"
+	// ChurnElectra 按余额：min(256 ETH, max(128 ETH, total_active_balance/65536))，
+	// 作用于存款处理（体现为 activation_eligibility_epoch）
+	ChurnElectra ChurnRule = "electra"
+)
+
+const (
+	churnLimitQuotient = 65536
+
+	minPerEpochChurnLimit        = 4
+	maxPerEpochActivationChurn   = 8
+	minPerEpochChurnLimitElectra = 128_000_000_000 // gwei
+	maxPerEpochActivationElectra = 256_000_000_000 // gwei
+	effectiveBalanceIncrement    = 1_000_000_000   // gwei
+)
+
+// ParseChurnRule 解析 --churn 参数
+func ParseChurnRule(s string) (ChurnRule, error) {
+	switch ChurnRule(s) {
+	case ChurnDeneb, ChurnElectra:
+		return ChurnRule(s), nil
+	default:
+		return "", fmt.Errorf("unknown churn rule %q (want deneb|electra)", s)
+	}
+}
+
+// Limit epoch 的激活流量上限：deneb 为验证者个数，electra 为 gwei
+func (r ChurnRule) Limit(s *State, epoch uint64) uint64 {
+	count, total := s.ActiveCount(epoch)
+	if r == ChurnElectra {
+		limit := total / churnLimitQuotient
+		limit -= limit % effectiveBalanceIncrement
+		return min(max(limit, minPerEpochChurnLimitElectra), maxPerEpochActivationElectra)
+	}
+	return min(max(uint64(count)/churnLimitQuotient, minPerEpochChurnLimit), maxPerEpochActivationChurn)
+}
+
+// Unit 上限的单位说明
+func (r ChurnRule) Unit() string {
+	if r == ChurnElectra {
+		return "gwei"
+	}
+	return "validators"
+}
+
+// EpochUsage 某一纪元被占用的激活流量
+type EpochUsage struct {
+	Epoch uint64
+	Count int    // 该纪元的验证者个数
+	Gwei  uint64 // 该纪元验证者的有效余额之和
+	Limit uint64
+}
+
+// Used 按规则计量的占用值
+func (u EpochUsage) Used(r ChurnRule) uint64 {
+	if r == ChurnElectra {
+		return u.Gwei
+	}
+	return uint64(u.Count)
+}
+
+// Usage 统计状态中所有验证者按纪元的激活流量占用（deneb 看 activation_epoch，
+// electra 看 activation_eligibility_epoch），sinceEpoch 之前的纪元忽略（创世验证者不受限）。
+func (r ChurnRule) Usage(s *State, sinceEpoch uint64) []EpochUsage {
+	byEpoch := map[uint64]*EpochUsage{}
+	for _, v := range s.Validators {
+		e := v.ActivationEpoch
+		if r == ChurnElectra {
+			e = v.ActivationEligibilityEpoch
+		}
+		if e == FarFutureEpoch || e < sinceEpoch || e == 0 {
+			continue
+		}
+		u, ok := byEpoch[e]
+		if !ok {
+			u = &EpochUsage{Epoch: e, Limit: r.Limit(s, e)}
+			byEpoch[e] = u
+		}
+		u.Count++
+		u.Gwei += v.EffectiveBalance
+	}
+	out := make([]EpochUsage, 0, len(byEpoch))
+	for _, u := range byEpoch {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Epoch < out[j].Epoch })
+	return out
+}
@@ -0,0 +1,216 @@
+package beaconstate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// -------------------- 状态对比 --------------------
+//
+// 批量质押 / 退出前后各取一份状态，列出验证者集合的变化（新增、激活、发起退出、退出、罚没）、
+// 余额增减与 justified / finalized checkpoint 的推进，代替手工 diff 两份 JSON。
+
+// ValidatorChange 一个验证者在两份状态间的变化；FromStatus 为空表示前一份状态中还没有该验证者
+type ValidatorChange struct {
+	Index      int    `json:"index"`
+	Pubkey     string `json:"pubkey"`
+	FromStatus string `json:"from_status,omitempty"`
+	ToStatus   string `json:"to_status"`
+	Epoch      uint64 `json:"epoch,omitempty"` // 相关纪元：激活为 activation_epoch，退出为 exit_epoch
+}
+
+// BalanceDelta 一个验证者的余额变化（gwei）
+type BalanceDelta struct {
+	Index  int    `json:"index"`
+	Pubkey string `json:"pubkey"`
+	From   uint64 `json:"from"`
+	To     uint64 `json:"to"`
+	Delta  int64  `json:"delta"`
+}
+
+// CheckpointChange checkpoint 在两份状态间的变化
+type CheckpointChange struct {
+	From    Checkpoint `json:"from"`
+	To      Checkpoint `json:"to"`
+	Changed bool       `json:"changed"`
+}
+
+// Diff DiffStates 的结果；各列表按验证者下标排列
+type Diff struct {
+	FromSlot  uint64 `json:"from_slot"`
+	ToSlot    uint64 `json:"to_slot"`
+	FromEpoch uint64 `json:"from_epoch"`
+	ToEpoch   uint64 `json:"to_epoch"`
+
+	Added         []ValidatorChange `json:"added"`          // 后一份状态中新出现的验证者
+	Activated     []ValidatorChange `json:"activated"`      // 由未激活变为激活
+	ExitInitiated []ValidatorChange `json:"exit_initiated"` // exit_epoch 由未设置变为已设置
+	Exited        []ValidatorChange `json:"exited"`         // 由激活变为不再激活
+	Slashed       []ValidatorChange `json:"slashed"`        // 新被罚没
+	StatusChanges []ValidatorChange `json:"status_changes"` // 全部状态变化（含以上各类）
+
+	BalanceDeltas []BalanceDelta `json:"balance_deltas"` // 余额有变化的验证者（新增的验证者从 0 算起）
+	TotalFrom     uint64         `json:"total_balance_from"`
+	TotalTo       uint64         `json:"total_balance_to"`
+
+	PreviousJustified CheckpointChange `json:"previous_justified"`
+	CurrentJustified  CheckpointChange `json:"current_justified"`
+	Finalized         CheckpointChange `json:"finalized"`
+}
+
+// DiffStates 对比 a（之前）与 b（之后）两份状态；验证者按下标对应（下标只增不减），纪元按各自的 slot 计算
+func DiffStates(a, b *State, slotsPerEpoch uint64) *Diff {
+	d := &Diff{
+		FromSlot: a.Slot, ToSlot: b.Slot,
+		FromEpoch: a.Epoch(slotsPerEpoch), ToEpoch: b.Epoch(slotsPerEpoch),
+		PreviousJustified: checkpointChange(a.PreviousJustifiedCheckpoint, b.PreviousJustifiedCheckpoint),
+		CurrentJustified:  checkpointChange(a.CurrentJustifiedCheckpoint, b.CurrentJustifiedCheckpoint),
+		Finalized:         checkpointChange(a.FinalizedCheckpoint, b.FinalizedCheckpoint),
+	}
+	for i := range a.Validators {
+		d.TotalFrom += a.Balance(i)
+	}
+	for i, vb := range b.Validators {
+		pk := NormPubkey(vb.Pubkey)
+		balB := b.Balance(i)
+		d.TotalTo += balB
+		to := vb.Status(d.ToEpoch, balB)
+
+		if i >= len(a.Validators) {
+			c := ValidatorChange{Index: i, Pubkey: pk, ToStatus: to}
+			d.Added = append(d.Added, c)
+			d.StatusChanges = append(d.StatusChanges, c)
+			if vb.IsActive(d.ToEpoch) {
+				d.Activated = append(d.Activated, ValidatorChange{Index: i, Pubkey: pk, ToStatus: to, Epoch: vb.ActivationEpoch})
+			}
+			if vb.Slashed {
+				d.Slashed = append(d.Slashed, c)
+			}
+			if balB != 0 {
+				d.BalanceDeltas = append(d.BalanceDeltas, BalanceDelta{Index: i, Pubkey: pk, To: balB, Delta: int64(balB)})
+			}
+			continue
+		}
+
+		va := a.Validators[i]
+		balA := a.Balance(i)
+		from := va.Status(d.FromEpoch, balA)
+		change := ValidatorChange{Index: i, Pubkey: pk, FromStatus: from, ToStatus: to}
+		if from != to {
+			d.StatusChanges = append(d.StatusChanges, change)
+		}
+		wasActive, isActive := va.IsActive(d.FromEpoch), vb.IsActive(d.ToEpoch)
+		switch {
+		case !wasActive && isActive:
+			change.Epoch = vb.ActivationEpoch
+			d.Activated = append(d.Activated, change)
+		case wasActive && !isActive:
+			change.Epoch = vb.ExitEpoch
+			d.Exited = append(d.Exited, change)
+		}
+		if va.ExitEpoch == FarFutureEpoch && vb.ExitEpoch != FarFutureEpoch {
+			change.Epoch = vb.ExitEpoch
+			d.ExitInitiated = append(d.ExitInitiated, change)
+		}
+		if !va.Slashed && vb.Slashed {
+			d.Slashed = append(d.Slashed, change)
+		}
+		if balA != balB {
+			d.BalanceDeltas = append(d.BalanceDeltas, BalanceDelta{Index: i, Pubkey: pk, From: balA, To: balB, Delta: int64(balB) - int64(balA)})
+		}
+	}
+	return d
+}
+
+func checkpointChange(a, b Checkpoint) CheckpointChange {
+	return CheckpointChange{From: a, To: b, Changed: a != b}
+}
+
+// Empty 两份状态之间没有任何变化（不含 slot）
+func (d *Diff) Empty() bool {
+	return len(d.StatusChanges) == 0 && len(d.Activated) == 0 && len(d.ExitInitiated) == 0 && len(d.Slashed) == 0 &&
+		len(d.BalanceDeltas) == 0 && !d.CurrentJustified.Changed && !d.PreviousJustified.Changed && !d.Finalized.Changed
+}
+
+// LargestDeltas 按余额变化绝对值从大到小的前 n 个（n<=0 为全部），不修改 d.BalanceDeltas
+func (d *Diff) LargestDeltas(n int) []BalanceDelta {
+	out := append([]BalanceDelta(nil), d.BalanceDeltas...)
+	sort.SliceStable(out, func(i, j int) bool { return abs64(out[i].Delta) > abs64(out[j].Delta) })
+	if n > 0 && n < len(out) {
+		out = out[:n]
+	}
+	return out
+}
+
+// Print 以文本输出；余额变化只列 LargestDeltas(topDeltas)
+func (d *Diff) Print(w io.Writer, topDeltas int) {
+	fmt.Fprintf(w, "slot %d → %d（epoch %d → %d）\n", d.FromSlot, d.ToSlot, d.FromEpoch, d.ToEpoch)
+	printCheckpoint(w, "finalized", d.Finalized)
+	printCheckpoint(w, "current_justified", d.CurrentJustified)
+	printCheckpoint(w, "previous_justified", d.PreviousJustified)
+	fmt.Fprintf(w, "总余额 %s → %s ETH（%s ETH）\n", gweiToETH(d.TotalFrom), gweiToETH(d.TotalTo), signedGweiToETH(int64(d.TotalTo)-int64(d.TotalFrom)))
+	fmt.Fprintf(w, "新增 %d，激活 %d，发起退出 %d，退出 %d，罚没 %d，状态变化 %d，余额变化 %d\n",
+		len(d.Added), len(d.Activated), len(d.ExitInitiated), len(d.Exited), len(d.Slashed), len(d.StatusChanges), len(d.BalanceDeltas))
+
+	printChanges(w, "新增", d.Added, false)
+	printChanges(w, "激活", d.Activated, true)
+	printChanges(w, "发起退出", d.ExitInitiated, true)
+	printChanges(w, "退出", d.Exited, true)
+	printChanges(w, "罚没", d.Slashed, false)
+	if len(d.BalanceDeltas) > 0 {
+		shown := d.LargestDeltas(topDeltas)
+		fmt.Fprintf(w, "\n余额变化（按绝对值前 %d 个，共 %d 个）\n", len(shown), len(d.BalanceDeltas))
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "INDEX\tPUBKEY\tFROM\tTO\tDELTA")
+		for _, b := range shown {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", b.Index, shortPubkey(b.Pubkey), gweiToETH(b.From), gweiToETH(b.To), signedGweiToETH(b.Delta))
+		}
+		tw.Flush()
+	}
+}
+
+func printCheckpoint(w io.Writer, name string, c CheckpointChange) {
+	if !c.Changed {
+		fmt.Fprintf(w, "%-18s 未变（epoch %d）\n", name, c.To.Epoch)
+		return
+	}
+	fmt.Fprintf(w, "%-18s epoch %d → %d（root %s → %s）\n", name, c.From.Epoch, c.To.Epoch, shortPubkey(c.From.Root), shortPubkey(c.To.Root))
+}
+
+// printChanges withEpoch 为 false 时 EPOCH 列显示为 -（新增、罚没没有对应纪元）
+func printChanges(w io.Writer, title string, cs []ValidatorChange, withEpoch bool) {
+	if len(cs) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s（%d）\n", title, len(cs))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tPUBKEY\tFROM\tTO\tEPOCH")
+	for _, c := range cs {
+		from := c.FromStatus
+		if from == "" {
+			from = "-"
+		}
+		epoch := "-"
+		if withEpoch {
+			epoch = epochOrDash(c.Epoch)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", c.Index, shortPubkey(c.Pubkey), from, c.ToStatus, epoch)
+	}
+	tw.Flush()
+}
+
+func signedGweiToETH(g int64) string {
+	if g < 0 {
+		return "-" + gweiToETH(uint64(-g))
+	}
+	return "+" + gweiToETH(uint64(g))
+}
+
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,133 @@
+package beaconstate
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CommitteeCache 状态里的委员会缓存（上一/当前/下一纪元），与 beacon_state.json 中 committee_caches 元素同形。
+// shuffling 为洗牌后的验证者下标；shuffling_positions[i] 为验证者 i 在 shuffling 中的位置 +1（0 表示不在其中）。
+type CommitteeCache struct {
+	InitializedEpoch   *uint64  `json:"initialized_epoch"`
+	Shuffling          []uint64 `json:"shuffling"`
+	ShufflingPositions []uint64 `json:"shuffling_positions"`
+	CommitteesPerSlot  uint64   `json:"committees_per_slot"`
+	SlotsPerEpoch      uint64   `json:"slots_per_epoch"`
+}
+
+// Duty 一个验证者在某纪元的见证职责
+type Duty struct {
+	Pubkey            string `json:"pubkey"`
+	ValidatorIndex    uint64 `json:"validator_index"`
+	Epoch             uint64 `json:"epoch"`
+	Slot              uint64 `json:"slot"`
+	CommitteeIndex    uint64 `json:"committee_index"`
+	CommitteePosition uint64 `json:"committee_position"`
+	CommitteeSize     uint64 `json:"committee_size"`
+}
+
+// committeeFor 验证者在本缓存纪元内的职责；不在洗牌中返回 false
+func (c *CommitteeCache) committeeFor(validatorIndex uint64) (slot, committee, position, size uint64, ok bool) {
+	if c.InitializedEpoch == nil || validatorIndex >= uint64(len(c.ShufflingPositions)) {
+		return 0, 0, 0, 0, false
+	}
+	p := c.ShufflingPositions[validatorIndex]
+	if p == 0 || c.CommitteesPerSlot == 0 || c.SlotsPerEpoch == 0 {
+		return 0, 0, 0, 0, false
+	}
+	pos := p - 1
+	n := uint64(len(c.Shuffling))
+	count := c.CommitteesPerSlot * c.SlotsPerEpoch
+	// 与 compute_committee 相同的切分：第 k 个委员会为 [n*k/count, n*(k+1)/count)
+	for k := uint64(0); k < count; k++ {
+		start, end := n*k/count, n*(k+1)/count
+		if pos >= start && pos < end {
+			slot = *c.InitializedEpoch*c.SlotsPerEpoch + k/c.CommitteesPerSlot
+			return slot, k % c.CommitteesPerSlot, pos - start, end - start, true
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+// SlotsPerEpochHint 状态委员会缓存中记录的每纪元 slot 数；缓存未初始化时返回 0
+func (s *State) SlotsPerEpochHint() uint64 {
+	for _, c := range s.CommitteeCaches {
+		if c.InitializedEpoch != nil && c.SlotsPerEpoch > 0 {
+			return c.SlotsPerEpoch
+		}
+	}
+	return 0
+}
+
+// Duties 根据委员会缓存推算 pubkeys 在缓存覆盖的各纪元（通常为上一/当前/下一纪元）的见证职责，
+// 按 epoch、slot 排序；不在验证者集合中的公钥返回在 missing 里。
+func (s *State) Duties(pubkeys []string) (duties []Duty, missing []string) {
+	idx := s.Index()
+	for _, pk := range pubkeys {
+		pk = NormPubkey(pk)
+		vi, ok := idx[pk]
+		if !ok {
+			missing = append(missing, pk)
+			continue
+		}
+		for i := range s.CommitteeCaches {
+			c := &s.CommitteeCaches[i]
+			slot, committee, pos, size, ok := c.committeeFor(uint64(vi))
+			if !ok {
+				continue
+			}
+			duties = append(duties, Duty{
+				Pubkey:            pk,
+				ValidatorIndex:    uint64(vi),
+				Epoch:             *c.InitializedEpoch,
+				Slot:              slot,
+				CommitteeIndex:    committee,
+				CommitteePosition: pos,
+				CommitteeSize:     size,
+			})
+		}
+	}
+	sort.Slice(duties, func(i, j int) bool {
+		if duties[i].Epoch != duties[j].Epoch {
+			return duties[i].Epoch < duties[j].Epoch
+		}
+		if duties[i].Slot != duties[j].Slot {
+			return duties[i].Slot < duties[j].Slot
+		}
+		return duties[i].ValidatorIndex < duties[j].ValidatorIndex
+	})
+	return duties, missing
+}
+
+// EpochActivity 某纪元的预期与实际见证
+type EpochActivity struct {
+	Epoch    uint64
+	Expected []uint64 // 有职责的验证者下标
+	Attested []uint64 // 其中出现在参与标记里的
+	Missing  []uint64 // 有职责但未见证的
+}
+
+func (a EpochActivity) String() string {
+	return fmt.Sprintf("epoch %d: expected=%d attested=%d missing=%d %v", a.Epoch, len(a.Expected), len(a.Attested), len(a.Missing), a.Missing)
+}
+
+// CompareActivity 对比某纪元的预期职责与该纪元结束前最后一次看到的参与标记
+func CompareActivity(epoch uint64, duties []Duty, attesters []uint64) EpochActivity {
+	seen := make(map[uint64]bool, len(attesters))
+	for _, i := range attesters {
+		seen[i] = true
+	}
+	a := EpochActivity{Epoch: epoch}
+	for _, d := range duties {
+		if d.Epoch != epoch {
+			continue
+		}
+		a.Expected = append(a.Expected, d.ValidatorIndex)
+		if seen[d.ValidatorIndex] {
+			a.Attested = append(a.Attested, d.ValidatorIndex)
+		} else {
+			a.Missing = append(a.Missing, d.ValidatorIndex)
+		}
+	}
+	return a
+}
@@ -0,0 +1,241 @@
+package beaconstate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"n42-test/internal/beaconext"
+)
+
+// -------------------- 类型化模型 --------------------
+//
+// beaconext 的方法都返回原始 JSON；这里把场景里用到的区块 / 状态 / 验证者字段解码为结构体。
+// 不同客户端对整数的编码不一（JSON 数字、十进制字符串、0x 十六进制），布尔值偶尔为字符串，
+// 区块可能包在 message 或 data.message 里，解码时一律兼容。
+
+// quantity 宽松解码的 uint64：数字、"123"、"0x7b" 与 null（为 0）
+type quantity uint64
+
+func (q *quantity) UnmarshalJSON(b []byte) error {
+	n, err := parseQuantity(b)
+	if err != nil {
+		return err
+	}
+	*q = quantity(n)
+	return nil
+}
+
+// parseQuantity 见 quantity
+func parseQuantity(b []byte) (uint64, error) {
+	s := string(bytes.TrimSpace(b))
+	if s == "null" || s == `""` {
+		return 0, nil
+	}
+	s = strings.Trim(s, `"`)
+	var (
+		n   uint64
+		err error
+	)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		n, err = strconv.ParseUint(s[2:], 16, 64)
+	} else {
+		n, err = strconv.ParseUint(s, 10, 64)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %s", b)
+	}
+	return n, nil
+}
+
+// flexBool 宽松解码的布尔值：true / false / "true" / "false" / null
+type flexBool bool
+
+func (f *flexBool) UnmarshalJSON(b []byte) error {
+	switch strings.Trim(string(bytes.TrimSpace(b)), `"`) {
+	case "true", "1":
+		*f = true
+	case "false", "0", "null", "":
+		*f = false
+	default:
+		return fmt.Errorf("invalid bool %s", b)
+	}
+	return nil
+}
+
+// UnmarshalJSON 宽松解码验证者：整数字段接受数字或字符串，公钥与凭证统一为小写 0x
+func (v *Validator) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Pubkey                     string   `json:"pubkey"`
+		WithdrawalCredentials      string   `json:"withdrawal_credentials"`
+		EffectiveBalance           quantity `json:"effective_balance"`
+		Slashed                    flexBool `json:"slashed"`
+		ActivationEligibilityEpoch quantity `json:"activation_eligibility_epoch"`
+		ActivationEpoch            quantity `json:"activation_epoch"`
+		ExitEpoch                  quantity `json:"exit_epoch"`
+		WithdrawableEpoch          quantity `json:"withdrawable_epoch"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("parse validator: %w", err)
+	}
+	*v = Validator{
+		Pubkey:                     normHex(raw.Pubkey),
+		WithdrawalCredentials:      normHex(raw.WithdrawalCredentials),
+		EffectiveBalance:           uint64(raw.EffectiveBalance),
+		Slashed:                    bool(raw.Slashed),
+		ActivationEligibilityEpoch: uint64(raw.ActivationEligibilityEpoch),
+		ActivationEpoch:            uint64(raw.ActivationEpoch),
+		ExitEpoch:                  uint64(raw.ExitEpoch),
+		WithdrawableEpoch:          uint64(raw.WithdrawableEpoch),
+	}
+	return nil
+}
+
+// UnmarshalJSON 宽松解码 eth1_data：deposit_count 接受数字或字符串
+func (d *Eth1Data) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		DepositRoot  string   `json:"deposit_root"`
+		DepositCount quantity `json:"deposit_count"`
+		BlockHash    string   `json:"block_hash"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("parse eth1_data: %w", err)
+	}
+	*d = Eth1Data{DepositRoot: raw.DepositRoot, DepositCount: uint64(raw.DepositCount), BlockHash: raw.BlockHash}
+	return nil
+}
+
+// UnmarshalJSON 宽松解码 checkpoint：epoch 接受数字或字符串
+func (c *Checkpoint) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Epoch quantity `json:"epoch"`
+		Root  string   `json:"root"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("parse checkpoint: %w", err)
+	}
+	*c = Checkpoint{Epoch: uint64(raw.Epoch), Root: raw.Root}
+	return nil
+}
+
+// normHex 非空时规范化为小写 0x
+func normHex(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	return NormPubkey(s)
+}
+
+// ValidatorByPubkey 按公钥（大小写、0x 前缀不限）查找验证者；找不到时 index 为 -1。
+// 经由 Index 的缓存表查找，同一份状态上重复查找为 O(1)
+func (s *State) ValidatorByPubkey(pk string) (v Validator, index int, ok bool) {
+	i, ok := s.Index()[NormPubkey(pk)]
+	if !ok {
+		return Validator{}, -1, false
+	}
+	return s.Validators[i], i, true
+}
+
+// Balance 验证者 index 的当前余额（gwei）；balances 比 validators 短时为 0
+func (s *State) Balance(index int) uint64 {
+	if index < 0 || index >= len(s.Balances) {
+		return 0
+	}
+	return s.Balances[index]
+}
+
+// ValidatorStatus 验证者 index 在状态所在纪元时的状态
+func (s *State) ValidatorStatus(index int, slotsPerEpoch uint64) string {
+	return s.Validators[index].Status(s.Epoch(slotsPerEpoch), s.Balance(index))
+}
+
+// ExecutionPayload 信标区块 body.execution_payload 中用到的字段
+type ExecutionPayload struct {
+	BlockHash   string `json:"block_hash"`
+	BlockNumber uint64 `json:"block_number"`
+	Timestamp   uint64 `json:"timestamp"`
+}
+
+// Block 信标区块（解开 message / data 包装后）中用到的字段；Raw 为原始 JSON
+type Block struct {
+	Slot             uint64
+	ProposerIndex    uint64
+	ParentRoot       string
+	StateRoot        string
+	Eth1Data         *Eth1Data         // body.eth1_data（没有时为 nil）
+	ExecutionPayload *ExecutionPayload // body.execution_payload（合并前或客户端未给出时为 nil）
+	Raw              json.RawMessage
+}
+
+// blockFields 一层区块对象；body 可能省略，eth1_data 也可能直接在这一层
+type blockFields struct {
+	Slot          quantity `json:"slot"`
+	ProposerIndex quantity `json:"proposer_index"`
+	ParentRoot    string   `json:"parent_root"`
+	StateRoot     string   `json:"state_root"`
+	Body          *struct {
+		Eth1Data         *Eth1Data `json:"eth1_data"`
+		ExecutionPayload *struct {
+			BlockHash   string   `json:"block_hash"`
+			BlockNumber quantity `json:"block_number"`
+			Timestamp   quantity `json:"timestamp"`
+		} `json:"execution_payload"`
+	} `json:"body"`
+	Eth1Data *Eth1Data        `json:"eth1_data"`
+	Message  *json.RawMessage `json:"message"`
+	Data     *json.RawMessage `json:"data"`
+}
+
+// ParseBlock 解析信标区块 JSON；兼容 data.message / message / 顶层三种包装
+func ParseBlock(raw json.RawMessage) (*Block, error) {
+	cur := raw
+	for depth := 0; ; depth++ {
+		var f blockFields
+		if err := json.Unmarshal(cur, &f); err != nil {
+			return nil, fmt.Errorf("parse beacon block: %w", err)
+		}
+		switch {
+		case depth < 2 && f.Data != nil:
+			cur = *f.Data
+			continue
+		case depth < 2 && f.Message != nil:
+			cur = *f.Message
+			continue
+		}
+		b := &Block{
+			Slot: uint64(f.Slot), ProposerIndex: uint64(f.ProposerIndex),
+			ParentRoot: f.ParentRoot, StateRoot: f.StateRoot, Eth1Data: f.Eth1Data, Raw: raw,
+		}
+		if f.Body != nil {
+			if f.Body.Eth1Data != nil {
+				b.Eth1Data = f.Body.Eth1Data
+			}
+			if p := f.Body.ExecutionPayload; p != nil {
+				b.ExecutionPayload = &ExecutionPayload{
+					BlockHash: p.BlockHash, BlockNumber: uint64(p.BlockNumber), Timestamp: uint64(p.Timestamp),
+				}
+			}
+		}
+		return b, nil
+	}
+}
+
+// FetchBlockAt 执行层区块 eth1Hash 对应的信标区块，同时返回信标区块哈希
+func FetchBlockAt(ctx context.Context, r beaconext.BeaconReader, eth1Hash string) (*Block, string, error) {
+	beaconHash, err := r.GetBeaconBlockHashByEth1Hash(ctx, eth1Hash)
+	if err != nil {
+		return nil, "", fmt.Errorf("map eth1 hash -> beacon block hash: %w", err)
+	}
+	raw, err := r.GetBeaconBlockByHash(ctx, beaconHash)
+	if err != nil {
+		return nil, beaconHash, fmt.Errorf("get beacon block by hash: %w", err)
+	}
+	b, err := ParseBlock(raw)
+	if err != nil {
+		return nil, beaconHash, err
+	}
+	return b, beaconHash, nil
+}
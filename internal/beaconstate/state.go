@@ -0,0 +1,195 @@
+// 信标状态解析：consensusBeaconExt_get_beacon_state_by_beacon_block_hash 返回的 JSON
+// （与 beacon_state.json 同形）转换为类型化结构，供各场景统计使用。
+package beaconstate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/hexutil"
+)
+
+// FarFutureEpoch 与信标链一致的“未设置”纪元
+const FarFutureEpoch = ^uint64(0)
+
+// DefaultSlotsPerEpoch 每纪元 slot 数
+const DefaultSlotsPerEpoch = 32
+
+// Validator 与 beacon_state.json 中 validators 元素同形
+type Validator struct {
+	Pubkey                     string `json:"pubkey"`
+	WithdrawalCredentials      string `json:"withdrawal_credentials"`
+	EffectiveBalance           uint64 `json:"effective_balance"`
+	Slashed                    bool   `json:"slashed"`
+	ActivationEligibilityEpoch uint64 `json:"activation_eligibility_epoch"`
+	ActivationEpoch            uint64 `json:"activation_epoch"`
+	ExitEpoch                  uint64 `json:"exit_epoch"`
+	WithdrawableEpoch          uint64 `json:"withdrawable_epoch"`
+}
+
+// IsActive 验证者在 epoch 是否处于激活状态
+func (v Validator) IsActive(epoch uint64) bool {
+	return v.ActivationEpoch <= epoch && epoch < v.ExitEpoch
+}
+
+// Checkpoint 与 justified / finalized checkpoint 同形
+type Checkpoint struct {
+	Epoch uint64 `json:"epoch"`
+	Root  string `json:"root"`
+}
+
+type Eth1Data struct {
+	DepositRoot  string `json:"deposit_root"`
+	DepositCount uint64 `json:"deposit_count"`
+	BlockHash    string `json:"block_hash"`
+}
+
+// State 场景里用得到的信标状态字段；其余字段保持原始 JSON
+type State struct {
+	Slot                         uint64          `json:"slot"`
+	Eth1DepositIndex             uint64          `json:"eth1_deposit_index"`
+	Validators                   []Validator     `json:"validators"`
+	Balances                     []uint64        `json:"balances"`
+	NextWithdrawalIndex          uint64          `json:"next_withdrawal_index"`
+	NextWithdrawalValidatorIndex uint64          `json:"next_withdrawal_validator_index"`
+	PendingPartialWithdrawals    json.RawMessage `json:"pending_partial_withdrawals"`
+	EarliestExitEpoch            uint64          `json:"earliest_exit_epoch"`
+	ExitBalanceToConsume         uint64          `json:"exit_balance_to_consume"`
+	Eth1Data                     Eth1Data        `json:"eth1_data"`
+	PreviousJustifiedCheckpoint  Checkpoint      `json:"previous_justified_checkpoint"`
+	CurrentJustifiedCheckpoint   Checkpoint      `json:"current_justified_checkpoint"`
+	FinalizedCheckpoint          Checkpoint      `json:"finalized_checkpoint"`
+	// N42 扩展：本纪元已提交见证（参与标记）的验证者下标
+	EpochAttesterIndexes []uint64 `json:"epoch_attester_indexes"`
+	// 上一/当前/下一纪元的委员会缓存，用于推算见证职责
+	CommitteeCaches []CommitteeCache `json:"committee_caches"`
+
+	indexOnce sync.Once
+	index     map[string]int // Index 的缓存
+}
+
+// Parse 解析信标状态 JSON
+func Parse(raw json.RawMessage) (*State, error) {
+	var s State
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("parse beacon state: %w", err)
+	}
+	return &s, nil
+}
+
+// Epoch 状态所在纪元
+func (s *State) Epoch(slotsPerEpoch uint64) uint64 {
+	if slotsPerEpoch == 0 {
+		slotsPerEpoch = DefaultSlotsPerEpoch
+	}
+	return s.Slot / slotsPerEpoch
+}
+
+// Index 按公钥（小写 0x hex）查找验证者下标。表在首次调用时建立并缓存，之后的调用（含并发）不再扫描 validators；
+// 调用方不得修改返回的表，解码完成后也不应再改动 Validators
+func (s *State) Index() map[string]int {
+	s.indexOnce.Do(func() {
+		s.index = make(map[string]int, len(s.Validators))
+		for i, v := range s.Validators {
+			s.index[NormPubkey(v.Pubkey)] = i
+		}
+	})
+	return s.index
+}
+
+// ActiveCount epoch 时的激活验证者数量与总有效余额（gwei）
+func (s *State) ActiveCount(epoch uint64) (count int, totalBalance uint64) {
+	for _, v := range s.Validators {
+		if v.IsActive(epoch) {
+			count++
+			totalBalance += v.EffectiveBalance
+		}
+	}
+	return count, totalBalance
+}
+
+// PendingPartialWithdrawal 与 pending_partial_withdrawals 元素同形（Electra）
+type PendingPartialWithdrawal struct {
+	ValidatorIndex    uint64 `json:"validator_index"`
+	Amount            uint64 `json:"amount"` // gwei
+	WithdrawableEpoch uint64 `json:"withdrawable_epoch"`
+}
+
+// PendingPartials 解析 pending_partial_withdrawals；状态中没有该字段（Electra 之前）时返回空
+func (s *State) PendingPartials() ([]PendingPartialWithdrawal, error) {
+	if len(s.PendingPartialWithdrawals) == 0 || string(s.PendingPartialWithdrawals) == "null" {
+		return nil, nil
+	}
+	var out []PendingPartialWithdrawal
+	if err := json.Unmarshal(s.PendingPartialWithdrawals, &out); err != nil {
+		return nil, fmt.Errorf("parse pending_partial_withdrawals: %w", err)
+	}
+	return out, nil
+}
+
+// PendingBalanceToWithdraw 验证者排队中的部分提款总额（gwei），即规范的 get_pending_balance_to_withdraw
+func (s *State) PendingBalanceToWithdraw(index uint64) (uint64, error) {
+	pending, err := s.PendingPartials()
+	if err != nil {
+		return 0, err
+	}
+	var sum uint64
+	for _, w := range pending {
+		if w.ValidatorIndex == index {
+			sum += w.Amount
+		}
+	}
+	return sum, nil
+}
+
+// NormPubkey 统一公钥格式：小写、带 0x
+func NormPubkey(pk string) string {
+	return hexutil.Normalize(pk)
+}
+
+// FetchLatest 取执行层 latest 块对应的信标状态（流式解析，只保留 State 中的字段）
+func FetchLatest(ctx context.Context, r beaconext.BeaconReader) (*State, error) {
+	var s State
+	if err := StreamLatest(ctx, r, s.DecodeField); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FetchAt 取执行层区块 eth1Hash 对应的信标状态（流式解析，只保留 State 中的字段）
+func FetchAt(ctx context.Context, r beaconext.BeaconReader, eth1Hash string) (*State, error) {
+	var s State
+	if err := StreamAt(ctx, r, eth1Hash, s.DecodeField); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// BlockEth1Data 从信标区块 JSON 中取 eth1_data；兼容 data.message / message / 顶层包装（见 ParseBlock）
+func BlockEth1Data(raw json.RawMessage) (*Eth1Data, bool) {
+	b, err := ParseBlock(raw)
+	if err != nil || b.Eth1Data == nil {
+		return nil, false
+	}
+	return b.Eth1Data, true
+}
+
+// Eth1DataAt 执行层区块 eth1Hash 对应的信标区块所承诺的 Eth1Data，同时返回信标区块哈希；
+// 区块 JSON 里没有 eth1_data 时退回该区块对应信标状态中的 eth1_data
+func Eth1DataAt(ctx context.Context, r beaconext.BeaconReader, eth1Hash string) (*Eth1Data, string, error) {
+	blk, beaconHash, err := FetchBlockAt(ctx, r, eth1Hash)
+	if err != nil {
+		return nil, beaconHash, err
+	}
+	if blk.Eth1Data != nil {
+		return blk.Eth1Data, beaconHash, nil
+	}
+	var s State
+	if err := StreamAt(ctx, r, eth1Hash, s.DecodeField); err != nil {
+		return nil, beaconHash, err
+	}
+	return &s.Eth1Data, beaconHash, nil
+}
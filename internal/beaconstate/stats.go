@@ -0,0 +1,197 @@
+package beaconstate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// 验证者状态（与 beacon API /eth/v1/beacon/states/{id}/validators 的 status 一致）
+const (
+	StatusPendingInitialized = "pending_initialized"
+	StatusPendingQueued      = "pending_queued"
+	StatusActiveOngoing      = "active_ongoing"
+	StatusActiveExiting      = "active_exiting"
+	StatusActiveSlashed      = "active_slashed"
+	StatusExitedUnslashed    = "exited_unslashed"
+	StatusExitedSlashed      = "exited_slashed"
+	StatusWithdrawalPossible = "withdrawal_possible"
+	StatusWithdrawalDone     = "withdrawal_done"
+)
+
+// statusOrder 输出顺序
+var statusOrder = []string{
+	StatusPendingInitialized, StatusPendingQueued,
+	StatusActiveOngoing, StatusActiveExiting, StatusActiveSlashed,
+	StatusExitedUnslashed, StatusExitedSlashed,
+	StatusWithdrawalPossible, StatusWithdrawalDone,
+}
+
+// Statuses 全部状态，按生命周期先后排列
+func Statuses() []string {
+	return append([]string(nil), statusOrder...)
+}
+
+// Status 验证者在 epoch 时的状态；balance 为当前余额（gwei）
+func (v Validator) Status(epoch, balance uint64) string {
+	switch {
+	case epoch < v.ActivationEpoch:
+		if v.ActivationEligibilityEpoch == FarFutureEpoch {
+			return StatusPendingInitialized
+		}
+		return StatusPendingQueued
+	case epoch < v.ExitEpoch:
+		if v.Slashed {
+			return StatusActiveSlashed
+		}
+		if v.ExitEpoch == FarFutureEpoch {
+			return StatusActiveOngoing
+		}
+		return StatusActiveExiting
+	case epoch < v.WithdrawableEpoch:
+		if v.Slashed {
+			return StatusExitedSlashed
+		}
+		return StatusExitedUnslashed
+	default:
+		if balance == 0 {
+			return StatusWithdrawalDone
+		}
+		return StatusWithdrawalPossible
+	}
+}
+
+// CredentialType 提款凭证前缀："0x00" | "0x01" | "0x02" | 其他原样
+func (v Validator) CredentialType() string {
+	wc := strings.ToLower(strings.TrimPrefix(v.WithdrawalCredentials, "0x"))
+	if len(wc) < 2 {
+		return "unknown"
+	}
+	return "0x" + wc[:2]
+}
+
+// Histogram 按 ETH 分桶的余额分布
+type Histogram struct {
+	Edges  []uint64 `json:"edges_eth"` // 桶的下界（ETH），最后一个桶无上界
+	Counts []int    `json:"counts"`
+}
+
+// defaultBalanceEdges 余额分桶（ETH）：覆盖 0、部分提款后、32 附近与 0x02 的大余额
+var defaultBalanceEdges = []uint64{0, 1, 16, 31, 32, 33, 64, 512, 2048}
+
+func newHistogram(edges []uint64) *Histogram {
+	return &Histogram{Edges: edges, Counts: make([]int, len(edges))}
+}
+
+func (h *Histogram) add(gwei uint64) {
+	eth := float64(gwei) / 1e9
+	i := sort.Search(len(h.Edges), func(i int) bool { return float64(h.Edges[i]) > eth }) - 1
+	if i < 0 {
+		i = 0
+	}
+	h.Counts[i]++
+}
+
+// Print 以条形图输出
+func (h *Histogram) Print(w io.Writer, label string) {
+	fmt.Fprintf(w, "%s:\n", label)
+	total := 0
+	for _, c := range h.Counts {
+		total += c
+	}
+	for i, c := range h.Counts {
+		var rng string
+		if i+1 < len(h.Edges) {
+			rng = fmt.Sprintf("[%d, %d)", h.Edges[i], h.Edges[i+1])
+		} else {
+			rng = fmt.Sprintf("[%d, +∞)", h.Edges[i])
+		}
+		bar := ""
+		if total > 0 {
+			bar = strings.Repeat("█", (c*40+total-1)/total)
+		}
+		fmt.Fprintf(w, "  %-14s ETH %8d %s\n", rng, c, bar)
+	}
+}
+
+// Stats 信标状态概览
+type Stats struct {
+	Slot             uint64         `json:"slot"`
+	Epoch            uint64         `json:"epoch"`
+	Validators       int            `json:"validators"`
+	ByStatus         map[string]int `json:"by_status"`
+	ByCredentialType map[string]int `json:"by_credential_type"`
+	Slashed          int            `json:"slashed"`
+	TotalBalance     uint64         `json:"total_balance_gwei"`
+	TotalEffective   uint64         `json:"total_effective_balance_gwei"`
+	ActiveEffective  uint64         `json:"active_effective_balance_gwei"`
+	Balance          *Histogram     `json:"balance_histogram"`
+	Effective        *Histogram     `json:"effective_balance_histogram"`
+}
+
+// ComputeStats 统计验证者状态、余额分布、提款凭证类型与罚没数
+func ComputeStats(s *State, slotsPerEpoch uint64) *Stats {
+	epoch := s.Epoch(slotsPerEpoch)
+	st := &Stats{
+		Slot:             s.Slot,
+		Epoch:            epoch,
+		Validators:       len(s.Validators),
+		ByStatus:         map[string]int{},
+		ByCredentialType: map[string]int{},
+		Balance:          newHistogram(defaultBalanceEdges),
+		Effective:        newHistogram(defaultBalanceEdges),
+	}
+	for i, v := range s.Validators {
+		var bal uint64
+		if i < len(s.Balances) {
+			bal = s.Balances[i]
+		}
+		st.ByStatus[v.Status(epoch, bal)]++
+		st.ByCredentialType[v.CredentialType()]++
+		if v.Slashed {
+			st.Slashed++
+		}
+		st.TotalBalance += bal
+		st.TotalEffective += v.EffectiveBalance
+		if v.IsActive(epoch) {
+			st.ActiveEffective += v.EffectiveBalance
+		}
+		st.Balance.add(bal)
+		st.Effective.add(v.EffectiveBalance)
+	}
+	return st
+}
+
+// Print 输出概览
+func (st *Stats) Print(w io.Writer) {
+	fmt.Fprintf(w, "slot %d（epoch %d），验证者 %d 个，罚没 %d 个\n", st.Slot, st.Epoch, st.Validators, st.Slashed)
+	fmt.Fprintf(w, "总余额 %s ETH，总有效余额 %s ETH（激活中 %s ETH）\n",
+		gweiToETH(st.TotalBalance), gweiToETH(st.TotalEffective), gweiToETH(st.ActiveEffective))
+
+	fmt.Fprintln(w, "\n按状态:")
+	for _, k := range statusOrder {
+		if n := st.ByStatus[k]; n > 0 {
+			fmt.Fprintf(w, "  %-22s %8d\n", k, n)
+		}
+	}
+
+	fmt.Fprintln(w, "\n按提款凭证类型:")
+	types := make([]string, 0, len(st.ByCredentialType))
+	for k := range st.ByCredentialType {
+		types = append(types, k)
+	}
+	sort.Strings(types)
+	for _, k := range types {
+		fmt.Fprintf(w, "  %-22s %8d\n", k, st.ByCredentialType[k])
+	}
+
+	fmt.Fprintln(w)
+	st.Balance.Print(w, "余额分布")
+	fmt.Fprintln(w)
+	st.Effective.Print(w, "有效余额分布")
+}
+
+func gweiToETH(g uint64) string {
+	return fmt.Sprintf("%d.%09d", g/1e9, g%1e9)
+}
@@ -0,0 +1,120 @@
+package beaconstate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"n42-test/internal/beaconext"
+)
+
+// DecodeField 把状态的一个顶层字段解码进 s；不是 State 中的字段返回 false（由调用方跳过）。
+// validators / balances 逐元素解码，不缓存整个数组的原始 JSON。
+func (s *State) DecodeField(key string, dec *json.Decoder) (bool, error) {
+	switch key {
+	case "validators":
+		s.Validators = s.Validators[:0]
+		return true, beaconext.EachElement(dec, func(dec *json.Decoder) error {
+			var v Validator
+			if err := dec.Decode(&v); err != nil {
+				return err
+			}
+			s.Validators = append(s.Validators, v)
+			return nil
+		})
+	case "balances":
+		s.Balances = s.Balances[:0]
+		return true, beaconext.EachElement(dec, func(dec *json.Decoder) error {
+			var b quantity
+			if err := dec.Decode(&b); err != nil {
+				return err
+			}
+			s.Balances = append(s.Balances, uint64(b))
+			return nil
+		})
+	}
+
+	// 整数字段宽松解码（数字或字符串）
+	var num *uint64
+	switch key {
+	case "slot":
+		num = &s.Slot
+	case "eth1_deposit_index":
+		num = &s.Eth1DepositIndex
+	case "next_withdrawal_index":
+		num = &s.NextWithdrawalIndex
+	case "next_withdrawal_validator_index":
+		num = &s.NextWithdrawalValidatorIndex
+	case "earliest_exit_epoch":
+		num = &s.EarliestExitEpoch
+	case "exit_balance_to_consume":
+		num = &s.ExitBalanceToConsume
+	}
+	if num != nil {
+		var q quantity
+		if err := dec.Decode(&q); err != nil {
+			return true, err
+		}
+		*num = uint64(q)
+		return true, nil
+	}
+
+	var target any
+	switch key {
+	case "pending_partial_withdrawals":
+		target = &s.PendingPartialWithdrawals
+	case "eth1_data":
+		target = &s.Eth1Data
+	case "previous_justified_checkpoint":
+		target = &s.PreviousJustifiedCheckpoint
+	case "current_justified_checkpoint":
+		target = &s.CurrentJustifiedCheckpoint
+	case "finalized_checkpoint":
+		target = &s.FinalizedCheckpoint
+	case "epoch_attester_indexes":
+		target = &s.EpochAttesterIndexes
+	case "committee_caches":
+		target = &s.CommitteeCaches
+	default:
+		return false, nil
+	}
+	return true, dec.Decode(target)
+}
+
+// ParseStream 从 r 流式解析状态 JSON，只保留 State 中的字段
+func ParseStream(r io.Reader) (*State, error) {
+	var s State
+	dec := json.NewDecoder(r)
+	if err := beaconext.WalkObject(dec, s.DecodeField); err != nil {
+		return nil, fmt.Errorf("parse beacon state: %w", err)
+	}
+	return &s, nil
+}
+
+// StreamLatest 取执行层 latest 块对应的信标状态，只把 visit 关心的字段交给它
+func StreamLatest(ctx context.Context, r beaconext.BeaconReader, visit beaconext.FieldVisitor) error {
+	blk, err := r.EthGetBlockByNumber(ctx, "latest", false)
+	if err != nil {
+		return fmt.Errorf("get latest block: %w", err)
+	}
+	return StreamAt(ctx, r, blk.Hash, visit)
+}
+
+// StreamAt 取执行层区块 eth1Hash 对应的信标状态，只把 visit 关心的字段交给它；
+// 节点客户端不支持流式读取时退回整体读取再逐字段回放。
+func StreamAt(ctx context.Context, r beaconext.BeaconReader, eth1Hash string, visit beaconext.FieldVisitor) error {
+	beaconHash, err := r.GetBeaconBlockHashByEth1Hash(ctx, eth1Hash)
+	if err != nil {
+		return fmt.Errorf("map eth1 hash -> beacon block hash: %w", err)
+	}
+	if st, ok := r.(beaconext.BeaconStateStreamer); ok {
+		return st.StreamBeaconStateByBeaconBlockHash(ctx, beaconHash, visit)
+	}
+	raw, err := r.GetBeaconStateByBeaconBlockHash(ctx, beaconHash)
+	if err != nil {
+		return fmt.Errorf("get beacon state by beacon block hash: %w", err)
+	}
+	return beaconext.WalkObject(json.NewDecoder(bytes.NewReader(raw)), visit)
+}
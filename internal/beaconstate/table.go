@@ -0,0 +1,122 @@
+package beaconstate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// ValidatorRow 验证者状态表的一行
+type ValidatorRow struct {
+	Index            int
+	Pubkey           string
+	Balance          uint64 // gwei
+	EffectiveBalance uint64 // gwei
+	Status           string
+	ActivationEpoch  uint64
+	ExitEpoch        uint64
+}
+
+// 状态表的排序键
+const (
+	SortIndex      = "index"
+	SortBalance    = "balance"
+	SortEffective  = "effective"
+	SortStatus     = "status"
+	SortActivation = "activation"
+	SortExit       = "exit"
+)
+
+// SortKeys 支持的排序键
+func SortKeys() []string {
+	return []string{SortIndex, SortBalance, SortEffective, SortStatus, SortActivation, SortExit}
+}
+
+// ValidatorRows 按下标列出每个验证者在 epoch 时的状态
+func (s *State) ValidatorRows(epoch uint64) []ValidatorRow {
+	rows := make([]ValidatorRow, len(s.Validators))
+	for i, v := range s.Validators {
+		var bal uint64
+		if i < len(s.Balances) {
+			bal = s.Balances[i]
+		}
+		rows[i] = ValidatorRow{
+			Index: i, Pubkey: NormPubkey(v.Pubkey), Balance: bal, EffectiveBalance: v.EffectiveBalance,
+			Status: v.Status(epoch, bal), ActivationEpoch: v.ActivationEpoch, ExitEpoch: v.ExitEpoch,
+		}
+	}
+	return rows
+}
+
+// SortValidatorRows 按 key 稳定排序（相同时按下标）；desc 为降序
+func SortValidatorRows(rows []ValidatorRow, key string, desc bool) error {
+	var less func(a, b ValidatorRow) bool
+	switch key {
+	case SortIndex:
+		less = func(a, b ValidatorRow) bool { return a.Index < b.Index }
+	case SortBalance:
+		less = func(a, b ValidatorRow) bool { return a.Balance < b.Balance }
+	case SortEffective:
+		less = func(a, b ValidatorRow) bool { return a.EffectiveBalance < b.EffectiveBalance }
+	case SortStatus:
+		less = func(a, b ValidatorRow) bool { return statusRank(a.Status) < statusRank(b.Status) }
+	case SortActivation:
+		less = func(a, b ValidatorRow) bool { return a.ActivationEpoch < b.ActivationEpoch }
+	case SortExit:
+		less = func(a, b ValidatorRow) bool { return a.ExitEpoch < b.ExitEpoch }
+	default:
+		return fmt.Errorf("unknown sort key %q (known: %s)", key, strings.Join(SortKeys(), "|"))
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if desc {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return rows[i].Index < rows[j].Index
+	})
+	return nil
+}
+
+// statusRank 状态在生命周期中的先后（statusOrder 的下标）
+func statusRank(s string) int {
+	for i, k := range statusOrder {
+		if k == s {
+			return i
+		}
+	}
+	return len(statusOrder)
+}
+
+// PrintValidatorTable 以表格输出；公钥截短，余额为 ETH，未设置的纪元显示为 -
+func PrintValidatorTable(w io.Writer, rows []ValidatorRow) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tPUBKEY\tBALANCE\tEFFECTIVE\tSTATUS\tACTIVATION\tEXIT")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Index, shortPubkey(r.Pubkey),
+			gweiToETH(r.Balance), gweiToETH(r.EffectiveBalance), r.Status, epochOrDash(r.ActivationEpoch), epochOrDash(r.ExitEpoch))
+	}
+	tw.Flush()
+}
+
+func shortPubkey(s string) string {
+	if len(s) <= 14 {
+		return s
+	}
+	return s[:10] + "…" + s[len(s)-4:]
+}
+
+func epochOrDash(e uint64) string {
+	if e == FarFutureEpoch {
+		return "-"
+	}
+	return strconv.FormatUint(e, 10)
+}
@@ -0,0 +1,319 @@
+// EIP-4844 blob 交易（type 3）的构造、发送与上链校验：用随机的占位 blob 生成 sidecar，
+// 让开发链上出现携带 blob 的区块，覆盖见证路径与节点对 blob 区块/收据的处理。
+package blobtx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+
+	"n42-test/internal/capability"
+	"n42-test/internal/keys"
+	"n42-test/internal/rpcpool"
+)
+
+// MaxBlobsPerTx 单笔交易最多携带的 blob 数（受区块 blob gas 上限约束）
+const MaxBlobsPerTx = params.MaxBlobGasPerBlock / params.BlobTxBlobGasPerBlob
+
+// DummyBlobs 生成 n 个随机 blob；每个 32 字节域元素的首字节置 0，保证小于 BLS12-381 的模数
+func DummyBlobs(n int, rng *rand.Rand) []kzg4844.Blob {
+	blobs := make([]kzg4844.Blob, n)
+	for i := range blobs {
+		rng.Read(blobs[i][:])
+		for j := 0; j < len(blobs[i]); j += 32 {
+			blobs[i][j] = 0
+		}
+	}
+	return blobs
+}
+
+// NewSidecar 为 blobs 计算 KZG 承诺与证明
+func NewSidecar(blobs []kzg4844.Blob) (*types.BlobTxSidecar, error) {
+	sc := &types.BlobTxSidecar{Blobs: blobs}
+	for i := range blobs {
+		c, err := kzg4844.BlobToCommitment(&blobs[i])
+		if err != nil {
+			return nil, fmt.Errorf("blob %d commitment: %w", i, err)
+		}
+		p, err := kzg4844.ComputeBlobProof(&blobs[i], c)
+		if err != nil {
+			return nil, fmt.Errorf("blob %d proof: %w", i, err)
+		}
+		sc.Commitments = append(sc.Commitments, c)
+		sc.Proofs = append(sc.Proofs, p)
+	}
+	return sc, nil
+}
+
+// Params 一笔 blob 交易的参数
+type Params struct {
+	To    common.Address // blob 交易不能创建合约，必须有接收方
+	Value *big.Int
+	Data  []byte
+	Blobs []kzg4844.Blob
+
+	// 可选：nonce（为 -1 表示自动读取）
+	Nonce int64
+	// 可选：费用上限（nil 时按节点建议：blob 费用取当前 blob 基础费的 2 倍）
+	MaxPriorityFeePerGas *big.Int
+	MaxFeePerGas         *big.Int
+	MaxFeePerBlobGas     *big.Int
+}
+
+// Result 发送结果；Receipt 仅在等待上链时有效
+type Result struct {
+	TxHash     common.Hash
+	Nonce      uint64
+	BlobHashes []common.Hash
+	BlobFeeCap *big.Int
+	Receipt    *types.Receipt
+}
+
+// Sender 用一个账户发送 blob 交易
+type Sender struct {
+	cli     *ethclient.Client
+	chainID *big.Int
+	priv    *ecdsa.PrivateKey
+	from    common.Address
+	caps    *capability.Matrix
+}
+
+// NewSender 连接 RPC 并解析发送账户私钥（hex / keystore / 助记词，见 internal/keys）
+func NewSender(ctx context.Context, rpcURL, keySpec string) (*Sender, error) {
+	priv, err := keys.ParseECDSA(keySpec)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key failed: %w", err)
+	}
+	cli, err := rpcpool.DialEth(ctx, rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("dial rpc failed: %w", err)
+	}
+	chainID, err := cli.ChainID(ctx)
+	if err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("get chain id failed: %w", err)
+	}
+	return &Sender{
+		cli:     cli,
+		chainID: chainID,
+		priv:    priv,
+		from:    crypto.PubkeyToAddress(priv.PublicKey),
+		caps:    capability.For(ctx, rpcURL),
+	}, nil
+}
+
+func (s *Sender) Close() { s.cli.Close() }
+
+// From 发送账户地址
+func (s *Sender) From() common.Address { return s.from }
+
+// Client 底层 RPC 连接（校验收据等使用）
+func (s *Sender) Client() *ethclient.Client { return s.cli }
+
+// Send 构造 sidecar、签名并发送；wait 为 true 时等待回执
+func (s *Sender) Send(ctx context.Context, p *Params, wait bool) (*Result, error) {
+	if len(p.Blobs) == 0 || len(p.Blobs) > MaxBlobsPerTx {
+		return nil, fmt.Errorf("blob count must be 1..%d, got %d", MaxBlobsPerTx, len(p.Blobs))
+	}
+	sc, err := NewSidecar(p.Blobs)
+	if err != nil {
+		return nil, err
+	}
+
+	tp, err := capability.FetchTxParams(ctx, s.cli, s.caps, s.from)
+	if err != nil {
+		return nil, fmt.Errorf("get nonce failed: %w", err)
+	}
+	nonce := tp.Nonce
+	if p.Nonce >= 0 {
+		nonce = uint64(p.Nonce)
+	}
+	tip, feeCap, blobFeeCap, err := s.fees(ctx, p, tp)
+	if err != nil {
+		return nil, err
+	}
+
+	value := p.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	txData := &types.BlobTx{
+		ChainID:    uint256.MustFromBig(s.chainID),
+		Nonce:      nonce,
+		GasTipCap:  uint256.MustFromBig(tip),
+		GasFeeCap:  uint256.MustFromBig(feeCap),
+		Gas:        params.TxGas + uint64(len(p.Data))*params.TxDataNonZeroGasEIP2028,
+		To:         p.To,
+		Value:      uint256.MustFromBig(value),
+		Data:       p.Data,
+		BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+		BlobHashes: sc.BlobHashes(),
+		Sidecar:    sc,
+	}
+	signed, err := types.SignNewTx(s.priv, types.NewCancunSigner(s.chainID), txData)
+	if err != nil {
+		return nil, fmt.Errorf("sign tx failed: %w", err)
+	}
+	if err := s.cli.SendTransaction(ctx, signed); err != nil {
+		return nil, fmt.Errorf("send tx failed: %w", err)
+	}
+
+	res := &Result{TxHash: signed.Hash(), Nonce: nonce, BlobHashes: txData.BlobHashes, BlobFeeCap: blobFeeCap}
+	if !wait {
+		return res, nil
+	}
+	rcpt, err := waitMined(ctx, s.cli, signed.Hash())
+	if err != nil {
+		return res, fmt.Errorf("tx sent but waitMined failed: %w", err)
+	}
+	res.Receipt = rcpt
+	return res, nil
+}
+
+// fees 手动指定的直接使用；否则小费取节点建议，费用上限 20 倍小费兜底，blob 费用取当前 blob 基础费的 2 倍
+func (s *Sender) fees(ctx context.Context, p *Params, tp *capability.TxParams) (tip, feeCap, blobFeeCap *big.Int, err error) {
+	tip, feeCap, blobFeeCap = p.MaxPriorityFeePerGas, p.MaxFeePerGas, p.MaxFeePerBlobGas
+	if tip == nil {
+		if tp.TipCap == nil {
+			return nil, nil, nil, errors.New("node does not support EIP-1559; blob transactions need it")
+		}
+		tip = tp.TipCap
+	}
+	if feeCap == nil {
+		feeCap = new(big.Int).Mul(tip, big.NewInt(20))
+		if tp.BaseFee != nil {
+			if floor := new(big.Int).Add(new(big.Int).Mul(tp.BaseFee, big.NewInt(2)), tip); feeCap.Cmp(floor) < 0 {
+				feeCap = floor
+			}
+		}
+	}
+	if blobFeeCap == nil {
+		base, err := s.BlobBaseFee(ctx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		blobFeeCap = new(big.Int).Mul(base, big.NewInt(2))
+	}
+	return tip, feeCap, blobFeeCap, nil
+}
+
+// BlobBaseFee 由最新区块头的 excessBlobGas 计算当前 blob 基础费
+func (s *Sender) BlobBaseFee(ctx context.Context) (*big.Int, error) {
+	h, err := s.cli.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get latest header: %w", err)
+	}
+	if h.ExcessBlobGas == nil {
+		return nil, errors.New("latest header has no excessBlobGas (Cancun not active?)")
+	}
+	return eip4844.CalcBlobFee(*h.ExcessBlobGas), nil
+}
+
+// Check 对已上链 blob 交易的校验结果
+type Check struct {
+	Included     bool
+	BlockNumber  uint64
+	BlobGasUsed  uint64
+	BlobGasPrice *big.Int
+	Problems     []string
+}
+
+// OK 没有发现问题
+func (c *Check) OK() bool { return c.Included && len(c.Problems) == 0 }
+
+// Verify 校验回执与所在区块对 blob 交易的处理：收据类型为 3，blobGasUsed 与 blob 数一致，
+// blobGasPrice 不超过上限，节点返回的交易带相同的 blob 版本化哈希，区块头的 blobGasUsed 计入了本交易。
+func Verify(ctx context.Context, cli *ethclient.Client, res *Result) (*Check, error) {
+	c := &Check{}
+	rcpt := res.Receipt
+	if rcpt == nil {
+		var err error
+		if rcpt, err = cli.TransactionReceipt(ctx, res.TxHash); err != nil {
+			return nil, fmt.Errorf("get receipt: %w", err)
+		}
+	}
+	c.Included = true
+	c.BlockNumber = rcpt.BlockNumber.Uint64()
+	c.BlobGasUsed = rcpt.BlobGasUsed
+	c.BlobGasPrice = rcpt.BlobGasPrice
+
+	problem := func(format string, args ...any) { c.Problems = append(c.Problems, fmt.Sprintf(format, args...)) }
+	if rcpt.Status != types.ReceiptStatusSuccessful {
+		problem("status=%d", rcpt.Status)
+	}
+	if rcpt.Type != types.BlobTxType {
+		problem("receipt type=%d, want %d", rcpt.Type, types.BlobTxType)
+	}
+	if want := uint64(len(res.BlobHashes)) * params.BlobTxBlobGasPerBlob; rcpt.BlobGasUsed != want {
+		problem("receipt blobGasUsed=%d, want %d", rcpt.BlobGasUsed, want)
+	}
+	switch {
+	case rcpt.BlobGasPrice == nil:
+		problem("receipt has no blobGasPrice")
+	case res.BlobFeeCap != nil && rcpt.BlobGasPrice.Cmp(res.BlobFeeCap) > 0:
+		problem("blobGasPrice %s above cap %s", rcpt.BlobGasPrice, res.BlobFeeCap)
+	}
+
+	tx, _, err := cli.TransactionByHash(ctx, res.TxHash)
+	if err != nil {
+		problem("get tx: %v", err)
+	} else if !equalHashes(tx.BlobHashes(), res.BlobHashes) {
+		problem("node returned blob hashes %v, sent %v", tx.BlobHashes(), res.BlobHashes)
+	}
+
+	h, err := cli.HeaderByHash(ctx, rcpt.BlockHash)
+	switch {
+	case err != nil:
+		problem("get header: %v", err)
+	case h.BlobGasUsed == nil:
+		problem("block %d header has no blobGasUsed", c.BlockNumber)
+	case *h.BlobGasUsed < rcpt.BlobGasUsed:
+		problem("block %d header blobGasUsed=%d < tx blobGasUsed=%d", c.BlockNumber, *h.BlobGasUsed, rcpt.BlobGasUsed)
+	}
+	return c, nil
+}
+
+func equalHashes(a, b []common.Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func waitMined(ctx context.Context, cli *ethclient.Client, txHash common.Hash) (*types.Receipt, error) {
+	t := time.NewTicker(2 * time.Second)
+	defer t.Stop()
+	timeout := time.After(120 * time.Second) // 2 分钟兜底
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, fmt.Errorf("timeout waiting for receipt: %s", txHash.Hex())
+		case <-t.C:
+			rcpt, err := cli.TransactionReceipt(ctx, txHash)
+			if err == nil && rcpt != nil {
+				return rcpt, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// BLS 公共工具：库初始化、ETH mode、私钥解析、签名/验签与序列化。
+// deposit 等包统一从这里取 BLS 能力，避免各自初始化导致行为分叉。
+package blsutil
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/herumi/bls-eth-go-binary/bls"
+)
+
+var initOnce sync.Once
+
+// EnsureInit 在进程内只初始化一次 BLS 库
+func EnsureInit() {
+	initOnce.Do(func() {
+		// Init 默认即为 EthModeLatest；其它模式见 SetDefaultKeyOptions
+		bls.Init(bls.BLS12_381)
+	})
+}
+
+var ethModes = map[string]int{
+	"latest":  bls.EthModeLatest,
+	"draft07": bls.EthModeDraft07,
+	"draft06": bls.EthModeDraft06,
+	"draft05": bls.EthModeDraft05,
+	"old":     bls.EthModeOld,
+}
+
+var (
+	ethModeMu      sync.Mutex
+	currentETHMode = bls.EthModeLatest // bls.Init 之后的库默认值
+)
+
+// ApplyETHMode 初始化 BLS 库并切换到指定 ETH mode（空字符串保持当前模式）。
+// ETH mode 在 BLS 库里是全局状态，切换后对整个进程生效。
+func ApplyETHMode(name string) error {
+	EnsureInit()
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	mode, ok := ethModes[name]
+	if !ok {
+		return fmt.Errorf("unknown BLS ETH mode %q (latest|draft07|draft06|draft05|old)", name)
+	}
+	ethModeMu.Lock()
+	defer ethModeMu.Unlock()
+	if mode == currentETHMode {
+		return nil
+	}
+	if err := bls.SetETHmode(mode); err != nil {
+		return fmt.Errorf("set BLS ETH mode %s: %w", name, err)
+	}
+	currentETHMode = mode
+	return nil
+}
@@ -0,0 +1,107 @@
+package blsutil
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/herumi/bls-eth-go-binary/bls"
+
+	"n42-test/internal/keys"
+	"n42-test/internal/netprofile"
+)
+
+// KeyOptions 控制 BLS 私钥的解析方式
+type KeyOptions struct {
+	// 私钥十六进制的字节序："be"（默认）或 "le"
+	Endian string
+	// ETH mode："latest"（默认）| "draft07" | "draft06" | "draft05" | "old"
+	ETHMode string
+}
+
+// KeyOptionsFromProfile 取配置档中的 BLS 选项
+func KeyOptionsFromProfile(p netprofile.Profile) KeyOptions {
+	return KeyOptions{Endian: p.BLSKeyEndian, ETHMode: p.BLSETHMode}
+}
+
+var (
+	defaultOptsMu sync.RWMutex
+	defaultOpts   KeyOptions // 默认：大端 + 库默认的 ETH mode
+)
+
+// SetDefaultKeyOptions 设置进程内默认的私钥选项（deposit.ComputeDepositSignatureAndRoot 等使用）。
+func SetDefaultKeyOptions(opts KeyOptions) error {
+	if _, err := parseEndian(opts.Endian); err != nil {
+		return err
+	}
+	if err := ApplyETHMode(opts.ETHMode); err != nil {
+		return err
+	}
+	defaultOptsMu.Lock()
+	defaultOpts = opts
+	defaultOptsMu.Unlock()
+	return nil
+}
+
+// DefaultKeyOptions 当前进程内默认的私钥选项
+func DefaultKeyOptions() KeyOptions {
+	defaultOptsMu.RLock()
+	defer defaultOptsMu.RUnlock()
+	return defaultOpts
+}
+
+// LoadSecretKey 按选项解析 BLS 私钥：十六进制（0x 前缀可有可无，"le:" / "be:" 前缀覆盖 opts.Endian）
+// 或 EIP-2335 keystore（内联 JSON 或文件路径），格式识别见 internal/keys
+func LoadSecretKey(skHex string, opts KeyOptions) (*bls.SecretKey, error) {
+	if err := ApplyETHMode(opts.ETHMode); err != nil {
+		return nil, err
+	}
+	defaultLE, err := parseEndian(opts.Endian)
+	if err != nil {
+		return nil, err
+	}
+	raw, littleEndian, err := keys.ParseBLSSecret(skHex, defaultLE)
+	if err != nil {
+		return nil, err
+	}
+
+	var sk bls.SecretKey
+	if littleEndian {
+		if err := sk.SetLittleEndian(raw); err != nil {
+			return nil, fmt.Errorf("set BLS secret key (le) failed: %w", err)
+		}
+	} else if err := sk.SetHexString(hex.EncodeToString(raw)); err != nil {
+		return nil, fmt.Errorf("set BLS secret key failed: %w", err)
+	}
+	return &sk, nil
+}
+
+// DerivePublicKeyHex 由私钥推导 48 字节压缩公钥（0x 前缀）
+func DerivePublicKeyHex(skHex string, opts KeyOptions) (string, error) {
+	sk, err := LoadSecretKey(skHex, opts)
+	if err != nil {
+		return "", err
+	}
+	return PublicKeyHex(sk.GetPublicKey()), nil
+}
+
+func parseEndian(s string) (littleEndian bool, err error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "be", "big":
+		return false, nil
+	case "le", "little":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown BLS key endian %q (be|le)", s)
+	}
+}
+
+// SecretKeyBytes 私钥的 32 字节大端序列化（EIP-2335 keystore 的存储格式）
+func SecretKeyBytes(skHex string, opts KeyOptions) ([]byte, error) {
+	sk, err := LoadSecretKey(skHex, opts)
+	if err != nil {
+		return nil, err
+	}
+	return sk.Serialize(), nil
+}
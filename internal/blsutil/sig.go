@@ -0,0 +1,76 @@
+package blsutil
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/herumi/bls-eth-go-binary/bls"
+
+	"n42-test/internal/hexutil"
+)
+
+const (
+	PublicKeyLen = 48 // 压缩 G1 公钥
+	SignatureLen = 96 // 压缩 G2 签名
+)
+
+// Sign 对消息（通常是 32 字节 signing_root）签名
+func Sign(sk *bls.SecretKey, msg []byte) *bls.Sign {
+	return sk.SignByte(msg)
+}
+
+// PublicKeyHex 公钥序列化为 0x 前缀十六进制
+func PublicKeyHex(pk *bls.PublicKey) string {
+	return "0x" + hex.EncodeToString(pk.Serialize())
+}
+
+// SignatureHex 签名序列化为 0x 前缀十六进制
+func SignatureHex(sig *bls.Sign) string {
+	return "0x" + hex.EncodeToString(sig.Serialize())
+}
+
+// ParsePublicKey 解析 48 字节压缩公钥（十六进制，0x 可选）
+func ParsePublicKey(s string) (*bls.PublicKey, error) {
+	EnsureInit()
+	b, err := hexutil.DecodeFixed(s, PublicKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("pubkey: %w", err)
+	}
+	var pk bls.PublicKey
+	if err := pk.Deserialize(b); err != nil {
+		return nil, fmt.Errorf("pubkey: deserialize failed: %w", err)
+	}
+	return &pk, nil
+}
+
+// ParseSignature 解析 96 字节压缩签名（十六进制，0x 可选）
+func ParseSignature(s string) (*bls.Sign, error) {
+	EnsureInit()
+	b, err := hexutil.DecodeFixed(s, SignatureLen)
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+	var sig bls.Sign
+	if err := sig.Deserialize(b); err != nil {
+		return nil, fmt.Errorf("signature: deserialize failed: %w", err)
+	}
+	return &sig, nil
+}
+
+// Verify 验证 sig 是否为 pk 对 msg 的签名
+func Verify(pk *bls.PublicKey, msg []byte, sig *bls.Sign) bool {
+	return sig.VerifyByte(pk, msg)
+}
+
+// VerifyHex 十六进制入参版本的 Verify；公钥/签名无法解析时返回 error
+func VerifyHex(pubkeyHex string, msg []byte, sigHex string) (bool, error) {
+	pk, err := ParsePublicKey(pubkeyHex)
+	if err != nil {
+		return false, err
+	}
+	sig, err := ParseSignature(sigHex)
+	if err != nil {
+		return false, err
+	}
+	return Verify(pk, msg, sig), nil
+}
@@ -0,0 +1,68 @@
+// 构建信息：版本号/提交/构建时间。
+// 可在构建时注入：
+//
+//	go build -ldflags "-X n42-test/internal/buildinfo.Version=v0.3.0 -X n42-test/internal/buildinfo.Date=2025-09-01T00:00:00Z" ./cmd/...
+//
+// 未注入时从 Go 自带的 vcs 构建信息中回退读取（go build 在 git 仓库内会自动记录）。
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// 通过 -ldflags -X 注入
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Dirty     bool   `json:"dirty"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get 返回当前二进制的构建信息
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = s.Value
+			}
+		case "vcs.modified":
+			info.Dirty = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// ShortCommit 提交哈希前 12 位（未知时为 "unknown"）
+func (i Info) ShortCommit() string {
+	switch {
+	case i.Commit == "":
+		return "unknown"
+	case len(i.Commit) > 12:
+		return i.Commit[:12]
+	default:
+		return i.Commit
+	}
+}
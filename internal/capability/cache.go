@@ -0,0 +1,74 @@
+package capability
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultMaxAge 缓存的能力矩阵有效期
+const DefaultMaxAge = 24 * time.Hour
+
+// DefaultCachePath 默认缓存文件：$XDG_CACHE_HOME/n42-test/capabilities.json
+func DefaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "n42-test", "capabilities.json")
+}
+
+// 缓存文件内容：endpoint -> 能力矩阵
+type cacheFile map[string]*Matrix
+
+func readCache(path string) (cacheFile, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cacheFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := cacheFile{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse capability cache %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save 将能力矩阵写入缓存（同一 endpoint 覆盖旧记录）
+func Save(path string, m *Matrix) error {
+	c, err := readCache(path)
+	if err != nil {
+		// 缓存损坏时直接重建
+		c = cacheFile{}
+	}
+	c[m.Endpoint] = m
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Load 读取 endpoint 的缓存能力矩阵；不存在或超过 maxAge（<=0 表示不过期）时返回 false
+func Load(path, endpoint string, maxAge time.Duration) (*Matrix, bool) {
+	c, err := readCache(path)
+	if err != nil {
+		return nil, false
+	}
+	m, ok := c[endpoint]
+	if !ok || m == nil {
+		return nil, false
+	}
+	if maxAge > 0 && time.Since(m.ProbedAt) > maxAge {
+		return nil, false
+	}
+	return m, true
+}
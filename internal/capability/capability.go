@@ -0,0 +1,148 @@
+// 节点能力探测：检查连接的节点支持哪些 RPC 方法/特性，
+// 结果（能力矩阵）按 endpoint 缓存到本地，供其他命令查询。
+package capability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"n42-test/internal/rpcpool"
+)
+
+// BeaconExtMethods 探测的 consensusBeaconExt 方法
+var BeaconExtMethods = []string{
+	"consensusBeaconExt_get_beacon_block_hash_by_eth1_hash",
+	"consensusBeaconExt_get_beacon_block_by_hash",
+	"consensusBeaconExt_get_beacon_state_by_beacon_block_hash",
+}
+
+// Matrix 一个 endpoint 的能力矩阵
+type Matrix struct {
+	Endpoint      string          `json:"endpoint"`
+	ProbedAt      time.Time       `json:"probed_at"`
+	ClientVersion string          `json:"client_version,omitempty"`
+	ChainID       string          `json:"chain_id,omitempty"`
+	BeaconExt     map[string]bool `json:"beacon_ext"`
+	BlockReceipts bool            `json:"eth_getBlockReceipts"`
+	EIP1559       bool            `json:"eip1559"`
+
+	Batch           bool `json:"batch"`            // JSON-RPC 批量请求
+	TxPool          bool `json:"txpool"`           // txpool_status / txpool_content*
+	WSSubscriptions bool `json:"ws_subscriptions"` // eth_subscribe newHeads（仅 ws/wss endpoint 探测）
+}
+
+// Supports 查询某个 consensusBeaconExt 方法是否可用
+func (m *Matrix) Supports(method string) bool {
+	return m != nil && m.BeaconExt[method]
+}
+
+// 以下查询对 nil 安全：nil 视为“什么都不支持”，调用方走最保守的路径
+
+func (m *Matrix) HasBatch() bool           { return m != nil && m.Batch }
+func (m *Matrix) HasEIP1559() bool         { return m != nil && m.EIP1559 }
+func (m *Matrix) HasBlockReceipts() bool   { return m != nil && m.BlockReceipts }
+func (m *Matrix) HasTxPool() bool          { return m != nil && m.TxPool }
+func (m *Matrix) HasWSSubscriptions() bool { return m != nil && m.WSSubscriptions }
+
+// Probe 连接 endpoint 逐项探测能力
+func Probe(ctx context.Context, endpoint string) (*Matrix, error) {
+	cli, err := rpcpool.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("dial rpc: %w", err)
+	}
+	defer cli.Close()
+
+	m := &Matrix{
+		Endpoint:  endpoint,
+		ProbedAt:  time.Now().UTC(),
+		BeaconExt: map[string]bool{},
+	}
+
+	// 基本可达性：chainId 失败即认为节点不可用
+	var chainID hexutil.Big
+	if err := cli.CallContext(ctx, &chainID, "eth_chainId"); err != nil {
+		return nil, fmt.Errorf("eth_chainId: %w", err)
+	}
+	m.ChainID = chainID.ToInt().String()
+	_ = cli.CallContext(ctx, &m.ClientVersion, "web3_clientVersion")
+
+	// 取最新块：用于 EIP-1559 判断，以及作为其他方法的参数
+	var head struct {
+		Hash    common.Hash  `json:"hash"`
+		BaseFee *hexutil.Big `json:"baseFeePerGas"`
+	}
+	if err := cli.CallContext(ctx, &head, "eth_getBlockByNumber", "latest", false); err != nil {
+		return nil, fmt.Errorf("eth_getBlockByNumber: %w", err)
+	}
+	m.EIP1559 = head.BaseFee != nil
+
+	m.BlockReceipts = methodAvailable(ctx, cli, "eth_getBlockReceipts", "latest")
+	m.TxPool = methodAvailable(ctx, cli, "txpool_status")
+	for _, method := range BeaconExtMethods {
+		m.BeaconExt[method] = methodAvailable(ctx, cli, method, head.Hash.Hex())
+	}
+	m.Batch = probeBatch(ctx, cli)
+	if isWS(endpoint) {
+		m.WSSubscriptions = probeSubscribe(ctx, cli)
+	}
+	return m, nil
+}
+
+// probeBatch 发一个两条的批量请求，全部成功才认为支持
+func probeBatch(ctx context.Context, cli *rpc.Client) bool {
+	var a, b hexutil.Big
+	batch := []rpc.BatchElem{
+		{Method: "eth_chainId", Result: &a},
+		{Method: "eth_chainId", Result: &b},
+	}
+	if err := cli.BatchCallContext(ctx, batch); err != nil {
+		return false
+	}
+	for _, e := range batch {
+		if e.Error != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// probeSubscribe 订阅 newHeads 后立即取消
+func probeSubscribe(ctx context.Context, cli *rpc.Client) bool {
+	ch := make(chan map[string]any, 1)
+	sub, err := cli.EthSubscribe(ctx, ch, "newHeads")
+	if err != nil {
+		return false
+	}
+	sub.Unsubscribe()
+	return true
+}
+
+func isWS(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "ws://") || strings.HasPrefix(endpoint, "wss://")
+}
+
+// methodAvailable 调用一次方法：只有“方法不存在”才判为不支持，
+// 参数错误/查不到数据等业务错误说明方法本身是存在的。
+func methodAvailable(ctx context.Context, cli *rpc.Client, method string, args ...any) bool {
+	var raw any
+	err := cli.CallContext(ctx, &raw, method, args...)
+	return err == nil || !isMethodNotFound(err)
+}
+
+func isMethodNotFound(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "method not found") ||
+		strings.Contains(msg, "does not exist") ||
+		strings.Contains(msg, "not available")
+}
@@ -0,0 +1,78 @@
+package capability
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultProbeTimeout 单个 endpoint 探测的超时
+const DefaultProbeTimeout = 15 * time.Second
+
+// Detector 进程内按 endpoint 缓存能力矩阵：每个 endpoint 只探测一次，
+// 并发调用者共享同一次探测结果。优先复用 version --probe 写下的未过期文件缓存。
+type Detector struct {
+	// CachePath 文件缓存路径；为空不读写文件
+	CachePath string
+	// MaxAge 文件缓存有效期；<=0 使用 DefaultMaxAge
+	MaxAge time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*detectEntry
+}
+
+type detectEntry struct {
+	once sync.Once
+	m    *Matrix
+}
+
+var shared = &Detector{CachePath: DefaultCachePath()}
+
+// For 使用进程级共享 Detector 获取 endpoint 的能力矩阵
+func For(ctx context.Context, endpoint string) *Matrix {
+	return shared.Get(ctx, endpoint)
+}
+
+// Get 返回 endpoint 的能力矩阵；探测失败时返回全部为 false 的矩阵（即最保守路径），不会返回 nil
+func (d *Detector) Get(ctx context.Context, endpoint string) *Matrix {
+	d.mu.Lock()
+	if d.entries == nil {
+		d.entries = map[string]*detectEntry{}
+	}
+	e, ok := d.entries[endpoint]
+	if !ok {
+		e = &detectEntry{}
+		d.entries[endpoint] = e
+	}
+	d.mu.Unlock()
+
+	e.once.Do(func() { e.m = d.detect(ctx, endpoint) })
+	return e.m
+}
+
+func (d *Detector) detect(ctx context.Context, endpoint string) *Matrix {
+	maxAge := d.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	if d.CachePath != "" {
+		if m, ok := Load(d.CachePath, endpoint, maxAge); ok {
+			return m
+		}
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, DefaultProbeTimeout)
+	defer cancel()
+	m, err := Probe(pctx, endpoint)
+	if err != nil {
+		log.Printf("⚠️ 能力探测失败（%s），按最保守方式调用: %v", endpoint, err)
+		return &Matrix{Endpoint: endpoint, BeaconExt: map[string]bool{}}
+	}
+	if d.CachePath != "" {
+		if err := Save(d.CachePath, m); err != nil {
+			log.Printf("⚠️ 写能力缓存失败: %v", err)
+		}
+	}
+	return m
+}
@@ -0,0 +1,80 @@
+package capability
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TxParams 发交易前需要的链上参数
+type TxParams struct {
+	Nonce   uint64
+	TipCap  *big.Int // eth_maxPriorityFeePerGas；节点不支持 EIP-1559 时为 nil
+	BaseFee *big.Int // 最新块 baseFee；不支持 EIP-1559 时为 nil
+}
+
+// FetchTxParams 获取 pending nonce、建议小费与最新 baseFee。
+// 节点支持批量请求时一次往返取回；不支持 EIP-1559 时跳过小费与 baseFee 查询。
+func FetchTxParams(ctx context.Context, cli *ethclient.Client, m *Matrix, from common.Address) (*TxParams, error) {
+	if m.HasBatch() {
+		return fetchTxParamsBatch(ctx, cli.Client(), m.HasEIP1559(), from)
+	}
+
+	p := &TxParams{}
+	nonce, err := cli.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("get nonce: %w", err)
+	}
+	p.Nonce = nonce
+	if !m.HasEIP1559() {
+		return p, nil
+	}
+	if tip, err := cli.SuggestGasTipCap(ctx); err == nil {
+		p.TipCap = tip
+	}
+	if h, err := cli.HeaderByNumber(ctx, nil); err == nil {
+		p.BaseFee = h.BaseFee
+	}
+	return p, nil
+}
+
+func fetchTxParamsBatch(ctx context.Context, rc *rpc.Client, eip1559 bool, from common.Address) (*TxParams, error) {
+	var (
+		nonce hexutil.Uint64
+		tip   hexutil.Big
+		head  struct {
+			BaseFee *hexutil.Big `json:"baseFeePerGas"`
+		}
+	)
+	batch := []rpc.BatchElem{
+		{Method: "eth_getTransactionCount", Args: []any{from, "pending"}, Result: &nonce},
+	}
+	if eip1559 {
+		batch = append(batch,
+			rpc.BatchElem{Method: "eth_maxPriorityFeePerGas", Result: &tip},
+			rpc.BatchElem{Method: "eth_getBlockByNumber", Args: []any{"latest", false}, Result: &head},
+		)
+	}
+	if err := rc.BatchCallContext(ctx, batch); err != nil {
+		return nil, fmt.Errorf("batch call: %w", err)
+	}
+	if batch[0].Error != nil {
+		return nil, fmt.Errorf("get nonce: %w", batch[0].Error)
+	}
+
+	p := &TxParams{Nonce: uint64(nonce)}
+	if eip1559 {
+		if batch[1].Error == nil {
+			p.TipCap = tip.ToInt()
+		}
+		if batch[2].Error == nil && head.BaseFee != nil {
+			p.BaseFee = head.BaseFee.ToInt()
+		}
+	}
+	return p, nil
+}
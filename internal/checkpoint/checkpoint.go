@@ -0,0 +1,201 @@
+// 批量运行的断点状态文件：每处理完一条（以及提交前、提交后）追加一行 JSON，记录条目下标、状态与交易哈希。
+// 进程中途崩溃后用 --resume 读回，跳过已确认的条目；同一下标以最后一行为准。
+// 只追加不改写，崩溃时最多损坏最后一行，读取时忽略该行。
+package checkpoint
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Status 条目状态
+type Status string
+
+const (
+	Pending   Status = "pending"   // 开始提交（崩溃时可能已广播）
+	Sent      Status = "sent"      // 已提交，未确认
+	Confirmed Status = "confirmed" // 已上链且成功
+	Failed    Status = "failed"
+)
+
+// Record 状态文件中的一行
+type Record struct {
+	Index  int       `json:"index"`
+	Status Status    `json:"status"`
+	TxHash string    `json:"tx_hash,omitempty"`
+	Nonce  uint64    `json:"nonce,omitempty"` // TxHash 的 nonce（TxHash 为空时无意义）
+	Block  uint64    `json:"block,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Writer 追加写状态文件，可并发调用；nil Writer 的方法均为空操作
+type Writer struct {
+	Path string
+
+	mu  sync.Mutex
+	f   *os.File
+	err error
+}
+
+// Create 以追加方式打开（不存在则创建）状态文件，续跑时接着原文件写
+func Create(path string) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{Path: path, f: f}, nil
+}
+
+// Mark 追加一条记录；写失败只保留第一个错误（见 Err），不影响发送
+func (w *Writer) Mark(r Record) {
+	if w == nil {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.f.Write(append(b, '\n')); err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+// Err 第一次写失败的错误
+func (w *Writer) Err() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	return w.f.Close()
+}
+
+// State 每个下标最后一次的记录
+type State map[int]Record
+
+// Load 读取状态文件；文件不存在时返回空状态（首次使用 --resume 与正常运行相同）
+func Load(path string) (State, error) {
+	s := State{}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var r Record
+		if json.Unmarshal(sc.Bytes(), &r) != nil {
+			continue // 崩溃时写了一半的行
+		}
+		s[r.Index] = r
+	}
+	return s, sc.Err()
+}
+
+// Count 各状态的条数
+func (s State) Count() map[Status]int {
+	out := map[Status]int{}
+	for _, r := range s {
+		out[r.Status]++
+	}
+	return out
+}
+
+// Prior 该下标上次发出的交易（未确认时续跑前用 Check 核对）；没有记录哈希时 ok 为 false
+func (s State) Prior(index int) (p Prior, ok bool) {
+	r := s[index]
+	if r.TxHash == "" {
+		return Prior{}, false
+	}
+	return Prior{TxHash: r.TxHash, Nonce: r.Nonce}, true
+}
+
+// Done 该下标是否已确认
+func (s State) Done(index int) bool {
+	return s[index].Status == Confirmed
+}
+
+// Reconcile 查询 sent 状态条目的回执：已上链且成功的改记为 confirmed（同时追加到 w），返回改记的条数
+func (s State) Reconcile(ctx context.Context, cli *ethclient.Client, w *Writer) (int, error) {
+	n := 0
+	for idx, r := range s {
+		if r.Status != Sent || r.TxHash == "" {
+			continue
+		}
+		rcpt, err := cli.TransactionReceipt(ctx, common.HexToHash(r.TxHash))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		if rcpt.Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		r.Status, r.Block, r.Time = Confirmed, rcpt.BlockNumber.Uint64(), time.Now().UTC()
+		w.Mark(r)
+		s[idx] = r
+		n++
+	}
+	return n, nil
+}
+
+// WaitReceipts 每隔 poll 查询一遍 hashes 中尚无回执的交易，直到全部上链或 ctx 结束；
+// 返回已查到的回执（按哈希），ctx 结束不算错误，查不到的哈希不在结果中
+func WaitReceipts(ctx context.Context, cli *ethclient.Client, hashes []string, poll time.Duration) map[string]*types.Receipt {
+	out := map[string]*types.Receipt{}
+	pending := map[string]bool{}
+	for _, h := range hashes {
+		pending[h] = true
+	}
+	t := time.NewTicker(poll)
+	defer t.Stop()
+	for {
+		for h := range pending {
+			rcpt, err := cli.TransactionReceipt(ctx, common.HexToHash(h))
+			if err == nil && rcpt != nil {
+				out[h] = rcpt
+				delete(pending, h)
+			}
+		}
+		if len(pending) == 0 {
+			return out
+		}
+		select {
+		case <-ctx.Done():
+			return out
+		case <-t.C:
+		}
+	}
+}
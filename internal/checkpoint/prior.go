@@ -0,0 +1,83 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// -------------------- 重发前核对 --------------------
+//
+// 续跑（--resume）与重跑（--retry-failed）时，同一条目上次可能已经广播过交易：
+// 只是等回执超时、进程崩溃，或交易被提价替换成了另一个哈希。重发前先按上次记录的哈希与 (发送账户, nonce)
+// 核对链上与交易池，能沿用的沿用，nonce 已被占用的不再重发，避免重复存款 / 重复退出。
+
+// Prior 条目上次发出的交易；Nonce 只在 TxHash 非空时有意义
+type Prior struct {
+	TxHash string
+	Nonce  uint64
+	From   string // 发出该交易的账户；非空且与 Check 的 from 不同时不按 nonce 判断（换了发送账户）
+}
+
+// Verdict Check 的结论
+type Verdict int
+
+const (
+	Resend    Verdict = iota // 上次的交易不存在（未广播或已被丢弃）且 nonce 未被占用，可以重发
+	Mined                    // 上次的交易已上链（回执见 Check 的返回值，可能 revert）
+	InPool                   // 上次的交易仍在交易池中：等它上链，不要重发
+	NonceUsed                // 查不到该哈希，但发送账户在该 nonce 上已有其它交易上链（如提价替换后的版本）：不要重发
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Resend:
+		return "resend"
+	case Mined:
+		return "mined"
+	case InPool:
+		return "in_pool"
+	case NonceUsed:
+		return "nonce_used"
+	}
+	return fmt.Sprintf("verdict(%d)", int(v))
+}
+
+// Check 核对 from 上次发出的交易 p；p.TxHash 为空时直接返回 Resend。Mined 时返回回执
+func Check(ctx context.Context, cli *ethclient.Client, from common.Address, p Prior) (Verdict, *types.Receipt, error) {
+	if p.TxHash == "" {
+		return Resend, nil, nil
+	}
+	h := common.HexToHash(p.TxHash)
+	rcpt, err := cli.TransactionReceipt(ctx, h)
+	switch {
+	case err == nil && rcpt != nil:
+		return Mined, rcpt, nil
+	case err != nil && !errors.Is(err, ethereum.NotFound):
+		return Resend, nil, fmt.Errorf("get receipt %s: %w", p.TxHash, err)
+	}
+	// 仍在池中，或已打包但回执还没索引到：都等回执即可
+	_, _, err = cli.TransactionByHash(ctx, h)
+	switch {
+	case err == nil:
+		return InPool, nil, nil
+	case !errors.Is(err, ethereum.NotFound):
+		return Resend, nil, fmt.Errorf("get tx %s: %w", p.TxHash, err)
+	}
+	if p.From != "" && common.HexToAddress(p.From) != from {
+		return Resend, nil, nil
+	}
+	mined, err := cli.NonceAt(ctx, from, nil)
+	if err != nil {
+		return Resend, nil, fmt.Errorf("get nonce of %s: %w", from.Hex(), err)
+	}
+	if mined > p.Nonce {
+		return NonceUsed, nil, nil
+	}
+	return Resend, nil, nil
+}
@@ -0,0 +1,117 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/resultout"
+	"n42-test/internal/rpcpool"
+)
+
+// -------------------- 批量工具共用的续跑流程 --------------------
+//
+// deposit-batch 与 exit-batch 的续跑、重跑与运行后补查流程相同，只是结果字段不同：
+// 这里负责读状态、查回执、核对上次的交易与打印汇总，改记结果交给调用方的回调。
+
+// ErrNonceUsed 上次交易的哈希查不到，但其 nonce 已被占用
+var ErrNonceUsed = errors.New("nonce of the previous tx is already used by another tx (possibly a fee-bumped replacement); not resending, check the sender's transactions")
+
+// Resume 读取 path 已有的状态，并查询上次已提交未确认条目的回执（上链成功的改记为 confirmed 并追加到 w）；
+// 查询失败只告警。risk 描述中断于提交中的条目重发可能造成的后果（如"重复存款"）
+func Resume(path, rpc string, w *Writer, risk string) (State, error) {
+	s, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if cli, err := rpcpool.DialEth(context.Background(), rpc); err != nil {
+		log.Printf("⚠️ 无法查询已提交条目的回执，这些条目将重新处理: %v", err)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		if n, err := s.Reconcile(ctx, cli, w); err != nil {
+			log.Printf("⚠️ 查询已提交条目的回执失败: %v", err)
+		} else if n > 0 {
+			log.Printf("🔎 %d 条已提交的交易已上链，记为已确认", n)
+		}
+		cancel()
+		cli.Close()
+	}
+	c := s.Count()
+	log.Printf("⏯️ 续跑 %s：已确认 %d，失败 %d，已提交未确认 %d，中断于提交中 %d", path,
+		c[Confirmed], c[Failed], c[Sent], c[Pending])
+	if c[Pending] > 0 {
+		log.Printf("⚠️ %d 条中断于提交中、没有记下交易哈希，会重新发送；若原交易已广播，可能%s", c[Pending], risk)
+	}
+	return s, nil
+}
+
+// CollectPriors 汇总上次已发出、尚未确认的交易：resumed 中未确认的记录，及 retry 中 prior 返回 ok 的记录
+// （失败但有交易哈希的条目）。续跑的记录优先；retry 可为 nil
+func CollectPriors[T any](resumed State, retry *resultout.RetrySet[T], prior func(T) (index int, p Prior, ok bool)) map[int]Prior {
+	out := map[int]Prior{}
+	if retry != nil {
+		for _, r := range retry.Prior {
+			if idx, p, ok := prior(r); ok {
+				out[idx] = p
+			}
+		}
+	}
+	for idx := range resumed {
+		if p, ok := resumed.Prior(idx); ok && !resumed.Done(idx) {
+			out[idx] = p
+		}
+	}
+	return out
+}
+
+// Reuse 重发前核对 from 上次发出的交易 p（见 Check）：已成功上链或仍在交易池中的返回 reuse，沿用该交易等回执；
+// 核对失败或 nonce 已被占用时返回错误，不要重发；其余（已丢弃、已 revert）两者都为零值，照常发送。
+// 返回的 Verdict 供调用方打印
+func Reuse(ctx context.Context, cli *ethclient.Client, from common.Address, p Prior) (v Verdict, reuse bool, err error) {
+	v, rcpt, err := Check(ctx, cli, from, p)
+	switch {
+	case err != nil:
+		return v, false, fmt.Errorf("check previous tx, not resending: %w", err)
+	case v == NonceUsed:
+		return v, false, fmt.Errorf("tx=%s nonce=%d: %w", p.TxHash, p.Nonce, ErrNonceUsed)
+	case v == InPool, v == Mined && rcpt.Status == types.ReceiptStatusSuccessful:
+		return v, true, nil
+	}
+	return v, false, nil
+}
+
+// Resolve 运行结束后统一补查已发送未确认交易的回执（最长 timeout）：查到回执的按 hashes 中的位置 i 调用 apply
+// （reverted 为回执不是成功状态），由调用方改记结果、断点状态与汇总；查不到的不调用，保持未确认
+func Resolve(ctx context.Context, rpc string, hashes []string, timeout time.Duration, apply func(i int, rcpt *types.Receipt, reverted bool)) {
+	cli, err := rpcpool.DialEth(ctx, rpc)
+	if err != nil {
+		log.Printf("⚠️ 无法补查未确认交易的回执: %v", err)
+		return
+	}
+	defer cli.Close()
+	log.Printf("🔎 补查 %d 笔已发送未确认交易的回执（最长 %s）……", len(hashes), timeout)
+	rctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	rcpts := WaitReceipts(rctx, cli, hashes, 2*time.Second)
+
+	mined, reverted := 0, 0
+	for i, h := range hashes {
+		rcpt, ok := rcpts[h]
+		if !ok {
+			continue
+		}
+		mined++
+		failed := rcpt.Status != types.ReceiptStatusSuccessful
+		if failed {
+			reverted++
+		}
+		apply(i, rcpt, failed)
+	}
+	log.Printf("🔎 补查完成：%d 笔已上链（其中 revert %d），%d 笔仍未确认", mined, reverted, len(hashes)-mined)
+}
@@ -0,0 +1,435 @@
+// consensusBeaconExt_* 一致性测试：对每个方法分别用合法输入与非法输入（错误哈希、未知区块、畸形参数）调用节点，
+// 检查 JSON-RPC 响应外形（jsonrpc/id/result|error 二选一）与结果内容是否符合本工具依赖的约定，
+// 作为节点 RPC 层的回归用例。
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"n42-test/internal/hexutil"
+	"n42-test/internal/rpcclient"
+)
+
+const (
+	MethodBeaconHash  = "consensusBeaconExt_get_beacon_block_hash_by_eth1_hash"
+	MethodBeaconBlock = "consensusBeaconExt_get_beacon_block_by_hash"
+	MethodBeaconState = "consensusBeaconExt_get_beacon_state_by_beacon_block_hash"
+)
+
+// JSON-RPC 2.0 标准错误码
+const (
+	CodeInvalidParams  = -32602
+	CodeMethodNotFound = -32601
+)
+
+// Expect 用例期望的响应
+type Expect int
+
+const (
+	ExpectResult      Expect = iota // 成功返回非空结果
+	ExpectError                     // 返回 error（参数非法）
+	ExpectErrorOrNull               // 返回 error 或 null（查无此块）
+)
+
+func (e Expect) String() string {
+	switch e {
+	case ExpectResult:
+		return "result"
+	case ExpectError:
+		return "error"
+	default:
+		return "error|null"
+	}
+}
+
+// Fixture 用例需要的链上数据：执行层 latest 块及其对应的信标区块
+type Fixture struct {
+	Eth1Number uint64
+	Eth1Hash   string
+	BeaconHash string
+	BeaconSlot uint64
+}
+
+// Case 一个用例
+type Case struct {
+	Method string
+	Name   string
+	Params func(f *Fixture) []any
+	Expect Expect
+	// Check 对 ExpectResult 用例检查结果内容；可回填 Fixture 供后续用例使用
+	Check func(f *Fixture, raw json.RawMessage) error
+}
+
+// Outcome 一个用例的结果
+type Outcome struct {
+	Method  string        `json:"method"`
+	Case    string        `json:"case"`
+	Expect  string        `json:"expect"`
+	Pass    bool          `json:"pass"`
+	Code    int           `json:"code,omitempty"` // 节点返回的错误码
+	Detail  string        `json:"detail,omitempty"`
+	Warn    string        `json:"warn,omitempty"` // 通过但不理想（如参数非法却不是 -32602）
+	Elapsed time.Duration `json:"elapsed_ns"`
+}
+
+// Report 一次运行的全部结果
+type Report struct {
+	Endpoint string    `json:"endpoint"`
+	Fixture  Fixture   `json:"fixture"`
+	Outcomes []Outcome `json:"outcomes"`
+}
+
+// Failed 未通过的用例数
+func (r *Report) Failed() int {
+	n := 0
+	for _, o := range r.Outcomes {
+		if !o.Pass {
+			n++
+		}
+	}
+	return n
+}
+
+// Print 按方法分组打印
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "endpoint %s  eth1 #%d %s → beacon %s (slot %d)\n",
+		r.Endpoint, r.Fixture.Eth1Number, r.Fixture.Eth1Hash, r.Fixture.BeaconHash, r.Fixture.BeaconSlot)
+	last := ""
+	for _, o := range r.Outcomes {
+		if o.Method != last {
+			fmt.Fprintf(w, "\n%s\n", o.Method)
+			last = o.Method
+		}
+		mark := "✅"
+		if !o.Pass {
+			mark = "❌"
+		} else if o.Warn != "" {
+			mark = "⚠️"
+		}
+		line := fmt.Sprintf("  %s %-28s expect=%-10s %6s", mark, o.Case, o.Expect, o.Elapsed.Round(time.Millisecond))
+		if o.Detail != "" {
+			line += "  " + o.Detail
+		}
+		if o.Warn != "" {
+			line += "  (" + o.Warn + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintf(w, "\n通过 %d / %d\n", len(r.Outcomes)-r.Failed(), len(r.Outcomes))
+}
+
+// Options 运行选项
+type Options struct {
+	Eth1Hash string // 使用该执行层区块作为合法输入；为空取 latest
+	Filter   string // 只跑方法名包含该子串的用例
+	Timeout  time.Duration
+}
+
+// Run 取 fixture 后依次执行全部用例。合法输入的用例按方法顺序执行，前一个方法的结果作为后一个的输入；
+// fixture 取不到（如第一个方法就不可用）时后续依赖它的用例记为失败，不中止其余用例。
+func Run(ctx context.Context, endpoint string, opts Options) (*Report, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	c := &client{rpc: rpcclient.New(endpoint, rpcclient.Options{Timeout: opts.Timeout})}
+	f := &Fixture{Eth1Hash: opts.Eth1Hash}
+	if err := c.loadEth1(ctx, f); err != nil {
+		return nil, err
+	}
+	rep := &Report{Endpoint: endpoint}
+	for _, tc := range Cases() {
+		if opts.Filter != "" && !strings.Contains(tc.Method, opts.Filter) {
+			continue
+		}
+		rep.Outcomes = append(rep.Outcomes, c.run(ctx, f, tc))
+	}
+	rep.Fixture = *f
+	return rep, nil
+}
+
+// Cases 全部用例（顺序有意义：合法输入用例回填 Fixture）
+func Cases() []Case {
+	var cs []Case
+	for _, m := range []string{MethodBeaconHash, MethodBeaconBlock, MethodBeaconState} {
+		cs = append(cs, Case{Method: m, Name: "valid", Params: validParams(m), Expect: ExpectResult, Check: checks[m]})
+		cs = append(cs, invalidCases(m)...)
+	}
+	return cs
+}
+
+func validParams(method string) func(f *Fixture) []any {
+	return func(f *Fixture) []any {
+		if method == MethodBeaconHash {
+			return []any{f.Eth1Hash}
+		}
+		return []any{f.BeaconHash}
+	}
+}
+
+// invalidCases 三个方法都只接受一个 32 字节哈希，非法输入相同
+func invalidCases(method string) []Case {
+	fixed := func(params ...any) func(*Fixture) []any {
+		return func(*Fixture) []any { return params }
+	}
+	unknown := randomHash()
+	return []Case{
+		{Method: method, Name: "unknown-hash", Params: fixed(unknown), Expect: ExpectErrorOrNull},
+		{Method: method, Name: "zero-hash", Params: fixed("0x" + strings.Repeat("00", 32)), Expect: ExpectErrorOrNull},
+		{Method: method, Name: "short-hash", Params: fixed("0x1234"), Expect: ExpectError},
+		{Method: method, Name: "long-hash", Params: fixed(unknown + "00"), Expect: ExpectError},
+		{Method: method, Name: "non-hex", Params: fixed("0x" + strings.Repeat("zz", 32)), Expect: ExpectError},
+		{Method: method, Name: "empty-string", Params: fixed(""), Expect: ExpectError},
+		{Method: method, Name: "number-param", Params: fixed(12345), Expect: ExpectError},
+		{Method: method, Name: "object-param", Params: fixed(map[string]any{"hash": unknown}), Expect: ExpectError},
+		{Method: method, Name: "no-params", Params: fixed(), Expect: ExpectError},
+		{Method: method, Name: "extra-param", Params: validPlus(method, true), Expect: ExpectError},
+	}
+}
+
+func validPlus(method string, extra any) func(f *Fixture) []any {
+	return func(f *Fixture) []any {
+		return append(validParams(method)(f), extra)
+	}
+}
+
+var checks = map[string]func(f *Fixture, raw json.RawMessage) error{
+	MethodBeaconHash: func(f *Fixture, raw json.RawMessage) error {
+		var h string
+		if err := json.Unmarshal(raw, &h); err != nil {
+			return fmt.Errorf("result 不是字符串: %s", abbrev(raw))
+		}
+		if !strings.HasPrefix(h, "0x") || !hexutil.IsHex(h) || len(hexutil.Trim(h)) != 64 {
+			return fmt.Errorf("result 不是 0x 开头的 32 字节哈希: %q", h)
+		}
+		if strings.Trim(hexutil.Trim(h), "0") == "" {
+			return errors.New("result 为零哈希")
+		}
+		f.BeaconHash = h
+		return nil
+	},
+	MethodBeaconBlock: func(f *Fixture, raw json.RawMessage) error {
+		var blk struct {
+			Slot    json.RawMessage `json:"slot"`
+			Message *struct {
+				Slot json.RawMessage `json:"slot"`
+			} `json:"message"`
+		}
+		if err := decodeObject(raw, &blk); err != nil {
+			return err
+		}
+		slot := blk.Slot
+		if slot == nil && blk.Message != nil {
+			slot = blk.Message.Slot
+		}
+		if slot == nil {
+			return errors.New("缺少 slot（顶层或 message.slot）")
+		}
+		n, err := parseUint(slot)
+		if err != nil {
+			return fmt.Errorf("slot: %w", err)
+		}
+		f.BeaconSlot = n
+		return nil
+	},
+	MethodBeaconState: func(f *Fixture, raw json.RawMessage) error {
+		var fields map[string]json.RawMessage
+		if err := decodeObject(raw, &fields); err != nil {
+			return err
+		}
+		for _, k := range []string{"slot", "validators", "balances", "eth1_data"} {
+			if _, ok := fields[k]; !ok {
+				return fmt.Errorf("缺少 %s", k)
+			}
+		}
+		// 没有验证者时 validators/balances 可能为 null
+		var st struct {
+			Slot       json.RawMessage   `json:"slot"`
+			Validators []json.RawMessage `json:"validators"`
+			Balances   []json.RawMessage `json:"balances"`
+		}
+		if err := json.Unmarshal(raw, &st); err != nil {
+			return fmt.Errorf("字段类型不符: %w", err)
+		}
+		if len(st.Validators) != len(st.Balances) {
+			return fmt.Errorf("validators(%d) 与 balances(%d) 长度不一致", len(st.Validators), len(st.Balances))
+		}
+		slot, err := parseUint(st.Slot)
+		if err != nil {
+			return fmt.Errorf("slot: %w", err)
+		}
+		if f.BeaconSlot != 0 && slot != f.BeaconSlot {
+			return fmt.Errorf("state.slot=%d 与区块 slot=%d 不一致", slot, f.BeaconSlot)
+		}
+		return nil
+	},
+}
+
+// run 执行一个用例并判定
+func (c *client) run(ctx context.Context, f *Fixture, tc Case) Outcome {
+	o := Outcome{Method: tc.Method, Case: tc.Name, Expect: tc.Expect.String()}
+	if tc.Expect == ExpectResult || tc.Name == "extra-param" {
+		need := f.Eth1Hash
+		if tc.Method != MethodBeaconHash {
+			need = f.BeaconHash
+		}
+		if need == "" {
+			o.Detail = "缺少前置结果（上一方法的合法输入用例未通过）"
+			return o
+		}
+	}
+	began := time.Now()
+	resp, err := c.call(ctx, tc.Method, tc.Params(f))
+	o.Elapsed = time.Since(began)
+	if err != nil {
+		o.Detail = err.Error()
+		return o
+	}
+	if resp.Error != nil {
+		o.Code = resp.Error.Code
+	}
+	isNull := resp.Error == nil && (len(resp.Result) == 0 || string(resp.Result) == "null")
+
+	switch tc.Expect {
+	case ExpectResult:
+		switch {
+		case resp.Error != nil:
+			o.Detail = fmt.Sprintf("error %d: %s", resp.Error.Code, resp.Error.Message)
+		case isNull:
+			o.Detail = "result 为 null"
+		default:
+			if err := tc.Check(f, resp.Result); err != nil {
+				o.Detail = err.Error()
+			} else {
+				o.Pass = true
+			}
+		}
+	case ExpectError:
+		if resp.Error == nil {
+			o.Detail = "期望 error，得到 result " + abbrev(resp.Result)
+			break
+		}
+		o.Pass = true
+		o.Detail = fmt.Sprintf("error %d: %s", resp.Error.Code, resp.Error.Message)
+		if resp.Error.Code == CodeMethodNotFound {
+			o.Pass, o.Detail = false, "方法不存在"
+		} else if resp.Error.Code != CodeInvalidParams {
+			o.Warn = fmt.Sprintf("参数非法应返回 %d", CodeInvalidParams)
+		}
+	case ExpectErrorOrNull:
+		switch {
+		case resp.Error != nil:
+			o.Pass = true
+			o.Detail = fmt.Sprintf("error %d: %s", resp.Error.Code, resp.Error.Message)
+			if resp.Error.Code == CodeMethodNotFound {
+				o.Pass, o.Detail = false, "方法不存在"
+			}
+		case isNull:
+			o.Pass = true
+			o.Detail = "null"
+		default:
+			o.Detail = "未知区块却返回了结果 " + abbrev(resp.Result)
+		}
+	}
+	return o
+}
+
+// -------------------- 原始 JSON-RPC --------------------
+
+type client struct {
+	rpc *rpcclient.Client
+}
+
+// call 发送请求并检查响应外形：jsonrpc 为 "2.0"、id 原样返回、result 与 error 恰有一个
+func (c *client) call(ctx context.Context, method string, params []any) (*rpcclient.Response, error) {
+	resp, err := c.rpc.CallRaw(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	if v := string(resp.JSONRPC); v != `"2.0"` {
+		return nil, fmt.Errorf("jsonrpc 字段为 %s，应为 \"2.0\"", v)
+	}
+	var gotID int64
+	if err := json.Unmarshal(resp.ID, &gotID); err != nil || gotID != resp.RequestID {
+		return nil, fmt.Errorf("id 为 %s，应为 %d", resp.ID, resp.RequestID)
+	}
+	if resp.HasResult == resp.HasError {
+		return nil, errors.New("result 与 error 应恰有一个")
+	}
+	if resp.HasError && (resp.Error == nil || resp.Error.Message == "") {
+		return nil, fmt.Errorf("error 应为含 code/message 的对象: %s", abbrev(resp.ErrorRaw))
+	}
+	return resp, nil
+}
+
+// loadEth1 取合法输入用的执行层区块（--eth1-hash 或 latest）
+func (c *client) loadEth1(ctx context.Context, f *Fixture) error {
+	method, params := "eth_getBlockByNumber", []any{"latest", false}
+	if f.Eth1Hash != "" {
+		method, params = "eth_getBlockByHash", []any{f.Eth1Hash, false}
+	}
+	resp, err := c.call(ctx, method, params)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s: error %d: %s", method, resp.Error.Code, resp.Error.Message)
+	}
+	var blk struct {
+		Number string `json:"number"`
+		Hash   string `json:"hash"`
+	}
+	if err := json.Unmarshal(resp.Result, &blk); err != nil || blk.Hash == "" {
+		return fmt.Errorf("%s: 区块不存在或格式错误: %s", method, abbrev(resp.Result))
+	}
+	n, err := parseUint(json.RawMessage(blk.Number))
+	if err != nil {
+		return fmt.Errorf("%s: number: %w", method, err)
+	}
+	f.Eth1Number, f.Eth1Hash = n, blk.Hash
+	return nil
+}
+
+// -------------------- 工具 --------------------
+
+func decodeObject(raw json.RawMessage, v any) error {
+	if t := bytes.TrimSpace(raw); len(t) == 0 || t[0] != '{' {
+		return fmt.Errorf("result 不是 JSON 对象: %s", abbrev(raw))
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("字段类型不符: %w", err)
+	}
+	return nil
+}
+
+// parseUint 接受十进制数字、十进制字符串或 0x 十六进制字符串
+func parseUint(raw json.RawMessage) (uint64, error) {
+	s := strings.Trim(string(raw), `"`)
+	if h, ok := strings.CutPrefix(s, "0x"); ok {
+		return strconv.ParseUint(h, 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func randomHash() string {
+	var b [32]byte
+	_, _ = rand.Read(b[:])
+	return fmt.Sprintf("0x%x", b)
+}
+
+func abbrev(b []byte) string {
+	const max = 120
+	s := string(b)
+	if len(s) > max {
+		return s[:max] + "…"
+	}
+	return s
+}
@@ -0,0 +1,158 @@
+// Package consolidate EIP-7251 合并请求：打包 calldata、读取费用、发送与按信标状态预检。
+//
+// source 的余额并入 target 后 source 退出；source == target 时为“切换为复利凭证”（0x01 → 0x02）。
+// 合约的取费与发送方式同 EIP-7002（internal/exit），calldata 为 [source_pubkey(48) | target_pubkey(48)]。
+package consolidate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/capability"
+	"n42-test/internal/exit"
+)
+
+// 请求类型
+const (
+	KindConsolidation       = "consolidation"
+	KindSwitchToCompounding = "switch-to-compounding"
+)
+
+// DefaultContract EIP-7251 合并请求系统合约
+const DefaultContract = "0x0000BBdDc7CE488642fb579F8B00f3a590007251"
+
+// Request 一条 EIP-7251 合并请求
+type Request struct {
+	Source []byte // 48 字节 BLS 公钥
+	Target []byte // 48 字节 BLS 公钥
+}
+
+// IsSwitchToCompounding source 与 target 相同：只把 0x01 凭证切换为 0x02
+func (r Request) IsSwitchToCompounding() bool {
+	return bytes.Equal(r.Source, r.Target)
+}
+
+// Kind consolidation | switch-to-compounding
+func (r Request) Kind() string {
+	if r.IsSwitchToCompounding() {
+		return KindSwitchToCompounding
+	}
+	return KindConsolidation
+}
+
+// Calldata 合约调用数据 [source(48) | target(48)]
+func (r Request) Calldata() ([]byte, error) {
+	return PackCalldata(r.Source, r.Target)
+}
+
+// PackCalldata [source_pubkey(48) | target_pubkey(48)]
+func PackCalldata(source48, target48 []byte) ([]byte, error) {
+	if len(source48) != 48 {
+		return nil, fmt.Errorf("source pubkey length must be 48, got %d", len(source48))
+	}
+	if len(target48) != 48 {
+		return nil, fmt.Errorf("target pubkey length must be 48, got %d", len(target48))
+	}
+	data := make([]byte, 0, 96)
+	data = append(data, source48...)
+	return append(data, target48...), nil
+}
+
+// GetFee 读取当前区块的合并请求费用（wei），取费方式同 exit.GetExitFee
+func GetFee(ctx context.Context, cli *ethclient.Client, contract common.Address) (*big.Int, error) {
+	return exit.GetExitFee(ctx, cli, contract)
+}
+
+// SendRequest 发送合并请求：费用、gas、EIP-1559/legacy 与 nonce 重试同 exit.SendExitRequestWithCaps。
+// caps 为 nil 时逐项查询。
+func SendRequest(
+	ctx context.Context,
+	cli *ethclient.Client,
+	caps *capability.Matrix,
+	priv *ecdsa.PrivateKey,
+	contract common.Address,
+	req Request,
+	wait bool,
+) (*types.Transaction, *types.Receipt, error) {
+	calldata, err := req.Calldata()
+	if err != nil {
+		return nil, nil, err
+	}
+	return exit.SendSystemRequest(ctx, cli, caps, priv, contract, calldata, wait)
+}
+
+// Check 按信标状态对一条合并请求的预检结果
+type Check struct {
+	Kind   string
+	Source *exit.ValidatorInfo
+	Target *exit.ValidatorInfo
+	// Problems 信标链会忽略该请求的原因；为空表示可以发送
+	Problems []string
+}
+
+func (c *Check) OK() bool { return len(c.Problems) == 0 }
+
+// Err 有问题时返回汇总错误
+func (c *Check) Err() error {
+	if c.OK() {
+		return nil
+	}
+	return fmt.Errorf("%s rejected by beacon state check: %s", c.Kind, strings.Join(c.Problems, "; "))
+}
+
+// CheckRequest 按 Electra process_consolidation_request 的规则预检请求：
+// 切换凭证要求 source 为 0x01、已激活且未在退出中；合并要求 source 与 target 都已激活且未在退出中、
+// target 为 0x02 凭证、source 激活满 exit.ShardCommitteePeriod 且没有排队中的部分提款。
+// sender 非零时还检查它与 source 验证者凭证中的地址一致。合并队列与 churn 上限不在预检范围内。
+func CheckRequest(st *beaconstate.State, sender common.Address, req Request, slotsPerEpoch uint64) (*Check, error) {
+	src, err := exit.LookupValidator(st, req.Source, slotsPerEpoch)
+	if err != nil {
+		return nil, err
+	}
+	c := &Check{Kind: req.Kind(), Source: src, Target: src}
+	if !req.IsSwitchToCompounding() {
+		if c.Target, err = exit.LookupValidator(st, req.Target, slotsPerEpoch); err != nil {
+			return nil, err
+		}
+	}
+	if !src.Found() {
+		c.Problems = append(c.Problems, "source validator not found in beacon state")
+	}
+	if !c.Target.Found() && !req.IsSwitchToCompounding() {
+		c.Problems = append(c.Problems, "target validator not found in beacon state")
+	}
+	if len(c.Problems) > 0 {
+		return c, nil
+	}
+
+	if req.IsSwitchToCompounding() {
+		if src.CredentialType != "0x01" {
+			c.Problems = append(c.Problems, fmt.Sprintf("switch to compounding requires 0x01 credentials, got %s", src.CredentialType))
+		}
+		c.Problems = append(c.Problems, src.RequestProblems(sender, false)...)
+		return c, nil
+	}
+	for _, p := range src.RequestProblems(sender, true) {
+		c.Problems = append(c.Problems, "source: "+p)
+	}
+	if src.PendingGwei > 0 {
+		c.Problems = append(c.Problems, fmt.Sprintf("source: %d gwei of partial withdrawals still pending", src.PendingGwei))
+	}
+	// target 只要求复利凭证与激活状态，不要求由同一地址控制
+	if c.Target.CredentialType != "0x02" {
+		c.Problems = append(c.Problems, fmt.Sprintf("target: consolidation requires 0x02 (compounding) credentials, got %s", c.Target.CredentialType))
+	}
+	for _, p := range c.Target.ActivityProblems(false) {
+		c.Problems = append(c.Problems, "target: "+p)
+	}
+	return c, nil
+}
@@ -0,0 +1,46 @@
+package consolidate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/capability"
+)
+
+// Sender 发送合并请求的能力（同 exit.ExitSender）；*Client 为 RPC 实现
+type Sender interface {
+	GetFee(ctx context.Context) (*big.Int, error)
+	SendRequest(ctx context.Context, priv *ecdsa.PrivateKey, req Request, wait bool) (*types.Transaction, *types.Receipt, error)
+}
+
+// Client 绑定了 RPC 连接与合并合约地址的 Sender 实现
+type Client struct {
+	cli      *ethclient.Client
+	contract common.Address
+	caps     *capability.Matrix
+}
+
+var _ Sender = (*Client)(nil)
+
+func NewClient(cli *ethclient.Client, contract common.Address) *Client {
+	return &Client{cli: cli, contract: contract}
+}
+
+// WithCapabilities 设置节点能力矩阵，发送时据此选择快速路径
+func (c *Client) WithCapabilities(m *capability.Matrix) *Client {
+	c.caps = m
+	return c
+}
+
+func (c *Client) GetFee(ctx context.Context) (*big.Int, error) {
+	return GetFee(ctx, c.cli, c.contract)
+}
+
+func (c *Client) SendRequest(ctx context.Context, priv *ecdsa.PrivateKey, req Request, wait bool) (*types.Transaction, *types.Receipt, error) {
+	return SendRequest(ctx, c.cli, c.caps, priv, c.contract, req, wait)
+}
@@ -0,0 +1,144 @@
+// 常驻命令（attest 运行器、-watch 跟踪、模拟服务器）交给 systemd 管理时的支持：
+// PID 文件、sd_notify 就绪/重载/停止通知、看门狗心跳。不在 systemd 下运行时通知是空操作。
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Daemon 一个常驻进程的生命周期；零值（未设置 PID 文件、不在 systemd 下）也可用
+type Daemon struct {
+	pidFile string
+	stop    context.CancelFunc
+}
+
+// Start 写 PID 文件（pidFile 为空时不写）并在 systemd 设置了 WatchdogSec 时按一半间隔发送心跳，
+// 直到 ctx 结束或调用 Close。PID 文件已存在且对应进程仍在运行时返回错误，避免同一服务起两份
+func Start(ctx context.Context, pidFile string) (*Daemon, error) {
+	d := &Daemon{pidFile: pidFile}
+	if pidFile != "" {
+		if err := writePIDFile(pidFile); err != nil {
+			return nil, err
+		}
+	}
+	wctx, stop := context.WithCancel(ctx)
+	d.stop = stop
+	if every := watchdogInterval(); every > 0 {
+		go func() {
+			t := time.NewTicker(every)
+			defer t.Stop()
+			for {
+				select {
+				case <-wctx.Done():
+					return
+				case <-t.C:
+					Notify("WATCHDOG=1")
+				}
+			}
+		}()
+	}
+	return d, nil
+}
+
+// Ready 通知 systemd 服务已就绪（Type=notify），status 非空时一并更新状态行
+func (d *Daemon) Ready(status string) { notifyWithStatus("READY=1", status) }
+
+// Reloading 开始重新加载配置；重载完成后再调用 Ready
+func (d *Daemon) Reloading(status string) { notifyWithStatus("RELOADING=1", status) }
+
+// Status 只更新 systemctl status 中显示的状态行
+func (d *Daemon) Status(status string) { Notify("STATUS=" + status) }
+
+// Close 通知 systemd 正在停止、停止心跳并删除 PID 文件（只删内容仍是本进程 PID 的文件）
+func (d *Daemon) Close() {
+	if d == nil {
+		return
+	}
+	Notify("STOPPING=1")
+	if d.stop != nil {
+		d.stop()
+	}
+	if d.pidFile == "" {
+		return
+	}
+	if pid, err := readPID(d.pidFile); err == nil && pid == os.Getpid() {
+		os.Remove(d.pidFile)
+	}
+}
+
+func notifyWithStatus(state, status string) {
+	if status != "" {
+		state += "\nSTATUS=" + status
+	}
+	Notify(state)
+}
+
+// Notify 按 sd_notify 协议向 $NOTIFY_SOCKET 发送状态；未设置该变量时返回 false, nil
+func Notify(state string) (bool, error) {
+	sock := os.Getenv("NOTIFY_SOCKET")
+	if sock == "" {
+		return false, nil
+	}
+	if strings.HasPrefix(sock, "@") {
+		// 抽象命名空间的 socket
+		sock = "\x00" + sock[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("sd_notify: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("sd_notify: %w", err)
+	}
+	return true, nil
+}
+
+// watchdogInterval WatchdogSec 的一半；未启用（或 WATCHDOG_PID 指向别的进程）时为 0
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if p := os.Getenv("WATCHDOG_PID"); p != "" && p != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+func writePIDFile(path string) error {
+	if pid, err := readPID(path); err == nil && pid != os.Getpid() && alive(pid) {
+		return fmt.Errorf("pid file %s: process %d is still running", path, pid)
+	}
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644)
+}
+
+func readPID(path string) (int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("pid file %s: bad content %q", path, strings.TrimSpace(string(raw)))
+	}
+	return pid, nil
+}
+
+// alive 进程是否存在（信号 0 只做权限与存在性检查；无权限说明进程存在）
+func alive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,170 @@
+package deposit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"n42-test/internal/keys"
+)
+
+// BatchSender 批量发送 deposit：按发送账户（由 PrivateKeyHex 推导的地址）分组，
+// 每个账户只建一个连接、在本地连续分配 nonce。同一账户的交易串行签名并提交，
+// 提交成功即放行下一笔，等回执则各自并行——单个出资账户也能同时驱动数百笔存款，
+// 不会因并发读取 pending nonce 而冲突。
+type BatchSender struct {
+	rpc   string
+	retry RetryPolicy
+
+	mu       sync.Mutex
+	addrs    map[string]common.Address // 私钥串 -> 地址（keystore/助记词解析较慢，只做一次）
+	accounts map[common.Address]*senderAccount
+}
+
+type senderAccount struct {
+	initMu sync.Mutex
+	cli    *Client
+	nonces *NonceManager
+
+	submitMu sync.Mutex // 串行化本账户的 分配 nonce → 签名 → 提交
+}
+
+func NewBatchSender(rpc string) *BatchSender {
+	return &BatchSender{
+		rpc:      rpc,
+		addrs:    map[string]common.Address{},
+		accounts: map[common.Address]*senderAccount{},
+	}
+}
+
+// SetRetryPolicy 设置各账户连接的重试策略（见 Client.SetRetryPolicy），需在发送前调用
+func (b *BatchSender) SetRetryPolicy(p RetryPolicy) { b.retry = p }
+
+// RPC 发送使用的端点
+func (b *BatchSender) RPC() string { return b.rpc }
+
+// Senders 已使用的不同发送账户数
+func (b *BatchSender) Senders() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.accounts)
+}
+
+// Close 关闭所有账户的连接
+func (b *BatchSender) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, a := range b.accounts {
+		if a.cli != nil {
+			a.cli.Close()
+		}
+	}
+}
+
+// account 取（必要时创建）私钥对应账户的连接与 nonce 管理器
+func (b *BatchSender) account(ctx context.Context, keyHex string) (*senderAccount, error) {
+	b.mu.Lock()
+	addr, ok := b.addrs[keyHex]
+	if !ok {
+		priv, err := keys.ParseECDSA(keyHex)
+		if err != nil {
+			b.mu.Unlock()
+			return nil, fmt.Errorf("parse private key failed: %w", err)
+		}
+		addr = crypto.PubkeyToAddress(priv.PublicKey)
+		b.addrs[keyHex] = addr
+	}
+	a, ok := b.accounts[addr]
+	if !ok {
+		a = &senderAccount{}
+		b.accounts[addr] = a
+	}
+	b.mu.Unlock()
+
+	a.initMu.Lock()
+	defer a.initMu.Unlock()
+	if a.cli == nil {
+		cli, err := NewClient(ctx, b.rpc, keyHex)
+		if err != nil {
+			return nil, err
+		}
+		cli.SetRetryPolicy(b.retry)
+		a.cli, a.nonces = cli, NewNonceManager(cli)
+	}
+	return a, nil
+}
+
+// Send 发送一笔 deposit；p.Nonce >= 0 时使用指定 nonce，否则由账户的 NonceManager 分配。
+// wait 为 true 时提交后等待回执（等待期间同账户的后续交易照常提交）。
+func (b *BatchSender) Send(ctx context.Context, p *DepositParams, wait bool) (*TxResult, error) {
+	res, err := b.Submit(ctx, p)
+	if err != nil || !wait {
+		return res, err
+	}
+	return res, b.Confirm(ctx, p, res)
+}
+
+// Submit 只签名并提交，不等待回执
+func (b *BatchSender) Submit(ctx context.Context, p *DepositParams) (*TxResult, error) {
+	a, err := b.account(ctx, p.PrivateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	return a.submit(ctx, p)
+}
+
+// Confirm 等待 Submit 返回的交易上链，并把回执信息填入 res；p.StuckTx 非空时卡住的交易提价替换，res.TxHash 随之更新。
+// 上链后解码回执中的 DepositEvent 与提交参数比对（res.Deposit / res.DepositMismatch）
+func (b *BatchSender) Confirm(ctx context.Context, p *DepositParams, res *TxResult) error {
+	a, err := b.account(ctx, p.PrivateKeyHex)
+	if err != nil {
+		return err
+	}
+	if p.StuckTx != nil {
+		err = a.cli.waitStuck(ctx, res, *p.StuckTx)
+	} else {
+		err = a.cli.WaitTx(ctx, res)
+	}
+	if err != nil {
+		return err
+	}
+	checkDepositReceipt(res, p)
+	return nil
+}
+
+func (a *senderAccount) submit(ctx context.Context, p *DepositParams) (*TxResult, error) {
+	if p.Nonce >= 0 {
+		return a.cli.SendDepositNoWait(ctx, p)
+	}
+	a.submitMu.Lock()
+	defer a.submitMu.Unlock()
+
+	q := *p
+	// 账户同时被外部使用时本地计数会落后：nonce too low 时重新同步再试一次
+	for attempt := 0; ; attempt++ {
+		nctx, cancel := stepContext(ctx, p.SubmitTimeout)
+		n, err := a.nonces.Next(nctx)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		q.Nonce = n
+		res, err := a.cli.SendDepositNoWait(ctx, &q)
+		if err == nil {
+			return res, nil
+		}
+		// 未提交成功的 nonce 需要收回，否则后续交易全部卡在空洞之后
+		a.nonces.Reset()
+		if attempt > 0 || !isNonceTooLow(err) {
+			return nil, err
+		}
+	}
+}
+
+func isNonceTooLow(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
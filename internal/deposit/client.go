@@ -4,7 +4,6 @@ package deposit
 import (
 	"context"
 	"crypto/ecdsa"
-	"encoding/hex"
 	"fmt"
 	"log"
 	"math/big"
@@ -17,6 +16,11 @@ import (
 	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/capability"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+	"n42-test/internal/rpcpool"
 )
 
 // deposit 函数 ABI（与以太坊存款合约一致）
@@ -35,19 +39,20 @@ type Client struct {
 	fromAddr   common.Address
 	privKey    *ecdsa.PrivateKey
 	depositABI abi.ABI
+	caps       *capability.Matrix // 节点能力，用于选择批量请求等快速路径
+	retry      RetryPolicy        // 瞬时错误的重试策略，见 SetRetryPolicy
 }
 
 // 新建客户端，用来连接RPC，解析私钥，获取链ID
 func NewClient(ctx context.Context, rpcURL, privateKeyHex string) (*Client, error) {
-	privateKeyHex = strings.TrimPrefix(privateKeyHex, "0x")
-	// 转换成标准的*ecdsa.PrivateKey对象
-	priv, err := crypto.HexToECDSA(privateKeyHex)
+	// 转换成标准的*ecdsa.PrivateKey对象（hex / keystore / 助记词，见 internal/keys）
+	priv, err := keys.ParseECDSA(privateKeyHex)
 	if err != nil {
 		return nil, fmt.Errorf("parse private key failed: %w", err)
 	}
 	from := crypto.PubkeyToAddress(priv.PublicKey)
 
-	cli, err := ethclient.DialContext(ctx, rpcURL)
+	cli, err := rpcpool.DialEth(ctx, rpcURL)
 	if err != nil {
 		return nil, fmt.Errorf("dial rpc failed: %w", err)
 	}
@@ -67,52 +72,39 @@ func NewClient(ctx context.Context, rpcURL, privateKeyHex string) (*Client, erro
 		fromAddr:   from,
 		privKey:    priv,
 		depositABI: ab,
+		caps:       capability.For(ctx, rpcURL),
 	}, nil
 }
 
 func (c *Client) Close() { c.cli.Close() }
 
-// 工具：解 hex -> []byte（校验长度）
-func mustDecodeHex(s string) ([]byte, error) {
-	s = strings.TrimSpace(s)
-	s = strings.TrimPrefix(s, "0x")
-	if s == "" {
-		return nil, fmt.Errorf("empty hex")
-	}
-	b, err := hex.DecodeString(s)
-	if err != nil {
-		return nil, fmt.Errorf("decode hex failed: %w", err)
-	}
-	return b, nil
-}
-
 // 不做严格长度校验，错误数据也能上链
 func buildDepositArgs(p *DepositParams) (pubkey, wc, sig []byte, root [32]byte, err error) {
 	if p == nil {
 		err = fmt.Errorf("nil params")
 		return
 	}
-	pubkey, err = mustDecodeHex(p.PubkeyHex)
+	pubkey, err = hexutil.Decode(p.PubkeyHex)
 	if err != nil {
 		return
 	}
 	// 常见为 48 字节；也有 96（压缩/非压缩差异）。这里放宽只检查 >=48
-	if l := len(pubkey); l != 48 && l != 96 {
+	if l := len(pubkey); !p.Unchecked && l != 48 && l != 96 {
 		err = ErrInvalidPubkeyLen
 		return
 	}
 
-	wc, err = mustDecodeHex(p.WCHex)
+	wc, err = hexutil.Decode(p.WCHex)
 	if err != nil {
 		return
 	}
 
-	sig, err = mustDecodeHex(p.SignatureHex)
+	sig, err = hexutil.Decode(p.SignatureHex)
 	if err != nil {
 		return
 	}
 
-	rootBytes, err := mustDecodeHex(p.RootHex)
+	rootBytes, err := hexutil.Decode(p.RootHex)
 	if err != nil {
 		return
 	}
@@ -123,7 +115,7 @@ func buildDepositArgs(p *DepositParams) (pubkey, wc, sig []byte, root [32]byte,
 
 // SendDeposit 组装并发送 deposit 交易
 func (c *Client) SendDeposit(ctx context.Context, p *DepositParams) (*TxResult, error) {
-	if p.AmountWei == nil || p.AmountWei.Sign() <= 0 {
+	if !p.Unchecked && p.Amount.Sign() <= 0 {
 		return nil, fmt.Errorf("amount must be > 0 wei")
 	}
 	contract := common.HexToAddress(p.Contract)
@@ -139,37 +131,15 @@ func (c *Client) SendDeposit(ctx context.Context, p *DepositParams) (*TxResult,
 		return nil, fmt.Errorf("abi pack failed: %w", err)
 	}
 
-	// nonce
+	// nonce 与 EIP-1559 fee
 	var nonce uint64
-	if p.Nonce >= 0 {
-		nonce = uint64(p.Nonce)
-	} else {
-		nonce, err = c.cli.PendingNonceAt(ctx, c.fromAddr)
-		if err != nil {
-			return nil, fmt.Errorf("get nonce failed: %w", err)
-		}
-	}
-
-	// EIP-1559 fee
 	var maxPriority, maxFee *big.Int
-	if p.MaxPriorityFeePerGas != nil && p.MaxFeePerGas != nil {
-		maxPriority = new(big.Int).Set(p.MaxPriorityFeePerGas)
-		maxFee = new(big.Int).Set(p.MaxFeePerGas)
-	} else {
-		// 自动建议
-		maxPriority, err = c.cli.SuggestGasTipCap(ctx)
-		if err != nil {
-			// 回退到旧接口
-			gp, e2 := c.cli.SuggestGasPrice(ctx)
-			if e2 != nil {
-				return nil, fmt.Errorf("fee suggest failed: %v / %v", err, e2)
-			}
-			maxPriority = gp
-			maxFee = new(big.Int).Mul(gp, big.NewInt(2))
-		} else {
-			// maxFee = baseFee + tip * 2，简化做法：用 tip 的若干倍兜底
-			maxFee = new(big.Int).Mul(maxPriority, big.NewInt(20))
-		}
+	err = c.withRetry(ctx, "获取 nonce/费用", func() (err error) {
+		nonce, maxPriority, maxFee, err = c.nonceAndFees(ctx, p.txOptions())
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// gas 估算
@@ -182,10 +152,14 @@ func (c *Client) SendDeposit(ctx context.Context, p *DepositParams) (*TxResult,
 			GasPrice:  nil,
 			GasFeeCap: maxFee,
 			GasTipCap: maxPriority,
-			Value:     p.AmountWei,
+			Value:     p.Amount.Wei(),
 			Data:      data,
 		}
-		est, e := c.cli.EstimateGas(ctx, call)
+		var est uint64
+		e := c.withRetry(ctx, "估算 gas", func() (err error) {
+			est, err = c.cli.EstimateGas(ctx, call)
+			return err
+		})
 		if e != nil {
 			return nil, fmt.Errorf("estimate gas failed: %w", e)
 		}
@@ -198,30 +172,34 @@ func (c *Client) SendDeposit(ctx context.Context, p *DepositParams) (*TxResult,
 		ChainID:   c.chainID,
 		Nonce:     nonce,
 		To:        &contract,
-		Value:     p.AmountWei,
+		Value:     p.Amount.Wei(),
 		Data:      data,
 		Gas:       gasLimit,
 		GasTipCap: maxPriority,
 		GasFeeCap: maxFee,
 	}
 
-	tx := gethtypes.NewTx(txData)
-
-	// 签名并发送
-	signer := gethtypes.LatestSignerForChainID(c.chainID)
-	signedTx, err := gethtypes.SignTx(tx, signer, c.privKey)
+	// 签名并发送（按重试策略处理瞬时错误）
+	signedTx, err := c.signAndSend(ctx, txData)
 	if err != nil {
-		return nil, fmt.Errorf("sign tx failed: %w", err)
-	}
-
-	if err := c.cli.SendTransaction(ctx, signedTx); err != nil {
-		return nil, fmt.Errorf("send tx failed: %w", err)
+		return nil, err
 	}
 
-	// 可选：等待上链（简单轮询）
-	receipt, err := waitMined(ctx, c.cli, signedTx.Hash())
-	if err != nil {
-		return &TxResult{TxHash: signedTx.Hash().Hex(), EstimatedGas: gasLimit, Nonce: nonce}, fmt.Errorf("tx sent but waitMined failed: %w", err)
+	// 等待上链（简单轮询）；设置了 StuckTx 时卡住的交易按策略提价替换
+	var receipt *gethtypes.Receipt
+	replaced := false
+	if p.StuckTx != nil {
+		var mined *gethtypes.Transaction
+		mined, receipt, err = c.ReplaceByFee(ctx, signedTx, *p.StuckTx)
+		if err != nil {
+			return &TxResult{TxHash: signedTx.Hash().Hex(), EstimatedGas: gasLimit, Nonce: nonce}, fmt.Errorf("tx sent but not mined: %w", err)
+		}
+		signedTx, replaced = mined, mined.Hash() != signedTx.Hash()
+	} else {
+		receipt, err = waitMined(ctx, c.cli, signedTx.Hash())
+		if err != nil {
+			return &TxResult{TxHash: signedTx.Hash().Hex(), EstimatedGas: gasLimit, Nonce: nonce}, fmt.Errorf("tx sent but waitMined failed: %w", err)
+		}
 	}
 
 	// 打印区块信息
@@ -230,27 +208,85 @@ func (c *Client) SendDeposit(ctx context.Context, p *DepositParams) (*TxResult,
 		receipt.BlockHash.Hex(),
 	)
 
-	return &TxResult{
+	res := &TxResult{
 		TxHash:       signedTx.Hash().Hex(),
 		UsedGas:      receipt.GasUsed,
 		Nonce:        nonce,
 		EstimatedGas: gasLimit,
 		BlockNumber:  receipt.BlockNumber.Uint64(),
 		BlockHash:    receipt.BlockHash.Hex(),
-	}, nil
+		Status:       receipt.Status,
+		GasCostWei:   gasCost(receipt),
+		Logs:         receipt.Logs,
+		Replaced:     replaced,
+	}
+	checkDepositReceipt(res, p)
+	return res, nil
+}
+
+// nonceAndFees 返回本笔交易的 nonce 与费用：手动指定的直接使用，其余一次性从节点获取
+// （节点支持批量请求时只需一次往返）。
+func (c *Client) nonceAndFees(ctx context.Context, p TxOptions) (nonce uint64, maxPriority, maxFee *big.Int, err error) {
+	manualFee := p.MaxPriorityFeePerGas != nil && p.MaxFeePerGas != nil
+	if p.Nonce >= 0 {
+		nonce = uint64(p.Nonce)
+	}
+	if manualFee {
+		maxPriority = new(big.Int).Set(p.MaxPriorityFeePerGas)
+		maxFee = new(big.Int).Set(p.MaxFeePerGas)
+		if p.Nonce >= 0 {
+			return nonce, maxPriority, maxFee, nil
+		}
+	}
+
+	tp, err := capability.FetchTxParams(ctx, c.cli, c.caps, c.fromAddr)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("get nonce failed: %w", err)
+	}
+	if p.Nonce < 0 {
+		nonce = tp.Nonce
+	}
+	if manualFee {
+		return nonce, maxPriority, maxFee, nil
+	}
+
+	if tp.TipCap != nil {
+		// maxFee = baseFee + tip * 2，简化做法：用 tip 的若干倍兜底
+		maxPriority = tp.TipCap
+		maxFee = new(big.Int).Mul(maxPriority, big.NewInt(20))
+		return nonce, maxPriority, maxFee, nil
+	}
+	// 节点不支持 EIP-1559 建议：回退到旧接口
+	gp, err := c.cli.SuggestGasPrice(ctx)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("fee suggest failed: %w", err)
+	}
+	return nonce, gp, new(big.Int).Mul(gp, big.NewInt(2)), nil
+}
+
+// gasCost 回执的实际 gas 费用；节点未返回 effectiveGasPrice 时为 nil
+func gasCost(r *gethtypes.Receipt) *big.Int {
+	if r.EffectiveGasPrice == nil {
+		return nil
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(r.GasUsed), r.EffectiveGasPrice)
 }
 
 func waitMined(ctx context.Context, cli *ethclient.Client, txHash common.Hash) (*gethtypes.Receipt, error) {
 	t := time.NewTicker(2 * time.Second)
 	defer t.Stop()
-	timeout := time.After(120 * time.Second) // 2 分钟兜底
+	// ctx 没有时限时 2 分钟兜底；有时限时以调用方为准
+	var timeout <-chan time.Time
+	if _, ok := ctx.Deadline(); !ok {
+		timeout = time.After(120 * time.Second)
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		case <-timeout:
-			return nil, fmt.Errorf("timeout waiting for receipt: %s", txHash.Hex())
+			return nil, fmt.Errorf("timeout waiting for receipt %s: %w", txHash.Hex(), context.DeadlineExceeded)
 		case <-t.C:
 			rcpt, err := cli.TransactionReceipt(ctx, txHash)
 			if err == nil && rcpt != nil {
@@ -308,7 +344,7 @@ func (c *Client) DebugPrintAccountState(ctx context.Context) {
 
 // SendDepositNoWait 组装并发送 deposit 交易（不等待回执）
 func (c *Client) SendDepositNoWait(ctx context.Context, p *DepositParams) (*TxResult, error) {
-	if p.AmountWei == nil || p.AmountWei.Sign() <= 0 {
+	if !p.Unchecked && p.Amount.Sign() <= 0 {
 		return nil, fmt.Errorf("amount must be > 0 wei")
 	}
 	contract := common.HexToAddress(p.Contract)
@@ -324,74 +360,58 @@ func (c *Client) SendDepositNoWait(ctx context.Context, p *DepositParams) (*TxRe
 		return nil, fmt.Errorf("abi pack failed: %w", err)
 	}
 
-	// nonce
-	var nonce uint64
-	if p.Nonce >= 0 {
-		nonce = uint64(p.Nonce)
-	} else {
-		nonce, err = c.cli.PendingNonceAt(ctx, c.fromAddr)
-		if err != nil {
-			return nil, fmt.Errorf("get nonce failed: %w", err)
-		}
-	}
+	// 取 nonce / 费用与签名广播共用 SubmitTimeout，gas 估算单独用 EstimateTimeout
+	sctx, cancel := stepContext(ctx, p.SubmitTimeout)
+	defer cancel()
 
-	// EIP-1559 fee（与原函数保持一致）
+	// nonce 与 EIP-1559 fee
+	var nonce uint64
 	var maxPriority, maxFee *big.Int
-	if p.MaxPriorityFeePerGas != nil && p.MaxFeePerGas != nil {
-		maxPriority = new(big.Int).Set(p.MaxPriorityFeePerGas)
-		maxFee = new(big.Int).Set(p.MaxFeePerGas)
-	} else {
-		maxPriority, err = c.cli.SuggestGasTipCap(ctx)
-		if err != nil {
-			gp, e2 := c.cli.SuggestGasPrice(ctx)
-			if e2 != nil {
-				return nil, fmt.Errorf("fee suggest failed: %v / %v", err, e2)
-			}
-			maxPriority = gp
-			maxFee = new(big.Int).Mul(gp, big.NewInt(2))
-		} else {
-			maxFee = new(big.Int).Mul(maxPriority, big.NewInt(20))
-		}
+	err = c.withRetry(sctx, "获取 nonce/费用", func() (err error) {
+		nonce, maxPriority, maxFee, err = c.nonceAndFees(sctx, p.txOptions())
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// gas 估算
 	gasLimit := p.GasLimit
 	if gasLimit == 0 {
+		ectx, cancel := stepContext(ctx, p.EstimateTimeout)
+		defer cancel()
 		call := ethereum.CallMsg{
 			From:      c.fromAddr,
 			To:        &contract,
 			GasFeeCap: maxFee,
 			GasTipCap: maxPriority,
-			Value:     p.AmountWei,
+			Value:     p.Amount.Wei(),
 			Data:      data,
 		}
-		est, e := c.cli.EstimateGas(ctx, call)
+		var est uint64
+		e := c.withRetry(ectx, "估算 gas", func() (err error) {
+			est, err = c.cli.EstimateGas(ectx, call)
+			return err
+		})
 		if e != nil {
 			return nil, fmt.Errorf("estimate gas failed: %w", e)
 		}
 		gasLimit = uint64(float64(est)*1.15) + 300000
 	}
 
-	// 构造并签名
-	tx := gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+	// 构造、签名并发送，不等待
+	signedTx, err := c.signAndSend(sctx, &gethtypes.DynamicFeeTx{
 		ChainID:   c.chainID,
 		Nonce:     nonce,
 		To:        &contract,
-		Value:     p.AmountWei,
+		Value:     p.Amount.Wei(),
 		Data:      data,
 		Gas:       gasLimit,
 		GasTipCap: maxPriority,
 		GasFeeCap: maxFee,
 	})
-	signer := gethtypes.LatestSignerForChainID(c.chainID)
-	signedTx, err := gethtypes.SignTx(tx, signer, c.privKey)
 	if err != nil {
-		return nil, fmt.Errorf("sign tx failed: %w", err)
-	}
-
-	// 只发送，不等待
-	if err := c.cli.SendTransaction(ctx, signedTx); err != nil {
-		return nil, fmt.Errorf("send tx failed: %w", err)
+		return nil, err
 	}
 
 	return &TxResult{
@@ -0,0 +1,79 @@
+package deposit
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 存款合约只读方法 ABI（与以太坊存款合约一致；get_deposit_count 返回 8 字节小端）
+const depositViewABI = `[
+ {"inputs":[],"name":"get_deposit_root","outputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"stateMutability":"view","type":"function"},
+ {"inputs":[],"name":"get_deposit_count","outputs":[{"internalType":"bytes","name":"","type":"bytes"}],"stateMutability":"view","type":"function"}
+]`
+
+var depositView = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(depositViewABI))
+	if err != nil {
+		panic("deposit: parse view abi: " + err.Error())
+	}
+	return parsed
+}()
+
+// DepositRootAt 在区块 block（nil 为 latest）调用合约 get_deposit_root
+func DepositRootAt(ctx context.Context, cli ethereum.ContractCaller, contract common.Address, block *big.Int) (common.Hash, error) {
+	vals, err := callView(ctx, cli, contract, block, "get_deposit_root")
+	if err != nil {
+		return common.Hash{}, err
+	}
+	root, ok := vals[0].([32]byte)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("get_deposit_root: unexpected return %T", vals[0])
+	}
+	return root, nil
+}
+
+// DepositCountAt 在区块 block（nil 为 latest）调用合约 get_deposit_count
+func DepositCountAt(ctx context.Context, cli ethereum.ContractCaller, contract common.Address, block *big.Int) (uint64, error) {
+	vals, err := callView(ctx, cli, contract, block, "get_deposit_count")
+	if err != nil {
+		return 0, err
+	}
+	b, _ := vals[0].([]byte)
+	if len(b) != 8 {
+		return 0, fmt.Errorf("get_deposit_count: expect 8 bytes, got %d", len(b))
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func callView(ctx context.Context, cli ethereum.ContractCaller, contract common.Address, block *big.Int, method string) ([]any, error) {
+	data, err := depositView.Pack(method)
+	if err != nil {
+		return nil, err
+	}
+	out, err := cli.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, block)
+	if err != nil {
+		return nil, fmt.Errorf("%s: eth_call failed: %w", method, err)
+	}
+	vals, err := depositView.Unpack(method, out)
+	if err != nil || len(vals) != 1 {
+		return nil, fmt.Errorf("%s: decode %d bytes: %v", method, len(out), err)
+	}
+	return vals, nil
+}
+
+// GetDepositRoot 合约当前（latest）的存款树根
+func (c *Client) GetDepositRoot(ctx context.Context, contract common.Address) (common.Hash, error) {
+	return DepositRootAt(ctx, c.cli, contract, nil)
+}
+
+// GetDepositCount 合约当前（latest）的存款数
+func (c *Client) GetDepositCount(ctx context.Context, contract common.Address) (uint64, error) {
+	return DepositCountAt(ctx, c.cli, contract, nil)
+}
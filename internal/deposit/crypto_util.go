@@ -6,9 +6,12 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"strings"
+	"hash"
+	"runtime"
+	"sync"
 
-	"github.com/herumi/bls-eth-go-binary/bls"
+	"n42-test/internal/blsutil"
+	"n42-test/internal/hexutil"
 )
 
 /*
@@ -21,22 +24,9 @@ import (
 - uint64：小端写入 8 字节，放在 32 字节 chunk 前 8 字节，其余补 0
 - Container：把各字段的 32B 根顺序拼接成叶子做 merkleize
 - signing_root = HTR(SigningData{ObjectRoot, Domain})
-- DOMAIN_DEPOSIT = 0x03000000 + 28*0x00
+- 存款域 = compute_domain(DOMAIN_DEPOSIT, fork_version, genesis_validators_root)，见 DepositDomainConfig
 */
 
-// ---------------- Domain 常量 ----------------
-
-// 后面需要手动修改domain！！！！！
-var DOMAIN_DEPOSIT = func() [32]byte {
-	b, err := hex.DecodeString("03000000f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a9")
-	if err != nil {
-		panic("invalid DOMAIN_DEPOSIT hex")
-	}
-	var arr [32]byte
-	copy(arr[:], b)
-	return arr
-}()
-
 // ---------------- SSZ 基础工具 ----------------
 
 var zeroChunk = [32]byte{}
@@ -59,31 +49,119 @@ func chunkify(data []byte) [][32]byte {
 	return out
 }
 
+// 叶子数达到该阈值才按子树并行；容器字段、bytesN 这类小树仍单协程计算，避免 goroutine 开销
+const parallelLeaves = 1 << 12
+
+// sha256 实例复用，避免每个节点 New 一次
+var hasherPool = sync.Pool{New: func() any { return sha256.New() }}
+
+// hashPair 用 h 计算 sha256(a || b) 写入 out
+func hashPair(h hash.Hash, out, a, b *[32]byte) {
+	h.Reset()
+	h.Write(a[:])
+	h.Write(b[:])
+	h.Sum(out[:0])
+}
+
+// 零子树哈希表：zeroHashes[d] 为深度 d 的全零子树的根（zeroHashes[0] 即零块）。
+// 补齐的叶子直接用这里的值代替，深度 32 的存款树、带上限的列表不用真的去哈希大量零块
+const maxZeroDepth = 64
+
+var zeroHashes = func() (z [maxZeroDepth + 1][32]byte) {
+	h := sha256.New()
+	for d := 1; d <= maxZeroDepth; d++ {
+		hashPair(h, &z[d], &z[d-1], &z[d-1])
+	}
+	return z
+}()
+
 // Merkleize：对若干 32 字节块做二叉 Merkle，补到 2^k 叶子
 func merkleize(leaves [][32]byte) [32]byte {
 	if len(leaves) == 0 {
 		return zeroChunk
 	}
-	// 扩展到 2^k 叶子
-	size := 1
-	for size < len(leaves) {
-		size <<= 1
+	depth := 0
+	for 1<<depth < len(leaves) {
+		depth++
+	}
+	return merkleizeDepth(leaves, depth)
+}
+
+// merkleizeDepth 把 leaves 视为深度 depth（2^depth 个叶子）的树的前几个叶子、其余为零块求根；
+// 调用方保证 len(leaves) <= 2^depth
+func merkleizeDepth(leaves [][32]byte, depth int) [32]byte {
+	if len(leaves) == 0 {
+		return zeroHashes[depth]
 	}
-	nodes := make([][32]byte, size)
+	nodes := make([][32]byte, len(leaves))
 	copy(nodes, leaves)
-	for i := len(leaves); i < size; i++ {
-		nodes[i] = zeroChunk
-	}
-	// 自底向上
-	for width := size; width > 1; width >>= 1 {
-		next := make([][32]byte, width/2)
-		for i := 0; i < width; i += 2 {
-			h := sha256.New()
-			h.Write(nodes[i][:])
-			h.Write(nodes[i+1][:])
-			copy(next[i/2][:], h.Sum(nil))
+	return merkleizeInPlace(nodes, depth, runtime.GOMAXPROCS(0))
+}
+
+// merkleizeLimit SSZ List[..., limit] 的 merkleize：按 limit 决定树深，再由调用方 mix_in_length
+func merkleizeLimit(leaves [][32]byte, limit uint64) ([32]byte, error) {
+	if uint64(len(leaves)) > limit {
+		return [32]byte{}, fmt.Errorf("merkleize: %d chunks exceed limit %d", len(leaves), limit)
+	}
+	depth := 0
+	for depth < maxZeroDepth && uint64(1)<<depth < limit {
+		depth++
+	}
+	return merkleizeDepth(leaves, depth), nil
+}
+
+// mixInLength SSZ 列表根 = sha256(root || uint256_le(length))
+func mixInLength(root [32]byte, length uint64) [32]byte {
+	var l, out [32]byte
+	binary.LittleEndian.PutUint64(l[:8], length)
+	h := hasherPool.Get().(hash.Hash)
+	hashPair(h, &out, &root, &l)
+	hasherPool.Put(h)
+	return out
+}
+
+// merkleizeInPlace 对深度 depth 的树原地自底向上求根（会覆盖 nodes），nodes 之后的叶子视为零块；
+// 节点足够多且 par > 1 时左右子树各开一个协程，par 为还可分配的并行度
+func merkleizeInPlace(nodes [][32]byte, depth, par int) [32]byte {
+	if depth == 0 {
+		return nodes[0]
+	}
+	var root [32]byte
+	if par > 1 && len(nodes) >= parallelLeaves {
+		left, right := zeroHashes[depth-1], zeroHashes[depth-1]
+		if half := 1 << (depth - 1); depth < 63 && len(nodes) > half {
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				left = merkleizeInPlace(nodes[:half], depth-1, par/2)
+			}()
+			right = merkleizeInPlace(nodes[half:], depth-1, par-par/2)
+			wg.Wait()
+		} else {
+			// 右半棵全零，不必开协程
+			left = merkleizeInPlace(nodes, depth-1, par)
+		}
+
+		h := hasherPool.Get().(hash.Hash)
+		hashPair(h, &root, &left, &right)
+		hasherPool.Put(h)
+		return root
+	}
+
+	h := hasherPool.Get().(hash.Hash)
+	defer hasherPool.Put(h)
+	n := len(nodes)
+	for d := 0; d < depth; d++ {
+		// 第 i/2 个父节点只依赖 i、i+1，且 i/2 <= i，可以原地覆盖；落单的节点与同层零子树配对
+		for i := 0; i < n; i += 2 {
+			right := &zeroHashes[d]
+			if i+1 < n {
+				right = &nodes[i+1]
+			}
+			hashPair(h, &nodes[i/2], &nodes[i], right)
 		}
-		nodes = next
+		n = (n + 1) / 2
 	}
 	return nodes[0]
 }
@@ -150,34 +228,21 @@ func htrDepositData(pubkey48 []byte, wc32 []byte, amountGwei uint64, sig96 []byt
 
 // ---------------- 对外工具函数 ----------------
 
-// 严格长度 hex 解码
-func decodeExactHex(s string, want int) ([]byte, error) {
-	raw := strings.TrimPrefix(strings.TrimSpace(s), "0x")
-	b, err := hex.DecodeString(raw)
-	if err != nil {
-		return nil, fmt.Errorf("hex decode failed: %w", err)
-	}
-	if len(b) != want {
-		return nil, fmt.Errorf("invalid length %d want %d", len(b), want)
-	}
-	return b, nil
-}
-
-// 计算：BLS 签名(96B hex) + deposit_data_root(32B hex)
+// 计算：BLS 签名(96B hex) + deposit_data_root(32B hex)；签名使用 dc 给出的存款域
 func ComputeDepositSignatureAndRoot(
 	pubkeyHex string,
 	withdrawalCredHex string,
 	amountGwei uint64,
 	blsSkHex string,
+	dc DepositDomainConfig,
 ) (signatureHex string, depositDataRootHex string, err error) {
 
-	EnsureBLS()
 	// 1) 解析 hex
-	pubkey, err := decodeExactHex(pubkeyHex, 48)
+	pubkey, err := hexutil.DecodeFixed(pubkeyHex, hexutil.PubkeyLen)
 	if err != nil {
 		return "", "", fmt.Errorf("pubkey: %w", err)
 	}
-	wc, err := decodeExactHex(withdrawalCredHex, 32)
+	wc, err := hexutil.DecodeFixed(withdrawalCredHex, hexutil.HashLen)
 	if err != nil {
 		return "", "", fmt.Errorf("withdrawal_credentials: %w", err)
 	}
@@ -188,21 +253,20 @@ func ComputeDepositSignatureAndRoot(
 		return "", "", err
 	}
 
-	// 3) signing_root = HTR(SigningData{msgRoot, DOMAIN_DEPOSIT})
-	signingRoot := htrSigningData(msgRoot, DOMAIN_DEPOSIT)
+	// 3) signing_root = HTR(SigningData{msgRoot, domain})
+	signingRoot := htrSigningData(msgRoot, dc.Domain())
 
-	// 4) BLS 签名 (G2，96B)
-	// bls.Init(bls.BLS12_381)
-	var sk bls.SecretKey
-	if err := sk.SetHexString(strings.TrimPrefix(blsSkHex, "0x")); err != nil {
-		return "", "", fmt.Errorf("set BLS secret key failed: %w", err)
+	// 4) BLS 签名 (G2，96B)，私钥按 blsutil 默认选项的字节序/ETH mode 解析
+	sk, err := blsutil.LoadSecretKey(blsSkHex, blsutil.DefaultKeyOptions())
+	if err != nil {
+		return "", "", err
 	}
-	sig := sk.SignByte(signingRoot[:])
+	sig := blsutil.Sign(sk, signingRoot[:])
 	sigBytes := sig.Serialize()
-	if len(sigBytes) != 96 {
+	if len(sigBytes) != blsutil.SignatureLen {
 		return "", "", errors.New("unexpected bls signature length")
 	}
-	signatureHex = "0x" + hex.EncodeToString(sigBytes)
+	signatureHex = blsutil.SignatureHex(sig)
 
 	// 5) deposit_data_root = HTR(DepositData{..., signature})
 	ddRoot, err := htrDepositData(pubkey, wc, amountGwei, sigBytes)
@@ -216,31 +280,20 @@ func ComputeDepositSignatureAndRoot(
 // 从执行层地址(20B)构造 ETH1 类型的 withdrawal_credentials：
 // wc = 0x01 || 11*0x00 || sha256(address)[12:]
 func ComputeWithdrawalCredentialsFromEth1(executionAddressHex string) (string, error) {
-	addrBytes, err := hex.DecodeString(strings.TrimPrefix(executionAddressHex, "0x"))
-	if err != nil {
-		return "", fmt.Errorf("decode address hex failed: %w", err)
-	}
-	if len(addrBytes) != 20 {
-		return "", fmt.Errorf("execution address must be 20 bytes")
-	}
-	// hash := sha256.Sum256(addrBytes)
-	var wc [32]byte
-	wc[0] = 0x01
-	copy(wc[12:], addrBytes)
-	return "0x" + hex.EncodeToString(wc[:]), nil
+	return addressCredentials(WCTypeEth1, executionAddressHex)
 }
 
 // 根据 已给定的 signature(96B hex) 计算 deposit_data_root（32B hex）
 func ComputeDepositDataRoot(pubkeyHex string, withdrawalCredHex string, amountGwei uint64, signatureHex string) (string, error) {
-	pubkey, err := decodeExactHex(pubkeyHex, 48)
+	pubkey, err := hexutil.DecodeFixed(pubkeyHex, hexutil.PubkeyLen)
 	if err != nil {
 		return "", fmt.Errorf("pubkey: %w", err)
 	}
-	wc, err := decodeExactHex(withdrawalCredHex, 32)
+	wc, err := hexutil.DecodeFixed(withdrawalCredHex, hexutil.HashLen)
 	if err != nil {
 		return "", fmt.Errorf("withdrawal_credentials: %w", err)
 	}
-	sig, err := decodeExactHex(signatureHex, 96)
+	sig, err := hexutil.DecodeFixed(signatureHex, hexutil.SignatureLen)
 	if err != nil {
 		return "", fmt.Errorf("signature: %w", err)
 	}
@@ -0,0 +1,80 @@
+package deposit
+
+import (
+	"fmt"
+
+	"n42-test/internal/hexutil"
+	"n42-test/internal/netprofile"
+)
+
+// DepositDomainConfig 存款签名域的参数。零值即 fork_version 0x00000000、genesis_validators_root 为零的存款域
+// （主网与本地 N42 链），fork_version 不同的 devnet 设置 ForkVersion 即可，不必重新编译
+type DepositDomainConfig struct {
+	ForkVersion [4]byte
+	// GenesisValidatorsRoot 规范中存款域固定用零（存款可能早于创世）；只有改过存款域的链才需要设置
+	GenesisValidatorsRoot [32]byte
+}
+
+// Domain compute_domain(DOMAIN_DEPOSIT, fork_version, genesis_validators_root)
+func (c DepositDomainConfig) Domain() [32]byte {
+	var current [32]byte
+	copy(current[:], c.ForkVersion[:])
+	forkDataRoot := htrContainer(current, c.GenesisValidatorsRoot)
+	var d [32]byte
+	d[0] = 0x03 // DOMAIN_DEPOSIT 类型
+	copy(d[4:], forkDataRoot[:28])
+	return d
+}
+
+func (c DepositDomainConfig) String() string {
+	s := "fork_version=" + hexutil.Encode(c.ForkVersion[:])
+	if c.GenesisValidatorsRoot != ([32]byte{}) {
+		s += " genesis_validators_root=" + hexutil.Encode(c.GenesisValidatorsRoot[:])
+	}
+	return s
+}
+
+// ParseDepositDomainConfig 解析十六进制的 fork_version（4 字节）与 genesis_validators_root（32 字节），0x 可选，空串取零
+func ParseDepositDomainConfig(forkVersion, genesisValidatorsRoot string) (DepositDomainConfig, error) {
+	var c DepositDomainConfig
+	if forkVersion != "" {
+		fv, err := ParseForkVersion(forkVersion)
+		if err != nil {
+			return c, err
+		}
+		c.ForkVersion = fv
+	}
+	if genesisValidatorsRoot != "" {
+		b, err := hexutil.DecodeFixed(genesisValidatorsRoot, hexutil.HashLen)
+		if err != nil {
+			return c, fmt.Errorf("genesis_validators_root: %w", err)
+		}
+		c.GenesisValidatorsRoot = [32]byte(b)
+	}
+	return c, nil
+}
+
+// DomainConfigFromProfile 取配置档中的存款域；非空的 forkVersion / genesisValidatorsRoot 覆盖配置档
+func DomainConfigFromProfile(p netprofile.Profile, forkVersion, genesisValidatorsRoot string) (DepositDomainConfig, error) {
+	if forkVersion == "" {
+		forkVersion = p.DepositForkVersion
+	}
+	if genesisValidatorsRoot == "" {
+		genesisValidatorsRoot = p.DepositGenesisValidatorsRoot
+	}
+	return ParseDepositDomainConfig(forkVersion, genesisValidatorsRoot)
+}
+
+// DepositDomain compute_domain(DOMAIN_DEPOSIT, fork_version, ZERO_HASH)：按规范只由 fork_version 决定的存款域
+func DepositDomain(forkVersion [4]byte) [32]byte {
+	return DepositDomainConfig{ForkVersion: forkVersion}.Domain()
+}
+
+// ParseForkVersion 解析 4 字节 fork_version（十六进制，0x 可选）
+func ParseForkVersion(s string) ([4]byte, error) {
+	b, err := hexutil.DecodeFixed(s, 4)
+	if err != nil {
+		return [4]byte{}, fmt.Errorf("fork_version: %w", err)
+	}
+	return [4]byte(b), nil
+}
@@ -0,0 +1,179 @@
+package deposit
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"n42-test/internal/hexutil"
+	"n42-test/internal/units"
+)
+
+// DepositEvent 事件 ABI（与以太坊存款合约一致；amount / index 为 8 字节小端）
+const depositEventABI = `[{"anonymous":false,"inputs":[
+ {"indexed":false,"internalType":"bytes","name":"pubkey","type":"bytes"},
+ {"indexed":false,"internalType":"bytes","name":"withdrawal_credentials","type":"bytes"},
+ {"indexed":false,"internalType":"bytes","name":"amount","type":"bytes"},
+ {"indexed":false,"internalType":"bytes","name":"signature","type":"bytes"},
+ {"indexed":false,"internalType":"bytes","name":"index","type":"bytes"}
+],"name":"DepositEvent","type":"event"}]`
+
+// DefaultLogChunk 每次 eth_getLogs 查询的区块跨度
+const DefaultLogChunk = 5000
+
+// DepositEvent 一条已解码的 DepositEvent 日志
+type DepositEvent struct {
+	Index                 uint64 `json:"index"`
+	Pubkey                string `json:"pubkey"`
+	WithdrawalCredentials string `json:"withdrawal_credentials"`
+	AmountGwei            uint64 `json:"amount_gwei"`
+	Signature             string `json:"signature"`
+	BlockNumber           uint64 `json:"block_number"`
+	BlockHash             string `json:"block_hash"`
+	TxHash                string `json:"tx_hash"`
+	LogIndex              uint   `json:"log_index"`
+}
+
+// depositEvent 解析后的 DepositEvent ABI
+var depositEvent = func() abi.Event {
+	parsed, err := abi.JSON(strings.NewReader(depositEventABI))
+	if err != nil {
+		panic("deposit: parse event abi: " + err.Error())
+	}
+	return parsed.Events["DepositEvent"]
+}()
+
+// FilterDepositEvents 按 chunk 分段查询 [from, to] 内合约的 DepositEvent，按存款下标排序返回
+func FilterDepositEvents(ctx context.Context, cli ethereum.LogFilterer, contract common.Address, from, to, chunk uint64) ([]DepositEvent, error) {
+	ev := depositEvent
+	if chunk == 0 {
+		chunk = DefaultLogChunk
+	}
+
+	var out []DepositEvent
+	for start := from; start <= to; start += chunk {
+		end := min(start+chunk-1, to)
+		logs, err := cli.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{contract},
+			Topics:    [][]common.Hash{{ev.ID}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("eth_getLogs [%d, %d]: %w", start, end, err)
+		}
+		for _, l := range logs {
+			if l.Removed {
+				continue
+			}
+			e, err := decodeDepositLog(&l)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, e)
+		}
+		if end == to {
+			break
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out, nil
+}
+
+// ParseDepositEvents 解码回执日志中由 contract 发出的 DepositEvent（按日志顺序）
+func ParseDepositEvents(logs []*gethtypes.Log, contract common.Address) ([]DepositEvent, error) {
+	var out []DepositEvent
+	for _, l := range logs {
+		if l == nil || l.Removed || l.Address != contract || len(l.Topics) == 0 || l.Topics[0] != depositEvent.ID {
+			continue
+		}
+		e, err := decodeDepositLog(l)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func decodeDepositLog(l *gethtypes.Log) (DepositEvent, error) {
+	vals, err := depositEvent.Inputs.Unpack(l.Data)
+	if err != nil || len(vals) != 5 {
+		return DepositEvent{}, fmt.Errorf("decode DepositEvent in tx %s: %v", l.TxHash.Hex(), err)
+	}
+	amount, _ := vals[2].([]byte)
+	index, _ := vals[4].([]byte)
+	if len(amount) != 8 || len(index) != 8 {
+		return DepositEvent{}, fmt.Errorf("decode DepositEvent in tx %s: bad amount/index length", l.TxHash.Hex())
+	}
+	return DepositEvent{
+		Index:                 binary.LittleEndian.Uint64(index),
+		Pubkey:                hexBytes(vals[0]),
+		WithdrawalCredentials: hexBytes(vals[1]),
+		AmountGwei:            binary.LittleEndian.Uint64(amount),
+		Signature:             hexBytes(vals[3]),
+		BlockNumber:           l.BlockNumber,
+		BlockHash:             l.BlockHash.Hex(),
+		TxHash:                l.TxHash.Hex(),
+		LogIndex:              l.Index,
+	}, nil
+}
+
+// Mismatches 与提交的参数逐项比对（pubkey、withdrawal_credentials、amount、signature），
+// 返回不一致项的描述；全部一致时为空
+func (e DepositEvent) Mismatches(p *DepositParams) []string {
+	var out []string
+	same := func(name, got, want string) {
+		if hexutil.Normalize(got) != hexutil.Normalize(want) {
+			out = append(out, fmt.Sprintf("%s: event=%s submitted=%s", name, got, hexutil.Normalize(want)))
+		}
+	}
+	same("pubkey", e.Pubkey, p.PubkeyHex)
+	same("withdrawal_credentials", e.WithdrawalCredentials, p.WCHex)
+	same("signature", e.Signature, p.SignatureHex)
+	if got := units.FromGwei(e.AmountGwei); got.Cmp(p.Amount) != 0 {
+		out = append(out, fmt.Sprintf("amount: event=%s submitted=%s", got.Format(units.Gwei), p.Amount.Format(units.Gwei)))
+	}
+	return out
+}
+
+// checkDepositReceipt 从 res.Logs 中找出本笔质押的 DepositEvent 填入 res.Deposit，
+// 并与 p 比对；找不到事件或字段不一致都记入 res.DepositMismatch。revert 的交易不检查
+func checkDepositReceipt(res *TxResult, p *DepositParams) {
+	res.Deposit, res.DepositMismatch = nil, nil
+	if res.Status == 0 {
+		return
+	}
+	evs, err := ParseDepositEvents(res.Logs, common.HexToAddress(p.Contract))
+	if err != nil {
+		res.DepositMismatch = []string{err.Error()}
+		return
+	}
+	if len(evs) == 0 {
+		res.DepositMismatch = []string{"no DepositEvent in receipt"}
+		return
+	}
+	// 正常只有一条；多条时取 pubkey 相同的那条
+	ev := evs[0]
+	for _, e := range evs[1:] {
+		if hexutil.Normalize(e.Pubkey) == hexutil.Normalize(p.PubkeyHex) {
+			ev = e
+			break
+		}
+	}
+	res.Deposit = &ev
+	res.DepositMismatch = ev.Mismatches(p)
+}
+
+func hexBytes(v any) string {
+	b, _ := v.([]byte)
+	return "0x" + common.Bytes2Hex(b)
+}
@@ -0,0 +1,161 @@
+package deposit
+
+import (
+	"fmt"
+	"strings"
+
+	"n42-test/internal/hexutil"
+	"n42-test/internal/units"
+)
+
+// Fault 存款故障注入的类型：在正确的存款数据上做一处改动，检验合约 / 信标链是否按预期拒绝
+type Fault string
+
+const (
+	// FaultSigTamper 篡改签名，deposit_data_root 仍是正确签名的值：合约重算的 root 不符而 revert
+	FaultSigTamper Fault = "sig-tamper"
+	// FaultSigTamperRehash 篡改签名并按篡改后的签名重算 root：合约接受，信标链验签失败、忽略这笔存款
+	FaultSigTamperRehash Fault = "sig-tamper-rehash"
+	// FaultAmountMismatch 签名针对 Amount，交易却按另一个金额发送（root 按实际金额重算）：合约接受，信标链验签失败
+	FaultAmountMismatch Fault = "amount-mismatch"
+	// FaultBadWC 提款凭证截成 31 字节：合约检查长度而 revert
+	FaultBadWC Fault = "bad-wc"
+	// FaultBadRoot 签名正确，deposit_data_root 翻转一位：合约 revert
+	FaultBadRoot Fault = "bad-root"
+	// FaultShortPubkey 公钥截成 47 字节：合约检查长度而 revert
+	FaultShortPubkey Fault = "short-pubkey"
+	// FaultZeroAmount 交易不带金额：合约要求至少 1 ETH 而 revert
+	FaultZeroAmount Fault = "zero-amount"
+)
+
+// faultOrder 全部故障类型（FaultNames 的顺序）
+var faultOrder = []Fault{
+	FaultSigTamper, FaultSigTamperRehash, FaultAmountMismatch, FaultBadWC, FaultBadRoot, FaultShortPubkey, FaultZeroAmount,
+}
+
+// FaultNames 全部故障类型名
+func FaultNames() []string {
+	out := make([]string, len(faultOrder))
+	for i, f := range faultOrder {
+		out[i] = string(f)
+	}
+	return out
+}
+
+// LookupFault 按名字查找故障类型
+func LookupFault(name string) (Fault, error) {
+	for _, f := range faultOrder {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown fault %q (known: %s)", name, strings.Join(FaultNames(), "|"))
+}
+
+// ContractRejects 合约是否应 revert；false 表示合约接受、由信标链忽略这笔存款
+func (f Fault) ContractRejects() bool {
+	switch f {
+	case FaultSigTamperRehash, FaultAmountMismatch:
+		return false
+	}
+	return true
+}
+
+// FaultInput 构造故障存款所用的正确材料
+type FaultInput struct {
+	PubkeyHex  string
+	WCHex      string
+	BLSKeyHex  string // 验证者 BLS 私钥（按 blsutil 默认选项解析）
+	AmountGwei uint64
+	Domain     DepositDomainConfig // 签名使用的存款域
+	// MismatchAmountGwei amount-mismatch 时交易实际发送的金额；0 时为 AmountGwei 的两倍
+	MismatchAmountGwei uint64
+}
+
+// FaultDeposit 注入故障后的存款数据
+type FaultDeposit struct {
+	Fault        Fault
+	PubkeyHex    string
+	WCHex        string
+	SignatureHex string
+	RootHex      string
+	AmountGwei   uint64 // 交易实际发送的金额
+	Note         string // 与正确存款的差异
+}
+
+// ApplyFault 先按 in 计算正确的签名与 deposit_data_root，再施加故障 f
+func ApplyFault(f Fault, in FaultInput) (*FaultDeposit, error) {
+	sig, root, err := ComputeDepositSignatureAndRoot(in.PubkeyHex, in.WCHex, in.AmountGwei, in.BLSKeyHex, in.Domain)
+	if err != nil {
+		return nil, err
+	}
+	d := &FaultDeposit{
+		Fault:     f,
+		PubkeyHex: hexutil.Normalize(in.PubkeyHex), WCHex: hexutil.Normalize(in.WCHex),
+		SignatureHex: sig, RootHex: root, AmountGwei: in.AmountGwei,
+	}
+	switch f {
+	case FaultSigTamper:
+		d.SignatureHex = flipLastBit(sig)
+		d.Note = "签名末位翻转，root 仍为正确签名的值"
+	case FaultSigTamperRehash:
+		d.SignatureHex = flipLastBit(sig)
+		if d.RootHex, err = ComputeDepositDataRoot(d.PubkeyHex, d.WCHex, d.AmountGwei, d.SignatureHex); err != nil {
+			return nil, err
+		}
+		d.Note = "签名末位翻转，root 按篡改后的签名重算"
+	case FaultAmountMismatch:
+		d.AmountGwei = in.MismatchAmountGwei
+		if d.AmountGwei == 0 {
+			d.AmountGwei = in.AmountGwei * 2
+		}
+		if d.AmountGwei == in.AmountGwei {
+			return nil, fmt.Errorf("amount-mismatch: mismatch amount equals signed amount %d gwei", in.AmountGwei)
+		}
+		if d.RootHex, err = ComputeDepositDataRoot(d.PubkeyHex, d.WCHex, d.AmountGwei, d.SignatureHex); err != nil {
+			return nil, err
+		}
+		d.Note = fmt.Sprintf("签名针对 %d gwei，交易发送 %d gwei，root 按发送金额重算", in.AmountGwei, d.AmountGwei)
+	case FaultBadWC:
+		d.WCHex = d.WCHex[:len(d.WCHex)-2]
+		d.Note = "withdrawal_credentials 截为 31 字节"
+	case FaultBadRoot:
+		d.RootHex = flipLastBit(root)
+		d.Note = "签名正确，deposit_data_root 末位翻转"
+	case FaultShortPubkey:
+		d.PubkeyHex = d.PubkeyHex[:len(d.PubkeyHex)-2]
+		d.Note = "pubkey 截为 47 字节"
+	case FaultZeroAmount:
+		d.AmountGwei = 0
+		d.Note = "交易金额为 0"
+	default:
+		return nil, fmt.Errorf("unknown fault %q", f)
+	}
+	return d, nil
+}
+
+// Params 组装发送参数；跳过本地检查，让格式错误的数据也能送到合约
+func (d *FaultDeposit) Params(contract, senderKeyHex, rpc string) *DepositParams {
+	return &DepositParams{
+		Contract:      contract,
+		PrivateKeyHex: senderKeyHex,
+		RPC:           rpc,
+		PubkeyHex:     d.PubkeyHex,
+		WCHex:         d.WCHex,
+		SignatureHex:  d.SignatureHex,
+		RootHex:       d.RootHex,
+		Amount:        units.FromGwei(d.AmountGwei),
+		Nonce:         -1,
+		Unchecked:     true,
+	}
+}
+
+// flipLastBit 翻转十六进制串最后一个字节的最低位，长度不变
+func flipLastBit(h string) string {
+	b, err := hexutil.Decode(h)
+	if err != nil || len(b) == 0 {
+		return h
+	}
+	b[len(b)-1] ^= 0x01
+	return hexutil.Encode(b)
+}
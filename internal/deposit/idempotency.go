@@ -0,0 +1,41 @@
+package deposit
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"n42-test/internal/hexutil"
+)
+
+// IdempotencyKey 一条存款数据的幂等键：sha256(pubkey || withdrawal_credentials || amount_gwei(8B 小端) || signature)，0x 十六进制。
+// 只取决于存款数据本身，与发送账户、nonce、费用无关：提价重发、换账户重跑得到同一个键，
+// 跨运行据此识别“同一笔存款已经发过”。字段长度不做检查（故障注入的畸形数据也有确定的键）
+func IdempotencyKey(pubkeyHex, wcHex string, amountGwei uint64, signatureHex string) (string, error) {
+	h := sha256.New()
+	for _, f := range []struct{ name, hex string }{{"pubkey", pubkeyHex}, {"withdrawal_credentials", wcHex}} {
+		b, err := hexutil.Decode(f.hex)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", f.name, err)
+		}
+		h.Write(b)
+	}
+	var amt [8]byte
+	binary.LittleEndian.PutUint64(amt[:], amountGwei)
+	h.Write(amt[:])
+	sig, err := hexutil.Decode(signatureHex)
+	if err != nil {
+		return "", fmt.Errorf("signature: %w", err)
+	}
+	h.Write(sig)
+	return hexutil.Encode(h.Sum(nil)), nil
+}
+
+// IdempotencyKey 本条参数的幂等键，见 IdempotencyKey
+func (p *DepositParams) IdempotencyKey() (string, error) {
+	gwei, err := p.Amount.Gwei()
+	if err != nil {
+		return "", fmt.Errorf("amount: %w", err)
+	}
+	return IdempotencyKey(p.PubkeyHex, p.WCHex, gwei, p.SignatureHex)
+}
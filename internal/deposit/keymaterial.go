@@ -0,0 +1,49 @@
+package deposit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// KeyMaterial 单笔存款的密钥材料，与 accounts.json（keygen 输出）的条目同形
+type KeyMaterial struct {
+	ValidatorPublicKey    string `json:"validator-public-key"`
+	ValidatorPrivateKey   string `json:"validator-private-key"`
+	WithdrawalAddress     string `json:"withdrawal-address"`
+	WithdrawalCredentials string `json:"withdrawal-credentials,omitempty"` // 可选：直接给出 32 字节凭证，优先于 withdrawal-address
+	DepositPrivateKey     string `json:"deposit-private-key"`
+}
+
+// Credentials 提款凭证：WithdrawalCredentials 优先，否则由 WithdrawalAddress 生成 0x01 凭证
+func (km KeyMaterial) Credentials() (string, error) {
+	if km.WithdrawalCredentials != "" {
+		return km.WithdrawalCredentials, nil
+	}
+	return ComputeWithdrawalCredentialsFromEth1(km.WithdrawalAddress)
+}
+
+// ReadKeyMaterial 读取单个对象，或数组（accounts.json）中的第 index 条
+func ReadKeyMaterial(path string, index int) (KeyMaterial, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return KeyMaterial{}, err
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		var items []KeyMaterial
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return KeyMaterial{}, fmt.Errorf("解析 %s: %w", path, err)
+		}
+		if index < 0 || index >= len(items) {
+			return KeyMaterial{}, fmt.Errorf("%s 只有 %d 条，-index %d 越界", path, len(items), index)
+		}
+		return items[index], nil
+	}
+	var km KeyMaterial
+	if err := json.Unmarshal(raw, &km); err != nil {
+		return KeyMaterial{}, fmt.Errorf("解析 %s: %w", path, err)
+	}
+	return km, nil
+}
@@ -0,0 +1,44 @@
+package deposit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// NonceManager 为同一发送账户的并发交易在本地分配连续 nonce：
+// 首次从链上 pending nonce 起步，之后逐个递增；某笔发送失败时调用 Reset，
+// 下一次分配会重新从链上同步，补上被跳过的 nonce。
+type NonceManager struct {
+	c      *Client
+	mu     sync.Mutex
+	next   uint64
+	synced bool
+}
+
+func NewNonceManager(c *Client) *NonceManager {
+	return &NonceManager{c: c}
+}
+
+// Next 分配下一个 nonce，可直接填入 TxOptions.Nonce
+func (m *NonceManager) Next(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.synced {
+		n, err := m.c.cli.PendingNonceAt(ctx, m.c.fromAddr)
+		if err != nil {
+			return 0, fmt.Errorf("get pending nonce failed: %w", err)
+		}
+		m.next, m.synced = n, true
+	}
+	n := m.next
+	m.next++
+	return int64(n), nil
+}
+
+// Reset 丢弃本地计数，下次 Next 重新读取 pending nonce
+func (m *NonceManager) Reset() {
+	m.mu.Lock()
+	m.synced = false
+	m.mu.Unlock()
+}
@@ -0,0 +1,32 @@
+package deposit
+
+import (
+	"fmt"
+
+	"n42-test/internal/blsutil"
+	"n42-test/internal/hexutil"
+)
+
+// VerifyDepositSignature 按 is_valid_deposit_signature 用 dc 的存款域（与 ComputeDepositSignatureAndRoot 一致）
+// 验证存款签名；公钥/凭证/签名格式不对时返回 error。签名无效的存款在执行层照常成功，但会被信标链忽略
+func VerifyDepositSignature(pubkeyHex, wcHex string, amountGwei uint64, sigHex string, dc DepositDomainConfig) (bool, error) {
+	return VerifyDepositSignatureInDomain(pubkeyHex, wcHex, amountGwei, sigHex, dc.Domain())
+}
+
+// VerifyDepositSignatureInDomain 同 VerifyDepositSignature，使用指定的存款域（如 DepositDomain(fork_version)）
+func VerifyDepositSignatureInDomain(pubkeyHex, wcHex string, amountGwei uint64, sigHex string, domain [32]byte) (bool, error) {
+	pubkey, err := hexutil.DecodeFixed(pubkeyHex, hexutil.PubkeyLen)
+	if err != nil {
+		return false, fmt.Errorf("pubkey: %w", err)
+	}
+	wc, err := hexutil.DecodeFixed(wcHex, hexutil.HashLen)
+	if err != nil {
+		return false, fmt.Errorf("withdrawal_credentials: %w", err)
+	}
+	msgRoot, err := htrDepositMessage(pubkey, wc, amountGwei)
+	if err != nil {
+		return false, err
+	}
+	signingRoot := htrSigningData(msgRoot, domain)
+	return blsutil.VerifyHex(pubkeyHex, signingRoot[:], sigHex)
+}
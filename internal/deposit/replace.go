@@ -0,0 +1,161 @@
+package deposit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	defaultBumpPercent  = 12 // 节点替换同 nonce 交易要求至少 +10%，留一点余量
+	defaultStuckTimeout = 10 * time.Minute
+	defaultMaxFeeFactor = 4 // 未指定 MaxFeePerGas 时，fee cap 最多提到初始值的 4 倍
+)
+
+// StuckTxPolicy 交易长时间未打包时的处理：每过 After 仍未上链，就用同一 nonce、提高 BumpPercent 的
+// tip 与 fee cap 重新签名广播，直到任一版本上链；fee cap 将超过 MaxFeePerGas 时不再提价，只继续等待。
+type StuckTxPolicy struct {
+	After        time.Duration // 未打包多久后提价重发（必填，>0）
+	BumpPercent  int           // 每次提价的百分比；<10 时取 12
+	MaxFeePerGas *big.Int      // fee cap 上限；nil 时为初始 fee cap 的 4 倍
+	Timeout      time.Duration // 总等待上限；0 表示 10 分钟
+}
+
+// ErrStuckTx 超过等待上限仍未上链
+var ErrStuckTx = errors.New("tx not mined before stuck-tx timeout")
+
+// ReplaceByFee 等待 tx 上链，按 policy 对卡住的交易做同 nonce 提价替换。
+// 返回上链的那个版本及其回执；任何时刻只有一个版本能上链，不会重复执行。
+func (c *Client) ReplaceByFee(ctx context.Context, tx *gethtypes.Transaction, policy StuckTxPolicy) (*gethtypes.Transaction, *gethtypes.Receipt, error) {
+	if policy.After <= 0 {
+		return nil, nil, fmt.Errorf("stuck-tx policy: After must be > 0")
+	}
+	if tx.Type() != gethtypes.DynamicFeeTxType {
+		return nil, nil, fmt.Errorf("stuck-tx policy: only EIP-1559 transactions can be replaced (type %d)", tx.Type())
+	}
+	bump := policy.BumpPercent
+	if bump < 10 {
+		bump = defaultBumpPercent
+	}
+	maxFee := policy.MaxFeePerGas
+	if maxFee == nil {
+		maxFee = new(big.Int).Mul(tx.GasFeeCap(), big.NewInt(defaultMaxFeeFactor))
+	}
+	timeout := policy.Timeout
+	if timeout <= 0 {
+		timeout = defaultStuckTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	signer := gethtypes.LatestSignerForChainID(c.chainID)
+	sent := []*gethtypes.Transaction{tx}
+	cur := tx
+	capped := false
+	for {
+		wait := min(policy.After, time.Until(deadline))
+		mined, rcpt, err := c.waitAny(ctx, sent, wait)
+		if err != nil || mined != nil {
+			return mined, rcpt, err
+		}
+		if !time.Now().Before(deadline) {
+			return cur, nil, fmt.Errorf("%w: %s (nonce %d, %d replacements)", ErrStuckTx, cur.Hash().Hex(), cur.Nonce(), len(sent)-1)
+		}
+		if capped {
+			continue
+		}
+
+		tip := scalePercent(cur.GasTipCap(), 100+bump)
+		feeCap := scalePercent(cur.GasFeeCap(), 100+bump)
+		if feeCap.Cmp(maxFee) > 0 {
+			capped = true
+			log.Printf("⚠️ 交易 %s（nonce %d）已卡住 %s，fee cap 将超过上限 %s wei，不再提价，继续等待",
+				cur.Hash().Hex(), cur.Nonce(), policy.After, maxFee)
+			continue
+		}
+		if tip.Cmp(feeCap) > 0 {
+			tip = feeCap
+		}
+		next, err := gethtypes.SignTx(gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+			ChainID:   c.chainID,
+			Nonce:     cur.Nonce(),
+			To:        cur.To(),
+			Value:     cur.Value(),
+			Data:      cur.Data(),
+			Gas:       cur.Gas(),
+			GasTipCap: tip,
+			GasFeeCap: feeCap,
+		}), signer, c.privKey)
+		if err != nil {
+			return cur, nil, fmt.Errorf("sign replacement failed: %w", err)
+		}
+		err = c.cli.SendTransaction(ctx, next)
+		switch {
+		case err == nil || isAlreadyKnown(err):
+		case isNonceTooLow(err):
+			// 某个已发出的版本刚好上链，下一轮即可查到回执
+			log.Printf("⚠️ 替换交易 nonce %d 时 nonce 已被使用，等待已发出版本的回执", cur.Nonce())
+			continue
+		case isUnderpriced(err):
+			// 提价幅度不够（节点要求更高），下一轮在此基础上继续提价
+			log.Printf("⚠️ 替换交易被拒（%v），下一轮继续提价", err)
+			cur = next
+			continue
+		default:
+			log.Printf("⚠️ 发送替换交易失败，继续等待原交易: %v", err)
+			continue
+		}
+		log.Printf("⛽ 交易 nonce %d 已卡住 %s，提价 %d%% 重发：%s → %s（tip %s，fee cap %s wei）",
+			cur.Nonce(), policy.After, bump, cur.Hash().Hex(), next.Hash().Hex(), tip, feeCap)
+		sent = append(sent, next)
+		cur = next
+	}
+}
+
+// waitAny 在 d 时间内轮询 sent 中各版本的回执，任一上链即返回该版本
+func (c *Client) waitAny(ctx context.Context, sent []*gethtypes.Transaction, d time.Duration) (*gethtypes.Transaction, *gethtypes.Receipt, error) {
+	t := time.NewTicker(2 * time.Second)
+	defer t.Stop()
+	timeout := time.After(d)
+	for {
+		for i := len(sent) - 1; i >= 0; i-- {
+			if rcpt, err := c.cli.TransactionReceipt(ctx, sent[i].Hash()); err == nil && rcpt != nil {
+				return sent[i], rcpt, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-timeout:
+			return nil, nil, nil
+		case <-t.C:
+		}
+	}
+}
+
+// waitStuck 按 policy 等待 txHash 上链（必要时提价替换），把最终上链版本的哈希与回执信息填入 res
+func (c *Client) waitStuck(ctx context.Context, res *TxResult, policy StuckTxPolicy) error {
+	tx, _, err := c.cli.TransactionByHash(ctx, common.HexToHash(res.TxHash))
+	if err != nil {
+		return fmt.Errorf("get tx %s failed: %w", res.TxHash, err)
+	}
+	mined, receipt, err := c.ReplaceByFee(ctx, tx, policy)
+	if err != nil {
+		return fmt.Errorf("tx sent but not mined: %w", err)
+	}
+	res.TxHash, res.Replaced = mined.Hash().Hex(), mined.Hash() != tx.Hash()
+	fillReceipt(res, receipt)
+	return nil
+}
+
+// scalePercent v * pct / 100，向上取整
+func scalePercent(v *big.Int, pct int) *big.Int {
+	out := new(big.Int).Mul(v, big.NewInt(int64(pct)))
+	out.Add(out, big.NewInt(99))
+	return out.Div(out, big.NewInt(100))
+}
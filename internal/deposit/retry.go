@@ -0,0 +1,181 @@
+package deposit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const defaultMaxBackoff = 30 * time.Second
+
+// RetryPolicy 遇到瞬时 RPC 错误（连接中断、限流、already known、underpriced 等）时的重试策略；零值表示不重试
+type RetryPolicy struct {
+	MaxAttempts int              // 总尝试次数（含首次）；<=1 不重试
+	Backoff     time.Duration    // 首次重试前的等待，之后每次翻倍
+	MaxBackoff  time.Duration    // 单次等待上限；0 表示 30s
+	Retryable   func(error) bool // 判定错误可否重试；nil 时用 IsRetryable
+}
+
+// IsRetryable 默认的可重试错误判定：网络层错误、超时、限流与 5xx、节点的 already known / underpriced
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var he rpc.HTTPError
+	if errors.As(err, &he) {
+		return he.StatusCode == 429 || he.StatusCode >= 500
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"connection reset", "connection refused", "broken pipe", "eof", "timeout", "i/o timeout",
+		"too many requests", "rate limit", "header not found", "already known", "underpriced",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRetryPolicy 设置本客户端发送交易时的重试策略
+func (c *Client) SetRetryPolicy(p RetryPolicy) { c.retry = p }
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// delay 第 attempt 次失败后的等待：Backoff * 2^(attempt-1)，不超过 MaxBackoff
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	limit := p.MaxBackoff
+	if limit <= 0 {
+		limit = defaultMaxBackoff
+	}
+	d := p.Backoff
+	for i := 1; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+func (p RetryPolicy) sleep(ctx context.Context, attempt int) error {
+	t := time.NewTimer(p.delay(attempt))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// withRetry 对只读请求（nonce、费用、gas 估算）按策略重试
+func (c *Client) withRetry(ctx context.Context, what string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= c.retry.attempts() || !c.retry.retryable(err) {
+			return err
+		}
+		log.Printf("⚠️ %s 失败（第 %d/%d 次），%s 后重试: %v", what, attempt, c.retry.attempts(), c.retry.delay(attempt), err)
+		if serr := c.retry.sleep(ctx, attempt); serr != nil {
+			return err
+		}
+	}
+}
+
+// signAndSend 签名并广播交易，按策略重试。重试始终沿用同一 nonce，因此任意时刻至多一笔能上链，不会重复存款：
+//   - already known：节点已有这笔交易（如上次请求已送达但响应丢失），视为成功；
+//   - underpriced：按 10%+ 提高 tip 与 fee cap 后重新签名（同 nonce 替换）；该 nonce 上已有并非本次发出的交易时
+//     （首次发送即 replacement underpriced）不替换，直接返回错误；
+//   - 重试后 nonce too low：之前某次发送其实已被接受，查到该交易则视为成功。
+func (c *Client) signAndSend(ctx context.Context, txData *gethtypes.DynamicFeeTx) (*gethtypes.Transaction, error) {
+	signer := gethtypes.LatestSignerForChainID(c.chainID)
+	signedTx, err := gethtypes.SignTx(gethtypes.NewTx(txData), signer, c.privKey)
+	if err != nil {
+		return nil, fmt.Errorf("sign tx failed: %w", err)
+	}
+	sent := []*gethtypes.Transaction{signedTx}
+	for attempt := 1; ; attempt++ {
+		err := c.cli.SendTransaction(ctx, signedTx)
+		if err == nil || isAlreadyKnown(err) {
+			return signedTx, nil
+		}
+		if attempt > 1 && isNonceTooLow(err) {
+			if tx := c.findSent(ctx, sent); tx != nil {
+				return tx, nil
+			}
+		}
+		if attempt >= c.retry.attempts() || !c.retry.retryable(err) || (attempt == 1 && isReplacementUnderpriced(err)) {
+			return nil, fmt.Errorf("send tx failed: %w", err)
+		}
+		if isUnderpriced(err) {
+			txData.GasTipCap, txData.GasFeeCap = bumpFee(txData.GasTipCap), bumpFee(txData.GasFeeCap)
+			bumped, serr := gethtypes.SignTx(gethtypes.NewTx(txData), signer, c.privKey)
+			if serr != nil {
+				return nil, fmt.Errorf("sign tx failed: %w", serr)
+			}
+			signedTx = bumped
+			sent = append(sent, signedTx)
+		}
+		log.Printf("⚠️ 发送交易 nonce=%d 失败（第 %d/%d 次），%s 后重试: %v", txData.Nonce, attempt, c.retry.attempts(), c.retry.delay(attempt), err)
+		if serr := c.retry.sleep(ctx, attempt); serr != nil {
+			return nil, fmt.Errorf("send tx failed: %w", err)
+		}
+	}
+}
+
+// findSent 在已发出的各版本中找出节点已知的那笔
+func (c *Client) findSent(ctx context.Context, sent []*gethtypes.Transaction) *gethtypes.Transaction {
+	for i := len(sent) - 1; i >= 0; i-- {
+		if _, _, err := c.cli.TransactionByHash(ctx, sent[i].Hash()); err == nil {
+			return sent[i]
+		}
+	}
+	return nil
+}
+
+// bumpFee 提高 10%（向上取整再加 1 wei），满足节点替换同 nonce 交易的最低涨幅
+func bumpFee(v *big.Int) *big.Int {
+	out := scalePercent(v, 110)
+	return out.Add(out, common.Big1)
+}
+
+func isAlreadyKnown(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "already known")
+}
+
+func isUnderpriced(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "underpriced")
+}
+
+func isReplacementUnderpriced(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "replacement transaction underpriced")
+}
@@ -0,0 +1,122 @@
+package deposit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+
+	"n42-test/internal/hexutil"
+)
+
+// DepositContractTreeDepth 存款合约增量 Merkle 树的深度（与信标链规范一致）
+const DepositContractTreeDepth = 32
+
+// DepositTree 存款树：按存款下标依次追加 deposit_data_root 作为叶子，
+// 可取任意前缀（对应某个 Eth1Data.deposit_count）的根与某个下标的证明。
+// 未填充的叶子用零子树哈希代替，不需要真的展开 2^32 个叶子。
+type DepositTree struct {
+	leaves [][32]byte
+}
+
+// Push 追加下一个存款的 deposit_data_root
+func (t *DepositTree) Push(leaf [32]byte) { t.leaves = append(t.leaves, leaf) }
+
+// Count 已追加的存款数
+func (t *DepositTree) Count() uint64 { return uint64(len(t.leaves)) }
+
+// Root 前 count 个存款构成的树根（含 mix_in_length），即合约 get_deposit_root 在当时的返回值
+func (t *DepositTree) Root(count uint64) ([32]byte, error) {
+	if count > t.Count() {
+		return [32]byte{}, fmt.Errorf("deposit tree has %d leaves, need %d", t.Count(), count)
+	}
+	return mixInLength(merkleizeDepth(t.leaves[:count], DepositContractTreeDepth), count), nil
+}
+
+// Proof 在前 count 个存款构成的树中，下标 index 的 Merkle 分支：
+// 32 个兄弟节点（自底向上）+ 末尾的长度块，共 DepositContractTreeDepth+1 项，与 Deposit.proof 同形
+func (t *DepositTree) Proof(index, count uint64) ([][32]byte, error) {
+	if count > t.Count() {
+		return nil, fmt.Errorf("deposit tree has %d leaves, need %d", t.Count(), count)
+	}
+	if index >= count {
+		return nil, fmt.Errorf("deposit index %d not covered by deposit count %d", index, count)
+	}
+	h := hasherPool.Get().(hash.Hash)
+	defer hasherPool.Put(h)
+
+	level := append([][32]byte(nil), t.leaves[:count]...)
+	proof := make([][32]byte, 0, DepositContractTreeDepth+1)
+	for d := 0; d < DepositContractTreeDepth; d++ {
+		sib := (index >> d) ^ 1
+		if sib < uint64(len(level)) {
+			proof = append(proof, level[sib])
+		} else {
+			proof = append(proof, zeroHashes[d])
+		}
+		// 上一层：落单节点与同层零子树配对
+		n := len(level)
+		for i := 0; i < n; i += 2 {
+			right := &zeroHashes[d]
+			if i+1 < n {
+				right = &level[i+1]
+			}
+			hashPair(h, &level[i/2], &level[i], right)
+		}
+		level = level[:(n+1)/2]
+	}
+	var length [32]byte
+	binary.LittleEndian.PutUint64(length[:8], count)
+	return append(proof, length), nil
+}
+
+// VerifyMerkleBranch 即规范中的 is_valid_merkle_branch；
+// 验证存款证明时 depth = DepositContractTreeDepth+1，root 为 Eth1Data.deposit_root
+func VerifyMerkleBranch(leaf [32]byte, branch [][32]byte, depth int, index uint64, root [32]byte) bool {
+	if len(branch) < depth {
+		return false
+	}
+	h := hasherPool.Get().(hash.Hash)
+	defer hasherPool.Put(h)
+	value := leaf
+	for i := 0; i < depth; i++ {
+		if (index>>i)&1 == 1 {
+			hashPair(h, &value, &branch[i], &value)
+		} else {
+			hashPair(h, &value, &value, &branch[i])
+		}
+	}
+	return value == root
+}
+
+// DataRoot 事件对应的 deposit_data_root，即存款树的叶子
+func (e DepositEvent) DataRoot() ([32]byte, error) {
+	pubkey, err := hexutil.DecodeFixed(e.Pubkey, hexutil.PubkeyLen)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("deposit %d pubkey: %w", e.Index, err)
+	}
+	wc, err := hexutil.DecodeFixed(e.WithdrawalCredentials, hexutil.HashLen)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("deposit %d withdrawal_credentials: %w", e.Index, err)
+	}
+	sig, err := hexutil.DecodeFixed(e.Signature, hexutil.SignatureLen)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("deposit %d signature: %w", e.Index, err)
+	}
+	return htrDepositData(pubkey, wc, e.AmountGwei, sig)
+}
+
+// BuildDepositTree 由按下标排序的 DepositEvent 构建存款树；下标必须从 0 连续
+func BuildDepositTree(events []DepositEvent) (*DepositTree, error) {
+	t := &DepositTree{leaves: make([][32]byte, 0, len(events))}
+	for i, ev := range events {
+		if ev.Index != uint64(i) {
+			return nil, fmt.Errorf("deposit events not contiguous: position %d has index %d (missing logs? check -from-block)", i, ev.Index)
+		}
+		leaf, err := ev.DataRoot()
+		if err != nil {
+			return nil, err
+		}
+		t.Push(leaf)
+	}
+	return t, nil
+}
@@ -0,0 +1,99 @@
+package deposit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// From 发送账户地址
+func (c *Client) From() common.Address { return c.fromAddr }
+
+// Call 对任意合约做 eth_call；block 为 nil 表示 latest
+func (c *Client) Call(ctx context.Context, to common.Address, data []byte, block *big.Int) ([]byte, error) {
+	out, err := c.cli.CallContract(ctx, ethereum.CallMsg{From: c.fromAddr, To: &to, Data: data}, block)
+	if err != nil {
+		return nil, fmt.Errorf("eth_call failed: %w", err)
+	}
+	return out, nil
+}
+
+// SendTx 用与 deposit 相同的 nonce/费用策略发送任意交易（to 为 nil 时部署合约）；
+// wait 为 true 时等待回执并填入区块、状态、gas 费用与日志。
+func (c *Client) SendTx(ctx context.Context, to *common.Address, value *big.Int, data []byte, opt TxOptions, wait bool) (*TxResult, error) {
+	if value == nil {
+		value = new(big.Int)
+	}
+	var nonce uint64
+	var maxPriority, maxFee *big.Int
+	err := c.withRetry(ctx, "获取 nonce/费用", func() (err error) {
+		nonce, maxPriority, maxFee, err = c.nonceAndFees(ctx, opt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gasLimit := opt.GasLimit
+	if gasLimit == 0 {
+		var est uint64
+		err := c.withRetry(ctx, "估算 gas", func() (err error) {
+			est, err = c.cli.EstimateGas(ctx, ethereum.CallMsg{
+				From:      c.fromAddr,
+				To:        to,
+				GasFeeCap: maxFee,
+				GasTipCap: maxPriority,
+				Value:     value,
+				Data:      data,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("estimate gas failed: %w", err)
+		}
+		gasLimit = est * 12 / 10
+	}
+
+	signedTx, err := c.signAndSend(ctx, &gethtypes.DynamicFeeTx{
+		ChainID:   c.chainID,
+		Nonce:     nonce,
+		To:        to,
+		Value:     value,
+		Data:      data,
+		Gas:       gasLimit,
+		GasTipCap: maxPriority,
+		GasFeeCap: maxFee,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &TxResult{TxHash: signedTx.Hash().Hex(), EstimatedGas: gasLimit, Nonce: nonce}
+	if !wait {
+		return res, nil
+	}
+	return res, c.WaitTx(ctx, res)
+}
+
+// WaitTx 等待已发送交易的回执，并把区块、状态、gas 费用与日志填入 res
+func (c *Client) WaitTx(ctx context.Context, res *TxResult) error {
+	receipt, err := waitMined(ctx, c.cli, common.HexToHash(res.TxHash))
+	if err != nil {
+		return fmt.Errorf("tx sent but waitMined failed: %w", err)
+	}
+	fillReceipt(res, receipt)
+	return nil
+}
+
+func fillReceipt(res *TxResult, receipt *gethtypes.Receipt) {
+	res.UsedGas = receipt.GasUsed
+	res.BlockNumber = receipt.BlockNumber.Uint64()
+	res.BlockHash = receipt.BlockHash.Hex()
+	res.Status = receipt.Status
+	res.GasCostWei = gasCost(receipt)
+	res.Logs = receipt.Logs
+}
@@ -1,8 +1,14 @@
 package deposit
 
 import (
+	"context"
 	"errors"
 	"math/big"
+	"time"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"n42-test/internal/units"
 )
 
 var (
@@ -28,8 +34,8 @@ type DepositParams struct {
 	SignatureHex string // BLS 签名，96字节
 	RootHex      string // deposit_data_root，32字节
 
-	// 质押转账金额（wei）。主网固定 32 ETH，这里保留自定义以兼容本地链/测试
-	AmountWei *big.Int
+	// 质押转账金额。主网固定 32 ETH，这里保留自定义以兼容本地链/测试
+	Amount units.Amount
 
 	// 可选：nonce（为 -1 表示自动读取）
 	Nonce int64
@@ -40,6 +46,43 @@ type DepositParams struct {
 	// 可选：EIP-1559 参数（如为 nil 则自动建议）
 	MaxPriorityFeePerGas *big.Int
 	MaxFeePerGas         *big.Int
+
+	// 可选：交易卡住时同 nonce 提价替换（nil 表示只等待，超时即失败）
+	StuckTx *StuckTxPolicy
+
+	// 可选：提交时 gas 估算与其余步骤（取 nonce / 费用、签名广播）各自的时限，互不占用；0 表示只受调用方 ctx 限制。
+	// 等回执的时限由调用 Confirm 时的 ctx 决定
+	EstimateTimeout time.Duration
+	SubmitTimeout   time.Duration
+
+	// 故障注入：跳过本地的金额 / 公钥长度检查，原样交给合约判定（见 faults.go）
+	Unchecked bool
+}
+
+// stepContext d > 0 时给 ctx 加上时限；否则原样返回
+func stepContext(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// txOptions 取出 nonce / gas / 费用相关的可选参数
+func (p *DepositParams) txOptions() TxOptions {
+	return TxOptions{
+		Nonce:                p.Nonce,
+		GasLimit:             p.GasLimit,
+		MaxPriorityFeePerGas: p.MaxPriorityFeePerGas,
+		MaxFeePerGas:         p.MaxFeePerGas,
+	}
+}
+
+// TxOptions 通用交易的可选参数，语义与 DepositParams 中的同名字段一致
+type TxOptions struct {
+	Nonce                int64  // -1 表示自动读取
+	GasLimit             uint64 // 0 表示自动估算
+	MaxPriorityFeePerGas *big.Int
+	MaxFeePerGas         *big.Int
 }
 
 type TxResult struct {
@@ -47,6 +90,24 @@ type TxResult struct {
 	UsedGas      uint64
 	Nonce        uint64
 	EstimatedGas uint64
-	BlockNumber  uint64 // 交易打包的区块号
-	BlockHash    string // 交易所在区块的哈希
+	BlockNumber  uint64           // 交易打包的区块号
+	BlockHash    string           // 交易所在区块的哈希
+	Status       uint64           // 回执状态：1 成功，0 revert（仅等待回执时有效）
+	GasCostWei   *big.Int         // 实际 gas 费用 = gasUsed * effectiveGasPrice（仅等待回执时有效）
+	Logs         []*gethtypes.Log // 回执中的日志（仅等待回执时有效）
+	Replaced     bool             // 上链的是提价替换后的版本（TxHash 为该版本的哈希）
+
+	// 仅 deposit 且等待回执时有效：回执中解码出的 DepositEvent（含链上存款下标），
+	// 以及它与提交参数的不一致项（找不到事件也记在这里）
+	Deposit         *DepositEvent
+	DepositMismatch []string
+}
+
+// DepositSender 发送 deposit 交易的能力；*Client 为 RPC 实现，lifecycle.Backend.Deposits 按发送私钥打开它
+type DepositSender interface {
+	SendDeposit(ctx context.Context, p *DepositParams) (*TxResult, error)
+	SendDepositNoWait(ctx context.Context, p *DepositParams) (*TxResult, error)
+	Close()
 }
+
+var _ DepositSender = (*Client)(nil)
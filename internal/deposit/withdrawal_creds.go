@@ -0,0 +1,141 @@
+package deposit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"n42-test/internal/blsutil"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+)
+
+// 提款凭证（withdrawal_credentials）前缀类型
+const (
+	WCTypeBLS         byte = 0x00 // 0x00 || sha256(BLS 提款公钥)[1:]
+	WCTypeEth1        byte = 0x01 // 0x01 || 11 个 0 || 执行层地址
+	WCTypeCompounding byte = 0x02 // 0x02 || 11 个 0 || 执行层地址（复利）
+)
+
+// ParseWCType 解析凭证类型：0x00|0x01|0x02（也接受 00/01/02、bls/eth1/compounding）
+func ParseWCType(s string) (byte, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "0x00", "00", "0", "bls":
+		return WCTypeBLS, nil
+	case "0x01", "01", "1", "eth1":
+		return WCTypeEth1, nil
+	case "0x02", "02", "2", "compounding":
+		return WCTypeCompounding, nil
+	default:
+		return 0, fmt.Errorf("unknown withdrawal credential type %q (want 0x00|0x01|0x02)", s)
+	}
+}
+
+// WCTypeName 凭证类型的展示形式，如 "0x01"
+func WCTypeName(t byte) string {
+	return fmt.Sprintf("0x%02x", t)
+}
+
+// ComputeBLSWithdrawalCredentials 0x00 凭证：0x00 || sha256(pubkey)[1:]
+func ComputeBLSWithdrawalCredentials(blsPubkeyHex string) (string, error) {
+	pk, err := hexutil.DecodeFixed(blsPubkeyHex, hexutil.PubkeyLen)
+	if err != nil {
+		return "", fmt.Errorf("bls withdrawal pubkey: %w", err)
+	}
+	wc := sha256.Sum256(pk)
+	wc[0] = WCTypeBLS
+	return hexutil.Encode(wc[:]), nil
+}
+
+// ComputeCompoundingWithdrawalCredentials 0x02 凭证
+func ComputeCompoundingWithdrawalCredentials(executionAddressHex string) (string, error) {
+	return addressCredentials(WCTypeCompounding, executionAddressHex)
+}
+
+// ComputeWithdrawalCredentials 按类型生成凭证：0x00 使用 blsPubkeyHex，0x01/0x02 使用执行层地址
+func ComputeWithdrawalCredentials(t byte, executionAddressHex, blsPubkeyHex string) (string, error) {
+	switch t {
+	case WCTypeBLS:
+		return ComputeBLSWithdrawalCredentials(blsPubkeyHex)
+	case WCTypeEth1:
+		return ComputeWithdrawalCredentialsFromEth1(executionAddressHex)
+	case WCTypeCompounding:
+		return ComputeCompoundingWithdrawalCredentials(executionAddressHex)
+	default:
+		return "", fmt.Errorf("unsupported withdrawal credential type 0x%02x", t)
+	}
+}
+
+func addressCredentials(prefix byte, executionAddressHex string) (string, error) {
+	addrBytes, err := hexutil.DecodeFixed(executionAddressHex, hexutil.AddressLen)
+	if err != nil {
+		return "", fmt.Errorf("execution address: %w", err)
+	}
+	var wc [32]byte
+	wc[0] = prefix
+	copy(wc[12:], addrBytes)
+	return "0x" + hex.EncodeToString(wc[:]), nil
+}
+
+// WithdrawalSource accounts.json 中生成提款凭证用到的字段；地址/公钥缺失时由对应私钥推导
+type WithdrawalSource struct {
+	Address       string // withdrawal-address
+	PrivateKey    string // withdrawal-private-key（secp256k1），Address 为空时推导执行层地址
+	BLSPubkey     string // bls-withdrawal-public-key
+	BLSPrivateKey string // bls-withdrawal-private-key，BLSPubkey 为空时推导 0x00 凭证用的公钥
+	FallbackBLS   string // 以上都没有时 0x00 凭证使用的公钥（通常为验证者公钥）
+}
+
+// ResolvedWC 生成的凭证；Derived 非空表示输入缺失、由 DerivedFrom 字段推导出的值（地址或 BLS 公钥）
+type ResolvedWC struct {
+	Credentials string
+	Derived     string
+	DerivedFrom string
+}
+
+// ResolveWithdrawalCredentials 按类型生成凭证，缺少地址/BLS 公钥时从私钥推导，而不是直接失败
+func ResolveWithdrawalCredentials(t byte, src WithdrawalSource) (*ResolvedWC, error) {
+	res := &ResolvedWC{}
+	addr, blsPub := strings.TrimSpace(src.Address), strings.TrimSpace(src.BLSPubkey)
+	switch t {
+	case WCTypeBLS:
+		if blsPub == "" && strings.TrimSpace(src.BLSPrivateKey) != "" {
+			pk, err := blsutil.DerivePublicKeyHex(src.BLSPrivateKey, blsutil.DefaultKeyOptions())
+			if err != nil {
+				return nil, fmt.Errorf("derive bls withdrawal pubkey from bls-withdrawal-private-key: %w", err)
+			}
+			blsPub, res.Derived, res.DerivedFrom = pk, pk, "bls-withdrawal-private-key"
+		}
+		if blsPub == "" {
+			blsPub = src.FallbackBLS
+		}
+	case WCTypeEth1, WCTypeCompounding:
+		if addr == "" {
+			if strings.TrimSpace(src.PrivateKey) == "" {
+				return nil, errors.New("withdrawal-address is empty and no withdrawal-private-key to derive it from")
+			}
+			a, err := DeriveWithdrawalAddress(src.PrivateKey)
+			if err != nil {
+				return nil, fmt.Errorf("derive withdrawal address from withdrawal-private-key: %w", err)
+			}
+			addr, res.Derived, res.DerivedFrom = a, a, "withdrawal-private-key"
+		}
+	}
+	wc, err := ComputeWithdrawalCredentials(t, addr, blsPub)
+	if err != nil {
+		return nil, err
+	}
+	res.Credentials = wc
+	return res, nil
+}
+
+// DeriveWithdrawalAddress 由 secp256k1 提款私钥推导执行层地址（0x 校验和格式）
+func DeriveWithdrawalAddress(privHex string) (string, error) {
+	addr, err := keys.Address(privHex)
+	if err != nil {
+		return "", err
+	}
+	return addr.Hex(), nil
+}
@@ -0,0 +1,381 @@
+// 存款流水线关联报告：把每条 DepositEvent（执行层区块、存款下标）对应到
+// 信标链处理它的区块/纪元（eth1_deposit_index 越过该下标的第一个区块）以及最终的验证者记录，
+// 统计整个活动从存款上链到被信标链处理、再到激活的端到端延迟。
+package depositreport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/deposit"
+	"n42-test/internal/units"
+)
+
+// Row 一条存款的关联结果；未处理的存款 Processed=false，相关字段为零值
+type Row struct {
+	deposit.DepositEvent
+	EventTime  time.Time `json:"event_time"`
+	EventSlot  uint64    `json:"event_slot"`
+	EventEpoch uint64    `json:"event_epoch"`
+
+	Processed      bool          `json:"processed"`
+	ProcessedBlock uint64        `json:"processed_block,omitempty"`
+	ProcessedSlot  uint64        `json:"processed_slot,omitempty"`
+	ProcessedEpoch uint64        `json:"processed_epoch,omitempty"`
+	ProcessedTime  time.Time     `json:"processed_time"`
+	LatencyBlocks  uint64        `json:"latency_blocks,omitempty"`
+	Latency        time.Duration `json:"latency_ns,omitempty"`
+
+	// 验证者记录（取自最新状态）；TopUp 表示该公钥在此之前已有存款
+	ValidatorIndex             *uint64 `json:"validator_index,omitempty"`
+	TopUp                      bool    `json:"top_up"`
+	ActivationEligibilityEpoch *uint64 `json:"activation_eligibility_epoch,omitempty"`
+	ActivationEpoch            *uint64 `json:"activation_epoch,omitempty"`
+	Status                     string  `json:"status,omitempty"`
+}
+
+// Distribution 延迟分布
+type Distribution struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+}
+
+func newDistribution(xs []float64) Distribution {
+	if len(xs) == 0 {
+		return Distribution{}
+	}
+	sort.Float64s(xs)
+	sum := 0.0
+	for _, x := range xs {
+		sum += x
+	}
+	at := func(q float64) float64 { return xs[int(q*float64(len(xs)-1)+0.5)] }
+	return Distribution{Count: len(xs), Min: xs[0], P50: at(0.5), P95: at(0.95), Max: xs[len(xs)-1], Avg: sum / float64(len(xs))}
+}
+
+func (d Distribution) String() string {
+	if d.Count == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("min=%.1f p50=%.1f p95=%.1f max=%.1f avg=%.1f (n=%d)", d.Min, d.P50, d.P95, d.Max, d.Avg, d.Count)
+}
+
+// Report 整个活动的关联报告
+type Report struct {
+	GeneratedAt   time.Time `json:"generated_at"`
+	Contract      string    `json:"contract"`
+	FromBlock     uint64    `json:"from_block"`
+	ToBlock       uint64    `json:"to_block"`
+	StateBlock    uint64    `json:"state_block"`
+	StateSlot     uint64    `json:"state_slot"`
+	DepositIndex  uint64    `json:"state_eth1_deposit_index"`
+	SlotsPerEpoch uint64    `json:"slots_per_epoch"`
+	Rows          []Row     `json:"rows"`
+
+	Pending            int          `json:"pending"`
+	LatencySeconds     Distribution `json:"latency_seconds"`
+	LatencyBlocks      Distribution `json:"latency_blocks"`
+	EligibilityEpochs  Distribution `json:"processed_to_eligibility_epochs"`
+	ActivationEpochs   Distribution `json:"processed_to_activation_epochs"`
+	EndToEndActivation Distribution `json:"event_to_activation_epochs"`
+}
+
+// Options 关联参数
+type Options struct {
+	Contract      string
+	FromBlock     uint64
+	ToBlock       uint64 // 0 表示到最新区块
+	SlotsPerEpoch uint64
+	LogChunk      uint64
+	Pubkeys       []string // 非空时只关联这些公钥的存款（一次活动）
+}
+
+// point 某执行层区块对应信标状态的存款处理进度
+type point struct {
+	number       uint64
+	time         time.Time
+	slot         uint64
+	depositIndex uint64
+}
+
+type correlator struct {
+	r      beaconext.BeaconReader
+	points map[uint64]*point
+}
+
+func (c *correlator) block(ctx context.Context, number uint64) (*beaconext.EthBlock, error) {
+	blk, err := c.r.EthGetBlockByNumber(ctx, "0x"+strconv.FormatUint(number, 16), false)
+	if err != nil {
+		return nil, fmt.Errorf("get block %d: %w", number, err)
+	}
+	if blk == nil || blk.Hash == "" {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	return blk, nil
+}
+
+// probe 区块 number 对应信标状态的 slot 与 eth1_deposit_index（带缓存，只流式读取这两个字段）
+func (c *correlator) probe(ctx context.Context, number uint64) (*point, error) {
+	if p, ok := c.points[number]; ok {
+		return p, nil
+	}
+	blk, err := c.block(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseTimestamp(blk.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	var st beaconstate.State
+	err = beaconstate.StreamAt(ctx, c.r, blk.Hash, func(key string, dec *json.Decoder) (bool, error) {
+		switch key {
+		case "slot", "eth1_deposit_index":
+			return st.DecodeField(key, dec)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("state at block %d: %w", number, err)
+	}
+	p := &point{number: number, time: t, slot: st.Slot, depositIndex: st.Eth1DepositIndex}
+	c.points[number] = p
+	return p, nil
+}
+
+// firstProcessed 二分查找 [lo, hi] 内 eth1_deposit_index 越过 index 的第一个区块；
+// 调用方保证 hi 处已越过（eth1_deposit_index 随区块单调不减）。
+func (c *correlator) firstProcessed(ctx context.Context, index, lo, hi uint64) (*point, error) {
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		p, err := c.probe(ctx, mid)
+		if err != nil {
+			return nil, err
+		}
+		if p.depositIndex > index {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return c.probe(ctx, lo)
+}
+
+// Build 查询 DepositEvent 并逐条关联信标链处理区块与验证者记录
+func Build(ctx context.Context, cli ethereum.LogFilterer, r beaconext.BeaconReader, opt Options) (*Report, error) {
+	spe := opt.SlotsPerEpoch
+	if spe == 0 {
+		spe = beaconstate.DefaultSlotsPerEpoch
+	}
+	latest, err := r.EthGetBlockByNumber(ctx, "latest", false)
+	if err != nil {
+		return nil, fmt.Errorf("get latest block: %w", err)
+	}
+	head, err := strconv.ParseUint(strings.TrimPrefix(latest.Number, "0x"), 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse latest block number %q: %w", latest.Number, err)
+	}
+	var st beaconstate.State
+	if err := beaconstate.StreamAt(ctx, r, latest.Hash, st.DecodeField); err != nil {
+		return nil, fmt.Errorf("read latest beacon state: %w", err)
+	}
+
+	to := opt.ToBlock
+	if to == 0 || to > head {
+		to = head
+	}
+	events, err := deposit.FilterDepositEvents(ctx, cli, common.HexToAddress(opt.Contract), opt.FromBlock, to, opt.LogChunk)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &Report{
+		GeneratedAt:   time.Now(),
+		Contract:      opt.Contract,
+		FromBlock:     opt.FromBlock,
+		ToBlock:       to,
+		StateBlock:    head,
+		StateSlot:     st.Slot,
+		DepositIndex:  st.Eth1DepositIndex,
+		SlotsPerEpoch: spe,
+	}
+
+	var want map[string]bool
+	if len(opt.Pubkeys) > 0 {
+		want = make(map[string]bool, len(opt.Pubkeys))
+		for _, pk := range opt.Pubkeys {
+			want[beaconstate.NormPubkey(pk)] = true
+		}
+	}
+	// TopUp 依据查询范围内的全部事件判断（含活动之外的公钥），范围之前的存款看不到
+	seen := map[string]bool{}
+	index := st.Index()
+	c := &correlator{r: r, points: map[uint64]*point{}}
+	var lo uint64
+	for _, ev := range events {
+		pk := beaconstate.NormPubkey(ev.Pubkey)
+		topUp := seen[pk]
+		seen[pk] = true
+		if want != nil && !want[pk] {
+			continue
+		}
+		row := Row{DepositEvent: ev, TopUp: topUp}
+		at, err := c.probe(ctx, ev.BlockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("deposit #%d: %w", ev.Index, err)
+		}
+		row.EventTime, row.EventSlot, row.EventEpoch = at.time, at.slot, at.slot/spe
+		if ev.Index < st.Eth1DepositIndex {
+			p, err := c.firstProcessed(ctx, ev.Index, max(lo, ev.BlockNumber), head)
+			if err != nil {
+				return nil, fmt.Errorf("deposit #%d: %w", ev.Index, err)
+			}
+			lo = p.number
+			row.Processed = true
+			row.ProcessedBlock = p.number
+			row.ProcessedSlot = p.slot
+			row.ProcessedEpoch = p.slot / spe
+			row.ProcessedTime = p.time
+			row.LatencyBlocks = p.number - ev.BlockNumber
+			row.Latency = p.time.Sub(row.EventTime)
+		}
+		if i, ok := index[pk]; ok {
+			v := st.Validators[i]
+			vi := uint64(i)
+			row.ValidatorIndex = &vi
+			var bal uint64
+			if i < len(st.Balances) {
+				bal = st.Balances[i]
+			}
+			row.Status = v.Status(st.Epoch(spe), bal)
+			if v.ActivationEligibilityEpoch != beaconstate.FarFutureEpoch {
+				e := v.ActivationEligibilityEpoch
+				row.ActivationEligibilityEpoch = &e
+			}
+			if v.ActivationEpoch != beaconstate.FarFutureEpoch {
+				e := v.ActivationEpoch
+				row.ActivationEpoch = &e
+			}
+		}
+		rep.Rows = append(rep.Rows, row)
+	}
+	rep.summarize()
+	return rep, nil
+}
+
+func (rep *Report) summarize() {
+	var secs, blocks, elig, act, e2e []float64
+	for _, row := range rep.Rows {
+		if !row.Processed {
+			rep.Pending++
+			continue
+		}
+		secs = append(secs, row.Latency.Seconds())
+		blocks = append(blocks, float64(row.LatencyBlocks))
+		if row.TopUp {
+			continue // 追加存款不触发激活
+		}
+		if row.ActivationEligibilityEpoch != nil && *row.ActivationEligibilityEpoch >= row.ProcessedEpoch {
+			elig = append(elig, float64(*row.ActivationEligibilityEpoch-row.ProcessedEpoch))
+		}
+		if row.ActivationEpoch != nil && *row.ActivationEpoch >= row.ProcessedEpoch {
+			act = append(act, float64(*row.ActivationEpoch-row.ProcessedEpoch))
+			e2e = append(e2e, float64(*row.ActivationEpoch-row.EventEpoch))
+		}
+	}
+	rep.LatencySeconds = newDistribution(secs)
+	rep.LatencyBlocks = newDistribution(blocks)
+	rep.EligibilityEpochs = newDistribution(elig)
+	rep.ActivationEpochs = newDistribution(act)
+	rep.EndToEndActivation = newDistribution(e2e)
+}
+
+// Print 输出逐条关联表与延迟汇总
+func (rep *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "区块 [%d, %d] 内 %d 条存款；最新状态 slot %d，eth1_deposit_index=%d，未处理 %d 条\n",
+		rep.FromBlock, rep.ToBlock, len(rep.Rows), rep.StateSlot, rep.DepositIndex, rep.Pending)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tPUBKEY\tAMOUNT\tEVENT_BLOCK\tPROCESSED_BLOCK\tSLOT\tEPOCH\tLATENCY\tVALIDATOR\tELIGIBLE\tACTIVE\tSTATUS")
+	for _, row := range rep.Rows {
+		processed := []string{"-", "-", "-", "pending"}
+		if row.Processed {
+			processed = []string{
+				strconv.FormatUint(row.ProcessedBlock, 10),
+				strconv.FormatUint(row.ProcessedSlot, 10),
+				strconv.FormatUint(row.ProcessedEpoch, 10),
+				fmt.Sprintf("%s (%d blk)", row.Latency.Round(time.Second), row.LatencyBlocks),
+			}
+		}
+		status := row.Status
+		if row.TopUp {
+			status += " (top-up)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", row.Index, shortHex(row.Pubkey),
+			units.FromGwei(row.AmountGwei).ETH(), row.BlockNumber, strings.Join(processed, "\t"),
+			optUint(row.ValidatorIndex), optUint(row.ActivationEligibilityEpoch), optUint(row.ActivationEpoch), emptyDash(status))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\n延迟汇总：")
+	fmt.Fprintf(w, "  存款上链 -> 信标链处理（秒）    %s\n", rep.LatencySeconds)
+	fmt.Fprintf(w, "  存款上链 -> 信标链处理（区块）  %s\n", rep.LatencyBlocks)
+	fmt.Fprintf(w, "  处理 -> 激活资格（纪元）        %s\n", rep.EligibilityEpochs)
+	fmt.Fprintf(w, "  处理 -> 激活（纪元）            %s\n", rep.ActivationEpochs)
+	fmt.Fprintf(w, "  存款上链 -> 激活（纪元）        %s\n", rep.EndToEndActivation)
+}
+
+// Save 以 JSON 写出报告
+func (rep *Report) Save(path string) error {
+	b, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func parseTimestamp(hex string) (time.Time, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(hex, "0x"), 16, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse block timestamp %q: %w", hex, err)
+	}
+	return time.Unix(int64(v), 0), nil
+}
+
+func optUint(v *uint64) string {
+	if v == nil {
+		return "-"
+	}
+	return strconv.FormatUint(*v, 10)
+}
+
+func shortHex(s string) string {
+	if len(s) <= 14 {
+		return s
+	}
+	return s[:10] + "…" + s[len(s)-4:]
+}
+
+func emptyDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
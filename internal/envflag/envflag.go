@@ -0,0 +1,130 @@
+// 命令行参数的环境变量配置（12-factor）：容器里不挂载配置文件、不改启动命令也能配置任意参数。
+// 参数 -<flag> 对应环境变量 N42_<TOOL>_<FLAG>；在所有命令里含义相同的参数（见 Shared）另有通用名 N42_<FLAG>，
+// 优先级为 命令行 > N42_<TOOL>_<FLAG> > N42_<FLAG> > 默认值：
+//
+//	deposit-batch -rpc          → N42_DEPOSIT_BATCH_RPC，或所有命令共用的 N42_RPC
+//	attestion-test sim -rate    → N42_ATTESTION_TEST_SIM_RATE（没有通用名）
+//
+// 名字一律大写，'-'、'.' 与空格换成 '_'；布尔参数取 true/false/1/0。
+package envflag
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Prefix 所有命令共用的环境变量前缀
+const Prefix = "N42_"
+
+// Shared 可用通用名 N42_<FLAG> 设置的参数：在每个命令里含义都相同。
+// 同名但含义不同的参数不在此列，如 -key（attestion-test 为 BLS 私钥，transfer 为 ECDSA 发送私钥）、
+// -contract（存款合约 / EIP-7002 退出合约 / 合并合约）
+var Shared = map[string]bool{
+	"rpc":          true,
+	"ws":           true,
+	"runs-dir":     true,
+	"registry-dir": true,
+	"profile":      true,
+	"pushgateway":  true,
+}
+
+var replacer = strings.NewReplacer("-", "_", ".", "_", " ", "_")
+
+// Name 参数在 tool 下对应的环境变量名；tool 为空时为通用名 N42_<FLAG>
+func Name(tool, flagName string) string {
+	if tool == "" {
+		return Prefix + strings.ToUpper(replacer.Replace(flagName))
+	}
+	return Prefix + strings.ToUpper(replacer.Replace(tool+"_"+flagName))
+}
+
+// keys 参数依次查找的环境变量名
+func keys(tool, flagName string) []string {
+	if Shared[flagName] {
+		return []string{Name(tool, flagName), Name("", flagName)}
+	}
+	return []string{Name(tool, flagName)}
+}
+
+// Apply 对 fs 中命令行未给出的参数，依次取 N42_<TOOL>_<FLAG>、N42_<FLAG>（仅 Shared 中的参数）设置；
+// 返回值格式不对的环境变量错误
+func Apply(fs *flag.FlagSet, tool string) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || set[f.Name] {
+			return
+		}
+		for _, key := range keys(tool, f.Name) {
+			v, ok := os.LookupEnv(key)
+			if !ok {
+				continue
+			}
+			if e := fs.Set(f.Name, v); e != nil {
+				err = fmt.Errorf("环境变量 %s: %w", key, e)
+			}
+			return
+		}
+	})
+	return err
+}
+
+// Parse 解析 os.Args[1:] 到 flag.CommandLine，再按 tool 应用环境变量（替代 flag.Parse）
+func Parse(tool string) {
+	ParseSet(flag.CommandLine, tool, os.Args[1:])
+}
+
+// ParseSet 解析 args 到 fs，再按 tool 应用环境变量（替代 fs.Parse）；tool 为空时取 fs.Name()。
+// 环境变量值不合法时按 fs 的 ErrorHandling 处理；-h 的帮助末尾附上环境变量名的对应规则
+func ParseSet(fs *flag.FlagSet, tool string, args []string) {
+	if tool == "" {
+		tool = fs.Name()
+	}
+	usage := fs.Usage
+	fs.Usage = func() {
+		if usage != nil {
+			usage()
+		} else {
+			fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+			fs.PrintDefaults()
+		}
+		fmt.Fprintf(fs.Output(), "\n每个参数也可用环境变量设置（命令行优先）：-<flag> → %s，如 -rpc → %s\n"+
+			"各命令含义相同的 -%s 另可用通用名 %s，如 %s\n",
+			Name(tool, "<FLAG>"), Name(tool, "rpc"), strings.Join(sharedNames(), "、-"), Name("", "<FLAG>"), Name("", "rpc"))
+	}
+	fs.Parse(args) // 按 fs 的 ErrorHandling 处理错误
+	if err := Apply(fs, tool); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		switch fs.ErrorHandling() {
+		case flag.ExitOnError:
+			os.Exit(2)
+		case flag.PanicOnError:
+			panic(err)
+		}
+	}
+}
+
+// sharedNames 排序后的 Shared
+func sharedNames() []string {
+	names := make([]string, 0, len(Shared))
+	for n := range Shared {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String 没有命令行参数的固定配置（早期的单场景脚本）也按同样规则取值：
+// 依次取 N42_<TOOL>_<NAME>、N42_<NAME>（仅 Shared 中的名字），都未设置时返回 def
+func String(tool, name, def string) string {
+	for _, key := range keys(tool, name) {
+		if v, ok := os.LookupEnv(key); ok {
+			return v
+		}
+	}
+	return def
+}
@@ -15,23 +15,25 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/capability"
 )
 
-// PackExitCalldata 将 48 字节的 BLS 公钥 与 8 字节 amount(wei, 大端) 打包成 calldata:
-// [pubkey(48) | amount(8)]
-func PackExitCalldata(pubkey48 []byte, amountWei *big.Int) ([]byte, error) {
+// PackExitCalldata 将 48 字节的 BLS 公钥 与 8 字节 amount(gwei, 大端) 打包成 calldata:
+// [pubkey(48) | amount(8)]。按 EIP-7002，amount=0 为全额退出，>0 为部分提款的金额。
+func PackExitCalldata(pubkey48 []byte, amountGwei *big.Int) ([]byte, error) {
 	if len(pubkey48) != 48 {
 		return nil, fmt.Errorf("pubkey length must be 48, got %d", len(pubkey48))
 	}
-	if amountWei == nil || amountWei.Sign() < 0 {
+	if amountGwei == nil || amountGwei.Sign() < 0 {
 		return nil, errors.New("amount must be non-negative")
 	}
 
 	// amount 需要 8 字节无符号整数（大端）。若超出 2^64-1，报错。
-	if amountWei.BitLen() > 64 {
-		return nil, fmt.Errorf("amount too large for 8-byte field (bitlen=%d)", amountWei.BitLen())
+	if amountGwei.BitLen() > 64 {
+		return nil, fmt.Errorf("amount too large for 8-byte field (bitlen=%d)", amountGwei.BitLen())
 	}
-	amountU64 := amountWei.Uint64()
+	amountU64 := amountGwei.Uint64()
 
 	data := make([]byte, 0, 56)
 	data = append(data, pubkey48...)
@@ -42,7 +44,7 @@ func PackExitCalldata(pubkey48 []byte, amountWei *big.Int) ([]byte, error) {
 }
 
 // GetExitFee 读取当前区块的退出请求费用（wei）。
-// 调用规则：对合约做一次无 calldata 的 eth_call，返回 32 字节整数。
+// 调用规则：对合约做一次无 calldata 的 eth_call，返回 32 字节整数（EIP-7251 合并合约的取费方式相同）。
 func GetExitFee(ctx context.Context, cli *ethclient.Client, contract common.Address) (*big.Int, error) {
 	// 空 data，value=0
 	call := ethereum.CallMsg{
@@ -72,29 +74,72 @@ func SendExitRequest(
 	priv *ecdsa.PrivateKey,
 	contract common.Address,
 	pubkey48 []byte,
-	amountWei *big.Int,
+	amountGwei *big.Int,
 	wait bool,
 ) (*types.Transaction, *types.Receipt, error) {
+	return SendExitRequestWithCaps(ctx, cli, nil, priv, contract, pubkey48, amountGwei, wait)
+}
 
-	// 修复：正确获取 from 地址
-	from := crypto.PubkeyToAddress(priv.PublicKey)
+// SendExitRequestWithCaps 同 SendExitRequest，但按节点能力选择快速路径：
+// 支持批量请求时 nonce/小费/baseFee 一次往返取回；已知不支持 EIP-1559 时直接走 legacy。
+// caps 为 nil 时行为与 SendExitRequest 一致（逐项查询、失败再回退）。
+func SendExitRequestWithCaps(
+	ctx context.Context,
+	cli *ethclient.Client,
+	caps *capability.Matrix,
+	priv *ecdsa.PrivateKey,
+	contract common.Address,
+	pubkey48 []byte,
+	amountGwei *big.Int,
+	wait bool,
+) (*types.Transaction, *types.Receipt, error) {
+	calldata, err := PackExitCalldata(pubkey48, amountGwei)
+	if err != nil {
+		return nil, nil, err
+	}
+	return SendSystemRequest(ctx, cli, caps, priv, contract, calldata, wait)
+}
 
+// SendSystemRequest 向 EIP-7002 / EIP-7251 系统合约发送一笔请求：按合约当前费用付 value，calldata 由调用方打包。
+// 合并请求（internal/consolidate）与退出请求共用这条发送路径。
+func SendSystemRequest(
+	ctx context.Context,
+	cli *ethclient.Client,
+	caps *capability.Matrix,
+	priv *ecdsa.PrivateKey,
+	contract common.Address,
+	calldata []byte,
+	wait bool,
+) (*types.Transaction, *types.Receipt, error) {
 	// 1) 读取费用
 	fee, err := GetExitFee(ctx, cli, contract)
 	if err != nil {
 		return nil, nil, err
 	}
-	if fee.Sign() <= 0 {
-		return nil, nil, fmt.Errorf("exit fee invalid: %s", fee.String())
-	}
+	return SendSystemRequestWithFee(ctx, cli, caps, priv, contract, calldata, fee, wait)
+}
 
-	// 2) 打包 calldata
-	calldata, err := PackExitCalldata(pubkey48, amountWei)
-	if err != nil {
-		return nil, nil, err
+// SendSystemRequestWithFee 同 SendSystemRequest，但按调用方给出的费用付 value（如 FeeOracle 含余量的报价）；
+// 合约只要求 value 不低于当前费用，多付的部分不退。
+func SendSystemRequestWithFee(
+	ctx context.Context,
+	cli *ethclient.Client,
+	caps *capability.Matrix,
+	priv *ecdsa.PrivateKey,
+	contract common.Address,
+	calldata []byte,
+	fee *big.Int,
+	wait bool,
+) (*types.Transaction, *types.Receipt, error) {
+
+	// 修复：正确获取 from 地址
+	from := crypto.PubkeyToAddress(priv.PublicKey)
+
+	if fee == nil || fee.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("request fee invalid: %v", fee)
 	}
 
-	// 3) 估算 gas（写路径要带 value）
+	// 2) 估算 gas（写路径要带 value）
 	estGas, err := cli.EstimateGas(ctx, ethereum.CallMsg{
 		From:  from,
 		To:    &contract,
@@ -127,16 +172,38 @@ func SendExitRequest(
 			Data:     calldata,
 		}), nil
 	}
+	// 批量预取的参数（仅在节点支持批量请求时有值）
+	var prefetched *capability.TxParams
+	if caps.HasBatch() {
+		tp, tpErr := capability.FetchTxParams(ctx, cli, caps, from)
+		if tpErr != nil {
+			return nil, nil, tpErr
+		}
+		prefetched = tp
+	}
 	make1559 := func(nonce uint64) (*types.Transaction, error) {
-		tipCap, tipErr := cli.SuggestGasTipCap(ctx)
-		if tipErr != nil {
+		if caps != nil && !caps.HasEIP1559() {
+			return makeLegacy(nonce)
+		}
+		var tipCap, baseFee *big.Int
+		if prefetched != nil {
+			tipCap, baseFee = prefetched.TipCap, prefetched.BaseFee
+		} else {
+			var tipErr error
+			if tipCap, tipErr = cli.SuggestGasTipCap(ctx); tipErr != nil {
+				tipCap = nil
+			}
+			if h, herr := cli.HeaderByNumber(ctx, nil); herr == nil {
+				baseFee = h.BaseFee
+			}
+		}
+		if tipCap == nil {
 			tipCap = big.NewInt(1_000_000_000) // 1 gwei 兜底
 		}
-		h, herr := cli.HeaderByNumber(ctx, nil)
-		if herr != nil || h.BaseFee == nil {
+		if baseFee == nil {
 			return makeLegacy(nonce) // 回退 legacy
 		}
-		feeCap := new(big.Int).Mul(h.BaseFee, big.NewInt(10))
+		feeCap := new(big.Int).Mul(baseFee, big.NewInt(10))
 		feeCap.Add(feeCap, tipCap)
 		return types.NewTx(&types.DynamicFeeTx{
 			ChainID:   chainID,
@@ -168,8 +235,10 @@ func SendExitRequest(
 	}
 
 	// 第一次用 pending nonce 发送
-	nonce, err := cli.PendingNonceAt(ctx, from)
-	if err != nil {
+	var nonce uint64
+	if prefetched != nil {
+		nonce = prefetched.Nonce
+	} else if nonce, err = cli.PendingNonceAt(ctx, from); err != nil {
 		return nil, nil, err
 	}
 	signed, sendErr := sendOnce(nonce)
@@ -0,0 +1,261 @@
+package exit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/capability"
+)
+
+// -------------------- 请求费用预估 --------------------
+//
+// 费用 = fake_exponential(MIN_FEE, excess, UPDATE_FRACTION)。excess 在每个区块末尾按
+// max(0, excess + 本块请求数 - TARGET) 更新，块内费用不变。批量发送时自己的请求会推高下一块的费用，
+// 按发送前读到的费用付款的交易落到后面的区块就会 revert；FeeOracle 在每次发送前重新读取费用，
+// 乘以余量，并可按本批次尚未上链的请求数预估 excess 的增长。
+
+// 系统合约存储槽（EIP-7002 与 EIP-7251 布局相同）
+const (
+	SlotExcess    = 0 // 超额请求数（决定费用）
+	SlotCount     = 1 // 当前块内的请求数（块末清零）
+	SlotQueueHead = 2
+	SlotQueueTail = 3
+)
+
+// EIP-7002 费用参数
+const (
+	MinRequestFee          = 1  // MIN_WITHDRAWAL_REQUEST_FEE（wei）
+	FeeUpdateFraction      = 17 // WITHDRAWAL_REQUEST_FEE_UPDATE_FRACTION
+	TargetRequestsPerBlock = 2  // TARGET_WITHDRAWAL_REQUESTS_PER_BLOCK
+)
+
+// DefaultFeeMultiplierPercent FeeOracle 报价相对预估费用的倍数（百分比）
+const DefaultFeeMultiplierPercent = 120
+
+// RequestFee 按 excess 计算请求费用（wei）
+func RequestFee(excess uint64) *big.Int {
+	return fakeExponential(big.NewInt(MinRequestFee), new(big.Int).SetUint64(excess), big.NewInt(FeeUpdateFraction))
+}
+
+// fakeExponential EIP-4844 / EIP-7002 的 factor * e^(numerator/denominator) 整数近似
+func fakeExponential(factor, numerator, denominator *big.Int) *big.Int {
+	output := new(big.Int)
+	accum := new(big.Int).Mul(factor, denominator)
+	for i := int64(1); accum.Sign() > 0; i++ {
+		output.Add(output, accum)
+		accum.Mul(accum, numerator)
+		accum.Div(accum, new(big.Int).Mul(denominator, big.NewInt(i)))
+	}
+	return output.Div(output, denominator)
+}
+
+// NextExcess 一个区块包含 count 个请求后的 excess
+func NextExcess(excess, count uint64) uint64 {
+	if excess+count > TargetRequestsPerBlock {
+		return excess + count - TargetRequestsPerBlock
+	}
+	return 0
+}
+
+// SimulateFees 从 excess 开始连续发送 n 个请求、每块包含 perBlock 个（且没有其他人的请求）时，
+// 每个请求需付的费用
+func SimulateFees(excess uint64, n, perBlock int) []*big.Int {
+	if perBlock < 1 {
+		perBlock = 1
+	}
+	fees := make([]*big.Int, 0, n)
+	for sent := 0; sent < n; {
+		fee := RequestFee(excess)
+		inBlock := min(perBlock, n-sent)
+		for j := 0; j < inBlock; j++ {
+			fees = append(fees, fee)
+		}
+		sent += inBlock
+		excess = NextExcess(excess, uint64(inBlock))
+	}
+	return fees
+}
+
+// QueueState 读取合约当前的 excess 与块内请求数；合约尚未激活时 excess 为 2^256-1（抑制值），此时报错
+func QueueState(ctx context.Context, cli *ethclient.Client, contract common.Address) (excess, count uint64, err error) {
+	read := func(slot int64) (*big.Int, error) {
+		raw, err := cli.StorageAt(ctx, contract, common.BigToHash(big.NewInt(slot)), nil)
+		if err != nil {
+			return nil, fmt.Errorf("read slot %d: %w", slot, err)
+		}
+		return new(big.Int).SetBytes(raw), nil
+	}
+	ex, err := read(SlotExcess)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !ex.IsUint64() {
+		return 0, 0, fmt.Errorf("excess %s out of range (contract not activated?)", ex)
+	}
+	c, err := read(SlotCount)
+	if err != nil {
+		return 0, 0, err
+	}
+	return ex.Uint64(), c.Uint64(), nil
+}
+
+// FeeOracle 发送前的请求费用报价：每次重新读取费用（失败时重试），乘以 MultiplierPercent；
+// Simulate 时再按本批次已发送、尚未上链的请求数预估 excess，取两者较大者。并发安全。
+type FeeOracle struct {
+	cli      *ethclient.Client
+	contract common.Address
+
+	// 报价相对预估费用的百分比（100 为不加余量）
+	MultiplierPercent int64
+	// 读取失败时的重试次数与首次重试间隔（之后每次翻倍）
+	Retries    int
+	RetryDelay time.Duration
+	// 按在途请求预估 excess 的增长
+	Simulate bool
+
+	mu       sync.Mutex
+	inflight []uint64 // 在途请求发送时的链头高度
+}
+
+func NewFeeOracle(cli *ethclient.Client, contract common.Address) *FeeOracle {
+	return &FeeOracle{
+		cli:               cli,
+		contract:          contract,
+		MultiplierPercent: DefaultFeeMultiplierPercent,
+		Retries:           3,
+		RetryDelay:        500 * time.Millisecond,
+	}
+}
+
+// Current 读取合约当前费用，失败时按 Retries 重试
+func (o *FeeOracle) Current(ctx context.Context) (*big.Int, error) {
+	delay := o.RetryDelay
+	for attempt := 0; ; attempt++ {
+		fee, err := GetExitFee(ctx, o.cli, o.contract)
+		if err == nil {
+			return fee, nil
+		}
+		if attempt >= o.Retries {
+			return nil, fmt.Errorf("after %d attempts: %w", attempt+1, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Quote 下一笔请求的报价：当前费用与（Simulate 时）在途请求全部上链后的预估费用取大，再乘以余量
+func (o *FeeOracle) Quote(ctx context.Context) (*big.Int, error) {
+	fee, err := o.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fee.Sign() <= 0 {
+		// 系统合约的费用至少 1 wei；为 0 通常是该地址上没有合约
+		return nil, fmt.Errorf("request fee is %s: no system contract at %s?", fee, o.contract.Hex())
+	}
+	if o.Simulate {
+		predicted, err := o.predict(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if predicted.Cmp(fee) > 0 {
+			fee = predicted
+		}
+	}
+	return applyPercent(fee, o.MultiplierPercent), nil
+}
+
+// predict 在途请求都落在下一个区块后的费用
+func (o *FeeOracle) predict(ctx context.Context) (*big.Int, error) {
+	head, err := o.cli.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get block number: %w", err)
+	}
+	excess, count, err := QueueState(ctx, o.cli, o.contract)
+	if err != nil {
+		return nil, err
+	}
+	return RequestFee(NextExcess(excess, count+uint64(o.pending(head)))), nil
+}
+
+// pending 在途请求数：在 head 之前发出的请求视为已上链（其影响已体现在 excess 中）并移除
+func (o *FeeOracle) pending(head uint64) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kept := o.inflight[:0]
+	for _, at := range o.inflight {
+		if at >= head {
+			kept = append(kept, at)
+		}
+	}
+	o.inflight = kept
+	return len(kept)
+}
+
+// track 登记一笔即将发送的请求，返回登记时的链头高度；未开启 Simulate 或读取失败时 ok=false
+func (o *FeeOracle) track(ctx context.Context) (at uint64, ok bool) {
+	if !o.Simulate {
+		return 0, false
+	}
+	head, err := o.cli.BlockNumber(ctx)
+	if err != nil {
+		return 0, false
+	}
+	o.mu.Lock()
+	o.inflight = append(o.inflight, head)
+	o.mu.Unlock()
+	return head, true
+}
+
+// untrack 撤销一次 track（发送失败）
+func (o *FeeOracle) untrack(at uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i, v := range o.inflight {
+		if v == at {
+			o.inflight = append(o.inflight[:i], o.inflight[i+1:]...)
+			return
+		}
+	}
+}
+
+// Send 按报价发送一笔系统合约请求（calldata 由调用方打包），其余同 SendSystemRequestWithFee。
+// 报价后即登记为在途请求，并发的其他发送据此预估费用。
+func (o *FeeOracle) Send(
+	ctx context.Context,
+	cli *ethclient.Client,
+	caps *capability.Matrix,
+	priv *ecdsa.PrivateKey,
+	calldata []byte,
+	wait bool,
+) (*types.Transaction, *types.Receipt, error) {
+	fee, err := o.Quote(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	at, tracked := o.track(ctx)
+	tx, rcpt, err := SendSystemRequestWithFee(ctx, cli, caps, priv, o.contract, calldata, fee, wait)
+	if tx == nil && tracked {
+		o.untrack(at)
+	}
+	return tx, rcpt, err
+}
+
+// applyPercent v × percent / 100，向上取整
+func applyPercent(v *big.Int, percent int64) *big.Int {
+	out := new(big.Int).Mul(v, big.NewInt(percent))
+	out.Add(out, big.NewInt(99))
+	return out.Div(out, big.NewInt(100))
+}
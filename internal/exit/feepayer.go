@@ -0,0 +1,161 @@
+package exit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// DefaultFeeMarginPercent 退出费用的余量：充值到发送之间费用可能上涨
+const DefaultFeeMarginPercent = 20
+
+// FeePayer 代付账户：测试集里的提款地址往往没有 ETH，付不起 EIP-7002 费用，
+// 发送退出请求前由它把差额即时转给发送者。转账串行发送，避免代付账户 nonce 冲突。
+type FeePayer struct {
+	cli      *ethclient.Client
+	priv     *ecdsa.PrivateKey
+	from     common.Address
+	contract common.Address
+
+	// 退出费用上浮的百分比
+	MarginPercent int64
+	// 非 nil 时按它的报价（含余量与在途请求预估）计算退出费用，而不是只读一次当前费用
+	Oracle *FeeOracle
+
+	mu sync.Mutex
+}
+
+func NewFeePayer(cli *ethclient.Client, priv *ecdsa.PrivateKey, contract common.Address) *FeePayer {
+	return &FeePayer{
+		cli:           cli,
+		priv:          priv,
+		from:          crypto.PubkeyToAddress(priv.PublicKey),
+		contract:      contract,
+		MarginPercent: DefaultFeeMarginPercent,
+	}
+}
+
+// Address 代付账户地址
+func (p *FeePayer) Address() common.Address { return p.from }
+
+// Required 发送一次退出请求所需的余额：退出费用（含余量）+ gas 上限 × 费用上限，
+// gas 与费用的算法与 SendExitRequestWithCaps 一致。
+func (p *FeePayer) Required(ctx context.Context, pubkey48 []byte, amountGwei *big.Int) (*big.Int, error) {
+	fee, err := p.fee(ctx)
+	if err != nil {
+		return nil, err
+	}
+	calldata, err := PackExitCalldata(pubkey48, amountGwei)
+	if err != nil {
+		return nil, err
+	}
+	// 用代付账户估算：发送者余额为 0 时估算会因余额不足失败
+	estGas, err := p.cli.EstimateGas(ctx, ethereum.CallMsg{From: p.from, To: &p.contract, Value: fee, Data: calldata})
+	if err != nil {
+		estGas = 150_000
+	}
+	gas := new(big.Int).SetUint64(estGas * 10)
+
+	price, err := p.maxGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	need := new(big.Int).Mul(fee, big.NewInt(100+p.MarginPercent))
+	need.Div(need, big.NewInt(100))
+	return need.Add(need, gas.Mul(gas, price)), nil
+}
+
+func (p *FeePayer) fee(ctx context.Context) (*big.Int, error) {
+	if p.Oracle != nil {
+		return p.Oracle.Quote(ctx)
+	}
+	return GetExitFee(ctx, p.cli, p.contract)
+}
+
+// maxGasPrice 发送者可能使用的最高单价：EIP-1559 为 10*baseFee+小费，legacy 为 10*gasPrice
+func (p *FeePayer) maxGasPrice(ctx context.Context) (*big.Int, error) {
+	if h, err := p.cli.HeaderByNumber(ctx, nil); err == nil && h.BaseFee != nil {
+		tip, err := p.cli.SuggestGasTipCap(ctx)
+		if err != nil {
+			tip = big.NewInt(1_000_000_000)
+		}
+		v := new(big.Int).Mul(h.BaseFee, big.NewInt(10))
+		return v.Add(v, tip), nil
+	}
+	gp, err := p.cli.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("suggest gas: %w", err)
+	}
+	return gp.Mul(gp, big.NewInt(10)), nil
+}
+
+// TopUp 确保 to 的余额足以发送这次退出请求，不足时转入差额并等待上链；
+// 余额已足够时返回 nil 交易。
+func (p *FeePayer) TopUp(ctx context.Context, to common.Address, pubkey48 []byte, amountGwei *big.Int) (*types.Transaction, error) {
+	need, err := p.Required(ctx, pubkey48, amountGwei)
+	if err != nil {
+		return nil, err
+	}
+	// 余额检查也在锁内：同一发送者的并发请求不会重复充值
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bal, err := p.cli.BalanceAt(ctx, to, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get balance: %w", err)
+	}
+	if bal.Cmp(need) >= 0 {
+		return nil, nil
+	}
+	value := new(big.Int).Sub(need, bal)
+
+	chainID, err := p.cli.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := p.cli.PendingNonceAt(ctx, p.from)
+	if err != nil {
+		return nil, fmt.Errorf("get nonce: %w", err)
+	}
+	tip, err := p.cli.SuggestGasTipCap(ctx)
+	if err != nil {
+		tip = big.NewInt(1_000_000_000)
+	}
+	feeCap := new(big.Int).Set(tip)
+	if h, err := p.cli.HeaderByNumber(ctx, nil); err == nil && h.BaseFee != nil {
+		feeCap.Add(feeCap, new(big.Int).Mul(h.BaseFee, big.NewInt(2)))
+	}
+
+	tx, err := types.SignNewTx(p.priv, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		To:        &to,
+		Value:     value,
+		Gas:       21_000,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.cli.SendTransaction(ctx, tx); err != nil {
+		return nil, fmt.Errorf("fund %s: %w", to.Hex(), err)
+	}
+	rcpt, err := WaitMined(ctx, p.cli, tx.Hash())
+	if err != nil {
+		return tx, err
+	}
+	if rcpt.Status == 0 {
+		return tx, fmt.Errorf("fund %s: tx %s reverted", to.Hex(), tx.Hash().Hex())
+	}
+	return tx, nil
+}
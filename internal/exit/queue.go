@@ -0,0 +1,188 @@
+package exit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// -------------------- 请求队列 --------------------
+//
+// 合约把收到的请求追加到存储里的队列（下标 [head, tail)），每个区块末尾由系统调用从队首取出至多
+// MaxRequestsPerBlock 条交给共识层，取空时 head、tail 归零。第 i 条请求占 QueueStorageOffset+3i 起的 3 个槽：
+// 发送者地址、公钥前 32 字节、公钥后 16 字节 ++ amount(8 字节大端)。
+// 入队时合约另发一条 log0，数据为 发送者(20) ++ 公钥(48) ++ amount(8)。
+// 队列不长时请求在所在区块末尾就已出队，此时只能从回执日志确认入队。
+
+const (
+	QueueStorageOffset  = 4   // 队列起始槽
+	MaxRequestsPerBlock = 16  // MAX_WITHDRAWAL_REQUESTS_PER_BLOCK
+	MaxQueueScan        = 512 // VerifyEnqueued 最多从队尾向前检查的条数
+)
+
+// requestLogLen 入队日志的数据长度
+const requestLogLen = 20 + 48 + 8
+
+// QueuedRequest 由合约存储或入队日志解码出的一条请求
+type QueuedRequest struct {
+	WithdrawalRequest
+	Index  uint64 // 队列下标（从日志解码时为 0）
+	Source common.Address
+}
+
+// QueueBounds 读取 block 高度（nil 为最新）的队首与队尾下标，队列长度为 tail-head
+func QueueBounds(ctx context.Context, cli *ethclient.Client, contract common.Address, block *big.Int) (head, tail uint64, err error) {
+	h, err := readSlot(ctx, cli, contract, big.NewInt(SlotQueueHead), block)
+	if err != nil {
+		return 0, 0, err
+	}
+	t, err := readSlot(ctx, cli, contract, big.NewInt(SlotQueueTail), block)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !h.IsUint64() || !t.IsUint64() || t.Uint64() < h.Uint64() {
+		return 0, 0, fmt.Errorf("invalid queue bounds head=%s tail=%s", h, t)
+	}
+	return h.Uint64(), t.Uint64(), nil
+}
+
+// ReadQueuedRequest 读取 block 高度（nil 为最新）队列下标 index 处的请求；不检查 index 是否在 [head, tail) 内
+func ReadQueuedRequest(ctx context.Context, cli *ethclient.Client, contract common.Address, index uint64, block *big.Int) (QueuedRequest, error) {
+	raw, err := readEntry(ctx, cli, contract, index, block, 0, 1, 2)
+	if err != nil {
+		return QueuedRequest{}, err
+	}
+	return decodeEntry(index, raw[0], raw[1], raw[2]), nil
+}
+
+// Enqueued VerifyEnqueued 的结果
+type Enqueued struct {
+	Block   uint64 // 读取队列时的区块高度
+	Head    uint64
+	Tail    uint64
+	Found   bool
+	Matches int           // 队列中该公钥的请求条数
+	Request QueuedRequest // 最靠近队尾（最新）的一条
+	Scanned uint64        // 实际检查的条数（队列超过 MaxQueueScan 时只检查队尾部分）
+}
+
+// Len 队列长度
+func (e *Enqueued) Len() uint64 { return e.Tail - e.Head }
+
+// Position 找到的请求前面还有多少条（0 为下一个出队）
+func (e *Enqueued) Position() uint64 { return e.Request.Index - e.Head }
+
+// Blocks 按每块出队 MaxRequestsPerBlock 条，找到的请求还需多少个区块才出队（1 为下一个区块）
+func (e *Enqueued) Blocks() uint64 { return e.Position()/MaxRequestsPerBlock + 1 }
+
+// VerifyEnqueued 在最新区块的合约队列中查找该公钥的请求，从队尾向前最多检查 MaxQueueScan 条。
+// 没找到不一定是没入队：请求可能已在区块末尾出队，需结合回执日志（RequestLogs）判断。
+func VerifyEnqueued(ctx context.Context, cli *ethclient.Client, contract common.Address, pubkey48 []byte) (*Enqueued, error) {
+	if len(pubkey48) != 48 {
+		return nil, fmt.Errorf("pubkey length must be 48, got %d", len(pubkey48))
+	}
+	// 固定在同一高度读取，避免扫描过程中队列出队
+	n, err := cli.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get block number: %w", err)
+	}
+	block := new(big.Int).SetUint64(n)
+	head, tail, err := QueueBounds(ctx, cli, contract, block)
+	if err != nil {
+		return nil, err
+	}
+	res := &Enqueued{Block: n, Head: head, Tail: tail}
+	for i := tail; i > head && res.Scanned < MaxQueueScan; i-- {
+		idx := i - 1
+		res.Scanned++
+		// 先比较公钥前 32 字节，命中再读其余两个槽
+		first, err := readEntry(ctx, cli, contract, idx, block, 1)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(first[0], pubkey48[:32]) {
+			continue
+		}
+		rest, err := readEntry(ctx, cli, contract, idx, block, 0, 2)
+		if err != nil {
+			return nil, err
+		}
+		q := decodeEntry(idx, rest[0], first[0], rest[1])
+		if !bytes.Equal(q.Pubkey, pubkey48) {
+			continue
+		}
+		if !res.Found {
+			res.Found, res.Request = true, q
+		}
+		res.Matches++
+	}
+	return res, nil
+}
+
+// RequestLogs 从回执中解码合约的入队日志；回执里没有该合约的日志（交易 revert 或不是请求交易）时为空
+func RequestLogs(rcpt *types.Receipt, contract common.Address) []QueuedRequest {
+	if rcpt == nil {
+		return nil
+	}
+	var out []QueuedRequest
+	for _, lg := range rcpt.Logs {
+		if lg.Address != contract || len(lg.Data) != requestLogLen {
+			continue
+		}
+		d := lg.Data
+		out = append(out, QueuedRequest{
+			WithdrawalRequest: WithdrawalRequest{
+				Pubkey:     common.CopyBytes(d[20:68]),
+				AmountGwei: binary.BigEndian.Uint64(d[68:76]),
+			},
+			Source: common.BytesToAddress(d[:20]),
+		})
+	}
+	return out
+}
+
+// readEntry 读取队列下标 index 处请求的若干槽（0..2）
+func readEntry(ctx context.Context, cli *ethclient.Client, contract common.Address, index uint64, block *big.Int, slots ...int) ([][]byte, error) {
+	base := new(big.Int).SetUint64(index)
+	base.Mul(base, big.NewInt(3))
+	base.Add(base, big.NewInt(QueueStorageOffset))
+	out := make([][]byte, len(slots))
+	for i, s := range slots {
+		slot := new(big.Int).Add(base, big.NewInt(int64(s)))
+		raw, err := cli.StorageAt(ctx, contract, common.BigToHash(slot), block)
+		if err != nil {
+			return nil, fmt.Errorf("read queue entry %d slot %d: %w", index, s, err)
+		}
+		out[i] = common.LeftPadBytes(raw, 32)
+	}
+	return out, nil
+}
+
+// decodeEntry 由三个 32 字节槽解码一条请求
+func decodeEntry(index uint64, source, pk1, pk2amt []byte) QueuedRequest {
+	pubkey := make([]byte, 0, 48)
+	pubkey = append(pubkey, pk1...)
+	pubkey = append(pubkey, pk2amt[:16]...)
+	return QueuedRequest{
+		WithdrawalRequest: WithdrawalRequest{
+			Pubkey:     pubkey,
+			AmountGwei: binary.BigEndian.Uint64(pk2amt[16:24]),
+		},
+		Index:  index,
+		Source: common.BytesToAddress(source),
+	}
+}
+
+func readSlot(ctx context.Context, cli *ethclient.Client, contract common.Address, slot, block *big.Int) (*big.Int, error) {
+	raw, err := cli.StorageAt(ctx, contract, common.BigToHash(slot), block)
+	if err != nil {
+		return nil, fmt.Errorf("read slot %s: %w", slot, err)
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,46 @@
+package exit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/capability"
+)
+
+// ExitSender 发送退出请求的能力：调用方给出发送私钥，实现决定发往哪个合约、怎样付费
+type ExitSender interface {
+	GetExitFee(ctx context.Context) (*big.Int, error)
+	SendExitRequest(ctx context.Context, priv *ecdsa.PrivateKey, pubkey48 []byte, amountGwei *big.Int, wait bool) (*types.Transaction, *types.Receipt, error)
+}
+
+// Client 绑定了 RPC 连接与退出合约地址的 ExitSender 实现
+type Client struct {
+	cli      *ethclient.Client
+	contract common.Address
+	caps     *capability.Matrix
+}
+
+var _ ExitSender = (*Client)(nil)
+
+func NewClient(cli *ethclient.Client, contract common.Address) *Client {
+	return &Client{cli: cli, contract: contract}
+}
+
+// WithCapabilities 设置节点能力矩阵，发送时据此选择快速路径
+func (c *Client) WithCapabilities(m *capability.Matrix) *Client {
+	c.caps = m
+	return c
+}
+
+func (c *Client) GetExitFee(ctx context.Context) (*big.Int, error) {
+	return GetExitFee(ctx, c.cli, c.contract)
+}
+
+func (c *Client) SendExitRequest(ctx context.Context, priv *ecdsa.PrivateKey, pubkey48 []byte, amountGwei *big.Int, wait bool) (*types.Transaction, *types.Receipt, error) {
+	return SendExitRequestWithCaps(ctx, c.cli, c.caps, priv, c.contract, pubkey48, amountGwei, wait)
+}
@@ -0,0 +1,259 @@
+package exit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/capability"
+	"n42-test/internal/hexutil"
+)
+
+// -------------------- EIP-7002 提款请求 --------------------
+//
+// 同一个合约承载两种请求：amount=0 为全额退出，amount>0 为部分提款（只有 0x02 复利凭证、
+// 余额超过 32 ETH 的部分可提）。信标链对不满足条件的请求静默忽略，合约照常收费，
+// 所以发送前按信标状态校验，比事后对账更早发现问题。
+
+// 请求类型
+const (
+	KindFullExit = "full-exit"
+	KindPartial  = "partial"
+)
+
+// Electra 规范常量
+const (
+	MinActivationBalanceGwei = 32_000_000_000 // MIN_ACTIVATION_BALANCE
+	ShardCommitteePeriod     = 256            // 激活后至少经过这么多纪元才能发起退出 / 部分提款
+)
+
+// WithdrawalRequest 一条 EIP-7002 提款请求
+type WithdrawalRequest struct {
+	Pubkey     []byte // 48 字节 BLS 公钥
+	AmountGwei uint64 // 0 为全额退出
+}
+
+// FullExit 全额退出请求
+func FullExit(pubkey48 []byte) WithdrawalRequest {
+	return WithdrawalRequest{Pubkey: pubkey48}
+}
+
+// PartialWithdrawal 部分提款请求；amountGwei 必须大于 0（0 在合约语义里是全额退出）
+func PartialWithdrawal(pubkey48 []byte, amountGwei uint64) (WithdrawalRequest, error) {
+	if amountGwei == 0 {
+		return WithdrawalRequest{}, errors.New("partial withdrawal amount must be > 0 (0 means full exit)")
+	}
+	return WithdrawalRequest{Pubkey: pubkey48, AmountGwei: amountGwei}, nil
+}
+
+func (r WithdrawalRequest) IsFullExit() bool { return r.AmountGwei == 0 }
+
+// Kind full-exit | partial
+func (r WithdrawalRequest) Kind() string {
+	if r.IsFullExit() {
+		return KindFullExit
+	}
+	return KindPartial
+}
+
+// Amount 请求金额（gwei），供 SendExitRequest / FeePayer 使用
+func (r WithdrawalRequest) Amount() *big.Int {
+	return new(big.Int).SetUint64(r.AmountGwei)
+}
+
+// Calldata 合约调用数据 [pubkey(48) | amount(8)]
+func (r WithdrawalRequest) Calldata() ([]byte, error) {
+	return PackExitCalldata(r.Pubkey, r.Amount())
+}
+
+// SendWithdrawalRequest 发送一条提款请求（全额退出或部分提款），发送流程同 SendExitRequestWithCaps
+func SendWithdrawalRequest(
+	ctx context.Context,
+	cli *ethclient.Client,
+	caps *capability.Matrix,
+	priv *ecdsa.PrivateKey,
+	contract common.Address,
+	req WithdrawalRequest,
+	wait bool,
+) (*types.Transaction, *types.Receipt, error) {
+	return SendExitRequestWithCaps(ctx, cli, caps, priv, contract, req.Pubkey, req.Amount(), wait)
+}
+
+// SendPartialWithdrawal 发送部分提款请求；amountGwei 为 0 时报错，避免误发全额退出
+func SendPartialWithdrawal(
+	ctx context.Context,
+	cli *ethclient.Client,
+	caps *capability.Matrix,
+	priv *ecdsa.PrivateKey,
+	contract common.Address,
+	pubkey48 []byte,
+	amountGwei uint64,
+	wait bool,
+) (*types.Transaction, *types.Receipt, error) {
+	req, err := PartialWithdrawal(pubkey48, amountGwei)
+	if err != nil {
+		return nil, nil, err
+	}
+	return SendWithdrawalRequest(ctx, cli, caps, priv, contract, req, wait)
+}
+
+// SendPartialWithdrawal 同包级 SendPartialWithdrawal，使用 Client 绑定的连接、合约与能力矩阵
+func (c *Client) SendPartialWithdrawal(ctx context.Context, priv *ecdsa.PrivateKey, pubkey48 []byte, amountGwei uint64, wait bool) (*types.Transaction, *types.Receipt, error) {
+	return SendPartialWithdrawal(ctx, c.cli, c.caps, priv, c.contract, pubkey48, amountGwei, wait)
+}
+
+// ValidatorInfo 信标状态中一个验证者与提款请求相关的信息
+type ValidatorInfo struct {
+	Index             int // 状态中没有该公钥时为 -1
+	Pubkey            string
+	Status            string
+	CredentialType    string
+	WithdrawalAddress common.Address // 0x01 / 0x02 凭证中的执行层地址
+	Balance           uint64         // gwei
+	EffectiveBalance  uint64         // gwei
+	PendingGwei       uint64         // 排队中的部分提款
+	ActivationEpoch   uint64
+	ExitEpoch         uint64
+	Epoch             uint64 // 状态所在纪元
+}
+
+func (v *ValidatorInfo) Found() bool { return v.Index >= 0 }
+
+// HasExecutionAddress 凭证为 0x01 / 0x02，请求只能由其中的地址发起
+func (v *ValidatorInfo) HasExecutionAddress() bool {
+	return v.CredentialType == "0x01" || v.CredentialType == "0x02"
+}
+
+// LookupValidator 按公钥在信标状态中查找验证者；找不到时返回 Index=-1 的 ValidatorInfo
+func LookupValidator(st *beaconstate.State, pubkey48 []byte, slotsPerEpoch uint64) (*ValidatorInfo, error) {
+	info := &ValidatorInfo{Index: -1, Pubkey: hexutil.Encode(pubkey48), Epoch: st.Epoch(slotsPerEpoch)}
+	i, ok := st.Index()[beaconstate.NormPubkey(info.Pubkey)]
+	if !ok {
+		return info, nil
+	}
+	v := st.Validators[i]
+	info.Index = i
+	info.CredentialType = v.CredentialType()
+	info.EffectiveBalance = v.EffectiveBalance
+	info.ActivationEpoch, info.ExitEpoch = v.ActivationEpoch, v.ExitEpoch
+	if info.HasExecutionAddress() {
+		info.WithdrawalAddress = credentialAddress(v.WithdrawalCredentials)
+	}
+	info.Balance = st.Balance(i)
+	info.Status = v.Status(info.Epoch, info.Balance)
+	pending, err := st.PendingBalanceToWithdraw(uint64(i))
+	if err != nil {
+		return nil, err
+	}
+	info.PendingGwei = pending
+	return info, nil
+}
+
+// ExcessGwei 部分提款可提取的上限：balance - 32 ETH - pending（不足时为 0）
+func (v *ValidatorInfo) ExcessGwei() uint64 {
+	if v.Balance > MinActivationBalanceGwei+v.PendingGwei {
+		return v.Balance - MinActivationBalanceGwei - v.PendingGwei
+	}
+	return 0
+}
+
+// RequestProblems 退出 / 部分提款 / 合并 source（internal/consolidate）共同的前提：凭证中有执行层地址，且 source 非零时
+// 须为该地址（合约按交易发送者记录请求来源）；其余同 ActivityProblems
+func (v *ValidatorInfo) RequestProblems(source common.Address, checkPeriod bool) []string {
+	var out []string
+	if !v.HasExecutionAddress() {
+		out = append(out, fmt.Sprintf("withdrawal credentials %s have no execution address", v.CredentialType))
+	} else if source != (common.Address{}) && v.WithdrawalAddress != source {
+		out = append(out, fmt.Sprintf("sender %s is not the withdrawal address %s", source.Hex(), v.WithdrawalAddress.Hex()))
+	}
+	return append(out, v.ActivityProblems(checkPeriod)...)
+}
+
+// ActivityProblems 已激活、未在退出中；checkPeriod 时还要求激活满 ShardCommitteePeriod 个纪元
+func (v *ValidatorInfo) ActivityProblems(checkPeriod bool) []string {
+	var out []string
+	if v.Epoch < v.ActivationEpoch || v.Epoch >= v.ExitEpoch {
+		out = append(out, fmt.Sprintf("validator not active at epoch %d (status %s)", v.Epoch, v.Status))
+	} else if v.ExitEpoch != beaconstate.FarFutureEpoch {
+		out = append(out, fmt.Sprintf("validator already exiting (exit_epoch %d)", v.ExitEpoch))
+	}
+	if checkPeriod && v.ActivationEpoch != beaconstate.FarFutureEpoch && v.Epoch < v.ActivationEpoch+ShardCommitteePeriod {
+		out = append(out, fmt.Sprintf("activated at epoch %d, requests allowed from epoch %d (now %d)",
+			v.ActivationEpoch, v.ActivationEpoch+ShardCommitteePeriod, v.Epoch))
+	}
+	return out
+}
+
+// RequestCheck 按信标状态对一条提款请求的预检结果
+type RequestCheck struct {
+	Kind string
+	ValidatorInfo
+	// Problems 信标链会忽略该请求的原因；为空表示可以发送
+	Problems []string
+}
+
+func (c *RequestCheck) OK() bool { return len(c.Problems) == 0 }
+
+// Err 有问题时返回汇总错误
+func (c *RequestCheck) Err() error {
+	return problemsErr(c.Kind, c.Problems)
+}
+
+func problemsErr(kind string, problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s rejected by beacon state check: %s", kind, strings.Join(problems, "; "))
+}
+
+// CheckWithdrawalRequest 按 Electra process_withdrawal_request 的规则预检请求：
+// 验证者须已激活、未在退出中、激活满 ShardCommitteePeriod 个纪元；全额退出要求没有排队中的部分提款；
+// 部分提款要求 0x02 凭证、有效余额不低于 32 ETH、金额不超过超额余额。
+// source 非零时还检查它与提款凭证中的地址一致。
+func CheckWithdrawalRequest(st *beaconstate.State, source common.Address, req WithdrawalRequest, slotsPerEpoch uint64) (*RequestCheck, error) {
+	info, err := LookupValidator(st, req.Pubkey, slotsPerEpoch)
+	if err != nil {
+		return nil, err
+	}
+	c := &RequestCheck{Kind: req.Kind(), ValidatorInfo: *info}
+	if !info.Found() {
+		c.Problems = append(c.Problems, "validator not found in beacon state")
+		return c, nil
+	}
+	c.Problems = info.RequestProblems(source, true)
+
+	if req.IsFullExit() {
+		if info.PendingGwei > 0 {
+			c.Problems = append(c.Problems, fmt.Sprintf("%d gwei of partial withdrawals still pending", info.PendingGwei))
+		}
+		return c, nil
+	}
+	if info.CredentialType != "0x02" {
+		c.Problems = append(c.Problems, "partial withdrawals require 0x02 (compounding) credentials")
+	}
+	if info.EffectiveBalance < MinActivationBalanceGwei {
+		c.Problems = append(c.Problems, fmt.Sprintf("effective balance %d gwei below %d", info.EffectiveBalance, uint64(MinActivationBalanceGwei)))
+	}
+	if excess := info.ExcessGwei(); req.AmountGwei > excess {
+		c.Problems = append(c.Problems, fmt.Sprintf("amount %d gwei exceeds withdrawable excess %d gwei (balance %d, pending %d)",
+			req.AmountGwei, excess, info.Balance, info.PendingGwei))
+	}
+	return c, nil
+}
+
+// credentialAddress 0x01 / 0x02 提款凭证末 20 字节的执行层地址
+func credentialAddress(wc string) common.Address {
+	b, err := hexutil.DecodeFixed(wc, hexutil.HashLen)
+	if err != nil {
+		return common.Address{}
+	}
+	return common.BytesToAddress(b[12:])
+}
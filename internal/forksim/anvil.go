@@ -0,0 +1,130 @@
+// 分叉模拟：在目标链的 anvil 分叉上先跑一遍批量交易，
+// 提前发现会 revert 的条目并估算总消耗，确认无误后再上真实链。
+package forksim
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// DefaultAnvilBin 默认的 anvil 可执行文件
+const DefaultAnvilBin = "anvil"
+
+// Fork 一个可用的分叉 RPC；由 StartAnvil 启动的会在 Close 时结束进程
+type Fork struct {
+	URL string
+
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+	done   chan struct{}
+}
+
+// Use 直接使用一个已有的分叉 endpoint（例如外部启动的 anvil/hardhat）
+func Use(url string) *Fork {
+	return &Fork{URL: url}
+}
+
+// StartAnvil 以 upstream 为源启动 anvil 分叉（自动出块），等待 RPC 就绪后返回
+func StartAnvil(ctx context.Context, anvilBin, upstream string) (*Fork, error) {
+	if anvilBin == "" {
+		anvilBin = DefaultAnvilBin
+	}
+	if _, err := exec.LookPath(anvilBin); err != nil {
+		return nil, fmt.Errorf("anvil not found (%s): %w；可安装 foundry 或用 --fork-rpc 指定现成的分叉", anvilBin, err)
+	}
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fork{
+		URL:    "http://127.0.0.1:" + strconv.Itoa(port),
+		stderr: &bytes.Buffer{},
+		done:   make(chan struct{}),
+	}
+	f.cmd = exec.Command(anvilBin,
+		"--fork-url", upstream,
+		"--port", strconv.Itoa(port),
+		"--host", "127.0.0.1",
+		"--silent",
+	)
+	f.cmd.Stderr = f.stderr
+	if err := f.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start anvil: %w", err)
+	}
+	go func() {
+		_ = f.cmd.Wait()
+		close(f.done)
+	}()
+
+	if err := f.waitReady(ctx, 30*time.Second); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// waitReady 轮询 eth_chainId 直到分叉可用
+func (f *Fork) waitReady(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		select {
+		case <-f.done:
+			return fmt.Errorf("anvil exited early: %s", f.stderr.String())
+		default:
+		}
+		cctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		cli, err := ethclient.DialContext(cctx, f.URL)
+		if err == nil {
+			_, err = cli.ChainID(cctx)
+			cli.Close()
+		}
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("anvil not ready after %s: %w", timeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+}
+
+// Close 结束由 StartAnvil 启动的进程；Use 得到的分叉不做任何事
+func (f *Fork) Close() error {
+	if f.cmd == nil || f.cmd.Process == nil {
+		return nil
+	}
+	select {
+	case <-f.done:
+		return nil
+	default:
+	}
+	if err := f.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	<-f.done
+	return nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("pick free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
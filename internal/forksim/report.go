@@ -0,0 +1,78 @@
+package forksim
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"n42-test/internal/units"
+)
+
+// ItemOutcome 单条模拟结果
+type ItemOutcome struct {
+	Index    int
+	Reverted bool   // 交易被打包但 status=0
+	Err      error  // 发送前/发送时失败（估算 gas 失败通常意味着会 revert）
+	GasUsed  uint64 // 已打包交易的实际 gas
+	GasCost  *big.Int
+	ValueWei *big.Int // 交易附带的转账金额
+}
+
+func (o ItemOutcome) failed() bool { return o.Reverted || o.Err != nil }
+
+// Report 整批模拟汇总
+type Report struct {
+	Items []ItemOutcome
+}
+
+func (r *Report) Add(o ItemOutcome) { r.Items = append(r.Items, o) }
+
+// Failed 会失败（revert 或发送失败）的条目
+func (r *Report) Failed() []ItemOutcome {
+	var out []ItemOutcome
+	for _, o := range r.Items {
+		if o.failed() {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// Totals 成功条目的总 gas、总 gas 费用与总转账金额（wei）
+func (r *Report) Totals() (gas uint64, gasCost, value *big.Int) {
+	gasCost, value = new(big.Int), new(big.Int)
+	for _, o := range r.Items {
+		if o.failed() {
+			continue
+		}
+		gas += o.GasUsed
+		if o.GasCost != nil {
+			gasCost.Add(gasCost, o.GasCost)
+		}
+		if o.ValueWei != nil {
+			value.Add(value, o.ValueWei)
+		}
+	}
+	return gas, gasCost, value
+}
+
+// String 多行可读汇总
+func (r *Report) String() string {
+	var b strings.Builder
+	failed := r.Failed()
+	gas, gasCost, value := r.Totals()
+	fmt.Fprintf(&b, "分叉模拟：共 %d 条，通过 %d，失败 %d\n", len(r.Items), len(r.Items)-len(failed), len(failed))
+	for _, o := range failed {
+		if o.Reverted {
+			fmt.Fprintf(&b, "  [#%d] revert（gasUsed=%d）\n", o.Index, o.GasUsed)
+		} else {
+			fmt.Fprintf(&b, "  [#%d] 失败: %v\n", o.Index, o.Err)
+		}
+	}
+	total := new(big.Int).Add(gasCost, value)
+	fmt.Fprintf(&b, "  总 gas: %d\n", gas)
+	fmt.Fprintf(&b, "  gas 费用: %s ETH\n", units.FromWei(gasCost).ETH())
+	fmt.Fprintf(&b, "  转账金额: %s ETH\n", units.FromWei(value).ETH())
+	fmt.Fprintf(&b, "  合计消耗: %s ETH", units.FromWei(total).ETH())
+	return b.String()
+}
@@ -0,0 +1,219 @@
+// 质押→验证交接：为已激活的验证者生成 keystore/secrets 目录、启动脚本与 systemd unit，
+// 用我们的见证 runner（attestion-test）或 Lighthouse 直接跑起来。
+//
+// 生成的目录结构：
+//
+//	<dir>/
+//	  validator_keys/keystore-<pubkey>.json   EIP-2335 keystore
+//	  secrets/password.txt                    所有 keystore 共用的口令
+//	  secrets/0x<pubkey>                      同一口令，按公钥命名（Lighthouse/Teku 的 secrets 目录格式）
+//	  launch.sh                               启动命令
+//	  systemd/*.service                       可选
+package handoff
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"n42-test/internal/keystore"
+)
+
+// 支持的验证者客户端
+const (
+	ClientAttest     = "attest"     // 本仓库的 cmd/attestion-test
+	ClientLighthouse = "lighthouse" // Lighthouse validator client
+)
+
+// Entry 一个要交接的验证者
+type Entry struct {
+	PubkeyHex string // 0x 前缀 48 字节
+	Secret    []byte // 32 字节私钥（大端）
+}
+
+// Options 生成选项
+type Options struct {
+	Dir      string
+	Client   string // attest|lighthouse
+	Systemd  bool
+	Password string // 为空时随机生成
+
+	// attest 客户端参数
+	AttestBin string // 为空时使用 "go run ./cmd/attestion-test"
+	WSURL     string
+	HTTPURL   string
+
+	// lighthouse 参数
+	LighthouseBin string // 为空时使用 "lighthouse"
+	BeaconNode    string
+	Network       string // 透传给 --network；为空则不加
+}
+
+// Result 生成结果
+type Result struct {
+	Keystores    []string
+	LaunchScript string
+	Units        []string
+}
+
+// Write 生成目录、keystore、启动脚本与（可选）systemd unit
+func Write(entries []Entry, opts Options) (*Result, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no validators to hand off")
+	}
+	if opts.Client == "" {
+		opts.Client = ClientAttest
+	}
+	if opts.Client != ClientAttest && opts.Client != ClientLighthouse {
+		return nil, fmt.Errorf("unknown client %q (want attest|lighthouse)", opts.Client)
+	}
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	opts.Dir = dir
+	for _, sub := range []string{"validator_keys", "secrets", "logs"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Password == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		opts.Password = hex.EncodeToString(b)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secrets", "password.txt"), []byte(opts.Password), 0o600); err != nil {
+		return nil, err
+	}
+
+	res := &Result{}
+	for _, e := range entries {
+		pk := "0x" + strings.TrimPrefix(strings.ToLower(e.PubkeyHex), "0x")
+		ks, err := keystore.Encrypt(e.Secret, pk, opts.Password)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt %s: %w", pk, err)
+		}
+		path := filepath.Join(dir, "validator_keys", "keystore-"+strings.TrimPrefix(pk, "0x")+".json")
+		if err := ks.Save(path); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "secrets", pk), []byte(opts.Password), 0o600); err != nil {
+			return nil, err
+		}
+		res.Keystores = append(res.Keystores, path)
+	}
+
+	res.LaunchScript = filepath.Join(dir, "launch.sh")
+	if err := os.WriteFile(res.LaunchScript, []byte(launchScript(res.Keystores, opts)), 0o755); err != nil {
+		return nil, err
+	}
+	if opts.Systemd {
+		units, err := writeUnits(res.Keystores, opts)
+		if err != nil {
+			return nil, err
+		}
+		res.Units = units
+	}
+	return res, nil
+}
+
+func attestCommand(keystorePath string, opts Options) string {
+	bin := opts.AttestBin
+	if bin == "" {
+		bin = "go run ./cmd/attestion-test"
+	}
+	cmd := fmt.Sprintf("%s -keystore %q -password-file %q", bin, keystorePath, filepath.Join(opts.Dir, "secrets", "password.txt"))
+	if opts.WSURL != "" {
+		cmd += fmt.Sprintf(" -ws %q", opts.WSURL)
+	}
+	if opts.HTTPURL != "" {
+		cmd += fmt.Sprintf(" -rpc %q", opts.HTTPURL)
+	}
+	return cmd
+}
+
+func lighthouseCommands(opts Options) (imp, vc string) {
+	bin := opts.LighthouseBin
+	if bin == "" {
+		bin = "lighthouse"
+	}
+	datadir := filepath.Join(opts.Dir, "lighthouse")
+	network := ""
+	if opts.Network != "" {
+		network = " --network " + opts.Network
+	}
+	imp = fmt.Sprintf("%s%s account validator import --datadir %q --directory %q --password-file %q --reuse-password",
+		bin, network, datadir, filepath.Join(opts.Dir, "validator_keys"), filepath.Join(opts.Dir, "secrets", "password.txt"))
+	vc = fmt.Sprintf("%s%s vc --datadir %q", bin, network, datadir)
+	if opts.BeaconNode != "" {
+		vc += fmt.Sprintf(" --beacon-nodes %q", opts.BeaconNode)
+	}
+	return imp, vc
+}
+
+func launchScript(keystores []string, opts Options) string {
+	var b strings.Builder
+	b.WriteString("#!/usr/bin/env bash\n")
+	b.WriteString("# 由 deposit-batch --handoff-dir 生成\n")
+	b.WriteString("set -euo pipefail\n\n")
+	switch opts.Client {
+	case ClientLighthouse:
+		imp, vc := lighthouseCommands(opts)
+		b.WriteString(imp + "\n")
+		b.WriteString("exec " + vc + "\n")
+	default:
+		b.WriteString("# 每个验证者一个见证 runner，日志写入 logs/\n")
+		for _, ks := range keystores {
+			name := strings.TrimSuffix(filepath.Base(ks), ".json")
+			fmt.Fprintf(&b, "nohup %s > %q 2>&1 &\n", attestCommand(ks, opts), filepath.Join(opts.Dir, "logs", name+".log"))
+		}
+		b.WriteString("wait\n")
+	}
+	return b.String()
+}
+
+func writeUnits(keystores []string, opts Options) ([]string, error) {
+	unitDir := filepath.Join(opts.Dir, "systemd")
+	if err := os.MkdirAll(unitDir, 0o755); err != nil {
+		return nil, err
+	}
+	cwd, _ := os.Getwd()
+	write := func(name, desc, exec string, pre ...string) (string, error) {
+		var b strings.Builder
+		fmt.Fprintf(&b, "[Unit]\nDescription=%s\nAfter=network-online.target\nWants=network-online.target\n\n", desc)
+		fmt.Fprintf(&b, "[Service]\nWorkingDirectory=%s\n", cwd)
+		for _, p := range pre {
+			fmt.Fprintf(&b, "ExecStartPre=%s\n", p)
+		}
+		fmt.Fprintf(&b, "ExecStart=%s\nRestart=on-failure\nRestartSec=5\n\n[Install]\nWantedBy=multi-user.target\n", exec)
+		path := filepath.Join(unitDir, name)
+		return path, os.WriteFile(path, []byte(b.String()), 0o644)
+	}
+
+	var units []string
+	switch opts.Client {
+	case ClientLighthouse:
+		imp, vc := lighthouseCommands(opts)
+		p, err := write("n42-lighthouse-vc.service", "N42 Lighthouse validator client", vc, imp)
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, p)
+	default:
+		for _, ks := range keystores {
+			short := strings.TrimPrefix(strings.TrimSuffix(filepath.Base(ks), ".json"), "keystore-")[:12]
+			p, err := write("n42-attest-"+short+".service", "N42 attest runner "+short, attestCommand(ks, opts))
+			if err != nil {
+				return nil, err
+			}
+			units = append(units, p)
+		}
+	}
+	return units, nil
+}
@@ -0,0 +1,48 @@
+package handoff
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+)
+
+// WaitActive 轮询信标状态，直到 pubkeys 全部激活或超时；返回已激活的公钥（小写 0x）。
+// 超时不算错误，调用方只为已激活的部分生成交接文件。
+func WaitActive(ctx context.Context, r beaconext.BeaconReader, pubkeys []string, slotsPerEpoch uint64, poll, timeout time.Duration) (map[string]bool, error) {
+	want := map[string]bool{}
+	for _, pk := range pubkeys {
+		want[beaconstate.NormPubkey(pk)] = true
+	}
+	deadline := time.Now().Add(timeout)
+	active := map[string]bool{}
+	for {
+		st, err := beaconstate.FetchLatest(ctx, r)
+		if err != nil {
+			log.Printf("⚠️ 读取信标状态失败: %v", err)
+		} else {
+			epoch := st.Epoch(slotsPerEpoch)
+			for _, v := range st.Validators {
+				pk := beaconstate.NormPubkey(v.Pubkey)
+				if want[pk] && v.IsActive(epoch) {
+					active[pk] = true
+				}
+			}
+			log.Printf("epoch %d：已激活 %d/%d", epoch, len(active), len(want))
+			if len(active) == len(want) {
+				return active, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			log.Printf("⚠️ 等待激活超时（%s），只为已激活的 %d 个验证者生成交接文件", timeout, len(active))
+			return active, nil
+		}
+		select {
+		case <-ctx.Done():
+			return active, ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// 各包共用的十六进制工具：统一的 0x 前缀处理、规范化与定长解码（错误信息一致），
+// 代替各处行为略有差异的 hexToBytes / mustDecodeHex / decodeExactHex。
+// 约定：输入两侧空白忽略，0x / 0X 前缀可有可无；输出一律小写带 0x。
+package hexutil
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 常用定长
+const (
+	AddressLen   = 20 // 执行层地址
+	HashLen      = 32 // 哈希 / withdrawal_credentials / deposit_data_root / 私钥
+	PubkeyLen    = 48 // BLS 公钥
+	SignatureLen = 96 // BLS 签名
+)
+
+// ErrEmpty 输入为空（去掉前缀后无内容）
+var ErrEmpty = errors.New("empty hex")
+
+// LengthError 解码成功但字节数不符
+type LengthError struct {
+	Got, Want int
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("invalid length %d bytes, want %d", e.Got, e.Want)
+}
+
+// Trim 去掉两侧空白与 0x / 0X 前缀
+func Trim(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return s[2:]
+	}
+	return s
+}
+
+// Normalize 规范化为小写带 0x（不校验内容）
+func Normalize(s string) string {
+	return "0x" + strings.ToLower(Trim(s))
+}
+
+// IsHex 去掉前缀后是否为非空的十六进制串（长度可为奇数）
+func IsHex(s string) bool {
+	s = Trim(s)
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// Decode 解码任意长度的十六进制；空输入返回 ErrEmpty
+func Decode(s string) ([]byte, error) {
+	raw := Trim(s)
+	if raw == "" {
+		return nil, ErrEmpty
+	}
+	b, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("hex decode failed: %w", err)
+	}
+	return b, nil
+}
+
+// DecodeFixed 解码并要求正好 want 字节；长度不符返回 *LengthError
+func DecodeFixed(s string, want int) ([]byte, error) {
+	b, err := Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != want {
+		return nil, &LengthError{Got: len(b), Want: want}
+	}
+	return b, nil
+}
+
+// Encode 编码为小写带 0x
+func Encode(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
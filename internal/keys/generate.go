@@ -0,0 +1,46 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// blsCurveOrder BLS12-381 的子群阶 r；BLS 私钥取值范围为 [1, r)
+var blsCurveOrder, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+// GenerateECDSA 随机生成执行层账户，返回 0x 前缀私钥与地址
+func GenerateECDSA() (keyHex string, addr common.Address, err error) {
+	priv, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+	if err != nil {
+		return "", common.Address{}, fmt.Errorf("generate secp256k1 key: %w", err)
+	}
+	return "0x" + hex.EncodeToString(crypto.FromECDSA(priv)), crypto.PubkeyToAddress(priv.PublicKey), nil
+}
+
+// GenerateBLSSecret 随机生成 BLS 私钥，按字节序编码为 32 字节十六进制（无 0x，与 accounts.json 一致）；
+// 公钥由调用方用同样的字节序推导（blsutil.DerivePublicKeyHex）
+func GenerateBLSSecret(littleEndian bool) (string, error) {
+	for {
+		var b [32]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", fmt.Errorf("generate BLS key: %w", err)
+		}
+		k := new(big.Int).SetBytes(b[:])
+		if k.Sign() == 0 || k.Cmp(blsCurveOrder) >= 0 {
+			continue // 拒绝采样，保证均匀分布
+		}
+		k.FillBytes(b[:])
+		if littleEndian {
+			for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+				b[i], b[j] = b[j], b[i]
+			}
+		}
+		return hex.EncodeToString(b[:]), nil
+	}
+}
@@ -0,0 +1,206 @@
+// 统一的私钥解析：各命令的私钥参数与 JSON 中的私钥字段都经由这里，按内容自动识别格式。
+//
+// secp256k1（执行层账户）：
+//   - 十六进制 32 字节，0x 前缀可有可无
+//   - go-ethereum keystore（v3）JSON，内联或文件路径
+//   - 助记词，可在末尾附派生路径："word1 … word12 m/44'/60'/0'/0/1"（缺省 DefaultDerivationPath）
+//
+// BLS（验证者）：
+//   - 十六进制 32 字节，字节序按调用方默认（blsutil.KeyOptions），也可用 "le:" / "be:" 前缀显式指定
+//   - EIP-2335 keystore JSON，内联或文件路径
+//
+// keystore 的密码取 Options.Password，为空时取环境变量 KEYSTORE_PASSWORD。
+package keys
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	gethkeystore "github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keystore"
+)
+
+// PasswordEnv 未设置 Options.Password 时读取 keystore 密码的环境变量
+const PasswordEnv = "KEYSTORE_PASSWORD"
+
+// Format 识别出的私钥格式
+type Format string
+
+const (
+	FormatHex      Format = "hex"
+	FormatKeystore Format = "keystore" // go-ethereum keystore v3
+	FormatEIP2335  Format = "eip2335"  // BLS keystore v4
+	FormatMnemonic Format = "mnemonic"
+)
+
+// Options 解析选项
+type Options struct {
+	Password string // keystore 密码
+}
+
+var (
+	defaultOptsMu sync.RWMutex
+	defaultOpts   Options
+)
+
+// SetDefaultOptions 设置进程内默认的解析选项（ParseECDSA / ParseBLSSecret 使用）
+func SetDefaultOptions(opts Options) {
+	defaultOptsMu.Lock()
+	defaultOpts = opts
+	defaultOptsMu.Unlock()
+}
+
+// DefaultOptions 当前进程内默认的解析选项
+func DefaultOptions() Options {
+	defaultOptsMu.RLock()
+	defer defaultOptsMu.RUnlock()
+	return defaultOpts
+}
+
+func (o Options) password() (string, error) {
+	if o.Password != "" {
+		return o.Password, nil
+	}
+	if pw, ok := os.LookupEnv(PasswordEnv); ok {
+		return pw, nil
+	}
+	return "", fmt.Errorf("keystore needs a password (set %s)", PasswordEnv)
+}
+
+// Detect 识别 spec 的格式；文件路径按文件内容识别，返回内容供后续解析
+func Detect(spec string) (Format, string, error) {
+	s := strings.TrimSpace(spec)
+	if s == "" {
+		return "", "", errors.New("empty private key")
+	}
+	if strings.HasPrefix(s, "{") {
+		var probe struct {
+			Version int             `json:"version"`
+			Crypto  json.RawMessage `json:"crypto"`
+			Legacy  json.RawMessage `json:"Crypto"`
+		}
+		if err := json.Unmarshal([]byte(s), &probe); err != nil {
+			return "", "", fmt.Errorf("parse key json: %w", err)
+		}
+		switch {
+		case probe.Version == 4 && len(probe.Crypto) > 0:
+			return FormatEIP2335, s, nil
+		case probe.Version == 3 && (len(probe.Crypto) > 0 || len(probe.Legacy) > 0):
+			return FormatKeystore, s, nil
+		}
+		return "", "", fmt.Errorf("unsupported key json (version %d)", probe.Version)
+	}
+	if hexutil.IsHex(s) {
+		return FormatHex, s, nil
+	}
+	if len(strings.Fields(s)) >= 12 {
+		return FormatMnemonic, s, nil
+	}
+	if b, err := os.ReadFile(s); err == nil {
+		f, content, err := Detect(string(b))
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %w", s, err)
+		}
+		return f, content, nil
+	}
+	return "", "", errors.New("unrecognized private key format (want hex, keystore json/path or mnemonic)")
+}
+
+// ParseECDSA 按默认选项解析 secp256k1 私钥
+func ParseECDSA(spec string) (*ecdsa.PrivateKey, error) {
+	return ParseECDSAWith(spec, DefaultOptions())
+}
+
+// ParseECDSAWith 解析 secp256k1 私钥：hex / go-ethereum keystore / 助记词[+路径]
+func ParseECDSAWith(spec string, opts Options) (*ecdsa.PrivateKey, error) {
+	f, s, err := Detect(spec)
+	if err != nil {
+		return nil, err
+	}
+	switch f {
+	case FormatHex:
+		b, err := hexutil.DecodeFixed(s, hexutil.HashLen)
+		if err != nil {
+			return nil, fmt.Errorf("private key: %w", err)
+		}
+		return crypto.ToECDSA(b)
+	case FormatKeystore:
+		pw, err := opts.password()
+		if err != nil {
+			return nil, err
+		}
+		k, err := gethkeystore.DecryptKey([]byte(s), pw)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt keystore: %w", err)
+		}
+		return k.PrivateKey, nil
+	case FormatMnemonic:
+		return fromMnemonic(s)
+	default:
+		return nil, fmt.Errorf("%s is not a secp256k1 key format", f)
+	}
+}
+
+// Address 私钥对应的执行层地址
+func Address(spec string) (common.Address, error) {
+	priv, err := ParseECDSA(spec)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(priv.PublicKey), nil
+}
+
+// ParseBLSSecret 解析 BLS 私钥，返回 32 字节原始值及其字节序：
+// 十六进制按 defaultLE（"le:" / "be:" 前缀优先），EIP-2335 keystore 固定为大端。
+func ParseBLSSecret(spec string, defaultLE bool) (raw []byte, littleEndian bool, err error) {
+	return ParseBLSSecretWith(spec, defaultLE, DefaultOptions())
+}
+
+// ParseBLSSecretWith 同 ParseBLSSecret，使用给定选项
+func ParseBLSSecretWith(spec string, defaultLE bool, opts Options) (raw []byte, littleEndian bool, err error) {
+	s := strings.TrimSpace(spec)
+	littleEndian = defaultLE
+	switch lower := strings.ToLower(s); {
+	case strings.HasPrefix(lower, "le:"):
+		s, littleEndian = s[3:], true
+	case strings.HasPrefix(lower, "be:"):
+		s, littleEndian = s[3:], false
+	}
+	f, s, err := Detect(s)
+	if err != nil {
+		return nil, false, err
+	}
+	switch f {
+	case FormatHex:
+		raw, err = hexutil.DecodeFixed(s, hexutil.HashLen)
+		if err != nil {
+			return nil, false, fmt.Errorf("BLS secret key: %w", err)
+		}
+		return raw, littleEndian, nil
+	case FormatEIP2335:
+		var ks keystore.Keystore
+		if err := json.Unmarshal([]byte(s), &ks); err != nil {
+			return nil, false, fmt.Errorf("parse keystore: %w", err)
+		}
+		pw, err := opts.password()
+		if err != nil {
+			return nil, false, err
+		}
+		raw, err = ks.Decrypt(pw)
+		if err != nil {
+			return nil, false, err
+		}
+		return raw, false, nil
+	default:
+		return nil, false, fmt.Errorf("%s is not a BLS key format", f)
+	}
+}
@@ -0,0 +1,107 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// DefaultDerivationPath 助记词未附路径时使用的 BIP-44 以太坊路径
+const DefaultDerivationPath = "m/44'/60'/0'/0/0"
+
+const hardened = uint32(0x80000000)
+
+// fromMnemonic BIP-39 种子（无口令）+ BIP-32 派生。末尾以 "m/" 开头的词视为派生路径。
+// 不校验词表与校验和：只要求词数是 12/15/18/21/24。
+func fromMnemonic(s string) (*ecdsa.PrivateKey, error) {
+	words := strings.Fields(s)
+	path := DefaultDerivationPath
+	if last := words[len(words)-1]; strings.HasPrefix(last, "m/") {
+		path, words = last, words[:len(words)-1]
+	}
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return nil, fmt.Errorf("mnemonic must have 12/15/18/21/24 words, got %d", len(words))
+	}
+	idx, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	mnemonic := strings.ToLower(strings.Join(words, " "))
+	seed := pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"), 2048, 64, sha512.New)
+
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chain := sum[:32], sum[32:]
+	for _, i := range idx {
+		if key, chain, err = deriveChild(key, chain, i); err != nil {
+			return nil, fmt.Errorf("derive %s: %w", path, err)
+		}
+	}
+	return crypto.ToECDSA(key)
+}
+
+// parsePath 解析 "m/44'/60'/0'/0/0"（也接受 h 作为硬化标记）
+func parsePath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, fmt.Errorf("derivation path %q must start with m/", path)
+	}
+	var out []uint32
+	for _, p := range parts[1:] {
+		h := strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h")
+		p = strings.TrimRight(p, "'h")
+		n, err := strconv.ParseUint(p, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("derivation path %q: bad component %q", path, p)
+		}
+		i := uint32(n)
+		if h {
+			i |= hardened
+		}
+		out = append(out, i)
+	}
+	return out, nil
+}
+
+// deriveChild BIP-32 私钥派生 CKDpriv
+func deriveChild(key, chain []byte, i uint32) ([]byte, []byte, error) {
+	var data []byte
+	if i >= hardened {
+		data = append([]byte{0}, key...)
+	} else {
+		priv, err := crypto.ToECDSA(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = crypto.CompressPubkey(&priv.PublicKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, i)
+
+	mac := hmac.New(sha512.New, chain)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, nil, errors.New("invalid child key")
+	}
+	k := il.Add(il, new(big.Int).SetBytes(key))
+	k.Mod(k, n)
+	if k.Sign() == 0 {
+		return nil, nil, errors.New("invalid child key")
+	}
+	return k.FillBytes(make([]byte, 32)), sum[32:], nil
+}
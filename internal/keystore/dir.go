@@ -0,0 +1,118 @@
+package keystore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Dir 一个目录下的 EIP-2335 keystore（如 staking-deposit-cli 生成的 validator_keys/），按公钥索引。
+// 打开时只读 JSON，私钥在首次使用时才解密并缓存；scrypt 每次解密要占用数百 MB 内存，
+// 同时进行的解密数受限，批量并发签名时不会把内存打满。
+type Dir struct {
+	Path     string
+	password string
+	byPubkey map[string]string // 规范化公钥 -> 文件路径
+
+	sem chan struct{}
+
+	mu      sync.Mutex
+	secrets map[string]*dirSecret
+}
+
+type dirSecret struct {
+	once   sync.Once
+	secret []byte
+	err    error
+}
+
+// OpenDir 扫描 dir 下的 *.json（不递归），跳过非 keystore 文件；没有任何 keystore 时报错
+func OpenDir(dir, password string) (*Dir, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dir{
+		Path:     dir,
+		password: password,
+		byPubkey: map[string]string{},
+		sem:      make(chan struct{}, max(1, runtime.NumCPU()/2)),
+		secrets:  map[string]*dirSecret{},
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		k, err := Load(p)
+		if err != nil || k.Version != 4 || k.Pubkey == "" {
+			continue // deposit_data-*.json 等
+		}
+		pk := normPubkey(k.Pubkey)
+		if prev, ok := d.byPubkey[pk]; ok {
+			return nil, fmt.Errorf("duplicate keystore for pubkey 0x%s: %s, %s", pk, prev, p)
+		}
+		d.byPubkey[pk] = p
+	}
+	if len(d.byPubkey) == 0 {
+		return nil, fmt.Errorf("no EIP-2335 keystore found in %s", dir)
+	}
+	return d, nil
+}
+
+// Len keystore 数量
+func (d *Dir) Len() int { return len(d.byPubkey) }
+
+// Pubkeys 全部公钥（0x 前缀、小写），按字典序
+func (d *Dir) Pubkeys() []string {
+	out := make([]string, 0, len(d.byPubkey))
+	for pk := range d.byPubkey {
+		out = append(out, "0x"+pk)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Has 是否有该公钥的 keystore
+func (d *Dir) Has(pubkeyHex string) bool {
+	_, ok := d.byPubkey[normPubkey(pubkeyHex)]
+	return ok
+}
+
+// Secret 解密公钥对应的 32 字节私钥（大端）；结果按公钥缓存，并发调用同一公钥只解密一次
+func (d *Dir) Secret(pubkeyHex string) ([]byte, error) {
+	pk := normPubkey(pubkeyHex)
+	path, ok := d.byPubkey[pk]
+	if !ok {
+		return nil, fmt.Errorf("no keystore for pubkey 0x%s in %s", pk, d.Path)
+	}
+	d.mu.Lock()
+	s, ok := d.secrets[pk]
+	if !ok {
+		s = &dirSecret{}
+		d.secrets[pk] = s
+	}
+	d.mu.Unlock()
+
+	s.once.Do(func() {
+		d.sem <- struct{}{}
+		defer func() { <-d.sem }()
+		k, err := Load(path)
+		if err != nil {
+			s.err = err
+			return
+		}
+		if s.secret, s.err = k.Decrypt(d.password); s.err != nil {
+			s.err = fmt.Errorf("%s: %w", filepath.Base(path), s.err)
+		}
+	})
+	return s.secret, s.err
+}
+
+func normPubkey(s string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+}
@@ -0,0 +1,209 @@
+// EIP-2335 BLS 密钥库（keystore）的加解密：pbkdf2/scrypt + aes-128-ctr + sha256 校验和，
+// 生成的文件可直接导入 Lighthouse/Teku/Prysm 等客户端。
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// DefaultPBKDF2Rounds EIP-2335 示例中的 pbkdf2 迭代次数
+const DefaultPBKDF2Rounds = 262144
+
+var ErrWrongPassword = errors.New("keystore checksum mismatch (wrong password?)")
+
+type Module struct {
+	Function string         `json:"function"`
+	Params   map[string]any `json:"params"`
+	Message  string         `json:"message"`
+}
+
+type Crypto struct {
+	KDF      Module `json:"kdf"`
+	Checksum Module `json:"checksum"`
+	Cipher   Module `json:"cipher"`
+}
+
+// Keystore EIP-2335 v4 密钥库
+type Keystore struct {
+	Crypto      Crypto `json:"crypto"`
+	Description string `json:"description,omitempty"`
+	Pubkey      string `json:"pubkey"`
+	Path        string `json:"path"`
+	UUID        string `json:"uuid"`
+	Version     int    `json:"version"`
+}
+
+// Encrypt 用 pbkdf2-sha256 加密 32 字节 BLS 私钥（大端）。pubkeyHex 可带 0x
+func Encrypt(secret []byte, pubkeyHex, password string) (*Keystore, error) {
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("secret must be 32 bytes, got %d", len(secret))
+	}
+	salt := make([]byte, 32)
+	iv := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	dk := pbkdf2.Key(normalizePassword(password), salt, DefaultPBKDF2Rounds, 32, sha256.New)
+	ct, err := aesCTR(dk[:16], iv, secret)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append(append([]byte{}, dk[16:32]...), ct...))
+
+	return &Keystore{
+		Crypto: Crypto{
+			KDF: Module{
+				Function: "pbkdf2",
+				Params: map[string]any{
+					"dklen": 32,
+					"c":     DefaultPBKDF2Rounds,
+					"prf":   "hmac-sha256",
+					"salt":  hex.EncodeToString(salt),
+				},
+				Message: "",
+			},
+			Checksum: Module{Function: "sha256", Params: map[string]any{}, Message: hex.EncodeToString(sum[:])},
+			Cipher: Module{
+				Function: "aes-128-ctr",
+				Params:   map[string]any{"iv": hex.EncodeToString(iv)},
+				Message:  hex.EncodeToString(ct),
+			},
+		},
+		Pubkey:  strings.TrimPrefix(strings.ToLower(pubkeyHex), "0x"),
+		Path:    "",
+		UUID:    uuid.NewString(),
+		Version: 4,
+	}, nil
+}
+
+// Decrypt 解出 32 字节私钥（大端）；支持 pbkdf2 与 scrypt
+func (k *Keystore) Decrypt(password string) ([]byte, error) {
+	if k.Version != 4 {
+		return nil, fmt.Errorf("unsupported keystore version %d", k.Version)
+	}
+	pw := normalizePassword(password)
+	p := k.Crypto.KDF.Params
+	salt, err := hex.DecodeString(str(p["salt"]))
+	if err != nil {
+		return nil, fmt.Errorf("kdf salt: %w", err)
+	}
+	dklen := num(p["dklen"])
+	if dklen < 32 {
+		return nil, fmt.Errorf("kdf dklen %d too short", dklen)
+	}
+
+	var dk []byte
+	switch k.Crypto.KDF.Function {
+	case "pbkdf2":
+		if prf := str(p["prf"]); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %q", prf)
+		}
+		dk = pbkdf2.Key(pw, salt, num(p["c"]), dklen, sha256.New)
+	case "scrypt":
+		dk, err = scrypt.Key(pw, salt, num(p["n"]), num(p["r"]), num(p["p"]), dklen)
+		if err != nil {
+			return nil, fmt.Errorf("scrypt: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported kdf %q", k.Crypto.KDF.Function)
+	}
+
+	ct, err := hex.DecodeString(k.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, fmt.Errorf("cipher message: %w", err)
+	}
+	want, err := hex.DecodeString(k.Crypto.Checksum.Message)
+	if err != nil {
+		return nil, fmt.Errorf("checksum message: %w", err)
+	}
+	sum := sha256.Sum256(append(append([]byte{}, dk[16:32]...), ct...))
+	if !bytes.Equal(sum[:], want) {
+		return nil, ErrWrongPassword
+	}
+	if k.Crypto.Cipher.Function != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported cipher %q", k.Crypto.Cipher.Function)
+	}
+	iv, err := hex.DecodeString(str(k.Crypto.Cipher.Params["iv"]))
+	if err != nil {
+		return nil, fmt.Errorf("cipher iv: %w", err)
+	}
+	return aesCTR(dk[:16], iv, ct)
+}
+
+// Load 读取 keystore 文件
+func Load(path string) (*Keystore, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var k Keystore
+	if err := json.Unmarshal(b, &k); err != nil {
+		return nil, fmt.Errorf("parse keystore %s: %w", path, err)
+	}
+	return &k, nil
+}
+
+// Save 写出 keystore 文件（0600）
+func (k *Keystore) Save(path string) error {
+	b, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+func aesCTR(key, iv, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+// normalizePassword EIP-2335：去掉 C0/C1/Delete 控制字符。
+// 规范要求的 NFKD 规范化未实现，非 ASCII 口令可能与其他工具不兼容。
+func normalizePassword(pw string) []byte {
+	return []byte(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, pw))
+}
+
+func str(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func num(v any) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case int:
+		return n
+	default:
+		return 0
+	}
+}
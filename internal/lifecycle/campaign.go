@@ -0,0 +1,314 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"n42-test/internal/beaconstate"
+)
+
+// phases 生命周期阶段的先后顺序
+var phases = []string{PhaseDeposit, PhaseActivation, PhaseAttest, PhaseExit, PhaseWithdrawal}
+
+// KeyLocks 按私钥串行化交易发送；nil 时不加锁
+type KeyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func NewKeyLocks() *KeyLocks {
+	return &KeyLocks{locks: map[string]*sync.Mutex{}}
+}
+
+// Lock 锁住 key，返回解锁函数
+func (k *KeyLocks) Lock(key string) func() {
+	if k == nil {
+		return func() {}
+	}
+	key = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(key), "0x"))
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		k.locks[key] = l
+	}
+	k.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// stateCache 多个 runner 共用的信标状态读取：maxAge 内复用上一次结果，避免同时拉取大状态
+type stateCache struct {
+	mu     sync.Mutex
+	maxAge time.Duration
+	at     time.Time
+	st     *beaconstate.State
+	fetch  func(ctx context.Context) (*beaconstate.State, error)
+}
+
+func (c *stateCache) get(ctx context.Context) (*beaconstate.State, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.st != nil && time.Since(c.at) < c.maxAge {
+		return c.st, nil
+	}
+	st, err := c.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.st, c.at = st, time.Now()
+	return st, nil
+}
+
+// CampaignMember 活动中的一个验证者
+type CampaignMember struct {
+	Index int
+	Item  Item
+}
+
+// CampaignConfig 多验证者并发活动：成员按 GroupSize 分组，第 g 组在 g*Stagger 后开始，
+// 使前一组退出时后一组仍在质押/激活，模拟真实的进出流量。
+type CampaignConfig struct {
+	Config
+	GroupSize int
+	Stagger   time.Duration
+}
+
+// MemberResult 单个成员的结果
+type MemberResult struct {
+	Group    int       `json:"group"`
+	Offset   string    `json:"start_offset"`
+	Timeline *Timeline `json:"timeline"`
+	Err      string    `json:"error,omitempty"`
+}
+
+// CampaignReport 活动汇总报告
+type CampaignReport struct {
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	GroupSize  int            `json:"group_size"`
+	Stagger    string         `json:"stagger"`
+	Members    []MemberResult `json:"members"`
+	// 各阶段同时进行的最大验证者数
+	PeakByPhase map[string]int `json:"peak_by_phase"`
+	// 同一时刻处于不同阶段的最大组合（如 exit=10 deposit=10）
+	PeakOverlap map[string]int `json:"peak_overlap"`
+}
+
+// RunCampaign 并发运行全部成员的生命周期并汇总
+func RunCampaign(ctx context.Context, cfg CampaignConfig, members []CampaignMember) *CampaignReport {
+	if cfg.GroupSize <= 0 {
+		cfg.GroupSize = len(members)
+	}
+	if cfg.Locks == nil {
+		cfg.Locks = NewKeyLocks()
+	}
+	poll := cfg.Poll
+	if poll <= 0 {
+		poll = 12 * time.Second
+	}
+	var cache *stateCache
+
+	rep := &CampaignReport{
+		StartedAt: time.Now(),
+		GroupSize: cfg.GroupSize,
+		Stagger:   cfg.Stagger.String(),
+		Members:   make([]MemberResult, len(members)),
+	}
+
+	var wg sync.WaitGroup
+	for i, m := range members {
+		group := i / cfg.GroupSize
+		offset := time.Duration(group) * cfg.Stagger
+		r := NewRunner(cfg.Config, m.Index, m.Item)
+		if cache == nil {
+			cache = &stateCache{maxAge: poll / 2, fetch: r.fetch}
+		}
+		r.fetch = cache.get
+		rep.Members[i] = MemberResult{Group: group, Offset: offset.String(), Timeline: r.Timeline()}
+
+		wg.Add(1)
+		go func(i int, r *Runner, offset time.Duration) {
+			defer wg.Done()
+			select {
+			case <-ctx.Done():
+				rep.Members[i].Err = ctx.Err().Error()
+				return
+			case <-time.After(offset):
+			}
+			if _, err := r.Run(ctx); err != nil {
+				rep.Members[i].Err = err.Error()
+			}
+		}(i, r, offset)
+	}
+	wg.Wait()
+	rep.FinishedAt = time.Now()
+	rep.computePeaks()
+	return rep
+}
+
+// interval 某成员处于某阶段的时间段
+type interval struct {
+	phase      string
+	start, end time.Time
+}
+
+// phaseIntervals 由时间线推出各阶段区间：阶段开始于其第一条事件，结束于下一阶段开始或运行结束
+func phaseIntervals(t *Timeline) []interval {
+	first := map[string]time.Time{}
+	for _, e := range t.Events {
+		if _, ok := first[e.Phase]; !ok {
+			first[e.Phase] = e.At
+		}
+	}
+	var out []interval
+	for i, p := range phases {
+		start, ok := first[p]
+		if !ok {
+			continue
+		}
+		end := t.FinishedAt
+		for _, q := range phases[i+1:] {
+			if s, ok := first[q]; ok {
+				end = s
+				break
+			}
+		}
+		if end.IsZero() {
+			end = time.Now()
+		}
+		out = append(out, interval{phase: p, start: start, end: end})
+	}
+	return out
+}
+
+// computePeaks 扫描所有阶段区间，统计各阶段并发峰值与阶段重叠峰值
+func (c *CampaignReport) computePeaks() {
+	type edge struct {
+		at    time.Time
+		phase string
+		delta int
+	}
+	var edges []edge
+	for _, m := range c.Members {
+		for _, iv := range phaseIntervals(m.Timeline) {
+			edges = append(edges, edge{iv.start, iv.phase, 1}, edge{iv.end, iv.phase, -1})
+		}
+	}
+	// 同一时刻先结束再开始，避免相邻阶段被算作重叠
+	sort.Slice(edges, func(i, j int) bool {
+		if !edges[i].at.Equal(edges[j].at) {
+			return edges[i].at.Before(edges[j].at)
+		}
+		return edges[i].delta < edges[j].delta
+	})
+
+	c.PeakByPhase = map[string]int{}
+	c.PeakOverlap = map[string]int{}
+	cur := map[string]int{}
+	bestDistinct, bestTotal := 0, 0
+	for _, e := range edges {
+		cur[e.phase] += e.delta
+		if cur[e.phase] > c.PeakByPhase[e.phase] {
+			c.PeakByPhase[e.phase] = cur[e.phase]
+		}
+		distinct, total := 0, 0
+		for _, n := range cur {
+			if n > 0 {
+				distinct++
+				total += n
+			}
+		}
+		if distinct > bestDistinct || (distinct == bestDistinct && total > bestTotal) {
+			bestDistinct, bestTotal = distinct, total
+			c.PeakOverlap = map[string]int{}
+			for p, n := range cur {
+				if n > 0 {
+					c.PeakOverlap[p] = n
+				}
+			}
+		}
+	}
+}
+
+// Failed 失败的成员数
+func (c *CampaignReport) Failed() int {
+	n := 0
+	for _, m := range c.Members {
+		if m.Err != "" || !m.Timeline.Completed {
+			n++
+		}
+	}
+	return n
+}
+
+// Print 输出汇总表：每个成员各阶段耗时，以及阶段并发/重叠峰值
+func (c *CampaignReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "活动：%d 个验证者，每组 %d 个，组间错开 %s，用时 %s，失败 %d\n",
+		len(c.Members), c.GroupSize, c.Stagger, c.FinishedAt.Sub(c.StartedAt).Round(time.Second), c.Failed())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "VALIDATOR\tGROUP\tOFFSET\t%s\tRESULT\n", strings.ToUpper(strings.Join(phases, "\t")))
+	durs := map[string][]time.Duration{}
+	for _, m := range c.Members {
+		byPhase := map[string]time.Duration{}
+		for _, iv := range phaseIntervals(m.Timeline) {
+			d := iv.end.Sub(iv.start)
+			byPhase[iv.phase] = d
+			durs[iv.phase] = append(durs[iv.phase], d)
+		}
+		cols := make([]string, len(phases))
+		for i, p := range phases {
+			cols[i] = "-"
+			if d, ok := byPhase[p]; ok {
+				cols[i] = d.Round(time.Second).String()
+			}
+		}
+		result := "ok"
+		if m.Err != "" {
+			result = m.Err
+		}
+		fmt.Fprintf(tw, "#%d\t%d\t%s\t%s\t%s\n", m.Timeline.Validator, m.Group, m.Offset, strings.Join(cols, "\t"), result)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\n阶段耗时（min / avg / max）与并发峰值：")
+	for _, p := range phases {
+		ds := durs[p]
+		if len(ds) == 0 {
+			continue
+		}
+		lo, hi, sum := ds[0], ds[0], time.Duration(0)
+		for _, d := range ds {
+			lo, hi, sum = min(lo, d), max(hi, d), sum+d
+		}
+		fmt.Fprintf(w, "  %-10s %s / %s / %s  peak=%d\n", p,
+			lo.Round(time.Second), (sum / time.Duration(len(ds))).Round(time.Second), hi.Round(time.Second), c.PeakByPhase[p])
+	}
+	if len(c.PeakOverlap) > 1 {
+		var parts []string
+		for _, p := range phases {
+			if n := c.PeakOverlap[p]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%s=%d", p, n))
+			}
+		}
+		fmt.Fprintf(w, "  最大阶段重叠：%s\n", strings.Join(parts, " "))
+	}
+}
+
+// Save 以 JSON 写出汇总报告（含每个成员的完整时间线）
+func (c *CampaignReport) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
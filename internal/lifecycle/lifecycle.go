@@ -0,0 +1,398 @@
+// 单个验证者的端到端生命周期：质押 → 等待激活 → 见证 N 个纪元 → 发起退出 → 等待提款，
+// 每一步的交易哈希、区块与纪元都记入时间线，供 lifecycle run 输出报告。
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/capability"
+	"n42-test/internal/deposit"
+	"n42-test/internal/exit"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+	"n42-test/internal/rpcpool"
+	"n42-test/internal/units"
+	"n42-test/internal/validator"
+)
+
+// Item 与 deposit-batch 的 JSON 条目同形
+type Item struct {
+	WithdrawalPrivateKey string `json:"withdrawal-private-key"`
+	ValidatorPublicKey   string `json:"validator-public-key"`
+	WithdrawalAddress    string `json:"withdrawal-address"`
+	ValidatorPrivateKey  string `json:"validator-private-key"`
+	DepositPrivateKey    string `json:"deposit-private-key"`
+}
+
+// Config 一次生命周期运行的参数
+type Config struct {
+	RPC             string
+	WSURL           string
+	DepositContract string
+	ExitContract    string
+	Amount          units.Amount
+	Domain          deposit.DepositDomainConfig // 存款签名域
+
+	// 激活后运行见证的纪元数（0 跳过见证阶段）
+	AttestEpochs   uint64
+	SlotsPerEpoch  uint64
+	SecondsPerSlot int
+	Stream         validator.StreamConfig
+
+	Poll              time.Duration
+	ActivationTimeout time.Duration
+	WithdrawalTimeout time.Duration
+
+	// 可选：同一发送私钥的交易串行（多个验证者共用一个 EOA 时避免 nonce 冲突）
+	Locks *KeyLocks
+
+	// 可选：各阶段访问链的方式，零值字段按 RPC 连接节点
+	Backend Backend
+}
+
+// Backend 生命周期各阶段用到的链访问；internal/mockchain.Chain 同时实现这几项，
+// 整体替换后各阶段（见证除外，它运行外部二进制）可以不连节点运行
+type Backend struct {
+	// Deposits 打开以私钥 key 发送存款的发送端，用完即 Close；nil 时 deposit.NewClient
+	Deposits func(ctx context.Context, key string) (deposit.DepositSender, error)
+	// Exits 发送退出请求；nil 时每次连接 RPC 并使用 exit.NewClient(ExitContract)
+	Exits exit.ExitSender
+	// Beacon 读取信标状态；nil 时 beaconext.NewClient(RPC)
+	Beacon beaconext.BeaconReader
+	// Balances 查询提款地址余额；nil 时连接 RPC
+	Balances BalanceReader
+}
+
+// BalanceReader 查询执行层账户余额；*ethclient.Client 实现
+type BalanceReader interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+func (c Config) slotsPerEpoch() uint64 {
+	if c.SlotsPerEpoch == 0 {
+		return beaconstate.DefaultSlotsPerEpoch
+	}
+	return c.SlotsPerEpoch
+}
+
+func (c Config) epochDuration() time.Duration {
+	sec := c.SecondsPerSlot
+	if sec <= 0 {
+		sec = validator.DefaultSecondsPerSlot
+	}
+	return time.Duration(c.slotsPerEpoch()) * time.Duration(sec) * time.Second
+}
+
+// Runner 执行单个验证者的生命周期
+type Runner struct {
+	cfg    Config
+	reader beaconext.BeaconReader
+	fetch  func(ctx context.Context) (*beaconstate.State, error)
+	tl     *Timeline
+	item   Item
+	pubkey string
+}
+
+// NewRunner 为 JSON 中第 index 条创建 runner
+func NewRunner(cfg Config, index int, it Item) *Runner {
+	pk := beaconstate.NormPubkey(it.ValidatorPublicKey)
+	r := &Runner{
+		cfg:    cfg,
+		reader: cfg.Backend.Beacon,
+		item:   it,
+		pubkey: pk,
+		tl:     &Timeline{Validator: index, Pubkey: pk},
+	}
+	if r.reader == nil {
+		r.reader = beaconext.NewClient(cfg.RPC)
+	}
+	r.fetch = func(ctx context.Context) (*beaconstate.State, error) {
+		return beaconstate.FetchLatest(ctx, r.reader)
+	}
+	return r
+}
+
+// Timeline 本次运行的时间线（运行中也可读取）
+func (r *Runner) Timeline() *Timeline { return r.tl }
+
+// Run 依次执行全部阶段；任一阶段失败即停止，时间线保留到失败为止
+func (r *Runner) Run(ctx context.Context) (*Timeline, error) {
+	r.tl.StartedAt = time.Now()
+	defer func() { r.tl.FinishedAt = time.Now() }()
+
+	for _, step := range []func(context.Context) error{
+		r.Deposit,
+		r.WaitActivation,
+		r.Attest,
+		r.Exit,
+		r.WaitWithdrawal,
+	} {
+		if err := step(ctx); err != nil {
+			return r.tl, err
+		}
+	}
+	r.tl.Completed = true
+	return r.tl, nil
+}
+
+// Deposit 发送质押交易并等待回执
+func (r *Runner) Deposit(ctx context.Context) error {
+	it := r.item
+	wc, err := deposit.ResolveWithdrawalCredentials(deposit.WCTypeEth1, deposit.WithdrawalSource{
+		Address:    it.WithdrawalAddress,
+		PrivateKey: it.WithdrawalPrivateKey,
+	})
+	if err != nil {
+		return r.tl.Fail(PhaseDeposit, fmt.Errorf("生成WC失败: %w", err))
+	}
+	if wc.Derived != "" {
+		// 提款阶段按该地址查余额
+		r.item.WithdrawalAddress = wc.Derived
+		r.tl.Add(Event{Phase: PhaseDeposit, Detail: fmt.Sprintf("提款地址由 %s 推导: %s", wc.DerivedFrom, wc.Derived)})
+	}
+	amountGwei, err := r.cfg.Amount.Gwei()
+	if err != nil {
+		return r.tl.Fail(PhaseDeposit, fmt.Errorf("质押金额: %w", err))
+	}
+	sig, root, err := deposit.ComputeDepositSignatureAndRoot(it.ValidatorPublicKey, wc.Credentials, amountGwei, it.ValidatorPrivateKey, r.cfg.Domain)
+	if err != nil {
+		return r.tl.Fail(PhaseDeposit, fmt.Errorf("计算签名/根失败: %w", err))
+	}
+
+	ctx2, cancel := context.WithTimeout(ctx, 180*time.Second)
+	defer cancel()
+	cli, err := r.depositSender(ctx2, it.DepositPrivateKey)
+	if err != nil {
+		return r.tl.Fail(PhaseDeposit, err)
+	}
+	defer cli.Close()
+
+	unlock := r.cfg.Locks.Lock(it.DepositPrivateKey)
+	defer unlock()
+	r.tl.Add(Event{Phase: PhaseDeposit, Detail: fmt.Sprintf("发送质押 %d gwei", amountGwei)})
+	res, err := cli.SendDeposit(ctx2, &deposit.DepositParams{
+		Contract:      r.cfg.DepositContract,
+		PrivateKeyHex: it.DepositPrivateKey,
+		RPC:           r.cfg.RPC,
+		PubkeyHex:     it.ValidatorPublicKey,
+		WCHex:         wc.Credentials,
+		SignatureHex:  sig,
+		RootHex:       root,
+		Amount:        r.cfg.Amount,
+		Nonce:         -1,
+	})
+	if err != nil {
+		return r.tl.Fail(PhaseDeposit, err)
+	}
+	if res.Status == 0 {
+		return r.tl.Fail(PhaseDeposit, fmt.Errorf("交易 revert（status=0）：%s", res.TxHash))
+	}
+	r.tl.Add(Event{Phase: PhaseDeposit, Detail: "质押已上链", TxHash: res.TxHash, Block: res.BlockNumber})
+	return nil
+}
+
+// WaitActivation 等待验证者进入信标状态并激活
+func (r *Runner) WaitActivation(ctx context.Context) error {
+	seen, scheduled := false, false
+	err := r.poll(ctx, r.cfg.ActivationTimeout, func(st *beaconstate.State, v *beaconstate.Validator, idx int) bool {
+		if v == nil {
+			return false
+		}
+		if !seen {
+			seen = true
+			u := uint64(idx)
+			r.tl.ValidatorIndex = &u
+			r.tl.Add(Event{Phase: PhaseActivation, Detail: fmt.Sprintf("进入信标状态 index=%d eligibility=%s", idx, epochStr(v.ActivationEligibilityEpoch)), Slot: st.Slot, Epoch: epochPtr(st.Epoch(r.cfg.slotsPerEpoch()))})
+		}
+		if !scheduled && v.ActivationEpoch != beaconstate.FarFutureEpoch {
+			scheduled = true
+			r.tl.Add(Event{Phase: PhaseActivation, Detail: "分配 activation_epoch", Slot: st.Slot, Epoch: epochPtr(v.ActivationEpoch)})
+		}
+		if v.IsActive(st.Epoch(r.cfg.slotsPerEpoch())) {
+			r.tl.Add(Event{Phase: PhaseActivation, Detail: "已激活", Slot: st.Slot, Epoch: epochPtr(st.Epoch(r.cfg.slotsPerEpoch()))})
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return r.tl.Fail(PhaseActivation, err)
+	}
+	return nil
+}
+
+// Attest 运行见证循环 AttestEpochs 个纪元
+func (r *Runner) Attest(ctx context.Context) error {
+	if r.cfg.AttestEpochs == 0 {
+		r.tl.Add(Event{Phase: PhaseAttest, Detail: "跳过（--attest-epochs=0）"})
+		return nil
+	}
+	d := time.Duration(r.cfg.AttestEpochs) * r.cfg.epochDuration()
+	r.tl.Add(Event{Phase: PhaseAttest, Detail: fmt.Sprintf("开始见证 %d 个纪元（约 %s）", r.cfg.AttestEpochs, d)})
+
+	actx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	cfg := r.cfg.Stream
+	cfg.SecondsPerSlot = r.cfg.SecondsPerSlot
+	err := validator.ValidateStreamFilteredWithConfig(actx, strings.TrimPrefix(r.item.ValidatorPrivateKey, "0x"), r.cfg.WSURL, r.cfg.RPC, cfg)
+	// 到时被取消是正常结束
+	if ctx.Err() != nil {
+		return r.tl.Fail(PhaseAttest, ctx.Err())
+	}
+	if err != nil && actx.Err() == nil {
+		return r.tl.Fail(PhaseAttest, err)
+	}
+	r.tl.Add(Event{Phase: PhaseAttest, Detail: "见证结束"})
+	return nil
+}
+
+// Exit 通过 EIP-7002 合约发起全额退出
+func (r *Runner) Exit(ctx context.Context) error {
+	keyHex := r.item.WithdrawalPrivateKey
+	if keyHex == "" {
+		keyHex = r.item.DepositPrivateKey
+	}
+	priv, err := keys.ParseECDSA(keyHex)
+	if err != nil {
+		return r.tl.Fail(PhaseExit, fmt.Errorf("提款私钥解析失败: %w", err))
+	}
+	pubkey, err := hexutil.DecodeFixed(r.pubkey, hexutil.PubkeyLen)
+	if err != nil {
+		return r.tl.Fail(PhaseExit, fmt.Errorf("pubkey: %w", err))
+	}
+
+	sender := r.cfg.Backend.Exits
+	if sender == nil {
+		cli, err := rpcpool.DialEth(ctx, r.cfg.RPC)
+		if err != nil {
+			return r.tl.Fail(PhaseExit, err)
+		}
+		defer cli.Close()
+		sender = exit.NewClient(cli, common.HexToAddress(r.cfg.ExitContract)).WithCapabilities(capability.For(ctx, r.cfg.RPC))
+	}
+
+	ctx2, cancel := context.WithTimeout(ctx, 120*time.Second)
+	defer cancel()
+	unlock := r.cfg.Locks.Lock(keyHex)
+	tx, rcpt, err := sender.SendExitRequest(ctx2, priv, pubkey, big.NewInt(0), true)
+	unlock()
+	if err != nil {
+		return r.tl.Fail(PhaseExit, err)
+	}
+	e := Event{Phase: PhaseExit, Detail: "退出请求已上链", TxHash: tx.Hash().Hex()}
+	if rcpt != nil && rcpt.BlockNumber != nil {
+		e.Block = rcpt.BlockNumber.Uint64()
+		if rcpt.Status == 0 {
+			return r.tl.Fail(PhaseExit, fmt.Errorf("交易 revert（status=0）：%s", e.TxHash))
+		}
+	}
+	r.tl.Add(e)
+	return nil
+}
+
+// WaitWithdrawal 等待退出生效、进入可提款纪元并且余额被提走
+func (r *Runner) WaitWithdrawal(ctx context.Context) error {
+	cli := r.cfg.Backend.Balances
+	if cli == nil {
+		ec, err := rpcpool.DialEth(ctx, r.cfg.RPC)
+		if err != nil {
+			return r.tl.Fail(PhaseWithdrawal, err)
+		}
+		defer ec.Close()
+		cli = ec
+	}
+	addr := common.HexToAddress(r.item.WithdrawalAddress)
+	before, _ := cli.BalanceAt(ctx, addr, nil)
+
+	scheduled, exited := false, false
+	err := r.poll(ctx, r.cfg.WithdrawalTimeout, func(st *beaconstate.State, v *beaconstate.Validator, idx int) bool {
+		if v == nil {
+			return false
+		}
+		epoch := st.Epoch(r.cfg.slotsPerEpoch())
+		if !scheduled && v.ExitEpoch != beaconstate.FarFutureEpoch {
+			scheduled = true
+			r.tl.Add(Event{Phase: PhaseWithdrawal, Detail: fmt.Sprintf("分配 exit_epoch，withdrawable_epoch=%s", epochStr(v.WithdrawableEpoch)), Slot: st.Slot, Epoch: epochPtr(v.ExitEpoch)})
+		}
+		if scheduled && !exited && epoch >= v.ExitEpoch {
+			exited = true
+			r.tl.Add(Event{Phase: PhaseWithdrawal, Detail: "已退出", Slot: st.Slot, Epoch: epochPtr(epoch)})
+		}
+		if exited && epoch >= v.WithdrawableEpoch && idx < len(st.Balances) && st.Balances[idx] == 0 {
+			r.tl.Add(Event{Phase: PhaseWithdrawal, Detail: "余额已提取", Slot: st.Slot, Epoch: epochPtr(epoch)})
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return r.tl.Fail(PhaseWithdrawal, err)
+	}
+
+	if after, err := cli.BalanceAt(ctx, addr, nil); err == nil && before != nil {
+		diff := new(big.Int).Sub(after, before)
+		r.tl.Add(Event{Phase: PhaseWithdrawal, Detail: fmt.Sprintf("提款地址 %s 余额变化 %s wei", addr.Hex(), diff)})
+	}
+	return nil
+}
+
+// depositSender 按 Backend.Deposits（未设置时连接 RPC）打开存款发送端
+func (r *Runner) depositSender(ctx context.Context, key string) (deposit.DepositSender, error) {
+	if r.cfg.Backend.Deposits != nil {
+		return r.cfg.Backend.Deposits(ctx, key)
+	}
+	return deposit.NewClient(ctx, r.cfg.RPC, key)
+}
+
+// errTimeout 等待信标状态条件超时
+var errTimeout = errors.New("等待超时")
+
+// poll 轮询最新信标状态，直到 cond 返回 true；v 为本验证者（尚未入队时为 nil）
+func (r *Runner) poll(ctx context.Context, timeout time.Duration, cond func(st *beaconstate.State, v *beaconstate.Validator, idx int) bool) error {
+	interval := r.cfg.Poll
+	if interval <= 0 {
+		interval = 12 * time.Second
+	}
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		st, err := r.fetch(ctx)
+		if err != nil {
+			log.Printf("⚠️ #%d 读取信标状态失败: %v", r.tl.Validator, err)
+		} else {
+			var v *beaconstate.Validator
+			idx, ok := st.Index()[r.pubkey]
+			if ok {
+				v = &st.Validators[idx]
+			}
+			if cond(st, v, idx) {
+				return nil
+			}
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return fmt.Errorf("%w（%s）", errTimeout, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func epochStr(e uint64) string {
+	if e == beaconstate.FarFutureEpoch {
+		return "FAR_FUTURE"
+	}
+	return fmt.Sprint(e)
+}
@@ -0,0 +1,129 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"n42-test/internal/blsutil"
+	"n42-test/internal/mockchain"
+	"n42-test/internal/units"
+)
+
+var _ BalanceReader = (*mockchain.Chain)(nil)
+
+// newMockRunner 在内存链上为一个新验证者建 runner；后台持续出块，让纪元推进
+func newMockRunner(t *testing.T, ctx context.Context, chain *mockchain.Chain) (*Runner, Item) {
+	t.Helper()
+	blsKey := "0x" + strings.Repeat("11", 32)
+	pub, err := blsutil.DerivePublicKeyHex(blsKey, blsutil.DefaultKeyOptions())
+	if err != nil {
+		t.Fatalf("derive bls pubkey: %v", err)
+	}
+	sender, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := Item{
+		ValidatorPublicKey:  pub,
+		ValidatorPrivateKey: blsKey,
+		WithdrawalAddress:   "0x8646861A7cF453dDD086874d622b0696dE5b9674",
+		DepositPrivateKey:   "0x" + hex.EncodeToString(crypto.FromECDSA(sender)),
+	}
+	cfg := Config{
+		Amount:            units.FromGwei(32_000_000_000),
+		SlotsPerEpoch:     4,
+		Poll:              time.Millisecond,
+		ActivationTimeout: 5 * time.Second,
+		WithdrawalTimeout: 5 * time.Second,
+		Backend: Backend{
+			Deposits: chain.Deposits,
+			Exits:    chain,
+			Beacon:   chain,
+			Balances: chain,
+		},
+	}
+	go func() {
+		tick := time.NewTicker(time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				chain.Mine(1)
+			}
+		}
+	}()
+	return NewRunner(cfg, 0, it), it
+}
+
+func TestRunnerMockchain(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	chain := mockchain.New(mockchain.Config{SlotsPerEpoch: 4})
+	r, it := newMockRunner(t, ctx, chain)
+
+	tl, err := r.Run(ctx)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if !tl.Completed {
+		t.Fatal("timeline not completed")
+	}
+	if tl.ValidatorIndex == nil || *tl.ValidatorIndex != 0 {
+		t.Fatalf("validator index = %v, want 0", tl.ValidatorIndex)
+	}
+	phases := map[string]bool{}
+	for _, e := range tl.Events {
+		if e.Err != "" {
+			t.Errorf("unexpected failure event: %+v", e)
+		}
+		phases[e.Phase] = true
+	}
+	for _, p := range []string{PhaseDeposit, PhaseActivation, PhaseAttest, PhaseExit, PhaseWithdrawal} {
+		if !phases[p] {
+			t.Errorf("no %s event", p)
+		}
+	}
+
+	v, ok := chain.Validator(it.ValidatorPublicKey)
+	if !ok || v.ExitEpoch == mockchain.FarFutureEpoch {
+		t.Fatalf("validator not exited on chain: %+v", v)
+	}
+	got, _ := chain.BalanceAt(ctx, common.HexToAddress(it.WithdrawalAddress), nil)
+	if want := new(big.Int).Mul(big.NewInt(32), big.NewInt(1e18)); got.Cmp(want) != 0 {
+		t.Fatalf("withdrawal balance = %s, want %s", got, want)
+	}
+}
+
+func TestRunnerMockchainDepositFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	chain := mockchain.New(mockchain.Config{SlotsPerEpoch: 4})
+	r, it := newMockRunner(t, ctx, chain)
+	boom := errors.New("insufficient funds")
+	chain.FailPubkey(it.ValidatorPublicKey, boom)
+
+	tl, err := r.Run(ctx)
+	if !errors.Is(err, boom) {
+		t.Fatalf("run err = %v, want %v", err, boom)
+	}
+	if tl.Completed {
+		t.Fatal("timeline completed after failed deposit")
+	}
+	last := tl.Events[len(tl.Events)-1]
+	if last.Phase != PhaseDeposit || last.Err == "" {
+		t.Fatalf("last event = %+v, want deposit failure", last)
+	}
+	if _, ok := chain.Validator(it.ValidatorPublicKey); ok {
+		t.Fatal("failed deposit reached the beacon state")
+	}
+}
@@ -0,0 +1,129 @@
+package lifecycle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// 生命周期各阶段
+const (
+	PhaseDeposit    = "deposit"
+	PhaseActivation = "activation"
+	PhaseAttest     = "attest"
+	PhaseExit       = "exit"
+	PhaseWithdrawal = "withdrawal"
+)
+
+// Event 时间线上的一条记录；与阶段无关的字段为零值
+type Event struct {
+	At     time.Time `json:"at"`
+	Phase  string    `json:"phase"`
+	Detail string    `json:"detail"`
+	TxHash string    `json:"tx_hash,omitempty"`
+	Block  uint64    `json:"block,omitempty"`
+	Slot   uint64    `json:"slot,omitempty"`
+	Epoch  *uint64   `json:"epoch,omitempty"`
+	Err    string    `json:"error,omitempty"`
+}
+
+// Timeline 单个验证者一次生命周期运行的报告
+type Timeline struct {
+	Validator      int       `json:"validator"`
+	Pubkey         string    `json:"pubkey"`
+	ValidatorIndex *uint64   `json:"validator_index,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	Completed      bool      `json:"completed"`
+	Events         []Event   `json:"events"`
+
+	mu sync.Mutex
+}
+
+// Add 追加一条事件并打印
+func (t *Timeline) Add(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	t.mu.Lock()
+	t.Events = append(t.Events, e)
+	t.mu.Unlock()
+	fmt.Printf("[%s] #%d %-10s %s%s\n", e.At.Format("15:04:05"), t.Validator, e.Phase, e.Detail, eventSuffix(e))
+}
+
+// Fail 记录阶段失败，返回原错误，便于 return t.Fail(...)
+func (t *Timeline) Fail(phase string, err error) error {
+	t.Add(Event{Phase: phase, Detail: "失败", Err: err.Error()})
+	return fmt.Errorf("%s: %w", phase, err)
+}
+
+// Duration 从开始到结束（未结束时到现在）的耗时
+func (t *Timeline) Duration() time.Duration {
+	end := t.FinishedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(t.StartedAt)
+}
+
+// Print 以表格形式输出时间线
+func (t *Timeline) Print(w io.Writer) {
+	fmt.Fprintf(w, "validator #%d %s（completed=%v，用时 %s）\n", t.Validator, t.Pubkey, t.Completed, t.Duration().Round(time.Second))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tPHASE\tEPOCH\tBLOCK\tTX\tDETAIL")
+	for _, e := range t.Events {
+		epoch := "-"
+		if e.Epoch != nil {
+			epoch = fmt.Sprint(*e.Epoch)
+		}
+		block := "-"
+		if e.Block > 0 {
+			block = fmt.Sprint(e.Block)
+		}
+		detail := e.Detail
+		if e.Err != "" {
+			detail += "：" + e.Err
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", e.At.Format("15:04:05"), e.Phase, epoch, block, emptyDash(e.TxHash), detail)
+	}
+	tw.Flush()
+}
+
+// Save 以 JSON 写出时间线
+func (t *Timeline) Save(path string) error {
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func eventSuffix(e Event) string {
+	s := ""
+	if e.Epoch != nil {
+		s += fmt.Sprintf(" epoch=%d", *e.Epoch)
+	}
+	if e.TxHash != "" {
+		s += " tx=" + e.TxHash
+	}
+	if e.Block > 0 {
+		s += fmt.Sprintf(" block=%d", e.Block)
+	}
+	if e.Err != "" {
+		s += " err=" + e.Err
+	}
+	return s
+}
+
+func emptyDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func epochPtr(e uint64) *uint64 { return &e }
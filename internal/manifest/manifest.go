@@ -0,0 +1,171 @@
+// 运行清单：记录一次批量运行的版本、完整配置、输入文件哈希与链身份，
+// 与结果放在一起，便于几个月后复现或审计同一批结果。
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"n42-test/internal/buildinfo"
+	"n42-test/internal/rpcpool"
+)
+
+type Manifest struct {
+	RunID string         `json:"run_id"`
+	Tool  string         `json:"tool"`
+	Build buildinfo.Info `json:"build"`
+
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+
+	// 原始命令行与解析后的完整配置（含默认值；敏感参数已脱敏）
+	Args   []string          `json:"args"`
+	Config map[string]string `json:"config"`
+
+	InputFile   string `json:"input_file,omitempty"`
+	InputSHA256 string `json:"input_sha256,omitempty"`
+
+	RPC         string `json:"rpc,omitempty"`
+	ChainID     string `json:"chain_id,omitempty"`
+	GenesisHash string `json:"genesis_hash,omitempty"`
+
+	// 运行结束时的汇总（成功/失败数等），由调用方填写
+	Summary map[string]any `json:"summary,omitempty"`
+}
+
+// New 创建清单并生成 run ID
+func New(tool string) *Manifest {
+	return &Manifest{
+		RunID:     NewRunID(),
+		Tool:      tool,
+		Build:     buildinfo.Get(),
+		StartedAt: time.Now().UTC(),
+		Args:      append([]string(nil), os.Args[1:]...),
+		Config:    map[string]string{},
+	}
+}
+
+// ForRun 为批量工具本次运行建清单：记录 flag.CommandLine 的参数、输入文件哈希（inputPath 为空时跳过）与 rpc 的链身份；
+// 哈希或链查询失败只告警，清单照常返回
+func ForRun(tool, inputPath, rpc string) *Manifest {
+	mf := New(tool)
+	mf.CaptureFlags(flag.CommandLine)
+	if inputPath != "" {
+		if err := mf.HashInput(inputPath); err != nil {
+			log.Printf("⚠️ 计算输入文件哈希失败: %v", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := mf.ProbeChain(ctx, rpc); err != nil {
+		log.Printf("⚠️ 获取链 ID/创世哈希失败: %v", err)
+	}
+	return mf
+}
+
+// NewRunID 生成形如 20250901-153000-a1b2c3 的 run ID（时间有序，随机后缀防并发冲突）
+func NewRunID() string {
+	var b [3]byte
+	_, _ = rand.Read(b[:])
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b[:])
+}
+
+// CaptureFlags 记录 FlagSet 中所有参数的最终取值（包括未显式设置的默认值）
+func (m *Manifest) CaptureFlags(fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		v := f.Value.String()
+		if isSensitive(f.Name) && v != "" {
+			v = "<redacted>"
+		}
+		m.Config[f.Name] = v
+	})
+}
+
+// 私钥/口令类参数不落盘
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "private-key") || strings.HasSuffix(name, "-key") ||
+		strings.Contains(name, "password") && !strings.HasSuffix(name, "-file") ||
+		strings.Contains(name, "secret")
+}
+
+// HashInput 计算输入文件的 sha256 并记入清单
+func (m *Manifest) HashInput(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("hash input: %w", err)
+	}
+	m.InputFile = path
+	m.InputSHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// ProbeChain 记录链 ID 与创世块哈希，用于确认结果来自哪条链
+func (m *Manifest) ProbeChain(ctx context.Context, rpc string) error {
+	m.RPC = rpc
+	cli, err := rpcpool.DialEth(ctx, rpc)
+	if err != nil {
+		return fmt.Errorf("dial rpc: %w", err)
+	}
+	defer cli.Close()
+
+	chainID, err := cli.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("get chain id: %w", err)
+	}
+	m.ChainID = chainID.String()
+
+	genesis, err := cli.HeaderByNumber(ctx, big.NewInt(0))
+	if err != nil {
+		return fmt.Errorf("get genesis header: %w", err)
+	}
+	m.GenesisHash = genesis.Hash().Hex()
+	return nil
+}
+
+// Write 写出清单（缩进 JSON）；写之前补上结束时间
+func (m *Manifest) Write(path string) error {
+	if m.FinishedAt.IsZero() {
+		m.FinishedAt = time.Now().UTC()
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// Load 读取清单；汇总中的数字保留为 json.Number（自动生成的 seed 等超出 float64 精度）
+func Load(path string) (*Manifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m Manifest
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
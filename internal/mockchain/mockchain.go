@@ -0,0 +1,497 @@
+// 内存链桩：同时实现 deposit.DepositSender、exit.ExitSender 与 beaconext.BeaconReader，
+// 每笔交易立即“出块”，并维护一个与 N42 BeaconState JSON 同形的最小状态；
+// 到达可提款纪元的验证者余额在出块时转入其提款地址（执行层余额见 BalanceAt）。
+// 让批量工具、场景脚本等上层逻辑可以在没有任何 RPC 的情况下测试，见 internal/lifecycle 的测试。
+package mockchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/deposit"
+	"n42-test/internal/exit"
+	"n42-test/internal/hexutil"
+	"n42-test/internal/keys"
+)
+
+// FarFutureEpoch 与信标链一致的“未设置”纪元
+const FarFutureEpoch = beaconstate.FarFutureEpoch
+
+// Config 内存链参数
+type Config struct {
+	SlotsPerEpoch uint64   // 每纪元 slot 数（每个块算一个 slot），默认 32
+	ExitFeeWei    *big.Int // 退出请求费用，默认 1 wei
+	GasPerDeposit uint64   // 每笔 deposit 记账的 gas，默认 60000
+	GasPerExit    uint64   // 每笔退出请求记账的 gas，默认 100000
+}
+
+// Validator 与 beacon_state.json 中 validators 元素同形
+type Validator = beaconstate.Validator
+
+type beaconState struct {
+	Slot             uint64      `json:"slot"`
+	Eth1DepositIndex uint64      `json:"eth1_deposit_index"`
+	Validators       []Validator `json:"validators"`
+	Balances         []uint64    `json:"balances"`
+}
+
+type block struct {
+	number     uint64
+	hash       common.Hash
+	beaconHash common.Hash
+	stateJSON  json.RawMessage
+	blockJSON  json.RawMessage
+}
+
+// Chain 内存链；所有方法并发安全
+type Chain struct {
+	mu sync.Mutex
+
+	cfg    Config
+	blocks []block
+	byHash map[string]int // eth1 hash / beacon hash（小写）-> blocks 下标
+	nonces map[common.Address]uint64
+	wei    map[common.Address]*big.Int // 执行层余额：只记提款转入
+
+	state beaconState
+	index map[string]int // pubkey（小写）-> 验证者下标
+
+	failNext error            // 下一次发送返回的错误（一次性）
+	failKey  map[string]error // 按 BLS 公钥注入的错误（小写 0x 公钥）
+}
+
+var (
+	_ deposit.DepositSender  = (*Chain)(nil)
+	_ exit.ExitSender        = (*Chain)(nil)
+	_ beaconext.BeaconReader = (*Chain)(nil)
+)
+
+// New 创建只有创世块的内存链
+func New(cfg Config) *Chain {
+	if cfg.SlotsPerEpoch == 0 {
+		cfg.SlotsPerEpoch = 32
+	}
+	if cfg.ExitFeeWei == nil {
+		cfg.ExitFeeWei = big.NewInt(1)
+	}
+	if cfg.GasPerDeposit == 0 {
+		cfg.GasPerDeposit = 60_000
+	}
+	if cfg.GasPerExit == 0 {
+		cfg.GasPerExit = 100_000
+	}
+	c := &Chain{
+		cfg:     cfg,
+		byHash:  map[string]int{},
+		nonces:  map[common.Address]uint64{},
+		wei:     map[common.Address]*big.Int{},
+		index:   map[string]int{},
+		failKey: map[string]error{},
+	}
+	c.mineLocked()
+	return c
+}
+
+// FailNext 让下一次发送（deposit 或 exit）返回 err
+func (c *Chain) FailNext(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failNext = err
+}
+
+// FailPubkey 让涉及该 BLS 公钥的发送一直返回 err（err 为 nil 时取消）
+func (c *Chain) FailPubkey(pubkeyHex string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := hexutil.Normalize(pubkeyHex)
+	if err == nil {
+		delete(c.failKey, k)
+		return
+	}
+	c.failKey[k] = err
+}
+
+// Head 当前链头高度
+func (c *Chain) Head() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blocks[len(c.blocks)-1].number
+}
+
+// Validator 按公钥查找验证者
+func (c *Chain) Validator(pubkeyHex string) (Validator, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, ok := c.index[hexutil.Normalize(pubkeyHex)]
+	if !ok {
+		return Validator{}, false
+	}
+	return c.state.Validators[i], true
+}
+
+// Mine 空出 n 个块（推进纪元，用于让激活/退出生效）
+func (c *Chain) Mine(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i < n; i++ {
+		c.mineLocked()
+	}
+}
+
+func (c *Chain) Close() {}
+
+// Deposits 以 Chain 本身作为任意发送私钥的存款发送端（形同 lifecycle.Backend.Deposits）
+func (c *Chain) Deposits(ctx context.Context, key string) (deposit.DepositSender, error) {
+	return c, nil
+}
+
+// BalanceAt 执行层地址收到的提款总额（wei）；blockNumber 被忽略，总是最新值
+func (c *Chain) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v := c.wei[account]; v != nil {
+		return new(big.Int).Set(v), nil
+	}
+	return new(big.Int), nil
+}
+
+// -------------------- deposit.DepositSender --------------------
+
+func (c *Chain) SendDeposit(ctx context.Context, p *deposit.DepositParams) (*deposit.TxResult, error) {
+	return c.sendDeposit(ctx, p)
+}
+
+// SendDepositNoWait 与 SendDeposit 相同（内存链立即出块），但不返回区块信息
+func (c *Chain) SendDepositNoWait(ctx context.Context, p *deposit.DepositParams) (*deposit.TxResult, error) {
+	res, err := c.sendDeposit(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return &deposit.TxResult{TxHash: res.TxHash, Nonce: res.Nonce, EstimatedGas: res.EstimatedGas}, nil
+}
+
+func (c *Chain) sendDeposit(ctx context.Context, p *deposit.DepositParams) (*deposit.TxResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, errors.New("nil params")
+	}
+	if p.Amount.Sign() <= 0 {
+		return nil, fmt.Errorf("amount must be > 0 wei")
+	}
+	gwei, err := p.Amount.Gwei()
+	if err != nil {
+		return nil, fmt.Errorf("amount: %w", err)
+	}
+	from, err := senderAddress(p.PrivateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.takeFailureLocked(p.PubkeyHex); err != nil {
+		return nil, err
+	}
+
+	nonce := c.nonces[from]
+	if p.Nonce >= 0 {
+		nonce = uint64(p.Nonce)
+	}
+	c.nonces[from] = nonce + 1
+
+	epoch := c.epochLocked()
+	pk := hexutil.Normalize(p.PubkeyHex)
+	if i, ok := c.index[pk]; ok {
+		// 追加质押：只加余额
+		c.state.Balances[i] += gwei
+		if c.state.Validators[i].EffectiveBalance < 32_000_000_000 {
+			c.state.Validators[i].EffectiveBalance = min(c.state.Balances[i], 32_000_000_000)
+		}
+	} else {
+		c.index[pk] = len(c.state.Validators)
+		c.state.Validators = append(c.state.Validators, Validator{
+			Pubkey:                     pk,
+			WithdrawalCredentials:      hexutil.Normalize(p.WCHex),
+			EffectiveBalance:           min(gwei, 32_000_000_000),
+			ActivationEligibilityEpoch: epoch + 1,
+			ActivationEpoch:            epoch + 2,
+			ExitEpoch:                  FarFutureEpoch,
+			WithdrawableEpoch:          FarFutureEpoch,
+		})
+		c.state.Balances = append(c.state.Balances, gwei)
+	}
+	depositIndex := c.state.Eth1DepositIndex
+	c.state.Eth1DepositIndex++
+
+	txHash := crypto.Keccak256Hash(from.Bytes(), u64(nonce), []byte(pk), []byte(p.RootHex))
+	b := c.mineLocked()
+	// 内存合约照单记账，事件与提交参数总是一致
+	ev := &deposit.DepositEvent{
+		Index: depositIndex, Pubkey: pk, WithdrawalCredentials: hexutil.Normalize(p.WCHex), AmountGwei: gwei,
+		Signature: hexutil.Normalize(p.SignatureHex), BlockNumber: b.number, BlockHash: b.hash.Hex(), TxHash: txHash.Hex(),
+	}
+	return &deposit.TxResult{
+		TxHash:       txHash.Hex(),
+		UsedGas:      c.cfg.GasPerDeposit,
+		Nonce:        nonce,
+		EstimatedGas: c.cfg.GasPerDeposit,
+		BlockNumber:  b.number,
+		BlockHash:    b.hash.Hex(),
+		Status:       1,
+		GasCostWei:   new(big.Int),
+		Deposit:      ev,
+	}, nil
+}
+
+// -------------------- exit.ExitSender --------------------
+
+func (c *Chain) GetExitFee(ctx context.Context) (*big.Int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return new(big.Int).Set(c.cfg.ExitFeeWei), nil
+}
+
+// SendExitRequest 记录退出请求：amount=0 为全额退出，否则为部分提款（从余额中扣除）
+func (c *Chain) SendExitRequest(ctx context.Context, priv *ecdsa.PrivateKey, pubkey48 []byte, amountGwei *big.Int, wait bool) (*types.Transaction, *types.Receipt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	calldata, err := exit.PackExitCalldata(pubkey48, amountGwei)
+	if err != nil {
+		return nil, nil, err
+	}
+	from := crypto.PubkeyToAddress(priv.PublicKey)
+	pk := "0x" + hex.EncodeToString(pubkey48)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.takeFailureLocked(pk); err != nil {
+		return nil, nil, err
+	}
+	i, ok := c.index[pk]
+	if !ok {
+		return nil, nil, fmt.Errorf("execution reverted: unknown validator %s", pk)
+	}
+
+	epoch := c.epochLocked()
+	v := &c.state.Validators[i]
+	if amountGwei.Sign() == 0 {
+		if v.ExitEpoch == FarFutureEpoch {
+			v.ExitEpoch = epoch + 1
+			v.WithdrawableEpoch = epoch + 2
+		}
+	} else {
+		amt := amountGwei.Uint64()
+		if amt > c.state.Balances[i] {
+			amt = c.state.Balances[i]
+		}
+		c.state.Balances[i] -= amt
+		c.withdrawLocked(i, amt)
+	}
+
+	nonce := c.nonces[from]
+	c.nonces[from] = nonce + 1
+	contract := common.Address{}
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce: nonce,
+		To:    &contract,
+		Value: new(big.Int).Set(c.cfg.ExitFeeWei),
+		Gas:   c.cfg.GasPerExit,
+		Data:  calldata,
+	})
+	b := c.mineLocked()
+	if !wait {
+		return tx, nil, nil
+	}
+	return tx, &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		TxHash:      tx.Hash(),
+		GasUsed:     c.cfg.GasPerExit,
+		BlockHash:   b.hash,
+		BlockNumber: new(big.Int).SetUint64(b.number),
+	}, nil
+}
+
+// -------------------- beaconext.BeaconReader --------------------
+
+func (c *Chain) EthGetBlockByNumber(ctx context.Context, tag string, fullTx bool) (*beaconext.EthBlock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n uint64
+	switch tag {
+	case "latest", "pending", "safe", "finalized":
+		n = c.blocks[len(c.blocks)-1].number
+	case "earliest":
+		n = 0
+	default:
+		v, err := strconv.ParseUint(strings.TrimPrefix(tag, "0x"), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block tag %q", tag)
+		}
+		n = v
+	}
+	if n >= uint64(len(c.blocks)) {
+		return nil, errors.New("empty result")
+	}
+	b := c.blocks[n]
+	parent := common.Hash{}
+	if n > 0 {
+		parent = c.blocks[n-1].hash
+	}
+	return &beaconext.EthBlock{
+		Number:       "0x" + strconv.FormatUint(b.number, 16),
+		Hash:         b.hash.Hex(),
+		ParentHash:   parent.Hex(),
+		ReceiptsRoot: types.EmptyReceiptsHash.Hex(),
+		Timestamp:    "0x" + strconv.FormatUint(b.number*12, 16),
+		Transactions: json.RawMessage("[]"),
+	}, nil
+}
+
+func (c *Chain) GetBeaconBlockHashByEth1Hash(ctx context.Context, eth1Hash string) (string, error) {
+	b, err := c.lookup(ctx, eth1Hash)
+	if err != nil {
+		return "", err
+	}
+	return b.beaconHash.Hex(), nil
+}
+
+func (c *Chain) GetBeaconBlockByHash(ctx context.Context, beaconBlockHash string) (json.RawMessage, error) {
+	b, err := c.lookup(ctx, beaconBlockHash)
+	if err != nil {
+		return nil, err
+	}
+	return b.blockJSON, nil
+}
+
+func (c *Chain) GetBeaconStateByBeaconBlockHash(ctx context.Context, beaconBlockHash string) (json.RawMessage, error) {
+	b, err := c.lookup(ctx, beaconBlockHash)
+	if err != nil {
+		return nil, err
+	}
+	return b.stateJSON, nil
+}
+
+func (c *Chain) ResolveBeaconByEth1Hash(ctx context.Context, eth1Hash string) (*beaconext.BeaconSnapshot, error) {
+	b, err := c.lookup(ctx, eth1Hash)
+	if err != nil {
+		return nil, err
+	}
+	return &beaconext.BeaconSnapshot{
+		Eth1Hash:        eth1Hash,
+		BeaconBlockHash: b.beaconHash.Hex(),
+		BeaconBlockRaw:  b.blockJSON,
+		BeaconStateRaw:  b.stateJSON,
+	}, nil
+}
+
+// -------------------- 内部 --------------------
+
+func (c *Chain) lookup(ctx context.Context, hash string) (block, error) {
+	if err := ctx.Err(); err != nil {
+		return block{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, ok := c.byHash[hexutil.Normalize(hash)]
+	if !ok {
+		return block{}, fmt.Errorf("rpc error -32000: unknown block %s", hash)
+	}
+	return c.blocks[i], nil
+}
+
+func (c *Chain) takeFailureLocked(pubkeyHex string) error {
+	if err := c.failNext; err != nil {
+		c.failNext = nil
+		return err
+	}
+	return c.failKey[hexutil.Normalize(pubkeyHex)]
+}
+
+func (c *Chain) epochLocked() uint64 {
+	return c.state.Slot / c.cfg.SlotsPerEpoch
+}
+
+// withdrawLocked 把 gwei 转入验证者 i 的提款地址；0x00 凭证没有执行层地址，只扣余额
+func (c *Chain) withdrawLocked(i int, gwei uint64) {
+	wc := common.FromHex(c.state.Validators[i].WithdrawalCredentials)
+	if len(wc) != 32 || wc[0] == 0x00 || gwei == 0 {
+		return
+	}
+	addr := common.BytesToAddress(wc[12:])
+	if c.wei[addr] == nil {
+		c.wei[addr] = new(big.Int)
+	}
+	c.wei[addr].Add(c.wei[addr], new(big.Int).Mul(new(big.Int).SetUint64(gwei), big.NewInt(1e9)))
+}
+
+// mineLocked 出一个块，提走已到可提款纪元的余额，并快照当前信标状态
+func (c *Chain) mineLocked() block {
+	n := uint64(len(c.blocks))
+	c.state.Slot = n
+	epoch := c.epochLocked()
+	for i, v := range c.state.Validators {
+		if epoch >= v.WithdrawableEpoch && c.state.Balances[i] > 0 {
+			c.withdrawLocked(i, c.state.Balances[i])
+			c.state.Balances[i] = 0
+			c.state.Validators[i].EffectiveBalance = 0
+		}
+	}
+	stateJSON, _ := json.Marshal(c.state)
+	b := block{
+		number:     n,
+		hash:       crypto.Keccak256Hash([]byte("eth1"), u64(n)),
+		beaconHash: crypto.Keccak256Hash([]byte("beacon"), u64(n)),
+		stateJSON:  stateJSON,
+	}
+	b.blockJSON, _ = json.Marshal(map[string]any{
+		"slot":       n,
+		"state_root": crypto.Keccak256Hash(stateJSON).Hex(),
+		"eth1_hash":  b.hash.Hex(),
+	})
+	c.blocks = append(c.blocks, b)
+	c.byHash[hexutil.Normalize(b.hash.Hex())] = int(n)
+	c.byHash[hexutil.Normalize(b.beaconHash.Hex())] = int(n)
+	return b
+}
+
+func senderAddress(privHex string) (common.Address, error) {
+	priv, err := keys.ParseECDSA(privHex)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("parse private key failed: %w", err)
+	}
+	return crypto.PubkeyToAddress(priv.PublicKey), nil
+}
+
+func u64(v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return b[:]
+}
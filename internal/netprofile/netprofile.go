@@ -0,0 +1,82 @@
+// 网络配置档：把不同链/工具链之间的差异（BLS 私钥格式、存款签名域、收据 trie 规则等）集中到一个名字下，
+// 命令行用 --profile 选择，避免在各个工具里分别硬编码。
+package netprofile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultName 默认配置档（本地 N42 开发链）
+const DefaultName = "n42"
+
+type Profile struct {
+	Name string
+
+	// BLS 私钥十六进制的字节序："be"（大端，blst/Rust/staking-deposit-cli 生成的密钥）或 "le"（小端）
+	BLSKeyEndian string
+
+	// BLS ETH mode："latest" | "draft07" | "draft06" | "draft05" | "old"
+	BLSETHMode string
+
+	// 存款签名域的 fork_version（十六进制 4 字节），空为 0x00000000
+	DepositForkVersion string
+
+	// 存款签名域的 genesis_validators_root（十六进制 32 字节），空为零（规范取值）
+	DepositGenesisValidatorsRoot string
+
+	// 空区块的 receipts_root；为空时取以太坊的空 trie 根 keccak256(rlp(""))
+	EmptyReceiptsRoot string
+
+	// 收据在 receipts trie 中的编码："eip2718"（类型字节 + RLP，默认）|
+	// "legacy"（忽略类型一律按 legacy RLP）| "wrapped"（类型化收据再包一层 RLP 字符串）
+	ReceiptEncoding string
+}
+
+var profiles = map[string]Profile{
+	// N42 本地链：mobile-sdk-test（Rust/blst）生成的大端私钥，ETH draft-07 哈希到曲线
+	"n42": {
+		Name:            "n42",
+		BLSKeyEndian:    "be",
+		BLSETHMode:      "draft07",
+		ReceiptEncoding: "eip2718",
+	},
+	// 以太坊主网/公共测试网，规则同上
+	"mainnet": {
+		Name:            "mainnet",
+		BLSKeyEndian:    "be",
+		BLSETHMode:      "latest",
+		ReceiptEncoding: "eip2718",
+	},
+	// 部分旧工具（herumi 早期版本）导出的小端私钥
+	"legacy-le": {
+		Name:            "legacy-le",
+		BLSKeyEndian:    "le",
+		BLSETHMode:      "latest",
+		ReceiptEncoding: "eip2718",
+	},
+}
+
+// Lookup 按名字查找配置档（大小写不敏感），空名字返回默认配置档
+func Lookup(name string) (Profile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultName
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown network profile %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return p, nil
+}
+
+// Names 返回所有已知配置档名（排序后）
+func Names() []string {
+	out := make([]string, 0, len(profiles))
+	for k := range profiles {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
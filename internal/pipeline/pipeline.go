@@ -0,0 +1,89 @@
+// 分阶段流水线：每个阶段有自己的 worker 数与有界输出队列，阶段之间只通过 channel 衔接。
+// 批量发送时把 CPU 密集的 BLS 签名、网络提交与等待回执拆开，
+// 慢的阶段只会让上游在队列满时阻塞（背压），不会占用其他阶段的 worker。
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats 单个阶段的运行统计
+type Stats struct {
+	Name    string
+	Workers int
+	Items   atomic.Int64
+	busy    atomic.Int64 // 所有 worker 处理条目的累计耗时（纳秒）
+	started time.Time
+	ended   atomic.Int64 // 最后一个 worker 退出的时间（unix 纳秒）
+}
+
+// String 处理条数、单条平均耗时与 worker 利用率（忙碌时间 / (workers × 阶段存活时间)）
+func (s *Stats) String() string {
+	n := s.Items.Load()
+	end := time.Now()
+	if e := s.ended.Load(); e != 0 {
+		end = time.Unix(0, e)
+	}
+	wall := end.Sub(s.started)
+	var avg time.Duration
+	var util float64
+	if n > 0 {
+		avg = time.Duration(s.busy.Load() / n)
+	}
+	if wall > 0 && s.Workers > 0 {
+		util = float64(s.busy.Load()) / float64(wall) / float64(s.Workers) * 100
+	}
+	return fmt.Sprintf("%-8s workers=%-3d items=%-6d avg=%-8s util=%.0f%%", s.Name, s.Workers, n, avg.Round(time.Millisecond), util)
+}
+
+// Stage 启动 workers 个 goroutine 对 in 中的每个元素执行 fn，结果写入容量为 queue 的输出 channel；
+// in 关闭且全部处理完后关闭输出。输出顺序与输入无关，需要时由调用方按下标重排。
+func Stage[I, O any](name string, in <-chan I, workers, queue int, fn func(I) O) (<-chan O, *Stats) {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queue < 0 {
+		queue = 0
+	}
+	out := make(chan O, queue)
+	st := &Stats{Name: name, Workers: workers, started: time.Now()}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range in {
+				began := time.Now()
+				r := fn(v)
+				st.busy.Add(int64(time.Since(began)))
+				st.Items.Add(1)
+				out <- r
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		st.ended.Store(time.Now().UnixNano())
+		close(out)
+	}()
+	return out, st
+}
+
+// Source 把切片按顺序送入容量为 queue 的 channel，送完关闭
+func Source[T any](items []T, queue int) <-chan T {
+	if queue < 0 {
+		queue = 0
+	}
+	ch := make(chan T, queue)
+	go func() {
+		defer close(ch)
+		for _, v := range items {
+			ch <- v
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,141 @@
+// Prometheus Pushgateway 推送：批量运行是短命进程，等不到 Prometheus 来抓，
+// 结束时把运行清单里的汇总（成功/失败数、gas 合计、耗时）推到 Pushgateway，
+// 以 run_id 与 network 作为分组标签，CI 里的临时任务也能出现在看板上。
+package pushgw
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"n42-test/internal/manifest"
+)
+
+// MetricPrefix 所有推送指标的前缀
+const MetricPrefix = "n42_run_"
+
+// Metric 一个 gauge
+type Metric struct {
+	Name  string
+	Help  string
+	Value float64
+}
+
+// Push 以 PUT 替换 <gateway>/metrics/job/<job>/<k>/<v>... 分组下的全部指标（文本格式）
+func Push(ctx context.Context, gateway, job string, grouping map[string]string, metrics []Metric) error {
+	u := strings.TrimRight(gateway, "/") + "/metrics/job/" + url.PathEscape(job)
+	keys := make([]string, 0, len(grouping))
+	for k := range grouping {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if grouping[k] == "" {
+			continue
+		}
+		u += "/" + url.PathEscape(k) + "/" + url.PathEscape(grouping[k])
+	}
+
+	var body bytes.Buffer
+	for _, m := range metrics {
+		if m.Help != "" {
+			fmt.Fprintf(&body, "# HELP %s %s\n", m.Name, m.Help)
+		}
+		fmt.Fprintf(&body, "# TYPE %s gauge\n%s %s\n", m.Name, m.Name, strconv.FormatFloat(m.Value, 'g', -1, 64))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("push to %s: %w", gateway, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push to %s: %s: %s", gateway, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// SummaryMetrics 把清单汇总中的数值字段转成 n42_run_<key>，另加耗时与完成时间戳；
+// 字符串与嵌套结构忽略，布尔记为 0/1，*big.Int（如 gas_cost_wei）按浮点近似
+func SummaryMetrics(mf *manifest.Manifest) []Metric {
+	keys := make([]string, 0, len(mf.Summary))
+	for k := range mf.Summary {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out []Metric
+	for _, k := range keys {
+		v, ok := number(mf.Summary[k])
+		if !ok {
+			continue
+		}
+		out = append(out, Metric{Name: MetricPrefix + sanitize(k), Help: "run summary " + k, Value: v})
+	}
+	end := mf.FinishedAt
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	return append(out,
+		Metric{Name: MetricPrefix + "duration_seconds", Help: "wall time of the run", Value: end.Sub(mf.StartedAt).Seconds()},
+		Metric{Name: MetricPrefix + "completion_timestamp_seconds", Help: "unix time the run finished", Value: float64(end.Unix())},
+	)
+}
+
+// PushSummary 推送一次运行的汇总；job 为工具名，分组标签为 run_id 与 network
+// （network 为空时用清单里的链 ID）
+func PushSummary(ctx context.Context, gateway string, mf *manifest.Manifest, network string) error {
+	if network == "" && mf.ChainID != "" {
+		network = "chain-" + mf.ChainID
+	}
+	return Push(ctx, gateway, mf.Tool, map[string]string{"run_id": mf.RunID, "network": network}, SummaryMetrics(mf))
+}
+
+func number(v any) (float64, bool) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float64:
+		return x, true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	case *big.Int:
+		if x == nil {
+			return 0, false
+		}
+		f, _ := new(big.Float).SetInt(x).Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// sanitize 指标名只允许 [a-zA-Z0-9_]
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,121 @@
+// 令牌桶限速：批量发送按目标速率（笔/秒）放行任务，产生持续稳定的负载，而不是 worker 一拥而上；
+// 结束时对比实际达成的速率与目标速率——达不到目标说明瓶颈在节点或并发度，而不是限速本身。
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Limiter 令牌桶；nil *Limiter 的方法都是空操作，调用方可以不区分是否限速
+type Limiter struct {
+	rate  float64 // 每秒补充的令牌数
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64 // 可为负：已预约但尚未到放行时间的任务
+	last   time.Time
+
+	// 统计
+	taken       int64
+	first, end  time.Time // 第一次与最近一次放行时间
+	waitedTotal time.Duration
+}
+
+// New 目标速率 rate（笔/秒）；burst 为空闲后允许连续放行的数量，<1 时取 1（严格匀速）。rate<=0 返回 nil（不限速）
+func New(rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Rate 目标速率；nil 为 0
+func (l *Limiter) Rate() float64 {
+	if l == nil {
+		return 0
+	}
+	return l.rate
+}
+
+// Wait 预约一个令牌并等到它的放行时间；ctx 结束时归还令牌并返回 ctx.Err()
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			l.mu.Lock()
+			l.tokens++
+			l.mu.Unlock()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	l.mu.Lock()
+	at := time.Now()
+	if l.taken == 0 {
+		l.first = at
+	}
+	l.taken++
+	l.end = at
+	l.waitedTotal += delay
+	l.mu.Unlock()
+	return nil
+}
+
+// Achieved 实际放行速率（笔/秒）：按第一次到最近一次放行的间隔计算，少于两次时为 0
+func (l *Limiter) Achieved() float64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.achieved()
+}
+
+func (l *Limiter) achieved() float64 {
+	span := l.end.Sub(l.first).Seconds()
+	if l.taken < 2 || span <= 0 {
+		return 0
+	}
+	return float64(l.taken-1) / span
+}
+
+// Summary 运行结束时的速率统计
+func (l *Limiter) Summary() string {
+	if l == nil {
+		return ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	achieved := l.achieved()
+	avgWait := time.Duration(0)
+	if l.taken > 0 {
+		avgWait = l.waitedTotal / time.Duration(l.taken)
+	}
+	return fmt.Sprintf("rate: target=%.2f/s achieved=%.2f/s (%.0f%%) released=%d over %s avg_throttle=%s",
+		l.rate, achieved, achieved/l.rate*100, l.taken, l.end.Sub(l.first).Round(time.Millisecond), avgWait.Round(time.Millisecond))
+}
@@ -0,0 +1,161 @@
+package receipts
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// SetCodeTxType EIP-7702 交易类型（所用 go-ethereum 版本尚未定义）
+const SetCodeTxType = 0x04
+
+// knownTypes 已知的收据/交易类型；共识编码都是 类型字节 || RLP([status, cumulativeGas, bloom, logs])，
+// blob 交易的 blobGasUsed / blobGasPrice 不参与编码
+var knownTypes = map[uint8]string{
+	types.LegacyTxType:     "legacy",
+	types.AccessListTxType: "access-list",
+	types.DynamicFeeTxType: "dynamic-fee",
+	types.BlobTxType:       "blob",
+	SetCodeTxType:          "set-code",
+}
+
+// TypeName 收据类型名；未知类型返回十六进制
+func TypeName(t uint8) string {
+	if n, ok := knownTypes[t]; ok {
+		return n
+	}
+	return fmt.Sprintf("0x%02x", t)
+}
+
+// rpcReceipt RPC 返回的收据中参与共识编码的字段
+type rpcReceipt struct {
+	Type              *hexutil.Uint64 `json:"type"`   // 旧节点可能缺失，退化为交易类型
+	Status            *hexutil.Uint64 `json:"status"` // 拜占庭之后
+	Root              hexutil.Bytes   `json:"root"`   // 拜占庭之前的 post-state root
+	CumulativeGasUsed hexutil.Uint64  `json:"cumulativeGasUsed"`
+	LogsBloom         types.Bloom     `json:"logsBloom"`
+	Logs              []rpcLog        `json:"logs"`
+	TxHash            common.Hash     `json:"transactionHash"`
+}
+
+type rpcLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+// rpcTx 区块中交易的类型信息（eth_getBlockByNumber fullTx=true）
+type rpcTx struct {
+	Hash common.Hash    `json:"hash"`
+	Type hexutil.Uint64 `json:"type"`
+}
+
+// decodeGethReceiptFromRPC 把 RPC 收据还原为 go-ethereum 的 Receipt（只填共识字段）：
+// 类型取收据的 type 字段，缺失时取交易类型，两者都有时必须一致；
+// 有 root 字段时按拜占庭前的 post-state 编码，否则按 status 编码。
+func decodeGethReceiptFromRPC(raw *rpcReceipt, tx rpcTx) (*types.Receipt, error) {
+	if raw == nil {
+		return nil, fmt.Errorf("null receipt")
+	}
+	if raw.TxHash != (common.Hash{}) && raw.TxHash != tx.Hash {
+		return nil, fmt.Errorf("receipt for %s, want %s", raw.TxHash.Hex(), tx.Hash.Hex())
+	}
+	typ := uint64(tx.Type)
+	if raw.Type != nil {
+		if uint64(*raw.Type) != typ {
+			return nil, fmt.Errorf("receipt %s: type %d, transaction type %d", tx.Hash.Hex(), uint64(*raw.Type), typ)
+		}
+		typ = uint64(*raw.Type)
+	}
+	if typ > 0xff {
+		return nil, fmt.Errorf("receipt %s: bad type %d", tx.Hash.Hex(), typ)
+	}
+	if _, ok := knownTypes[uint8(typ)]; !ok {
+		return nil, fmt.Errorf("receipt %s: unsupported type %s", tx.Hash.Hex(), TypeName(uint8(typ)))
+	}
+	rc := &types.Receipt{
+		Type:              uint8(typ),
+		CumulativeGasUsed: uint64(raw.CumulativeGasUsed),
+		Bloom:             raw.LogsBloom,
+		TxHash:            tx.Hash,
+		Logs:              make([]*types.Log, len(raw.Logs)),
+	}
+	switch {
+	case len(raw.Root) > 0:
+		if len(raw.Root) != common.HashLength {
+			return nil, fmt.Errorf("receipt %s: post-state root is %d bytes", tx.Hash.Hex(), len(raw.Root))
+		}
+		rc.PostState = raw.Root
+	case raw.Status != nil:
+		if *raw.Status > 1 {
+			return nil, fmt.Errorf("receipt %s: bad status %d", tx.Hash.Hex(), uint64(*raw.Status))
+		}
+		rc.Status = uint64(*raw.Status)
+	default:
+		return nil, fmt.Errorf("receipt %s: neither status nor root", tx.Hash.Hex())
+	}
+	for i, l := range raw.Logs {
+		rc.Logs[i] = &types.Log{Address: l.Address, Topics: l.Topics, Data: l.Data}
+	}
+	return rc, nil
+}
+
+// Diag 单条收据的诊断信息（receipts_root 不一致时输出）
+type Diag struct {
+	Index             int    `json:"index"`
+	TxHash            string `json:"tx_hash"`
+	Type              string `json:"type"`
+	Status            uint64 `json:"status"`
+	PostState         string `json:"post_state,omitempty"`
+	CumulativeGasUsed uint64 `json:"cumulative_gas_used"`
+	Logs              int    `json:"logs"`
+	BloomOK           bool   `json:"bloom_ok"`      // logsBloom 与按日志重算的一致
+	GasMonotonic      bool   `json:"gas_monotonic"` // cumulativeGasUsed 不小于上一条
+	Encoded           string `json:"encoded"`       // 按规则编码后的 trie 值
+	EncodedLen        int    `json:"encoded_len"`
+	Err               string `json:"error,omitempty"`
+}
+
+func (d Diag) String() string {
+	s := fmt.Sprintf("#%d %s type=%s status=%d cum_gas=%d logs=%d bloom_ok=%t gas_monotonic=%t len=%d",
+		d.Index, d.TxHash, d.Type, d.Status, d.CumulativeGasUsed, d.Logs, d.BloomOK, d.GasMonotonic, d.EncodedLen)
+	if d.PostState != "" {
+		s += " post_state=" + d.PostState
+	}
+	if d.Err != "" {
+		s += " error=" + d.Err
+	}
+	return s
+}
+
+// Diagnose 逐条检查区块中的收据：bloom 是否与日志一致、累计 gas 是否单调、按规则的编码
+func Diagnose(rules Rules, b *Block) []Diag {
+	out := make([]Diag, len(b.Receipts))
+	var prevGas uint64
+	for i, rc := range b.Receipts {
+		d := Diag{
+			Index:             i,
+			TxHash:            rc.TxHash.Hex(),
+			Type:              TypeName(rc.Type),
+			Status:            rc.Status,
+			CumulativeGasUsed: rc.CumulativeGasUsed,
+			Logs:              len(rc.Logs),
+			BloomOK:           bytes.Equal(types.LogsBloom(rc.Logs), rc.Bloom.Bytes()),
+			GasMonotonic:      rc.CumulativeGasUsed >= prevGas,
+		}
+		if len(rc.PostState) > 0 {
+			d.PostState = hexutil.Encode(rc.PostState)
+		}
+		if enc, err := rules.Encode(rc); err != nil {
+			d.Err = err.Error()
+		} else {
+			d.Encoded, d.EncodedLen = hexutil.Encode(enc), len(enc)
+		}
+		prevGas = rc.CumulativeGasUsed
+		out[i] = d
+	}
+	return out
+}
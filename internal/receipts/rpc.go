@@ -0,0 +1,201 @@
+package receipts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"n42-test/internal/capability"
+	"n42-test/internal/rpcpool"
+)
+
+// Fetcher 从执行层 RPC 取区块头与收据
+type Fetcher struct {
+	cli  *rpc.Client
+	caps *capability.Matrix
+}
+
+// Dial 连接 endpoint；节点支持 eth_getBlockReceipts 时整块取收据，否则逐笔查询
+func Dial(ctx context.Context, endpoint string) (*Fetcher, error) {
+	cli, err := rpcpool.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("dial rpc: %w", err)
+	}
+	return &Fetcher{cli: cli, caps: capability.For(ctx, endpoint)}, nil
+}
+
+func (f *Fetcher) Close() { f.cli.Close() }
+
+// Block 计算 receipts_root 所需的区块信息
+type Block struct {
+	Number       uint64
+	Hash         common.Hash
+	ReceiptsRoot common.Hash // 区块头中的值
+	TxHashes     []common.Hash
+	TxTypes      []uint8
+	Receipts     []*types.Receipt // 按交易顺序；无交易时为空
+}
+
+type rpcBlock struct {
+	Number       hexutil.Uint64 `json:"number"`
+	Hash         common.Hash    `json:"hash"`
+	ReceiptsRoot common.Hash    `json:"receiptsRoot"`
+	Transactions []rpcTx        `json:"transactions"`
+}
+
+// Latest 当前最新块号
+func (f *Fetcher) Latest(ctx context.Context) (uint64, error) {
+	var n hexutil.Uint64
+	if err := f.cli.CallContext(ctx, &n, "eth_blockNumber"); err != nil {
+		return 0, fmt.Errorf("eth_blockNumber: %w", err)
+	}
+	return uint64(n), nil
+}
+
+// Header 取区块头中的 receipts_root 与交易哈希、类型列表
+func (f *Fetcher) Header(ctx context.Context, number uint64) (*Block, error) {
+	var raw *rpcBlock
+	if err := f.cli.CallContext(ctx, &raw, "eth_getBlockByNumber", hexutil.EncodeBig(new(big.Int).SetUint64(number)), true); err != nil {
+		return nil, fmt.Errorf("eth_getBlockByNumber %d: %w", number, err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	b := &Block{
+		Number:       uint64(raw.Number),
+		Hash:         raw.Hash,
+		ReceiptsRoot: raw.ReceiptsRoot,
+		TxHashes:     make([]common.Hash, len(raw.Transactions)),
+		TxTypes:      make([]uint8, len(raw.Transactions)),
+	}
+	for i, tx := range raw.Transactions {
+		if tx.Type > 0xff {
+			return nil, fmt.Errorf("block %d tx %s: bad type %d", number, tx.Hash.Hex(), tx.Type)
+		}
+		b.TxHashes[i], b.TxTypes[i] = tx.Hash, uint8(tx.Type)
+	}
+	return b, nil
+}
+
+// LoadReceipts 取区块中全部交易的收据，按交易顺序填入 b.Receipts
+func (f *Fetcher) LoadReceipts(ctx context.Context, b *Block) error {
+	if len(b.TxHashes) == 0 {
+		b.Receipts = nil
+		return nil
+	}
+	var raws []*rpcReceipt
+	if f.caps.HasBlockReceipts() {
+		if err := f.cli.CallContext(ctx, &raws, "eth_getBlockReceipts", b.Hash); err != nil {
+			return fmt.Errorf("eth_getBlockReceipts %d: %w", b.Number, err)
+		}
+	} else {
+		var err error
+		if raws, err = f.receiptsByTx(ctx, b.TxHashes); err != nil {
+			return err
+		}
+	}
+	if len(raws) != len(b.TxHashes) {
+		return fmt.Errorf("block %d: got %d receipts for %d transactions", b.Number, len(raws), len(b.TxHashes))
+	}
+	out := make([]*types.Receipt, len(raws))
+	for i, raw := range raws {
+		rc, err := decodeGethReceiptFromRPC(raw, rpcTx{Hash: b.TxHashes[i], Type: hexutil.Uint64(b.TxTypes[i])})
+		if err != nil {
+			return fmt.Errorf("block %d receipt %d: %w", b.Number, i, err)
+		}
+		out[i] = rc
+	}
+	b.Receipts = out
+	return nil
+}
+
+// receiptsByTx 逐笔 eth_getTransactionReceipt；节点支持批量请求时一次往返
+func (f *Fetcher) receiptsByTx(ctx context.Context, hashes []common.Hash) ([]*rpcReceipt, error) {
+	out := make([]*rpcReceipt, len(hashes))
+	if f.caps.HasBatch() {
+		reqs := make([]rpc.BatchElem, len(hashes))
+		for i, h := range hashes {
+			reqs[i] = rpc.BatchElem{Method: "eth_getTransactionReceipt", Args: []any{h}, Result: &out[i]}
+		}
+		if err := f.cli.BatchCallContext(ctx, reqs); err != nil {
+			return nil, fmt.Errorf("batch eth_getTransactionReceipt: %w", err)
+		}
+		for i, r := range reqs {
+			if r.Error != nil {
+				return nil, fmt.Errorf("eth_getTransactionReceipt %s: %w", hashes[i].Hex(), r.Error)
+			}
+		}
+		return out, nil
+	}
+	for i, h := range hashes {
+		if err := f.cli.CallContext(ctx, &out[i], "eth_getTransactionReceipt", h); err != nil {
+			return nil, fmt.Errorf("eth_getTransactionReceipt %s: %w", h.Hex(), err)
+		}
+	}
+	return out, nil
+}
+
+// Result 一个区块的 receipts_root 重算结果
+type Result struct {
+	Number   uint64
+	Header   common.Hash // 区块头中的 receipts_root
+	Computed common.Hash
+	TxCount  int
+	FastPath bool   // 无交易区块：直接取规则中的空 trie 根，未查询收据
+	Block    *Block // 取到的区块与收据，供 Diagnose 使用
+
+	FetchTime   time.Duration // 取区块头与收据的耗时
+	ComputeTime time.Duration // 本地编码并重算 trie 根的耗时
+}
+
+// Match 重算结果与区块头一致
+func (r Result) Match() bool { return r.Header == r.Computed }
+
+// Validate 与节点区块头比对；不一致时返回带逐条收据诊断的错误
+func (r *Result) Validate(rules Rules) error {
+	if r.Match() {
+		return nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "block %d receipts_root mismatch: header=%s computed=%s (txs=%d, %s)",
+		r.Number, r.Header.Hex(), r.Computed.Hex(), r.TxCount, rules)
+	if r.Block != nil {
+		for _, d := range Diagnose(rules, r.Block) {
+			sb.WriteString("\n  " + d.String())
+		}
+	}
+	return errors.New(sb.String())
+}
+
+// Compute 按规则重算区块 number 的 receipts_root
+func (f *Fetcher) Compute(ctx context.Context, rules Rules, number uint64) (*Result, error) {
+	began := time.Now()
+	b, err := f.Header(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Number: b.Number, Header: b.ReceiptsRoot, TxCount: len(b.TxHashes), Block: b}
+	if len(b.TxHashes) == 0 {
+		res.Computed, res.FastPath = rules.EmptyRoot, true
+		res.FetchTime = time.Since(began)
+		return res, nil
+	}
+	if err := f.LoadReceipts(ctx, b); err != nil {
+		return nil, err
+	}
+	res.FetchTime = time.Since(began)
+	began = time.Now()
+	if res.Computed, err = rules.Root(b.Receipts); err != nil {
+		return nil, err
+	}
+	res.ComputeTime = time.Since(began)
+	return res, nil
+}
@@ -0,0 +1,124 @@
+// 执行层 receipts_root 的本地重算：按网络配置档的规则（空 trie 根、收据编码）
+// 把 RPC 返回的收据编码进 trie，得到与区块头可比对的根。
+package receipts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+
+	"n42-test/internal/hexutil"
+	"n42-test/internal/netprofile"
+)
+
+// Encoding 收据在 receipts trie 中的编码方式
+type Encoding string
+
+const (
+	// EncodingEIP2718 以太坊规则：legacy 收据为 RLP 列表，类型化收据为 类型字节 || RLP
+	EncodingEIP2718 Encoding = "eip2718"
+	// EncodingLegacy 忽略收据类型，一律按 legacy RLP 列表编码（未实现类型信封的链）
+	EncodingLegacy Encoding = "legacy"
+	// EncodingWrapped 类型化收据在 类型字节 || RLP 之外再包一层 RLP 字符串（网络层编码）
+	EncodingWrapped Encoding = "wrapped"
+)
+
+// ParseEncoding 解析编码名；空串为 EncodingEIP2718
+func ParseEncoding(s string) (Encoding, error) {
+	switch Encoding(strings.ToLower(strings.TrimSpace(s))) {
+	case "", EncodingEIP2718:
+		return EncodingEIP2718, nil
+	case EncodingLegacy:
+		return EncodingLegacy, nil
+	case EncodingWrapped:
+		return EncodingWrapped, nil
+	default:
+		return "", fmt.Errorf("unknown receipt encoding %q (eip2718|legacy|wrapped)", s)
+	}
+}
+
+// Rules 一条链的 receipts trie 规则
+type Rules struct {
+	EmptyRoot common.Hash // 无交易区块的 receipts_root
+	Encoding  Encoding
+}
+
+// DefaultRules 以太坊主网规则
+func DefaultRules() Rules {
+	return Rules{EmptyRoot: types.EmptyReceiptsHash, Encoding: EncodingEIP2718}
+}
+
+// RulesFromProfile 取配置档中的收据规则
+func RulesFromProfile(p netprofile.Profile) (Rules, error) {
+	r := DefaultRules()
+	if err := r.Override(p.EmptyReceiptsRoot, p.ReceiptEncoding); err != nil {
+		return Rules{}, fmt.Errorf("profile %s: %w", p.Name, err)
+	}
+	return r, nil
+}
+
+// Override 用命令行参数覆盖规则；空串表示保持不变
+func (r *Rules) Override(emptyRoot, encoding string) error {
+	if strings.TrimSpace(emptyRoot) != "" {
+		b, err := hexutil.DecodeFixed(emptyRoot, hexutil.HashLen)
+		if err != nil {
+			return fmt.Errorf("empty receipts root: %w", err)
+		}
+		r.EmptyRoot = common.BytesToHash(b)
+	}
+	if strings.TrimSpace(encoding) != "" {
+		enc, err := ParseEncoding(encoding)
+		if err != nil {
+			return err
+		}
+		r.Encoding = enc
+	}
+	return nil
+}
+
+func (r Rules) String() string {
+	return fmt.Sprintf("encoding=%s empty_root=%s", r.Encoding, r.EmptyRoot.Hex())
+}
+
+// Encode 按规则编码单条收据（trie 中的值）
+func (r Rules) Encode(rc *types.Receipt) ([]byte, error) {
+	switch r.Encoding {
+	case EncodingLegacy:
+		legacy := *rc
+		legacy.Type = types.LegacyTxType
+		return rlp.EncodeToBytes(&legacy)
+	case EncodingWrapped:
+		// Receipt.EncodeRLP 对类型化收据输出的正是 RLP 字符串包裹的信封
+		return rlp.EncodeToBytes(rc)
+	default:
+		return rc.MarshalBinary()
+	}
+}
+
+// Root 计算收据列表的 receipts_root；空列表直接返回 EmptyRoot
+func (r Rules) Root(list []*types.Receipt) (common.Hash, error) {
+	if len(list) == 0 {
+		return r.EmptyRoot, nil
+	}
+	enc := make([][]byte, len(list))
+	for i, rc := range list {
+		b, err := r.Encode(rc)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("encode receipt %d: %w", i, err)
+		}
+		enc[i] = b
+	}
+	return types.DeriveSha(encodedList(enc), trie.NewStackTrie(nil)), nil
+}
+
+// encodedList 已编码的收据，实现 types.DerivableList
+type encodedList [][]byte
+
+func (l encodedList) Len() int { return len(l) }
+
+func (l encodedList) EncodeIndex(i int, w *bytes.Buffer) { w.Write(l[i]) }
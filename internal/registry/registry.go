@@ -0,0 +1,204 @@
+// 跨运行的去重登记：每个网络（按创世哈希区分）一个 SQLite 库，记录历次运行中已确认的存款 / 退出的验证者公钥。
+// deposit-batch / exit-batch 的 --skip-existing 据此跳过已处理过的验证者，重跑同一批输入不必每次扫描链上日志。
+// 另记每条存款数据的幂等键（deposit.IdempotencyKey）与最近一次为它发出的交易，deposit-batch 每次运行发送前据此核对，
+// 费用变化导致交易哈希不同时也能识别重复存款。
+// 登记只反映本工具发出的交易；链被重置后换了创世哈希，自然落到新库。
+package registry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"n42-test/internal/manifest"
+	"n42-test/internal/rpcpool"
+)
+
+const (
+	DefaultDir = "registry"         // 默认目录（相对当前目录）
+	EnvDir     = "N42_REGISTRY_DIR" // 覆盖默认目录的环境变量
+)
+
+// 登记的操作类型
+const (
+	KindDeposit = "deposit"
+	KindExit    = "exit"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS validators (
+	pubkey      TEXT    NOT NULL,
+	kind        TEXT    NOT NULL,
+	tx_hash     TEXT    NOT NULL DEFAULT '',
+	block       INTEGER NOT NULL DEFAULT 0,
+	run_id      TEXT    NOT NULL DEFAULT '',
+	recorded_at TEXT    NOT NULL,
+	PRIMARY KEY (pubkey, kind)
+);
+CREATE TABLE IF NOT EXISTS deposit_keys (
+	key         TEXT    NOT NULL PRIMARY KEY,
+	pubkey      TEXT    NOT NULL DEFAULT '',
+	status      TEXT    NOT NULL,
+	tx_hash     TEXT    NOT NULL DEFAULT '',
+	sender      TEXT    NOT NULL DEFAULT '',
+	nonce       INTEGER NOT NULL DEFAULT 0,
+	block       INTEGER NOT NULL DEFAULT 0,
+	run_id      TEXT    NOT NULL DEFAULT '',
+	recorded_at TEXT    NOT NULL
+)`
+
+// DefaultDirPath 环境变量 N42_REGISTRY_DIR 优先，否则为 ./registry
+func DefaultDirPath() string {
+	if v := os.Getenv(EnvDir); v != "" {
+		return v
+	}
+	return DefaultDir
+}
+
+// Entry 一条登记
+type Entry struct {
+	Pubkey string
+	TxHash string
+	Block  uint64
+	RunID  string
+}
+
+// Registry 某个网络的登记库；可被多个进程同时打开（WAL + busy_timeout）
+type Registry struct {
+	Path    string
+	Genesis string
+	db      *sql.DB
+}
+
+// Open 打开（不存在则创建）dir 下创世哈希为 genesis 的网络的登记库
+func Open(dir, genesis string) (*Registry, error) {
+	g := normHex(genesis)
+	if g == "" {
+		return nil, errors.New("registry: empty genesis hash")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, g+".db")
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("registry %s: %w", path, err)
+	}
+	return &Registry{Path: path, Genesis: "0x" + g, db: db}, nil
+}
+
+// OpenRPC 按 rpc 所连网络打开登记库；genesis 非空时直接使用（如运行清单里已探测到的值），否则查询创世块
+func OpenRPC(ctx context.Context, dir, rpc, genesis string) (*Registry, error) {
+	if genesis == "" {
+		cli, err := rpcpool.DialEth(ctx, rpc)
+		if err != nil {
+			return nil, fmt.Errorf("dial rpc: %w", err)
+		}
+		defer cli.Close()
+		h, err := cli.HeaderByNumber(ctx, big.NewInt(0))
+		if err != nil {
+			return nil, fmt.Errorf("get genesis header: %w", err)
+		}
+		genesis = h.Hash().Hex()
+	}
+	return Open(dir, genesis)
+}
+
+// OpenForRun 为批量工具本次运行打开 rpc 所连网络的登记库：运行清单 mf 里已探测到创世哈希时不再查询；mf 可为 nil
+func OpenForRun(dir, rpc string, mf *manifest.Manifest) (*Registry, error) {
+	var genesis string
+	if mf != nil {
+		genesis = mf.GenesisHash
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return OpenRPC(ctx, dir, rpc, genesis)
+}
+
+// Has 该公钥是否已有 kind 类型的登记
+func (r *Registry) Has(kind, pubkey string) (bool, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM validators WHERE pubkey = ? AND kind = ?`, normHex(pubkey), kind).Scan(&n)
+	return n > 0, err
+}
+
+// Record 登记一条；已有登记时保留最早的那条
+func (r *Registry) Record(kind string, e Entry) error {
+	_, err := r.db.Exec(
+		`INSERT OR IGNORE INTO validators (pubkey, kind, tx_hash, block, run_id, recorded_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		normHex(e.Pubkey), kind, e.TxHash, e.Block, e.RunID, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// Count kind 类型的登记条数
+func (r *Registry) Count(kind string) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM validators WHERE kind = ?`, kind).Scan(&n)
+	return n, err
+}
+
+// 存款幂等键的登记状态
+const (
+	KeySent      = "sent"      // 已广播，尚未确认
+	KeyConfirmed = "confirmed" // 已上链且成功
+)
+
+// KeyEntry 一条存款幂等键（deposit.IdempotencyKey）的登记：最近一次为该存款数据发出的交易
+type KeyEntry struct {
+	Key    string
+	Pubkey string
+	Status string // KeySent | KeyConfirmed
+	TxHash string
+	From   string // 发送账户
+	Nonce  uint64
+	Block  uint64
+	RunID  string
+}
+
+// LookupKey 查询幂等键的登记
+func (r *Registry) LookupKey(key string) (KeyEntry, bool, error) {
+	e := KeyEntry{Key: normHex(key)}
+	err := r.db.QueryRow(`SELECT pubkey, status, tx_hash, sender, nonce, block, run_id FROM deposit_keys WHERE key = ?`, e.Key).
+		Scan(&e.Pubkey, &e.Status, &e.TxHash, &e.From, &e.Nonce, &e.Block, &e.RunID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return KeyEntry{}, false, nil
+	}
+	if err != nil {
+		return KeyEntry{}, false, err
+	}
+	return e, true, nil
+}
+
+// RecordKey 登记幂等键：同一键以最新的交易为准（原交易 revert 或被丢弃后重发的覆盖旧记录）；
+// 同一交易已记为 confirmed 时不回退为 sent
+func (r *Registry) RecordKey(e KeyEntry) error {
+	_, err := r.db.Exec(
+		`INSERT INTO deposit_keys (key, pubkey, status, tx_hash, sender, nonce, block, run_id, recorded_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			pubkey = excluded.pubkey, tx_hash = excluded.tx_hash, sender = excluded.sender, nonce = excluded.nonce, run_id = excluded.run_id,
+			recorded_at = excluded.recorded_at,
+			status = CASE WHEN deposit_keys.tx_hash = excluded.tx_hash AND deposit_keys.status = 'confirmed' THEN 'confirmed' ELSE excluded.status END,
+			block = CASE WHEN excluded.block > 0 THEN excluded.block WHEN deposit_keys.tx_hash = excluded.tx_hash THEN deposit_keys.block ELSE 0 END`,
+		normHex(e.Key), normHex(e.Pubkey), e.Status, e.TxHash, e.From, e.Nonce, e.Block, e.RunID, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (r *Registry) Close() error { return r.db.Close() }
+
+func normHex(s string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+}
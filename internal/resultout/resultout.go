@@ -0,0 +1,139 @@
+// 批量工具的结构化结果输出：同一组逐条记录按 json（数组）/ ndjson（每行一个对象）/ csv 写出，供下游分析。
+// 记录为平铺的结构体，CSV 表头取字段的 json 标签名。
+package resultout
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// ResolveFormat 显式给出的格式优先，否则按扩展名（.csv / .ndjson / .jsonl），默认 json
+func ResolveFormat(path, format string) (string, error) {
+	switch f := strings.ToLower(format); f {
+	case FormatJSON, FormatNDJSON, FormatCSV:
+		return f, nil
+	case "":
+	default:
+		return "", fmt.Errorf("未知的输出格式 %q（可选 json|csv|ndjson）", format)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	}
+	return FormatJSON, nil
+}
+
+// Write 把 records 按 format 写到 path（覆盖）
+func Write[T any](path, format string, records []T) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if records == nil {
+			records = []T{}
+		}
+		err = enc.Encode(records)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err = enc.Encode(r); err != nil {
+				break
+			}
+		}
+	case FormatCSV:
+		err = writeCSV(w, records)
+	default:
+		err = fmt.Errorf("未知的输出格式 %q", format)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func writeCSV[T any](w *bufio.Writer, records []T) error {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("csv 输出需要结构体记录，得到 %s", t)
+	}
+	var header []string
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(fields))
+	for _, r := range records {
+		v := reflect.ValueOf(r)
+		for j, i := range fields {
+			row[j] = fmt.Sprint(v.Field(i).Interface())
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Read 读取 Write 写出的 json（数组）/ ndjson 结果文件；csv 字段都是字符串化的，不支持读回
+func Read[T any](path, format string) ([]T, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []T
+	dec := json.NewDecoder(bufio.NewReader(f))
+	switch format {
+	case FormatJSON:
+		if err := dec.Decode(&out); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case FormatNDJSON:
+		for n := 1; dec.More(); n++ {
+			var r T
+			if err := dec.Decode(&r); err != nil {
+				return nil, fmt.Errorf("%s 第 %d 条: %w", path, n, err)
+			}
+			out = append(out, r)
+		}
+	default:
+		return nil, fmt.Errorf("不支持读取 %s 格式的结果文件（可用 json|ndjson）", format)
+	}
+	return out, nil
+}
@@ -0,0 +1,85 @@
+package resultout
+
+import (
+	"n42-test/internal/hexutil"
+)
+
+// RetrySet --retry-failed：上一次运行的逐条结果及其中失败的条目。
+// 按下标与公钥识别失败条目，本次只重跑这些条目，结束后把新结果合并回原结果文件。
+type RetrySet[T any] struct {
+	Path   string
+	Format string
+	Prior  []T // 上一次的全部记录
+
+	index   func(T) int
+	failed  map[int]string // 下标 -> 公钥（规范化，可为空）
+	pubkeys map[string]bool
+}
+
+// LoadRetry 读取结果文件（格式按扩展名）；key 给出每条记录的下标、公钥与是否失败
+func LoadRetry[T any](path string, key func(T) (index int, pubkey string, failed bool)) (*RetrySet[T], error) {
+	format, err := ResolveFormat(path, "")
+	if err != nil {
+		return nil, err
+	}
+	prior, err := Read[T](path, format)
+	if err != nil {
+		return nil, err
+	}
+	s := &RetrySet[T]{
+		Path: path, Format: format, Prior: prior,
+		index:   func(r T) int { i, _, _ := key(r); return i },
+		failed:  map[int]string{},
+		pubkeys: map[string]bool{},
+	}
+	for _, r := range prior {
+		i, pk, failed := key(r)
+		if !failed {
+			continue
+		}
+		if pk != "" {
+			pk = hexutil.Normalize(pk)
+			s.pubkeys[pk] = true
+		}
+		s.failed[i] = pk
+	}
+	return s, nil
+}
+
+// Len 失败条目数
+func (s *RetrySet[T]) Len() int { return len(s.failed) }
+
+// Wants 输入中下标 index、公钥 pubkey 的条目是否需要重跑：该下标有失败记录且公钥一致（记录或输入没有公钥时只看下标），
+// 或公钥出现在失败记录中（输入文件调整过顺序）
+func (s *RetrySet[T]) Wants(index int, pubkey string) bool {
+	if pubkey != "" {
+		pubkey = hexutil.Normalize(pubkey)
+	}
+	if pk, ok := s.failed[index]; ok && (pk == "" || pubkey == "" || pk == pubkey) {
+		return true
+	}
+	return pubkey != "" && s.pubkeys[pubkey]
+}
+
+// Merge 用本次的记录替换上一次中同一下标的记录，其余保持原样与顺序；上一次没有的下标追加在末尾
+func (s *RetrySet[T]) Merge(records []T) []T {
+	updated := make(map[int]T, len(records))
+	for _, r := range records {
+		updated[s.index(r)] = r
+	}
+	out := make([]T, 0, len(s.Prior)+len(records))
+	for _, r := range s.Prior {
+		i := s.index(r)
+		if u, ok := updated[i]; ok {
+			r = u
+			delete(updated, i)
+		}
+		out = append(out, r)
+	}
+	for _, r := range records {
+		if _, ok := updated[s.index(r)]; ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,365 @@
+// 通用 JSON-RPC 客户端。HTTP：单个调用、批量调用、原始响应（供一致性检查）与流式响应体（供大结果逐 token 解析），
+// 瞬时错误按策略重试，每次往返可通过 Hook 上报方法、耗时与错误，供日志、指标与故障切换使用。
+// WS（见 ws.go）：按 id 关联请求与响应、多路订阅、心跳与断线重连。
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"n42-test/internal/rpcpool"
+)
+
+const DefaultTimeout = 15 * time.Second
+
+// Error 节点返回的 JSON-RPC error 对象
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message) }
+
+// HTTPError 非 2xx 的 HTTP 响应
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string { return fmt.Sprintf("http status %d: %s", e.StatusCode, e.Body) }
+
+// RetryPolicy 传输层错误（连接失败、超时、HTTP 429/5xx）的重试策略；节点返回的 JSON-RPC error 不重试。零值不重试
+type RetryPolicy struct {
+	MaxAttempts int           // 总尝试次数（含首次）；<=1 不重试
+	Backoff     time.Duration // 首次重试前的等待，之后每次翻倍
+}
+
+// Trace 一次 HTTP 往返
+type Trace struct {
+	Endpoint string
+	Method   string // 批量调用为第一个方法名
+	Batch    int    // 批量调用的请求数；单个调用为 0
+	Attempt  int    // 第几次尝试（从 1 开始）
+	Elapsed  time.Duration
+	Err      error
+}
+
+// Options 客户端选项，零值可用
+type Options struct {
+	Timeout    time.Duration // 单次往返超时（流式调用不受限，只受 ctx 控制）；0 表示 15s
+	Retry      RetryPolicy
+	Hook       func(Trace)  // 每次往返结束时调用（含重试），可为 nil
+	HTTPClient *http.Client // 为 nil 时按 Timeout 新建
+}
+
+type Client struct {
+	endpoint string
+	http     *http.Client
+	retry    RetryPolicy
+	hook     func(Trace)
+	id       atomic.Int64
+}
+
+// New endpoint 可为逗号分隔的多个 HTTP 地址：此时请求经由 rpcpool 的共享池轮询与故障切换
+// （opts.HTTPClient 非 nil 时不使用池）
+func New(endpoint string, opts Options) *Client {
+	hc := opts.HTTPClient
+	if hc == nil {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		hc = &http.Client{Timeout: timeout}
+		if rpcpool.IsList(endpoint) {
+			if p, err := rpcpool.For(endpoint); err == nil {
+				endpoint, hc = p.Primary(), p.HTTPClient(timeout)
+			}
+		}
+	}
+	return &Client{endpoint: endpoint, http: hc, retry: opts.Retry, hook: opts.Hook}
+}
+
+// Endpoint 请求发往的地址
+func (c *Client) Endpoint() string { return c.endpoint }
+
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+func (c *Client) newRequest(method string, params any) request {
+	return newRequest(c.id.Add(1), method, params)
+}
+
+func newRequest(id int64, method string, params any) request {
+	if p, ok := params.([]any); params == nil || ok && p == nil {
+		params = []any{}
+	}
+	return request{JSONRPC: "2.0", ID: id, Method: method, Params: params}
+}
+
+// Response 未加工的响应信封；Has* 区分字段缺失与显式 null
+type Response struct {
+	RequestID  int64 // 本次请求使用的 id
+	StatusCode int   // HTTP 状态码
+
+	JSONRPC   json.RawMessage
+	ID        json.RawMessage
+	Result    json.RawMessage
+	Error     *Error // error 字段不是合法的 error 对象时为 nil，原文见 ErrorRaw
+	ErrorRaw  json.RawMessage
+	HasResult bool
+	HasError  bool
+}
+
+func (r *Response) UnmarshalJSON(b []byte) error {
+	var env map[string]json.RawMessage
+	if err := json.Unmarshal(b, &env); err != nil {
+		return err
+	}
+	r.JSONRPC, r.ID = env["jsonrpc"], env["id"]
+	r.Result, r.HasResult = env["result"]
+	if r.ErrorRaw, r.HasError = env["error"]; r.HasError && string(r.ErrorRaw) != "null" {
+		var e Error
+		if json.Unmarshal(r.ErrorRaw, &e) == nil {
+			r.Error = &e
+		}
+	}
+	return nil
+}
+
+// Call 调用 method 并把 result 解码到 result（可为 nil）；result 为 null 时 result 保持零值（json.RawMessage 得到 "null"）
+func (c *Client) Call(ctx context.Context, result any, method string, params ...any) error {
+	resp, err := c.roundTrip(ctx, method, 0, c.newRequest(method, params))
+	if err != nil {
+		return err
+	}
+	var r Response
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return fmt.Errorf("decode rpc response: %w", err)
+	}
+	return r.decode(result)
+}
+
+// decode 把 error 对象转成 Go 错误，否则把 result 解码到 result（可为 nil）
+func (r *Response) decode(result any) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.HasError && string(r.ErrorRaw) != "null" {
+		return fmt.Errorf("malformed rpc error: %s", abbrev(r.ErrorRaw))
+	}
+	if result == nil || len(r.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Result, result); err != nil {
+		return fmt.Errorf("unmarshal result: %w; raw=%s", err, abbrev(r.Result))
+	}
+	return nil
+}
+
+// CallRaw 发送请求并原样返回响应信封，不检查 HTTP 状态、也不把 error 对象转成 Go 错误；
+// params 原样作为 params 字段（nil 发送空数组）。用于需要检查响应外形的场景（一致性测试）。
+func (c *Client) CallRaw(ctx context.Context, method string, params any) (*Response, error) {
+	req := c.newRequest(method, params)
+	status, raw, err := c.do(ctx, method, 0, req)
+	if err != nil {
+		return nil, err
+	}
+	r := &Response{}
+	if err := json.Unmarshal(raw, r); err != nil {
+		return nil, fmt.Errorf("响应不是 JSON 对象（http %d）: %s", status, abbrev(raw))
+	}
+	r.RequestID, r.StatusCode = req.ID, status
+	return r, nil
+}
+
+// BatchElem 批量调用中的一个请求；Error 为该请求自身的错误（节点 error 或解码失败）
+type BatchElem struct {
+	Method string
+	Params []any
+	Result any
+	Error  error
+}
+
+// BatchCall 一次 HTTP 往返发送全部请求；返回值只表示整批的传输错误，单个请求的错误见 BatchElem.Error
+func (c *Client) BatchCall(ctx context.Context, b []BatchElem) error {
+	if len(b) == 0 {
+		return nil
+	}
+	reqs := make([]request, len(b))
+	byID := make(map[int64]int, len(b))
+	for i, e := range b {
+		reqs[i] = c.newRequest(e.Method, e.Params)
+		byID[reqs[i].ID] = i
+	}
+	raw, err := c.roundTrip(ctx, b[0].Method, len(b), reqs)
+	if err != nil {
+		return err
+	}
+	var resps []Response
+	if err := json.Unmarshal(raw, &resps); err != nil {
+		return fmt.Errorf("decode batch response: %w", err)
+	}
+	seen := make([]bool, len(b))
+	for _, r := range resps {
+		var id int64
+		if json.Unmarshal(r.ID, &id) != nil {
+			continue
+		}
+		i, ok := byID[id]
+		if !ok {
+			continue
+		}
+		seen[i] = true
+		switch {
+		case r.Error != nil:
+			b[i].Error = r.Error
+		case b[i].Result != nil && len(r.Result) > 0:
+			if err := json.Unmarshal(r.Result, b[i].Result); err != nil {
+				b[i].Error = fmt.Errorf("unmarshal result: %w", err)
+			}
+		}
+	}
+	for i := range b {
+		if !seen[i] {
+			b[i].Error = errors.New("missing response in batch")
+		}
+	}
+	return nil
+}
+
+// Stream 发送请求并返回响应体，由调用方流式解析整个信封；不受 Timeout 限制（大结果传输可能很久），只受 ctx 控制。
+// 调用方负责关闭。非 2xx 返回 *HTTPError；不做重试。
+func (c *Client) Stream(ctx context.Context, method string, params ...any) (io.ReadCloser, error) {
+	body, err := json.Marshal(c.newRequest(method, params))
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	hc := *c.http
+	hc.Timeout = 0
+	began := time.Now()
+	resp, err := hc.Do(req)
+	if err == nil && resp.StatusCode/100 != 2 {
+		raw, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		err = &HTTPError{StatusCode: resp.StatusCode, Body: string(raw)}
+	} else if err != nil {
+		err = fmt.Errorf("do http request: %w", err)
+	}
+	c.trace(Trace{Method: method, Attempt: 1, Elapsed: time.Since(began), Err: err})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// roundTrip 发送 payload 并返回 2xx 响应体，非 2xx 返回 *HTTPError
+func (c *Client) roundTrip(ctx context.Context, method string, batch int, payload any) ([]byte, error) {
+	status, raw, err := c.do(ctx, method, batch, payload)
+	if err != nil {
+		return nil, err
+	}
+	if status/100 != 2 {
+		return nil, &HTTPError{StatusCode: status, Body: abbrev(raw)}
+	}
+	return raw, nil
+}
+
+// do 带重试的 HTTP 往返，返回最后一次的状态码与响应体；传输错误与 429、5xx 按策略重试
+func (c *Client) do(ctx context.Context, method string, batch int, payload any) (int, []byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("marshal request: %w", err)
+	}
+	attempts := max(c.retry.MaxAttempts, 1)
+	backoff := c.retry.Backoff
+	for attempt := 1; ; attempt++ {
+		began := time.Now()
+		status, raw, err := c.post(ctx, body)
+		terr := err
+		if terr == nil && status/100 != 2 {
+			terr = &HTTPError{StatusCode: status, Body: abbrev(raw)}
+		}
+		c.trace(Trace{Method: method, Batch: batch, Attempt: attempt, Elapsed: time.Since(began), Err: terr})
+		retryable := err != nil && isTransient(err) || status == http.StatusTooManyRequests || status >= 500
+		if !retryable || attempt >= attempts {
+			return status, raw, err
+		}
+		select {
+		case <-ctx.Done():
+			return status, raw, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) post(ctx context.Context, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("build http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("do http request: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("read http response: %w", err)
+	}
+	return resp.StatusCode, raw, nil
+}
+
+func (c *Client) trace(t Trace) {
+	if c.hook == nil {
+		return
+	}
+	t.Endpoint = c.endpoint
+	c.hook(t)
+}
+
+// isTransient 网络层的瞬时错误（ctx 取消不算）
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "connection reset") || strings.Contains(msg, "connection refused") || strings.Contains(msg, "eof")
+}
+
+func abbrev(b []byte) string {
+	const max = 200
+	if len(b) > max {
+		return string(b[:max]) + "…"
+	}
+	return string(b)
+}
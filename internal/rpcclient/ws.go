@@ -0,0 +1,469 @@
+package rpcclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	DefaultPingInterval = 15 * time.Second
+	DefaultSubBuffer    = 128
+)
+
+var (
+	ErrNotConnected        = errors.New("ws not connected")
+	ErrClientClosed        = errors.New("ws client closed")
+	ErrSubscriptionOverrun = errors.New("subscription buffer full")
+)
+
+// WSOptions WS 客户端选项，零值可用
+type WSOptions struct {
+	PingInterval time.Duration // 心跳间隔，两个间隔内收不到任何帧（含 pong）视为断线；0 表示 15s，<0 关闭心跳
+	Reconnect    RetryPolicy   // 断线后的重连策略（每次断线重新计数）；零值不重连，断线即结束全部订阅
+	SubBuffer    int           // 每个订阅的通知缓冲，写满时结束该订阅；0 表示 128
+	Hook         func(Trace)   // 每次调用结束时调用，可为 nil
+	OnReconnect  func(error)   // 每次重连结束时调用：成功为 nil，放弃时为最后一次错误；可为 nil
+}
+
+// WSClient WS 上的 JSON-RPC 客户端：并发调用按 id 关联响应，订阅在重连后自动重新建立
+type WSClient struct {
+	url  string
+	opts WSOptions
+	id   atomic.Int64
+
+	writeMu sync.Mutex // gorilla 连接同一时刻只允许一个写者
+
+	mu      sync.Mutex
+	conn    *wsConn // 断线重连期间为 nil
+	pending map[int64]*pendingCall
+	subs    map[string]*Subscription // 键为节点返回的订阅 id
+	closed  bool
+	done    chan struct{}
+}
+
+type wsConn struct {
+	*websocket.Conn
+	gone chan struct{}
+}
+
+type pendingCall struct {
+	ch  chan callResult
+	sub *Subscription // 订阅请求：收到响应时在读循环内登记，避免首条通知先于登记到达
+}
+
+type callResult struct {
+	resp *Response
+	err  error
+}
+
+// DialWS 建立连接；ctx 只约束首次拨号
+func DialWS(ctx context.Context, url string, opts WSOptions) (*WSClient, error) {
+	if opts.PingInterval == 0 {
+		opts.PingInterval = DefaultPingInterval
+	}
+	if opts.SubBuffer <= 0 {
+		opts.SubBuffer = DefaultSubBuffer
+	}
+	c := &WSClient{
+		url:     url,
+		opts:    opts,
+		pending: map[int64]*pendingCall{},
+		subs:    map[string]*Subscription{},
+		done:    make(chan struct{}),
+	}
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	c.start(conn)
+	return c, nil
+}
+
+// Endpoint 连接的地址
+func (c *WSClient) Endpoint() string { return c.url }
+
+// Close 关闭连接并结束全部订阅（Err 通道关闭而不发送错误）
+func (c *WSClient) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.done)
+	conn := c.conn
+	c.conn = nil
+	subs := c.takeSubs()
+	c.failPending(ErrClientClosed)
+	c.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+	for _, s := range subs {
+		s.end(nil)
+	}
+}
+
+// Call 调用 method 并把 result 解码到 result（可为 nil）；断线期间立即返回 ErrNotConnected
+func (c *WSClient) Call(ctx context.Context, result any, method string, params ...any) error {
+	began := time.Now()
+	resp, err := c.roundTrip(ctx, method, params, nil)
+	if err == nil {
+		err = resp.decode(result)
+	}
+	c.trace(Trace{Method: method, Attempt: 1, Elapsed: time.Since(began), Err: err})
+	return err
+}
+
+func (c *WSClient) roundTrip(ctx context.Context, method string, params []any, sub *Subscription) (*Response, error) {
+	req := newRequest(c.id.Add(1), method, params)
+	pc := &pendingCall{ch: make(chan callResult, 1), sub: sub}
+
+	c.mu.Lock()
+	conn := c.conn
+	switch {
+	case c.closed:
+		c.mu.Unlock()
+		return nil, ErrClientClosed
+	case conn == nil:
+		c.mu.Unlock()
+		return nil, ErrNotConnected
+	}
+	c.pending[req.ID] = pc
+	c.mu.Unlock()
+
+	if err := c.write(conn, req); err != nil {
+		c.dropPending(req.ID)
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		c.dropPending(req.ID)
+		return nil, ctx.Err()
+	case r := <-pc.ch:
+		return r.resp, r.err
+	}
+}
+
+// -------------------- 订阅 --------------------
+
+// Subscription 一个 <namespace>_subscribe 订阅；重连后以相同参数重新订阅，期间的通知会丢失
+type Subscription struct {
+	c         *WSClient
+	namespace string
+	args      []any
+	id        string // 当前连接上的订阅 id，受 c.mu 保护
+	notify    chan json.RawMessage
+	err       chan error
+	once      sync.Once
+	ended     atomic.Bool
+}
+
+// Subscribe 如 Subscribe(ctx, "eth", "newHeads")；通知的 params.result 原样送到 Notifications
+func (c *WSClient) Subscribe(ctx context.Context, namespace string, args ...any) (*Subscription, error) {
+	s := &Subscription{
+		c:         c,
+		namespace: namespace,
+		args:      args,
+		notify:    make(chan json.RawMessage, c.opts.SubBuffer),
+		err:       make(chan error, 1),
+	}
+	if err := c.subscribe(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *WSClient) subscribe(ctx context.Context, s *Subscription) error {
+	method := s.namespace + "_subscribe"
+	began := time.Now()
+	resp, err := c.roundTrip(ctx, method, s.args, s)
+	if err == nil {
+		err = resp.decode(nil)
+	}
+	c.trace(Trace{Method: method, Attempt: 1, Elapsed: time.Since(began), Err: err})
+	if err != nil {
+		// ctx 先于响应结束时读循环可能已登记
+		c.mu.Lock()
+		if s.id != "" && c.subs[s.id] == s {
+			delete(c.subs, s.id)
+		}
+		c.mu.Unlock()
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
+
+// Notifications 通知的 result 字段
+func (s *Subscription) Notifications() <-chan json.RawMessage { return s.notify }
+
+// Err 订阅结束时关闭；因错误结束（重连失败、缓冲写满）时先送出该错误
+func (s *Subscription) Err() <-chan error { return s.err }
+
+// Unsubscribe 结束订阅并尽力通知节点取消
+func (s *Subscription) Unsubscribe() {
+	c := s.c
+	c.mu.Lock()
+	id := s.id
+	if c.subs[id] == s {
+		delete(c.subs, id)
+	}
+	c.mu.Unlock()
+	s.end(nil)
+	if id != "" {
+		c.unsubscribe(s.namespace, id)
+	}
+}
+
+func (c *WSClient) unsubscribe(namespace, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = c.Call(ctx, nil, namespace+"_unsubscribe", id)
+}
+
+func (s *Subscription) end(err error) {
+	s.once.Do(func() {
+		s.ended.Store(true)
+		if err != nil {
+			s.err <- err
+		}
+		close(s.err)
+	})
+}
+
+// -------------------- 连接、读循环与重连 --------------------
+
+func (c *WSClient) dial(ctx context.Context) (*wsConn, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", c.url, err)
+	}
+	return &wsConn{Conn: conn, gone: make(chan struct{})}, nil
+}
+
+func (c *WSClient) start(conn *wsConn) {
+	if c.opts.PingInterval > 0 {
+		conn.SetReadDeadline(time.Now().Add(2 * c.opts.PingInterval))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * c.opts.PingInterval))
+		})
+		go c.pingLoop(conn)
+	}
+	go c.readLoop(conn)
+}
+
+func (c *WSClient) pingLoop(conn *wsConn) {
+	t := time.NewTicker(c.opts.PingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-conn.gone:
+			return
+		case <-t.C:
+			c.writeMu.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.opts.PingInterval))
+			c.writeMu.Unlock()
+			if err != nil {
+				conn.Close() // 读循环随之出错并触发重连
+				return
+			}
+		}
+	}
+}
+
+func (c *WSClient) readLoop(conn *wsConn) {
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			c.lost(conn, err)
+			return
+		}
+		if c.opts.PingInterval > 0 {
+			conn.SetReadDeadline(time.Now().Add(2 * c.opts.PingInterval))
+		}
+		c.dispatch(msg)
+	}
+}
+
+type wsNotification struct {
+	Method string `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+// dispatch 带 id 的是调用响应，带 method 的是订阅通知；其余（批量数组等）忽略
+func (c *WSClient) dispatch(msg []byte) {
+	var r Response
+	if json.Unmarshal(msg, &r) != nil {
+		return
+	}
+	var id int64
+	if len(r.ID) > 0 && json.Unmarshal(r.ID, &id) == nil {
+		c.mu.Lock()
+		pc := c.pending[id]
+		delete(c.pending, id)
+		if pc != nil && pc.sub != nil && r.Error == nil {
+			var subID string
+			if json.Unmarshal(r.Result, &subID) == nil && subID != "" {
+				pc.sub.id = subID
+				c.subs[subID] = pc.sub
+			}
+		}
+		c.mu.Unlock()
+		if pc != nil {
+			pc.ch <- callResult{resp: &r}
+		}
+		return
+	}
+
+	var n wsNotification
+	if json.Unmarshal(msg, &n) != nil || n.Params.Subscription == "" {
+		return
+	}
+	c.mu.Lock()
+	s := c.subs[n.Params.Subscription]
+	c.mu.Unlock()
+	if s == nil {
+		return
+	}
+	select {
+	case s.notify <- n.Params.Result:
+	default:
+		// 消费者跟不上：结束该订阅而不是阻塞读循环拖慢其他订阅与调用
+		c.mu.Lock()
+		delete(c.subs, s.id)
+		c.mu.Unlock()
+		s.end(ErrSubscriptionOverrun)
+		go c.unsubscribe(s.namespace, n.Params.Subscription)
+	}
+}
+
+// lost 连接断开：让挂起的调用失败，按策略重连并重新订阅；不重连或重连失败时结束全部订阅
+func (c *WSClient) lost(conn *wsConn, cause error) {
+	conn.Close()
+	close(conn.gone)
+
+	c.mu.Lock()
+	if c.conn != conn {
+		c.mu.Unlock()
+		return
+	}
+	c.conn = nil
+	c.failPending(fmt.Errorf("ws connection lost: %w", cause))
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	subs := c.takeSubs()
+	c.mu.Unlock()
+	if c.opts.Reconnect.MaxAttempts <= 0 {
+		for _, s := range subs {
+			s.end(fmt.Errorf("ws connection lost: %w", cause))
+		}
+		return
+	}
+	go c.reconnect(subs, cause)
+}
+
+func (c *WSClient) reconnect(subs []*Subscription, cause error) {
+	err := cause
+	backoff := c.opts.Reconnect.Backoff
+	for attempt := 1; attempt <= c.opts.Reconnect.MaxAttempts; attempt++ {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+		var conn *wsConn
+		conn, err = c.dial(ctx)
+		cancel()
+		if err != nil {
+			continue
+		}
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			conn.Close()
+			return
+		}
+		c.conn = conn
+		c.mu.Unlock()
+		c.start(conn)
+
+		for _, s := range subs {
+			if s.ended.Load() { // 重连期间已 Unsubscribe
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+			if err := c.subscribe(ctx, s); err != nil {
+				s.end(fmt.Errorf("resubscribe after reconnect: %w", err))
+			}
+			cancel()
+		}
+		if c.opts.OnReconnect != nil {
+			c.opts.OnReconnect(nil)
+		}
+		return
+	}
+	for _, s := range subs {
+		s.end(fmt.Errorf("ws reconnect failed: %w", err))
+	}
+	if c.opts.OnReconnect != nil {
+		c.opts.OnReconnect(err)
+	}
+}
+
+// takeSubs 取出并清空订阅表；调用方持有 c.mu
+func (c *WSClient) takeSubs() []*Subscription {
+	subs := make([]*Subscription, 0, len(c.subs))
+	for id, s := range c.subs {
+		subs = append(subs, s)
+		delete(c.subs, id)
+	}
+	return subs
+}
+
+// failPending 让全部挂起的调用以 err 失败；调用方持有 c.mu
+func (c *WSClient) failPending(err error) {
+	for id, pc := range c.pending {
+		pc.ch <- callResult{err: err}
+		delete(c.pending, id)
+	}
+}
+
+func (c *WSClient) dropPending(id int64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *WSClient) write(conn *wsConn, v any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := conn.WriteJSON(v); err != nil {
+		return fmt.Errorf("ws write: %w", err)
+	}
+	return nil
+}
+
+func (c *WSClient) trace(t Trace) {
+	if c.opts.Hook == nil {
+		return
+	}
+	t.Endpoint = c.url
+	c.opts.Hook(t)
+}
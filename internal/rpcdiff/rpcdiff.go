@@ -0,0 +1,414 @@
+// 执行层 JSON-RPC 差异对比：对两个节点（如 N42 与 geth）在同一区块区间发出相同的 eth_* 查询
+// （区块含完整交易、回执、日志），归一化后逐字段比较，报告哪些字段不一致或缺失——
+// 这些差异往往就是 attest、beaconext 等代码在某个节点上出错的原因。
+package rpcdiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"n42-test/internal/rpcclient"
+)
+
+// 对比的查询种类
+const (
+	KindBlock    = "block"
+	KindReceipts = "receipts"
+	KindLogs     = "logs"
+)
+
+// 数量类字段：十六进制数值，比较时去掉前导零（0x01 与 0x1 视为相同）；其余十六进制串只统一小写
+var quantityFields = map[string]bool{
+	"number": true, "gasLimit": true, "gasUsed": true, "timestamp": true, "baseFeePerGas": true,
+	"difficulty": true, "totalDifficulty": true, "size": true, "blobGasUsed": true, "excessBlobGas": true,
+	"blockNumber": true, "transactionIndex": true, "logIndex": true, "cumulativeGasUsed": true,
+	"effectiveGasPrice": true, "status": true, "type": true, "blobGasPrice": true,
+	"value": true, "gas": true, "gasPrice": true, "maxFeePerGas": true, "maxPriorityFeePerGas": true,
+	"maxFeePerBlobGas": true, "chainId": true, "v": true, "r": true, "s": true, "yParity": true,
+}
+
+// 交易对象里的 nonce 是数量（区块的 nonce 是 8 字节数据）
+var txQuantityFields = map[string]bool{"nonce": true}
+
+// Options 对比范围与选项
+type Options struct {
+	A, B     string // 两个 RPC endpoint
+	From, To uint64 // 区块区间（闭区间）
+	Receipts bool
+	Logs     bool
+	Ignore   []string // 忽略的字段：字段名（如 totalDifficulty）或去掉下标的路径（如 block.transactions.yParity）
+	Workers  int
+}
+
+// Divergence 一处字段级差异
+type Divergence struct {
+	Block uint64 `json:"block"`
+	Kind  string `json:"kind"`
+	Path  string `json:"path"`            // 如 block.transactions[3].yParity
+	A     string `json:"a"`               // A 侧的值（缺失为 <missing>）
+	B     string `json:"b"`               // B 侧的值
+	Error string `json:"error,omitempty"` // 查询失败（一侧或两侧）
+}
+
+// Report 对比结果
+type Report struct {
+	A       string       `json:"a"`
+	B       string       `json:"b"`
+	From    uint64       `json:"from"`
+	To      uint64       `json:"to"`
+	Queries int          `json:"queries"`
+	Diffs   []Divergence `json:"divergences"`
+}
+
+// ByField 按去掉下标的路径聚合：每个字段的差异次数，按次数降序
+func (r *Report) ByField() []FieldCount {
+	m := map[string]int{}
+	for _, d := range r.Diffs {
+		m[stripIndex(d.Path)]++
+	}
+	out := make([]FieldCount, 0, len(m))
+	for p, n := range m {
+		out = append(out, FieldCount{Path: p, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+// FieldCount 某字段的差异次数
+type FieldCount struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+// Print 打印按字段聚合的差异与前 examples 条示例
+func (r *Report) Print(w io.Writer, examples int) {
+	fmt.Fprintf(w, "A=%s\nB=%s\n区块 %d..%d，查询 %d 次，差异 %d 处\n", r.A, r.B, r.From, r.To, r.Queries, len(r.Diffs))
+	if len(r.Diffs) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n按字段：")
+	for _, fc := range r.ByField() {
+		fmt.Fprintf(w, "  %6d  %s\n", fc.Count, fc.Path)
+	}
+	fmt.Fprintln(w, "\n示例：")
+	for i, d := range r.Diffs {
+		if i >= examples {
+			fmt.Fprintf(w, "  … 另有 %d 处\n", len(r.Diffs)-examples)
+			break
+		}
+		if d.Error != "" {
+			fmt.Fprintf(w, "  #%d %s: %s\n", d.Block, d.Kind, d.Error)
+			continue
+		}
+		fmt.Fprintf(w, "  #%d %s\n      A: %s\n      B: %s\n", d.Block, d.Path, d.A, d.B)
+	}
+}
+
+// Run 对区间内每个区块执行查询并对比
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	a := rpcclient.New(opts.A, rpcclient.Options{})
+	b := rpcclient.New(opts.B, rpcclient.Options{})
+
+	ignore := map[string]bool{}
+	for _, s := range opts.Ignore {
+		if s = strings.TrimSpace(s); s != "" {
+			ignore[s] = true
+		}
+	}
+	workers := max(opts.Workers, 1)
+
+	rep := &Report{A: opts.A, B: opts.B, From: opts.From, To: opts.To}
+	var mu sync.Mutex
+	blocks := make(chan uint64)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range blocks {
+				q, diffs := compareBlock(ctx, a, b, n, opts, ignore)
+				mu.Lock()
+				rep.Queries += q
+				rep.Diffs = append(rep.Diffs, diffs...)
+				mu.Unlock()
+			}
+		}()
+	}
+	for n := opts.From; n <= opts.To && ctx.Err() == nil; n++ {
+		blocks <- n
+	}
+	close(blocks)
+	wg.Wait()
+	sort.SliceStable(rep.Diffs, func(i, j int) bool { return rep.Diffs[i].Block < rep.Diffs[j].Block })
+	return rep, ctx.Err()
+}
+
+// Head 两个节点中较低的最新块高（区间默认以它为终点）
+func Head(ctx context.Context, endpoints ...string) (uint64, error) {
+	var head uint64
+	for i, ep := range endpoints {
+		var n hexutil.Uint64
+		if err := rpcclient.New(ep, rpcclient.Options{}).Call(ctx, &n, "eth_blockNumber"); err != nil {
+			return 0, fmt.Errorf("%s: eth_blockNumber: %w", ep, err)
+		}
+		if i == 0 || uint64(n) < head {
+			head = uint64(n)
+		}
+	}
+	return head, nil
+}
+
+// compareBlock 对一个区块执行全部查询，返回查询次数与差异
+func compareBlock(ctx context.Context, a, b *rpcclient.Client, n uint64, opts Options, ignore map[string]bool) (int, []Divergence) {
+	var diffs []Divergence
+	queries := 0
+	num := hexutil.EncodeUint64(n)
+
+	cmp := func(kind string, fetch func(c *rpcclient.Client) (json.RawMessage, error)) any {
+		queries++
+		ra, errA := fetch(a)
+		rb, errB := fetch(b)
+		if errA != nil || errB != nil {
+			diffs = append(diffs, Divergence{Block: n, Kind: kind, Path: kind, Error: fmt.Sprintf("A: %v; B: %v", errA, errB)})
+			return nil
+		}
+		va, errA := decode(ra)
+		vb, errB := decode(rb)
+		if errA != nil || errB != nil {
+			diffs = append(diffs, Divergence{Block: n, Kind: kind, Path: kind, Error: fmt.Sprintf("decode A: %v; B: %v", errA, errB)})
+			return nil
+		}
+		d := &differ{block: n, kind: kind, ignore: ignore}
+		d.walk(kind, "", normalize(va, "", false), normalize(vb, "", false))
+		diffs = append(diffs, d.out...)
+		return va
+	}
+
+	blk := cmp(KindBlock, func(c *rpcclient.Client) (json.RawMessage, error) {
+		var raw json.RawMessage
+		err := c.Call(ctx, &raw, "eth_getBlockByNumber", num, true)
+		return raw, err
+	})
+	if opts.Receipts {
+		hashes := txHashes(blk)
+		cmp(KindReceipts, func(c *rpcclient.Client) (json.RawMessage, error) {
+			return blockReceipts(ctx, c, num, hashes)
+		})
+	}
+	if opts.Logs {
+		cmp(KindLogs, func(c *rpcclient.Client) (json.RawMessage, error) {
+			var raw json.RawMessage
+			err := c.Call(ctx, &raw, "eth_getLogs", map[string]any{"fromBlock": num, "toBlock": num})
+			return raw, err
+		})
+	}
+	return queries, diffs
+}
+
+// blockReceipts eth_getBlockReceipts；节点不支持时按交易批量取回执拼成数组
+func blockReceipts(ctx context.Context, c *rpcclient.Client, num string, hashes []string) (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := c.Call(ctx, &raw, "eth_getBlockReceipts", num)
+	if err == nil {
+		return raw, nil
+	}
+	if len(hashes) == 0 {
+		return json.RawMessage("[]"), nil
+	}
+	out := make([]json.RawMessage, len(hashes))
+	batch := make([]rpcclient.BatchElem, len(hashes))
+	for i, h := range hashes {
+		batch[i] = rpcclient.BatchElem{Method: "eth_getTransactionReceipt", Params: []any{h}, Result: &out[i]}
+	}
+	if err := c.BatchCall(ctx, batch); err != nil {
+		return nil, fmt.Errorf("eth_getTransactionReceipt batch: %w", err)
+	}
+	for i, e := range batch {
+		if e.Error != nil {
+			return nil, fmt.Errorf("eth_getTransactionReceipt %s: %w", hashes[i], e.Error)
+		}
+	}
+	return json.Marshal(out)
+}
+
+func txHashes(blk any) []string {
+	m, ok := blk.(map[string]any)
+	if !ok {
+		return nil
+	}
+	txs, _ := m["transactions"].([]any)
+	var out []string
+	for _, tx := range txs {
+		switch t := tx.(type) {
+		case string:
+			out = append(out, t)
+		case map[string]any:
+			if h, ok := t["hash"].(string); ok {
+				out = append(out, h)
+			}
+		}
+	}
+	return out
+}
+
+// -------------------- 归一化与比较 --------------------
+
+func decode(raw json.RawMessage) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	return v, err
+}
+
+var hexRe = regexp.MustCompile(`^0[xX][0-9a-fA-F]*$`)
+
+// normalize 十六进制串统一小写，数量类字段去掉前导零；inTx 表示处于交易对象内
+func normalize(v any, key string, inTx bool) any {
+	switch t := v.(type) {
+	case map[string]any:
+		tx := inTx || key == "transactions"
+		out := make(map[string]any, len(t))
+		for k, e := range t {
+			out[k] = normalize(e, k, tx)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, e := range t {
+			out[i] = normalize(e, key, inTx)
+		}
+		return out
+	case string:
+		if !hexRe.MatchString(t) {
+			return t
+		}
+		s := strings.ToLower(t)
+		if quantityFields[key] || inTx && txQuantityFields[key] {
+			if s = strings.TrimLeft(s[2:], "0"); s == "" {
+				s = "0"
+			}
+			s = "0x" + s
+		}
+		return s
+	}
+	return v
+}
+
+type differ struct {
+	block  uint64
+	kind   string
+	ignore map[string]bool
+	out    []Divergence
+}
+
+const missing = "<missing>"
+
+func (d *differ) skip(path, key string) bool {
+	return d.ignore[key] || d.ignore[stripIndex(path)]
+}
+
+func (d *differ) add(path string, a, b any) {
+	d.out = append(d.out, Divergence{Block: d.block, Kind: d.kind, Path: path, A: show(a), B: show(b)})
+}
+
+func (d *differ) walk(path, key string, a, b any) {
+	if d.skip(path, key) {
+		return
+	}
+	switch ta := a.(type) {
+	case map[string]any:
+		tb, ok := b.(map[string]any)
+		if !ok {
+			d.add(path, a, b)
+			return
+		}
+		keys := map[string]bool{}
+		for k := range ta {
+			keys[k] = true
+		}
+		for k := range tb {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			va, okA := ta[k]
+			vb, okB := tb[k]
+			p := path + "." + k
+			switch {
+			case d.skip(p, k):
+			case !okA:
+				d.add(p, missing, vb)
+			case !okB:
+				d.add(p, va, missing)
+			default:
+				d.walk(p, k, va, vb)
+			}
+		}
+	case []any:
+		tb, ok := b.([]any)
+		if !ok {
+			d.add(path, a, b)
+			return
+		}
+		if len(ta) != len(tb) {
+			d.add(path+".length", len(ta), len(tb))
+		}
+		for i := 0; i < min(len(ta), len(tb)); i++ {
+			d.walk(fmt.Sprintf("%s[%d]", path, i), key, ta[i], tb[i])
+		}
+	default:
+		if !equalScalar(a, b) {
+			d.add(path, a, b)
+		}
+	}
+}
+
+func equalScalar(a, b any) bool {
+	if na, ok := a.(json.Number); ok {
+		nb, ok := b.(json.Number)
+		return ok && na == nb
+	}
+	return a == b
+}
+
+func show(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case nil:
+		return "null"
+	case map[string]any, []any:
+		b, _ := json.Marshal(t)
+		if len(b) > 120 {
+			return string(b[:120]) + "…"
+		}
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+var indexRe = regexp.MustCompile(`\[\d+\]`)
+
+// stripIndex 去掉路径中的数组下标：block.transactions[3].v → block.transactions.v
+func stripIndex(path string) string {
+	return indexRe.ReplaceAllString(path, "")
+}
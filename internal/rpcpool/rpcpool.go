@@ -0,0 +1,319 @@
+// 多节点 RPC 池：--rpc 可以是逗号分隔的多个 HTTP endpoint。池定期用 eth_blockNumber 检查各节点，
+// 出错或落后最高块超过 MaxLag 的节点暂时摘除；请求在健康节点间轮询，连接失败、HTTP 429/5xx 时
+// 换下一个节点重发。池实现为 http.RoundTripper，geth 的 rpc/ethclient 与 internal/rpcclient 都可以直接使用。
+//
+// 注意：轮询意味着相邻请求可能落在不同节点上（如刚发出的交易在另一节点上暂时查不到回执），
+// 调用方已有的等待/重试足以覆盖这类短暂不一致。
+package rpcpool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	DefaultMaxLag        = 3
+	DefaultCheckInterval = 5 * time.Second
+	checkTimeout         = 5 * time.Second
+)
+
+// Options 零值可用
+type Options struct {
+	MaxLag        uint64        // 落后最高块超过该值的节点视为不健康；0 表示 3
+	CheckInterval time.Duration // 健康检查间隔（随请求惰性触发）；0 表示 5s
+}
+
+// Pool 一组等价的 HTTP endpoint
+type Pool struct {
+	eps  []*endpoint
+	opts Options
+	base http.RoundTripper
+	rr   atomic.Uint64
+
+	initOnce  sync.Once
+	checking  atomic.Bool
+	lastCheck atomic.Int64 // UnixNano
+}
+
+type endpoint struct {
+	raw     string
+	url     *url.URL
+	healthy atomic.Bool
+	head    atomic.Uint64
+	fails   atomic.Int64 // 累计被摘除次数
+}
+
+// Parse 拆分逗号分隔的 endpoint 列表，去掉空白与空项
+func Parse(spec string) []string {
+	var out []string
+	for _, s := range strings.Split(spec, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// IsList spec 是否包含多个 endpoint
+func IsList(spec string) bool { return len(Parse(spec)) > 1 }
+
+// New 用 endpoints 建池；只支持 http/https
+func New(endpoints []string, opts Options) (*Pool, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("rpcpool: no endpoints")
+	}
+	if opts.MaxLag == 0 {
+		opts.MaxLag = DefaultMaxLag
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = DefaultCheckInterval
+	}
+	p := &Pool{opts: opts, base: http.DefaultTransport}
+	for _, raw := range endpoints {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("rpcpool: parse %q: %w", raw, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("rpcpool: %s: only http/https endpoints can be pooled", raw)
+		}
+		ep := &endpoint{raw: raw, url: u}
+		ep.healthy.Store(true)
+		p.eps = append(p.eps, ep)
+	}
+	return p, nil
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = map[string]*Pool{}
+)
+
+// For 进程内按 spec 共享的池（同一 --rpc 的各个客户端共用健康状态）
+func For(spec string) (*Pool, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if p, ok := shared[spec]; ok {
+		return p, nil
+	}
+	p, err := New(Parse(spec), Options{})
+	if err != nil {
+		return nil, err
+	}
+	shared[spec] = p
+	return p, nil
+}
+
+// Primary 第一个 endpoint；作为客户端的名义地址，实际发往哪个节点由池决定
+func (p *Pool) Primary() string { return p.eps[0].raw }
+
+// HTTPClient 请求经由池转发的 http.Client
+func (p *Pool) HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: p, Timeout: timeout}
+}
+
+// RoundTrip 从轮询位置起依次尝试健康节点（都不健康时也尝试其余节点）；
+// 传输错误与 HTTP 429/5xx 摘除该节点并换下一个，其余响应原样返回
+func (p *Pool) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.initOnce.Do(func() { p.check(context.Background()) })
+	p.maybeCheck()
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	var lastErr error
+	var lastResp *http.Response
+	for _, ep := range p.order() {
+		if lastResp != nil {
+			lastResp.Body.Close()
+			lastResp = nil
+		}
+		out := req.Clone(req.Context())
+		u := *ep.url
+		out.URL, out.Host = &u, ""
+		out.Body = io.NopCloser(bytes.NewReader(body))
+		out.ContentLength = int64(len(body))
+		out.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+
+		resp, err := p.base.RoundTrip(out)
+		switch {
+		case err != nil:
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			p.markDown(ep, err.Error())
+			lastErr = err
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			p.markDown(ep, "http "+resp.Status)
+			lastResp = resp
+		default:
+			return resp, nil
+		}
+	}
+	if lastResp != nil {
+		return lastResp, nil
+	}
+	return nil, fmt.Errorf("rpcpool: all %d endpoints failed: %w", len(p.eps), lastErr)
+}
+
+// order 本次请求的尝试顺序：健康节点从轮询位置起排在前面，不健康的垫后
+func (p *Pool) order() []*endpoint {
+	n := len(p.eps)
+	start := int(p.rr.Add(1)-1) % n
+	healthy := make([]*endpoint, 0, n)
+	var rest []*endpoint
+	for i := 0; i < n; i++ {
+		ep := p.eps[(start+i)%n]
+		if ep.healthy.Load() {
+			healthy = append(healthy, ep)
+		} else {
+			rest = append(rest, ep)
+		}
+	}
+	return append(healthy, rest...)
+}
+
+func (p *Pool) markDown(ep *endpoint, reason string) {
+	if ep.healthy.Swap(false) {
+		ep.fails.Add(1)
+		log.Printf("rpcpool: %s marked down: %s", ep.raw, reason)
+	}
+}
+
+// maybeCheck 距上次检查超过 CheckInterval 时在后台检查一次
+func (p *Pool) maybeCheck() {
+	if time.Since(time.Unix(0, p.lastCheck.Load())) < p.opts.CheckInterval || !p.checking.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer p.checking.Store(false)
+		p.check(context.Background())
+	}()
+}
+
+// check 并发查询各节点块高，按错误与落后程度更新健康状态
+func (p *Pool) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	errs := make([]error, len(p.eps))
+	var wg sync.WaitGroup
+	for i, ep := range p.eps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h, err := p.blockNumber(ctx, ep)
+			if err == nil {
+				ep.head.Store(h)
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	p.lastCheck.Store(time.Now().UnixNano())
+
+	var top uint64
+	for i, ep := range p.eps {
+		if errs[i] == nil {
+			top = max(top, ep.head.Load())
+		}
+	}
+	for i, ep := range p.eps {
+		switch {
+		case errs[i] != nil:
+			p.markDown(ep, errs[i].Error())
+		case top-ep.head.Load() > p.opts.MaxLag:
+			p.markDown(ep, fmt.Sprintf("head %d lags %d blocks behind %d", ep.head.Load(), top-ep.head.Load(), top))
+		default:
+			if !ep.healthy.Swap(true) {
+				log.Printf("rpcpool: %s back up at head %d", ep.raw, ep.head.Load())
+			}
+		}
+	}
+}
+
+func (p *Pool) blockNumber(ctx context.Context, ep *endpoint) (uint64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.raw,
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.base.RoundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("http %s", resp.Status)
+	}
+	var r struct {
+		Result string `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return 0, fmt.Errorf("decode eth_blockNumber: %w", err)
+	}
+	if r.Error != nil {
+		return 0, fmt.Errorf("eth_blockNumber: %s", r.Error.Message)
+	}
+	return strconv.ParseUint(strings.TrimPrefix(r.Result, "0x"), 16, 64)
+}
+
+// Status 各节点当前状态，用于日志
+func (p *Pool) Status() string {
+	parts := make([]string, len(p.eps))
+	for i, ep := range p.eps {
+		state := "up"
+		if !ep.healthy.Load() {
+			state = "down"
+		}
+		parts[i] = fmt.Sprintf("%s=%s(head=%d, downs=%d)", ep.raw, state, ep.head.Load(), ep.fails.Load())
+	}
+	return strings.Join(parts, " ")
+}
+
+// -------------------- 拨号 --------------------
+
+// DialContext 同 rpc.DialContext；spec 含多个 endpoint 时经由共享池
+func DialContext(ctx context.Context, spec string) (*rpc.Client, error) {
+	if !IsList(spec) {
+		return rpc.DialContext(ctx, strings.TrimSpace(spec))
+	}
+	p, err := For(spec)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.DialOptions(ctx, p.Primary(), rpc.WithHTTPClient(p.HTTPClient(0)))
+}
+
+// DialEth 同 ethclient.DialContext；spec 含多个 endpoint 时经由共享池
+func DialEth(ctx context.Context, spec string) (*ethclient.Client, error) {
+	c, err := DialContext(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(c), nil
+}
@@ -0,0 +1,219 @@
+// 每次运行的产物目录：<根>/<run_id>/ 下统一存放运行清单、结果、日志、断点与 RPC 录制，
+// 并发跑多个活动时各自的产物不再互相覆盖。run ID 与运行清单一致（见 internal/manifest）。
+package rundir
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"n42-test/internal/manifest"
+)
+
+const (
+	DefaultRoot = "runs"         // 默认根目录（相对当前目录）
+	EnvRoot     = "N42_RUNS_DIR" // 覆盖默认根目录的环境变量
+
+	ManifestFile = "manifest.json"
+)
+
+// 运行目录下的固定子目录
+const (
+	Results     = "results"     // 结果文件（CSV / JSON 报告等）
+	Logs        = "logs"        // 日志副本
+	Checkpoints = "checkpoints" // 断点续跑状态
+	Traces      = "traces"      // 录制的 RPC 请求/响应
+)
+
+var subdirs = []string{Results, Logs, Checkpoints, Traces}
+
+// DefaultRootDir 环境变量 N42_RUNS_DIR 优先，否则为 ./runs
+func DefaultRootDir() string {
+	if v := os.Getenv(EnvRoot); v != "" {
+		return v
+	}
+	return DefaultRoot
+}
+
+// Run 一次运行的产物目录
+type Run struct {
+	ID   string
+	Tool string
+	Dir  string
+
+	manifest *manifest.Manifest
+	logFile  *os.File
+}
+
+// Create 在 root 下按清单的 run ID 建目录与子目录，并立即写出一份清单
+// （结束前 finished_at 为空，runs list 据此显示为运行中）
+func Create(root string, mf *manifest.Manifest) (*Run, error) {
+	dir := filepath.Join(root, mf.RunID)
+	for _, sub := range subdirs {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("create run dir: %w", err)
+		}
+	}
+	r := &Run{ID: mf.RunID, Tool: mf.Tool, Dir: dir, manifest: mf}
+	if err := r.writeManifest(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Start 供命令行工具使用：root 为空时返回 nil（不建目录）；创建失败只告警，不影响本次运行。
+// 成功时标准 log 同时写入 logs/<tool>.log
+func Start(root string, mf *manifest.Manifest) *Run {
+	if root == "" {
+		return nil
+	}
+	r, err := Create(root, mf)
+	if err != nil {
+		log.Printf("⚠️ 创建运行目录失败: %v", err)
+		return nil
+	}
+	if err := r.TeeLog(); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	log.Printf("📁 运行目录 %s", r.Dir)
+	return r
+}
+
+// Path 运行目录下某类产物的路径，如 Path(Results, "exit-stress.csv")
+func (r *Run) Path(kind, name string) string {
+	return filepath.Join(r.Dir, kind, name)
+}
+
+// TeeLog 把标准 log 输出同时写到 logs/<tool>.log
+func (r *Run) TeeLog() error {
+	f, err := os.OpenFile(r.Path(Logs, r.Tool+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open run log: %w", err)
+	}
+	r.logFile = f
+	log.SetOutput(io.MultiWriter(os.Stderr, f))
+	return nil
+}
+
+// WriteJSON 把 v 以缩进 JSON 写到 <kind>/<name>
+func (r *Run) WriteJSON(kind, name string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", name, err)
+	}
+	return os.WriteFile(r.Path(kind, name), append(b, '\n'), 0o644)
+}
+
+// Finish 写出最终清单（含汇总与结束时间）并关闭日志副本
+func (r *Run) Finish() error {
+	r.manifest.FinishedAt = time.Now().UTC()
+	err := r.writeManifest()
+	if r.logFile != nil {
+		log.SetOutput(os.Stderr)
+		r.logFile.Close()
+		r.logFile = nil
+	}
+	return err
+}
+
+func (r *Run) writeManifest() error {
+	// 中途写出时不能让 Manifest.Write 补上结束时间
+	if r.manifest.FinishedAt.IsZero() {
+		b, err := json.MarshalIndent(r.manifest, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal manifest: %w", err)
+		}
+		return os.WriteFile(filepath.Join(r.Dir, ManifestFile), append(b, '\n'), 0o644)
+	}
+	return r.manifest.Write(filepath.Join(r.Dir, ManifestFile))
+}
+
+// Info 已有运行目录的概要
+type Info struct {
+	ID       string
+	Dir      string
+	Manifest *manifest.Manifest // 清单缺失或损坏时为 nil
+	Size     int64              // 目录总字节数
+}
+
+// Running 清单里没有结束时间（运行中，或进程被中断）
+func (i Info) Running() bool {
+	return i.Manifest != nil && i.Manifest.FinishedAt.IsZero()
+}
+
+// Before 按开始时间排序（同一秒内的 run ID 后缀是随机的，不能只比 ID）
+func (i Info) Before(o Info) bool {
+	if i.Manifest != nil && o.Manifest != nil && !i.Manifest.StartedAt.Equal(o.Manifest.StartedAt) {
+		return i.Manifest.StartedAt.Before(o.Manifest.StartedAt)
+	}
+	return i.ID < o.ID
+}
+
+// List 列出 root 下的所有运行目录，按开始时间升序
+func List(root string) ([]Info, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Info
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		out = append(out, load(filepath.Join(root, e.Name())))
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Before(out[b]) })
+	return out, nil
+}
+
+// Find 按 run ID 或其唯一前缀查找
+func Find(root, id string) (Info, error) {
+	all, err := List(root)
+	if err != nil {
+		return Info{}, err
+	}
+	var hits []Info
+	for _, i := range all {
+		if i.ID == id {
+			return i, nil
+		}
+		if strings.HasPrefix(i.ID, id) {
+			hits = append(hits, i)
+		}
+	}
+	switch len(hits) {
+	case 0:
+		return Info{}, fmt.Errorf("run %q not found under %s", id, root)
+	case 1:
+		return hits[0], nil
+	default:
+		return Info{}, fmt.Errorf("run prefix %q is ambiguous (%d matches)", id, len(hits))
+	}
+}
+
+func load(dir string) Info {
+	info := Info{ID: filepath.Base(dir), Dir: dir}
+	if mf, err := manifest.Load(filepath.Join(dir, ManifestFile)); err == nil {
+		info.Manifest = mf
+	}
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			info.Size += fi.Size()
+		}
+		return nil
+	})
+	return info
+}
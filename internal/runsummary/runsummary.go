@@ -0,0 +1,299 @@
+// 批量运行汇总：deposit-batch / exit-batch / transfer 的各执行模式返回同一个 RunSummary
+// （成功失败数、按类别的错误计数、耗时与吞吐、gas 合计），日志、运行清单与 Pushgateway 都从它取数，
+// 不再各自数一遍结果。
+package runsummary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 错误类别
+const (
+	ClassTimeout     = "timeout"            // 超时（含等待回执超时）
+	ClassCanceled    = "canceled"           // 运行被中断
+	ClassNonce       = "nonce"              // nonce 过低 / 过高 / 已被占用
+	ClassUnderpriced = "underpriced"        // 费用不足、替换交易出价过低
+	ClassFunds       = "insufficient_funds" // 余额不足
+	ClassReverted    = "reverted"           // 上链但 revert，或 eth_call / 估算 gas 时 revert
+	ClassRPC         = "rpc"                // 连接失败、限流等节点侧错误
+	ClassInput       = "bad_input"          // 输入条目有误或未通过预检，未发送（由 BadInput 标记）
+	ClassOther       = "other"
+)
+
+// MaxTopErrors 汇总里保留的高频失败信息条数
+const MaxTopErrors = 10
+
+// inputError 标记输入错误，信息不变
+type inputError struct{ error }
+
+func (e inputError) Unwrap() error { return e.error }
+
+// BadInput 把 err 标记为输入错误（条目字段非法、私钥不配对、预检未通过等，交易未发出），Classify 归为 ClassInput；nil 原样返回
+func BadInput(err error) error {
+	if err == nil {
+		return nil
+	}
+	return inputError{err}
+}
+
+// ErrorCount 一种失败信息的出现次数；Message 为首次出现的原文，同类信息按 normalize 后的文本合并
+type ErrorCount struct {
+	Class   string `json:"class"`
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// RunSummary 一次批量运行的汇总
+type RunSummary struct {
+	Mode    string `json:"mode"`              // sequential | concurrent | pipeline
+	Workers int    `json:"workers,omitempty"` // 并发度（顺序执行为 0）
+
+	Total  int            `json:"total"`
+	OK     int            `json:"ok"`
+	Fail   int            `json:"fail"`
+	Errors map[string]int `json:"errors,omitempty"` // 失败条目按类别计数
+
+	Started    time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration_ns"`
+	Throughput float64       `json:"throughput"` // 每秒完成（成功 + 失败）的条数
+
+	GasUsed    uint64   `json:"gas_used"`
+	GasCostWei *big.Int `json:"gas_cost_wei"` // 已上链交易的 gas 费用合计（不等待回执时为 0）
+
+	TopErrors []ErrorCount `json:"top_errors,omitempty"` // 出现最多的失败信息（Finish 时按次数排序，至多 MaxTopErrors 条）
+
+	messages map[string]*ErrorCount
+}
+
+// New 开始计时
+func New(mode string, workers int) *RunSummary {
+	return &RunSummary{
+		Mode: mode, Workers: workers, Errors: map[string]int{}, Started: time.Now(), GasCostWei: new(big.Int),
+		messages: map[string]*ErrorCount{},
+	}
+}
+
+// Add 记一条结果：err 为 nil 计成功，否则按 Classify 归类计失败；gasCost 可为 nil
+func (s *RunSummary) Add(err error, gasUsed uint64, gasCost *big.Int) {
+	s.Total++
+	if err != nil {
+		class := Classify(err)
+		s.Fail++
+		s.Errors[class]++
+		key := class + "\x00" + normalize(err.Error())
+		if c, ok := s.messages[key]; ok {
+			c.Count++
+		} else {
+			s.messages[key] = &ErrorCount{Class: class, Message: truncate(err.Error(), 300), Count: 1}
+		}
+	} else {
+		s.OK++
+	}
+	s.GasUsed += gasUsed
+	if gasCost != nil {
+		s.GasCostWei.Add(s.GasCostWei, gasCost)
+	}
+}
+
+// Outcome 可计入汇总的单条结果：批量工具各自的 Result 实现
+type Outcome interface {
+	Outcome() (gasUsed uint64, gasCost *big.Int, err error)
+}
+
+// Summarize 把 results 逐条计入 s 并停止计时，返回 s 本身
+func Summarize[T Outcome](s *RunSummary, results []T) *RunSummary {
+	for _, r := range results {
+		gasUsed, gasCost, err := r.Outcome()
+		s.Add(err, gasUsed, gasCost)
+	}
+	return s.Finish()
+}
+
+// Finish 停止计时并计算吞吐，返回 s 本身
+func (s *RunSummary) Finish() *RunSummary {
+	s.Duration = time.Since(s.Started)
+	if sec := s.Duration.Seconds(); sec > 0 {
+		s.Throughput = float64(s.Total) / sec
+	}
+	s.sortErrors()
+	return s
+}
+
+// Amend 一条已按成功计入的结果事后有了结论（如运行结束后补查到回执）：补记 gas，err 非 nil 时改记为失败；
+// 已 Finish 时同步刷新 TopErrors，耗时与吞吐不变
+func (s *RunSummary) Amend(err error, gasUsed uint64, gasCost *big.Int) {
+	s.Total--
+	s.OK--
+	s.Add(err, gasUsed, gasCost)
+	if s.Duration > 0 {
+		s.sortErrors()
+	}
+}
+
+// sortErrors 按次数重建 TopErrors
+func (s *RunSummary) sortErrors() {
+	s.TopErrors = s.TopErrors[:0]
+	for _, c := range s.messages {
+		s.TopErrors = append(s.TopErrors, *c)
+	}
+	sort.Slice(s.TopErrors, func(i, j int) bool {
+		a, b := s.TopErrors[i], s.TopErrors[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Message < b.Message
+	})
+	if len(s.TopErrors) > MaxTopErrors {
+		s.TopErrors = s.TopErrors[:MaxTopErrors]
+	}
+}
+
+// ErrorReport 多行失败分布：每个类别的条数与占比，以及出现最多的失败信息；没有失败时为空
+func (s *RunSummary) ErrorReport() string {
+	if s.Fail == 0 {
+		return ""
+	}
+	classes := make([]string, 0, len(s.Errors))
+	for c := range s.Errors {
+		classes = append(classes, c)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if s.Errors[classes[i]] != s.Errors[classes[j]] {
+			return s.Errors[classes[i]] > s.Errors[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+	var b strings.Builder
+	fmt.Fprintf(&b, "失败分布（共 %d 条）：\n", s.Fail)
+	for _, c := range classes {
+		fmt.Fprintf(&b, "  %-18s %6d  %5.1f%%\n", c, s.Errors[c], float64(s.Errors[c])*100/float64(s.Fail))
+	}
+	b.WriteString("高频失败信息：\n")
+	for _, e := range s.TopErrors {
+		fmt.Fprintf(&b, "  %6d × [%s] %s\n", e.Count, e.Class, e.Message)
+	}
+	if n := len(s.messages) - len(s.TopErrors); n > 0 {
+		fmt.Fprintf(&b, "  ……另有 %d 种失败信息\n", n)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// String 一行中文汇总，用于运行结束时的日志
+func (s *RunSummary) String() string {
+	out := fmt.Sprintf("成功 %d，失败 %d，耗时 %s，%.2f 笔/秒", s.OK, s.Fail, s.Duration.Round(time.Millisecond), s.Throughput)
+	if s.Workers > 0 {
+		out += fmt.Sprintf("，并发度 %d", s.Workers)
+	}
+	if s.GasUsed > 0 {
+		out += fmt.Sprintf("，gas %d", s.GasUsed)
+	}
+	if s.Fail > 0 {
+		out += "；失败类别 " + s.errorList()
+	}
+	return out
+}
+
+func (s *RunSummary) errorList() string {
+	classes := make([]string, 0, len(s.Errors))
+	for c := range s.Errors {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+	parts := make([]string, len(classes))
+	for i, c := range classes {
+		parts[i] = fmt.Sprintf("%s=%d", c, s.Errors[c])
+	}
+	return strings.Join(parts, " ")
+}
+
+// Fields 写入运行清单 summary 的键值：total / ok / fail / gas_used / gas_cost_wei 与此前各工具手写的一致，
+// 另加 mode、send_seconds（发送阶段耗时；Pushgateway 的 duration_seconds 是整个进程的墙钟时间）、
+// throughput、errors_<类别>（平铺为数值，Pushgateway 直接可用）与 top_errors
+func (s *RunSummary) Fields() map[string]any {
+	m := map[string]any{
+		"mode":         s.Mode,
+		"total":        s.Total,
+		"ok":           s.OK,
+		"fail":         s.Fail,
+		"send_seconds": s.Duration.Seconds(),
+		"throughput":   s.Throughput,
+		"gas_used":     s.GasUsed,
+		"gas_cost_wei": s.GasCostWei,
+	}
+	if s.Workers > 0 {
+		m["workers"] = s.Workers
+	}
+	for c, n := range s.Errors {
+		m["errors_"+c] = n
+	}
+	if len(s.TopErrors) > 0 {
+		m["top_errors"] = s.TopErrors
+	}
+	return m
+}
+
+// Classify 按错误链与错误信息把失败归类（节点返回的错误只有文本，按关键字匹配）
+func Classify(err error) string {
+	var ie inputError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &ie):
+		return ClassInput
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	}
+	msg := strings.ToLower(err.Error())
+	has := func(subs ...string) bool {
+		for _, s := range subs {
+			if strings.Contains(msg, s) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("insufficient funds"):
+		return ClassFunds
+	case has("underpriced", "fee cap", "max fee per gas less than", "tip higher than"):
+		return ClassUnderpriced
+	case has("nonce"):
+		return ClassNonce
+	case has("revert", "status=0"):
+		return ClassReverted
+	case has("timeout", "timed out", "deadline exceeded", "超时"):
+		return ClassTimeout
+	case has("connection refused", "connection reset", "no such host", "eof", "429", "too many requests", "503", "dial", "rpc"):
+		return ClassRPC
+	}
+	return ClassOther
+}
+
+var (
+	reHex    = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	reNumber = regexp.MustCompile(`\d+`)
+)
+
+// normalize 去掉哈希、地址与数字（下标、nonce、金额……），只差这些的失败信息合并为一种
+func normalize(msg string) string {
+	msg = reHex.ReplaceAllString(msg, "0x…")
+	return reNumber.ReplaceAllString(msg, "N")
+}
+
+// truncate 截到 n 个字符
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
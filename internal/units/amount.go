@@ -0,0 +1,68 @@
+package units
+
+import "math/big"
+
+// Amount 以 wei 计的金额：存款金额、退出 / 部分提款金额、充值与报表共用，
+// 代替各处 *big.Int wei、uint64 gwei 与浮点 ETH 之间的临时换算。值类型，零值为 0，方法不修改接收者。
+type Amount struct {
+	wei *big.Int
+}
+
+// FromWei 由 wei 构造（复制；nil 为 0）
+func FromWei(wei *big.Int) Amount {
+	if wei == nil {
+		return Amount{}
+	}
+	return Amount{wei: new(big.Int).Set(wei)}
+}
+
+// FromGwei 由 gwei 构造（存款事件、信标状态余额、EIP-7002 amount 的单位）
+func FromGwei(gwei uint64) Amount {
+	return Amount{wei: GweiToWei(gwei)}
+}
+
+// ParseAmount 同 Parse，返回 Amount
+func ParseAmount(s string, def Unit) (Amount, error) {
+	wei, err := Parse(s, def)
+	if err != nil {
+		return Amount{}, err
+	}
+	return Amount{wei: wei}, nil
+}
+
+// Wei 金额（wei）的副本
+func (a Amount) Wei() *big.Int {
+	if a.wei == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(a.wei)
+}
+
+// Gwei 换算为 gwei；不是整 gwei 或超出 uint64 时报错
+func (a Amount) Gwei() (uint64, error) {
+	return ToGwei(a.Wei())
+}
+
+func (a Amount) Sign() int {
+	if a.wei == nil {
+		return 0
+	}
+	return a.wei.Sign()
+}
+
+func (a Amount) IsZero() bool { return a.Sign() == 0 }
+
+func (a Amount) Cmp(b Amount) int { return a.Wei().Cmp(b.Wei()) }
+
+func (a Amount) Add(b Amount) Amount {
+	return Amount{wei: new(big.Int).Add(a.Wei(), b.Wei())}
+}
+
+// ETH 以 ETH 精确输出十进制数（不带单位），如 "32"、"0.000000001"
+func (a Amount) ETH() string { return Decimal(a.Wei(), Ether) }
+
+// Format 以 u 精确输出并带单位，如 Format(Gwei) = "32000000000gwei"
+func (a Amount) Format(u Unit) string { return Format(a.Wei(), u) }
+
+// String 带单位的 ETH 表示，可被 ParseAmount 原样解析
+func (a Amount) String() string { return a.Format(Ether) }
@@ -0,0 +1,143 @@
+// 金额解析：十进制数 + 可选单位后缀（"32eth"、"1000000gwei"、"5wei"），精确换算为 wei。
+// 不经过浮点数：换算不精确（wei 以下的小数、存款金额不足 1 gwei 的尾数）时报错而不是舍入，
+// 命令行给出的金额与实际发送的值逐位一致，便于审计。
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Unit 金额单位：Decimals 为相对 wei 的十进制位数
+type Unit struct {
+	Name     string
+	Decimals int
+}
+
+var (
+	Wei   = Unit{Name: "wei", Decimals: 0}
+	Gwei  = Unit{Name: "gwei", Decimals: 9}
+	Ether = Unit{Name: "eth", Decimals: 18}
+)
+
+func (u Unit) String() string { return u.Name }
+
+// lookupUnit 单位后缀（大小写不敏感）；ether 为 eth 的别名
+func lookupUnit(s string) (Unit, bool) {
+	switch strings.ToLower(s) {
+	case "wei":
+		return Wei, true
+	case "gwei":
+		return Gwei, true
+	case "eth", "ether":
+		return Ether, true
+	}
+	return Unit{}, false
+}
+
+// Parse 解析金额为 wei：十进制数（可小数，可用 _ 分组）后跟可选的单位后缀 eth|ether|gwei|wei
+// （大小写不敏感，数与单位间可有空格），没有后缀时按 def 解释。负数、科学计数法与无法精确换算的小数报错。
+func Parse(s string, def Unit) (*big.Int, error) {
+	raw := strings.TrimSpace(s)
+	num := strings.TrimRight(raw, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	unit := def
+	if suffix := raw[len(num):]; suffix != "" {
+		u, ok := lookupUnit(suffix)
+		if !ok {
+			return nil, fmt.Errorf("amount %q: unknown unit %q (want eth|gwei|wei)", s, suffix)
+		}
+		unit = u
+	}
+	num = strings.ReplaceAll(strings.TrimSpace(num), "_", "")
+	intPart, frac, _ := strings.Cut(num, ".")
+	if intPart == "" && frac == "" || !isDigits(intPart) || !isDigits(frac) {
+		return nil, fmt.Errorf("amount %q: want a non-negative decimal number with optional eth|gwei|wei suffix", s)
+	}
+	// 超出单位精度的小数位只能是 0，否则换算为 wei 会丢精度
+	if len(frac) > unit.Decimals {
+		if strings.Trim(frac[unit.Decimals:], "0") != "" {
+			return nil, fmt.Errorf("amount %q: more than %d decimal places cannot be represented exactly in wei", s, unit.Decimals)
+		}
+		frac = frac[:unit.Decimals]
+	}
+	digits := intPart + frac + strings.Repeat("0", unit.Decimals-len(frac))
+	wei, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("amount %q: invalid number", s)
+	}
+	return wei, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParsePositive 同 ParseAmount，并要求金额 > 0
+func ParsePositive(s string, def Unit) (Amount, error) {
+	a, err := ParseAmount(s, def)
+	if err != nil {
+		return Amount{}, err
+	}
+	if a.IsZero() {
+		return Amount{}, fmt.Errorf("amount %q: must be > 0", s)
+	}
+	return a, nil
+}
+
+// ParseGwei 解析以 gwei 计的金额（存款金额、EIP-7002 amount 等）：须为 1 gwei 的整数倍且不超过 uint64
+func ParseGwei(s string, def Unit) (uint64, error) {
+	wei, err := Parse(s, def)
+	if err != nil {
+		return 0, err
+	}
+	g, err := ToGwei(wei)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q: %w", s, err)
+	}
+	return g, nil
+}
+
+// ToGwei wei 换算为 gwei；不能整除或超出 uint64 时报错
+func ToGwei(wei *big.Int) (uint64, error) {
+	q, r := new(big.Int).QuoRem(wei, big.NewInt(1_000_000_000), new(big.Int))
+	if r.Sign() != 0 {
+		return 0, fmt.Errorf("%s wei is not a whole number of gwei", wei)
+	}
+	if q.Sign() < 0 || !q.IsUint64() {
+		return 0, errors.New("gwei amount out of uint64 range")
+	}
+	return q.Uint64(), nil
+}
+
+// GweiToWei gwei 换算为 wei
+func GweiToWei(gwei uint64) *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(gwei), big.NewInt(1_000_000_000))
+}
+
+// Decimal 以 unit 精确输出十进制数（去掉小数末尾的 0，不带单位），如 Decimal(32.5e18, Ether) = "32.5"
+func Decimal(wei *big.Int, u Unit) string {
+	s := new(big.Int).Abs(wei).String()
+	if len(s) <= u.Decimals {
+		s = strings.Repeat("0", u.Decimals-len(s)+1) + s
+	}
+	intPart, frac := s[:len(s)-u.Decimals], strings.TrimRight(s[len(s)-u.Decimals:], "0")
+	if wei.Sign() < 0 {
+		intPart = "-" + intPart
+	}
+	if frac == "" {
+		return intPart
+	}
+	return intPart + "." + frac
+}
+
+// Format 带单位的精确输出，可被 Parse 原样解析，如 "32.5eth"
+func Format(wei *big.Int, u Unit) string {
+	return Decimal(wei, u) + u.Name
+}
@@ -0,0 +1,226 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"n42-test/internal/blsutil"
+)
+
+// FleetMember 舰队（同一进程内的多个验证者）中的一员
+type FleetMember struct {
+	Name    string // 输出标签；为空时取公钥前缀
+	PrivHex string // 大端 BLS 私钥 hex，作为成员身份
+	WSURL   string
+	HTTPURL string
+}
+
+func (m FleetMember) key() string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(m.PrivHex), "0x"))
+}
+
+// FleetChange 一次 Apply 的结果（成员名）
+type FleetChange struct {
+	Added     []string
+	Removed   []string
+	Restarted []string // 端点或标签变化、或此前已退出而重新启动
+	Unchanged []string // 原样保留，订阅不中断
+}
+
+func (c FleetChange) String() string {
+	return fmt.Sprintf("added=%v removed=%v restarted=%v unchanged=%d",
+		c.Added, c.Removed, c.Restarted, len(c.Unchanged))
+}
+
+// Fleet 每个验证者一个 streamRunner（各自的二进制进程与订阅），由 Apply 增删改。
+// 除 Name 外所有成员共用同一份 StreamConfig；重载只影响成员列表与端点。
+type Fleet struct {
+	cfg StreamConfig
+
+	mu      sync.Mutex
+	running map[string]*fleetRunner // key: FleetMember.key()
+}
+
+type fleetRunner struct {
+	m      FleetMember
+	r      *streamRunner // 配置有误而未能启动时为 nil
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error // 未被停止而自行退出时的错误；done 关闭后有效
+}
+
+func (fr *fleetRunner) exited() bool {
+	select {
+	case <-fr.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewFleet 创建空舰队；成员由 Apply 启动
+func NewFleet(cfg StreamConfig) *Fleet {
+	return &Fleet{cfg: cfg, running: map[string]*fleetRunner{}}
+}
+
+// Apply 把运行中的成员调整为 members：新增的启动，移除的停止，端点或标签变化的只重启该成员，
+// 其余原样保留（不重启二进制，订阅不中断）；此前已退出的成员重新启动。
+// members 有误（私钥为空、重复或无法派生公钥）时不做任何改动并返回错误。
+// 停止成员会等待其二进制退出，避免同一私钥同时有两个进程在提交。
+func (f *Fleet) Apply(ctx context.Context, members []FleetMember) (FleetChange, error) {
+	want := map[string]FleetMember{}
+	var order []string
+	for i, m := range members {
+		k := m.key()
+		if k == "" {
+			return FleetChange{}, fmt.Errorf("fleet member %d (%s): empty private key", i, m.Name)
+		}
+		if prev, dup := want[k]; dup {
+			return FleetChange{}, fmt.Errorf("fleet member %d (%s): same key as %s", i, m.Name, prev.Name)
+		}
+		if m.Name == "" {
+			name, err := defaultMemberName(k)
+			if err != nil {
+				return FleetChange{}, fmt.Errorf("fleet member %d: %w", i, err)
+			}
+			m.Name = name
+		}
+		want[k] = m
+		order = append(order, k)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ch FleetChange
+	for k, fr := range f.running {
+		if _, keep := want[k]; !keep {
+			fr.stop()
+			delete(f.running, k)
+			ch.Removed = append(ch.Removed, fr.m.Name)
+		}
+	}
+	for _, k := range order {
+		m := want[k]
+		fr, ok := f.running[k]
+		switch {
+		case !ok:
+			ch.Added = append(ch.Added, m.Name)
+		case fr.m != m || fr.exited():
+			fr.stop()
+			ch.Restarted = append(ch.Restarted, m.Name)
+		default:
+			ch.Unchanged = append(ch.Unchanged, m.Name)
+			continue
+		}
+		f.running[k] = f.start(ctx, m)
+	}
+	return ch, nil
+}
+
+func (f *Fleet) start(ctx context.Context, m FleetMember) *fleetRunner {
+	cctx, cancel := context.WithCancel(ctx)
+	fr := &fleetRunner{m: m, cancel: cancel, done: make(chan struct{})}
+	cfg := f.cfg
+	cfg.Name = m.Name
+	printTS(fmt.Sprintf("Fleet: starting %s (ws=%s rpc=%s)", m.Name, emptyDash(m.WSURL), emptyDash(m.HTTPURL)))
+	r, err := newStreamRunner(m.PrivHex, m.WSURL, m.HTTPURL, cfg)
+	if err != nil {
+		fr.err = err
+		close(fr.done)
+		printTS(fmt.Sprintf("ALERT: fleet member %s exited: %v (restarted on next reload)", m.Name, err))
+		return fr
+	}
+	fr.r = r
+	go func() {
+		defer close(fr.done)
+		err := r.run(cctx)
+		if err != nil && cctx.Err() == nil {
+			fr.err = err
+			printTS(fmt.Sprintf("ALERT: fleet member %s exited: %v (restarted on next reload)", m.Name, err))
+		}
+	}()
+	return fr
+}
+
+func (fr *fleetRunner) stop() {
+	fr.cancel()
+	<-fr.done
+}
+
+// Stop 停止全部成员并等待退出；返回停止前已自行退出的成员的错误。
+// 停止后 Stats 仍返回各成员的最终统计
+func (f *Fleet) Stop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var errs []error
+	for _, fr := range f.running {
+		fr.stop()
+		if fr.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", fr.m.Name, fr.err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MemberStats 一个成员当前这次运行（重载重启后从零计）的统计
+type MemberStats struct {
+	Name           string `json:"name"`
+	Running        bool   `json:"running"`
+	Received       int64  `json:"received"`
+	Processed      int64  `json:"processed"`
+	Attested       int64  `json:"attested"`
+	MissedDeadline int64  `json:"missed_deadline"`
+	Dropped        int64  `json:"dropped"`
+	Malformed      int64  `json:"malformed"`
+	Duplicate      int64  `json:"duplicate"`
+	DoubleAttest   int64  `json:"double_attest"`
+	Gap            int64  `json:"gap"`
+	Restarts       int64  `json:"restarts"`
+	Err            string `json:"error,omitempty"` // 自行退出时的错误
+}
+
+// Stats 各成员的统计，按名字排列
+func (f *Fleet) Stats() []MemberStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]MemberStats, 0, len(f.running))
+	for _, fr := range f.running {
+		ms := MemberStats{Name: fr.m.Name, Running: !fr.exited()}
+		if s := fr.stats(); s != nil {
+			ms.Received, ms.Processed, ms.Attested = s.received.Load(), s.processed.Load(), s.attested.Load()
+			ms.MissedDeadline, ms.Dropped, ms.Malformed = s.missedDeadline.Load(), s.dropped.Load(), s.malformed.Load()
+			ms.Duplicate, ms.DoubleAttest, ms.Gap = s.duplicate.Load(), s.doubleAttest.Load(), s.gapChecked.Load()
+			ms.Restarts = s.restarts.Load()
+		}
+		if fr.exited() && fr.err != nil {
+			ms.Err = fr.err.Error()
+		}
+		out = append(out, ms)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (fr *fleetRunner) stats() *streamStats {
+	if fr.r == nil {
+		return nil
+	}
+	return fr.r.stats
+}
+
+// defaultMemberName 公钥前缀，作为未命名成员的输出标签（不在输出里暴露私钥）
+func defaultMemberName(privHex string) (string, error) {
+	pk, err := blsutil.DerivePublicKeyHex(privHex, blsutil.DefaultKeyOptions())
+	if err != nil {
+		return "", fmt.Errorf("derive pubkey: %w", err)
+	}
+	pk = strings.TrimPrefix(pk, "0x")
+	if len(pk) > 10 {
+		pk = pk[:10]
+	}
+	return "0x" + pk, nil
+}
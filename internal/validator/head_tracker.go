@@ -0,0 +1,85 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"n42-test/internal/capability"
+	"n42-test/internal/rpcclient"
+)
+
+// headTracker 节点支持 WS 订阅时，用 newHeads 维护链头，
+// 看门狗判断“链是否仍在出块”时就不必反复轮询 HTTP。
+type headTracker struct {
+	head      atomic.Uint64
+	updatedAt atomic.Int64 // UnixNano
+	tag       string       // 输出前缀（舰队模式下的验证者标签）
+}
+
+// track 订阅 newHeads 直到 ctx 结束；WS 断线时自动重连并重新订阅，
+// 节点不支持订阅或重连失败时静默退出（调用方回退到 HTTP）
+func (t *headTracker) track(ctx context.Context, wsURL string) {
+	if !capability.For(ctx, wsURL).HasWSSubscriptions() {
+		return
+	}
+	cli, err := rpcclient.DialWS(ctx, wsURL, rpcclient.WSOptions{
+		Reconnect: rpcclient.RetryPolicy{MaxAttempts: 5, Backoff: time.Second},
+		OnReconnect: func(err error) {
+			if err == nil {
+				printTS(t.tag + "head tracker: ws reconnected, newHeads resubscribed")
+			}
+		},
+	})
+	if err != nil {
+		return
+	}
+	defer cli.Close()
+
+	sub, err := cli.Subscribe(ctx, "eth", "newHeads")
+	if err != nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-sub.Err():
+			if err != nil {
+				printTS(t.tag + fmt.Sprintf("head tracker: subscription ended: %v; falling back to HTTP polling", err))
+			}
+			return
+		case raw := <-sub.Notifications():
+			var h struct {
+				Number *hexutil.Big `json:"number"`
+			}
+			if json.Unmarshal(raw, &h) == nil && h.Number != nil {
+				t.head.Store(h.Number.ToInt().Uint64())
+				t.updatedAt.Store(time.Now().UnixNano())
+			}
+		}
+	}
+}
+
+// latest 返回 maxAge 内更新过的链头；没有时返回 false
+func (t *headTracker) latest(maxAge time.Duration) (uint64, bool) {
+	h := t.head.Load()
+	if h == 0 || time.Since(time.Unix(0, t.updatedAt.Load())) > maxAge {
+		return 0, false
+	}
+	return h, true
+}
+
+// chainHead 优先使用 WS 订阅维护的链头（一个 slot 内新鲜），否则走 HTTP 查询
+func (r *streamRunner) chainHead(ctx context.Context) (uint64, error) {
+	if h, ok := r.heads.latest(r.slot); ok {
+		return h, nil
+	}
+	return getLatestNumber(ctx, r.ethCli)
+}
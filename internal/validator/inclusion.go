@@ -0,0 +1,203 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"n42-test/internal/beaconext"
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/blsutil"
+)
+
+// DefaultInclusionWindow 提交后最多在多少个后续区块里查找参与标记
+const DefaultInclusionWindow = 8
+
+// submission 一次见证提交：二进制对区块 Number 验证成功
+type submission struct {
+	Number uint64
+	At     time.Time
+}
+
+// inclusionStats 见证上链情况
+type inclusionStats struct {
+	checked   atomic.Int64 // 检查过的纪元数
+	included  atomic.Int64 // 在窗口内看到参与标记的纪元数
+	missed    atomic.Int64 // 窗口内未看到参与标记的纪元数
+	skipped   atomic.Int64 // 检查器繁忙被跳过的提交数
+	distTotal atomic.Int64 // 包含距离之和（slot）
+	distMax   atomic.Int64
+}
+
+func (s *inclusionStats) String() string {
+	avg := 0.0
+	if n := s.included.Load(); n > 0 {
+		avg = float64(s.distTotal.Load()) / float64(n)
+	}
+	return fmt.Sprintf("inclusion: checked=%d included=%d missed=%d skipped=%d dist_avg=%.1f dist_max=%d",
+		s.checked.Load(), s.included.Load(), s.missed.Load(), s.skipped.Load(), avg, s.distMax.Load())
+}
+
+// inclusionChecker 在后续区块的信标状态里查找本验证者的参与标记（epoch_attester_indexes），
+// 记录每个纪元第一次提交到被包含的距离（slot），以确认 N42 确实消费了我们的提交。
+// 参与标记按纪元记录，同一纪元内的后续提交不再重复检查。
+type inclusionChecker struct {
+	r      *streamRunner
+	pubkey string
+	index  int // 验证者下标；-1 表示尚未在状态里找到
+	window int
+	subs   chan submission
+	stats  inclusionStats
+
+	lastEpoch uint64
+	hasEpoch  bool
+}
+
+func newInclusionChecker(r *streamRunner) (*inclusionChecker, error) {
+	pk, err := blsutil.DerivePublicKeyHex(r.privHex, blsutil.DefaultKeyOptions())
+	if err != nil {
+		return nil, fmt.Errorf("derive pubkey: %w", err)
+	}
+	window := r.cfg.InclusionWindow
+	if window <= 0 {
+		window = DefaultInclusionWindow
+	}
+	return &inclusionChecker{
+		r:      r,
+		pubkey: beaconstate.NormPubkey(pk),
+		index:  -1,
+		window: window,
+		subs:   make(chan submission, 64),
+	}, nil
+}
+
+// submit 记录一次提交；检查器跟不上时丢弃并计数，不阻塞读取输出
+func (c *inclusionChecker) submit(s submission) {
+	select {
+	case c.subs <- s:
+	default:
+		c.stats.skipped.Add(1)
+	}
+}
+
+func (c *inclusionChecker) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-c.subs:
+			c.check(ctx, s)
+		}
+	}
+}
+
+// stateView 检查所需的状态字段
+type stateView struct {
+	slot      uint64
+	attesters []uint64
+}
+
+// stateAt 读取区块 number 对应信标状态的 slot 与参与标记；下标未知时顺便查找本验证者
+func (c *inclusionChecker) stateAt(ctx context.Context, number uint64) (*stateView, error) {
+	qctx, cancel := context.WithTimeout(ctx, 4*c.r.slot)
+	defer cancel()
+	hash, _, err := c.r.queryEth1HashByNumberWait(qctx, strconv.FormatUint(number, 10))
+	if err != nil {
+		return nil, err
+	}
+	// 下标已知，或已由客户端索引查过（不论找到与否）时不必解码 validators
+	scan := c.index < 0 && !c.lookupIndex(qctx)
+	var st beaconstate.State
+	err = beaconstate.StreamAt(qctx, c.r.ethCli, hash, func(key string, dec *json.Decoder) (bool, error) {
+		switch key {
+		case "slot", "epoch_attester_indexes":
+			return st.DecodeField(key, dec)
+		case "validators":
+			if !scan {
+				return false, nil
+			}
+			return st.DecodeField(key, dec)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if scan {
+		if i, ok := st.Index()[c.pubkey]; ok {
+			c.index = i
+			c.r.printTS(fmt.Sprintf("Inclusion check: validator %s has index %d", c.pubkey, i))
+		}
+	}
+	return &stateView{slot: st.Slot, attesters: st.EpochAttesterIndexes}, nil
+}
+
+// lookupIndex 客户端支持按公钥查下标时用它找本验证者（缓存的索引，不必每次解码 validators）；
+// 返回是否已查过。查询失败或不支持时返回 false，由 stateAt 在状态里查找
+func (c *inclusionChecker) lookupIndex(ctx context.Context) bool {
+	ix, ok := c.r.ethCli.(beaconext.ValidatorIndexer)
+	if !ok {
+		return false
+	}
+	i, found, err := ix.ValidatorIndexByPubkey(ctx, c.pubkey)
+	if err != nil {
+		return false
+	}
+	if found {
+		c.index = i
+		c.r.printTS(fmt.Sprintf("Inclusion check: validator %s has index %d", c.pubkey, i))
+	}
+	return true
+}
+
+func (c *inclusionChecker) check(ctx context.Context, s submission) {
+	base, err := c.stateAt(ctx, s.Number)
+	if err != nil {
+		c.r.printTS(fmt.Sprintf("Inclusion check for block #%d failed: %v", s.Number, err))
+		return
+	}
+	if c.index < 0 {
+		c.r.printTS(fmt.Sprintf("Inclusion check: %s not in validator set yet", c.pubkey))
+		return
+	}
+	spe := c.r.cfg.slotsPerEpoch()
+	epoch := base.slot / spe
+	if c.hasEpoch && epoch == c.lastEpoch {
+		return
+	}
+	c.lastEpoch, c.hasEpoch = epoch, true
+	c.stats.checked.Add(1)
+
+	for n := s.Number + 1; n <= s.Number+uint64(c.window); n++ {
+		st, err := c.stateAt(ctx, n)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.r.printTS(fmt.Sprintf("Inclusion check for block #%d failed: %v", n, err))
+			continue
+		}
+		if st.slot/spe != epoch {
+			break // 参与标记按纪元重置，跨纪元后不再可见
+		}
+		if slices.Contains(st.attesters, uint64(c.index)) {
+			dist := int64(st.slot - base.slot)
+			c.stats.included.Add(1)
+			c.stats.distTotal.Add(dist)
+			for {
+				cur := c.stats.distMax.Load()
+				if dist <= cur || c.stats.distMax.CompareAndSwap(cur, dist) {
+					break
+				}
+			}
+			c.r.printTS(fmt.Sprintf("Attestation for epoch %d included at slot %d (block #%d, distance=%d slots)", epoch, st.slot, n, dist))
+			return
+		}
+	}
+	c.stats.missed.Add(1)
+	c.r.printTS(fmt.Sprintf("ALERT: attestation for epoch %d (submitted at block #%d, slot %d) not included within %d blocks", epoch, s.Number, base.slot, c.window))
+}
@@ -0,0 +1,256 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyStage 一次见证经过的阶段；节点侧与本地流水线侧分开汇总，用于判断延迟来自哪一边
+type latencyStage int
+
+const (
+	stagePush          latencyStage = iota // 区块头 timestamp → 收到推送（节点出块与推送）
+	stageQueue                             // 收到推送 → worker 开始处理（本地积压）
+	stageVisibility                        // 等待 HTTP 节点追上该高度（节点）
+	stageHash                              // 查询 eth1 区块哈希（节点）
+	stageReceiptsFetch                     // 取区块头与收据（节点）
+	stageRootCompute                       // 本地重算 receipts_root
+	stageSign                              // 收到推送 → 二进制输出 success 行（执行并签名）
+	stageSubmit                            // success 行 → sig verify result 行（提交并验签）
+	numStages
+)
+
+var stageNames = [numStages]string{"push", "queue", "visibility", "hash", "receipts_fetch", "root_compute", "sign", "submit"}
+
+// nodeStage 耗时主要由节点决定的阶段；其余计入本地流水线
+var nodeStage = [numStages]bool{stagePush: true, stageVisibility: true, stageHash: true, stageReceiptsFetch: true}
+
+func (s latencyStage) String() string { return stageNames[s] }
+
+func stageMask(stages ...latencyStage) uint16 {
+	var m uint16
+	for _, s := range stages {
+		m |= 1 << s
+	}
+	return m
+}
+
+// latencyRecord 一个区块推送的各阶段耗时；pending 为尚未结束（记录或跳过）的阶段
+type latencyRecord struct {
+	number     uint64
+	receivedAt time.Time
+	signedAt   time.Time
+	d          [numStages]time.Duration
+	has        [numStages]bool
+	pending    uint16
+}
+
+// LatencyEntry 写入 -latency-out 的一行（JSON Lines）
+type LatencyEntry struct {
+	Validator  string             `json:"validator,omitempty"` // StreamConfig.Name
+	Number     uint64             `json:"number"`
+	ReceivedAt time.Time          `json:"received_at"`
+	StagesMs   map[string]float64 `json:"stages_ms"` // 只含实际测得的阶段
+	NodeMs     float64            `json:"node_ms"`
+	PipelineMs float64            `json:"pipeline_ms"`
+	Complete   bool               `json:"complete"` // false 表示部分阶段因超时淘汰或运行结束而未测得
+}
+
+type stageStat struct {
+	count int64
+	total time.Duration
+	max   time.Duration
+}
+
+// latencyTracker 按块号汇集各阶段耗时；一个推送的全部阶段结束后输出一行 key=value 明细（可另写 JSON Lines），
+// 并累计每个阶段的均值/峰值。迟迟不结束的记录在 maxAge 后按部分结果输出。
+type latencyTracker struct {
+	mu        sync.Mutex
+	maxAge    time.Duration
+	recs      map[uint64]*latencyRecord
+	stats     [numStages]stageStat
+	out       io.Writer // 可为 nil
+	outErr    error
+	tag       string // 输出前缀（舰队模式下的验证者标签）
+	validator string // 写入 LatencyEntry.Validator
+}
+
+func newLatencyTracker(maxAge time.Duration, out io.Writer) *latencyTracker {
+	return &latencyTracker{maxAge: maxAge, recs: map[uint64]*latencyRecord{}, out: out}
+}
+
+// begin 登记一个推送；push<0 表示推送里没有区块 timestamp，不计 push 阶段。expect 为之后会结束的阶段
+func (t *latencyTracker) begin(number uint64, receivedAt time.Time, push time.Duration, expect uint16) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for n, rec := range t.recs {
+		if receivedAt.Sub(rec.receivedAt) > t.maxAge {
+			t.emit(rec)
+			delete(t.recs, n)
+		}
+	}
+	if old := t.recs[number]; old != nil {
+		// 同一高度的重复推送（重订阅后重放等）：先输出旧记录
+		t.emit(old)
+	}
+	rec := &latencyRecord{number: number, receivedAt: receivedAt, pending: expect}
+	if push >= 0 {
+		t.record(rec, stagePush, push)
+	}
+	t.recs[number] = rec
+	t.finishIfDone(rec)
+}
+
+// observe 记录阶段耗时
+func (t *latencyTracker) observe(number uint64, s latencyStage, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rec := t.recs[number]; rec != nil {
+		t.record(rec, s, d)
+		t.finishIfDone(rec)
+	}
+}
+
+// skip 这些阶段不会再发生（超时放弃、查询失败、检查器繁忙等）
+func (t *latencyTracker) skip(number uint64, stages ...latencyStage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rec := t.recs[number]; rec != nil {
+		rec.pending &^= stageMask(stages...)
+		t.finishIfDone(rec)
+	}
+}
+
+// signed 二进制对区块 number 输出 success 行
+func (t *latencyTracker) signed(number uint64, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rec := t.recs[number]; rec != nil {
+		rec.signedAt = at
+		t.record(rec, stageSign, at.Sub(rec.receivedAt))
+		t.finishIfDone(rec)
+	}
+}
+
+// submitted 二进制对区块 number 输出 sig verify result 行；没有 success 行时不计 submit
+func (t *latencyTracker) submitted(number uint64, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rec := t.recs[number]; rec != nil {
+		if !rec.signedAt.IsZero() {
+			t.record(rec, stageSubmit, at.Sub(rec.signedAt))
+		}
+		rec.pending &^= stageMask(stageSign, stageSubmit)
+		t.finishIfDone(rec)
+	}
+}
+
+// flush 输出全部未结束的记录（运行结束时）
+func (t *latencyTracker) flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	nums := make([]uint64, 0, len(t.recs))
+	for n := range t.recs {
+		nums = append(nums, n)
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+	for _, n := range nums {
+		t.emit(t.recs[n])
+		delete(t.recs, n)
+	}
+}
+
+// 以下方法调用方持有 t.mu
+
+func (t *latencyTracker) record(rec *latencyRecord, s latencyStage, d time.Duration) {
+	rec.d[s], rec.has[s] = d, true
+	rec.pending &^= stageMask(s)
+	st := &t.stats[s]
+	st.count++
+	st.total += d
+	st.max = max(st.max, d)
+}
+
+func (t *latencyTracker) finishIfDone(rec *latencyRecord) {
+	if rec.pending == 0 {
+		t.emit(rec)
+		delete(t.recs, rec.number)
+	}
+}
+
+func (t *latencyTracker) emit(rec *latencyRecord) {
+	var node, pipeline time.Duration
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "latency #%d", rec.number)
+	for s := latencyStage(0); s < numStages; s++ {
+		if !rec.has[s] {
+			fmt.Fprintf(&sb, " %s=-", s)
+			continue
+		}
+		fmt.Fprintf(&sb, " %s=%s", s, rec.d[s].Round(time.Millisecond))
+		if nodeStage[s] {
+			node += rec.d[s]
+		} else {
+			pipeline += rec.d[s]
+		}
+	}
+	fmt.Fprintf(&sb, " node=%s pipeline=%s", node.Round(time.Millisecond), pipeline.Round(time.Millisecond))
+	if rec.pending != 0 {
+		sb.WriteString(" (partial)")
+	}
+	printTS(t.tag + sb.String())
+
+	if t.out == nil || t.outErr != nil {
+		return
+	}
+	e := LatencyEntry{
+		Validator: t.validator, Number: rec.number, ReceivedAt: rec.receivedAt, StagesMs: map[string]float64{},
+		NodeMs: ms(node), PipelineMs: ms(pipeline), Complete: rec.pending == 0,
+	}
+	for s := latencyStage(0); s < numStages; s++ {
+		if rec.has[s] {
+			e.StagesMs[s.String()] = ms(rec.d[s])
+		}
+	}
+	if err := json.NewEncoder(t.out).Encode(e); err != nil {
+		t.outErr = err
+		printTS(t.tag + fmt.Sprintf("latency output disabled: %v", err))
+	}
+}
+
+func ms(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+
+// String 各阶段 avg/max 汇总
+func (t *latencyTracker) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var sb strings.Builder
+	sb.WriteString("latency (avg/max):")
+	for s := latencyStage(0); s < numStages; s++ {
+		st := t.stats[s]
+		if st.count == 0 {
+			fmt.Fprintf(&sb, " %s=-", s)
+			continue
+		}
+		avg := st.total / time.Duration(st.count)
+		fmt.Fprintf(&sb, " %s=%s/%s", s, avg.Round(time.Millisecond), st.max.Round(time.Millisecond))
+	}
+	return sb.String()
+}
+
+// expectedStages 一个推送在当前配置下会经历的阶段（push 在 begin 时直接记录）
+func (r *streamRunner) expectedStages() uint16 {
+	m := stageMask(stageSign, stageSubmit)
+	if r.ethCli != nil {
+		m |= stageMask(stageQueue, stageVisibility, stageHash)
+	}
+	if r.rcpt != nil {
+		m |= stageMask(stageReceiptsFetch, stageRootCompute)
+	}
+	return m
+}
@@ -0,0 +1,262 @@
+package validator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"n42-test/internal/beaconstate"
+)
+
+// DefaultGapCheckBlocks 重新订阅后最多检查的漏推区块数
+const DefaultGapCheckBlocks = 64
+
+// -------------------- 断线续订：进度记录、推送去重与漏推检查 --------------------
+//
+// WS 断开后二进制重启重新订阅：节点可能把断线前的区块再推一次，断线期间的区块则不会再推。
+// 每个私钥最近一次成功签名提交的区块号追加写入进度文件，重启进程后也能接上；
+// 不超过该高度、或本次运行已转发过的推送由推送闸门（见 pushgate.go）拦下，不会到达二进制，也就不会重复签名。
+// 断线期间漏推的区块无法补签：验证请求（带执行见证）只随推送下发，HTTP 取不到。
+// 每次订阅成功后按 HTTP 检查上次处理的区块到链头之间漏推的区块，记入 gap_checked 并做收据比对，便于对账。
+
+// Progress 每个验证者（按公钥）最近一次成功签名提交的区块号。文件为 JSON Lines，只追加不改写，
+// 同一公钥以最大值为准，崩溃时最多损坏最后一行。舰队成员可共用一个 Progress；nil 的方法均为空操作
+type Progress struct {
+	Path string
+
+	mu   sync.Mutex
+	f    *os.File
+	last map[string]uint64
+	err  error
+}
+
+type progressRecord struct {
+	Pubkey string    `json:"pubkey"`
+	Block  uint64    `json:"block"`
+	Time   time.Time `json:"time"`
+}
+
+// OpenProgress 读取已有记录并以追加方式打开（不存在则创建）进度文件
+func OpenProgress(path string) (*Progress, error) {
+	p := &Progress{Path: path, last: map[string]uint64{}}
+	if f, err := os.Open(path); err == nil {
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			var r progressRecord
+			if json.Unmarshal(sc.Bytes(), &r) != nil || r.Pubkey == "" {
+				continue // 崩溃时写了一半的行
+			}
+			pk := beaconstate.NormPubkey(r.Pubkey)
+			p.last[pk] = max(p.last[pk], r.Block)
+		}
+		err := sc.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	p.f = f
+	return p, nil
+}
+
+// Last 公钥最近一次成功签名提交的区块号；没有记录时为 0
+func (p *Progress) Last(pubkey string) uint64 {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last[beaconstate.NormPubkey(pubkey)]
+}
+
+// Record 记录公钥在区块 block 上成功签名提交；不大于已有记录时忽略。写失败只保留第一个错误（见 Err）
+func (p *Progress) Record(pubkey string, block uint64) {
+	if p == nil {
+		return
+	}
+	pk := beaconstate.NormPubkey(pubkey)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if block <= p.last[pk] {
+		return
+	}
+	p.last[pk] = block
+	b, err := json.Marshal(progressRecord{Pubkey: pk, Block: block, Time: time.Now().UTC()})
+	if err != nil {
+		return
+	}
+	if _, err := p.f.Write(append(b, '\n')); err != nil && p.err == nil {
+		p.err = err
+	}
+}
+
+// Err 第一次写失败的错误
+func (p *Progress) Err() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *Progress) Close() error {
+	if p == nil {
+		return nil
+	}
+	return p.f.Close()
+}
+
+func (c StreamConfig) gapCheckBlocks() int {
+	switch {
+	case c.GapCheckBlocks < 0:
+		return 0
+	case c.GapCheckBlocks == 0:
+		return DefaultGapCheckBlocks
+	default:
+		return c.GapCheckBlocks
+	}
+}
+
+// pushDedup 本次运行已转发（或已检查）过的区块号，只保留最高处附近的一段
+type pushDedup struct {
+	mu   sync.Mutex
+	seen map[uint64]struct{}
+	high uint64
+}
+
+const dedupWindow = 1024
+
+// add 记下区块 n；已记过时返回 false
+func (d *pushDedup) add(n uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen == nil {
+		d.seen = map[uint64]struct{}{}
+	}
+	if _, ok := d.seen[n]; ok {
+		return false
+	}
+	d.seen[n] = struct{}{}
+	if n > d.high {
+		d.high = n
+		if len(d.seen) > 2*dedupWindow {
+			for k := range d.seen {
+				if k+dedupWindow < n {
+					delete(d.seen, k)
+				}
+			}
+		}
+	}
+	return true
+}
+
+func (d *pushDedup) has(n uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.seen[n]
+	return ok
+}
+
+func (d *pushDedup) highest() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.high
+}
+
+// isDuplicate 推送的区块 n 不应再交给二进制：不高于进度记录中已签名提交的区块，或本次运行已转发过；
+// 否则记为已转发
+func (r *streamRunner) isDuplicate(n uint64) bool {
+	if n <= r.resumeFrom {
+		return true
+	}
+	return !r.seen.add(n)
+}
+
+// attested 二进制报告对区块 n 签名提交成功：更新进度；不高于已记录的区块时视为重复见证并告警
+// （闸门生效时不应发生：没有 WS 地址可拦截，或节点换了推送形状时的最后一道检查）
+func (r *streamRunner) attested(n uint64) {
+	for {
+		cur := r.lastAttested.Load()
+		if n <= cur {
+			r.stats.doubleAttest.Add(1)
+			r.printTS(fmt.Sprintf("ALERT: validate attested block #%d again (last attested #%d)", n, cur))
+			return
+		}
+		if r.lastAttested.CompareAndSwap(cur, n) {
+			break
+		}
+	}
+	r.cfg.Progress.Record(r.pubkey, n)
+}
+
+// gapCheckLoop 每次订阅成功后检查漏推的区块，直到 ctx 结束
+func (r *streamRunner) gapCheckLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.subscribed:
+		}
+		r.checkGap(ctx)
+	}
+}
+
+// checkGap 检查上次处理的区块到当前链头之间没有收到推送的区块：经 HTTP 取区块哈希并做收据比对，计入 gap_checked。
+// 这些区块不会被签名（验证请求只随推送下发）。首次订阅且没有进度记录时不检查
+func (r *streamRunner) checkGap(ctx context.Context) {
+	limit := r.cfg.gapCheckBlocks()
+	from := max(r.seen.highest(), r.lastAttested.Load(), r.gap.highest()) + 1
+	if limit == 0 || from == 1 {
+		return
+	}
+	head, err := r.chainHead(ctx)
+	if err != nil {
+		r.printTS(fmt.Sprintf("Gap check skipped: latest block query failed: %v", err))
+		return
+	}
+	if head < from {
+		return
+	}
+	if gap := head - from + 1; gap > uint64(limit) {
+		r.stats.gapSkipped.Add(int64(gap) - int64(limit))
+		r.printTS(fmt.Sprintf("Gap check: %d blocks not pushed since #%d, only checking the last %d", gap, from-1, limit))
+		from = head - uint64(limit) + 1
+	}
+	r.printTS(fmt.Sprintf("Gap check: blocks #%d..#%d were produced while resubscribing", from, head))
+	for n := from; n <= head; n++ {
+		if ctx.Err() != nil {
+			return
+		}
+		// 订阅恢复后已推送的由二进制处理；这里只记入 r.gap 而不记入 r.seen，检查之后才迟到的推送仍会转发给二进制
+		if r.seen.has(n) || !r.gap.add(n) {
+			continue
+		}
+		blk, err := r.ethCli.EthGetBlockByNumber(ctx, "0x"+strconv.FormatUint(n, 16), false)
+		if err != nil {
+			r.printTS(fmt.Sprintf("Gap check: block #%d query failed: %v", n, err))
+			continue
+		}
+		r.stats.gapChecked.Add(1)
+		r.printTS(fmt.Sprintf("Gap check: block #%d = %s never pushed, not attested (verification requests are only delivered by push)", n, blk.Hash))
+		if r.rcpt != nil {
+			r.rcpt.submit(blockPush{Number: strconv.FormatUint(n, 10), ReceiptsRoot: blk.ReceiptsRoot, Timestamp: time.Now(), ReceivedAt: time.Now()})
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultQueueSize 推送积压队列的默认容量
+const DefaultQueueSize = 8
+
+// QueuePolicy 推送到达快于处理速度时的积压策略
+type QueuePolicy string
+
+const (
+	// PolicyNewestFirst 优先处理最新推送（LIFO），队列满时丢弃最旧的推送
+	PolicyNewestFirst QueuePolicy = "newest-first"
+	// PolicyDropOldest 按到达顺序处理（FIFO），队列满时丢弃最旧的推送
+	PolicyDropOldest QueuePolicy = "drop-oldest"
+)
+
+// ParseQueuePolicy 解析命令行传入的策略名
+func ParseQueuePolicy(s string) (QueuePolicy, error) {
+	switch QueuePolicy(strings.ToLower(strings.TrimSpace(s))) {
+	case "", PolicyNewestFirst:
+		return PolicyNewestFirst, nil
+	case PolicyDropOldest:
+		return PolicyDropOldest, nil
+	default:
+		return "", fmt.Errorf("unknown queue policy %q (newest-first|drop-oldest)", s)
+	}
+}
+
+// pushQueue 有界推送队列：满了丢最旧的，取出顺序由策略决定
+type pushQueue struct {
+	mu     sync.Mutex
+	items  []blockPush // 按到达顺序排列，items[0] 最旧
+	size   int
+	policy QueuePolicy
+	notify chan struct{}
+}
+
+func newPushQueue(size int, policy QueuePolicy) *pushQueue {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	if policy == "" {
+		policy = PolicyNewestFirst
+	}
+	return &pushQueue{size: size, policy: policy, notify: make(chan struct{}, 1)}
+}
+
+// put 入队；返回入队后的深度，以及因队列已满被丢弃的推送（若有）
+func (q *pushQueue) put(p blockPush) (depth int, dropped *blockPush) {
+	q.mu.Lock()
+	if len(q.items) >= q.size {
+		old := q.items[0]
+		dropped = &old
+		q.items = q.items[1:]
+	}
+	q.items = append(q.items, p)
+	depth = len(q.items)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return depth, dropped
+}
+
+// take 按策略取出一个推送，并返回取出后剩余的深度
+func (q *pushQueue) take() (p blockPush, remaining int, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.items)
+	if n == 0 {
+		return blockPush{}, 0, false
+	}
+	if q.policy == PolicyNewestFirst {
+		p = q.items[n-1]
+		q.items = q.items[:n-1]
+	} else {
+		p = q.items[0]
+		q.items = q.items[1:]
+	}
+	remaining = len(q.items)
+	if remaining > 0 {
+		// 还有积压，保证 worker 会再次被唤醒
+		select {
+		case q.notify <- struct{}{}:
+		default:
+		}
+	}
+	return p, remaining, true
+}
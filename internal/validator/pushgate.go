@@ -0,0 +1,193 @@
+package validator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// -------------------- 推送闸门：在推送到达二进制之前去重、丢弃过期推送 --------------------
+//
+// 二进制自己订阅、自己签名，stdout 只是事后的报告：读到 "Received block" 时它已经拿到推送并会签名。
+// 要避免重连后重复见证，只能在推送到达二进制之前拦下：设置了 WS 地址时，runner 在本机起一个 WS 转发，
+// 二进制的 RPC_URL 指向它。二进制发出的请求原样转给节点；节点推来的验证请求按区块号查重（见 isDuplicate），
+// 重复的不转发；已过 slot 截止时间（区块头 timestamp + slot）的也不转发，二进制不会再签名提交注定作废的见证，
+// 计入 missed_deadline。其余原样转发；读不出区块号的消息一律转发，交给二进制与解析容错处理。
+// 每个二进制连接对应一个到节点的连接，任一端断开时两端一起断开，二进制照常按断线重连。
+
+type pushGate struct {
+	r        *streamRunner
+	upstream string
+	ln       net.Listener
+	srv      *http.Server
+
+	mu    sync.Mutex
+	conns map[*websocket.Conn]struct{}
+}
+
+var gateUpgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+// startGate 在 127.0.0.1 的随机端口上监听，转发到 r.wsURL
+func (r *streamRunner) startGate() (*pushGate, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen push gate: %w", err)
+	}
+	g := &pushGate{r: r, upstream: r.wsURL, ln: ln, conns: map[*websocket.Conn]struct{}{}}
+	g.srv = &http.Server{Handler: g, ReadHeaderTimeout: 10 * time.Second}
+	go g.srv.Serve(ln)
+	return g, nil
+}
+
+// URL 交给二进制的 WS 地址
+func (g *pushGate) URL() string {
+	return "ws://" + g.ln.Addr().String()
+}
+
+// Close 停止监听并断开所有转发中的连接
+func (g *pushGate) Close() error {
+	err := g.srv.Close()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for c := range g.conns {
+		c.Close()
+	}
+	return err
+}
+
+func (g *pushGate) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// 先连节点：节点不可达时二进制在握手阶段就失败，与直连时一样
+	up, _, err := websocket.DefaultDialer.DialContext(req.Context(), g.upstream, nil)
+	if err != nil {
+		g.r.printTS(fmt.Sprintf("Push gate: dial %s failed: %v", g.upstream, err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	down, err := gateUpgrader.Upgrade(w, req, nil)
+	if err != nil {
+		up.Close()
+		return
+	}
+	g.track(up, down)
+	defer g.untrack(up, down)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		relay(up, down, nil)
+		done <- struct{}{}
+	}()
+	go func() {
+		relay(down, up, g.forward)
+		done <- struct{}{}
+	}()
+	<-done
+	up.Close()
+	down.Close()
+	<-done
+}
+
+func (g *pushGate) track(conns ...*websocket.Conn) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, c := range conns {
+		g.conns[c] = struct{}{}
+	}
+}
+
+func (g *pushGate) untrack(conns ...*websocket.Conn) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, c := range conns {
+		delete(g.conns, c)
+	}
+}
+
+// forward 节点推来的消息是否转发给二进制：重复的、已过 slot 截止时间的验证请求推送不转发
+func (g *pushGate) forward(msg []byte) bool {
+	n, ts, ok := pushHeader(msg)
+	if !ok {
+		return true
+	}
+	if g.r.isDuplicate(n) {
+		g.r.stats.duplicate.Add(1)
+		g.r.printTS(fmt.Sprintf("Push gate: duplicate push for block #%d not forwarded (already attested or forwarded)", n))
+		return false
+	}
+	p := newBlockPush(strconv.FormatUint(n, 10), ts, time.Now())
+	if deadline := p.deadline(g.r.slot); p.ReceivedAt.After(deadline) {
+		g.r.stats.missedDeadline.Add(1)
+		g.r.printTS(fmt.Sprintf("Push gate: block #%d past its slot deadline %s, not forwarded (missed=%d)",
+			n, deadline.Format("15:04:05"), g.r.stats.missedDeadline.Load()))
+		return false
+	}
+	return true
+}
+
+// relay 把 src 的消息逐条写到 dst，直到任一端出错；keep 非 nil 时只转发它返回 true 的文本消息。
+// src 正常关闭时把关闭码转给 dst
+func relay(dst, src *websocket.Conn, keep func([]byte) bool) {
+	for {
+		mt, msg, err := src.ReadMessage()
+		if err != nil {
+			var ce *websocket.CloseError
+			if errors.As(err, &ce) {
+				dst.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(ce.Code, ce.Text), time.Now().Add(time.Second))
+			}
+			return
+		}
+		if keep != nil && mt == websocket.TextMessage && !keep(msg) {
+			continue
+		}
+		if err := dst.WriteMessage(mt, msg); err != nil {
+			return
+		}
+	}
+}
+
+// pushHeader 验证请求推送（params.result.blockbody.header.header）中的区块号与 timestamp（转为十进制秒，缺失时为空）；
+// 不是这种推送、或区块号读不出时 ok 为 false
+func pushHeader(msg []byte) (number uint64, timestampDec string, ok bool) {
+	var m struct {
+		Method string `json:"method"`
+		Params struct {
+			Result struct {
+				Blockbody struct {
+					Header struct {
+						Header struct {
+							Number    string `json:"number"`
+							Timestamp string `json:"timestamp"`
+						} `json:"header"`
+					} `json:"header"`
+				} `json:"blockbody"`
+			} `json:"result"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(msg, &m) != nil || m.Method == "" {
+		return 0, "", false
+	}
+	h := m.Params.Result.Blockbody.Header.Header
+	number, err := parseQuantity(h.Number)
+	if err != nil {
+		return 0, "", false
+	}
+	if ts, err := parseQuantity(h.Timestamp); err == nil {
+		timestampDec = strconv.FormatUint(ts, 10)
+	}
+	return number, timestampDec, true
+}
+
+// parseQuantity 解析 0x 前缀的十六进制或十进制数
+func parseQuantity(s string) (uint64, error) {
+	if strings.HasPrefix(s, "0x") {
+		return strconv.ParseUint(s[2:], 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
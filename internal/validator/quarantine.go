@@ -0,0 +1,52 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuarantineEntry 写入 -quarantine-out 的一行（JSON Lines）：解析有问题的推送原文与错误
+type QuarantineEntry struct {
+	At     time.Time `json:"at"`
+	Schema string    `json:"schema"`           // 解析所用的区块头形状
+	Number string    `json:"number,omitempty"` // 解析出的块号；fatal 时为空
+	Fatal  bool      `json:"fatal"`            // true 表示推送被丢弃，false 表示缺失字段后仍继续处理
+	Errors []string  `json:"errors"`
+	Raw    string    `json:"raw"`
+}
+
+// quarantine 把解析失败的推送原文落盘，便于收集节点升级后的新负载形状；out 为 nil 时只计数不写
+type quarantine struct {
+	mu     sync.Mutex
+	out    io.Writer
+	outErr error
+	tag    string // 输出前缀（舰队模式下的验证者标签）
+}
+
+func (q *quarantine) write(e QuarantineEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.out == nil || q.outErr != nil {
+		return
+	}
+	if err := json.NewEncoder(q.out).Encode(e); err != nil {
+		q.outErr = err
+		printTS(q.tag + fmt.Sprintf("quarantine output disabled: %v", err))
+	}
+}
+
+// quarantinePush 记录一条有问题的推送并计数
+func (r *streamRunner) quarantinePush(line, schema string, f pushFields, problems []string, fatal bool) {
+	if fatal {
+		r.stats.malformed.Add(1)
+		r.printTS(fmt.Sprintf("Malformed push dropped: %s (malformed=%d)", strings.Join(problems, "; "), r.stats.malformed.Load()))
+	} else {
+		r.stats.partial.Add(1)
+		r.printTS(fmt.Sprintf("Push #%s parsed with problems: %s (partial=%d)", f.Number, strings.Join(problems, "; "), r.stats.partial.Load()))
+	}
+	r.quar.write(QuarantineEntry{At: time.Now(), Schema: schema, Number: f.Number, Fatal: fatal, Errors: problems, Raw: line})
+}
@@ -0,0 +1,114 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"n42-test/internal/receipts"
+)
+
+// receiptsStats receipts_root 比对情况
+type receiptsStats struct {
+	checked  atomic.Int64 // 比对过的区块数
+	fastPath atomic.Int64 // 其中无交易、直接取空 trie 根的区块数
+	mismatch atomic.Int64 // 重算结果与推送值不一致的区块数
+	header   atomic.Int64 // 自检模式：重算结果与 RPC 区块头不一致的区块数
+	failed   atomic.Int64 // 取区块/收据失败的次数
+	skipped  atomic.Int64 // 检查器繁忙被跳过的推送数
+}
+
+func (s *receiptsStats) String() string {
+	return fmt.Sprintf("receipts_root: checked=%d fast_path=%d mismatch=%d header_mismatch=%d failed=%d skipped=%d",
+		s.checked.Load(), s.fastPath.Load(), s.mismatch.Load(), s.header.Load(), s.failed.Load(), s.skipped.Load())
+}
+
+// receiptsChecker 按网络配置档的规则在本地重算推送区块的 receipts_root，
+// 与二进制推送的值比对；无交易区块走快速路径，直接取规则中的空 trie 根。
+// 自检模式下每个重算结果还要与 RPC 区块头比对，不一致时打印逐条收据诊断。
+type receiptsChecker struct {
+	rules     receipts.Rules
+	selfCheck bool
+	fetcher   *receipts.Fetcher
+	pushes    chan blockPush
+	stats     receiptsStats
+	lat       *latencyTracker // 可为 nil
+	tag       string          // 输出前缀（舰队模式下的验证者标签）
+}
+
+func newReceiptsChecker(ctx context.Context, httpURL string, rules receipts.Rules, selfCheck bool) (*receiptsChecker, error) {
+	f, err := receipts.Dial(ctx, httpURL)
+	if err != nil {
+		return nil, err
+	}
+	return &receiptsChecker{rules: rules, selfCheck: selfCheck, fetcher: f, pushes: make(chan blockPush, 64)}, nil
+}
+
+// submit 记录一次待比对的推送；检查器跟不上时丢弃并计数，不阻塞 worker
+func (c *receiptsChecker) submit(p blockPush) {
+	select {
+	case c.pushes <- p:
+	default:
+		c.stats.skipped.Add(1)
+		if c.lat != nil {
+			c.lat.skip(p.height(), stageReceiptsFetch, stageRootCompute)
+		}
+	}
+}
+
+func (c *receiptsChecker) run(ctx context.Context) {
+	defer c.fetcher.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p := <-c.pushes:
+			c.check(ctx, p)
+		}
+	}
+}
+
+func (c *receiptsChecker) check(ctx context.Context, p blockPush) {
+	n, err := strconv.ParseUint(p.Number, 10, 64)
+	if err != nil {
+		return
+	}
+	res, err := c.fetcher.Compute(ctx, c.rules, n)
+	if c.lat != nil {
+		if err != nil {
+			c.lat.skip(n, stageReceiptsFetch, stageRootCompute)
+		} else {
+			c.lat.observe(n, stageReceiptsFetch, res.FetchTime)
+			c.lat.observe(n, stageRootCompute, res.ComputeTime)
+		}
+	}
+	if err != nil {
+		c.stats.failed.Add(1)
+		printTS(c.tag + fmt.Sprintf("receipts_root #%d: %v", n, err))
+		return
+	}
+	c.stats.checked.Add(1)
+	if res.FastPath {
+		c.stats.fastPath.Add(1)
+	}
+	if c.selfCheck {
+		if err := res.Validate(c.rules); err != nil {
+			c.stats.header.Add(1)
+			printTS(c.tag + "ALERT: self-check: " + err.Error())
+		}
+	}
+	// 推送里没有 receipts_root 时退化为与 RPC 区块头比对
+	want := res.Header.Hex()
+	if p.ReceiptsRoot != "" {
+		want = p.ReceiptsRoot
+	}
+	if strings.EqualFold(want, res.Computed.Hex()) {
+		printTS(c.tag + fmt.Sprintf("receipts_root #%d ok (txs=%d, fast_path=%t)", n, res.TxCount, res.FastPath))
+		return
+	}
+	c.stats.mismatch.Add(1)
+	printTS(c.tag + fmt.Sprintf("ALERT: receipts_root #%d mismatch: pushed=%s computed=%s header=%s (txs=%d, %s)",
+		n, emptyDash(p.ReceiptsRoot), res.Computed.Hex(), res.Header.Hex(), res.TxCount, c.rules))
+}
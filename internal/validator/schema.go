@@ -0,0 +1,245 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HeaderField 推送区块头中 runner 关心的字段
+type HeaderField int
+
+const (
+	FieldNumber HeaderField = iota
+	FieldParentHash
+	FieldStateRoot
+	FieldReceiptsRoot
+	FieldRequestsHash
+	FieldTimestamp
+)
+
+// pushFields 从 "Received block" 行抽取的区块头字段（原样字符串）
+type pushFields struct {
+	Number       string
+	Parent       string
+	State        string
+	ReceiptsRoot string
+	RequestsHash string
+	Timestamp    string
+}
+
+func (f *pushFields) set(field HeaderField, v string) {
+	switch field {
+	case FieldNumber:
+		f.Number = v
+	case FieldParentHash:
+		f.Parent = v
+	case FieldStateRoot:
+		f.State = v
+	case FieldReceiptsRoot:
+		f.ReceiptsRoot = v
+	case FieldRequestsHash:
+		f.RequestsHash = v
+	case FieldTimestamp:
+		f.Timestamp = v
+	}
+}
+
+// FieldRule 一个字段在推送行中的取法
+type FieldRule struct {
+	Field    HeaderField
+	Name     string         // 推送行中的字段名，如 parent_hash
+	Pattern  *regexp.Regexp // 严格匹配，第一个分组为取值
+	Required bool           // 缺失时记为解析问题；非必需字段只有出现了却解析不了才算问题
+
+	loose *regexp.Regexp // 宽松匹配，区分“缺失”与“格式不对”；注册时生成
+}
+
+// HeaderSchema 一种版本化的推送区块头形状。节点负载变化时注册新的形状，而不是在解析处加分支
+type HeaderSchema struct {
+	Name   string
+	Desc   string
+	Fields []FieldRule
+	// Detect auto 模式下判断推送行是否为该形状；按注册顺序倒序（新版本优先）尝试
+	Detect func(line string) bool
+}
+
+var (
+	schemaMu    sync.RWMutex
+	schemaOrder []string
+	schemaByKey = map[string]HeaderSchema{}
+)
+
+// SchemaAuto 按推送行自动协商形状
+const SchemaAuto = "auto"
+
+// RegisterHeaderSchema 注册形状；名字重复或字段规则不完整时 panic（只应在 init 中调用）
+func RegisterHeaderSchema(s HeaderSchema) {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	key := strings.ToLower(s.Name)
+	if key == "" || key == SchemaAuto {
+		panic(fmt.Sprintf("validator: invalid header schema name %q", s.Name))
+	}
+	if _, dup := schemaByKey[key]; dup {
+		panic("validator: header schema registered twice: " + s.Name)
+	}
+	if s.Detect == nil {
+		panic("validator: header schema " + s.Name + " has no Detect")
+	}
+	hasNumber := false
+	rules := make([]FieldRule, len(s.Fields))
+	for i, r := range s.Fields {
+		if r.Pattern == nil || r.Name == "" {
+			panic(fmt.Sprintf("validator: header schema %s: field %d needs Name and Pattern", s.Name, i))
+		}
+		hasNumber = hasNumber || r.Field == FieldNumber
+		r.loose = regexp.MustCompile(`\b` + regexp.QuoteMeta(r.Name) + `:\s*([^,\s}]+)`)
+		rules[i] = r
+	}
+	if !hasNumber {
+		panic("validator: header schema " + s.Name + " has no number field")
+	}
+	s.Fields = rules
+	schemaByKey[key] = s
+	schemaOrder = append(schemaOrder, key)
+}
+
+// SchemaNames 已注册的形状名（注册顺序）加上 auto
+func SchemaNames() []string {
+	schemaMu.RLock()
+	defer schemaMu.RUnlock()
+	return append(slices.Clone(schemaOrder), SchemaAuto)
+}
+
+// LookupHeaderSchema 按名字查找（大小写不敏感）
+func LookupHeaderSchema(name string) (HeaderSchema, error) {
+	schemaMu.RLock()
+	defer schemaMu.RUnlock()
+	s, ok := schemaByKey[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return HeaderSchema{}, fmt.Errorf("unknown header schema %q (available: %s)", name, strings.Join(append(slices.Clone(schemaOrder), SchemaAuto), ", "))
+	}
+	return s, nil
+}
+
+// decode 按字段规则抽取；problems 为解析问题，fatal 表示拿不到块号，这条推送无法处理
+func (s HeaderSchema) decode(line string) (f pushFields, problems []string, fatal bool) {
+	for _, r := range s.Fields {
+		got := firstSub(r.Pattern, line)
+		if got == "" {
+			switch raw := firstSub(r.loose, line); {
+			case raw != "" && raw != "None":
+				problems = append(problems, fmt.Sprintf("bad %s %q", r.Name, raw))
+			case raw == "" && r.Required:
+				problems = append(problems, "missing "+r.Name)
+			}
+		}
+		f.set(r.Field, got)
+	}
+	if _, err := strconv.ParseUint(f.Number, 10, 64); f.Number != "" && err != nil {
+		problems = append(problems, fmt.Sprintf("bad number %q", f.Number))
+		f.Number = ""
+	}
+	return f, problems, f.Number == ""
+}
+
+// -------------------- 内置形状 --------------------
+
+var (
+	reNum       = regexp.MustCompile(`\bnumber:\s*(\d+)\b`)
+	reParent    = regexp.MustCompile(`\bparent_hash:\s*(0x[0-9a-fA-F]{64})`)
+	reState     = regexp.MustCompile(`\bstate_root:\s*(0x[0-9a-fA-F]{64})`)
+	reReceipt   = regexp.MustCompile(`\breceipts_root:\s*(0x[0-9a-fA-F]{64})`)
+	reReq       = regexp.MustCompile(`\brequests_hash:\s*Some\((0x[0-9a-fA-F]{64})\)`)
+	reTimestamp = regexp.MustCompile(`\btimestamp:\s*(\d+)\b`)
+)
+
+// v1Fields Prague 之前的区块头
+var v1Fields = []FieldRule{
+	{Field: FieldNumber, Name: "number", Pattern: reNum, Required: true},
+	{Field: FieldParentHash, Name: "parent_hash", Pattern: reParent, Required: true},
+	{Field: FieldTimestamp, Name: "timestamp", Pattern: reTimestamp, Required: true},
+	{Field: FieldStateRoot, Name: "state_root", Pattern: reState},
+	{Field: FieldReceiptsRoot, Name: "receipts_root", Pattern: reReceipt},
+}
+
+func init() {
+	RegisterHeaderSchema(HeaderSchema{
+		Name:   "v1",
+		Desc:   "pre-Prague header, no requests_hash",
+		Fields: v1Fields,
+		Detect: func(line string) bool { return !strings.Contains(line, "requests_hash:") },
+	})
+	RegisterHeaderSchema(HeaderSchema{
+		Name: "v2",
+		Desc: "Prague header with requests_hash, EIP-7685",
+		Fields: append(slices.Clone(v1Fields),
+			FieldRule{Field: FieldRequestsHash, Name: "requests_hash", Pattern: reReq, Required: true}),
+		Detect: func(line string) bool { return strings.Contains(line, "requests_hash:") },
+	})
+}
+
+// -------------------- 协商 --------------------
+
+// schemaNegotiator 固定形状时逐行按该形状解析（形状不符记为解析问题）；
+// auto 时按 Detect 逐行选形状，形状变化（如分叉激活）时打印切换
+type schemaNegotiator struct {
+	fixed   *HeaderSchema
+	current string // 只在读取 stdout 的 goroutine 中访问
+	tag     string // 输出前缀（舰队模式下的验证者标签）
+}
+
+func newSchemaNegotiator(name string) (*schemaNegotiator, error) {
+	if name == "" || strings.EqualFold(name, SchemaAuto) {
+		return &schemaNegotiator{}, nil
+	}
+	s, err := LookupHeaderSchema(name)
+	if err != nil {
+		return nil, err
+	}
+	return &schemaNegotiator{fixed: &s, current: s.Name}, nil
+}
+
+// decode 返回所用形状名与解析结果
+func (n *schemaNegotiator) decode(line string) (schema string, f pushFields, problems []string, fatal bool) {
+	if n.fixed != nil {
+		f, problems, fatal = n.fixed.decode(line)
+		if !n.fixed.Detect(line) {
+			problems = append(problems, "push does not match schema "+n.fixed.Name)
+		}
+		return n.fixed.Name, f, problems, fatal
+	}
+
+	schemaMu.RLock()
+	var picked *HeaderSchema
+	for i := len(schemaOrder) - 1; i >= 0; i-- {
+		if s := schemaByKey[schemaOrder[i]]; s.Detect(line) {
+			picked = &s
+			break
+		}
+	}
+	if picked == nil {
+		// 都不像：沿用当前形状（尚未协商时取最新版本），问题会记入隔离文件
+		key := strings.ToLower(n.current)
+		if key == "" {
+			key = schemaOrder[len(schemaOrder)-1]
+		}
+		s := schemaByKey[key]
+		picked = &s
+	}
+	schemaMu.RUnlock()
+
+	switch {
+	case n.current == "":
+		printTS(n.tag + fmt.Sprintf("Header schema negotiated: %s (%s)", picked.Name, picked.Desc))
+	case n.current != picked.Name:
+		printTS(n.tag + fmt.Sprintf("Header schema switched: %s -> %s (%s)", n.current, picked.Name, picked.Desc))
+	}
+	n.current = picked.Name
+	f, problems, fatal = picked.decode(line)
+	return picked.Name, f, problems, fatal
+}
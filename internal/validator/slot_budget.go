@@ -0,0 +1,171 @@
+package validator
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"n42-test/internal/beaconstate"
+	"n42-test/internal/receipts"
+)
+
+// DefaultSecondsPerSlot 默认每个 slot 的秒数（与以太坊主网一致）
+const DefaultSecondsPerSlot = 12
+
+// StreamConfig ValidateStreamFiltered 的可调参数
+type StreamConfig struct {
+	// 每个 slot 的秒数；<=0 时使用 DefaultSecondsPerSlot
+	SecondsPerSlot int
+
+	// 推送积压队列容量；<=0 时使用 DefaultQueueSize
+	QueueSize int
+
+	// 积压策略；为空时使用 PolicyNewestFirst
+	QueuePolicy QueuePolicy
+
+	// 看门狗阈值（slot 数）：超过该时长无推送且链仍在出块则重连；
+	// 0 使用 DefaultWatchdogSlots，<0 关闭看门狗
+	WatchdogSlots int
+
+	// 提交后在后续区块的信标状态中确认参与标记，记录包含距离
+	VerifyInclusion bool
+	// 确认参与标记最多查看的后续区块数；<=0 时使用 DefaultInclusionWindow
+	InclusionWindow int
+	// 每纪元 slot 数；0 时使用 beaconstate.DefaultSlotsPerEpoch
+	SlotsPerEpoch uint64
+
+	// 非 nil 时按该规则在本地重算每个推送区块的 receipts_root，与推送值比对
+	ReceiptRules *receipts.Rules
+	// 自检模式：重算结果同时与 RPC 区块头比对，不一致时打印逐条收据诊断（需设置 ReceiptRules）
+	ReceiptSelfCheck bool
+
+	// 非 nil 时把每个推送的分阶段耗时（LatencyEntry）以 JSON Lines 写入
+	LatencyOut io.Writer
+
+	// 非 nil 时把解析失败的推送原文与错误（QuarantineEntry）以 JSON Lines 写入
+	QuarantineOut io.Writer
+
+	// 推送区块头形状：已注册的名字（v1|v2）或 "auto"（按推送行自动协商）；为空等同 auto
+	HeaderSchema string
+
+	// 每次二进制订阅验证请求流成功（含看门狗重连后）时调用，可为 nil；用于向 systemd 报告就绪
+	OnSubscribed func()
+
+	// 非 nil 时每个私钥最近一次成功签名提交的区块号记入该进度（见 Progress），重启后不高于它的推送不再转发给二进制
+	Progress *Progress
+	// 每次订阅成功后最多检查的漏推区块数（只检查不签名）；0 使用 DefaultGapCheckBlocks，<0 不检查
+	GapCheckBlocks int
+
+	// 验证者标签：非空时每行输出加 "[Name] " 前缀、LatencyEntry 带 validator 字段（同一进程跑多个验证者时区分）
+	Name string
+}
+
+func (c StreamConfig) slotsPerEpoch() uint64 {
+	if c.SlotsPerEpoch == 0 {
+		return beaconstate.DefaultSlotsPerEpoch
+	}
+	return c.SlotsPerEpoch
+}
+
+func (c StreamConfig) slotDuration() time.Duration {
+	if c.SecondsPerSlot <= 0 {
+		return DefaultSecondsPerSlot * time.Second
+	}
+	return time.Duration(c.SecondsPerSlot) * time.Second
+}
+
+// blockPush 从二进制输出的 "Received block" 行中解析出的一次区块推送
+type blockPush struct {
+	Number       string
+	ReceiptsRoot string    // 推送中区块头的 receipts_root；缺失时为空
+	Timestamp    time.Time // 区块头里的 timestamp；缺失时退化为本地接收时间
+	ReceivedAt   time.Time
+}
+
+// newBlockPush 用块号与区块头 timestamp（十进制秒）构造推送
+func newBlockPush(number, timestampDec string, receivedAt time.Time) blockPush {
+	p := blockPush{Number: number, Timestamp: receivedAt, ReceivedAt: receivedAt}
+	if ts, err := strconv.ParseInt(timestampDec, 10, 64); err == nil && ts > 0 {
+		p.Timestamp = time.Unix(ts, 0)
+	}
+	return p
+}
+
+// height 十进制块号；解析失败时为 0
+func (p blockPush) height() uint64 {
+	n, _ := strconv.ParseUint(p.Number, 10, 64)
+	return n
+}
+
+// pushDelay 区块头 timestamp（十进制秒）到收到推送的耗时；没有 timestamp 时为 -1
+func pushDelay(timestampDec string, receivedAt time.Time) time.Duration {
+	ts, err := strconv.ParseInt(timestampDec, 10, 64)
+	if err != nil || ts <= 0 {
+		return -1
+	}
+	return receivedAt.Sub(time.Unix(ts, 0))
+}
+
+// deadline 该推送所在 slot 的截止时间：超过后提交已无意义
+func (p blockPush) deadline(slot time.Duration) time.Time {
+	return p.Timestamp.Add(slot)
+}
+
+// streamStats 运行期间的计数（并发安全）
+type streamStats struct {
+	received       atomic.Int64 // 收到的推送数
+	processed      atomic.Int64 // 在截止时间内处理完成的推送数
+	attested       atomic.Int64 // 二进制报告执行成功（已签名提交）的推送数
+	missedDeadline atomic.Int64 // 因超过 slot 截止时间而放弃的推送数（含推送闸门未转发给二进制的）
+	dropped        atomic.Int64 // 队列已满被丢弃的推送数
+	watchdogAlerts atomic.Int64 // 看门狗告警次数
+	restarts       atomic.Int64 // 重连（重启二进制）次数
+	malformed      atomic.Int64 // 拿不到块号而丢弃的推送数
+	partial        atomic.Int64 // 缺少或无法解析部分字段、仍继续处理的推送数
+	duplicate      atomic.Int64 // 推送闸门拦下、未转发给二进制的重复推送数
+	doubleAttest   atomic.Int64 // 二进制对已签名提交过的区块再次报告成功的次数
+	gapChecked     atomic.Int64 // 重新订阅后经 HTTP 检查的漏推区块数（未签名）
+	gapSkipped     atomic.Int64 // 超出检查上限而未检查的漏推区块数
+
+	// 滞后统计：推送从接收到开始处理的等待时间
+	lagCount atomic.Int64
+	lagTotal atomic.Int64 // 纳秒
+	lagMax   atomic.Int64 // 纳秒
+	maxDepth atomic.Int64 // 观察到的最大队列深度
+}
+
+// observeLag 记录一次推送的排队滞后
+func (s *streamStats) observeLag(d time.Duration) {
+	s.lagCount.Add(1)
+	s.lagTotal.Add(int64(d))
+	for {
+		cur := s.lagMax.Load()
+		if int64(d) <= cur || s.lagMax.CompareAndSwap(cur, int64(d)) {
+			return
+		}
+	}
+}
+
+// observeDepth 记录队列深度峰值
+func (s *streamStats) observeDepth(n int) {
+	for {
+		cur := s.maxDepth.Load()
+		if int64(n) <= cur || s.maxDepth.CompareAndSwap(cur, int64(n)) {
+			return
+		}
+	}
+}
+
+func (s *streamStats) String() string {
+	avg := time.Duration(0)
+	if n := s.lagCount.Load(); n > 0 {
+		avg = time.Duration(s.lagTotal.Load() / n)
+	}
+	return fmt.Sprintf("received=%d processed=%d attested=%d missed_deadline=%d dropped=%d malformed=%d partial=%d duplicate=%d double_attest=%d gap_checked=%d gap_skipped=%d lag_avg=%s lag_max=%s max_depth=%d watchdog_alerts=%d restarts=%d",
+		s.received.Load(), s.processed.Load(), s.attested.Load(), s.missedDeadline.Load(), s.dropped.Load(), s.malformed.Load(), s.partial.Load(),
+		s.duplicate.Load(), s.doubleAttest.Load(), s.gapChecked.Load(), s.gapSkipped.Load(),
+		avg.Round(time.Millisecond), time.Duration(s.lagMax.Load()).Round(time.Millisecond), s.maxDepth.Load(),
+		s.watchdogAlerts.Load(), s.restarts.Load())
+}
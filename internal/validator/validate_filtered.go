@@ -3,75 +3,251 @@ package validator
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	// 修改为你项目里 beaconext 的实际导入路径
 	"n42-test/internal/beaconext"
+	"n42-test/internal/blsutil"
 )
 
+// 关键行匹配
+var (
+	reConnected         = regexp.MustCompile(`^Connected to (.+)$`)
+	reSubscribed        = regexp.MustCompile(`Subscribed to 'subscribeToVerificationRequest'`)
+	reSuccess           = regexp.MustCompile(`^success,`)
+	reSigResult         = regexp.MustCompile(`sig verify result:\s*(\S+)`)
+	reComputedStateRoot = regexp.MustCompile(`^Computed state_root from genesis alloc:`)
+	reReceiptsRootLine  = regexp.MustCompile(`^receipts_root:\s*(0x[0-9a-fA-F]{64})$`)
+	reComputedHex       = regexp.MustCompile(`^computed\s+(0x[0-9a-fA-F]{64})$`)
+	reReceivedBlock     = regexp.MustCompile(`^Received block:`)
+	// 注意：不打印超长的 verify, ...
+	// “Received block”长行中的字段由 schema.go 中注册的区块头形状抽取
+)
+
+func printTS(s string) {
+	fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), s)
+}
+
+// printTS 带验证者标签打印
+func (r *streamRunner) printTS(s string) {
+	printTS(r.tag + s)
+}
+
 // ValidateStreamFiltered 启动 ./mobile-sdk-test validate 并实时筛选关键输出；
 // 收到块后，通过 HTTP RPC (eth_getBlockByNumber) 查询该高度的 eth1 区块哈希。
 // wsURL:  验证者订阅用 WS 端点（如 ws://127.0.0.1:8546），仅注入给二进制。
 // httpURL: 执行层 HTTP RPC 端点（如 http://127.0.0.1:8545），用于区块查询。
 func ValidateStreamFiltered(ctx context.Context, validatorPrivHex string, wsURL string, httpURL string) error {
-	bin := "./mobile-sdk-test"
-	args := []string{"validate", "--validator-private-key", validatorPrivHex}
+	return ValidateStreamFilteredWithConfig(ctx, validatorPrivHex, wsURL, httpURL, StreamConfig{})
+}
 
-	cmd := exec.CommandContext(ctx, bin, args...)
+// ValidateStreamFilteredWithConfig 同 ValidateStreamFiltered，但可配置 slot 时长等参数。
+// 每次推送都有 slot 截止时间：到达推送闸门时已过期的不转发给二进制，区块查询过期即放弃，都计入 missed deadline；
+// 查询跟不上推送时，推送进入有界队列，按 cfg.QueuePolicy 取出，队列满时丢弃最旧的推送。
+// 看门狗发现订阅静默死亡（长时间无推送但链仍在出块）时，会重启二进制重新订阅。
+func ValidateStreamFilteredWithConfig(ctx context.Context, validatorPrivHex string, wsURL string, httpURL string, cfg StreamConfig) error {
+	r, err := newStreamRunner(validatorPrivHex, wsURL, httpURL, cfg)
+	if err != nil {
+		return err
+	}
+	return r.run(ctx)
+}
 
-	// 注入 WS 地址给二进制（用于订阅）
-	if wsURL != "" {
-		cmd.Env = append(os.Environ(), "RPC_URL="+wsURL)
+func newStreamRunner(validatorPrivHex string, wsURL string, httpURL string, cfg StreamConfig) (*streamRunner, error) {
+	schema, err := newSchemaNegotiator(cfg.HeaderSchema)
+	if err != nil {
+		return nil, err
+	}
+	tag := ""
+	if cfg.Name != "" {
+		tag = "[" + cfg.Name + "] "
+	}
+	r := &streamRunner{
+		privHex: validatorPrivHex,
+		wsURL:   wsURL,
+		httpURL: httpURL,
+		cfg:     cfg,
+		tag:     tag,
+		slot:    cfg.slotDuration(),
+		stats:   &streamStats{},
+		queue:   newPushQueue(cfg.QueueSize, cfg.QueuePolicy),
+		lat:     newLatencyTracker(4*cfg.slotDuration(), cfg.LatencyOut),
+		quar:    &quarantine{out: cfg.QuarantineOut, tag: tag},
+		schema:  schema,
+
+		subscribed: make(chan struct{}, 1),
+	}
+	if cfg.Progress != nil {
+		pk, err := blsutil.DerivePublicKeyHex(validatorPrivHex, blsutil.DefaultKeyOptions())
+		if err != nil {
+			return nil, fmt.Errorf("derive pubkey: %w", err)
+		}
+		r.pubkey = pk
+		r.resumeFrom = cfg.Progress.Last(pk)
+		r.lastAttested.Store(r.resumeFrom)
+		if r.resumeFrom > 0 {
+			r.printTS(fmt.Sprintf("Resuming after block #%d (last attested, from %s)", r.resumeFrom, cfg.Progress.Path))
+		}
+	}
+	r.lat.tag, r.lat.validator = tag, cfg.Name
+	r.heads.tag, r.schema.tag = tag, tag
+	// ===== HTTP RPC 客户端（查询区块哈希）=====
+	if httpURL != "" {
+		r.ethCli = beaconext.NewClient(httpURL)
+	}
+	return r, nil
+}
+
+// streamRunner 一次验证运行的共享状态；二进制重启（重新订阅）时保留
+type streamRunner struct {
+	privHex string
+	wsURL   string
+	httpURL string
+	cfg     StreamConfig
+	tag     string // 输出前缀，cfg.Name 为空时为空
+	slot    time.Duration
+
+	ethCli beaconext.BeaconReader
+	stats  *streamStats
+	queue  *pushQueue
+	heads  headTracker
+	incl   *inclusionChecker // 未开启 VerifyInclusion 时为 nil
+	rcpt   *receiptsChecker  // 未设置 ReceiptRules 时为 nil
+	lat    *latencyTracker
+	quar   *quarantine
+	schema *schemaNegotiator
+
+	// 最近一次推送的块号，用于计算处理时落后的块数
+	latestNumber atomic.Uint64
+	// 最近一次推送（或进程启动）的时间，UnixNano
+	lastPushAt atomic.Int64
+	// printEverySec 上次打印的秒（Unix）
+	lastPrintSecond atomic.Int64
+	// 二进制最近一次收到的推送块号，二进制报告成功时据此记录进度
+	lastPushed atomic.Uint64
+
+	pubkey       string        // 设置了 cfg.Progress 时为本验证者公钥
+	resumeFrom   uint64        // 启动时进度记录中的区块号，不高于它的推送视为重复
+	lastAttested atomic.Uint64 // 最近一次成功签名提交的区块号
+	seen         pushDedup     // 本次运行已收到推送的区块号（有推送闸门时在转发前记入）
+	gap          pushDedup     // 漏推检查已检查过的区块号
+	gate         *pushGate     // 推送闸门，未设置 WS 地址时为 nil
+	subscribed   chan struct{} // 二进制每次订阅成功时通知漏推检查
+}
+
+func (r *streamRunner) run(ctx context.Context) error {
+	// ===== slot 截止时间内的区块哈希查询（有界队列 + 积压策略）=====
+	workerCtx, stopWorker := context.WithCancel(ctx)
+	defer stopWorker()
+	if r.ethCli != nil {
+		go r.worker(workerCtx)
+	}
+	if r.wsURL != "" {
+		go r.heads.track(workerCtx, r.wsURL)
+		gate, err := r.startGate()
+		if err != nil {
+			return err
+		}
+		defer gate.Close()
+		r.gate = gate
+	} else {
+		r.printTS("Push dedup disabled: no WS endpoint to gate, the binary subscribes with its own default")
+	}
+	if r.ethCli != nil {
+		go r.gapCheckLoop(workerCtx)
+	}
+	if r.cfg.VerifyInclusion && r.ethCli != nil {
+		incl, err := newInclusionChecker(r)
+		if err != nil {
+			r.printTS(fmt.Sprintf("Inclusion check disabled: %v", err))
+		} else {
+			r.incl = incl
+			go incl.run(workerCtx)
+		}
+	}
+	if r.cfg.ReceiptRules != nil && r.httpURL != "" {
+		rc, err := newReceiptsChecker(ctx, r.httpURL, *r.cfg.ReceiptRules, r.cfg.ReceiptSelfCheck)
+		if err != nil {
+			r.printTS(fmt.Sprintf("Receipts root check disabled: %v", err))
+		} else {
+			rc.lat, rc.tag = r.lat, r.tag
+			r.rcpt = rc
+			r.printTS(fmt.Sprintf("Receipts root check enabled (%s, self_check=%t)", rc.rules, rc.selfCheck))
+			go rc.run(workerCtx)
+		}
+	}
+
+	for {
+		restart, err := r.runOnce(ctx)
+		if !restart || ctx.Err() != nil {
+			stopWorker()
+			r.lat.flush()
+			r.printTS("stats: " + r.stats.String())
+			r.printTS(r.lat.String())
+			if r.incl != nil {
+				r.printTS(r.incl.stats.String())
+			}
+			if r.rcpt != nil {
+				r.printTS(r.rcpt.stats.String())
+			}
+			// 结束时加一条分割线，便于阅读
+			fmt.Println("-------------------------------------------------------------")
+			return err
+		}
+		r.stats.restarts.Add(1)
+		r.printTS(fmt.Sprintf("Restarting validate to resubscribe (restarts=%d)", r.stats.restarts.Load()))
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// runOnce 启动一次二进制并处理其输出，直到进程退出。
+// 若是看门狗要求重连而结束，restart 为 true。
+func (r *streamRunner) runOnce(ctx context.Context) (restart bool, err error) {
+	procCtx, killProc := context.WithCancel(ctx)
+	defer killProc()
+
+	args := []string{"validate", "--validator-private-key", r.privHex}
+	cmd := exec.CommandContext(procCtx, "./mobile-sdk-test", args...)
+
+	// 注入 WS 地址给二进制（用于订阅）：指向推送闸门，由闸门转发到 r.wsURL
+	if r.gate != nil {
+		cmd.Env = append(os.Environ(), "RPC_URL="+r.gate.URL())
 	} else {
 		cmd.Env = os.Environ()
 	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("stdout pipe: %w", err)
+		return false, fmt.Errorf("stdout pipe: %w", err)
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return fmt.Errorf("stderr pipe: %w", err)
+		return false, fmt.Errorf("stderr pipe: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("start validate: %w", err)
-	}
-
-	// ===== HTTP RPC 客户端（查询区块哈希）=====
-	var ethCli *beaconext.Client
-	if httpURL != "" {
-		ethCli = beaconext.NewClient(httpURL)
+		return false, fmt.Errorf("start validate: %w", err)
 	}
+	r.lastPushAt.Store(time.Now().UnixNano())
 
-	// 关键行匹配
-	reConnected := regexp.MustCompile(`^Connected to (.+)$`)
-	reSubscribed := regexp.MustCompile(`Subscribed to 'subscribeToVerificationRequest'`)
-	reSuccess := regexp.MustCompile(`^success,`)
-	reSigResult := regexp.MustCompile(`sig verify result:\s*(\S+)`)
-	reComputedStateRoot := regexp.MustCompile(`^Computed state_root from genesis alloc:`)
-	reReceiptsRootLine := regexp.MustCompile(`^receipts_root:\s*(0x[0-9a-fA-F]{64})$`)
-	reComputedHex := regexp.MustCompile(`^computed\s+(0x[0-9a-fA-F]{64})$`)
-	reReceivedBlock := regexp.MustCompile(`^Received block:`)
-	// 注意：不打印超长的 verify, ...
-
-	// 从“Received block”长行中抽取字段
-	reNum := regexp.MustCompile(`\bnumber:\s*(\d+)`)
-	reParent := regexp.MustCompile(`\bparent_hash:\s*(0x[0-9a-fA-F]{64})`)
-	reState := regexp.MustCompile(`\bstate_root:\s*(0x[0-9a-fA-F]{64})`)
-	reReceipt := regexp.MustCompile(`\breceipts_root:\s*(0x[0-9a-fA-F]{64})`)
-	reReq := regexp.MustCompile(`\brequests_hash:\s*Some\((0x[0-9a-fA-F]{64})\)`)
-
-	printTS := func(s string) {
-		fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), s)
-	}
+	// 看门狗：订阅静默死亡时杀掉进程，由 run 重新启动
+	var restartRequested atomic.Bool
+	go r.watchdog(procCtx, func(reason string) {
+		r.printTS("ALERT: subscription watchdog: " + reason + "; forcing reconnect")
+		restartRequested.Store(true)
+		killProc()
+	})
 
 	// 实时读取 stdout
 	go func() {
@@ -81,80 +257,11 @@ func ValidateStreamFiltered(ctx context.Context, validatorPrivHex string, wsURL
 		sc.Buffer(buf, 1024*1024)
 
 		for sc.Scan() {
-			line := sc.Text()
-
-			switch {
-			case reConnected.MatchString(line):
-				// 连接到执行层 WS
-				m := reConnected.FindStringSubmatch(line)
-				if len(m) >= 2 {
-					printTS(fmt.Sprintf("Connected to execution node %s", strings.TrimSpace(m[1])))
-				} else {
-					printTS(line)
-				}
-
-			case reSubscribed.MatchString(line):
-				// 订阅验证请求流成功
-				printTS("Subscribed to verification request stream")
-
-			case reReceivedBlock.MatchString(line):
-				// 收到待验证区块，抽取关键信息
-				number := firstSub(reNum, line)
-				parent := firstSub(reParent, line)
-				state := firstSub(reState, line)
-				rroot := firstSub(reReceipt, line)
-				req := firstSub(reReq, line)
-
-				// 单独打印块号
-				printTS(fmt.Sprintf("Block #%s", emptyDash(number)))
-
-				// 打印头部摘要
-				printTS(fmt.Sprintf("  parent_hash = %s", emptyDash(parent)))
-				if state != "" {
-					printTS("  state_root = " + state)
-				}
-				if rroot != "" {
-					printTS("  receipts_root = " + rroot)
-				}
-				if req != "" {
-					printTS("  requests_hash = " + req)
-				}
-
-				// 通过 HTTP RPC 查询 eth1 的区块哈希（等待 HTTP 节点追上 & 重试）
-				if ethCli != nil && number != "" {
-					if h, err := queryEth1HashByNumberWait(ctx, ethCli, number, httpURL); err == nil && h != "" {
-						printTS(fmt.Sprintf("Eth1 block hash (via RPC@%s) = %s", httpURL, h))
-					} else if err != nil {
-						printTS(fmt.Sprintf("Eth1 block hash query failed: %v", err))
-					}
-				}
-
-			case reSuccess.MatchString(line):
-				// 执行成功（压缩显示详细内容）
-				printTS("Block execution success (details: " + trimAfter(line, "success,") + ")")
-
-			case reSigResult.MatchString(line):
-				// BLS 签名验证结果
-				printTS(line)
-				fmt.Println("------------------------------------------")
-
-			case reComputedStateRoot.MatchString(line):
-				// 基于创世分配计算出的 state_root（用于比对）
-				printTS(line)
-
-			case reComputedHex.MatchString(line):
-				// 通常是本地重算的 receipts_root
-				printTS(line)
-
-			case reReceiptsRootLine.MatchString(line):
-				// 区块头里的 receipts_root
-				printTS(line)
-
-				// 其余行忽略（尤其是不打印超长的 verify, ...）
-			}
+			r.handleLine(sc.Text())
 		}
-		if err := sc.Err(); err != nil {
-			printTS(fmt.Sprintf("stdout scanner error: %v", err))
+		// 进程被杀掉时管道已关闭，不算错误
+		if err := sc.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+			r.printTS(fmt.Sprintf("stdout scanner error: %v", err))
 		}
 	}()
 
@@ -165,30 +272,210 @@ func ValidateStreamFiltered(ctx context.Context, validatorPrivHex string, wsURL
 		for sc.Scan() {
 			line := sc.Text()
 			if len(line) > 0 {
-				printTS("[stderr] " + line)
+				r.printTS("[stderr] " + line)
 			}
 		}
 	}()
 
 	// 等待进程退出
 	waitErr := cmd.Wait()
-	// 结束时加一条分割线，便于阅读
-	fmt.Println("-------------------------------------------------------------")
+	if restartRequested.Load() {
+		return true, nil
+	}
 	if waitErr != nil {
-		return fmt.Errorf("validate exit: %w", waitErr)
+		return false, fmt.Errorf("validate exit: %w", waitErr)
+	}
+	return false, nil
+}
+
+// handleLine 处理二进制 stdout 的一行
+func (r *streamRunner) handleLine(line string) {
+	switch {
+	case reConnected.MatchString(line):
+		// 连接到执行层 WS
+		m := reConnected.FindStringSubmatch(line)
+		if len(m) >= 2 {
+			r.printTS(fmt.Sprintf("Connected to execution node %s", strings.TrimSpace(m[1])))
+		} else {
+			r.printTS(line)
+		}
+
+	case reSubscribed.MatchString(line):
+		// 订阅验证请求流成功
+		r.printTS("Subscribed to verification request stream")
+		if r.cfg.OnSubscribed != nil {
+			r.cfg.OnSubscribed()
+		}
+		select {
+		case r.subscribed <- struct{}{}:
+		default:
+		}
+
+	case reReceivedBlock.MatchString(line):
+		// 收到待验证区块，抽取关键信息；解析有问题的推送原文进隔离文件，拿不到块号的直接丢弃
+		schema, f, problems, fatal := r.schema.decode(line)
+		number, parent, state, rroot, req, ts := f.Number, f.Parent, f.State, f.ReceiptsRoot, f.RequestsHash, f.Timestamp
+		now := time.Now()
+		r.stats.received.Add(1)
+		r.lastPushAt.Store(now.UnixNano())
+		if len(problems) > 0 {
+			r.quarantinePush(line, schema, f, problems, fatal)
+			if fatal {
+				return
+			}
+		}
+
+		n, nerr := strconv.ParseUint(number, 10, 64)
+		if nerr == nil {
+			r.lastPushed.Store(n)
+			// 有推送闸门时已在转发前记入；没有时只记录，供漏推检查判断
+			r.seen.add(n)
+		}
+
+		// 单独打印块号
+		r.printTS(fmt.Sprintf("Block #%s", emptyDash(number)))
+
+		// 打印头部摘要
+		r.printTS(fmt.Sprintf("  parent_hash = %s", emptyDash(parent)))
+		if state != "" {
+			r.printTS("  state_root = " + state)
+		}
+		if rroot != "" {
+			r.printTS("  receipts_root = " + rroot)
+		}
+		if req != "" {
+			r.printTS("  requests_hash = " + req)
+		}
+
+		if nerr == nil {
+			if n > r.latestNumber.Load() {
+				r.latestNumber.Store(n)
+			}
+			r.lat.begin(n, now, pushDelay(ts, now), r.expectedStages())
+		}
+
+		// 交给后台查询 eth1 区块哈希（等待 HTTP 节点追上 & 重试），不阻塞读取输出
+		if r.ethCli != nil && number != "" {
+			p := newBlockPush(number, ts, now)
+			p.ReceiptsRoot = rroot
+			depth, dropped := r.queue.put(p)
+			r.stats.observeDepth(depth)
+			if dropped != nil {
+				r.stats.dropped.Add(1)
+				r.lat.skip(dropped.height(), stageQueue, stageVisibility, stageHash, stageReceiptsFetch, stageRootCompute)
+				r.printTS(fmt.Sprintf("Queue full (%d), dropped block #%s", depth, dropped.Number))
+			}
+		}
+
+	case reSuccess.MatchString(line):
+		// 执行成功（压缩显示详细内容）
+		r.printTS("Block execution success (details: " + trimAfter(line, "success,") + ")")
+		r.stats.attested.Add(1)
+		if n := r.lastPushed.Load(); n > 0 {
+			r.attested(n)
+		}
+		if n := r.latestNumber.Load(); n > 0 {
+			r.lat.signed(n, time.Now())
+			if r.incl != nil {
+				r.incl.submit(submission{Number: n, At: time.Now()})
+			}
+		}
+
+	case reSigResult.MatchString(line):
+		// BLS 签名验证结果
+		r.printTS(line)
+		if n := r.latestNumber.Load(); n > 0 {
+			r.lat.submitted(n, time.Now())
+		}
+		fmt.Println("------------------------------------------")
+
+	case reComputedStateRoot.MatchString(line):
+		// 基于创世分配计算出的 state_root（用于比对）
+		r.printTS(line)
+
+	case reComputedHex.MatchString(line):
+		// 通常是本地重算的 receipts_root
+		r.printTS(line)
+
+	case reReceiptsRootLine.MatchString(line):
+		// 区块头里的 receipts_root
+		r.printTS(line)
+
+		// 其余行忽略（尤其是不打印超长的 verify, ...）
+	}
+}
+
+// worker 按队列策略取出推送，在 slot 截止时间内查询对应的 eth1 区块哈希
+func (r *streamRunner) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.queue.notify:
+		}
+		p, remaining, ok := r.queue.take()
+		if !ok {
+			continue
+		}
+		lag := time.Since(p.ReceivedAt)
+		r.stats.observeLag(lag)
+		r.lat.observe(p.height(), stageQueue, lag)
+		behind := uint64(0)
+		if n, err := strconv.ParseUint(p.Number, 10, 64); err == nil && r.latestNumber.Load() > n {
+			behind = r.latestNumber.Load() - n
+		}
+
+		deadline := p.deadline(r.slot)
+		if time.Now().After(deadline) {
+			r.stats.missedDeadline.Add(1)
+			r.printTS(fmt.Sprintf("Block #%s missed deadline before start (deadline %s, lag=%s, behind=%d, queue=%d, missed=%d)",
+				p.Number, deadline.Format("15:04:05"), lag.Round(time.Millisecond), behind, remaining, r.stats.missedDeadline.Load()))
+			r.lat.skip(p.height(), stageVisibility, stageHash, stageReceiptsFetch, stageRootCompute)
+			continue
+		}
+
+		qctx, cancel := context.WithDeadline(ctx, deadline)
+		began := time.Now()
+		h, visible, err := r.queryEth1HashByNumberWait(qctx, p.Number)
+		elapsed := time.Since(began)
+		cancel()
+		if visible >= 0 {
+			r.lat.observe(p.height(), stageVisibility, visible)
+		}
+		if err != nil || h == "" {
+			r.lat.skip(p.height(), stageVisibility, stageHash, stageReceiptsFetch, stageRootCompute)
+		}
+		switch {
+		case err == nil && h != "":
+			r.lat.observe(p.height(), stageHash, elapsed-visible)
+			r.stats.processed.Add(1)
+			r.printTS(fmt.Sprintf("Eth1 block hash (via RPC@%s) = %s [#%s, %s after push, lag=%s, behind=%d, queue=%d]",
+				r.httpURL, h, p.Number, time.Since(p.ReceivedAt).Round(time.Millisecond), lag.Round(time.Millisecond), behind, remaining))
+			if r.rcpt != nil {
+				r.rcpt.submit(p)
+			}
+		case errors.Is(err, context.DeadlineExceeded):
+			r.stats.missedDeadline.Add(1)
+			r.printTS(fmt.Sprintf("Block #%s missed deadline %s (missed=%d)",
+				p.Number, deadline.Format("15:04:05"), r.stats.missedDeadline.Load()))
+		case err != nil:
+			r.printTS(fmt.Sprintf("Eth1 block hash query failed: %v", err))
+		}
 	}
-	return nil
 }
 
 // 等待 HTTP 节点追上目标高度后，再查询该高度的区块哈希。
 // - 先轮询 latest（通过 tag="latest"），若 latest < 目标块高，则等待；
 // - 当 latest >= 目标块高时，再对该高度做多次重试查询；
 // - 都失败则返回最后一次错误。
-func queryEth1HashByNumberWait(ctx context.Context, cli *beaconext.Client, numberDec string, httpURL string) (string, error) {
+// visible 为等待节点追上目标高度的耗时；未追上时为 -1。
+func (r *streamRunner) queryEth1HashByNumberWait(ctx context.Context, numberDec string) (hash string, visible time.Duration, err error) {
+	cli, httpURL := r.ethCli, r.httpURL
 	target, err := strconv.ParseUint(numberDec, 10, 64)
 	if err != nil {
-		return "", fmt.Errorf("parse block number '%s': %w", numberDec, err)
+		return "", -1, fmt.Errorf("parse block number '%s': %w", numberDec, err)
 	}
+	began := time.Now()
 
 	// 1) 等待 latest >= target
 	const (
@@ -205,21 +492,22 @@ func queryEth1HashByNumberWait(ctx context.Context, cli *beaconext.Client, numbe
 			}
 			// 提示 HTTP 节点还没追上
 			//（只在首次或每秒打印一次以免刷屏）
-			// printEverySec(fmt.Sprintf("HTTP node @%s latest=%d, waiting to reach target=%d ...", httpURL, latest, target))
+			// r.printEverySec(fmt.Sprintf("HTTP node @%s latest=%d, waiting to reach target=%d ...", httpURL, latest, target))
 		} else {
 			// latest 获取失败也继续短暂等待后重试
-			printEverySec(fmt.Sprintf("HTTP node @%s latest query error: %v (will retry)", httpURL, err))
+			r.printEverySec(fmt.Sprintf("HTTP node @%s latest query error: %v (will retry)", httpURL, err))
 		}
 
 		if time.Now().After(deadlineLatest) {
-			return "", fmt.Errorf("http node did not catch up to %d within %s", target, latestMaxWait)
+			return "", -1, fmt.Errorf("http node did not catch up to %d within %s", target, latestMaxWait)
 		}
 		select {
 		case <-ctx.Done():
-			return "", ctx.Err()
+			return "", -1, ctx.Err()
 		case <-time.After(latestPollInterval):
 		}
 	}
+	visible = time.Since(began)
 
 	// 2) 查询目标高度的 hash（多次重试）
 	const (
@@ -232,7 +520,7 @@ func queryEth1HashByNumberWait(ctx context.Context, cli *beaconext.Client, numbe
 	for i := 0; i < attempts; i++ {
 		blk, err := cli.EthGetBlockByNumber(ctx, tag, false)
 		if err == nil && blk != nil && blk.Hash != "" && blk.Hash != "0x" {
-			return blk.Hash, nil
+			return blk.Hash, visible, nil
 		}
 		if err == nil {
 			lastErr = fmt.Errorf("empty result")
@@ -241,15 +529,15 @@ func queryEth1HashByNumberWait(ctx context.Context, cli *beaconext.Client, numbe
 		}
 		select {
 		case <-ctx.Done():
-			return "", ctx.Err()
+			return "", visible, ctx.Err()
 		case <-time.After(backoff):
 		}
 	}
-	return "", lastErr
+	return "", visible, lastErr
 }
 
 // 查询 latest 的区块号（十六进制转为十进制）
-func getLatestNumber(ctx context.Context, cli *beaconext.Client) (uint64, error) {
+func getLatestNumber(ctx context.Context, cli beaconext.BeaconReader) (uint64, error) {
 	blk, err := cli.EthGetBlockByNumber(ctx, "latest", false)
 	if err != nil {
 		return 0, err
@@ -269,14 +557,12 @@ func getLatestNumber(ctx context.Context, cli *beaconext.Client) (uint64, error)
 	return u, nil
 }
 
-var lastPrintSecond int64
-
-// 每秒最多打印一次提示，避免刷屏
-func printEverySec(s string) {
+// printEverySec 每秒最多打印一次提示，避免刷屏；worker 与看门狗在各自的 goroutine 中调用，舰队中每个验证者各自计时
+func (r *streamRunner) printEverySec(s string) {
 	now := time.Now().Unix()
-	if now != lastPrintSecond {
-		lastPrintSecond = now
-		fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), s)
+	last := r.lastPrintSecond.Load()
+	if now != last && r.lastPrintSecond.CompareAndSwap(last, now) {
+		r.printTS(s)
 	}
 }
 
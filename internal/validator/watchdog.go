@@ -0,0 +1,80 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultWatchdogSlots 无推送超过多少个 slot 视为订阅可能已静默死亡
+const DefaultWatchdogSlots = 3
+
+func (c StreamConfig) watchdogTimeout() time.Duration {
+	switch {
+	case c.WatchdogSlots < 0:
+		return 0
+	case c.WatchdogSlots == 0:
+		return DefaultWatchdogSlots * c.slotDuration()
+	default:
+		return time.Duration(c.WatchdogSlots) * c.slotDuration()
+	}
+}
+
+// watchdog 在 runOnce 期间运行：距上次推送超过阈值、且通过 HTTP 确认链仍在出块时，
+// 调用 restart 触发重连。链本身停止出块时只打印提示，不重连。
+func (r *streamRunner) watchdog(ctx context.Context, restart func(reason string)) {
+	threshold := r.cfg.watchdogTimeout()
+	if threshold <= 0 || r.ethCli == nil {
+		return
+	}
+
+	// 启动时的链高度，作为“尚未收到任何推送”时判断链是否前进的基准
+	var headAtStart uint64
+	if h, err := r.chainHead(ctx); err == nil {
+		headAtStart = h
+	}
+
+	interval := threshold / 3
+	if interval < time.Second {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		idle := time.Since(time.Unix(0, r.lastPushAt.Load()))
+		if idle < threshold {
+			continue
+		}
+
+		latest, err := r.chainHead(ctx)
+		if err != nil {
+			r.printEverySec(fmt.Sprintf("watchdog: no push for %s, latest block query failed: %v", idle.Round(time.Second), err))
+			continue
+		}
+		if headAtStart == 0 {
+			headAtStart = latest
+			continue
+		}
+
+		base := r.latestNumber.Load()
+		if headAtStart > base {
+			base = headAtStart
+		}
+		if latest <= base {
+			r.printEverySec(fmt.Sprintf("watchdog: no push for %s, chain head #%d not advancing", idle.Round(time.Second), latest))
+			continue
+		}
+
+		r.stats.watchdogAlerts.Add(1)
+		restart(fmt.Sprintf("no push for %s while chain advanced to #%d (last pushed #%d)",
+			idle.Round(time.Second), latest, r.latestNumber.Load()))
+		return
+	}
+}
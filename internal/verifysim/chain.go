@@ -0,0 +1,327 @@
+package verifysim
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// 区块形状：决定合成区块里的交易与区块头字段
+const (
+	ShapeEmpty     = "empty"     // 无交易，receipts_root 为空根
+	ShapeTransfers = "transfers" // 1..MaxTxs 笔普通转账
+	ShapeLogs      = "logs"      // 1..MaxTxs 笔带日志的交易（非空 bloom）
+	ShapeRequests  = "requests"  // 少量转账，区块头带 requests_hash（Prague 形状）
+)
+
+// ShapeNames 全部区块形状
+func ShapeNames() []string { return []string{ShapeEmpty, ShapeTransfers, ShapeLogs, ShapeRequests} }
+
+const gasPerTransfer = 21000
+
+// simBlock 合成区块；区块头、交易与收据自洽，HTTP 查询与 WS 推送看到的是同一个块
+type simBlock struct {
+	header   *types.Header
+	hash     common.Hash
+	txs      types.Transactions
+	receipts types.Receipts
+	shape    string
+	at       time.Time // 产生时间
+}
+
+// chain 内存中的合成链，只保留最近 history 个块
+type chain struct {
+	mu        sync.RWMutex
+	chainID   *big.Int
+	signer    types.Signer
+	key       *ecdsa.PrivateKey
+	from      common.Address
+	nonce     uint64
+	emptyRoot common.Hash
+	maxTxs    int
+	history   int
+
+	blocks []*simBlock // 按高度递增
+	byHash map[common.Hash]*simBlock
+	txs    map[common.Hash]txLoc
+}
+
+type txLoc struct {
+	block *simBlock
+	index int
+}
+
+func newChain(chainID uint64, emptyRoot common.Hash, maxTxs, history int) (*chain, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	id := new(big.Int).SetUint64(chainID)
+	c := &chain{
+		chainID: id, signer: types.LatestSignerForChainID(id), key: key, from: crypto.PubkeyToAddress(key.PublicKey),
+		emptyRoot: emptyRoot, maxTxs: maxTxs, history: history,
+		byHash: map[common.Hash]*simBlock{}, txs: map[common.Hash]txLoc{},
+	}
+	// 创世块：高度 0，空块
+	genesis := &types.Header{
+		UncleHash: types.EmptyUncleHash, TxHash: types.EmptyTxsHash, ReceiptHash: emptyRoot,
+		Difficulty: new(big.Int), Number: new(big.Int), GasLimit: 30_000_000, Time: uint64(time.Now().Unix()),
+		BaseFee: big.NewInt(1_000_000_000),
+	}
+	c.append(&simBlock{header: genesis, hash: genesis.Hash(), shape: ShapeEmpty, at: time.Now()})
+	return c, nil
+}
+
+// next 按 shape 产生下一个块并追加到链上
+func (c *chain) next(rng *rand.Rand, shape string) (*simBlock, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	parent := c.blocks[len(c.blocks)-1]
+	now := time.Now()
+	h := &types.Header{
+		ParentHash:       parent.hash,
+		UncleHash:        types.EmptyUncleHash,
+		Coinbase:         randAddress(rng),
+		Root:             randHash(rng),
+		Difficulty:       new(big.Int),
+		Number:           new(big.Int).Add(parent.header.Number, common.Big1),
+		GasLimit:         30_000_000,
+		Time:             max(uint64(now.Unix()), parent.header.Time+1),
+		Extra:            []byte("verifysim"),
+		BaseFee:          big.NewInt(1_000_000_000),
+		WithdrawalsHash:  &types.EmptyWithdrawalsHash,
+		BlobGasUsed:      new(uint64),
+		ExcessBlobGas:    new(uint64),
+		ParentBeaconRoot: new(common.Hash),
+	}
+
+	var n int
+	switch shape {
+	case ShapeEmpty:
+	case ShapeTransfers, ShapeLogs:
+		n = 1 + rng.Intn(c.maxTxs)
+	case ShapeRequests:
+		n = rng.Intn(3)
+		rh := types.EmptyRequestsHash
+		h.RequestsHash = &rh
+	default:
+		return nil, fmt.Errorf("unknown block shape %q", shape)
+	}
+
+	b := &simBlock{header: h, shape: shape, at: now}
+	var cumulative uint64
+	for i := 0; i < n; i++ {
+		tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+			Nonce: c.nonce, GasPrice: h.BaseFee, Gas: gasPerTransfer * 2, To: ptr(randAddress(rng)), Value: big.NewInt(int64(rng.Intn(1_000_000) + 1)),
+		}), c.signer, c.key)
+		if err != nil {
+			return nil, err
+		}
+		c.nonce++
+		cumulative += gasPerTransfer
+		r := &types.Receipt{
+			Type: tx.Type(), Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: cumulative,
+			TxHash: tx.Hash(), GasUsed: gasPerTransfer, EffectiveGasPrice: h.BaseFee,
+			BlockNumber: h.Number, TransactionIndex: uint(i), Logs: []*types.Log{},
+		}
+		if shape == ShapeLogs {
+			r.Logs = append(r.Logs, &types.Log{
+				Address: *tx.To(), Topics: []common.Hash{randHash(rng), randHash(rng)}, Data: randHash(rng).Bytes(),
+				BlockNumber: h.Number.Uint64(), TxHash: tx.Hash(), TxIndex: uint(i), Index: uint(i),
+			})
+		}
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+		b.txs = append(b.txs, tx)
+		b.receipts = append(b.receipts, r)
+	}
+
+	h.GasUsed = cumulative
+	h.Bloom = types.CreateBloom(b.receipts)
+	if n == 0 {
+		h.TxHash, h.ReceiptHash = types.EmptyTxsHash, c.emptyRoot
+	} else {
+		h.TxHash = types.DeriveSha(b.txs, trie.NewStackTrie(nil))
+		h.ReceiptHash = types.DeriveSha(b.receipts, trie.NewStackTrie(nil))
+	}
+	b.hash = h.Hash()
+	for _, r := range b.receipts {
+		r.BlockHash = b.hash
+		for _, l := range r.Logs {
+			l.BlockHash = b.hash
+		}
+	}
+	c.append(b)
+	return b, nil
+}
+
+// append 调用方持有 c.mu（newChain 除外）
+func (c *chain) append(b *simBlock) {
+	c.blocks = append(c.blocks, b)
+	c.byHash[b.hash] = b
+	for i, tx := range b.txs {
+		c.txs[tx.Hash()] = txLoc{block: b, index: i}
+	}
+	if len(c.blocks) > c.history {
+		old := c.blocks[0]
+		c.blocks = c.blocks[1:]
+		delete(c.byHash, old.hash)
+		for _, tx := range old.txs {
+			delete(c.txs, tx.Hash())
+		}
+	}
+}
+
+func (c *chain) head() *simBlock {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.blocks[len(c.blocks)-1]
+}
+
+func (c *chain) byNumber(n uint64) *simBlock {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	first := c.blocks[0].header.Number.Uint64()
+	if n < first || n-first >= uint64(len(c.blocks)) {
+		return nil
+	}
+	return c.blocks[n-first]
+}
+
+func (c *chain) lookup(hash common.Hash) *simBlock {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.byHash[hash]
+}
+
+func (c *chain) lookupTx(hash common.Hash) (txLoc, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	loc, ok := c.txs[hash]
+	return loc, ok
+}
+
+// resolve 块号参数：latest/pending/safe/finalized、earliest、十六进制块号或区块哈希
+func (c *chain) resolve(arg string) (*simBlock, error) {
+	switch arg {
+	case "latest", "pending", "safe", "finalized":
+		return c.head(), nil
+	case "earliest":
+		return c.byNumber(0), nil
+	}
+	if len(arg) == 66 {
+		return c.lookup(common.HexToHash(arg)), nil
+	}
+	n, err := hexutil.DecodeUint64(arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block number %q: %w", arg, err)
+	}
+	return c.byNumber(n), nil
+}
+
+// -------------------- JSON 形状 --------------------
+
+// headerJSON 区块头的 JSON（与节点返回的字段名一致：requests_hash 用 requestsHash）
+func headerJSON(h *types.Header) map[string]any {
+	raw, _ := json.Marshal(h)
+	var m map[string]any
+	json.Unmarshal(raw, &m)
+	delete(m, "hash")
+	if v, ok := m["requestsRoot"]; ok {
+		delete(m, "requestsRoot")
+		if v != nil {
+			m["requestsHash"] = v
+		}
+	}
+	for k, v := range m {
+		if v == nil {
+			delete(m, k)
+		}
+	}
+	return m
+}
+
+// txJSON 交易对象；带 blockHash 等位置字段，与 eth_getTransactionByHash 一致
+func (b *simBlock) txJSON(i int, from common.Address) map[string]any {
+	raw, _ := b.txs[i].MarshalJSON()
+	var m map[string]any
+	json.Unmarshal(raw, &m)
+	m["from"] = from
+	m["blockHash"] = b.hash
+	m["blockNumber"] = (*hexutil.Big)(b.header.Number)
+	m["transactionIndex"] = hexutil.Uint64(i)
+	return m
+}
+
+// rpcBlock eth_getBlockByNumber/eth_getBlockByHash 的返回
+func (c *chain) rpcBlock(b *simBlock, fullTx bool) map[string]any {
+	m := headerJSON(b.header)
+	m["hash"] = b.hash
+	m["uncles"] = []common.Hash{}
+	m["withdrawals"] = []any{}
+	m["totalDifficulty"] = "0x0"
+	m["size"] = hexutil.Uint64(b.header.Size())
+	txs := make([]any, len(b.txs))
+	for i, tx := range b.txs {
+		if fullTx {
+			txs[i] = b.txJSON(i, c.from)
+		} else {
+			txs[i] = tx.Hash()
+		}
+	}
+	m["transactions"] = txs
+	return m
+}
+
+// pushPayload 推送负载：按二进制反序列化的 UnverifiedBlock 组织
+// （blockbody 为 SealedBlock{header, body}，db 为执行所需的预读状态，这里为空）
+func (c *chain) pushPayload(b *simBlock) map[string]any {
+	txs := make([]any, len(b.txs))
+	for i := range b.txs {
+		raw, _ := b.txs[i].MarshalJSON()
+		txs[i] = json.RawMessage(raw)
+	}
+	return map[string]any{
+		"blockbody": map[string]any{
+			"header": map[string]any{"header": headerJSON(b.header)},
+			"body":   map[string]any{"transactions": txs, "ommers": []any{}, "withdrawals": []any{}},
+		},
+		"db":              map[string]any{"accounts": map[string]any{}, "contracts": map[string]any{}, "block_hashes": map[string]any{}},
+		"td":              "0x0",
+		"committee_index": 0,
+	}
+}
+
+// -------------------- 工具 --------------------
+
+func randHash(rng *rand.Rand) (h common.Hash) {
+	rng.Read(h[:])
+	return h
+}
+
+func randAddress(rng *rand.Rand) (a common.Address) {
+	rng.Read(a[:])
+	return a
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// parseHash 接受带或不带 0x 前缀的 32 字节哈希（二进制提交的是 hex::encode，不带前缀）
+func parseHash(s string) (common.Hash, bool) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	b, err := hexutil.Decode("0x" + s)
+	if err != nil || len(b) != common.HashLength {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(b), true
+}
@@ -0,0 +1,681 @@
+// 模拟验证请求服务器：在本地合成一条链，按设定速率以 consensusBeaconExt 订阅推送区块（可混入各种畸形负载），
+// 接受 consensusBeaconExt_submitVerification 提交并统计；同一端口同时应答 HTTP JSON-RPC 的 eth_ 查询，
+// 查到的区块哈希、收据与推送的区块一致。用于在没有共识节点时开发、压测 attest 运行器与其输出解析。
+package verifysim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gorilla/websocket"
+)
+
+// 订阅与提交的方法名
+const (
+	SubscribeMethod    = "consensusBeaconExt_subscribeToVerificationRequest"
+	UnsubscribeMethod  = "consensusBeaconExt_unsubscribeToVerificationRequest"
+	SubmitMethod       = "consensusBeaconExt_submitVerification"
+	NotificationMethod = SubscribeMethod // 推送通知沿用订阅方法名（客户端按订阅 id 分发）
+)
+
+// 畸形推送的种类
+const (
+	MalformedBadJSON       = "bad-json"       // 截断的 JSON 文本
+	MalformedMissingHeader = "missing-header" // blockbody 里缺少 header
+	MalformedBadHex        = "bad-hex"        // number 不是合法十六进制
+	MalformedWrongType     = "wrong-type"     // result 是字符串而不是对象
+	MalformedUnknownSub    = "unknown-sub"    // 订阅 id 不存在
+)
+
+// MalformedKinds 全部畸形推送种类
+func MalformedKinds() []string {
+	return []string{MalformedBadJSON, MalformedMissingHeader, MalformedBadHex, MalformedWrongType, MalformedUnknownSub}
+}
+
+const (
+	DefaultRate    = 1.0
+	DefaultMaxTxs  = 16
+	DefaultHistory = 1024
+	DefaultChainID = 1337
+	writeTimeout   = 5 * time.Second
+)
+
+// Config 零值可用（每秒 1 块，全部形状，不发畸形推送）
+type Config struct {
+	Rate              float64     // 每秒出块（推送）数；<=0 表示 1
+	Count             int         // 出块数上限；0 表示不限
+	Shapes            []string    // 随机选用的区块形状；为空表示全部
+	MaxTxs            int         // transfers/logs 形状每块最多交易数；0 表示 16
+	MalformedRate     float64     // 推送被替换为畸形负载的比例 [0,1]
+	MalformedKinds    []string    // 随机选用的畸形种类；为空表示全部
+	ChainID           uint64      // 0 表示 1337
+	EmptyReceiptsRoot common.Hash // 空块的 receipts_root；零值表示以太坊空根
+	History           int         // 保留可查询的块数；0 表示 1024
+	Seed              int64       // 随机种子；0 表示按时间
+	Logf              func(format string, args ...any)
+}
+
+// Server 实现 http.Handler：WebSocket 升级请求走订阅，其余按 HTTP JSON-RPC 处理
+type Server struct {
+	cfg   Config
+	chain *chain
+	rng   *rand.Rand // 只在 Run 的 goroutine 里使用
+
+	mu     sync.Mutex
+	conns  map[*wsConn]struct{}
+	pushed map[common.Hash]*submitState
+	subSeq uint64
+	stats  Stats
+
+	httpReqs atomic.Int64
+}
+
+type submitState struct {
+	at        time.Time
+	submitted bool
+}
+
+// Stats 运行统计
+type Stats struct {
+	Blocks        int            `json:"blocks"`
+	ByShape       map[string]int `json:"by_shape"`
+	Notifications int            `json:"notifications"` // 写出的推送条数（每个订阅各算一条）
+	Malformed     map[string]int `json:"malformed"`     // 按种类统计的畸形推送（按块计）
+	Subscriptions int            `json:"subscriptions"`
+	Connections   int            `json:"connections"` // 当前连接数
+	WriteErrors   int            `json:"write_errors"`
+	HTTPRequests  int64          `json:"http_requests"`
+
+	Accepted     int `json:"submit_accepted"`
+	Duplicate    int `json:"submit_duplicate"`
+	UnknownBlock int `json:"submit_unknown_block"`
+	Rejected     int `json:"submit_rejected"` // 参数不合法
+
+	SubmitLatencyTotal time.Duration `json:"-"`
+	SubmitLatencyMax   time.Duration `json:"-"`
+}
+
+// New 校验配置并建链
+func New(cfg Config) (*Server, error) {
+	if cfg.Rate <= 0 {
+		cfg.Rate = DefaultRate
+	}
+	if len(cfg.Shapes) == 0 {
+		cfg.Shapes = ShapeNames()
+	}
+	for _, s := range cfg.Shapes {
+		if !slices.Contains(ShapeNames(), s) {
+			return nil, fmt.Errorf("verifysim: unknown block shape %q (want %s)", s, strings.Join(ShapeNames(), "|"))
+		}
+	}
+	if cfg.MalformedRate < 0 || cfg.MalformedRate > 1 {
+		return nil, fmt.Errorf("verifysim: malformed rate %v out of [0,1]", cfg.MalformedRate)
+	}
+	if len(cfg.MalformedKinds) == 0 {
+		cfg.MalformedKinds = MalformedKinds()
+	}
+	for _, k := range cfg.MalformedKinds {
+		if !slices.Contains(MalformedKinds(), k) {
+			return nil, fmt.Errorf("verifysim: unknown malformed kind %q (want %s)", k, strings.Join(MalformedKinds(), "|"))
+		}
+	}
+	if cfg.MaxTxs <= 0 {
+		cfg.MaxTxs = DefaultMaxTxs
+	}
+	if cfg.ChainID == 0 {
+		cfg.ChainID = DefaultChainID
+	}
+	if cfg.EmptyReceiptsRoot == (common.Hash{}) {
+		cfg.EmptyReceiptsRoot = types.EmptyReceiptsHash
+	}
+	if cfg.History <= 0 {
+		cfg.History = DefaultHistory
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	if cfg.Logf == nil {
+		cfg.Logf = log.Printf
+	}
+	c, err := newChain(cfg.ChainID, cfg.EmptyReceiptsRoot, cfg.MaxTxs, cfg.History)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		cfg: cfg, chain: c, rng: rand.New(rand.NewSource(cfg.Seed)),
+		conns: map[*wsConn]struct{}{}, pushed: map[common.Hash]*submitState{},
+		stats: Stats{ByShape: map[string]int{}, Malformed: map[string]int{}},
+	}, nil
+}
+
+// Run 按速率出块并推送，直到 ctx 结束或达到 Count；连接保持打开，结束时由调用方 Close
+func (s *Server) Run(ctx context.Context) error {
+	t := time.NewTicker(time.Duration(float64(time.Second) / s.cfg.Rate))
+	defer t.Stop()
+	for n := 0; s.cfg.Count == 0 || n < s.cfg.Count; n++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		shape := s.cfg.Shapes[s.rng.Intn(len(s.cfg.Shapes))]
+		b, err := s.chain.next(s.rng, shape)
+		if err != nil {
+			return err
+		}
+		malformed := ""
+		if s.cfg.MalformedRate > 0 && s.rng.Float64() < s.cfg.MalformedRate {
+			malformed = s.cfg.MalformedKinds[s.rng.Intn(len(s.cfg.MalformedKinds))]
+		}
+		s.publish(b, malformed)
+	}
+	return nil
+}
+
+// publish 先推 newHeads，再推验证请求；畸形推送的块仍然上链（HTTP 可查），只是推送内容损坏
+func (s *Server) publish(b *simBlock, malformed string) {
+	s.mu.Lock()
+	s.stats.Blocks++
+	s.stats.ByShape[b.shape]++
+	if malformed != "" {
+		s.stats.Malformed[malformed]++
+	} else {
+		s.pushed[b.hash] = &submitState{at: b.at}
+		s.prunePushed()
+	}
+	conns := make([]*wsConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	head := headerJSON(b.header)
+	head["hash"] = b.hash
+	payload := s.chain.pushPayload(b)
+	for _, c := range conns {
+		for _, id := range c.subIDs(subNewHeads) {
+			s.countWrite(c.notify("eth_subscription", id, head))
+		}
+		for _, id := range c.subIDs(subVerification) {
+			s.countWrite(s.pushTo(c, id, payload, malformed))
+		}
+	}
+}
+
+// prunePushed 只保留与链历史等长的提交记录；调用方持有 s.mu
+func (s *Server) prunePushed() {
+	if len(s.pushed) <= s.cfg.History {
+		return
+	}
+	for h := range s.pushed {
+		if s.chain.lookup(h) == nil {
+			delete(s.pushed, h)
+		}
+	}
+}
+
+func (s *Server) pushTo(c *wsConn, id string, payload map[string]any, malformed string) error {
+	switch malformed {
+	case "":
+		return c.notify(NotificationMethod, id, payload)
+	case MalformedBadJSON:
+		return c.writeRaw([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":%q,"params":{"subscription":%q,"result":{"blockbody":{"header":`, NotificationMethod, id)))
+	case MalformedMissingHeader:
+		bad := clone(payload)
+		delete(bad["blockbody"].(map[string]any), "header")
+		return c.notify(NotificationMethod, id, bad)
+	case MalformedBadHex:
+		bad := clone(payload)
+		bad["blockbody"].(map[string]any)["header"].(map[string]any)["header"].(map[string]any)["number"] = "0xzz"
+		return c.notify(NotificationMethod, id, bad)
+	case MalformedWrongType:
+		return c.notify(NotificationMethod, id, "unexpected string payload")
+	case MalformedUnknownSub:
+		return c.notify(NotificationMethod, "0xdeadbeef", payload)
+	}
+	return fmt.Errorf("unknown malformed kind %q", malformed)
+}
+
+func (s *Server) countWrite(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.stats.WriteErrors++
+		return
+	}
+	s.stats.Notifications++
+}
+
+// clone 深拷贝推送负载（经 JSON 往返），畸形化时不影响其他订阅者
+func clone(m map[string]any) map[string]any {
+	raw, _ := json.Marshal(m)
+	var out map[string]any
+	json.Unmarshal(raw, &out)
+	return out
+}
+
+// Stats 当前统计的拷贝
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.stats
+	st.ByShape, st.Malformed = copyMap(s.stats.ByShape), copyMap(s.stats.Malformed)
+	st.Connections = len(s.conns)
+	st.HTTPRequests = s.httpReqs.Load()
+	return st
+}
+
+func copyMap(m map[string]int) map[string]int {
+	out := make(map[string]int, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func (st Stats) String() string {
+	var avg time.Duration
+	if st.Accepted > 0 {
+		avg = st.SubmitLatencyTotal / time.Duration(st.Accepted)
+	}
+	return fmt.Sprintf("blocks=%d shapes=[%s] notifications=%d malformed=[%s] subs=%d conns=%d write_errors=%d http=%d submit: accepted=%d duplicate=%d unknown_block=%d rejected=%d latency avg=%s max=%s",
+		st.Blocks, fmtCounts(st.ByShape), st.Notifications, fmtCounts(st.Malformed), st.Subscriptions, st.Connections, st.WriteErrors, st.HTTPRequests,
+		st.Accepted, st.Duplicate, st.UnknownBlock, st.Rejected, avg.Round(time.Millisecond), st.SubmitLatencyMax.Round(time.Millisecond))
+}
+
+func fmtCounts(m map[string]int) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%d", k, m[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// -------------------- JSON-RPC --------------------
+
+type rpcRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+var (
+	errMethodNotFound  = &rpcError{Code: -32601, Message: "the method does not exist/is not available"}
+	errNoNotifications = &rpcError{Code: -32601, Message: "notifications not supported"}
+)
+
+func invalidParams(format string, args ...any) *rpcError {
+	return &rpcError{Code: -32602, Message: fmt.Sprintf(format, args...)}
+}
+
+func response(id json.RawMessage, result any, err error) map[string]any {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	m := map[string]any{"jsonrpc": "2.0", "id": id}
+	if err != nil {
+		var re *rpcError
+		if !errors.As(err, &re) {
+			re = &rpcError{Code: -32000, Message: err.Error()}
+		}
+		m["error"] = re
+	} else {
+		m["result"] = result
+	}
+	return m
+}
+
+// handleBody 处理单个请求或批量请求，返回要写回的 JSON
+func (s *Server) handleBody(c *wsConn, body []byte) []byte {
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []rpcRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			out, _ := json.Marshal(response(nil, nil, &rpcError{Code: -32700, Message: "parse error"}))
+			return out
+		}
+		resps := make([]any, len(reqs))
+		for i, req := range reqs {
+			res, err := s.dispatch(c, req)
+			resps[i] = response(req.ID, res, err)
+		}
+		out, _ := json.Marshal(resps)
+		return out
+	}
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		out, _ := json.Marshal(response(nil, nil, &rpcError{Code: -32700, Message: "parse error"}))
+		return out
+	}
+	res, err := s.dispatch(c, req)
+	out, _ := json.Marshal(response(req.ID, res, err))
+	return out
+}
+
+// dispatch c 为 nil 表示 HTTP 请求（不支持订阅）
+func (s *Server) dispatch(c *wsConn, req rpcRequest) (any, error) {
+	var params []json.RawMessage
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams("params must be an array")
+		}
+	}
+	str := func(i int) (string, error) {
+		if i >= len(params) {
+			return "", invalidParams("missing value for required argument %d", i)
+		}
+		var v string
+		if err := json.Unmarshal(params[i], &v); err != nil {
+			return "", invalidParams("argument %d: %v", i, err)
+		}
+		return v, nil
+	}
+	flag := func(i int) bool {
+		var v bool
+		if i < len(params) {
+			json.Unmarshal(params[i], &v)
+		}
+		return v
+	}
+
+	switch req.Method {
+	case SubscribeMethod:
+		if c == nil {
+			return nil, errNoNotifications
+		}
+		return s.subscribe(c, subVerification), nil
+	case "consensusBeaconExt_subscribe":
+		// geth 风格的 namespace_subscribe（internal/rpcclient.WSClient 走这条）
+		if c == nil {
+			return nil, errNoNotifications
+		}
+		kind, err := str(0)
+		if err != nil {
+			return nil, err
+		}
+		if kind != "subscribeToVerificationRequest" {
+			return nil, invalidParams("unsupported subscription %q", kind)
+		}
+		return s.subscribe(c, subVerification), nil
+	case "eth_subscribe":
+		if c == nil {
+			return nil, errNoNotifications
+		}
+		kind, err := str(0)
+		if err != nil {
+			return nil, err
+		}
+		if kind != "newHeads" {
+			return nil, invalidParams("unsupported subscription %q", kind)
+		}
+		return s.subscribe(c, subNewHeads), nil
+	case UnsubscribeMethod, "consensusBeaconExt_unsubscribe", "eth_unsubscribe":
+		if c == nil {
+			return nil, errNoNotifications
+		}
+		id, err := str(0)
+		if err != nil {
+			return nil, err
+		}
+		return c.unsubscribe(id), nil
+	case SubmitMethod:
+		return s.submit(params)
+
+	case "eth_chainId":
+		return hexutil.Uint64(s.cfg.ChainID), nil
+	case "net_version":
+		return fmt.Sprint(s.cfg.ChainID), nil
+	case "web3_clientVersion":
+		return "verifysim", nil
+	case "eth_blockNumber":
+		return (*hexutil.Big)(s.chain.head().header.Number), nil
+	case "eth_getBlockByNumber", "eth_getBlockByHash":
+		arg, err := str(0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := s.chain.resolve(arg)
+		if err != nil || b == nil {
+			return nil, err
+		}
+		return s.chain.rpcBlock(b, flag(1)), nil
+	case "eth_getBlockReceipts":
+		arg, err := str(0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := s.chain.resolve(arg)
+		if err != nil || b == nil {
+			return nil, err
+		}
+		return b.receipts, nil
+	case "eth_getTransactionReceipt", "eth_getTransactionByHash":
+		arg, err := str(0)
+		if err != nil {
+			return nil, err
+		}
+		loc, ok := s.chain.lookupTx(common.HexToHash(arg))
+		if !ok {
+			return nil, nil
+		}
+		if req.Method == "eth_getTransactionByHash" {
+			return loc.block.txJSON(loc.index, s.chain.from), nil
+		}
+		return loc.block.receipts[loc.index], nil
+	}
+	return nil, errMethodNotFound
+}
+
+// submit 参数与二进制一致：[pubkey hex, signature hex, attestation_data, block hash hex]。
+// 只检查格式与区块哈希，不验证 BLS 签名
+func (s *Server) submit(params []json.RawMessage) (any, error) {
+	reject := func(format string, args ...any) (any, error) {
+		s.mu.Lock()
+		s.stats.Rejected++
+		s.mu.Unlock()
+		return nil, invalidParams(format, args...)
+	}
+	if len(params) != 4 {
+		return reject("expected 4 params, got %d", len(params))
+	}
+	var pk, sig, hash string
+	if json.Unmarshal(params[0], &pk) != nil || !isHexLen(pk, 48) {
+		return reject("pubkey must be 48-byte hex")
+	}
+	if json.Unmarshal(params[1], &sig) != nil || !isHexLen(sig, 96) {
+		return reject("signature must be 96-byte hex")
+	}
+	h, ok := common.Hash{}, false
+	if json.Unmarshal(params[3], &hash) == nil {
+		h, ok = parseHash(hash)
+	}
+	if !ok {
+		return reject("block hash must be 32-byte hex")
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.pushed[h]
+	switch {
+	case st == nil:
+		s.stats.UnknownBlock++
+		return nil, &rpcError{Code: -32000, Message: "unknown block " + h.Hex()}
+	case st.submitted:
+		s.stats.Duplicate++
+	default:
+		st.submitted = true
+		s.stats.Accepted++
+		d := now.Sub(st.at)
+		s.stats.SubmitLatencyTotal += d
+		s.stats.SubmitLatencyMax = max(s.stats.SubmitLatencyMax, d)
+	}
+	return true, nil
+}
+
+func isHexLen(s string, n int) bool {
+	b, err := hexutil.Decode("0x" + strings.TrimPrefix(s, "0x"))
+	return err == nil && len(b) == n
+}
+
+// -------------------- 传输 --------------------
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+// ServeHTTP 见 Server
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWS(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "JSON-RPC over POST or WebSocket", http.StatusMethodNotAllowed)
+		return
+	}
+	s.httpReqs.Add(1)
+	body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.handleBody(nil, body))
+}
+
+type subKind int
+
+const (
+	subVerification subKind = iota
+	subNewHeads
+)
+
+type wsConn struct {
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	subs map[string]subKind
+}
+
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade 已写回错误
+	}
+	c := &wsConn{ws: ws, subs: map[string]subKind{}}
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	s.cfg.Logf("verifysim: ws connected from %s", r.RemoteAddr)
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		ws.Close()
+		s.cfg.Logf("verifysim: ws %s closed", r.RemoteAddr)
+	}()
+	for {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := c.writeRaw(s.handleBody(c, msg)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) subscribe(c *wsConn, kind subKind) string {
+	s.mu.Lock()
+	s.subSeq++
+	id := fmt.Sprintf("0x%x", s.subSeq)
+	s.stats.Subscriptions++
+	s.mu.Unlock()
+	c.mu.Lock()
+	c.subs[id] = kind
+	c.mu.Unlock()
+	return id
+}
+
+func (c *wsConn) unsubscribe(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.subs[id]
+	delete(c.subs, id)
+	return ok
+}
+
+func (c *wsConn) subIDs(kind subKind) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id, k := range c.subs {
+		if k == kind {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (c *wsConn) notify(method, id string, result any) error {
+	raw, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0", "method": method,
+		"params": map[string]any{"subscription": id, "result": result},
+	})
+	if err != nil {
+		return err
+	}
+	return c.writeRaw(raw)
+}
+
+// writeRaw 写超时的连接会被关闭，由读循环负责清理
+func (c *wsConn) writeRaw(b []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := c.ws.WriteMessage(websocket.TextMessage, b); err != nil {
+		c.ws.Close()
+		return err
+	}
+	return nil
+}
+
+// Close 通知并关闭全部 WS 连接
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.writeMu.Lock()
+		c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "simulation finished"), time.Now().Add(time.Second))
+		c.writeMu.Unlock()
+		c.ws.Close()
+	}
+}